@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/backup"
+	"github.com/noor-latif/fulldash/internal/maintenance"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/store"
+)
+
+// runCommand executes a CLI subcommand and returns the process exit code
+func runCommand(name string, args []string) int {
+	switch name {
+	case "verify":
+		return cmdVerify()
+	case "dr-test":
+		return cmdDRTest()
+	case "backup":
+		return cmdBackup(args)
+	case "export":
+		return cmdExport(args)
+	case "import":
+		return cmdImport(args)
+	case "restore":
+		return cmdRestore(args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\nusage: fullstacked [verify|dr-test|backup [--dir dir] [--keep n] [--local]|restore [--backup file|dir] [--to time]|export --out dump.json|import --in dump.json [--replace]]\n", name)
+		return 2
+	}
+}
+
+// cmdVerify runs migrations and integrity checks against DB_PATH
+func cmdVerify() int {
+	db, err := openStore(getEnv("DB_PATH", defaultDBPath))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	check, err := db.CheckIntegrity()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "check: %v\n", err)
+		return 1
+	}
+	if !check.OK {
+		fmt.Printf("FAILED\n%s\n", check.Details)
+		return 1
+	}
+	fmt.Println("OK")
+	return 0
+}
+
+// cmdDRTest restores the latest backup to a temp location, verifies it,
+// and records the result in the live database for the admin page
+func cmdDRTest() int {
+	result := backup.DryRun(getEnv("BACKUP_DIR", defaultBackupDir))
+	fmt.Println(result.Details)
+
+	if db, err := openStore(getEnv("DB_PATH", defaultDBPath)); err == nil {
+		if err := db.RecordDRTest(result); err != nil {
+			fmt.Fprintf(os.Stderr, "record result: %v\n", err)
+		}
+		db.Close()
+	}
+
+	if !result.OK {
+		fmt.Println("RECOVERY WOULD FAIL")
+		return 1
+	}
+	fmt.Println("RECOVERY OK")
+	return 0
+}
+
+// cmdBackup snapshots DB_PATH into --dir (BACKUP_DIR), safe while the
+// server runs, uploads it to the configured remotes and prunes them all
+// to the BACKUP_KEEP* policy (--keep overriding BACKUP_KEEP)
+func cmdBackup(args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	dir := fs.String("dir", getEnv("BACKUP_DIR", defaultBackupDir), "directory to write the snapshot to")
+	keep := fs.Int("keep", getEnvInt("BACKUP_KEEP", defaultBackupKeep), "latest snapshots to keep; 0 with no other BACKUP_KEEP_* keeps all")
+	local := fs.Bool("local", false, "skip the remotes")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := openStore(getEnv("DB_PATH", defaultDBPath))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	runner, err := backupRunner(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backup: %v\n", err)
+		return 1
+	}
+	runner.Dir, runner.Keep.Last = *dir, *keep
+
+	var path string
+	if *local {
+		path, err = runner.Local(models.BackupCLI)
+	} else {
+		path, err = runner.Run(context.Background(), models.BackupCLI)
+	}
+	if path != "" {
+		fmt.Println(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backup: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// restoreTimeLayouts are the forms --to takes, in local time unless the
+// RFC 3339 form gives a zone; a date alone is its midnight
+var restoreTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"}
+
+// cmdRestore replaces DB_PATH with a snapshot. --backup names one, or a
+// directory (BACKUP_DIR) to take the newest from, or with --to the newest
+// taken at or before that time; a named file must itself be no newer than
+// --to. The snapshot is migrated and checked
+// beside the database first; then, under the maintenance lock that makes
+// running servers step back, the current database is kept as a snapshot
+// in BACKUP_DIR and the checked copy swapped in.
+func cmdRestore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	from := fs.String("backup", getEnv("BACKUP_DIR", defaultBackupDir), "snapshot to restore, or a directory of snapshots")
+	to := fs.String("to", "", "take the newest snapshot at or before this time, e.g. \"2026-03-14 09:30\"; a snapshot file must be no newer")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var at time.Time
+	if *to != "" {
+		var err error
+		if at, err = parseRestoreTime(*to); err != nil {
+			fmt.Fprintf(os.Stderr, "--to: %v\n", err)
+			return 2
+		}
+	}
+
+	src := *from
+	info, err := os.Stat(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backup: %v\n", err)
+		return 1
+	}
+	if info.IsDir() {
+		if src, err = backup.Find(src, at); err != nil {
+			fmt.Fprintf(os.Stderr, "find backup in %s: %v\n", *from, err)
+			return 1
+		}
+	} else if !at.IsZero() {
+		// A named snapshot is only restored if it was taken by --to
+		taken, ok := backup.TakenAt(src)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "--to: %s is not named like a snapshot, so when it was taken is unknown\n", filepath.Base(src))
+			return 2
+		}
+		if taken.After(at) {
+			fmt.Fprintf(os.Stderr, "--to: %s was taken at %s, after %s\n", filepath.Base(src), taken.Local().Format(time.RFC3339), at.Format(time.RFC3339))
+			return 1
+		}
+	}
+
+	dbPath := getEnv("DB_PATH", defaultDBPath)
+	prepared, check := backup.Prepare(src, dbPath)
+	fmt.Println(check.Details)
+	if !check.OK {
+		fmt.Println("RESTORE ABORTED: the database is unchanged")
+		return 1
+	}
+
+	lock := maintenance.For(dbPath)
+	if err := lock.Acquire("restoring " + filepath.Base(src)); err != nil {
+		os.Remove(prepared)
+		fmt.Fprintf(os.Stderr, "maintenance lock: %v\n", err)
+		return 1
+	}
+	defer lock.Release()
+	fmt.Printf("maintenance mode on; waiting %s for running servers to let go of the database\n", maintenance.Grace)
+	time.Sleep(maintenance.Grace)
+
+	if _, err := os.Stat(dbPath); err == nil {
+		kept, err := keepCurrent(dbPath)
+		if err != nil {
+			os.Remove(prepared)
+			fmt.Fprintf(os.Stderr, "keep the current database: %v\nRESTORE ABORTED: the database is unchanged\n", err)
+			return 1
+		}
+		fmt.Printf("current database kept as %s\n", kept)
+	}
+	if err := backup.Swap(prepared, dbPath); err != nil {
+		fmt.Fprintf(os.Stderr, "swap: %v\n", err)
+		return 1
+	}
+	fmt.Printf("RESTORED %s to %s\n", filepath.Base(src), dbPath)
+	return 0
+}
+
+// parseRestoreTime reads --to in any of restoreTimeLayouts
+func parseRestoreTime(v string) (time.Time, error) {
+	for _, layout := range restoreTimeLayouts {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time such as 2026-03-14 09:30", v)
+}
+
+// keepCurrent snapshots the database about to be replaced into BACKUP_DIR,
+// so a restore can itself be undone
+func keepCurrent(dbPath string) (string, error) {
+	db, err := openStore(dbPath)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	return backup.Create(db, getEnv("BACKUP_DIR", defaultBackupDir), backup.Retention{})
+}
+
+// cmdExport writes DB_PATH as a JSON dump to --out, or stdout
+func cmdExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := fs.String("out", "-", "file to write the dump to; - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := openStore(getEnv("DB_PATH", defaultDBPath))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "create: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	sum, err := db.Export(w)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "exported %d rows in %d tables (schema version %d)\n", sum.Rows, sum.Tables, sum.SchemaVersion)
+	return 0
+}
+
+// cmdImport loads a JSON dump from --in into DB_PATH. A new database is
+// restored from it, upgrading a dump from an older FullDash; an existing
+// one has its data replaced, only with --replace and a dump of its own
+// schema version.
+func cmdImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	in := fs.String("in", "", "dump to import")
+	replace := fs.Bool("replace", false, "replace the data of an existing database")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *in == "" {
+		fmt.Fprintln(os.Stderr, "usage: fullstacked import --in dump.json [--replace]")
+		return 2
+	}
+	f, err := os.Open(*in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+
+	path := getEnv("DB_PATH", defaultDBPath)
+	var sum *models.DumpSummary
+	if _, err := os.Stat(path); err != nil {
+		sum, err = store.Restore(path, f)
+		if err != nil {
+			os.Remove(path)
+			fmt.Fprintf(os.Stderr, "restore: %v\n", err)
+			return 1
+		}
+	} else {
+		if !*replace {
+			fmt.Fprintf(os.Stderr, "%s exists; pass --replace to replace its data, or set DB_PATH to a new file\n", path)
+			return 2
+		}
+		db, err := openStore(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "open: %v\n", err)
+			return 1
+		}
+		defer db.Close()
+		if sum, err = db.Import(f); err != nil {
+			fmt.Fprintf(os.Stderr, "import: %v\n", err)
+			return 1
+		}
+	}
+	fmt.Printf("imported %d rows in %d tables (schema version %d, exported %s)\n",
+		sum.Rows, sum.Tables, sum.SchemaVersion, sum.ExportedAt.Format(time.RFC3339))
+	return 0
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/automation"
+	"github.com/noor-latif/fulldash/internal/backup"
+	"github.com/noor-latif/fulldash/internal/domain"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/fx"
+	"github.com/noor-latif/fulldash/internal/handlers"
+	"github.com/noor-latif/fulldash/internal/jobs"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/notify"
+	"github.com/noor-latif/fulldash/internal/replication"
+	"github.com/noor-latif/fulldash/internal/store"
+	"github.com/noor-latif/fulldash/internal/webhooks"
+)
+
+// registerJobs wires background maintenance jobs into the scheduler
+func registerJobs(sched *jobs.Scheduler, db *store.DB, bus *events.Bus, engine *automation.Engine, notifier *notify.Dispatcher, hooks *webhooks.Dispatcher, h *handlers.Handler) {
+	sched.Every(handlers.IntegrityJob, getEnvDuration("INTEGRITY_CHECK_INTERVAL", defaultIntegrityInterval),
+		func(ctx context.Context) error {
+			check, err := db.CheckIntegrity()
+			if err != nil {
+				return err
+			}
+			if !check.OK {
+				log.Printf("[ALERT] Database integrity check failed:\n%s", check.Details)
+				return errors.New("integrity check failed: " + strings.SplitN(check.Details, "\n", 2)[0])
+			}
+			return nil
+		})
+
+	// Snapshots on BACKUP_SCHEDULE, copied to the remotes set up, for the
+	// dry run below to restore. Checked every minute; the schedule decides.
+	sched.Every(handlers.BackupJob, time.Minute, func(ctx context.Context) error {
+		due, err := h.Config.Backups.Due(time.Now())
+		if err != nil || !due {
+			return err
+		}
+		_, err = h.Config.Backups.Run(ctx, models.BackupScheduled)
+		return err
+	})
+
+	drInterval := getEnvDuration("DR_TEST_INTERVAL", defaultDRTestInterval)
+	sched.Every(handlers.DRTestJob, drInterval, func(ctx context.Context) error {
+		// The scheduler also fires at startup; skip if a recent dry run exists
+		if last, err := db.LastDRTest(); err == nil && last != nil && time.Since(last.CheckedAt) < drInterval-time.Hour {
+			return nil
+		}
+
+		result := backup.DryRun(h.Config.Backups.Dir)
+		if err := db.RecordDRTest(result); err != nil {
+			return err
+		}
+		if !result.OK {
+			log.Printf("[ALERT] Disaster recovery dry run failed:\n%s", result.Details)
+			return errors.New("dr dry run failed")
+		}
+		return nil
+	})
+
+	// A timestamp for replicas to measure their lag against
+	if h.Config.Replication.Enabled() {
+		sched.Every(replication.JobName, replication.HeartbeatInterval, replication.Heartbeat(db))
+	}
+
+	// Today's metrics for the dashboard's history. Rerun hourly, so each
+	// day keeps the figures it closed with.
+	sched.Every(handlers.StatsSnapshotJob, time.Hour, func(ctx context.Context) error {
+		m, err := db.GetMetrics()
+		if err != nil {
+			return err
+		}
+		return db.SaveStatsSnapshot(&models.StatsSnapshot{Day: time.Now(), Metrics: *m})
+	})
+
+	// Disk use for the storage history, warning when a soft quota is passed
+	sched.Every(handlers.StorageJob, time.Hour, func(ctx context.Context) error {
+		dbBytes, err := db.DatabaseSize()
+		if err != nil {
+			return err
+		}
+		attachmentBytes, err := dirSize(getEnv("UPLOAD_DIR", defaultUploadDir))
+		if err != nil {
+			return err
+		}
+		if err := db.SaveStorageUsage(&models.StorageUsage{Day: time.Now(), DBBytes: dbBytes, AttachmentBytes: attachmentBytes}); err != nil {
+			return err
+		}
+
+		report, err := db.StorageReport()
+		if err != nil {
+			return err
+		}
+		if warnings := report.Warnings(); len(warnings) > 0 {
+			log.Printf("[ALERT] Storage soft quota passed:\n%s", strings.Join(warnings, "\n"))
+			return errors.New(warnings[0])
+		}
+		return nil
+	})
+
+	// Delayed automation rules (e.g. "archive 14 days after payment")
+	sched.Every(automation.JobName, getEnvDuration("AUTOMATION_INTERVAL", defaultAutomationInterval), engine.RunDue)
+
+	// Retries of webhook deliveries that failed, with backoff
+	sched.Every(webhooks.JobName, time.Minute, hooks.RunDue)
+
+	// Daily notification digests, composed during DIGEST_HOUR
+	digestHour := getEnvInt("DIGEST_HOUR", defaultDigestHour)
+	sched.Every(notify.DigestJob, time.Hour, func(ctx context.Context) error {
+		if time.Now().Hour() != digestHour {
+			return nil
+		}
+		return notifier.SendDigests(ctx)
+	})
+
+	// Retries of emails that failed for a reason that may pass, with backoff
+	sched.Every(handlers.MailRetryJob, time.Minute, h.RetryMail)
+
+	// Partners' weekly summary emails, sent on Mondays during DIGEST_HOUR
+	sched.Every(handlers.WeeklyJob, time.Hour, func(ctx context.Context) error {
+		if now := time.Now(); now.Weekday() != time.Monday || now.Hour() != digestHour {
+			return nil
+		}
+		return h.SendWeeklySummaries(ctx)
+	})
+
+	// Due dates kept in Google Calendar once it's connected in Admin
+	sched.Every(handlers.GoogleCalendarJob, getEnvDuration("GOOGLE_CALENDAR_INTERVAL", defaultGoogleCalInterval), h.SyncGoogleCalendar)
+
+	// Reminders through the notifications for documents that expire within
+	// models.DocumentReminderDays; each current version is reminded of once
+	sched.Every(handlers.DocumentExpiryJob, time.Hour, func(ctx context.Context) error {
+		now := time.Now()
+		docs, err := db.DocumentsExpiring(now, now.AddDate(0, 0, models.DocumentReminderDays))
+		if err != nil {
+			return err
+		}
+		for _, d := range docs {
+			if err := db.MarkDocumentReminded(d.ID); err != nil {
+				return err
+			}
+			bus.Publish(ctx, events.Event{
+				Type:      events.DocumentExpiring,
+				ProjectID: d.ProjectID,
+				Client:    d.Client,
+				Document:  d.Title,
+				Due:       *d.ExpiresOn,
+				Source:    events.SourceSchedule,
+			})
+		}
+		return nil
+	})
+
+	// Announces each receivable (see the aging report) once it's past due,
+	// for notification rules and inboxes
+	sched.Every(handlers.OverdueJob, time.Hour, func(ctx context.Context) error {
+		statuses, err := db.ListStatuses()
+		if err != nil {
+			return err
+		}
+		projects, err := db.ListProjects(models.ProjectFilter{})
+		if err != nil {
+			return err
+		}
+		invoices, err := db.ListInvoices("")
+		if err != nil {
+			return err
+		}
+		for _, r := range domain.Workflow(statuses).Aging(projects, invoices, time.Now()).Receivables {
+			if r.DaysOverdue == 0 {
+				continue
+			}
+			first, err := db.ClaimOverdueNotice(r)
+			if err != nil {
+				return err
+			}
+			if !first {
+				continue
+			}
+			bus.Publish(ctx, events.Event{
+				Type:        events.PaymentOverdue,
+				ProjectID:   r.ProjectID,
+				Client:      r.Client,
+				AmountCents: r.AmountCents,
+				Due:         r.DueOn,
+				Source:      events.SourceSchedule,
+			})
+		}
+		return nil
+	})
+
+	// Today's exchange rates for the currencies listed in Admin, from
+	// FX_PROVIDER; fetched once a day and cached in fx_rates
+	if name := os.Getenv("FX_PROVIDER"); name != "" {
+		provider, err := fx.New(name, os.Getenv("FX_API_URL"), os.Getenv("FX_API_KEY"))
+		if err != nil {
+			log.Fatalf("Exchange rates: %v", err)
+		}
+		rates := &fx.Cache{Provider: provider, Store: db}
+		sched.Every(fx.JobName, getEnvDuration("FX_REFRESH_INTERVAL", defaultFXRefreshInterval), rates.Refresh)
+	}
+}
+
+// dirSize totals the files under dir. A missing dir is empty, and files
+// deleted mid-walk are skipped.
+func dirSize(dir string) (int64, error) {
+	var n int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			var info fs.FileInfo
+			if info, err = d.Info(); err == nil {
+				n += info.Size()
+			}
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	})
+	return n, err
+}
@@ -1,57 +1,444 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/automation"
+	"github.com/noor-latif/fulldash/internal/backup"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/fortnox"
+	"github.com/noor-latif/fulldash/internal/gcal"
 	"github.com/noor-latif/fulldash/internal/handlers"
+	"github.com/noor-latif/fulldash/internal/jobs"
+	"github.com/noor-latif/fulldash/internal/mail"
+	"github.com/noor-latif/fulldash/internal/maintenance"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/notify"
+	"github.com/noor-latif/fulldash/internal/sentry"
 	"github.com/noor-latif/fulldash/internal/store"
+	"github.com/noor-latif/fulldash/internal/webhooks"
+)
+
+const (
+	defaultDBPath             = "data/fulldash.db"
+	defaultBackupDir          = "data/backups"
+	defaultUploadDir          = "data/uploads"
+	defaultIntegrityInterval  = 6 * time.Hour
+	defaultDRTestInterval     = 30 * 24 * time.Hour
+	defaultBackupSchedule     = "0 3 * * *" // 03:00 local time, daily
+	defaultBackupKeep         = 14
+	defaultAutomationInterval = 15 * time.Minute
+	defaultFXRefreshInterval  = 24 * time.Hour
+	defaultGoogleCalInterval  = 15 * time.Minute
+	defaultDigestHour         = 7   // local time
+	defaultLoginRateLimit     = 10  // attempts per minute per IP
+	defaultAPIRateLimit       = 120 // requests per minute per key
+	defaultSMTPPort           = 587
+	defaultMailFrom           = "FullDash <fulldash@localhost>"
 )
 
 func main() {
-	dbPath := getEnv("DB_PATH", "data/fulldash.db")
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+
+	dbPath := getEnv("DB_PATH", defaultDBPath)
 	port := getEnv("PORT", "8080")
 
-	db, err := store.New(dbPath)
+	repl, err := openReplication(dbPath)
+	if err != nil {
+		log.Fatalf("Replication: %v", err)
+	}
+	db, startChaos, err := openDB(dbPath, repl)
 	if err != nil {
 		log.Fatalf("DB error: %v", err)
 	}
 	defer db.Close()
 
-	h := handlers.New(db)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	bus := events.New()
+	stream, err := openStream(ctx, bus)
+	if err != nil {
+		log.Fatalf("Events backend: %v", err)
+	}
+	engine := automation.New(db, bus)
+	engine.Subscribe()
+	baseURL := strings.TrimSuffix(os.Getenv("BASE_URL"), "/")
+	notifier := notify.NewDispatcher(db, baseURL)
+	notifier.Subscribe(bus)
+	hooks := webhooks.New(db)
+	hooks.Subscribe(bus)
+
+	lock := maintenance.For(dbPath)
+	sched := jobs.New()
+	sched.UseLocker(db, instanceID())
+	sched.PauseWhen(func() bool { return repl.Replica() || lock.Held() })
+
+	portalSecret, err := loadPortalSecret(db)
+	if err != nil {
+		log.Fatalf("Portal secret: %v", err)
+	}
+
+	sessions, c, err := openState(db, repl.Replica())
+	if err != nil {
+		log.Fatalf("State backend: %v", err)
+	}
+	limiter, err := openLimiter()
+	if err != nil {
+		log.Fatalf("Rate limit backend: %v", err)
+	}
+
+	backups, err := backupRunner(db)
+	if err != nil {
+		log.Fatalf("Backups: %v", err)
+	}
+
+	mailer, err := mail.New(os.Getenv("MAIL_PROVIDER"), os.Getenv("MAIL_API_URL"), os.Getenv("MAIL_API_KEY"), mail.SMTP{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     getEnvInt("SMTP_PORT", defaultSMTPPort),
+		User:     os.Getenv("SMTP_USER"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     getEnv("MAIL_FROM", getEnv("SMTP_FROM", defaultMailFrom)),
+	})
+	if err != nil {
+		log.Fatalf("Mail: %v", err)
+	}
+
+	reporter, err := sentry.New(os.Getenv("SENTRY_DSN"), sentry.Options{
+		Release:     getEnv("SENTRY_RELEASE", buildVersion()),
+		Environment: getEnv("SENTRY_ENVIRONMENT", "production"),
+	})
+	if err != nil {
+		log.Fatalf("Error reporting: %v", err)
+	}
+
+	webhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
+	webhookInsecure := os.Getenv("STRIPE_WEBHOOK_INSECURE") == "1"
+	if webhookInsecure && webhookSecret == "" {
+		log.Printf("[STRIPE] WARNING: STRIPE_WEBHOOK_INSECURE=1 and no STRIPE_WEBHOOK_SECRET: /webhook accepts UNSIGNED events, so anyone can record payments. Never run this in production")
+	}
+
+	h := handlers.New(db, sched, bus, stream, engine, notifier, sessions, c, limiter, handlers.Config{
+		UploadDir:             getEnv("UPLOAD_DIR", defaultUploadDir),
+		Backups:               backups,
+		BaseURL:               baseURL,
+		PortalSecret:          portalSecret,
+		PortalTTL:             getEnvDuration("PORTAL_TTL", auth.PortalTTL),
+		PaymentLinkURL:        os.Getenv("STRIPE_PAYMENT_LINK"),
+		StripeKey:             os.Getenv("STRIPE_SECRET_KEY"),
+		StripeAPIURL:          os.Getenv("STRIPE_API_URL"),
+		StripeWebhookSecret:   webhookSecret,
+		StripeWebhookInsecure: webhookInsecure,
+		Mail:                  mailer,
+		LoginRateLimit:        getEnvInt("LOGIN_RATE_LIMIT", defaultLoginRateLimit),
+		APIRateLimit:          getEnvInt("API_RATE_LIMIT", defaultAPIRateLimit),
+		FortnoxAPIURL:         getEnv("FORTNOX_API_URL", fortnox.DefaultAPIURL),
+		FortnoxTokenURL:       getEnv("FORTNOX_TOKEN_URL", fortnox.DefaultTokenURL),
+		GoogleAPIURL:          getEnv("GOOGLE_CALENDAR_API_URL", gcal.DefaultAPIURL),
+		GoogleTokenURL:        getEnv("GOOGLE_TOKEN_URL", gcal.DefaultTokenURL),
+		FXProvider:            os.Getenv("FX_PROVIDER"),
+		Replication:           repl,
+		Maintenance:           lock,
+		Errors:                reporter,
+	})
+	h.WatchMetrics(bus)
+	go h.WatchMaintenance(ctx, func(active bool) {
+		if active {
+			db.SetMaxIdleConns(0) // close connections as they come back, so the swap leaves none on the old file
+		} else {
+			db.SetMaxIdleConns(2) // database/sql's default
+		}
+	})
+
+	registerJobs(sched, db, bus, engine, notifier, hooks, h)
+	sched.Start(ctx)
 
 	r := chi.NewRouter()
+	trustProxy := os.Getenv("TRUST_PROXY") != ""
+	if trustProxy {
+		r.Use(middleware.RealIP) // client IPs from X-Forwarded-For (rate limits, logs)
+	}
+	r.Use(handlers.RequestID(trustProxy)) // before Logger, which prints it
 	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
+	r.Use(h.Recoverer) // chi's, reporting panics and 5xx responses
+	r.Use(h.Maintenance)
+	r.Use(h.ReadOnly)
+	r.Use(h.CSRF("/webhook", "/api/"))
 
 	// Static files
 	r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
 
-	// Routes
-	r.Get("/", h.Dashboard)
-	r.Get("/projects/new", h.ProjectForm)
-	r.Get("/projects/{id}/edit", h.ProjectForm)
-	r.Post("/projects", h.CreateProject)
-	r.Put("/projects/{id}", h.UpdateProject)
-	r.Delete("/projects/{id}", h.DeleteProject)
+	// Auth (public)
+	r.Get("/login", h.LoginPage)
+	r.Post("/login", h.Login)
+	r.Post("/logout", h.Logout)
+	r.Get("/setup", h.SetupPage)
+	r.Post("/setup", h.Setup)
 
-	// Stripe webhook
+	// Stripe webhook (signed, no session)
 	r.Post("/webhook", h.StripeWebhook)
-	r.Get("/payment-link", h.CreatePaymentLink)
 
-	// Health
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("OK"))
+	// Client portal (signed token, no login)
+	r.Get("/portal/{token}", h.Portal)
+	r.Post("/portal/{token}/pay", h.PortalCheckout)
+	r.Get("/portal/{token}/invoices/{id:[0-9]+}.pdf", h.PortalInvoicePDF)
+	r.Get("/portal/quotes/{token}", h.QuotePortal)
+	r.Post("/portal/quotes/{token}/answer", h.AnswerQuote)
+
+	// Calendar feed (per-user token in the URL, no login)
+	r.Get("/calendar.ics", h.CalendarFeed)
+
+	// JSON API (Bearer API keys)
+	r.Route("/api/v1", func(r chi.Router) {
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/projects", h.APIListProjects)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/projects/{id}", h.APIGetProject)
+		r.With(h.RequireAPIKey(models.ScopeWrite)).Post("/projects", h.APICreateProject)
+		r.With(h.RequireAPIKey(models.ScopeWrite)).Put("/projects/{id}", h.APIUpdateProject)
+		r.With(h.RequireAPIKey(models.ScopeWrite)).Delete("/projects/{id}", h.APIDeleteProject)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/metrics", h.APIMetrics)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/stats/quarters", h.APIQuarterlyStats)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/reports/revenue", h.APIRevenueSeries)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/reports/compare", h.APICompare)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/reports/forecast", h.APIForecast)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/reports/win-rate", h.APIWinRate)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/reports/hours", h.APIHoursReport)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/reports/estimates", h.APIEstimates)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/reports/aging", h.APIAging)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/reports/currencies", h.APICurrencies)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/time-entries", h.APITimeEntries)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/timesheet", h.APITimesheet)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/reconciliation", h.APIReconciliation)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/clients", h.APIClients)
+		r.With(h.RequireAPIKey(models.ScopeRead)).Get("/clients/{id}", h.APIClient)
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.RequireAPIKey(models.ScopeAdmin))
+			r.Get("/keys", h.APIListKeys)
+			r.Post("/keys", h.APICreateKey)
+			r.Delete("/keys/{id}", h.APIRevokeKey)
+			r.Get("/metrics/queries", h.APIQueryMetrics)
+		})
 	})
 
+	// Web UI (session login, role-gated)
+	r.Group(func(r chi.Router) {
+		r.Use(h.RequireLogin)
+
+		// Viewers: read-only dashboard
+		r.Get("/", h.Dashboard)
+		r.Get("/events", h.EventStream)
+		r.Get("/metrics", h.MetricsRow)
+		r.Get("/notifications", h.NotificationsPage)
+		r.Post("/notifications/prefs", h.UpdateNotificationPrefs)
+		r.Post("/notifications/weekly", h.UpdateWeeklyDigest)
+		r.Post("/notifications/calendar", h.CreateCalendarLink)
+		r.Delete("/notifications/calendar", h.DeleteCalendarLink)
+		r.Get("/activity", h.AuditLog)
+		r.Get("/archive", h.ArchivePage)
+		r.Get("/stats", h.StatsPage)
+		r.Get("/reports/compare", h.ComparePage)
+		r.Get("/reports/hours", h.HoursReportPage)
+		r.Get("/reports/estimates", h.EstimatesPage)
+		r.Get("/reports/aging", h.AgingPage)
+		r.Get("/reports/currencies", h.CurrenciesPage)
+		r.Get("/export/projects.csv", h.ExportProjects)
+		r.Get("/export/payments.csv", h.ExportPayments)
+		r.Get("/export/hours.csv", h.ExportHours)
+		r.Get("/clients", h.ClientsPage)
+		r.Get("/clients/{id}", h.ClientPage)
+		r.Get("/projects/{id}/history", h.ProjectHistory)
+		r.Get("/expenses/{id}/receipt", h.ExpenseReceipt)
+		r.Get("/documents/{id}", h.DocumentFile)
+
+		// Partners: edit projects, log hours and expenses
+		r.Group(func(r chi.Router) {
+			r.Use(h.RequireRole(models.RolePartner))
+			r.Get("/projects/new", h.ProjectForm)
+			r.Get("/projects/{id}/edit", h.ProjectForm)
+			r.Post("/projects", h.CreateProject)
+			r.Get("/fx/convert", h.ConvertAmount)
+			r.Put("/projects/{id}", h.UpdateProject)
+			r.Post("/projects/{id}/move", h.MoveProject)
+			r.Post("/projects/{id}/duplicate", h.DuplicateProject)
+			r.Post("/projects/{id}/archive", h.ArchiveProject)
+			r.Post("/projects/{id}/unarchive", h.UnarchiveProject)
+			r.Post("/projects/{id}/expenses", h.CreateExpense)
+			r.Post("/projects/{id}/time-entries", h.CreateTimeEntry)
+			r.Get("/time-entries/{id}/edit", h.EditTimeEntry)
+			r.Put("/time-entries/{id}", h.UpdateTimeEntry)
+			r.Delete("/time-entries/{id}", h.DeleteTimeEntry)
+			r.Get("/timesheet", h.TimesheetPage)
+			r.Post("/timesheet", h.SetTimesheetHours)
+			r.Post("/projects/{id}/milestones", h.CreateMilestone)
+			r.Get("/milestones/{id}/edit", h.EditMilestone)
+			r.Put("/milestones/{id}", h.UpdateMilestone)
+			r.Delete("/milestones/{id}", h.DeleteMilestone)
+			r.Post("/milestones/{id}/payment-link", h.CreateMilestoneLink)
+			r.Post("/projects/{id}/tasks", h.CreateTask)
+			r.Post("/tasks/{id}/toggle", h.ToggleTask)
+			r.Get("/tasks/{id}/edit", h.EditTask)
+			r.Put("/tasks/{id}", h.UpdateTask)
+			r.Post("/tasks/{id}/move", h.MoveTask)
+			r.Delete("/tasks/{id}", h.DeleteTask)
+			r.Post("/clients/{id}/retainers", h.CreateRetainer)
+			r.Post("/clients/{id}/documents", h.CreateClientDocument)
+			r.Post("/projects/{id}/documents", h.CreateProjectDocument)
+			r.Delete("/documents/{id}", h.DeleteDocument)
+			r.Delete("/retainers/{id}", h.DeleteRetainer)
+			r.Post("/projects/{id}/adjustments", h.CreateAdjustment)
+			r.Post("/projects/{id}/comments", h.CreateComment)
+			r.Post("/projects/{id}/invites", h.SendInvite)
+			r.Post("/projects/{id}/invite.ics", h.DownloadInvite)
+			r.Delete("/comments/{id}", h.DeleteComment)
+			r.Get("/expenses", h.ExpensesPage)
+			r.Post("/expenses", h.AddExpense)
+			r.Get("/expenses/{id}/edit", h.EditExpense)
+			r.Put("/expenses/{id}", h.UpdateExpense)
+			r.Delete("/expenses/{id}", h.DeleteExpense)
+			r.Get("/settlements", h.SettlementsPage)
+			r.Post("/settlements", h.RecordPayout)
+			r.Delete("/settlements/{id}", h.DeletePayout)
+			r.Get("/invoices", h.InvoicesPage)
+			r.Post("/projects/{id}/invoices", h.CreateInvoice)
+			r.Get("/invoices/{id:[0-9]+}.pdf", h.InvoicePDF)
+			r.Get("/invoices/{id}", h.InvoicePage)
+			r.Put("/invoices/{id}", h.UpdateInvoice)
+			r.Post("/invoices/{id}/issue", h.IssueInvoice)
+			r.Post("/invoices/{id}/status", h.SetInvoiceStatus)
+			r.Post("/invoices/{id}/email", h.EmailInvoice)
+			r.Post("/invoices/{id}/fortnox", h.SyncInvoiceFortnox)
+			r.Delete("/invoices/{id}", h.DeleteInvoice)
+			r.Get("/mail", h.SentMailPage)
+			r.Get("/reports/vat", h.VATReportPage)
+			r.Get("/reports/sie", h.SIEExport)
+			r.Get("/export/workbook.xlsx", h.ExportWorkbook)
+			r.Get("/reports/{year:[0-9]{4}}/{month:[0-9]{1,2}}.pdf", h.MonthlyReportPDF)
+			r.Get("/quotes", h.QuotesPage)
+			r.Post("/quotes", h.CreateQuote)
+			r.Get("/quotes/{id}", h.QuotePage)
+			r.Put("/quotes/{id}", h.UpdateQuote)
+			r.Post("/quotes/{id}/share", h.ShareQuote)
+			r.Post("/quotes/{id}/status", h.SetQuoteStatus)
+			r.Post("/quotes/{id}/convert", h.ConvertQuote)
+			r.Delete("/quotes/{id}", h.DeleteQuote)
+			r.Post("/checklist/{id}/toggle", h.ToggleChecklistItem)
+			r.Get("/payment-link", h.CreatePaymentLink)
+			r.Post("/projects/{id}/portal-link", h.CreatePortalLink)
+			r.Get("/history", h.HistoryPanel)
+			r.Post("/history/undo", h.Undo)
+			r.Post("/history/redo", h.Redo)
+			r.Post("/history/{id}/revert", h.RevertEntry)
+			r.Get("/simulator", h.Simulator)
+			r.Get("/travel", h.TravelPage)
+			r.Post("/travel", h.CreateTravelEntry)
+			r.Delete("/travel/{id}", h.DeleteTravelEntry)
+		})
+
+		// Owners: delete and settings
+		r.Group(func(r chi.Router) {
+			r.Use(h.RequireRole(models.RoleOwner))
+			r.Delete("/projects/{id}", h.DeleteProject)
+
+			// Profiles of the running server: /debug/pprof/ and /debug/vars
+			r.Mount("/debug", middleware.Profiler())
+
+			r.Get("/admin", h.Admin)
+			r.Get("/reconciliation", h.ReconciliationPage)
+			r.Post("/admin/settings", h.UpdateSettings)
+			r.Post("/admin/agreements", h.CreateAgreement)
+			r.Post("/admin/periods", h.LockPeriod)
+			r.Delete("/admin/periods/{month}", h.UnlockPeriod)
+			r.Delete("/admin/agreements/{id}", h.DeleteAgreement)
+			r.Post("/admin/clients", h.SaveClient)
+			r.Delete("/admin/clients/{id}", h.DeleteClient)
+			r.Post("/admin/exchange-rates", h.SaveExchangeRate)
+			r.Post("/admin/exchange-rates/refresh", h.RefreshExchangeRates)
+			r.Delete("/admin/exchange-rates/{currency}", h.DeleteExchangeRate)
+			r.Post("/admin/goals", h.SaveGoal)
+			r.Delete("/admin/goals/{id}", h.DeleteGoal)
+			r.Post("/admin/people", h.CreatePerson)
+			r.Put("/admin/people/{id}", h.UpdatePerson)
+			r.Post("/admin/statuses", h.CreateStatus)
+			r.Put("/admin/statuses/{key}", h.UpdateStatus)
+			r.Delete("/admin/statuses/{key}", h.DeleteStatus)
+			r.Post("/admin/checklists", h.CreateChecklistTemplate)
+			r.Delete("/admin/checklists/{id}", h.DeleteChecklistTemplate)
+			r.Post("/admin/automations", h.CreateAutomationRule)
+			r.Put("/admin/automations/{id}", h.ToggleAutomationRule)
+			r.Delete("/admin/automations/{id}", h.DeleteAutomationRule)
+			r.Post("/admin/notifications", h.CreateNotificationRule)
+			r.Delete("/admin/notifications/{id}", h.DeleteNotificationRule)
+			r.Post("/admin/webhooks", h.CreateWebhook)
+			r.Get("/admin/webhooks/{id}", h.WebhookPage)
+			r.Put("/admin/webhooks/{id}", h.ToggleWebhook)
+			r.Delete("/admin/webhooks/{id}", h.DeleteWebhook)
+			r.Post("/admin/webhooks/deliveries/{id}/redeliver", h.RedeliverWebhook)
+			r.Post("/admin/integrity-check", h.RunIntegrityCheck)
+			r.Post("/admin/dr-test", h.RunDRTest)
+			r.Get("/admin/backup", h.DownloadBackup)
+			r.Get("/admin/backups", h.BackupsPage)
+			r.Post("/admin/backups/run", h.RunBackupNow)
+			r.Get("/admin/database/export", h.ExportDatabase)
+			r.Post("/admin/database/import", h.ImportDatabase)
+			r.Post("/admin/storage", h.RunStorageCheck)
+			r.Post("/admin/google-calendar/sync", h.RunGoogleCalendarSync)
+			r.Post("/admin/stripe/import", h.ImportStripeHistory)
+			r.Get("/admin/import", h.ImportPage)
+			r.Post("/admin/import/upload", h.UploadImport)
+			r.Post("/admin/import/check", h.CheckImport)
+			r.Post("/admin/import/run", h.RunImport)
+			r.Post("/admin/api-keys", h.CreateAPIKey)
+			r.Delete("/admin/api-keys/{id}", h.RevokeAPIKey)
+			r.Post("/admin/users", h.CreateUser)
+			r.Put("/admin/users/{id}/role", h.UpdateUserRole)
+			r.Delete("/admin/users/{id}", h.DeleteUser)
+		})
+	})
+
+	// Health
+	r.Get("/health", h.Health)
+	r.Get("/health/replication", h.ReplicationHealth)
+
 	addr := ":" + port
+	srv := &http.Server{Addr: addr, Handler: r}
+	srv.RegisterOnShutdown(stream.Close) // end SSE streams so Shutdown can finish
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	if pprofAddr := os.Getenv("PPROF_ADDR"); pprofAddr != "" {
+		if err := startProfiler(pprofAddr); err != nil {
+			log.Fatalf("Profiler: %v", err)
+		}
+	}
+
+	startChaos() // startup is done; injected failures from here on
 	log.Printf("FullDash on http://localhost%s", addr)
-	if err := http.ListenAndServe(addr, r); err != nil {
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Server error: %v", err)
 	}
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reporter.Close(flushCtx) // send the reports still queued
 }
 
 func getEnv(k, d string) string {
@@ -60,3 +447,120 @@ func getEnv(k, d string) string {
 	}
 	return d
 }
+
+func getEnvInt(k string, d int) int {
+	if v, err := strconv.Atoi(os.Getenv(k)); err == nil {
+		return v
+	}
+	return d
+}
+
+func getEnvDuration(k string, d time.Duration) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(k)); err == nil && v > 0 {
+		return v
+	}
+	return d
+}
+
+// backupRunner configures backups from the environment: the BACKUP_DIR
+// snapshots, kept by the BACKUP_KEEP* policy and copied to the S3 bucket
+// and WebDAV collection set up, on BACKUP_SCHEDULE
+func backupRunner(db *store.DB) (*backup.Runner, error) {
+	schedule, err := backup.ParseSchedule(getEnv("BACKUP_SCHEDULE", defaultBackupSchedule))
+	if err != nil {
+		return nil, err
+	}
+	r := &backup.Runner{
+		DB:  db,
+		Dir: getEnv("BACKUP_DIR", defaultBackupDir),
+		Keep: backup.Retention{
+			Last:    getEnvInt("BACKUP_KEEP", defaultBackupKeep),
+			Daily:   getEnvInt("BACKUP_KEEP_DAILY", 0),
+			Weekly:  getEnvInt("BACKUP_KEEP_WEEKLY", 0),
+			Monthly: getEnvInt("BACKUP_KEEP_MONTHLY", 0),
+		},
+		Schedule: schedule,
+	}
+	if bucket := os.Getenv("BACKUP_S3_BUCKET"); bucket != "" {
+		r.Remotes = append(r.Remotes, backup.NewS3(os.Getenv("BACKUP_S3_ENDPOINT"), os.Getenv("BACKUP_S3_REGION"), bucket,
+			os.Getenv("BACKUP_S3_PREFIX"), os.Getenv("BACKUP_S3_ACCESS_KEY"), os.Getenv("BACKUP_S3_SECRET_KEY")))
+	}
+	if url := os.Getenv("BACKUP_WEBDAV_URL"); url != "" {
+		r.Remotes = append(r.Remotes, backup.NewWebDAV(url, os.Getenv("BACKUP_WEBDAV_USER"), os.Getenv("BACKUP_WEBDAV_PASSWORD")))
+	}
+	return r, nil
+}
+
+// instanceID names this process when several instances share the database:
+// INSTANCE_ID, or hostname and pid
+func instanceID() string {
+	if v := os.Getenv("INSTANCE_ID"); v != "" {
+		return v
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// startProfiler serves /debug/pprof/ without login on addr, which must be
+// a loopback address, so go tool pprof can read it from the host itself
+func startProfiler(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("PPROF_ADDR: %w", err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("PPROF_ADDR must be a loopback address such as localhost:6060, not %q", addr)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	r := chi.NewRouter()
+	r.Mount("/debug", middleware.Profiler())
+	log.Printf("Profiling on http://%s/debug/pprof/", addr)
+	go http.Serve(ln, r)
+	return nil
+}
+
+// buildVersion names this build for error reports: the VCS revision Go
+// stamped into the binary, marked -dirty if built with local changes, or "dev"
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	var rev, dirty string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			rev = s.Value
+		case "vcs.modified":
+			if s.Value == "true" {
+				dirty = "-dirty"
+			}
+		}
+	}
+	if rev == "" {
+		if v := info.Main.Version; v != "" && v != "(devel)" {
+			return v
+		}
+		return "dev"
+	}
+	return rev[:min(12, len(rev))] + dirty
+}
+
+// loadPortalSecret returns PORTAL_SECRET, or a key generated once and kept
+// in settings so portal links survive restarts
+func loadPortalSecret(db *store.DB) ([]byte, error) {
+	if v := os.Getenv("PORTAL_SECRET"); v != "" {
+		return []byte(v), nil
+	}
+	v, err := db.GetSetting(models.SettingPortalSecret, "")
+	if err != nil || v != "" {
+		return []byte(v), err
+	}
+	if v, err = auth.NewPortalSecret(); err != nil {
+		return nil, err
+	}
+	return []byte(v), db.SetSetting(models.SettingPortalSecret, v)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/cache"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/ratelimit"
+	"github.com/noor-latif/fulldash/internal/replication"
+	"github.com/noor-latif/fulldash/internal/store"
+	"github.com/redis/go-redis/v9"
+)
+
+// openReplication reads how this instance replicates (REPLICATION,
+// READ_ONLY, REPLICATION_MAX_LAG)
+func openReplication(dbPath string) (replication.Config, error) {
+	c := replication.Config{
+		Mode:     os.Getenv("REPLICATION"),
+		ReadOnly: os.Getenv("READ_ONLY") != "",
+		DBPath:   dbPath,
+		MaxLag:   getEnvDuration("REPLICATION_MAX_LAG", replication.DefaultMaxLag),
+	}
+	return c, c.Validate()
+}
+
+// dbOptions reads the connection settings (DB_JOURNAL_MODE, DB_SYNCHRONOUS,
+// DB_BUSY_TIMEOUT, DB_MAX_OPEN_CONNS), store's defaults where unset, and
+// the query log (DB_LOG_QUERIES, DB_SLOW_QUERY)
+func dbOptions() store.Options {
+	return store.Options{
+		JournalMode:  os.Getenv("DB_JOURNAL_MODE"),
+		Synchronous:  os.Getenv("DB_SYNCHRONOUS"),
+		BusyTimeout:  getEnvDuration("DB_BUSY_TIMEOUT", 0),
+		MaxOpenConns: getEnvInt("DB_MAX_OPEN_CONNS", 0),
+		QueryLog: store.QueryLog{
+			All:  os.Getenv("DB_LOG_QUERIES") != "",
+			Slow: getEnvDuration("DB_SLOW_QUERY", 0),
+		},
+	}
+}
+
+// openStore opens DB_PATH for a command, with the server's connection settings
+func openStore(path string) (*store.DB, error) {
+	return store.Open(path, dbOptions())
+}
+
+// openDB opens the database with dbOptions, insisting on WAL mode under
+// replication and not migrating on a replica. When CHAOS_LATENCY or
+// CHAOS_ERROR_RATE is set (development only) store calls get failures
+// injected once startChaos is called; otherwise it does nothing.
+func openDB(path string, repl replication.Config) (db *store.DB, startChaos func(), err error) {
+	opts := dbOptions()
+	if repl.Enabled() && opts.JournalMode != "" && !strings.EqualFold(opts.JournalMode, "wal") {
+		return nil, nil, fmt.Errorf("REPLICATION needs DB_JOURNAL_MODE=wal, not %s", opts.JournalMode)
+	}
+	opts.QueryOnly, opts.SkipMigrations = repl.ReadOnly, repl.Replica()
+	rate, _ := strconv.ParseFloat(os.Getenv("CHAOS_ERROR_RATE"), 64)
+	chaos := store.Chaos{Latency: getEnvDuration("CHAOS_LATENCY", 0), ErrorRate: rate}
+	if !chaos.Enabled() {
+		db, err = store.Open(path, opts)
+		return db, func() {}, err
+	}
+
+	db, arm, err := store.NewChaos(path, opts, chaos)
+	return db, func() {
+		log.Printf("[CHAOS] Store calls get up to %s latency and fail %.0f%% of the time", chaos.Latency, chaos.ErrorRate*100)
+		arm()
+	}, err
+}
+
+// openState picks where sessions and cached values live (STATE_BACKEND):
+// "db" (default) shares them through the database, "redis" through
+// REDIS_URL, and "memory" keeps them in this process only. A replica
+// started read-only reads the primary's sessions but caches in memory.
+func openState(db *store.DB, replica bool) (auth.SessionStore, cache.Cache, error) {
+	switch backend := getEnv("STATE_BACKEND", "db"); backend {
+	case "db":
+		if replica {
+			return auth.NewDBSessions(db), cache.NewMemory(), nil
+		}
+		return auth.NewDBSessions(db), cache.NewDB(db), nil
+	case "memory":
+		return auth.NewMemorySessions(), cache.NewMemory(), nil
+	case "redis":
+		rdb, err := openRedis()
+		if err != nil {
+			return nil, nil, err
+		}
+		return auth.NewRedisSessions(rdb), cache.NewRedis(rdb), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown STATE_BACKEND %q (db, redis or memory)", backend)
+	}
+}
+
+// openLimiter picks where rate limit counters live (RATE_LIMIT_BACKEND):
+// "memory" (default, per instance) or "redis" (shared)
+func openLimiter() (ratelimit.Limiter, error) {
+	switch backend := getEnv("RATE_LIMIT_BACKEND", "memory"); backend {
+	case "memory":
+		return ratelimit.NewMemory(), nil
+	case "redis":
+		rdb, err := openRedis()
+		if err != nil {
+			return nil, err
+		}
+		return ratelimit.NewRedis(rdb), nil
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_BACKEND %q (memory or redis)", backend)
+	}
+}
+
+// openStream picks how live board events reach SSE clients
+// (EVENTS_BACKEND): "memory" (default) streams this instance's events,
+// "redis" relays every instance's events through Redis pub/sub
+func openStream(ctx context.Context, bus *events.Bus) (*events.Stream, error) {
+	switch backend := getEnv("EVENTS_BACKEND", "memory"); backend {
+	case "memory":
+		return events.NewStream(bus), nil
+	case "redis":
+		rdb, err := openRedis()
+		if err != nil {
+			return nil, err
+		}
+		return events.NewRedisStream(ctx, bus, rdb), nil
+	default:
+		return nil, fmt.Errorf("unknown EVENTS_BACKEND %q (memory or redis)", backend)
+	}
+}
+
+// sharedRedis is the client every Redis-backed piece uses, connected on
+// first use
+var sharedRedis *redis.Client
+
+// openRedis connects to REDIS_URL (e.g. redis://localhost:6379/0)
+func openRedis() (*redis.Client, error) {
+	if sharedRedis != nil {
+		return sharedRedis, nil
+	}
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		return nil, fmt.Errorf("REDIS_URL is required")
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("REDIS_URL: %w", err)
+	}
+	rdb := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	sharedRedis = rdb
+	return rdb, nil
+}
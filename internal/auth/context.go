@@ -0,0 +1,27 @@
+// auth/context.go - Request-scoped current user
+package auth
+
+import (
+	"context"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+type ctxKey struct{}
+
+// WithUser returns a context carrying the logged-in user
+func WithUser(ctx context.Context, u *models.User) context.Context {
+	return context.WithValue(ctx, ctxKey{}, u)
+}
+
+// UserFrom returns the logged-in user, or nil
+func UserFrom(ctx context.Context) *models.User {
+	u, _ := ctx.Value(ctxKey{}).(*models.User)
+	return u
+}
+
+// Can reports whether the current user holds at least the given role
+func Can(ctx context.Context, role models.Role) bool {
+	u := UserFrom(ctx)
+	return u != nil && u.Role.Allows(role)
+}
@@ -0,0 +1,58 @@
+// auth/redis.go - Redis-backed sessions
+package auth
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisSessionPrefix namespaces session keys in a shared Redis
+const redisSessionPrefix = "fulldash:session:"
+
+// RedisSessions keeps sessions in Redis, which expires them itself. Like
+// DBSessions, keys are token hashes.
+type RedisSessions struct {
+	rdb *redis.Client
+}
+
+// NewRedisSessions creates a Redis-backed session store
+func NewRedisSessions(rdb *redis.Client) *RedisSessions {
+	return &RedisSessions{rdb: rdb}
+}
+
+// Create starts a session for userID and returns its token
+func (s *RedisSessions) Create(userID int64) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	key := redisSessionPrefix + hashToken(token)
+	if err := s.rdb.Set(context.Background(), key, userID, SessionTTL).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Get returns the user for a live session; a Redis error counts as logged out
+func (s *RedisSessions) Get(token string) (int64, bool) {
+	v, err := s.rdb.Get(context.Background(), redisSessionPrefix+hashToken(token)).Result()
+	if err == redis.Nil {
+		return 0, false
+	}
+	if err != nil {
+		log.Printf("[AUTH] Session lookup failed: %v", err)
+		return 0, false
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	return id, err == nil
+}
+
+// Delete ends a session
+func (s *RedisSessions) Delete(token string) {
+	if err := s.rdb.Del(context.Background(), redisSessionPrefix+hashToken(token)).Err(); err != nil {
+		log.Printf("[AUTH] Session delete failed: %v", err)
+	}
+}
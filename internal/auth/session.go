@@ -0,0 +1,139 @@
+// auth/session.go - Browser sessions
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+)
+
+// SessionTTL is how long a login stays valid
+const SessionTTL = 30 * 24 * time.Hour
+
+// CookieName is the session cookie name
+const CookieName = "fulldash_session"
+
+// SessionStore maps opaque session tokens to user IDs
+type SessionStore interface {
+	Create(userID int64) (string, error)
+	Get(token string) (userID int64, ok bool)
+	Delete(token string)
+}
+
+type session struct {
+	userID  int64
+	expires time.Time
+}
+
+// MemorySessions is an in-process SessionStore; sessions are lost on restart
+type MemorySessions struct {
+	mu       sync.Mutex
+	sessions map[string]session
+}
+
+// NewMemorySessions creates an empty in-memory session store
+func NewMemorySessions() *MemorySessions {
+	return &MemorySessions{sessions: make(map[string]session)}
+}
+
+// Create starts a session for userID and returns its token
+func (m *MemorySessions) Create(userID int64) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[token] = session{userID: userID, expires: time.Now().Add(SessionTTL)}
+	return token, nil
+}
+
+// Get returns the user for a live session
+func (m *MemorySessions) Get(token string) (int64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[token]
+	if !ok {
+		return 0, false
+	}
+	if time.Now().After(s.expires) {
+		delete(m.sessions, token)
+		return 0, false
+	}
+	return s.userID, true
+}
+
+// Delete ends a session
+func (m *MemorySessions) Delete(token string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, token)
+}
+
+// SessionDB is the subset of the store DBSessions needs
+type SessionDB interface {
+	CreateSession(tokenHash string, userID int64, expires time.Time) error
+	SessionUser(tokenHash string) (int64, error)
+	DeleteSession(tokenHash string) error
+}
+
+// DBSessions keeps sessions in the database, so they survive restarts and
+// are shared by every instance. Only token hashes are stored.
+type DBSessions struct {
+	db SessionDB
+}
+
+// NewDBSessions creates a database-backed session store
+func NewDBSessions(db SessionDB) *DBSessions {
+	return &DBSessions{db: db}
+}
+
+// Create starts a session for userID and returns its token
+func (d *DBSessions) Create(userID int64) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	if err := d.db.CreateSession(hashToken(token), userID, time.Now().Add(SessionTTL)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Get returns the user for a live session; a database error counts as
+// logged out
+func (d *DBSessions) Get(token string) (int64, bool) {
+	id, err := d.db.SessionUser(hashToken(token))
+	if err != nil {
+		log.Printf("[AUTH] Session lookup failed: %v", err)
+		return 0, false
+	}
+	return id, id != 0
+}
+
+// Delete ends a session
+func (d *DBSessions) Delete(token string) {
+	if err := d.db.DeleteSession(hashToken(token)); err != nil {
+		log.Printf("[AUTH] Session delete failed: %v", err)
+	}
+}
+
+// hashToken returns the hex SHA-256 of a session token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newToken returns 32 random bytes, hex-encoded
+func newToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,259 @@
+// automation/engine.go - Per-status automation rules driven by the event bus
+package automation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/domain"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/service"
+)
+
+// JobName is the scheduler name of the delayed-run job
+const JobName = "automations"
+
+// ActionSetStatus moves the project to the status given as the rule's param
+const ActionSetStatus = "set_status"
+
+// maxChain bounds how many rules can trigger each other from one event,
+// so rules like "in_progress → done" and "done → in_progress" can't loop forever
+const maxChain = 3
+
+type chainKey struct{}
+
+// chainDepth returns how many rule applications led to the current event
+func chainDepth(ctx context.Context) int {
+	n, _ := ctx.Value(chainKey{}).(int)
+	return n
+}
+
+// Store is the data access the engine needs
+type Store interface {
+	GetProject(id int64) (*models.Project, error)
+	UpdateProject(p *models.Project) error
+	ListAutomationRulesFor(trigger models.AutomationTrigger) ([]models.AutomationRule, error)
+	GetAutomationRule(id int64) (*models.AutomationRule, error)
+	ScheduleAutomationRun(run *models.AutomationRun) error
+	DueAutomationRuns(now time.Time) ([]models.AutomationRun, error)
+	ClaimAutomationRun(id int64) (bool, error)
+	FinishAutomationRun(id int64, runErr string) error
+	LogActivity(a *models.Activity) error
+	ListStatuses() ([]models.Status, error)
+}
+
+// Action is something a rule can do to a project
+type Action struct {
+	Name  string
+	Label string // shown in the admin UI
+	Param string // label of the rule's param, "" if unused
+	Run   func(ctx context.Context, p *models.Project, param string) error
+}
+
+// Engine matches events to rules and runs (or queues) their actions
+type Engine struct {
+	db      Store
+	bus     *events.Bus
+	actions map[string]Action
+}
+
+// New creates an engine with the built-in actions registered
+func New(db Store, bus *events.Bus) *Engine {
+	e := &Engine{db: db, bus: bus, actions: make(map[string]Action)}
+	e.Register(Action{
+		Name:  ActionSetStatus,
+		Label: "Set status",
+		Param: "status",
+		Run:   e.setStatus,
+	})
+	return e
+}
+
+// Register adds an action that rules can refer to by name
+func (e *Engine) Register(a Action) {
+	e.actions[a.Name] = a
+}
+
+// Actions lists registered actions by name
+func (e *Engine) Actions() []Action {
+	out := make([]Action, 0, len(e.actions))
+	for _, a := range e.actions {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Validate checks a rule refers to a known trigger and action
+func (e *Engine) Validate(r *models.AutomationRule) error {
+	wf, err := e.workflow()
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(wf.Triggers(), r.Trigger) {
+		return fmt.Errorf("unknown trigger %q", r.Trigger)
+	}
+	if _, ok := e.actions[r.Action]; !ok {
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+	if r.Action == ActionSetStatus && !wf.Valid(models.ProjectStatus(r.Param)) {
+		return fmt.Errorf("invalid status %q", r.Param)
+	}
+	if r.Action == ActionSetStatus && models.ProjectStatus(r.Param) == models.StatusLost {
+		return errors.New("a project is only marked lost by hand, with its reason")
+	}
+	if r.DelayDays < 0 {
+		return errors.New("delay must not be negative")
+	}
+	return nil
+}
+
+// workflow loads the configured statuses
+func (e *Engine) workflow() (domain.Workflow, error) {
+	statuses, err := e.db.ListStatuses()
+	return domain.Workflow(statuses), err
+}
+
+// Subscribe attaches the engine to the bus
+func (e *Engine) Subscribe() {
+	e.bus.Subscribe(events.ProjectStatusChanged, e.onEvent)
+	e.bus.Subscribe(events.PaymentReceived, e.onEvent)
+}
+
+// onEvent runs immediate rules for the event's trigger and queues delayed ones
+func (e *Engine) onEvent(ctx context.Context, ev events.Event) error {
+	if chainDepth(ctx) >= maxChain {
+		return fmt.Errorf("automation chain deeper than %d, stopping", maxChain)
+	}
+
+	triggers := []models.AutomationTrigger{models.TriggerPayment}
+	if ev.Type == events.ProjectStatusChanged {
+		triggers = []models.AutomationTrigger{models.StatusTrigger(ev.To), models.TransitionTrigger(ev.From, ev.To)}
+	}
+
+	var rules []models.AutomationRule
+	for _, t := range triggers {
+		matched, err := e.db.ListAutomationRulesFor(t)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, matched...)
+	}
+
+	var errs []error
+	for _, r := range rules {
+		if r.DelayDays > 0 {
+			run := &models.AutomationRun{RuleID: r.ID, ProjectID: ev.ProjectID, RunAt: ev.At.AddDate(0, 0, r.DelayDays)}
+			errs = append(errs, e.db.ScheduleAutomationRun(run))
+			continue
+		}
+		errs = append(errs, e.apply(ctx, &r, ev.ProjectID))
+	}
+	return errors.Join(errs...)
+}
+
+// RunDue executes queued runs whose time has come; registered as a job
+func (e *Engine) RunDue(ctx context.Context) error {
+	runs, err := e.db.DueAutomationRuns(time.Now())
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, run := range runs {
+		// Another instance sharing the database may be working the queue
+		if ok, err := e.db.ClaimAutomationRun(run.ID); err != nil || !ok {
+			errs = append(errs, err)
+			continue
+		}
+		runErr := e.runQueued(ctx, run)
+		msg := ""
+		if runErr != nil {
+			msg = runErr.Error()
+			errs = append(errs, runErr)
+		}
+		if err := e.db.FinishAutomationRun(run.ID, msg); err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e *Engine) runQueued(ctx context.Context, run models.AutomationRun) error {
+	r, err := e.db.GetAutomationRule(run.RuleID)
+	if err != nil || r == nil || !r.Enabled {
+		return err
+	}
+	return e.apply(ctx, r, run.ProjectID)
+}
+
+// apply runs a rule's action on a project and records it in the activity feed
+func (e *Engine) apply(ctx context.Context, r *models.AutomationRule, projectID int64) error {
+	a, ok := e.actions[r.Action]
+	if !ok {
+		return fmt.Errorf("rule %d: unknown action %q", r.ID, r.Action)
+	}
+
+	p, err := e.db.GetProject(projectID)
+	if err != nil || p == nil {
+		return err
+	}
+
+	before := *p
+	ctx = context.WithValue(ctx, chainKey{}, chainDepth(ctx)+1)
+	if err := a.Run(ctx, p, r.Param); err != nil {
+		return fmt.Errorf("rule %d: %w", r.ID, err)
+	}
+
+	summary := fmt.Sprintf("rule #%d on %s: %s", r.ID, r.Trigger, a.Label)
+	if r.Param != "" {
+		summary += " " + r.Param
+	}
+	return e.db.LogActivity(&models.Activity{
+		ProjectID: p.ID,
+		Client:    p.Client,
+		Action:    "automation",
+		Summary:   summary,
+		Actor:     fmt.Sprintf("rule #%d", r.ID),
+		Source:    events.SourceAutomation,
+		Changes:   service.Diff(service.Snapshot(&before, nil), service.Snapshot(p, nil)),
+	})
+}
+
+// setStatus is the ActionSetStatus implementation
+func (e *Engine) setStatus(ctx context.Context, p *models.Project, param string) error {
+	to := models.ProjectStatus(param)
+	if p.Status == to {
+		return nil
+	}
+	wf, err := e.workflow()
+	if err != nil {
+		return err
+	}
+	// Rules never issue refunds, so they can't reopen paid projects
+	if err := wf.CheckTransition(p.Status, to, false); err != nil {
+		return err
+	}
+
+	from := p.Status
+	p.Status = to
+	p.LostReason = "" // rules never move to lost, so a lost project is reopened
+	if err := e.db.UpdateProject(p); err != nil {
+		return err
+	}
+	e.bus.Publish(ctx, events.Event{
+		Type:        events.ProjectStatusChanged,
+		ProjectID:   p.ID,
+		Client:      p.Client,
+		AmountCents: p.RevenueCents,
+		From:        from,
+		To:          to,
+		Source:      events.SourceAutomation,
+	})
+	return nil
+}
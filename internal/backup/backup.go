@@ -0,0 +1,217 @@
+// backup/backup.go - Online snapshots with rotation, backup discovery and disaster recovery dry runs
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/store"
+)
+
+// ErrNoBackups is returned when the backup directory holds no snapshots
+var ErrNoBackups = errors.New("no backups found")
+
+// Snapshotter writes a consistent copy of the live database to a new file
+// (store.DB.Snapshot)
+type Snapshotter interface {
+	Snapshot(path string) error
+}
+
+// Snapshots made by Create are named fulldash-<UTC time>.db, so they sort
+// by age and Rotate leaves other files alone
+const (
+	snapshotPrefix = "fulldash-"
+	snapshotLayout = "20060102-150405"
+)
+
+// Create snapshots db into dir, then deletes the snapshots keep doesn't
+// retain. The copy is written under a temporary name and renamed, so
+// Latest never picks up a half-written file.
+func Create(db Snapshotter, dir string, keep Retention) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, snapshotName(time.Now()))
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("%s already exists; snapshots are at most one a second", filepath.Base(path))
+	}
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	if err := db.Snapshot(tmp); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if _, err := Rotate(dir, keep); err != nil {
+		return path, fmt.Errorf("rotate: %w", err)
+	}
+	return path, nil
+}
+
+// Rotate deletes the snapshots in dir that keep doesn't retain and returns
+// the paths deleted
+func Rotate(dir string, keep Retention) ([]string, error) {
+	if keep.KeepsAll() {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	var deleted []string
+	for _, name := range keep.Prune(names) {
+		path := filepath.Join(dir, name)
+		if err := os.Remove(path); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, path)
+	}
+	return deleted, nil
+}
+
+// Latest returns the path of the most recently modified *.db file in dir
+func Latest(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNoBackups
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var latest string
+	var latestMod int64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".db") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return "", err
+		}
+		if mod := info.ModTime().UnixNano(); latest == "" || mod > latestMod {
+			latest, latestMod = filepath.Join(dir, e.Name()), mod
+		}
+	}
+
+	if latest == "" {
+		return "", ErrNoBackups
+	}
+	return latest, nil
+}
+
+// DryRun restores the latest backup in dir into a temp location, runs
+// migrations and integrity checks against it, and reports whether recovery
+// would succeed. The live database is never touched.
+func DryRun(dir string) *models.DRTest {
+	result, step, finish := newCheck()
+
+	src, err := Latest(dir)
+	if err != nil {
+		step("locate backup in %s: %v", dir, err)
+		return finish(false)
+	}
+	result.BackupFile = src
+	step("backup: %s", src)
+
+	tmpDir, err := os.MkdirTemp("", "fulldash-dr-")
+	if err != nil {
+		step("create temp dir: %v", err)
+		return finish(false)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	restored := filepath.Join(tmpDir, "restore.db")
+	if err := copyFile(src, restored); err != nil {
+		step("restore copy: %v", err)
+		return finish(false)
+	}
+	step("restored to temp location")
+
+	return finish(verify(restored, step))
+}
+
+// newCheck starts a recovery check: step logs a line, and finish records
+// the outcome and the log
+func newCheck() (result *models.DRTest, step func(format string, args ...any), finish func(ok bool) *models.DRTest) {
+	result = &models.DRTest{}
+	var log []string
+	step = func(format string, args ...any) {
+		log = append(log, fmt.Sprintf(format, args...))
+	}
+	finish = func(ok bool) *models.DRTest {
+		result.OK = ok
+		result.Details = strings.Join(log, "\n")
+		return result
+	}
+	return result, step, finish
+}
+
+// verify opens the database at path, migrating it, and checks its
+// integrity and that its projects can be read
+func verify(path string, step func(format string, args ...any)) bool {
+	db, err := store.New(path)
+	if err != nil {
+		step("open + migrate: %v", err)
+		return false
+	}
+	defer db.Close()
+	step("migrations: ok")
+
+	check, err := db.CheckIntegrity()
+	if err != nil {
+		step("integrity check: %v", err)
+		return false
+	}
+	if !check.OK {
+		step("integrity check failed:\n%s", check.Details)
+		return false
+	}
+	step("integrity: ok")
+
+	n, err := db.CountProjects()
+	if err != nil {
+		step("count projects: %v", err)
+		return false
+	}
+	step("projects: %d", n)
+	return true
+}
+
+// copyFile copies src to dst, syncing dst before returning
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,374 @@
+// backup/remote.go - Remote backup destinations: S3-compatible buckets and WebDAV
+package backup
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// uploadTimeout bounds one request to a remote, an upload included
+const uploadTimeout = 10 * time.Minute
+
+// Remote is a place snapshots are copied to after they are taken
+type Remote interface {
+	Name() string // shown on the status page, e.g. "s3 backups/fulldash"
+	Put(ctx context.Context, name, file string) error
+	List(ctx context.Context) ([]string, error) // snapshot names
+	Delete(ctx context.Context, name string) error
+}
+
+// statusError turns a non-2xx response into an error with the start of
+// its body, on one line
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("%s %s: %s: %s", resp.Request.Method, resp.Request.URL.Redacted(), resp.Status, strings.Join(strings.Fields(string(body)), " "))
+}
+
+// S3 stores snapshots in an S3-compatible bucket (AWS, MinIO, Backblaze,
+// Cloudflare R2...) under Prefix, addressed path-style and signed with
+// Signature Version 4
+type S3 struct {
+	Endpoint  string // e.g. https://s3.eu-north-1.amazonaws.com
+	Region    string
+	Bucket    string
+	Prefix    string // a "directory" in the bucket, e.g. "fulldash/"
+	AccessKey string
+	SecretKey string
+	HTTP      *http.Client
+}
+
+// NewS3 returns a bucket destination. Region defaults to us-east-1, which
+// most S3-compatible services accept, and endpoint to AWS's for the region.
+func NewS3(endpoint, region, bucket, prefix, accessKey, secretKey string) *S3 {
+	if region == "" {
+		region = "us-east-1"
+	}
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	return &S3{
+		Endpoint:  endpoint,
+		Region:    region,
+		Bucket:    bucket,
+		Prefix:    prefix,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		HTTP:      &http.Client{Timeout: uploadTimeout},
+	}
+}
+
+func (s *S3) Name() string {
+	return "s3 " + strings.TrimSuffix(path.Join(s.Bucket, s.Prefix), "/")
+}
+
+// Put uploads a snapshot in one request
+func (s *S3) Put(ctx context.Context, name, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := s.request(ctx, http.MethodPut, s.key(name), nil, hex.EncodeToString(h.Sum(nil)), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/vnd.sqlite3")
+	return s.do(req, nil)
+}
+
+// List pages through the objects under Prefix
+func (s *S3) List(ctx context.Context) ([]string, error) {
+	var names []string
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {s.prefix()}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		req, err := s.request(ctx, http.MethodGet, "", q, emptySHA256, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := s.do(req, &page); err != nil {
+			return nil, err
+		}
+		for _, c := range page.Contents {
+			if name := strings.TrimPrefix(c.Key, s.prefix()); !strings.Contains(name, "/") {
+				names = append(names, name)
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return names, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// Delete removes a snapshot
+func (s *S3) Delete(ctx context.Context, name string) error {
+	req, err := s.request(ctx, http.MethodDelete, s.key(name), nil, emptySHA256, nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req, nil)
+}
+
+// prefix is Prefix as a directory: "" or ending in a slash
+func (s *S3) prefix() string {
+	p := strings.Trim(s.Prefix, "/")
+	if p == "" {
+		return ""
+	}
+	return p + "/"
+}
+
+func (s *S3) key(name string) string {
+	return s.prefix() + name
+}
+
+// emptySHA256 is the payload hash of a request without a body
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// request builds a signed request for key in the bucket ("" for the
+// bucket itself)
+func (s *S3) request(ctx context.Context, method, key string, query url.Values, payloadHash string, body io.Reader) (*http.Request, error) {
+	base, err := url.Parse(strings.TrimRight(s.Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("s3 endpoint: %w", err)
+	}
+	escapedPath := "/" + s3Escape(s.Bucket, false)
+	if key != "" {
+		escapedPath += "/" + s3Escape(key, true)
+	}
+	u := *base
+	u.Path = base.Path + escapedPath
+	u.RawPath = base.EscapedPath() + escapedPath
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, payloadHash, time.Now().UTC())
+	return req, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	stamp := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" + "x-amz-content-sha256:" + payloadHash + "\n" + "x-amz-date:" + stamp + "\n",
+		strings.Join(signed, ";"),
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + s.Region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), day)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, strings.Join(signed, ";"), signature))
+}
+
+// do sends a request, decoding an XML reply into out when given
+func (s *S3) do(req *http.Request, out any) error {
+	resp, err := s.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return statusError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	return xml.NewDecoder(resp.Body).Decode(out)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+// s3Escape percent-encodes all but the unreserved characters, as SigV4
+// wants, leaving slashes in keys alone
+func s3Escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery sorts and encodes query parameters as SigV4 wants
+func canonicalQuery(q url.Values) string {
+	var pairs []string
+	for k, vs := range q {
+		for _, v := range vs {
+			pairs = append(pairs, s3Escape(k, false)+"="+s3Escape(v, false))
+		}
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// WebDAV stores snapshots in a WebDAV collection (Nextcloud, ownCloud, a
+// NAS...), which must exist, with basic auth
+type WebDAV struct {
+	URL      string // the collection, e.g. https://cloud.example.com/remote.php/dav/files/noor/backups
+	User     string
+	Password string
+	HTTP     *http.Client
+}
+
+// NewWebDAV returns a WebDAV destination
+func NewWebDAV(url, user, password string) *WebDAV {
+	return &WebDAV{URL: url, User: user, Password: password, HTTP: &http.Client{Timeout: uploadTimeout}}
+}
+
+func (d *WebDAV) Name() string {
+	u, err := url.Parse(d.URL)
+	if err != nil {
+		return "webdav"
+	}
+	return "webdav " + u.Host + strings.TrimRight(u.Path, "/")
+}
+
+// Put uploads a snapshot
+func (d *WebDAV) Put(ctx context.Context, name, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	req, err := d.request(ctx, http.MethodPut, name, f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/vnd.sqlite3")
+	return d.do(req, nil)
+}
+
+// List reads the collection's members with a PROPFIND one level deep
+func (d *WebDAV) List(ctx context.Context) ([]string, error) {
+	req, err := d.request(ctx, "PROPFIND", "", strings.NewReader(
+		`<?xml version="1.0" encoding="utf-8"?><d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/></d:prop></d:propfind>`))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", "1")
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	var ms struct {
+		Responses []struct {
+			Href string `xml:"href"`
+		} `xml:"response"`
+	}
+	if err := d.do(req, &ms); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, r := range ms.Responses {
+		href, err := url.PathUnescape(r.Href)
+		if err != nil || strings.HasSuffix(href, "/") { // the collection itself, or a sub-collection
+			continue
+		}
+		names = append(names, path.Base(href))
+	}
+	return names, nil
+}
+
+// Delete removes a snapshot
+func (d *WebDAV) Delete(ctx context.Context, name string) error {
+	req, err := d.request(ctx, http.MethodDelete, name, nil)
+	if err != nil {
+		return err
+	}
+	return d.do(req, nil)
+}
+
+func (d *WebDAV) request(ctx context.Context, method, name string, body io.Reader) (*http.Request, error) {
+	target := strings.TrimRight(d.URL, "/") + "/"
+	if name != "" {
+		target += url.PathEscape(name)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if d.User != "" {
+		req.SetBasicAuth(d.User, d.Password)
+	}
+	return req, nil
+}
+
+// do sends a request, decoding an XML reply into out when given
+func (d *WebDAV) do(req *http.Request, out any) error {
+	resp, err := d.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return statusError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	return xml.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,92 @@
+// backup/restore.go - Restoring a snapshot over the live database
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// Find returns the newest snapshot in dir taken at or before at, or the
+// newest of all when at is zero
+func Find(dir string, at time.Time) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNoBackups
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var found string
+	var foundAt time.Time
+	for _, e := range entries {
+		t, ok := snapshotTime(e.Name())
+		if !ok || e.IsDir() || (!at.IsZero() && t.After(at)) {
+			continue
+		}
+		if found == "" || t.After(foundAt) {
+			found, foundAt = e.Name(), t
+		}
+	}
+	if found == "" {
+		if at.IsZero() {
+			return "", ErrNoBackups
+		}
+		return "", fmt.Errorf("%w at or before %s", ErrNoBackups, at.Format(time.RFC3339))
+	}
+	return filepath.Join(dir, found), nil
+}
+
+// TakenAt reads when the snapshot at path was taken from its name; ok is
+// false for a file not named like a snapshot
+func TakenAt(path string) (t time.Time, ok bool) {
+	return snapshotTime(filepath.Base(path))
+}
+
+// Prepare copies the snapshot src beside dbPath, then migrates and checks
+// the copy as a dry run would. On success the copy's path is returned for
+// Swap; on failure it is removed. The live database is not touched.
+func Prepare(src, dbPath string) (string, *models.DRTest) {
+	result, step, finish := newCheck()
+	result.BackupFile = src
+	step("backup: %s", src)
+
+	prepared := dbPath + ".restore"
+	removeDB(prepared)
+	if err := copyFile(src, prepared); err != nil {
+		removeDB(prepared)
+		step("copy: %v", err)
+		return "", finish(false)
+	}
+	step("copied to %s", prepared)
+
+	if !verify(prepared, step) {
+		removeDB(prepared)
+		return "", finish(false)
+	}
+	return prepared, finish(true)
+}
+
+// Swap replaces the database at dbPath with a prepared copy. Nothing may
+// have the database open: hold the maintenance lock, and snapshot the
+// current database first, since its WAL is discarded.
+func Swap(prepared, dbPath string) error {
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(prepared, dbPath)
+}
+
+// removeDB deletes a database file with its WAL and shared memory
+func removeDB(path string) {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		os.Remove(path + suffix)
+	}
+}
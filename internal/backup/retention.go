@@ -0,0 +1,120 @@
+// backup/retention.go - Which snapshots to keep: the latest few, then one per day, week and month
+package backup
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retention decides which snapshots survive a backup. Last keeps the
+// newest; Daily, Weekly and Monthly each keep the newest snapshot of that
+// many days, ISO weeks and months, counting back from the newest. A
+// snapshot kept by any rule stays. The zero Retention keeps everything.
+type Retention struct {
+	Last    int
+	Daily   int
+	Weekly  int
+	Monthly int
+}
+
+// KeepsAll is true when no rule is set
+func (k Retention) KeepsAll() bool {
+	return k.Last <= 0 && k.Daily <= 0 && k.Weekly <= 0 && k.Monthly <= 0
+}
+
+// String describes the policy for the status page, e.g. "last 14, 8 weekly"
+func (k Retention) String() string {
+	if k.KeepsAll() {
+		return "all kept"
+	}
+	var parts []string
+	add := func(n int, label string) {
+		if n > 0 {
+			parts = append(parts, strconv.Itoa(n)+" "+label)
+		}
+	}
+	if k.Last > 0 {
+		parts = append(parts, "last "+strconv.Itoa(k.Last))
+	}
+	add(k.Daily, "daily")
+	add(k.Weekly, "weekly")
+	add(k.Monthly, "monthly")
+	return strings.Join(parts, ", ")
+}
+
+// Prune returns the snapshot names (fulldash-<UTC time>.db) the policy
+// deletes. Names that aren't snapshots are never deleted.
+func (k Retention) Prune(names []string) []string {
+	if k.KeepsAll() {
+		return nil
+	}
+	type snapshot struct {
+		name string
+		at   time.Time
+	}
+	var snaps []snapshot
+	for _, name := range names {
+		if at, ok := snapshotTime(name); ok {
+			snaps = append(snaps, snapshot{name, at})
+		}
+	}
+	slices.SortFunc(snaps, func(a, b snapshot) int { return b.at.Compare(a.at) }) // newest first
+
+	keep := make([]bool, len(snaps))
+	for i := range min(k.Last, len(snaps)) {
+		keep[i] = true
+	}
+	// bucket keeps the newest snapshot in each of the first n periods
+	bucket := func(n int, period func(time.Time) string) {
+		if n <= 0 {
+			return
+		}
+		seen := map[string]bool{}
+		for i, s := range snaps {
+			p := period(s.at)
+			if seen[p] {
+				continue
+			}
+			if len(seen) == n {
+				return
+			}
+			seen[p] = true
+			keep[i] = true
+		}
+	}
+	bucket(k.Daily, func(t time.Time) string { return t.Format("2006-01-02") })
+	bucket(k.Weekly, func(t time.Time) string {
+		y, w := t.ISOWeek()
+		return strconv.Itoa(y) + "-W" + strconv.Itoa(w)
+	})
+	bucket(k.Monthly, func(t time.Time) string { return t.Format("2006-01") })
+
+	var out []string
+	for i, s := range snaps {
+		if !keep[i] {
+			out = append(out, s.name)
+		}
+	}
+	return out
+}
+
+// snapshotName names a snapshot taken at t
+func snapshotName(t time.Time) string {
+	return snapshotPrefix + t.UTC().Format(snapshotLayout) + ".db"
+}
+
+// snapshotTime reads the time back out of a snapshot's name
+func snapshotTime(name string) (time.Time, bool) {
+	stamp, ok := strings.CutPrefix(name, snapshotPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	stamp, ok = strings.CutSuffix(stamp, ".db")
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(snapshotLayout, stamp)
+	return t, err == nil
+}
@@ -0,0 +1,153 @@
+// backup/runner.go - Scheduled backups: a local snapshot, copied to each remote, pruned and logged
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// LocalDestination names the backup directory in the run log
+const LocalDestination = "local"
+
+// Store is what a Runner needs from the database (store.DB)
+type Store interface {
+	Snapshotter
+	RecordBackupRun(r *models.BackupRun) error
+	ListBackupRuns(limit int) ([]models.BackupRun, error)
+}
+
+// Runner takes backups: a snapshot into Dir, then a copy to each remote,
+// with Keep applied everywhere and every destination's outcome logged for
+// the status page
+type Runner struct {
+	DB       Store
+	Dir      string
+	Keep     Retention
+	Schedule Schedule
+	Remotes  []Remote
+
+	mu sync.Mutex // one backup at a time
+}
+
+// Due reports whether the schedule has come round since the latest backup
+func (r *Runner) Due(now time.Time) (bool, error) {
+	runs, err := r.DB.ListBackupRuns(1)
+	if err != nil {
+		return false, err
+	}
+	var last time.Time
+	if len(runs) > 0 {
+		last = runs[0].StartedAt
+	}
+	return r.Schedule.Due(last, now), nil
+}
+
+// Local snapshots into Dir and rotates it, logging the outcome
+func (r *Runner) Local(trigger string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.local(trigger)
+}
+
+// Run takes a local snapshot and uploads it to every remote. One remote
+// failing doesn't stop the others; the errors are returned together.
+func (r *Runner) Run(ctx context.Context, trigger string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path, err := r.local(trigger)
+	if path == "" {
+		return "", err
+	}
+	errs := []error{err}
+	for _, remote := range r.Remotes {
+		errs = append(errs, r.upload(ctx, remote, path, trigger))
+	}
+	return path, errors.Join(errs...)
+}
+
+func (r *Runner) local(trigger string) (string, error) {
+	run := &models.BackupRun{Destination: LocalDestination, Trigger: trigger, StartedAt: time.Now()}
+	path, err := Create(r.DB, r.Dir, Retention{}) // rotated below, to log what goes
+	if err != nil {
+		run.Details = err.Error()
+		return "", r.record(run, err)
+	}
+	run.File, run.OK = filepath.Base(path), true
+	if info, err := os.Stat(path); err == nil {
+		run.SizeBytes = info.Size()
+	}
+
+	pruned, err := Rotate(r.Dir, r.Keep)
+	if err != nil {
+		run.Details = "rotate: " + err.Error()
+		err = fmt.Errorf("rotate: %w", err)
+	} else if len(pruned) > 0 {
+		names := make([]string, len(pruned))
+		for i, p := range pruned {
+			names[i] = filepath.Base(p)
+		}
+		run.Details = "pruned " + strings.Join(names, ", ")
+	}
+	return path, r.record(run, err)
+}
+
+// upload copies a snapshot to remote, then prunes it to Keep
+func (r *Runner) upload(ctx context.Context, remote Remote, path, trigger string) error {
+	name := filepath.Base(path)
+	run := &models.BackupRun{Destination: remote.Name(), File: name, Trigger: trigger, StartedAt: time.Now()}
+	if info, err := os.Stat(path); err == nil {
+		run.SizeBytes = info.Size()
+	}
+	if err := remote.Put(ctx, name, path); err != nil {
+		run.Details = err.Error()
+		return r.record(run, fmt.Errorf("%s: %w", remote.Name(), err))
+	}
+	run.OK = true
+
+	pruned, err := prune(ctx, remote, r.Keep)
+	switch {
+	case err != nil: // uploaded all the same
+		run.Details = "prune: " + err.Error()
+		err = fmt.Errorf("%s: prune: %w", remote.Name(), err)
+	case len(pruned) > 0:
+		run.Details = "pruned " + strings.Join(pruned, ", ")
+	}
+	return r.record(run, err)
+}
+
+// prune deletes the snapshots on remote that keep doesn't retain
+func prune(ctx context.Context, remote Remote, keep Retention) ([]string, error) {
+	if keep.KeepsAll() {
+		return nil, nil
+	}
+	names, err := remote.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var deleted []string
+	for _, name := range keep.Prune(names) {
+		if err := remote.Delete(ctx, name); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, name)
+	}
+	return deleted, nil
+}
+
+// record logs a run, passing err through
+func (r *Runner) record(run *models.BackupRun, err error) error {
+	run.FinishedAt = time.Now()
+	if rerr := r.DB.RecordBackupRun(run); rerr != nil {
+		return errors.Join(err, fmt.Errorf("record backup run: %w", rerr))
+	}
+	return err
+}
@@ -0,0 +1,157 @@
+// backup/schedule.go - Cron-style schedules for automatic backups
+package backup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression: minute, hour, day of month, month
+// and day of week, each a set of allowed values, in local time
+type Schedule struct {
+	expr                     string
+	minute, hour, dom, month uint64
+	dow                      uint64
+	domAny, dowAny           bool
+}
+
+// shorthands are the cron macros accepted besides five fields
+var shorthands = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// ParseSchedule reads a cron expression such as "0 3 * * *" (03:00 every
+// day) or "30 2 * * 1-5". Fields take *, numbers, ranges (1-5), steps
+// (*/15, 0-30/10) and lists of them; day of week runs 0-7, Sunday being 0
+// or 7. As in cron, when both day fields are set either may match.
+func ParseSchedule(expr string) (Schedule, error) {
+	s := Schedule{expr: strings.TrimSpace(expr)}
+	fields := strings.Fields(s.expr)
+	if len(fields) == 1 {
+		if full, ok := shorthands[fields[0]]; ok {
+			fields = strings.Fields(full)
+		}
+	}
+	if len(fields) != 5 {
+		return s, fmt.Errorf("schedule %q: want five fields (minute hour day month weekday)", expr)
+	}
+
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return s, fmt.Errorf("schedule %q: minute: %w", expr, err)
+	}
+	if s.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return s, fmt.Errorf("schedule %q: hour: %w", expr, err)
+	}
+	if s.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return s, fmt.Errorf("schedule %q: day of month: %w", expr, err)
+	}
+	if s.month, err = parseField(fields[3], 1, 12); err != nil {
+		return s, fmt.Errorf("schedule %q: month: %w", expr, err)
+	}
+	if s.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return s, fmt.Errorf("schedule %q: day of week: %w", expr, err)
+	}
+	if s.dow&(1<<7) != 0 { // 7 is Sunday too
+		s.dow |= 1
+	}
+	s.domAny, s.dowAny = strings.HasPrefix(fields[2], "*"), strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// parseField reads one field into a bit set of the values it allows
+func parseField(field string, lo, hi int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		span, stepText, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+			step = n
+		}
+
+		from, to := lo, hi
+		switch a, b, isRange := strings.Cut(span, "-"); {
+		case span == "*":
+		case isRange:
+			var err1, err2 error
+			from, err1 = strconv.Atoi(a)
+			to, err2 = strconv.Atoi(b)
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", span)
+			}
+		default:
+			n, err := strconv.Atoi(span)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", span)
+			}
+			from, to = n, n
+			if stepped {
+				to = hi // "5/15" is 5, 20, 35, 50
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return 0, fmt.Errorf("%q is outside %d-%d", span, lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// String is the expression as given
+func (s Schedule) String() string {
+	return s.expr
+}
+
+// Next returns the first minute after t that the schedule allows, or the
+// zero time if none comes within five years (such as 30 February)
+func (s Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches applies cron's rule for the two day fields: with both
+// restricted, either matching is enough
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if !s.domAny && !s.dowAny {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// Due reports whether a scheduled time has come since last, the start of
+// the latest run; never having run is due at once
+func (s Schedule) Due(last, now time.Time) bool {
+	if last.IsZero() {
+		return true
+	}
+	next := s.Next(last)
+	return !next.IsZero() && !next.After(now)
+}
@@ -0,0 +1,104 @@
+// cache/cache.go - Expiring key/value cache shared by handlers
+package cache
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Cache stores derived values for a while. A miss, expiry or backend error
+// all read as absent: callers recompute and Set again.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+	Delete(key string)
+}
+
+type entry struct {
+	value   []byte
+	expires time.Time
+}
+
+// Memory is an in-process Cache; each instance has its own and it is
+// empty after a restart
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+// NewMemory creates an empty in-memory cache
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]entry)}
+}
+
+// Get returns a live cached value
+func (m *Memory) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(m.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Set stores value until ttl from now
+func (m *Memory) Set(key string, value []byte, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = entry{value: value, expires: time.Now().Add(ttl)}
+}
+
+// Delete drops a cached value
+func (m *Memory) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+}
+
+// Store is the subset of the store DB needs
+type Store interface {
+	CacheGet(key string) ([]byte, error)
+	CacheSet(key string, value []byte, ttl time.Duration) error
+	CacheDelete(key string) error
+}
+
+// DB is a Cache kept in the database, shared by every instance
+type DB struct {
+	store Store
+}
+
+// NewDB creates a database-backed cache
+func NewDB(store Store) *DB {
+	return &DB{store: store}
+}
+
+// Get returns a live cached value
+func (d *DB) Get(key string) ([]byte, bool) {
+	v, err := d.store.CacheGet(key)
+	if err != nil {
+		log.Printf("[CACHE] Get %s failed: %v", key, err)
+		return nil, false
+	}
+	return v, v != nil
+}
+
+// Set stores value until ttl from now
+func (d *DB) Set(key string, value []byte, ttl time.Duration) {
+	if err := d.store.CacheSet(key, value, ttl); err != nil {
+		log.Printf("[CACHE] Set %s failed: %v", key, err)
+	}
+}
+
+// Delete drops a cached value
+func (d *DB) Delete(key string) {
+	if err := d.store.CacheDelete(key); err != nil {
+		log.Printf("[CACHE] Delete %s failed: %v", key, err)
+	}
+}
@@ -0,0 +1,50 @@
+// cache/redis.go - Redis-backed cache
+package cache
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPrefix namespaces cache keys in a shared Redis
+const redisPrefix = "fulldash:cache:"
+
+// Redis is a Cache kept in Redis, shared by every instance
+type Redis struct {
+	rdb *redis.Client
+}
+
+// NewRedis creates a Redis-backed cache
+func NewRedis(rdb *redis.Client) *Redis {
+	return &Redis{rdb: rdb}
+}
+
+// Get returns a live cached value
+func (r *Redis) Get(key string) ([]byte, bool) {
+	v, err := r.rdb.Get(context.Background(), redisPrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("[CACHE] Get %s failed: %v", key, err)
+		return nil, false
+	}
+	return v, true
+}
+
+// Set stores value until ttl from now
+func (r *Redis) Set(key string, value []byte, ttl time.Duration) {
+	if err := r.rdb.Set(context.Background(), redisPrefix+key, value, ttl).Err(); err != nil {
+		log.Printf("[CACHE] Set %s failed: %v", key, err)
+	}
+}
+
+// Delete drops a cached value
+func (r *Redis) Delete(key string) {
+	if err := r.rdb.Del(context.Background(), redisPrefix+key).Err(); err != nil {
+		log.Printf("[CACHE] Delete %s failed: %v", key, err)
+	}
+}
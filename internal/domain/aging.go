@@ -0,0 +1,71 @@
+// domain/aging.go - Accounts receivable aging
+package domain
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// Aging sorts what clients owe as of now into buckets by days overdue:
+// issued invoices in kronor, due on their due date, and projects waiting in
+// the status they're paid from without an issued or paid invoice, due
+// their payment terms after entering it. Foreign invoices are left out, as
+// their totals aren't in kronor.
+func (wf Workflow) Aging(projects []models.Project, invoices []models.Invoice, now time.Time) models.Aging {
+	var a models.Aging
+	for i, limit := range models.AgingLimits {
+		b := models.AgingBucket{MinDays: 1, MaxDays: limit}
+		if i > 0 {
+			b.MinDays = models.AgingLimits[i-1] + 1
+		}
+		a.Buckets = append(a.Buckets, b)
+	}
+	a.Buckets = append(a.Buckets, models.AgingBucket{MinDays: models.AgingLimits[len(models.AgingLimits)-1] + 1})
+	today := date(now)
+
+	invoiced := map[int64]bool{}
+	for _, inv := range invoices {
+		if inv.Status != models.InvoiceIssued && inv.Status != models.InvoicePaid {
+			continue
+		}
+		invoiced[inv.ProjectID] = true
+		if inv.Status != models.InvoiceIssued || inv.Currency != "" {
+			continue
+		}
+		a.Add(models.Receivable{
+			ProjectID:   inv.ProjectID,
+			InvoiceID:   inv.ID,
+			Client:      inv.Client,
+			Label:       inv.Label(),
+			AmountCents: inv.TotalCents(),
+			DueOn:       date(inv.DueDate),
+		}, today)
+	}
+
+	awaiting := wf.feeder(models.StatusPaid)
+	for _, p := range projects {
+		if p.Status != awaiting || p.Archived() || invoiced[p.ID] || p.GrossCents() <= 0 {
+			continue
+		}
+		a.Add(models.Receivable{
+			ProjectID:   p.ID,
+			Client:      p.Client,
+			Label:       p.Description,
+			AmountCents: p.GrossCents(),
+			DueOn:       date(p.WaitingSince()).AddDate(0, 0, p.PaymentDays),
+		}, today)
+	}
+
+	slices.SortStableFunc(a.Receivables, func(x, y models.Receivable) int {
+		return cmp.Or(x.DueOn.Compare(y.DueOn), cmp.Compare(x.Client, y.Client))
+	})
+	return a
+}
+
+// date is t's calendar day, at midnight UTC
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,53 @@
+// domain/forecast.go - The open pipeline weighted by each project's chance of payment
+package domain
+
+import (
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// Probability is the chance in percent that a project gets paid: its own
+// if it sets one, otherwise its status's
+func (wf Workflow) Probability(p models.Project) int {
+	if p.Probability != nil {
+		return *p.Probability
+	}
+	if s, ok := wf.Get(p.Status); ok {
+		return s.Probability
+	}
+	return 0
+}
+
+// Forecast weighs the revenue of the projects in open statuses. Each counts
+// in every horizon its expected date falls within, an expected date already
+// passed counting as due today; projects without one count in the total only.
+func (wf Workflow) Forecast(projects []models.Project, now time.Time) models.Forecast {
+	var f models.Forecast
+	for _, days := range models.ForecastHorizons {
+		f.Horizons = append(f.Horizons, models.ForecastHorizon{Days: days})
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	for _, p := range projects {
+		s, ok := wf.Get(p.Status)
+		if !ok || s.Terminal || p.Archived() {
+			continue
+		}
+		weighted := models.Weighted(p.RevenueCents, wf.Probability(p))
+		f.Projects++
+		f.PipelineCents += p.RevenueCents
+		f.WeightedCents += weighted
+		if p.ExpectedOn == nil {
+			f.UndatedCents += weighted
+			continue
+		}
+		for i := range f.Horizons {
+			if !p.ExpectedOn.After(today.AddDate(0, 0, f.Horizons[i].Days)) {
+				f.Horizons[i].Projects++
+				f.Horizons[i].ExpectedCents += weighted
+			}
+		}
+	}
+	return f
+}
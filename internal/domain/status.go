@@ -0,0 +1,225 @@
+// domain/status.go - Project status state machine
+package domain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// Workflow is the configured set of statuses in board order. Moves between
+// open (non-terminal) statuses are free; a terminal status such as paid is
+// entered only from the open status right before it on the board, and a
+// project leaves it again only after a refund. Lost is the exception: a deal
+// can fall through from any open status and be reopened without a refund.
+type Workflow []models.Status
+
+// Get returns the status with the given key
+func (wf Workflow) Get(key models.ProjectStatus) (models.Status, bool) {
+	for _, s := range wf {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return models.Status{}, false
+}
+
+// Valid reports whether key is a configured status
+func (wf Workflow) Valid(key models.ProjectStatus) bool {
+	_, ok := wf.Get(key)
+	return ok
+}
+
+// Label is the status as shown on the board
+func (wf Workflow) Label(key models.ProjectStatus) string {
+	if s, ok := wf.Get(key); ok {
+		return s.Label
+	}
+	return string(key)
+}
+
+// Initial returns the first open status on the board, where new and
+// duplicated projects start
+func (wf Workflow) Initial() models.ProjectStatus {
+	for _, s := range wf {
+		if !s.Terminal {
+			return s.Key
+		}
+	}
+	return models.StatusNew
+}
+
+// Transitions lists the statuses a project may move to from from
+func (wf Workflow) Transitions(from models.ProjectStatus) []models.Status {
+	var out []models.Status
+	for _, to := range wf {
+		if to.Key != from && wf.allowed(from, to) {
+			out = append(out, to)
+		}
+	}
+	return out
+}
+
+// allowed reports whether from → to is a transition (ignoring refunds)
+func (wf Workflow) allowed(from models.ProjectStatus, to models.Status) bool {
+	f, ok := wf.Get(from)
+	if !ok {
+		return false
+	}
+	switch {
+	case f.Terminal:
+		return !to.Terminal
+	case to.Key == models.StatusLost:
+		return true
+	case to.Terminal:
+		return wf.feeder(to.Key) == from
+	}
+	return true
+}
+
+// feeder returns the open status right before a terminal one on the board,
+// the only status it can be entered from
+func (wf Workflow) feeder(terminal models.ProjectStatus) models.ProjectStatus {
+	var last models.ProjectStatus
+	for _, s := range wf {
+		if s.Key == terminal {
+			return last
+		}
+		if !s.Terminal {
+			last = s.Key
+		}
+	}
+	return ""
+}
+
+// TransitionError explains why a status change isn't allowed
+type TransitionError struct {
+	From, To    models.ProjectStatus
+	NeedsRefund bool // allowed, but only once the payment has been refunded
+
+	wf Workflow
+}
+
+func (e *TransitionError) Error() string {
+	from, to := e.wf.Label(e.From), e.wf.Label(e.To)
+	if e.NeedsRefund {
+		return fmt.Sprintf("%s projects can only move to %s after a refund", from, to)
+	}
+
+	var allowed []string
+	for _, s := range e.wf.Transitions(e.From) {
+		allowed = append(allowed, s.Label)
+	}
+	return fmt.Sprintf("%s → %s isn't allowed (from %s: %s)", from, to, from, strings.Join(allowed, ", "))
+}
+
+// NeedsRefund reports whether leaving from requires a refund; a lost project
+// was never paid, so it has nothing to refund
+func (wf Workflow) NeedsRefund(from models.ProjectStatus) bool {
+	s, ok := wf.Get(from)
+	return ok && s.Terminal && from != models.StatusLost
+}
+
+// Archivable reports whether projects in a status may be archived: only
+// finished (terminal) work leaves the board
+func (wf Workflow) Archivable(key models.ProjectStatus) bool {
+	s, ok := wf.Get(key)
+	return ok && s.Terminal
+}
+
+// CanMove reports whether from → to is allowed (ignoring the refund
+// requirement). Staying put is always allowed.
+func (wf Workflow) CanMove(from, to models.ProjectStatus) bool {
+	if from == to {
+		return true
+	}
+	s, ok := wf.Get(to)
+	return ok && wf.allowed(from, s)
+}
+
+// CheckTransition returns a *TransitionError unless from → to is allowed;
+// refunded must be set to move a project out of a terminal status
+func (wf Workflow) CheckTransition(from, to models.ProjectStatus, refunded bool) error {
+	if !wf.Valid(to) {
+		return fmt.Errorf("invalid status %q", to)
+	}
+	if from == to {
+		return nil
+	}
+	if !wf.CanMove(from, to) {
+		return &TransitionError{From: from, To: to, wf: wf}
+	}
+	if wf.NeedsRefund(from) && !refunded {
+		return &TransitionError{From: from, To: to, NeedsRefund: true, wf: wf}
+	}
+	return nil
+}
+
+// TransitionTriggers lists a trigger for every allowed transition, for
+// automation rules that react to a specific move (e.g. done → paid)
+func (wf Workflow) TransitionTriggers() []models.AutomationTrigger {
+	var out []models.AutomationTrigger
+	for _, from := range wf {
+		for _, to := range wf.Transitions(from.Key) {
+			out = append(out, models.TransitionTrigger(from.Key, to.Key))
+		}
+	}
+	return out
+}
+
+// Triggers lists the automation triggers offered in the admin UI: entering
+// each status, payments, then each specific transition
+func (wf Workflow) Triggers() []models.AutomationTrigger {
+	var out []models.AutomationTrigger
+	for _, s := range wf {
+		out = append(out, models.StatusTrigger(s.Key))
+	}
+	out = append(out, models.TriggerPayment)
+	return append(out, wf.TransitionTriggers()...)
+}
+
+// Column is a status with the projects in it, as rendered on the board.
+// On a paged search Projects holds the pages loaded so far and Total
+// counts every match.
+type Column struct {
+	Status   models.Status
+	Projects []models.Project
+	Total    *models.ArchiveTotals
+}
+
+// Board groups projects into one column per status, in board order
+func (wf Workflow) Board(projects []models.Project) []Column {
+	cols := make([]Column, len(wf))
+	index := make(map[models.ProjectStatus]int, len(wf))
+	for i, s := range wf {
+		cols[i].Status = s
+		index[s.Key] = i
+	}
+	for _, p := range projects {
+		if i, ok := index[p.Status]; ok {
+			cols[i].Projects = append(cols[i].Projects, p)
+		}
+	}
+	return cols
+}
+
+// Count is how many projects are in the column, loaded or not
+func (c Column) Count() int {
+	if c.Total != nil {
+		return c.Total.Count
+	}
+	return len(c.Projects)
+}
+
+// TotalCents sums the revenue of the column's projects, loaded or not
+func (c Column) TotalCents() int64 {
+	if c.Total != nil {
+		return c.Total.RevenueCents
+	}
+	var total int64
+	for _, p := range c.Projects {
+		total += p.RevenueCents
+	}
+	return total
+}
@@ -0,0 +1,102 @@
+// events/bus.go - In-process publish/subscribe for domain events
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// Type names a kind of domain event
+type Type string
+
+const (
+	ProjectCreated       Type = "project.created"
+	ProjectUpdated       Type = "project.updated"
+	ProjectStatusChanged Type = "project.status_changed"
+	ProjectDeleted       Type = "project.deleted"
+	PaymentReceived      Type = "payment.received"
+	PaymentOverdue       Type = "payment.overdue"   // an invoice or uninvoiced project passed its due date unpaid
+	MilestonePaid        Type = "milestone.paid"    // part of a project paid; payment rules don't run
+	RetainerPaid         Type = "retainer.paid"     // a cycle of an ongoing retainer project paid; payment rules don't run
+	BudgetReached        Type = "project.budget"    // a project's burn reached a warning threshold of its budget
+	DocumentExpiring     Type = "document.expiring" // a client's or project's document expires within models.DocumentReminderDays
+)
+
+// Source values identify who caused an event
+const (
+	SourceUser       = "user"
+	SourceAPI        = "api"
+	SourceStripe     = "stripe"
+	SourceAutomation = "automation"
+	SourceSchedule   = "schedule" // a background job
+)
+
+// Event is a single domain event
+type Event struct {
+	Type        Type
+	ProjectID   int64
+	Client      string
+	AmountCents int64                // project revenue, or the amount paid for PaymentReceived, MilestonePaid and RetainerPaid
+	From, To    models.ProjectStatus // set for ProjectStatusChanged
+	Percent     int                  // the threshold reached, for BudgetReached
+	Document    string               // the document's title, for DocumentExpiring
+	Due         time.Time            // when the document expires (DocumentExpiring) or the payment was due (PaymentOverdue)
+	Source      string
+	At          time.Time
+}
+
+// Types lists every event type, for rule builders
+var Types = []Type{ProjectCreated, ProjectUpdated, ProjectStatusChanged, ProjectDeleted, PaymentReceived, PaymentOverdue, MilestonePaid, RetainerPaid, BudgetReached, DocumentExpiring}
+
+// Handler reacts to an event; errors are logged, not returned to the publisher
+type Handler func(ctx context.Context, e Event) error
+
+// Bus delivers events synchronously to subscribers in registration order
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[Type][]Handler
+	all  []Handler
+}
+
+// New creates an empty bus
+func New() *Bus {
+	return &Bus{subs: make(map[Type][]Handler)}
+}
+
+// Subscribe registers fn for one event type
+func (b *Bus) Subscribe(t Type, fn Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[t] = append(b.subs[t], fn)
+}
+
+// SubscribeAll registers fn for every event type
+func (b *Bus) SubscribeAll(fn Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.all = append(b.all, fn)
+}
+
+// Publish delivers e to its subscribers. A nil bus drops events.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	if b == nil {
+		return
+	}
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := append(append([]Handler(nil), b.subs[e.Type]...), b.all...)
+	b.mu.RUnlock()
+
+	for _, fn := range handlers {
+		if err := fn(ctx, e); err != nil {
+			log.Printf("[EVENTS] %s for project %d: %v", e.Type, e.ProjectID, err)
+		}
+	}
+}
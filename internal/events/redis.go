@@ -0,0 +1,56 @@
+// events/redis.go - Relaying events between instances through Redis
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keys for the relay: the pub/sub channel and the shared sequence that
+// numbers messages, so SSE ids (and Last-Event-ID replay) agree everywhere
+const (
+	redisChannel = "fulldash:events"
+	redisSeq     = "fulldash:events:seq"
+)
+
+// NewRedisStream creates a stream that sees the events published on every
+// instance's bus, not just this one's. Local events go out through Redis and
+// come back in with everyone else's; the relay stops when ctx is cancelled.
+func NewRedisStream(ctx context.Context, bus *Bus, rdb *redis.Client) *Stream {
+	s := newStream()
+	bus.SubscribeAll(func(ctx context.Context, e Event) error {
+		id, err := rdb.Incr(ctx, redisSeq).Uint64()
+		if err != nil {
+			return err
+		}
+		payload, err := json.Marshal(Message{ID: id, Event: e})
+		if err != nil {
+			return err
+		}
+		return rdb.Publish(ctx, redisChannel, payload).Err()
+	})
+
+	sub := rdb.Subscribe(ctx, redisChannel)
+	go func() {
+		<-ctx.Done()
+		sub.Close()
+	}()
+	go func() {
+		for msg := range sub.Channel() {
+			var m Message
+			if err := json.Unmarshal([]byte(msg.Payload), &m); err != nil {
+				log.Printf("[EVENTS] Bad relay message: %v", err)
+				continue
+			}
+			s.mu.Lock()
+			if !s.closed {
+				s.deliver(m)
+			}
+			s.mu.Unlock()
+		}
+	}()
+	return s
+}
@@ -0,0 +1,109 @@
+// events/stream.go - Fan-out of bus events to live clients (SSE)
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// streamBuffer is how many recent events are kept for reconnect replay
+const streamBuffer = 100
+
+// Message is an event with the sequence number used as the SSE id
+type Message struct {
+	ID    uint64
+	Event Event
+}
+
+// Stream fans bus events out to subscribers and remembers the most recent
+// ones so a reconnecting client can catch up from its Last-Event-ID
+type Stream struct {
+	mu     sync.Mutex
+	seq    uint64
+	recent []Message
+	subs   map[chan Message]struct{}
+	closed bool
+}
+
+// NewStream creates a stream fed by every event on bus
+func NewStream(bus *Bus) *Stream {
+	s := newStream()
+	bus.SubscribeAll(s.publish)
+	return s
+}
+
+func newStream() *Stream {
+	return &Stream{subs: make(map[chan Message]struct{})}
+}
+
+// publish numbers e and delivers it
+func (s *Stream) publish(_ context.Context, e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliver(Message{ID: s.seq + 1, Event: e})
+	return nil
+}
+
+// deliver records m and sends it to every subscriber; slow clients drop
+// messages rather than block the publisher. Callers hold s.mu.
+func (s *Stream) deliver(m Message) {
+	if m.ID > s.seq {
+		s.seq = m.ID
+	}
+	s.recent = append(s.recent, m)
+	if len(s.recent) > streamBuffer {
+		s.recent = s.recent[len(s.recent)-streamBuffer:]
+	}
+
+	for ch := range s.subs {
+		select {
+		case ch <- m:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of new messages, the messages after lastID
+// still in the buffer, and whether the buffer covered everything missed
+// (false means the client should reload). Call cancel when done.
+func (s *Stream) Subscribe(lastID uint64) (ch <-chan Message, missed []Message, complete bool, cancel func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := make(chan Message, 16)
+	if s.closed {
+		close(c)
+		return c, nil, true, func() {}
+	}
+	s.subs[c] = struct{}{}
+
+	complete = true
+	if lastID > 0 && lastID < s.seq {
+		complete = len(s.recent) > 0 && s.recent[0].ID <= lastID+1
+		for _, m := range s.recent {
+			if m.ID > lastID {
+				missed = append(missed, m)
+			}
+		}
+	}
+
+	return c, missed, complete, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subs[c]; ok {
+			delete(s.subs, c)
+			close(c)
+		}
+	}
+}
+
+// Close disconnects all subscribers (on server shutdown)
+func (s *Stream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for ch := range s.subs {
+		delete(s.subs, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,289 @@
+// fortnox/fortnox.go - Pushes invoices and their payments to Fortnox over its REST API
+package fortnox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// Where Fortnox lives; overridable for a sandbox or a test server
+const (
+	DefaultAPIURL   = "https://api.fortnox.se/3"
+	DefaultTokenURL = "https://apps.fortnox.se/oauth-v1/token"
+)
+
+// requestTimeout bounds a single call to Fortnox
+const requestTimeout = 15 * time.Second
+
+// dateLayout is a date as Fortnox reads it
+const dateLayout = "2006-01-02"
+
+// ErrNotConfigured means no credentials have been saved in Admin
+var ErrNotConfigured = errors.New("fortnox is not connected")
+
+// Credentials loads and saves the OAuth tokens; Fortnox rotates the refresh
+// token on every use, so each new one must be stored before the next call
+type Credentials interface {
+	FortnoxCredentials() (models.FortnoxCredentials, error)
+	SetFortnoxCredentials(c models.FortnoxCredentials) error
+}
+
+// Client talks to one Fortnox company with the stored credentials
+type Client struct {
+	APIURL   string
+	TokenURL string
+	HTTP     *http.Client
+	Store    Credentials
+
+	mu sync.Mutex // one token refresh at a time: a used refresh token is dead
+}
+
+// New creates a client against the given endpoints
+func New(apiURL, tokenURL string, store Credentials) *Client {
+	return &Client{
+		APIURL:   strings.TrimRight(apiURL, "/"),
+		TokenURL: tokenURL,
+		HTTP:     &http.Client{Timeout: requestTimeout},
+		Store:    store,
+	}
+}
+
+// Invoice is what Fortnox is told about an issued invoice
+type Invoice struct {
+	Customer    string // the client's name; a customer is created for it if Fortnox has none
+	Reference   string // our invoice number, kept as the external reference
+	InvoiceDate time.Time
+	DueDate     time.Time
+	Currency    string // ISO code; empty for SEK
+	Rows        []Row
+}
+
+// Row is one invoice line, priced excluding VAT
+type Row struct {
+	Description string
+	Quantity    float64
+	PriceCents  int64
+	VATPercent  float64
+	Account     string // the sales account it's booked to
+}
+
+// PushInvoice creates the invoice in Fortnox and returns its document number
+func (c *Client) PushInvoice(ctx context.Context, inv Invoice) (string, error) {
+	customer, err := c.customerNumber(ctx, inv.Customer)
+	if err != nil {
+		return "", err
+	}
+
+	rows := make([]map[string]any, 0, len(inv.Rows))
+	for _, r := range inv.Rows {
+		row := map[string]any{
+			"Description":       r.Description,
+			"DeliveredQuantity": r.Quantity,
+			"Price":             float64(r.PriceCents) / 100,
+			"VAT":               r.VATPercent,
+		}
+		if r.Account != "" {
+			row["AccountNumber"] = r.Account
+		}
+		rows = append(rows, row)
+	}
+	body := map[string]any{
+		"CustomerNumber":            customer,
+		"InvoiceDate":               inv.InvoiceDate.Format(dateLayout),
+		"DueDate":                   inv.DueDate.Format(dateLayout),
+		"ExternalInvoiceReference1": inv.Reference,
+		"InvoiceRows":               rows,
+	}
+	if inv.Currency != "" {
+		body["Currency"] = inv.Currency
+	}
+
+	var out struct {
+		Invoice struct {
+			DocumentNumber json.Number
+		}
+	}
+	if err := c.do(ctx, http.MethodPost, "/invoices", map[string]any{"Invoice": body}, &out); err != nil {
+		return "", err
+	}
+	if out.Invoice.DocumentNumber == "" {
+		return "", errors.New("fortnox returned no invoice number")
+	}
+	return out.Invoice.DocumentNumber.String(), nil
+}
+
+// PushPayment registers a payment of a Fortnox invoice and books it
+func (c *Client) PushPayment(ctx context.Context, invoiceNumber string, amountCents int64, paidOn time.Time) error {
+	body := map[string]any{"InvoicePayment": map[string]any{
+		"InvoiceNumber": invoiceNumber,
+		"Amount":        float64(amountCents) / 100,
+		"PaymentDate":   paidOn.Format(dateLayout),
+	}}
+	var out struct {
+		InvoicePayment struct {
+			Number json.Number
+		}
+	}
+	if err := c.do(ctx, http.MethodPost, "/invoicepayments", body, &out); err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPut, "/invoicepayments/"+url.PathEscape(out.InvoicePayment.Number.String())+"/bookkeep", nil, nil)
+}
+
+// customerNumber finds the customer with the client's name, creating it if
+// there is none
+func (c *Client) customerNumber(ctx context.Context, name string) (string, error) {
+	var found struct {
+		Customers []struct {
+			CustomerNumber string
+			Name           string
+		}
+	}
+	if err := c.do(ctx, http.MethodGet, "/customers?name="+url.QueryEscape(name), nil, &found); err != nil {
+		return "", err
+	}
+	for _, cu := range found.Customers {
+		if strings.EqualFold(cu.Name, name) {
+			return cu.CustomerNumber, nil
+		}
+	}
+
+	var created struct {
+		Customer struct {
+			CustomerNumber string
+		}
+	}
+	if err := c.do(ctx, http.MethodPost, "/customers", map[string]any{"Customer": map[string]any{"Name": name}}, &created); err != nil {
+		return "", err
+	}
+	return created.Customer.CustomerNumber, nil
+}
+
+// do sends a JSON request with a valid access token and decodes the reply
+// into out, if given. Fortnox's own error message is kept in the error.
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.APIURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("fortnox: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("fortnox: %w", err)
+	}
+	return nil
+}
+
+// responseError reads Fortnox's error message off a failed response
+func responseError(resp *http.Response) error {
+	var e struct {
+		ErrorInformation struct {
+			Message string `json:"message"`
+		}
+		ErrorDescription string `json:"error_description"` // from the token endpoint
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	json.Unmarshal(b, &e)
+	if msg := e.ErrorInformation.Message; msg != "" {
+		return fmt.Errorf("fortnox: %s (%s)", msg, resp.Status)
+	}
+	if e.ErrorDescription != "" {
+		return fmt.Errorf("fortnox: %s (%s)", e.ErrorDescription, resp.Status)
+	}
+	return fmt.Errorf("fortnox: %s", resp.Status)
+}
+
+// accessToken returns the stored access token, refreshing it when it's
+// about to expire and saving the rotated refresh token
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	creds, err := c.Store.FortnoxCredentials()
+	if err != nil {
+		return "", err
+	}
+	if !creds.Connected() {
+		return "", ErrNotConfigured
+	}
+	if creds.AccessToken != "" && time.Now().Add(time.Minute).Before(creds.ExpiresAt) {
+		return creds.AccessToken, nil
+	}
+
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {creds.RefreshToken}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(creds.ClientID, creds.ClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fortnox: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", responseError(resp)
+	}
+	var t struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", fmt.Errorf("fortnox: %w", err)
+	}
+	if t.AccessToken == "" {
+		return "", errors.New("fortnox: no access token in the reply")
+	}
+
+	creds.AccessToken = t.AccessToken
+	creds.ExpiresAt = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	if t.RefreshToken != "" {
+		creds.RefreshToken = t.RefreshToken
+	}
+	if err := c.Store.SetFortnoxCredentials(creds); err != nil {
+		return "", err
+	}
+	return creds.AccessToken, nil
+}
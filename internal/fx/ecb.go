@@ -0,0 +1,86 @@
+// fx/ecb.go - The European Central Bank's daily reference rates
+package fx
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// DefaultECBURL is the ECB's daily reference rates feed, published on
+// working days around 16:00 CET
+const DefaultECBURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+
+// ECB reads the ECB's euro reference rates. No key is needed.
+type ECB struct {
+	URL  string
+	HTTP *http.Client
+}
+
+// ecbFeed is the part of the feed that holds rates: one Cube per day, with
+// one Cube per currency in euro
+type ecbFeed struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string  `xml:"currency,attr"`
+			Rate     float64 `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// Name identifies the ECB
+func (e *ECB) Name() string { return ProviderECB }
+
+// Latest fetches the latest reference rates, crossed through the euro to
+// base
+func (e *ECB) Latest(ctx context.Context, base string) (models.FXRates, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.URL, nil)
+	if err != nil {
+		return models.FXRates{}, err
+	}
+	resp, err := e.HTTP.Do(req)
+	if err != nil {
+		return models.FXRates{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return models.FXRates{}, fmt.Errorf("status %s", resp.Status)
+	}
+
+	var feed ecbFeed
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return models.FXRates{}, fmt.Errorf("reading feed: %w", err)
+	}
+	if len(feed.Days) == 0 {
+		return models.FXRates{}, fmt.Errorf("feed has no rates")
+	}
+	latest := feed.Days[0]
+	day, err := time.Parse(dateLayout, latest.Time)
+	if err != nil {
+		return models.FXRates{}, fmt.Errorf("feed date: %w", err)
+	}
+
+	perEuro := map[string]float64{"EUR": 1}
+	for _, r := range latest.Rates {
+		if r.Rate > 0 {
+			perEuro[r.Currency] = r.Rate
+		}
+	}
+	baseRate, ok := perEuro[base]
+	if !ok {
+		return models.FXRates{}, fmt.Errorf("no rate for %s", base)
+	}
+
+	rates := models.FXRates{Day: day, FetchedAt: time.Now(), Rates: map[string]float64{}}
+	for currency, rate := range perEuro {
+		if currency != base {
+			rates.Rates[currency] = baseRate / rate
+		}
+	}
+	return rates, nil
+}
@@ -0,0 +1,78 @@
+// fx/exchangeratehost.go - Live rates from exchangerate.host
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// DefaultExchangeRateHostURL is exchangerate.host's API
+const DefaultExchangeRateHostURL = "https://api.exchangerate.host"
+
+// ExchangeRateHost reads exchangerate.host's live rates, which need an
+// access key (FX_API_KEY)
+type ExchangeRateHost struct {
+	URL  string
+	Key  string
+	HTTP *http.Client
+}
+
+// hostResponse is the live endpoint's answer: quotes are keyed by source
+// and currency ("SEKEUR") in units of the currency per unit of source
+type hostResponse struct {
+	Success   bool               `json:"success"`
+	Timestamp int64              `json:"timestamp"`
+	Source    string             `json:"source"`
+	Quotes    map[string]float64 `json:"quotes"`
+	Error     struct {
+		Code int    `json:"code"`
+		Info string `json:"info"`
+	} `json:"error"`
+}
+
+// Name identifies exchangerate.host
+func (e *ExchangeRateHost) Name() string { return ProviderExchangeRateHost }
+
+// Latest fetches the live rates quoted against base
+func (e *ExchangeRateHost) Latest(ctx context.Context, base string) (models.FXRates, error) {
+	q := url.Values{"access_key": {e.Key}, "source": {base}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(e.URL, "/")+"/live?"+q.Encode(), nil)
+	if err != nil {
+		return models.FXRates{}, err
+	}
+	resp, err := e.HTTP.Do(req)
+	if err != nil {
+		return models.FXRates{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return models.FXRates{}, fmt.Errorf("status %s", resp.Status)
+	}
+
+	var body hostResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return models.FXRates{}, fmt.Errorf("reading response: %w", err)
+	}
+	if !body.Success {
+		return models.FXRates{}, fmt.Errorf("error %d: %s", body.Error.Code, body.Error.Info)
+	}
+
+	rates := models.FXRates{Day: time.Unix(body.Timestamp, 0).UTC(), FetchedAt: time.Now(), Rates: map[string]float64{}}
+	for pair, quote := range body.Quotes {
+		currency, ok := strings.CutPrefix(pair, body.Source)
+		if ok && quote > 0 && currency != base {
+			rates.Rates[currency] = 1 / quote
+		}
+	}
+	if len(rates.Rates) == 0 {
+		return models.FXRates{}, fmt.Errorf("response has no rates")
+	}
+	return rates, nil
+}
@@ -0,0 +1,126 @@
+// fx/fx.go - Daily exchange rates from a pluggable provider, cached in the database
+package fx
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// JobName is the scheduled job that refreshes the stored rates
+const JobName = "fx-refresh"
+
+// requestTimeout bounds a single call to a provider
+const requestTimeout = 15 * time.Second
+
+// dateLayout is a date as providers write it
+const dateLayout = "2006-01-02"
+
+// Provider names, as set in FX_PROVIDER
+const (
+	ProviderECB              = "ecb"
+	ProviderExchangeRateHost = "exchangerate.host"
+)
+
+// ErrUnknownProvider means FX_PROVIDER names no provider this build has
+var ErrUnknownProvider = errors.New("unknown exchange rate provider")
+
+// ExchangeRateProvider fetches the latest published rates
+type ExchangeRateProvider interface {
+	// Name identifies the provider in the cache and logs
+	Name() string
+	// Latest returns the most recent rates against base: units of base per
+	// unit of each currency the provider quotes, keyed by ISO 4217 code
+	Latest(ctx context.Context, base string) (models.FXRates, error)
+}
+
+// New returns the named provider; apiURL overrides where it is reached ("" for
+// its default) and key is the access key, for providers that need one
+func New(name, apiURL, key string) (ExchangeRateProvider, error) {
+	client := &http.Client{Timeout: requestTimeout}
+	switch name {
+	case ProviderECB:
+		return &ECB{URL: cmp.Or(apiURL, DefaultECBURL), HTTP: client}, nil
+	case ProviderExchangeRateHost:
+		return &ExchangeRateHost{URL: cmp.Or(apiURL, DefaultExchangeRateHostURL), Key: key, HTTP: client}, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownProvider, name)
+}
+
+// Store caches provider rates and holds the rates amounts are converted at
+type Store interface {
+	LatestFXRates() (*models.FXRates, error)
+	SaveFXRates(r *models.FXRates) error
+	ListExchangeRates() ([]models.ExchangeRate, error)
+	SetExchangeRate(r *models.ExchangeRate) error
+}
+
+// Cache asks a provider for rates at most once a day, keeping them in the
+// store
+type Cache struct {
+	Provider ExchangeRateProvider
+	Store    Store
+}
+
+// Rates returns today's rates against models.BaseCurrency: the cached ones
+// if they were fetched today, otherwise fresh ones from the provider
+func (c *Cache) Rates(ctx context.Context, now time.Time) (*models.FXRates, error) {
+	cached, err := c.Store.LatestFXRates()
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && cached.Source == c.Provider.Name() &&
+		cached.FetchedAt.UTC().Format(dateLayout) == now.UTC().Format(dateLayout) {
+		return cached, nil
+	}
+
+	fresh, err := c.Provider.Latest(ctx, models.BaseCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.Provider.Name(), err)
+	}
+	fresh.Source = c.Provider.Name()
+	if err := c.Store.SaveFXRates(&fresh); err != nil {
+		return nil, err
+	}
+	return &fresh, nil
+}
+
+// Refresh updates the stored rate of every currency listed in Admin that the
+// provider quotes, so projects and Stripe payments convert at today's rate.
+// Currencies it doesn't quote keep the rate entered by hand.
+func (c *Cache) Refresh(ctx context.Context) error {
+	rates, err := c.Rates(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	listed, err := c.Store.ListExchangeRates()
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, r := range listed {
+		rate, ok := rates.Rates[r.Currency]
+		if !ok {
+			missing = append(missing, r.Currency)
+			continue
+		}
+		if rate == r.Rate {
+			continue
+		}
+		if err := c.Store.SetExchangeRate(&models.ExchangeRate{Currency: r.Currency, Rate: rate}); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		log.Printf("[FX] %s has no rate for %s; keeping the rates entered by hand", rates.Source, strings.Join(missing, ", "))
+	}
+	return nil
+}
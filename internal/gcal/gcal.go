@@ -0,0 +1,334 @@
+// gcal/gcal.go - Keeps FullDash's dated events in a Google Calendar over its REST API
+package gcal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// Where Google lives; overridable for a test server
+const (
+	DefaultAPIURL   = "https://www.googleapis.com/calendar/v3"
+	DefaultTokenURL = "https://oauth2.googleapis.com/token"
+)
+
+// requestTimeout bounds a single call to Google
+const requestTimeout = 15 * time.Second
+
+// dateLayout is an all-day date as the Calendar API reads it
+const dateLayout = "2006-01-02"
+
+// Private extended properties mark the events FullDash made: which item
+// each one is, and the date it was last given, so a date moved in Google
+// can be told from one moved here
+const (
+	propOwner = "fulldash"
+	propKey   = "fulldash_key"
+	propDate  = "fulldash_date"
+)
+
+// ErrNotConfigured means no credentials have been saved in Admin
+var ErrNotConfigured = errors.New("google calendar is not connected")
+
+// Credentials loads and saves the OAuth tokens; a refreshed access token is
+// stored so it's reused until it expires
+type Credentials interface {
+	GoogleCalendarCredentials() (models.GoogleCalendarCredentials, error)
+	SetGoogleCalendarCredentials(c models.GoogleCalendarCredentials) error
+}
+
+// Client talks to one calendar with the stored credentials
+type Client struct {
+	APIURL   string
+	TokenURL string
+	HTTP     *http.Client
+	Store    Credentials
+
+	mu sync.Mutex // one token refresh at a time
+}
+
+// New creates a client against the given endpoints
+func New(apiURL, tokenURL string, store Credentials) *Client {
+	return &Client{
+		APIURL:   strings.TrimRight(apiURL, "/"),
+		TokenURL: tokenURL,
+		HTTP:     &http.Client{Timeout: requestTimeout},
+		Store:    store,
+	}
+}
+
+// Event is an all-day event FullDash keeps in the calendar
+type Event struct {
+	ID          string    // Google's, "" until created
+	Key         string    // FullDash's, e.g. "milestone-12"
+	Date        time.Time // the day it's on
+	PushedDate  time.Time // the day FullDash last gave it; differs from Date once moved in Google
+	Summary     string
+	Description string
+	URL         string // links back to the card
+	Recurrence  string // an RRULE such as "FREQ=MONTHLY", or ""
+}
+
+// Same reports whether e and o would look the same in the calendar
+func (e Event) Same(o Event) bool {
+	return e.Key == o.Key && e.Date.Equal(o.Date) && e.PushedDate.Equal(o.PushedDate) && e.Summary == o.Summary &&
+		e.Description == o.Description && e.URL == o.URL && e.Recurrence == o.Recurrence
+}
+
+// apiEvent is an event as the Calendar API reads and writes it
+type apiEvent struct {
+	ID                 string     `json:"id,omitempty"`
+	Summary            string     `json:"summary"`
+	Description        string     `json:"description,omitempty"`
+	Start              apiDate    `json:"start"`
+	End                apiDate    `json:"end"`
+	Recurrence         []string   `json:"recurrence,omitempty"`
+	Source             *apiSource `json:"source,omitempty"`
+	ExtendedProperties struct {
+		Private map[string]string `json:"private"`
+	} `json:"extendedProperties"`
+}
+
+// apiSource links an event back to where it came from
+type apiSource struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// apiDate is an all-day start or end
+type apiDate struct {
+	Date string `json:"date"`
+}
+
+// Events lists the events FullDash made in the calendar
+func (c *Client) Events(ctx context.Context, calendarID string) ([]Event, error) {
+	var out []Event
+	query := url.Values{"privateExtendedProperty": {propOwner + "=1"}, "maxResults": {"2500"}}
+	for {
+		var page struct {
+			Items         []apiEvent `json:"items"`
+			NextPageToken string     `json:"nextPageToken"`
+		}
+		if err := c.do(ctx, http.MethodGet, eventsPath(calendarID, "")+"?"+query.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		for _, a := range page.Items {
+			out = append(out, fromAPI(a))
+		}
+		if page.NextPageToken == "" {
+			return out, nil
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// Insert creates e and returns its ID
+func (c *Client) Insert(ctx context.Context, calendarID string, e Event) (string, error) {
+	var created apiEvent
+	if err := c.do(ctx, http.MethodPost, eventsPath(calendarID, ""), toAPI(e), &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// Update replaces the event e.ID with e
+func (c *Client) Update(ctx context.Context, calendarID string, e Event) error {
+	return c.do(ctx, http.MethodPut, eventsPath(calendarID, e.ID), toAPI(e), nil)
+}
+
+// Delete removes an event; one already gone is fine
+func (c *Client) Delete(ctx context.Context, calendarID, id string) error {
+	err := c.do(ctx, http.MethodDelete, eventsPath(calendarID, id), nil, nil)
+	var se *StatusError
+	if errors.As(err, &se) && (se.Code == http.StatusNotFound || se.Code == http.StatusGone) {
+		return nil
+	}
+	return err
+}
+
+// eventsPath is the path of a calendar's events, or of one of them
+func eventsPath(calendarID, id string) string {
+	p := "/calendars/" + url.PathEscape(calendarID) + "/events"
+	if id != "" {
+		p += "/" + url.PathEscape(id)
+	}
+	return p
+}
+
+// toAPI writes e as the Calendar API takes it
+func toAPI(e Event) apiEvent {
+	a := apiEvent{
+		Summary:     e.Summary,
+		Description: e.Description,
+		Start:       apiDate{e.Date.Format(dateLayout)},
+		End:         apiDate{e.Date.AddDate(0, 0, 1).Format(dateLayout)},
+	}
+	if e.Recurrence != "" {
+		a.Recurrence = []string{"RRULE:" + e.Recurrence}
+	}
+	if e.URL != "" {
+		a.Source = &apiSource{Title: "FullDash", URL: e.URL}
+	}
+	a.ExtendedProperties.Private = map[string]string{propOwner: "1", propKey: e.Key, propDate: e.Date.Format(dateLayout)}
+	return a
+}
+
+// fromAPI reads an event FullDash made
+func fromAPI(a apiEvent) Event {
+	e := Event{
+		ID:          a.ID,
+		Key:         a.ExtendedProperties.Private[propKey],
+		Summary:     a.Summary,
+		Description: a.Description,
+		Recurrence:  strings.TrimPrefix(strings.Join(a.Recurrence, ""), "RRULE:"),
+	}
+	e.Date, _ = time.Parse(dateLayout, a.Start.Date)
+	e.PushedDate, _ = time.Parse(dateLayout, a.ExtendedProperties.Private[propDate])
+	if a.Source != nil {
+		e.URL = a.Source.URL
+	}
+	return e
+}
+
+// StatusError is a call Google answered with an error
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("google calendar: %s (%d)", e.Message, e.Code)
+	}
+	return fmt.Sprintf("google calendar: %d %s", e.Code, http.StatusText(e.Code))
+}
+
+// do sends a JSON request with a valid access token and decodes the reply
+// into out, if given. Google's own error message is kept in the error.
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.APIURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("google calendar: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("google calendar: %w", err)
+	}
+	return nil
+}
+
+// responseError reads Google's error message off a failed response
+func responseError(resp *http.Response) error {
+	var e struct {
+		Error json.RawMessage `json:"error"`
+		// The token endpoint's error is a string with a description;
+		// the API's is an object with a message
+		ErrorDescription string `json:"error_description"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	json.Unmarshal(b, &e)
+	se := &StatusError{Code: resp.StatusCode, Message: e.ErrorDescription}
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	if se.Message == "" && json.Unmarshal(e.Error, &apiErr) == nil {
+		se.Message = apiErr.Message
+	}
+	return se
+}
+
+// accessToken returns the stored access token, refreshing it when it's
+// about to expire
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	creds, err := c.Store.GoogleCalendarCredentials()
+	if err != nil {
+		return "", err
+	}
+	if !creds.Connected() {
+		return "", ErrNotConfigured
+	}
+	if creds.AccessToken != "" && time.Now().Add(time.Minute).Before(creds.ExpiresAt) {
+		return creds.AccessToken, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {creds.RefreshToken},
+		"client_id":     {creds.ClientID},
+		"client_secret": {creds.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("google calendar: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", responseError(resp)
+	}
+	var t struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", fmt.Errorf("google calendar: %w", err)
+	}
+	if t.AccessToken == "" {
+		return "", errors.New("google calendar: no access token in the reply")
+	}
+
+	creds.AccessToken = t.AccessToken
+	creds.ExpiresAt = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	if err := c.Store.SetGoogleCalendarCredentials(creds); err != nil {
+		return "", err
+	}
+	return creds.AccessToken, nil
+}
@@ -0,0 +1,129 @@
+// handlers/adjustments.go - Adjusting entries for projects in locked periods
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// CreateAdjustment posts a correction to a project paid in a locked month.
+// It counts in today's period; the locked month itself stays as it was.
+func (h *Handler) CreateAdjustment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.DB.GetProject(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	a := &models.Adjustment{
+		ProjectID:  p.ID,
+		PostedOn:   time.Now(),
+		Reason:     strings.TrimSpace(r.FormValue("reason")),
+		ShareCents: models.PerPerson[int64]{},
+	}
+	amount := func(name string) (int64, bool) {
+		s := strings.Replace(strings.TrimSpace(r.FormValue(name)), ",", ".", 1)
+		if s == "" {
+			return 0, true
+		}
+		c, err := parseCents(s)
+		if err != nil {
+			http.Error(w, name+" must be a number", http.StatusBadRequest)
+			return 0, false
+		}
+		return c, true
+	}
+	var ok bool
+	if a.RevenueCents, ok = amount("revenue"); !ok {
+		return
+	}
+	for _, person := range people {
+		c, ok := amount(fmt.Sprintf("share_%d", person.ID))
+		if !ok {
+			return
+		}
+		if c != 0 {
+			a.ShareCents[person.ID] = c
+		}
+	}
+	if a.Reason == "" {
+		http.Error(w, "Reason is required", http.StatusBadRequest)
+		return
+	}
+
+	lock, err := h.DB.ProjectLock(p)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var msg string
+	switch {
+	case lock == nil:
+		msg = p.Client + " isn't in a locked period; edit it directly"
+	case a.RevenueCents == 0 && len(a.ShareCents) == 0:
+		msg = "An adjustment needs an amount"
+	case !a.Balanced():
+		msg = "The changes to everyone's shares must add up to the revenue change"
+	}
+	if msg != "" {
+		triggerToast(w, msg)
+		h.renderAdjustments(w, r, p.ID, lock, people)
+		return
+	}
+
+	a.CreatedBy, _ = actorFrom(r)
+	if err := h.DB.CreateAdjustment(a); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	summary := fmt.Sprintf("revenue %+.2f", float64(a.RevenueCents)/100)
+	for _, person := range people {
+		if c, ok := a.ShareCents[person.ID]; ok {
+			summary += fmt.Sprintf("; %s %+.2f", person.Name, float64(c)/100)
+		}
+	}
+	summary += " — " + a.Reason
+	if err := h.logActivity(r, p, "adjusted", nil, nil, summary); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.publish(r, events.ProjectUpdated, p, p.Status, events.SourceUser)
+
+	triggerToast(w, "Adjustment posted for "+p.Client)
+	h.renderAdjustments(w, r, p.ID, lock, people)
+}
+
+// renderAdjustments renders a project's adjusting entries for the edit modal
+func (h *Handler) renderAdjustments(w http.ResponseWriter, r *http.Request, projectID int64, lock *models.PeriodLock, people models.People) {
+	adjustments, err := h.DB.ListAdjustments(projectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.AdjustmentList(projectID, lock, adjustments, people).Render(r.Context(), w)
+}
@@ -0,0 +1,564 @@
+// handlers/admin.go - Admin and health endpoints
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/backup"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/notify"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// Scheduler names of maintenance jobs
+const (
+	IntegrityJob      = "integrity-check"
+	DRTestJob         = "dr-test"
+	BackupJob         = "backup"
+	StatsSnapshotJob  = "stats-snapshot"
+	StorageJob        = "storage-usage"
+	DocumentExpiryJob = "document-expiry"
+	OverdueJob        = "overdue-payments"
+	WeeklyJob         = "weekly-summary"
+	GoogleCalendarJob = "google-calendar"
+)
+
+// Admin renders the admin page with maintenance status
+func (h *Handler) Admin(w http.ResponseWriter, r *http.Request) {
+	check, err := h.DB.LastIntegrityCheck()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	drTest, err := h.DB.LastDRTest()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	storage, err := h.DB.StorageReport()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	keys, err := h.DB.ListAPIKeys()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	users, err := h.DB.ListUsers()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	settings, err := h.settings()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	agreements, err := h.DB.ListAgreements()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	locks, err := h.DB.ListPeriodLocks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	checklists, err := h.DB.ListChecklistTemplates()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rules, err := h.DB.ListAutomationRules()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	notifyRules, err := notify.LoadRules(h.DB)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	hooks, err := h.DB.ListWebhooks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	clients, err := h.DB.ListClients()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rates, err := h.DB.ListExchangeRates()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	goals, err := h.DB.ListGoals()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	templates.Layout("FullDash Admin", templates.AdminPage(check, drTest, storage, h.Jobs.Statuses(), keys, users, settings, people, agreements, locks, wf, checklists,
+		rules, h.Automations.Actions(), notifyRules, h.Notify.Channels(), hooks, clients, rates, h.Config.FXProvider, goals)).Render(r.Context(), w)
+}
+
+// RunIntegrityCheck triggers an immediate integrity check and re-renders its status
+func (h *Handler) RunIntegrityCheck(w http.ResponseWriter, r *http.Request) {
+	h.Jobs.RunNow(r.Context(), IntegrityJob)
+
+	check, err := h.DB.LastIntegrityCheck()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	templates.IntegrityStatus(check).Render(r.Context(), w)
+}
+
+// RunDRTest triggers an immediate disaster recovery dry run and re-renders its status
+func (h *Handler) RunDRTest(w http.ResponseWriter, r *http.Request) {
+	h.Jobs.RunNow(r.Context(), DRTestJob)
+
+	t, err := h.DB.LastDRTest()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	templates.DRTestStatus(t).Render(r.Context(), w)
+}
+
+// DownloadBackup takes a snapshot of the live database into BACKUP_DIR,
+// rotating old ones out, and downloads it. It isn't uploaded to the remotes.
+func (h *Handler) DownloadBackup(w http.ResponseWriter, r *http.Request) {
+	path, err := h.Config.Backups.Local(models.BackupManual)
+	if err != nil && path == "" {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err != nil { // the snapshot was made, but old ones weren't rotated out
+		log.Printf("Backup %s: %v", path, err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filepath.Base(path)))
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
+
+// backupLogSize is how many runs the backups page lists
+const backupLogSize = 50
+
+// BackupsPage shows the backup schedule and policy, each destination's
+// latest success and failure, and the recent runs
+func (h *Handler) BackupsPage(w http.ResponseWriter, r *http.Request) {
+	b := h.Config.Backups
+	var dests []models.BackupDestination
+	for _, name := range append([]string{backup.LocalDestination}, remoteNames(b.Remotes)...) {
+		d := models.BackupDestination{Name: name}
+		var err error
+		if d.LastSuccess, err = h.DB.LastBackupRun(name, true); err == nil {
+			d.LastFailure, err = h.DB.LastBackupRun(name, false)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		dests = append(dests, d)
+	}
+
+	runs, err := h.DB.ListBackupRuns(backupLogSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	templates.Layout("FullDash Backups",
+		templates.BackupsPage(b.Schedule.String(), b.Schedule.Next(time.Now()), b.Keep.String(), b.Dir, dests, runs)).Render(r.Context(), w)
+}
+
+// RunBackupNow takes a backup to every destination, as the schedule would,
+// and returns to the backups page to show how it went
+func (h *Handler) RunBackupNow(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.Config.Backups.Run(r.Context(), models.BackupManual); err != nil {
+		log.Printf("Backup: %v", err)
+	}
+	w.Header().Set("HX-Redirect", "/admin/backups")
+}
+
+// remoteNames lists the remotes' names
+func remoteNames(remotes []backup.Remote) []string {
+	names := make([]string, len(remotes))
+	for i, r := range remotes {
+		names[i] = r.Name()
+	}
+	return names
+}
+
+// RunStorageCheck measures storage immediately and re-renders its status
+func (h *Handler) RunStorageCheck(w http.ResponseWriter, r *http.Request) {
+	h.Jobs.RunNow(r.Context(), StorageJob)
+
+	report, err := h.DB.StorageReport()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	templates.StorageStatus(report).Render(r.Context(), w)
+}
+
+// Health reports liveness plus the last integrity check result
+func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
+	check, err := h.DB.LastIntegrityCheck()
+	if err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if check != nil && !check.OK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "DEGRADED\nintegrity: failed at %s\n%s\n", check.CheckedAt.Format("2006-01-02 15:04:05"), check.Details)
+		return
+	}
+
+	w.Write([]byte("OK"))
+	if check != nil {
+		fmt.Fprintf(w, "\nintegrity: ok at %s\n", check.CheckedAt.Format("2006-01-02 15:04:05"))
+	}
+}
+
+// UpdateSettings saves instance settings from the admin form
+func (h *Handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	rate, err := parseCents(r.FormValue("mileage_rate"))
+	if err != nil || rate <= 0 {
+		http.Error(w, "Mileage rate must be a positive amount", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.SetSetting(models.SettingMileageRate, strconv.FormatInt(rate, 10)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for _, key := range []string{models.SettingKickoffGate, models.SettingDeliveryGate} {
+		if err := h.DB.SetSetting(key, formFlag(r, key)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rates := models.PerPerson[int64]{}
+	for _, p := range people {
+		s := r.FormValue(fmt.Sprintf("rate_%d", p.ID))
+		if s == "" {
+			continue
+		}
+		cents, err := parseCents(s)
+		if err != nil || cents < 0 {
+			http.Error(w, "Hourly rates must be amounts in kr (empty for none)", http.StatusBadRequest)
+			return
+		}
+		if cents > 0 {
+			rates[p.ID] = cents
+		}
+	}
+	if err := h.DB.SetHourlyRates(rates); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.invalidateMetrics()
+
+	for _, key := range []string{models.SettingStorageMax, models.SettingStorageGrowth} {
+		mb, err := strconv.ParseInt(r.FormValue(key), 10, 64)
+		if err != nil || mb < 0 {
+			http.Error(w, "Storage limits must be whole megabytes (0 for none)", http.StatusBadRequest)
+			return
+		}
+		if err := h.DB.SetSetting(key, strconv.FormatInt(mb, 10)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	thresholds, ok := parseThresholds(r.FormValue("budget_thresholds"))
+	if !ok {
+		http.Error(w, "Budget warnings must be whole percentages above 0, separated by commas", http.StatusBadRequest)
+		return
+	}
+	if err := h.DB.SetBudgetThresholds(thresholds); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	company := models.Company{
+		Name:      strings.TrimSpace(r.FormValue("company_name")),
+		OrgNumber: strings.TrimSpace(r.FormValue("company_org_number")),
+		VATNumber: strings.TrimSpace(r.FormValue("company_vat_number")),
+		Address:   strings.TrimSpace(r.FormValue("company_address")),
+		Email:     strings.TrimSpace(r.FormValue("company_email")),
+		Bankgiro:  strings.TrimSpace(r.FormValue("company_bankgiro")),
+	}
+	if err := h.DB.SetCompany(company); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	accounts, ok := parseAccounts(r)
+	if !ok {
+		http.Error(w, "Account numbers must be digits only", http.StatusBadRequest)
+		return
+	}
+	if err := h.DB.SetAccounts(accounts); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	fortnox, err := h.DB.FortnoxCredentials()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.DB.SetFortnoxCredentials(parseFortnox(r, fortnox)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	google, err := h.DB.GoogleCalendarCredentials()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.DB.SetGoogleCalendarCredentials(parseGoogleCalendar(r, google)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	triggerToast(w, "Settings saved")
+	h.renderSettings(w, r)
+}
+
+// renderSettings renders the admin settings panel
+func (h *Handler) renderSettings(w http.ResponseWriter, r *http.Request) {
+	s, err := h.settings()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.SettingsForm(s, people).Render(r.Context(), w)
+}
+
+// settings loads all instance settings
+func (h *Handler) settings() (models.Settings, error) {
+	rate, err := h.mileageRate()
+	if err != nil {
+		return models.Settings{}, err
+	}
+	kickoff, err := h.DB.GetSetting(models.SettingKickoffGate, "1")
+	if err != nil {
+		return models.Settings{}, err
+	}
+	delivery, err := h.DB.GetSetting(models.SettingDeliveryGate, "1")
+	if err != nil {
+		return models.Settings{}, err
+	}
+	quota, err := h.DB.StorageQuota()
+	if err != nil {
+		return models.Settings{}, err
+	}
+	rates, err := h.DB.HourlyRates()
+	if err != nil {
+		return models.Settings{}, err
+	}
+	thresholds, err := h.DB.BudgetThresholds()
+	if err != nil {
+		return models.Settings{}, err
+	}
+	company, err := h.DB.Company()
+	if err != nil {
+		return models.Settings{}, err
+	}
+	accounts, err := h.DB.Accounts()
+	if err != nil {
+		return models.Settings{}, err
+	}
+	fortnox, err := h.DB.FortnoxCredentials()
+	if err != nil {
+		return models.Settings{}, err
+	}
+	google, err := h.DB.GoogleCalendarCredentials()
+	if err != nil {
+		return models.Settings{}, err
+	}
+	googleSync, err := h.DB.GoogleCalendarSync()
+	if err != nil {
+		return models.Settings{}, err
+	}
+	return models.Settings{
+		MileageRateCents: rate,
+		KickoffGate:      kickoff == "1",
+		DeliveryGate:     delivery == "1",
+		StorageQuota:     quota,
+		HourlyRates:      rates,
+		BudgetThresholds: thresholds,
+		Company:          company,
+		Accounts:         accounts,
+		Fortnox:          fortnox,
+		GoogleCalendar:   google,
+		GoogleCalSync:    googleSync,
+	}, nil
+}
+
+// parseThresholds reads comma-separated budget warning percentages, sorted
+// and without repeats; none at all switches the warnings off
+func parseThresholds(s string) ([]int, bool) {
+	thresholds := []int{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(part), "%")); part == "" {
+			continue
+		}
+		pct, err := strconv.Atoi(part)
+		if err != nil || pct <= 0 {
+			return nil, false
+		}
+		thresholds = append(thresholds, pct)
+	}
+	slices.Sort(thresholds)
+	return slices.Compact(thresholds), true
+}
+
+// parseAccounts reads the SIE export's accounts from account_<name> and
+// account_expense_<category>; blank ones keep their default
+func parseAccounts(r *http.Request) (models.Accounts, bool) {
+	a := models.Accounts{Expenses: map[models.ExpenseCategory]string{}}
+	fields := map[string]*string{
+		"bank": &a.Bank, "receivables": &a.Receivables, "sales": &a.Sales, "sales_exempt": &a.SalesExempt,
+		"vat_25": &a.VAT25, "vat_12": &a.VAT12, "vat_6": &a.VAT6, "partner_debt": &a.PartnerDebt,
+	}
+	ok := true
+	read := func(name string) string {
+		v := strings.TrimSpace(r.FormValue("account_" + name))
+		ok = ok && (v == "" || models.ValidAccount(v))
+		return v
+	}
+	for name, dst := range fields {
+		*dst = read(name)
+	}
+	for _, c := range models.ExpenseCategories {
+		if v := read("expense_" + string(c)); v != "" {
+			a.Expenses[c] = v
+		}
+	}
+	return a, ok
+}
+
+// parseFortnox applies the Fortnox fields to the stored credentials. The
+// secret and refresh token are never shown, so a blank one keeps what's
+// stored; a new one drops the access token granted with the old.
+func parseFortnox(r *http.Request, c models.FortnoxCredentials) models.FortnoxCredentials {
+	if r.FormValue("fortnox_disconnect") == "on" {
+		return models.FortnoxCredentials{}
+	}
+	id := strings.TrimSpace(r.FormValue("fortnox_client_id"))
+	secret := strings.TrimSpace(r.FormValue("fortnox_client_secret"))
+	token := strings.TrimSpace(r.FormValue("fortnox_refresh_token"))
+	if id != c.ClientID || secret != "" || token != "" {
+		c.AccessToken, c.ExpiresAt = "", time.Time{}
+	}
+	c.ClientID = id
+	if secret != "" {
+		c.ClientSecret = secret
+	}
+	if token != "" {
+		c.RefreshToken = token
+	}
+	return c
+}
+
+// parseGoogleCalendar applies the Google Calendar fields to the stored
+// credentials, keeping a blank secret or refresh token like parseFortnox
+func parseGoogleCalendar(r *http.Request, c models.GoogleCalendarCredentials) models.GoogleCalendarCredentials {
+	if r.FormValue("google_disconnect") == "on" {
+		return models.GoogleCalendarCredentials{}
+	}
+	id := strings.TrimSpace(r.FormValue("google_client_id"))
+	secret := strings.TrimSpace(r.FormValue("google_client_secret"))
+	token := strings.TrimSpace(r.FormValue("google_refresh_token"))
+	if id != c.ClientID || secret != "" || token != "" {
+		c.AccessToken, c.ExpiresAt = "", time.Time{}
+	}
+	c.ClientID = id
+	c.CalendarID = strings.TrimSpace(r.FormValue("google_calendar_id"))
+	if secret != "" {
+		c.ClientSecret = secret
+	}
+	if token != "" {
+		c.RefreshToken = token
+	}
+	return c
+}
+
+// formFlag maps a checkbox to the "1"/"0" stored in settings
+func formFlag(r *http.Request, name string) string {
+	if r.FormValue(name) == "on" {
+		return "1"
+	}
+	return "0"
+}
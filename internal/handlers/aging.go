@@ -0,0 +1,50 @@
+// handlers/aging.go - Accounts receivable by how long they are overdue
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// AgingPage lists what clients owe, bucketed by days overdue
+func (h *Handler) AgingPage(w http.ResponseWriter, r *http.Request) {
+	a, err := h.aging()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Layout("FullDash Aging", templates.AgingPage(a)).Render(r.Context(), w)
+}
+
+// APIAging returns the receivables AgingPage shows
+func (h *Handler) APIAging(w http.ResponseWriter, r *http.Request) {
+	a, err := h.aging()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if a.Receivables == nil {
+		a.Receivables = []models.Receivable{}
+	}
+	writeJSON(w, http.StatusOK, a)
+}
+
+// aging ages the open invoices and uninvoiced payments as of today
+func (h *Handler) aging() (models.Aging, error) {
+	wf, err := h.workflow()
+	if err != nil {
+		return models.Aging{}, err
+	}
+	projects, err := h.DB.ListProjects(models.ProjectFilter{})
+	if err != nil {
+		return models.Aging{}, err
+	}
+	invoices, err := h.DB.ListInvoices("")
+	if err != nil {
+		return models.Aging{}, err
+	}
+	return wf.Aging(projects, invoices, time.Now()), nil
+}
@@ -0,0 +1,147 @@
+// handlers/agreements.go - Admin management of versioned owner agreements
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// CreateAgreement adds a new agreement version. It takes effect tomorrow at
+// the earliest: anything sooner would re-split payments already made.
+func (h *Handler) CreateAgreement(w http.ResponseWriter, r *http.Request) {
+	from, err := time.Parse(dateLayout, r.FormValue("effective_from"))
+	if err != nil {
+		http.Error(w, "Effective date must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	a := &models.Agreement{
+		EffectiveFrom: from,
+		SplitRules:    models.SplitRules{ExpensePolicy: models.ExpensePolicy(r.FormValue("expense_policy"))},
+		Notes:         strings.TrimSpace(r.FormValue("notes")),
+	}
+	for _, p := range people.Active() {
+		s := r.FormValue(fmt.Sprintf("percent_%d", p.ID))
+		if s == "" {
+			continue
+		}
+		pct, err := strconv.ParseFloat(s, 64)
+		if err != nil || !validPercent(pct) {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		if a.Percents == nil {
+			a.FixedSplit = true
+			a.Percents = models.PerPerson[float64]{}
+		}
+		a.Percents[p.ID] = pct
+	}
+	if s := r.FormValue("finder_fee"); s != "" {
+		a.FinderFeePercent, err = strconv.ParseFloat(s, 64)
+	}
+	if err != nil || !a.ExpensePolicy.Valid() || !validPercent(a.FinderFeePercent) {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if a.FixedSplit && math.Abs(a.Percents.Total()-100) > 0.01 {
+		triggerToast(w, "A fixed split must add up to 100%")
+		h.renderAgreements(w, r)
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if !from.After(today) {
+		triggerToast(w, "Agreements can't be backdated; pick tomorrow or a later date")
+		h.renderAgreements(w, r)
+		return
+	}
+
+	agreements, err := h.DB.ListAgreements()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, existing := range agreements {
+		if existing.EffectiveFrom.Equal(from) {
+			triggerToast(w, "An agreement already takes effect on "+from.Format(dateLayout))
+			h.renderAgreements(w, r)
+			return
+		}
+	}
+
+	if err := h.DB.CreateAgreement(a); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.invalidateMetrics()
+
+	triggerToast(w, "Agreement added, effective "+from.Format(dateLayout))
+	h.renderAgreements(w, r)
+}
+
+// DeleteAgreement removes an agreement version that hasn't taken effect yet
+func (h *Handler) DeleteAgreement(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	a, err := h.DB.GetAgreement(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if a == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if !a.EffectiveFrom.After(time.Now()) {
+		triggerToast(w, "Agreements in force or in the past can't be deleted; add a new version instead")
+		h.renderAgreements(w, r)
+		return
+	}
+
+	if err := h.DB.DeleteAgreement(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.invalidateMetrics()
+
+	h.renderAgreements(w, r)
+}
+
+// renderAgreements renders the admin agreements panel
+func (h *Handler) renderAgreements(w http.ResponseWriter, r *http.Request) {
+	agreements, err := h.DB.ListAgreements()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Agreements(agreements, people).Render(r.Context(), w)
+}
+
+// validPercent reports whether p is a percentage between 0 and 100
+func validPercent(p float64) bool {
+	return p >= 0 && p <= 100
+}
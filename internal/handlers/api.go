@@ -0,0 +1,503 @@
+// handlers/api.go - JSON API (/api/v1)
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/domain"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/service"
+)
+
+// dateLayout is the date format accepted in query params and CSV output
+const dateLayout = "2006-01-02"
+
+// Project list pages: the default size, and the most ?limit= may ask for
+const (
+	apiPageSize = 100
+	apiPageMax  = 500
+)
+
+// projectInput is the JSON body accepted for project create/update
+type projectInput struct {
+	Client       string               `json:"client"`
+	Description  string               `json:"description"`
+	RevenueCents int64                `json:"revenue_cents"`
+	Status       models.ProjectStatus `json:"status"`
+	SecuredBy    []int64              `json:"secured_by"` // person IDs
+
+	// SplitOverride maps person IDs to fixed percentages (adding up to 100)
+	SplitOverride models.PerPerson[float64] `json:"split_override"`
+
+	// Currency quotes the project in a foreign currency: revenue is then
+	// converted from OriginalCents at the stored rate
+	Currency      string `json:"currency"`
+	OriginalCents int64  `json:"original_cents"`
+
+	// VATPercent defaults to the standard rate. Amounts always exclude VAT;
+	// VATInclusive only marks a price agreed including it, for the form.
+	VATPercent   *float64 `json:"vat_percent"`
+	VATInclusive bool     `json:"vat_inclusive"`
+
+	// Probability (0-100) overrides the status's chance of payment in the
+	// forecast; null follows the status. ExpectedOn keeps only its date.
+	Probability *int       `json:"probability"`
+	ExpectedOn  *time.Time `json:"expected_on"`
+
+	// LostReason is required with status lost and dropped for any other
+	LostReason string `json:"lost_reason"`
+	// Source is the lead's channel: referral, website, cold_outreach or repeat_client
+	Source models.LeadSource `json:"source"`
+
+	// EstimatedHours is the expected effort; HourEstimates optionally splits
+	// it by person ID (filling in the total when it's left out)
+	EstimatedHours float64                   `json:"estimated_hours"`
+	HourEstimates  models.PerPerson[float64] `json:"hour_estimates"`
+
+	// PaymentDays are the payment terms in days, DefaultPaymentDays when left out
+	PaymentDays *int `json:"payment_days"`
+
+	// BudgetHours or BudgetCents is the budget, which hours logged burn at
+	// HourlyCents each; an amount budget needs the rate
+	BudgetHours float64 `json:"budget_hours"`
+	BudgetCents int64   `json:"budget_cents"`
+	HourlyCents int64   `json:"hourly_cents"`
+
+	// OverrideGate moves past a soft checklist gate (the override is logged)
+	OverrideGate bool `json:"override_gate"`
+	// Refund confirms a paid project's payment was refunded so it may reopen (logged)
+	Refund bool `json:"refund"`
+}
+
+// validate fills defaults and rejects unknown enum values and people
+func (in *projectInput) validate(wf domain.Workflow, people models.People) string {
+	if in.Status == "" {
+		in.Status = models.StatusNew
+	}
+	if in.Currency = strings.ToUpper(in.Currency); in.Currency == models.BaseCurrency {
+		in.Currency = ""
+	}
+	if in.Currency == "" {
+		in.OriginalCents = 0
+	}
+	in.LostReason = lostReason(in.Status, in.LostReason)
+	if in.VATPercent == nil {
+		vat := float64(models.DefaultVATPercent)
+		in.VATPercent = &vat
+	}
+	if in.PaymentDays == nil {
+		days := models.DefaultPaymentDays
+		in.PaymentDays = &days
+	}
+	slices.Sort(in.SecuredBy)
+	in.SecuredBy = slices.Compact(in.SecuredBy)
+	maps.DeleteFunc(in.SplitOverride, func(_ int64, pct float64) bool { return pct == 0 })
+	maps.DeleteFunc(in.HourEstimates, func(_ int64, h float64) bool { return h == 0 })
+	in.EstimatedHours = estimate(in.EstimatedHours, in.HourEstimates)
+	switch {
+	case in.Client == "":
+		return "client is required"
+	case !wf.Valid(in.Status):
+		return "invalid status"
+	case !people.Valid(in.SecuredBy):
+		return "invalid secured_by"
+	case !validSplit(in.SplitOverride, people):
+		return "split_override must name people and add up to 100"
+	case in.Currency != "" && !models.ValidCurrency(in.Currency):
+		return "invalid currency"
+	case !validPercent(*in.VATPercent):
+		return "vat_percent must be between 0 and 100"
+	case !validProbability(in.Probability):
+		return "probability must be between 0 and 100"
+	case !in.Source.Valid():
+		return "source must be referral, website, cold_outreach or repeat_client"
+	case !validEstimate(in.EstimatedHours, in.HourEstimates, people):
+		return "estimated_hours can't be negative, and hour_estimates must name people and fit within it"
+	case *in.PaymentDays < 0:
+		return "payment_days can't be negative"
+	case !validBudget(in.BudgetHours, in.BudgetCents, in.HourlyCents):
+		return "budget_hours, budget_cents and hourly_cents can't be negative, only one budget can be set, and budget_cents needs hourly_cents"
+	case in.Status == models.StatusLost && in.LostReason == "":
+		return "lost_reason is required for lost projects"
+	}
+	return ""
+}
+
+// applyTo copies input values onto a project
+func (in *projectInput) applyTo(p *models.Project) {
+	p.Client = in.Client
+	p.Description = in.Description
+	p.RevenueCents = in.RevenueCents
+	p.Status = in.Status
+	p.SecuredBy = in.SecuredBy
+	p.Currency = in.Currency
+	p.OriginalCents = in.OriginalCents
+	p.SplitOverride = in.SplitOverride
+	p.VATPercent = *in.VATPercent
+	p.VATInclusive = in.VATInclusive
+	p.Probability = in.Probability
+	p.ExpectedOn = in.ExpectedOn
+	p.LostReason = in.LostReason
+	p.Source = in.Source
+	p.EstimatedHours = in.EstimatedHours
+	p.HourEstimates = in.HourEstimates
+	p.PaymentDays = *in.PaymentDays
+	p.BudgetHours = in.BudgetHours
+	p.BudgetCents = in.BudgetCents
+	p.HourlyCents = in.HourlyCents
+}
+
+// convertInput sets the input's revenue from its foreign amount (stored is nil
+// for a new project), writing errors itself
+func (h *Handler) convertInput(w http.ResponseWriter, in *projectInput, stored *models.Project) bool {
+	if in.Currency == "" {
+		return true
+	}
+	revenue, err := h.foreignRevenue(in.Currency, in.OriginalCents, stored)
+	if errors.Is(err, errNoRate) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	in.RevenueCents = revenue
+	return true
+}
+
+// writeJSON encodes v as the response body with the given status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError writes {"error": msg}, with the request's ID as
+// "request_id" to quote when reporting it
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	body := map[string]string{"error": msg}
+	if id := w.Header().Get(RequestIDHeader); id != "" {
+		body["request_id"] = id
+	}
+	writeJSON(w, status, body)
+}
+
+// apiStatus maps a store error to a status code: a write refused by a period
+// lock is a conflict, anything else a server error
+func apiStatus(err error) int {
+	var locked *models.PeriodLockedError
+	if errors.As(err, &locked) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// decodeProjectInput parses and validates a JSON project body
+func (h *Handler) decodeProjectInput(w http.ResponseWriter, r *http.Request) (*projectInput, bool) {
+	wf, err := h.workflow()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+
+	var in projectInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return nil, false
+	}
+	if msg := in.validate(wf, people); msg != "" {
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return nil, false
+	}
+	return &in, true
+}
+
+// apiProject loads the project named by the {id} URL param, writing errors itself
+func (h *Handler) apiProject(w http.ResponseWriter, r *http.Request) (*models.Project, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid id")
+		return nil, false
+	}
+
+	p, err := h.DB.GetProject(id)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if p == nil {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return nil, false
+	}
+	return p, true
+}
+
+// APIListProjects returns the board's projects, filtered and sorted as
+// described at parseProjectFilter, a page at a time (?limit=, ?offset=).
+// A Link rel="next" header points at the following page, if there is one.
+func (h *Handler) APIListProjects(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f, msg := parseProjectFilter(q)
+	if msg == "" {
+		f.Page, msg = parsePage(q, apiPageSize, apiPageMax)
+	}
+	if msg != "" {
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	// One extra row tells whether there is a next page
+	page := f.Page
+	f.Limit++
+	projects, err := h.DB.ListProjects(f)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(projects) > page.Limit {
+		projects = projects[:page.Limit]
+		q.Set("offset", strconv.Itoa(page.Offset+page.Limit))
+		w.Header().Set("Link", `<`+h.absoluteURL(r, r.URL.Path+"?"+q.Encode())+`>; rel="next"`)
+	}
+	if projects == nil {
+		projects = []models.Project{}
+	}
+	writeJSON(w, http.StatusOK, projects)
+}
+
+// projectDetail is a single project with its comment thread and milestones
+type projectDetail struct {
+	*models.Project
+	Comments   []models.Comment  `json:"comments"`
+	Milestones models.Milestones `json:"milestones"`
+}
+
+// APIGetProject returns a single project with its comments and milestones
+func (h *Handler) APIGetProject(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.apiProject(w, r)
+	if !ok {
+		return
+	}
+
+	comments, err := h.DB.ListComments(p.ID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if comments == nil {
+		comments = []models.Comment{}
+	}
+	milestones, err := h.DB.ListMilestones(p.ID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if milestones == nil {
+		milestones = models.Milestones{}
+	}
+	writeJSON(w, http.StatusOK, projectDetail{Project: p, Comments: comments, Milestones: milestones})
+}
+
+// APICreateProject creates a project from a JSON body
+func (h *Handler) APICreateProject(w http.ResponseWriter, r *http.Request) {
+	in, ok := h.decodeProjectInput(w, r)
+	if !ok || !h.convertInput(w, in, nil) {
+		return
+	}
+
+	p := &models.Project{}
+	in.applyTo(p)
+	if err := h.createProject(p); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	created := service.Snapshot(p, nil)
+	if err := h.logActivity(r, p, "created", nil, &created, ""); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.publish(r, events.ProjectCreated, p, "", events.SourceAPI)
+
+	writeJSON(w, http.StatusCreated, p)
+}
+
+// APIUpdateProject replaces a project's editable fields from a JSON body
+func (h *Handler) APIUpdateProject(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.apiProject(w, r)
+	if !ok {
+		return
+	}
+	in, ok := h.decodeProjectInput(w, r)
+	if !ok || !h.convertInput(w, in, p) {
+		return
+	}
+
+	overridden, err := h.checkMove(p, in.Status, in.OverrideGate, in.Refund)
+	if err != nil {
+		if _, blocked := moveMessage(p, err); blocked {
+			writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+		} else {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	hours := h.hours(p.ID)
+	before := service.Snapshot(p, hours)
+
+	from := p.Status
+	in.applyTo(p)
+	if err := h.DB.UpdateProject(p); err != nil {
+		writeJSONError(w, apiStatus(err), err.Error())
+		return
+	}
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	after := service.Snapshot(p, hours)
+	if err := h.logActivity(r, p, "updated", &before, &after, service.Summary(service.Changes(people, &before, &after))); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := h.logMoveNotes(r, p, from, overridden, in.Refund); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.publish(r, events.ProjectUpdated, p, from, events.SourceAPI)
+	h.checkBudget(r, p.ID, events.SourceAPI)
+
+	writeJSON(w, http.StatusOK, p)
+}
+
+// APIDeleteProject removes a project
+func (h *Handler) APIDeleteProject(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.apiProject(w, r)
+	if !ok {
+		return
+	}
+
+	deleted := service.Snapshot(p, h.hours(p.ID))
+
+	if err := h.DB.DeleteProject(p.ID); err != nil {
+		writeJSONError(w, apiStatus(err), err.Error())
+		return
+	}
+
+	if err := h.logActivity(r, p, "deleted", &deleted, nil, ""); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.publish(r, events.ProjectDeleted, p, "", events.SourceAPI)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APIMetrics returns dashboard metrics
+func (h *Handler) APIMetrics(w http.ResponseWriter, r *http.Request) {
+	m, err := h.metrics()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// APIQueryMetrics returns the per-query counters: runs, errors, slow runs,
+// total and longest time, most total time first. Empty with enabled false
+// unless DB_LOG_QUERIES or DB_SLOW_QUERY is set.
+func (h *Handler) APIQueryMetrics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.DB.QueryStats())
+}
+
+// APITimeEntries lists dated time entries filtered by ?person_id=, ?project_id=,
+// ?from=, ?to= (YYYY-MM-DD);
+// ?format=csv streams a CSV download instead of JSON
+func (h *Handler) APITimeEntries(w http.ResponseWriter, r *http.Request) {
+	f, msg := parseTimeEntryFilter(r)
+	if msg != "" {
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	entries, err := h.DB.ListTimeEntries(f)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		writeTimeEntriesCSV(w, "time-entries.csv", entries)
+		return
+	}
+	if entries == nil {
+		entries = []models.TimeEntry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// parseTimeEntryFilter reads person_id, project_id and date range query params
+func parseTimeEntryFilter(r *http.Request) (models.TimeEntryFilter, string) {
+	q := r.URL.Query()
+	var f models.TimeEntryFilter
+	var err error
+	if v := q.Get("person_id"); v != "" {
+		if f.PersonID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return f, "person_id must be a person's ID"
+		}
+	}
+	if v := q.Get("project_id"); v != "" {
+		if f.ProjectID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return f, "project_id must be a project's ID"
+		}
+	}
+	if v := q.Get("from"); v != "" {
+		if f.From, err = time.Parse(dateLayout, v); err != nil {
+			return f, "from must be YYYY-MM-DD"
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if f.To, err = time.Parse(dateLayout, v); err != nil {
+			return f, "to must be YYYY-MM-DD"
+		}
+	}
+	return f, ""
+}
+
+// writeTimeEntriesCSV streams entries as a CSV attachment named name
+func writeTimeEntriesCSV(w http.ResponseWriter, name string, entries []models.TimeEntry) {
+	writeCSVHeaders(w, name)
+
+	cw := newCSVWriter(w, name)
+	cw.Write([]string{"date", "person", "project_id", "client", "hours", "notes"})
+	for _, e := range entries {
+		cw.Write([]string{
+			e.Date.Format(dateLayout),
+			e.Person,
+			strconv.FormatInt(e.ProjectID, 10),
+			e.Client,
+			strconv.FormatFloat(e.Hours, 'f', -1, 64),
+			e.Notes,
+		})
+	}
+	cw.Flush()
+}
@@ -0,0 +1,176 @@
+// handlers/apikeys.go - API key middleware and management
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/service"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+type ctxKey int
+
+const apiKeyCtxKey ctxKey = iota
+
+// APIKeyFrom returns the authenticated API key for a request, if any
+func APIKeyFrom(ctx context.Context) *models.APIKey {
+	k, _ := ctx.Value(apiKeyCtxKey).(*models.APIKey)
+	return k
+}
+
+// RequireAPIKey authenticates requests via "Authorization: Bearer <token>"
+// and rejects keys whose scope does not cover the required one
+func (h *Handler) RequireAPIKey(required models.APIScope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="fulldash"`)
+				writeJSONError(w, http.StatusUnauthorized, "missing bearer token")
+				return
+			}
+
+			key, err := h.DB.GetAPIKeyByHash(service.HashAPIToken(token))
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if key == nil {
+				writeJSONError(w, http.StatusUnauthorized, "invalid or revoked token")
+				return
+			}
+			if !key.Scope.Allows(required) {
+				writeJSONError(w, http.StatusForbidden, "token scope "+string(key.Scope)+" cannot "+string(required))
+				return
+			}
+			if ok, wait := h.allow(fmt.Sprintf("api:%d", key.ID), h.Config.APIRateLimit, w); !ok {
+				writeJSONError(w, http.StatusTooManyRequests, "rate limit exceeded; retry in "+wait.String())
+				return
+			}
+
+			if err := h.DB.TouchAPIKey(key.ID); err != nil {
+				log.Printf("[API] touch key %d: %v", key.ID, err)
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyCtxKey, key)))
+		})
+	}
+}
+
+// issueAPIKey creates a key and returns it with its one-time plaintext token
+func (h *Handler) issueAPIKey(name string, scope models.APIScope) (*models.APIKey, string, error) {
+	token, prefix, hash, err := service.NewAPIToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	k := &models.APIKey{Name: name, Prefix: prefix, TokenHash: hash, Scope: scope}
+	if err := h.DB.CreateAPIKey(k); err != nil {
+		return nil, "", err
+	}
+	return k, token, nil
+}
+
+// APIListKeys returns all API keys (never their tokens)
+func (h *Handler) APIListKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.DB.ListAPIKeys()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// APICreateKey issues a key from {"name": ..., "scope": ...}; the token is returned once
+func (h *Handler) APICreateKey(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Name  string          `json:"name"`
+		Scope models.APIScope `json:"scope"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if in.Name == "" || !in.Scope.Valid() {
+		writeJSONError(w, http.StatusBadRequest, "name and scope (read|write|admin) are required")
+		return
+	}
+
+	k, token, err := h.issueAPIKey(in.Name, in.Scope)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{"key": k, "token": token})
+}
+
+// APIRevokeKey revokes a key by ID
+func (h *Handler) APIRevokeKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if err := h.DB.RevokeAPIKey(id); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateAPIKey issues a key from the admin form and shows the token once
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	scope := models.APIScope(r.FormValue("scope"))
+	if name == "" || !scope.Valid() {
+		http.Error(w, "Name and scope are required", http.StatusBadRequest)
+		return
+	}
+
+	_, token, err := h.issueAPIKey(name, scope)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderAPIKeys(w, r, token)
+}
+
+// RevokeAPIKey revokes a key from the admin page
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.RevokeAPIKey(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderAPIKeys(w, r, "")
+}
+
+// renderAPIKeys renders the admin API key panel, optionally revealing a new token
+func (h *Handler) renderAPIKeys(w http.ResponseWriter, r *http.Request, newToken string) {
+	keys, err := h.DB.ListAPIKeys()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.APIKeys(keys, newToken).Render(r.Context(), w)
+}
@@ -0,0 +1,199 @@
+// handlers/archive.go - Archiving finished projects off the board
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/domain"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/service"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// archivePageSize is how many archived projects load at a time
+const archivePageSize = 50
+
+// ArchivePage lists archived projects, filtered by ?search=, ?status=,
+// ?secured_by= and ?year=, a page at a time. HTMX requests get only the list
+// back, or only the next page's rows when ?offset= asks for a later page.
+func (h *Handler) ArchivePage(w http.ResponseWriter, r *http.Request) {
+	f, msg := parseArchiveFilter(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// One extra row tells whether there is a next page
+	page := f.Page
+	f.Limit++
+	projects, err := h.DB.ListArchivedProjects(f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	next := 0
+	if len(projects) > page.Limit {
+		projects = projects[:page.Limit]
+		next = page.Offset + page.Limit
+	}
+	f.Page = page
+
+	if isHTMX(r) && f.Offset > 0 {
+		templates.ArchiveRows(wf, people, projects, next).Render(r.Context(), w)
+		return
+	}
+	totals, err := h.DB.ArchiveTotals(f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if isHTMX(r) {
+		templates.ArchiveList(wf, people, projects, totals, next).Render(r.Context(), w)
+		return
+	}
+	templates.Layout("FullDash Archive", templates.ArchivePage(wf, people, f, projects, totals, next)).Render(r.Context(), w)
+}
+
+// ArchiveProject takes a finished project off the board; it stays in metrics
+func (h *Handler) ArchiveProject(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.archiveTarget(w, r)
+	if !ok {
+		return
+	}
+
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !wf.Archivable(p.Status) {
+		msg := "Can't archive " + p.Client + ": only " + terminalLabels(wf) + " projects can be archived"
+		triggerToast(w, msg)
+		http.Error(w, msg, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := h.setArchived(r, p, true); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	triggerToast(w, "Archived "+p.Client)
+	h.Dashboard(w, r)
+}
+
+// UnarchiveProject puts a project back on the board and re-renders the
+// archive list with the filters sent along
+func (h *Handler) UnarchiveProject(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.archiveTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.setArchived(r, p, false); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	triggerToast(w, "Moved "+p.Client+" back to the board")
+	h.ArchivePage(w, r)
+}
+
+// archiveTarget loads the project named in the URL, writing the error
+// response itself when it can't
+func (h *Handler) archiveTarget(w http.ResponseWriter, r *http.Request) (*models.Project, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	p, err := h.DB.GetProject(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if p == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return nil, false
+	}
+	return p, true
+}
+
+// setArchived archives or restores p, logging the change so it can be undone
+func (h *Handler) setArchived(r *http.Request, p *models.Project, archived bool) error {
+	if p.Archived() == archived {
+		return nil
+	}
+
+	hours := h.hours(p.ID)
+	before := service.Snapshot(p, hours)
+	if err := h.DB.SetProjectArchived(p.ID, archived); err != nil {
+		return err
+	}
+	updated, err := h.DB.GetProject(p.ID)
+	if err != nil {
+		return err
+	}
+	*p = *updated
+	after := service.Snapshot(p, hours)
+
+	action := "archived"
+	if !archived {
+		action = "unarchived"
+	}
+	if err := h.logActivity(r, p, action, &before, &after, ""); err != nil {
+		return err
+	}
+	h.publish(r, events.ProjectUpdated, p, p.Status, events.SourceUser)
+	return nil
+}
+
+// parseArchiveFilter reads the archive filters from the query or form
+func parseArchiveFilter(r *http.Request) (models.ArchiveFilter, string) {
+	f := models.ArchiveFilter{
+		Search: strings.TrimSpace(r.FormValue("search")),
+		Status: models.ProjectStatus(r.FormValue("status")),
+	}
+	if s := r.FormValue("secured_by"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return f, "Invalid secured_by"
+		}
+		f.SecuredBy = id
+	}
+	if s := r.FormValue("year"); s != "" {
+		year, err := strconv.Atoi(s)
+		if err != nil {
+			return f, "Invalid year"
+		}
+		f.Year = year
+	}
+	var msg string
+	f.Page, msg = parsePage(r.Form, archivePageSize, archivePageSize)
+	return f, msg
+}
+
+// terminalLabels names the statuses whose projects can be archived
+func terminalLabels(wf domain.Workflow) string {
+	var labels []string
+	for _, s := range wf {
+		if s.Terminal {
+			labels = append(labels, s.Label)
+		}
+	}
+	return strings.Join(labels, " or ")
+}
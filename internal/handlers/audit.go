@@ -0,0 +1,196 @@
+// handlers/audit.go - Audit log page, per-project history and undo/redo
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/service"
+	"github.com/noor-latif/fulldash/internal/store"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// auditLogSize is the number of entries shown on the activity page
+const auditLogSize = 200
+
+// AuditLog lists recent audit log entries, optionally for one ?project=
+func (h *Handler) AuditLog(w http.ResponseWriter, r *http.Request) {
+	var projectID int64
+	if v := r.URL.Query().Get("project"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+		projectID = id
+	}
+
+	var entries []models.Activity
+	var err error
+	if projectID != 0 {
+		entries, err = h.DB.ListProjectActivity(projectID, auditLogSize)
+	} else {
+		entries, err = h.DB.ListActivity(auditLogSize)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	templates.Layout("FullDash Activity", templates.AuditPage(entries, projectID)).Render(r.Context(), w)
+}
+
+// ProjectHistory renders a project's audit history for the edit modal
+func (h *Handler) ProjectHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.DB.ListProjectActivity(id, auditLogSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	templates.ProjectHistory(entries).Render(r.Context(), w)
+}
+
+// historySize is the number of entries shown in a user's undo history panel
+const historySize = 20
+
+// HistoryPanel shows the current user's recent changes with undo/redo
+func (h *Handler) HistoryPanel(w http.ResponseWriter, r *http.Request) {
+	h.renderHistory(w, r, false)
+}
+
+// RevertEntry undoes (or redoes) one of the current user's history entries
+func (h *Handler) RevertEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	a, err := h.DB.GetActivity(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if a == nil || a.UserID != auth.UserFrom(r.Context()).ID {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	h.revert(w, r, a)
+}
+
+// Undo reverts the current user's latest change
+func (h *Handler) Undo(w http.ResponseWriter, r *http.Request) {
+	h.revertNext(w, r, service.NextUndo, "Nothing to undo")
+}
+
+// Redo reverts the current user's latest undo
+func (h *Handler) Redo(w http.ResponseWriter, r *http.Request) {
+	h.revertNext(w, r, service.NextRedo, "Nothing to redo")
+}
+
+// revertNext reverts the history entry picked by next, if any
+func (h *Handler) revertNext(w http.ResponseWriter, r *http.Request, next func([]models.Activity) *models.Activity, none string) {
+	history, err := h.DB.ListUserActivity(auth.UserFrom(r.Context()).ID, historySize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	a := next(history)
+	if a == nil {
+		triggerToast(w, none)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	h.revert(w, r, a)
+}
+
+// revert applies the compensating operation for a, announces the change and
+// re-renders the board (and the history panel when the request came from it)
+func (h *Handler) revert(w http.ResponseWriter, r *http.Request, a *models.Activity) {
+	old, err := h.DB.GetProject(a.ProjectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Revert returns ErrStale when the project is gone, so old is set below.
+	// The claim on a and the write commit together or not at all.
+	var entry *models.Activity
+	var p *models.Project
+	err = h.DB.Tx(func(tx *store.DB) error {
+		entry, p, err = service.Revert(tx, wf, people, a, actorEntry(r))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrStale) || errors.Is(err, service.ErrNotRevertible) || errors.Is(err, service.ErrAlreadyReverted) {
+			triggerToast(w, err.Error())
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if msg, blocked := moveMessage(old, err); blocked {
+			triggerToast(w, msg)
+			http.Error(w, msg, http.StatusUnprocessableEntity)
+			return
+		}
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if p == nil {
+		h.publish(r, events.ProjectDeleted, old, "", events.SourceUser)
+		triggerToast(w, historyLabel(entry.Action)+" "+old.Client+": removed")
+	} else {
+		h.publish(r, events.ProjectUpdated, p, old.Status, events.SourceUser)
+		triggerToast(w, historyLabel(entry.Action)+" "+p.Client+": "+entry.Summary)
+	}
+
+	h.Dashboard(w, r)
+	if r.FormValue("panel") != "" {
+		h.renderHistory(w, r, true)
+	}
+}
+
+// renderHistory renders the current user's undo history panel
+func (h *Handler) renderHistory(w http.ResponseWriter, r *http.Request, oob bool) {
+	history, err := h.DB.ListUserActivity(auth.UserFrom(r.Context()).ID, historySize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.HistoryPanel(history, service.NextUndo(history) != nil, service.NextRedo(history) != nil, oob).Render(r.Context(), w)
+}
+
+// historyLabel capitalizes an undo/redo action for toasts
+func historyLabel(action string) string {
+	if action == service.ActionRedo {
+		return "Redid"
+	}
+	return "Undid"
+}
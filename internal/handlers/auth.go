@@ -0,0 +1,215 @@
+// handlers/auth.go - Login, first-run setup, and role middleware
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// minPasswordLen is the shortest accepted password
+const minPasswordLen = 10
+
+// RequireLogin loads the session user into the context, redirecting to
+// /login (or /setup on a fresh install) when there is none
+func (h *Handler) RequireLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u := h.sessionUser(r); u != nil {
+			next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), u)))
+			return
+		}
+
+		target := "/login"
+		if n, err := h.DB.CountUsers(); err == nil && n == 0 {
+			target = "/setup"
+		}
+		redirect(w, r, target)
+	})
+}
+
+// RequireRole rejects users whose role does not cover the required one
+func (h *Handler) RequireRole(required models.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !auth.Can(r.Context(), required) {
+				triggerToast(w, "You need the "+string(required)+" role to do that")
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// sessionUser returns the user for the request's session cookie, if valid
+func (h *Handler) sessionUser(r *http.Request) *models.User {
+	c, err := r.Cookie(auth.CookieName)
+	if err != nil {
+		return nil
+	}
+	id, ok := h.Sessions.Get(c.Value)
+	if !ok {
+		return nil
+	}
+	u, err := h.DB.GetUser(id)
+	if err != nil {
+		return nil
+	}
+	return u
+}
+
+// LoginPage renders the login form
+func (h *Handler) LoginPage(w http.ResponseWriter, r *http.Request) {
+	templates.Layout("FullDash Login", templates.LoginPage("", "")).Render(r.Context(), w)
+}
+
+// Login verifies credentials and starts a session
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	email := strings.TrimSpace(r.FormValue("email"))
+
+	if ok, wait := h.allow("login:"+clientIP(r), h.Config.LoginRateLimit, w); !ok {
+		w.WriteHeader(http.StatusTooManyRequests)
+		msg := fmt.Sprintf("Too many attempts; try again in %s", wait)
+		templates.Layout("FullDash Login", templates.LoginPage(email, msg)).Render(r.Context(), w)
+		return
+	}
+
+	u, err := h.DB.GetUserByEmail(email)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if u == nil || !auth.CheckPassword(u.PasswordHash, r.FormValue("password")) {
+		w.WriteHeader(http.StatusUnauthorized)
+		templates.Layout("FullDash Login", templates.LoginPage(email, "Invalid email or password")).Render(r.Context(), w)
+		return
+	}
+
+	if err := h.startSession(w, r, u.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// Logout ends the current session
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(auth.CookieName); err == nil {
+		h.Sessions.Delete(c.Value)
+	}
+	http.SetCookie(w, &http.Cookie{Name: auth.CookieName, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+	redirect(w, r, "/login")
+}
+
+// SetupPage renders the first-run form for creating the initial owner
+func (h *Handler) SetupPage(w http.ResponseWriter, r *http.Request) {
+	if !h.needsSetup(w) {
+		return
+	}
+	templates.Layout("FullDash Setup", templates.SetupPage("")).Render(r.Context(), w)
+}
+
+// Setup creates the initial owner account and logs them in
+func (h *Handler) Setup(w http.ResponseWriter, r *http.Request) {
+	if !h.needsSetup(w) {
+		return
+	}
+
+	u, msg, err := h.newUserFromForm(r, models.RoleOwner)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		templates.Layout("FullDash Setup", templates.SetupPage(msg)).Render(r.Context(), w)
+		return
+	}
+
+	if err := h.startSession(w, r, u.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// needsSetup reports whether no users exist yet, otherwise writing a 404
+func (h *Handler) needsSetup(w http.ResponseWriter) bool {
+	n, err := h.DB.CountUsers()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if n > 0 {
+		http.Error(w, "Already set up", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// newUserFromForm validates name/email/password form fields and creates a user.
+// A non-empty msg is a validation problem to show the user.
+func (h *Handler) newUserFromForm(r *http.Request, role models.Role) (u *models.User, msg string, err error) {
+	name := strings.TrimSpace(r.FormValue("name"))
+	email := strings.TrimSpace(r.FormValue("email"))
+	password := r.FormValue("password")
+
+	switch {
+	case name == "" || !strings.Contains(email, "@"):
+		return nil, "Name and a valid email are required", nil
+	case len(password) < minPasswordLen:
+		return nil, "Password must be at least 10 characters", nil
+	case !role.Valid():
+		return nil, "Invalid role", nil
+	}
+
+	if existing, err := h.DB.GetUserByEmail(email); err != nil {
+		return nil, "", err
+	} else if existing != nil {
+		return nil, "A user with that email already exists", nil
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, "", err
+	}
+
+	u = &models.User{Email: email, Name: name, PasswordHash: hash, Role: role}
+	if err := h.DB.CreateUser(u); err != nil {
+		return nil, "", err
+	}
+	return u, "", nil
+}
+
+// startSession creates a session and sets the cookie
+func (h *Handler) startSession(w http.ResponseWriter, r *http.Request, userID int64) error {
+	token, err := h.Sessions.Create(userID)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.CookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(auth.SessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// redirect sends the browser to target, using HX-Redirect for HTMX requests
+func redirect(w http.ResponseWriter, r *http.Request, target string) {
+	if isHTMX(r) {
+		w.Header().Set("HX-Redirect", target)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
@@ -0,0 +1,83 @@
+// handlers/automations.go - Admin management of automation rules
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// CreateAutomationRule adds a rule from the admin form
+func (h *Handler) CreateAutomationRule(w http.ResponseWriter, r *http.Request) {
+	delay, _ := strconv.Atoi(r.FormValue("delay_days"))
+	rule := &models.AutomationRule{
+		Trigger:   models.AutomationTrigger(r.FormValue("trigger")),
+		Action:    r.FormValue("action"),
+		Param:     strings.TrimSpace(r.FormValue("param")),
+		DelayDays: delay,
+		Enabled:   true,
+	}
+	if err := h.Automations.Validate(rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.CreateAutomationRule(rule); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	triggerToast(w, "Automation added")
+	h.renderAutomations(w, r)
+}
+
+// ToggleAutomationRule pauses or resumes a rule
+func (h *Handler) ToggleAutomationRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.SetAutomationRuleEnabled(id, r.FormValue("enabled") == "on"); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderAutomations(w, r)
+}
+
+// DeleteAutomationRule removes a rule and its queued runs
+func (h *Handler) DeleteAutomationRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.DeleteAutomationRule(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderAutomations(w, r)
+}
+
+// renderAutomations renders the admin automation panel
+func (h *Handler) renderAutomations(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.DB.ListAutomationRules()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.AutomationRules(rules, h.Automations.Actions(), wf.Triggers()).Render(r.Context(), w)
+}
@@ -0,0 +1,43 @@
+// handlers/budget.go - Budget burn warnings for hourly-billed projects
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/noor-latif/fulldash/internal/events"
+)
+
+// checkBudget brings a project's budget warning up to date after its hours
+// or budget changed, announcing a threshold its burn newly reached. Going
+// back below a threshold (hours removed, a bigger budget) rearms it.
+// Failures are only logged: the change itself went through.
+func (h *Handler) checkBudget(r *http.Request, projectID int64, source string) {
+	p, err := h.DB.GetProject(projectID)
+	if err != nil || p == nil {
+		log.Printf("[BUDGET] Project %d not found: %v", projectID, err)
+		return
+	}
+	thresholds, err := h.DB.BudgetThresholds()
+	if err != nil {
+		log.Printf("[BUDGET] Thresholds: %v", err)
+		return
+	}
+	reached := p.BudgetReached(thresholds)
+	if reached == p.BudgetWarned {
+		return
+	}
+	if err := h.DB.SetBudgetWarned(p.ID, reached); err != nil {
+		log.Printf("[BUDGET] Project %d: %v", p.ID, err)
+		return
+	}
+	if reached > p.BudgetWarned {
+		h.Events.Publish(r.Context(), events.Event{
+			Type:      events.BudgetReached,
+			ProjectID: p.ID,
+			Client:    p.Client,
+			Percent:   reached,
+			Source:    source,
+		})
+	}
+}
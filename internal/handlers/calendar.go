@@ -0,0 +1,242 @@
+// handlers/calendar.go - The calendar feed: payment due dates, milestones and retainer renewals
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/ical"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/service"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// retainerFreqs maps Stripe's billing intervals to iCalendar frequencies
+var retainerFreqs = map[string]string{
+	"day":   "DAILY",
+	"week":  "WEEKLY",
+	"month": "MONTHLY",
+	"year":  "YEARLY",
+}
+
+// CalendarFeed serves /calendar.ics to calendar apps subscribing with a
+// user's feed token (?token=); ?person= keeps the projects and retainers
+// that person secured. Apps can't send headers or cookies, so the token
+// rides in the URL.
+func (h *Handler) CalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "missing token", http.StatusUnauthorized)
+		return
+	}
+	u, err := h.DB.GetUserByCalendarToken(service.HashAPIToken(token))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if u == nil {
+		http.Error(w, "invalid or revoked token", http.StatusUnauthorized)
+		return
+	}
+	if ok, wait := h.allow(fmt.Sprintf("calendar:%d", u.ID), h.Config.APIRateLimit, w); !ok {
+		http.Error(w, "rate limit exceeded; retry in "+wait.String(), http.StatusTooManyRequests)
+		return
+	}
+
+	name := "FullDash"
+	var person int64
+	if v := r.URL.Query().Get("person"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid person", http.StatusBadRequest)
+			return
+		}
+		people, err := h.DB.ListPeople()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		p, ok := people.Get(id)
+		if !ok {
+			http.Error(w, "no such person", http.StatusNotFound)
+			return
+		}
+		person, name = p.ID, "FullDash: "+p.Name
+	}
+
+	dates, err := h.dueDates(person)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	host := r.Host
+	if u, err := url.Parse(h.absoluteURL(r, "/")); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	events := make([]ical.Event, len(dates))
+	for i, d := range dates {
+		events[i] = ical.Event{
+			UID:         d.Key + "@" + host,
+			Start:       d.Day,
+			AllDay:      true,
+			Summary:     d.Summary,
+			Description: d.Description,
+			RRule:       d.Repeat,
+		}
+		if d.ProjectID != 0 {
+			events[i].URL = h.absoluteURL(r, fmt.Sprintf("/#project-%d", d.ProjectID))
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(ical.Feed(name, events...))
+}
+
+// dueDate is a day on the calendars: the feed and Google Calendar
+type dueDate struct {
+	Key         string // stable, e.g. "milestone-12"
+	ProjectID   int64  // the card it links to, 0 for none
+	MilestoneID int64  // set for milestones, whose date can be moved from Google Calendar
+	Day         time.Time
+	Summary     string
+	Description string
+	Repeat      string // an RRULE, or ""
+	Done        bool   // paid, or its project is: kept in the feed, removed from Google Calendar
+}
+
+// dueDates lists when unpaid projects and invoices fall due (or, before
+// then, when payment is expected), milestone due dates, and each active
+// retainer's next renewal, repeating at its interval. person, if not 0,
+// keeps what that person secured.
+func (h *Handler) dueDates(person int64) ([]dueDate, error) {
+	wf, err := h.workflow()
+	if err != nil {
+		return nil, err
+	}
+	projects, err := h.DB.ListProjects(models.ProjectFilter{SecuredBy: person})
+	if err != nil {
+		return nil, err
+	}
+	invoices, err := h.DB.ListInvoices("")
+	if err != nil {
+		return nil, err
+	}
+	byID := map[int64]models.Project{}
+	for _, p := range projects {
+		byID[p.ID] = p
+	}
+	var mine []models.Invoice
+	for _, inv := range invoices {
+		if _, ok := byID[inv.ProjectID]; ok {
+			mine = append(mine, inv)
+		}
+	}
+
+	var out []dueDate
+	receivable := map[int64]bool{}
+	for _, rc := range wf.Aging(projects, mine, time.Now()).Receivables {
+		receivable[rc.ProjectID] = true
+		key := fmt.Sprintf("due-project-%d", rc.ProjectID)
+		if rc.InvoiceID != 0 {
+			key = fmt.Sprintf("due-invoice-%d", rc.InvoiceID)
+		}
+		out = append(out, dueDate{Key: key, ProjectID: rc.ProjectID, Day: rc.DueOn,
+			Summary: fmt.Sprintf("Payment due: %s (%.2f kr)", rc.Client, float64(rc.AmountCents)/100), Description: rc.Label})
+	}
+	for _, p := range projects {
+		if p.ExpectedOn == nil || receivable[p.ID] || p.Status == models.StatusPaid || p.Archived() {
+			continue
+		}
+		out = append(out, dueDate{Key: fmt.Sprintf("expected-project-%d", p.ID), ProjectID: p.ID, Day: *p.ExpectedOn,
+			Summary: fmt.Sprintf("Payment expected: %s (%.2f kr)", p.Client, float64(p.GrossCents())/100), Description: p.Description})
+	}
+
+	milestones, err := h.DB.ListAllMilestones()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range milestones {
+		p, ok := byID[m.ProjectID]
+		if !ok || p.Archived() {
+			continue
+		}
+		d := dueDate{Key: fmt.Sprintf("milestone-%d", m.ID), ProjectID: p.ID, MilestoneID: m.ID, Day: m.DueOn,
+			Summary: fmt.Sprintf("Milestone due: %s, %s (%.2f kr)", p.Client, m.Title, float64(m.AmountCents)/100), Description: p.Description}
+		if m.Status == models.MilestonePaid {
+			d.Summary = "Paid: " + strings.TrimPrefix(d.Summary, "Milestone due: ")
+		}
+		d.Done = m.Status == models.MilestonePaid || p.Status == models.StatusPaid
+		out = append(out, d)
+	}
+
+	retainers, err := h.DB.ListAllRetainers()
+	if err != nil {
+		return nil, err
+	}
+	clients, err := h.DB.ListClients()
+	if err != nil {
+		return nil, err
+	}
+	clientNames := map[int64]string{}
+	for _, c := range clients {
+		clientNames[c.ID] = c.Name
+	}
+	for _, rt := range retainers {
+		if !rt.Active() || rt.CurrentPeriodEnd == nil || (person != 0 && !slices.Contains(rt.SecuredBy, person)) {
+			continue
+		}
+		d := dueDate{Key: fmt.Sprintf("retainer-%d", rt.ID), ProjectID: rt.ProjectID, Day: *rt.CurrentPeriodEnd,
+			Summary:     fmt.Sprintf("Retainer renews: %s (%.2f %s)", clientNames[rt.ClientID], float64(rt.AmountCents)/100, strings.ToUpper(rt.Currency)),
+			Description: rt.Description}
+		if freq, ok := retainerFreqs[rt.Interval]; ok {
+			d.Repeat = "FREQ=" + freq
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// CreateCalendarLink gives the current user a new calendar feed token,
+// replacing (and so revoking) any earlier one, and shows the feed's links
+// once
+func (h *Handler) CreateCalendarLink(w http.ResponseWriter, r *http.Request) {
+	u := auth.UserFrom(r.Context())
+
+	token, hash, err := service.NewCalendarToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.DB.SetCalendarToken(u.ID, hash); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	triggerToast(w, "Calendar link created")
+	templates.CalendarFeed(true, h.absoluteURL(r, "/calendar.ics?token="+url.QueryEscape(token)), people).Render(r.Context(), w)
+}
+
+// DeleteCalendarLink turns the current user's calendar feed off
+func (h *Handler) DeleteCalendarLink(w http.ResponseWriter, r *http.Request) {
+	u := auth.UserFrom(r.Context())
+
+	if err := h.DB.SetCalendarToken(u.ID, ""); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	triggerToast(w, "Calendar link revoked")
+	templates.CalendarFeed(false, "", nil).Render(r.Context(), w)
+}
@@ -0,0 +1,138 @@
+// handlers/checklists.go - Project checklist and checklist template handlers
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/service"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// ToggleChecklistItem ticks or unticks a project checklist item
+func (h *Handler) ToggleChecklistItem(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.DB.GetChecklistItem(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if item == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.DB.SetChecklistItemDone(id, !item.Done); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderChecklist(w, r, item.ProjectID)
+}
+
+// renderChecklist renders a project's checklist
+func (h *Handler) renderChecklist(w http.ResponseWriter, r *http.Request, projectID int64) {
+	items, err := h.DB.ListChecklist(projectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Checklist(items).Render(r.Context(), w)
+}
+
+// checkGates returns a *service.GateError if the status change is blocked by
+// an enforced checklist. A soft gate passed with override is returned as
+// overridden so the caller can log it.
+func (h *Handler) checkGates(p *models.Project, to models.ProjectStatus, override bool) (overridden *service.GateError, err error) {
+	if p.Status == to {
+		return nil, nil
+	}
+	s, err := h.settings()
+	if err != nil {
+		return nil, err
+	}
+	items, err := h.DB.ListChecklist(p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.KickoffGate {
+		if err := service.CheckKickoffGate(p.Status, to, items); err != nil {
+			return nil, err
+		}
+	}
+	if s.DeliveryGate {
+		var gate *service.GateError
+		if err := service.CheckDeliveryGate(p.Status, to, items); errors.As(err, &gate) {
+			if !override {
+				return nil, gate
+			}
+			return gate, nil
+		}
+	}
+	return nil, nil
+}
+
+// gateMessage explains a blocked status change, hinting at the override for soft gates
+func gateMessage(p *models.Project, gate *service.GateError) string {
+	msg := "Can't move " + p.Client + ": " + gate.Error()
+	if gate.Soft {
+		msg += " (tick override to move anyway)"
+	}
+	return msg
+}
+
+// CreateChecklistTemplate adds a standard checklist item for new projects
+func (h *Handler) CreateChecklistTemplate(w http.ResponseWriter, r *http.Request) {
+	t := &models.ChecklistTemplate{
+		Stage:    models.ChecklistStage(r.FormValue("stage")),
+		Title:    strings.TrimSpace(r.FormValue("title")),
+		Critical: r.FormValue("critical") == "on",
+	}
+	if t.Title == "" || !t.Stage.Valid() {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.CreateChecklistTemplate(t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderChecklistTemplates(w, r)
+}
+
+// DeleteChecklistTemplate removes a standard checklist item
+func (h *Handler) DeleteChecklistTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.DeleteChecklistTemplate(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderChecklistTemplates(w, r)
+}
+
+// renderChecklistTemplates renders the admin checklist template panel
+func (h *Handler) renderChecklistTemplates(w http.ResponseWriter, r *http.Request) {
+	items, err := h.DB.ListChecklistTemplates()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.ChecklistTemplates(items).Render(r.Context(), w)
+}
@@ -0,0 +1,170 @@
+// handlers/clients.go - Client reports, and admin per-client payment branding and billing email
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/mail"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// thankYouMaxLen is Stripe's limit for custom checkout text
+const thankYouMaxLen = 1200
+
+// SaveClient sets up a client's payment branding, replacing any saved for
+// the same client name
+func (h *Handler) SaveClient(w http.ResponseWriter, r *http.Request) {
+	c := &models.Client{
+		Name:                strings.TrimSpace(r.FormValue("name")),
+		StatementDescriptor: strings.ToUpper(strings.TrimSpace(r.FormValue("statement_descriptor"))),
+		LogoURL:             strings.TrimSpace(r.FormValue("logo_url")),
+		ThankYou:            strings.TrimSpace(r.FormValue("thank_you")),
+		Email:               strings.TrimSpace(r.FormValue("email")),
+	}
+	if c.Name == "" {
+		http.Error(w, "Client name is required", http.StatusBadRequest)
+		return
+	}
+
+	var msg string
+	switch {
+	case !models.ValidStatementDescriptor(c.StatementDescriptor):
+		msg = "Statement descriptors are up to 22 Latin letters, digits and spaces"
+	case c.LogoURL != "" && !httpsURL(c.LogoURL):
+		msg = "The logo must be an https:// image URL"
+	case len([]rune(c.ThankYou)) > thankYouMaxLen:
+		msg = "Thank-you text is limited to " + strconv.Itoa(thankYouMaxLen) + " characters"
+	case c.Email != "" && !validAddresses(c.Email):
+		msg = "Enter billing email addresses separated by commas"
+	}
+	if msg != "" {
+		triggerToast(w, msg)
+		h.renderClients(w, r)
+		return
+	}
+
+	if err := h.DB.SaveClient(c); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	triggerToast(w, "Saved branding for "+c.Name)
+	h.renderClients(w, r)
+}
+
+// DeleteClient removes a client's branding
+func (h *Handler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.DeleteClient(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderClients(w, r)
+}
+
+// renderClients renders the admin client branding panel
+func (h *Handler) renderClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := h.DB.ListClients()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Clients(clients).Render(r.Context(), w)
+}
+
+// ClientsPage lists every client with its lifetime revenue and what's open
+func (h *Handler) ClientsPage(w http.ResponseWriter, r *http.Request) {
+	reports, err := h.DB.ClientReports()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Layout("FullDash Clients", templates.ClientsPage(reports)).Render(r.Context(), w)
+}
+
+// ClientPage shows one client's lifetime figures, projects and invoices
+func (h *Handler) ClientPage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	report, err := h.DB.ClientReport(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if report == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Layout("FullDash "+report.Client.Name, templates.ClientPage(wf, people, report)).Render(r.Context(), w)
+}
+
+// APIClients returns every client's lifetime figures, largest first
+func (h *Handler) APIClients(w http.ResponseWriter, r *http.Request) {
+	reports, err := h.DB.ClientReports()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if reports == nil {
+		reports = []models.ClientReport{}
+	}
+	writeJSON(w, http.StatusOK, reports)
+}
+
+// APIClient returns one client's lifetime figures with its projects and invoices
+func (h *Handler) APIClient(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	report, err := h.DB.ClientReport(id)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if report == nil {
+		writeJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// validAddresses reports whether s is a comma-separated list of email
+// addresses
+func validAddresses(s string) bool {
+	_, err := mail.ParseAddresses(s)
+	return err == nil
+}
+
+// httpsURL reports whether s is an absolute https URL, as Stripe requires
+// for images
+func httpsURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
@@ -0,0 +1,96 @@
+// handlers/comments.go - Per-project comment threads
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// commentMaxLen caps a comment's length in characters
+const commentMaxLen = 4000
+
+// CreateComment adds a comment to a project's thread
+func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.DB.GetProject(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	body := strings.TrimSpace(r.FormValue("body"))
+	if body == "" {
+		http.Error(w, "Comment is empty", http.StatusBadRequest)
+		return
+	}
+	if len([]rune(body)) > commentMaxLen {
+		triggerToast(w, "Comments are limited to "+strconv.Itoa(commentMaxLen)+" characters")
+		h.renderComments(w, r, p.ID)
+		return
+	}
+
+	u := auth.UserFrom(r.Context())
+	c := &models.Comment{ProjectID: p.ID, AuthorID: u.ID, Author: u.Name, Body: body}
+	if err := h.DB.CreateComment(c); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderComments(w, r, p.ID)
+}
+
+// DeleteComment removes a comment; only its author or an owner may
+func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.DB.GetComment(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if c == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if !c.DeletableBy(auth.UserFrom(r.Context())) {
+		triggerToast(w, "Only the author or an owner can delete a comment")
+		h.renderComments(w, r, c.ProjectID)
+		return
+	}
+
+	if err := h.DB.DeleteComment(c.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderComments(w, r, c.ProjectID)
+}
+
+// renderComments renders a project's comment thread for the edit modal
+func (h *Handler) renderComments(w http.ResponseWriter, r *http.Request, projectID int64) {
+	comments, err := h.DB.ListComments(projectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.CommentThread(projectID, comments).Render(r.Context(), w)
+}
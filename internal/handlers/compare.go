@@ -0,0 +1,91 @@
+// handlers/compare.go - Comparing revenue, projects and hours between two periods
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// ComparePage sets one period's revenue, projects, average deal size and
+// hours against another's (see parseComparison)
+func (h *Handler) ComparePage(w http.ResponseWriter, r *http.Request) {
+	c, msg, err := h.periodComparison(r.URL.Query())
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Layout("FullDash Compare", templates.ComparePage(c)).Render(r.Context(), w)
+}
+
+// APICompare returns the comparison ComparePage shows
+func (h *Handler) APICompare(w http.ResponseWriter, r *http.Request) {
+	c, msg, err := h.periodComparison(r.URL.Query())
+	if msg != "" {
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// periodComparison compares the periods the query asks for. A non-empty message
+// means the query was invalid.
+func (h *Handler) periodComparison(q url.Values) (models.PeriodComparison, string, error) {
+	current, previous, msg := parseComparison(q, time.Now())
+	if msg != "" {
+		return models.PeriodComparison{}, msg, nil
+	}
+	cur, err := h.DB.PeriodStats(current[0], current[1])
+	if err != nil {
+		return models.PeriodComparison{}, "", err
+	}
+	prev, err := h.DB.PeriodStats(previous[0], previous[1])
+	if err != nil {
+		return models.PeriodComparison{}, "", err
+	}
+	return models.ComparePeriods(cur, prev), "", nil
+}
+
+// parseComparison reads the periods to compare as inclusive dates: from and
+// to (default this year so far) against compare_from and compare_to
+// (default the same dates a year earlier)
+func parseComparison(q url.Values, now time.Time) (current, previous [2]time.Time, msg string) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	current = [2]time.Time{time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC), today}
+	for i, name := range []string{"from", "to"} {
+		if s := q.Get(name); s != "" {
+			t, err := time.Parse(dateLayout, s)
+			if err != nil {
+				return current, previous, "Invalid " + name + " date"
+			}
+			current[i] = t
+		}
+	}
+
+	previous = [2]time.Time{current[0].AddDate(-1, 0, 0), current[1].AddDate(-1, 0, 0)}
+	for i, name := range []string{"compare_from", "compare_to"} {
+		if s := q.Get(name); s != "" {
+			t, err := time.Parse(dateLayout, s)
+			if err != nil {
+				return current, previous, "Invalid " + name + " date"
+			}
+			previous[i] = t
+		}
+	}
+
+	if current[0].After(current[1]) || previous[0].After(previous[1]) {
+		return current, previous, "A period can't end before it starts"
+	}
+	return current, previous, ""
+}
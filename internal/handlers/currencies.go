@@ -0,0 +1,42 @@
+// handlers/currencies.go - Revenue by the currency projects were quoted in
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// CurrenciesPage shows revenue by currency, as quoted and in kronor
+func (h *Handler) CurrenciesPage(w http.ResponseWriter, r *http.Request) {
+	report, err := h.currencyReport()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Layout("FullDash Currencies", templates.CurrenciesPage(report)).Render(r.Context(), w)
+}
+
+// APICurrencies returns the report CurrenciesPage shows
+func (h *Handler) APICurrencies(w http.ResponseWriter, r *http.Request) {
+	report, err := h.currencyReport()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// currencyReport totals every project, archived too, at the stored rates
+func (h *Handler) currencyReport() (models.CurrencyReport, error) {
+	projects, err := h.DB.ListProjects(models.ProjectFilter{})
+	if err != nil {
+		return models.CurrencyReport{}, err
+	}
+	rates, err := h.DB.ListExchangeRates()
+	if err != nil {
+		return models.CurrencyReport{}, err
+	}
+	return models.NewCurrencyReport(projects, rates), nil
+}
@@ -0,0 +1,225 @@
+// handlers/documents.go - Contracts and other documents for clients and projects
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// maxDocumentSize caps uploaded document files
+const maxDocumentSize = 25 << 20
+
+// errUnsupportedDocument is returned for document uploads of an unknown type
+var errUnsupportedDocument = errors.New("documents must be PDF, Word, OpenDocument, text or an image")
+
+// documentExts are the accepted document file types
+var documentExts = map[string]bool{
+	".pdf": true, ".doc": true, ".docx": true, ".odt": true, ".txt": true, ".md": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".webp": true, ".heic": true,
+}
+
+// CreateProjectDocument uploads a document, or a new version of one, for a
+// project (multipart)
+func (h *Handler) CreateProjectDocument(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	p, err := h.DB.GetProject(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if !h.createDocument(w, r, &models.Document{ProjectID: p.ID}) {
+		return
+	}
+	h.renderProjectDocuments(w, r, p.ID)
+}
+
+// CreateClientDocument uploads a document, or a new version of one, for a
+// client (multipart)
+func (h *Handler) CreateClientDocument(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	report, err := h.DB.ClientReport(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if report == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if !h.createDocument(w, r, &models.Document{ClientID: report.Client.ID}) {
+		return
+	}
+	h.renderClientDocuments(w, r, report.Client.ID)
+}
+
+// createDocument fills d from a multipart upload form, saves its file and
+// stores it as the title's next version; it writes a 400 itself and reports
+// false if the form is invalid
+func (h *Handler) createDocument(w http.ResponseWriter, r *http.Request, d *models.Document) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxDocumentSize+1<<20)
+	if err := r.ParseMultipartForm(maxDocumentSize); err != nil {
+		http.Error(w, "The file is too large or the form is invalid", http.StatusBadRequest)
+		return false
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Choose a file to upload", http.StatusBadRequest)
+		return false
+	}
+	defer file.Close()
+
+	d.Kind = models.DocumentKind(r.FormValue("kind"))
+	if d.Kind == "" {
+		d.Kind = models.DocOther
+	}
+	d.Title = strings.TrimSpace(r.FormValue("title"))
+	if d.Title == "" {
+		d.Title = strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename))
+	}
+	d.FileName = filepath.Base(header.Filename)
+	d.SizeBytes = header.Size
+	d.SignedOn = optionalDate(r.FormValue("signed_on"))
+	d.ExpiresOn = optionalDate(r.FormValue("expires_on"))
+	if u := auth.UserFrom(r.Context()); u != nil {
+		d.UploadedBy = u.Name
+	}
+
+	switch {
+	case !d.Kind.Valid():
+		http.Error(w, "Unknown kind", http.StatusBadRequest)
+		return false
+	case d.SignedOn != nil && d.ExpiresOn != nil && d.ExpiresOn.Before(*d.SignedOn):
+		http.Error(w, "A document can't expire before it's signed", http.StatusBadRequest)
+		return false
+	case !documentExts[strings.ToLower(filepath.Ext(d.FileName))]:
+		http.Error(w, errUnsupportedDocument.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	if d.Path, err = saveUpload(h.documentDir(), file, d.FileName); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if err := h.DB.CreateDocument(d); err != nil {
+		os.Remove(filepath.Join(h.documentDir(), d.Path))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	triggerToast(w, "Saved "+d.Title+" (version "+strconv.Itoa(d.Version)+")")
+	return true
+}
+
+// DeleteDocument removes one version of a document and its file, then
+// re-renders the list it was deleted from: the client's (?client=) or the
+// project's
+func (h *Handler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+	d, ok := h.documentFromURL(w, r)
+	if !ok {
+		return
+	}
+	if err := h.DB.DeleteDocument(d.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	os.Remove(filepath.Join(h.documentDir(), d.Path))
+
+	if clientID, err := strconv.ParseInt(r.URL.Query().Get("client"), 10, 64); err == nil {
+		h.renderClientDocuments(w, r, clientID)
+		return
+	}
+	h.renderProjectDocuments(w, r, d.ProjectID)
+}
+
+// DocumentFile serves a document version's file under its uploaded name
+func (h *Handler) DocumentFile(w http.ResponseWriter, r *http.Request) {
+	d, ok := h.documentFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": d.FileName}))
+	http.ServeFile(w, r, filepath.Join(h.documentDir(), d.Path))
+}
+
+// documentFromURL loads the document named by {id}, writing errors itself
+func (h *Handler) documentFromURL(w http.ResponseWriter, r *http.Request) (*models.Document, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return nil, false
+	}
+	d, err := h.DB.GetDocument(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if d == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return nil, false
+	}
+	return d, true
+}
+
+// renderProjectDocuments re-renders a project's documents
+func (h *Handler) renderProjectDocuments(w http.ResponseWriter, r *http.Request, projectID int64) {
+	docs, err := h.DB.ListProjectDocuments(projectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.DocumentList(templates.DocumentView{ProjectID: projectID, Documents: models.DocumentHistories(docs)}, time.Now()).Render(r.Context(), w)
+}
+
+// renderClientDocuments re-renders a client's documents, its projects' too
+func (h *Handler) renderClientDocuments(w http.ResponseWriter, r *http.Request, clientID int64) {
+	report, err := h.DB.ClientReport(clientID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if report == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	templates.DocumentList(templates.ClientDocumentView(report), time.Now()).Render(r.Context(), w)
+}
+
+// documentDir is where document files are stored
+func (h *Handler) documentDir() string {
+	return filepath.Join(h.Config.UploadDir, "documents")
+}
+
+// optionalDate parses a form's date, nil if it's empty or invalid
+func optionalDate(s string) *time.Time {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
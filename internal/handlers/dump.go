@@ -0,0 +1,61 @@
+// handlers/dump.go - Whole-database JSON export and import for moving FullDash between machines
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/store"
+)
+
+// maxDumpSize caps an uploaded dump
+const maxDumpSize = 512 << 20
+
+// ExportDatabase downloads every table as fulldash-<date>.json (see
+// store.Export), for the import on another machine
+func (h *Handler) ExportDatabase(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="fulldash-%s.json"`, time.Now().Format(dateLayout)))
+	if _, err := h.DB.Export(w); err != nil {
+		// Headers are sent once rows are; a failure midway leaves the JSON
+		// cut short, which the import rejects
+		log.Printf("Database export: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ImportDatabase replaces all data with an uploaded dump (multipart,
+// "file") of the same schema version. Sessions go with the old users, so
+// the owner is signed out and sent to the login page.
+func (h *Handler) ImportDatabase(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxDumpSize+1<<20)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Choose a dump to import", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	sum, err := h.DB.Import(file)
+	if errors.Is(err, store.ErrDumpVersion) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Import failed, nothing was changed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.invalidateMetrics()
+	log.Printf("Database imported by %s: %d rows in %d tables, exported %s",
+		auth.UserFrom(r.Context()).Email, sum.Rows, sum.Tables, sum.ExportedAt.Format(time.RFC3339))
+
+	if c, err := r.Cookie(auth.CookieName); err == nil {
+		h.Sessions.Delete(c.Value)
+	}
+	http.SetCookie(w, &http.Cookie{Name: auth.CookieName, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+	redirect(w, r, "/login")
+}
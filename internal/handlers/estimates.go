@@ -0,0 +1,40 @@
+// handlers/estimates.go - How estimated hours compared with the hours logged on finished projects
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// EstimatesPage compares finished projects' estimates with their hours
+func (h *Handler) EstimatesPage(w http.ResponseWriter, r *http.Request) {
+	a, err := h.DB.EstimateAccuracy()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Layout("FullDash Estimates", templates.EstimatesPage(wf, a)).Render(r.Context(), w)
+}
+
+// APIEstimates returns the comparison EstimatesPage shows
+func (h *Handler) APIEstimates(w http.ResponseWriter, r *http.Request) {
+	a, err := h.DB.EstimateAccuracy()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if a.Projects == nil {
+		a.Projects = []models.EstimatedProject{}
+	}
+	if a.People == nil {
+		a.People = []models.PersonEstimate{}
+	}
+	writeJSON(w, http.StatusOK, a)
+}
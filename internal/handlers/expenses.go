@@ -0,0 +1,384 @@
+// handlers/expenses.go - Project and overhead expense handlers (with receipts)
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// maxReceiptSize caps uploaded receipt files
+const maxReceiptSize = 10 << 20
+
+// errUnsupportedReceipt is returned for receipt uploads of an unknown type
+var errUnsupportedReceipt = errors.New("receipt must be a PDF or image")
+
+// receiptExts are the accepted receipt file types
+var receiptExts = map[string]bool{".pdf": true, ".png": true, ".jpg": true, ".jpeg": true, ".webp": true, ".heic": true}
+
+// ExpensesPage lists a year's expenses across projects and overhead, with
+// per-category totals and a form to add one
+func (h *Handler) ExpensesPage(w http.ResponseWriter, r *http.Request) {
+	view, err := h.expenseView(expenseFilter(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	projects, err := h.DB.ListProjects(models.ProjectFilter{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	templates.Layout("FullDash Expenses", templates.ExpensesPage(view, projects)).Render(r.Context(), w)
+}
+
+// CreateExpense records an expense for a project (multipart, optional receipt)
+func (h *Handler) CreateExpense(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	e := &models.Expense{ProjectID: projectID}
+	if !h.parseExpense(w, r, e, false) {
+		return
+	}
+
+	if err := h.DB.CreateExpense(e); err != nil {
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	h.invalidateMetrics()
+
+	h.renderExpenses(w, r, projectID)
+}
+
+// AddExpense records an expense from the expenses page: for the project
+// picked in the form, or overhead when none is
+func (h *Handler) AddExpense(w http.ResponseWriter, r *http.Request) {
+	e := &models.Expense{}
+	if !h.parseExpense(w, r, e, true) {
+		return
+	}
+
+	if err := h.DB.CreateExpense(e); err != nil {
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	h.invalidateMetrics()
+
+	triggerToast(w, "Added "+e.Description)
+	h.renderExpenseLog(w, r, models.ExpenseFilter{Year: e.SpentOn.Year()})
+}
+
+// EditExpense renders an expense as an inline edit form on the expenses page
+func (h *Handler) EditExpense(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.expenseFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	projects, err := h.DB.ListProjects(models.ProjectFilter{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !e.Overhead() {
+		if p, err := h.DB.GetProject(e.ProjectID); err == nil && p != nil {
+			e.Client = p.Client
+		}
+	}
+
+	templates.ExpenseEditRow(e, expenseFilter(r), projects, people).Render(r.Context(), w)
+}
+
+// UpdateExpense saves an edited expense; a new receipt replaces the old one
+func (h *Handler) UpdateExpense(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.expenseFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	oldReceipt := e.ReceiptPath
+	if !h.parseExpense(w, r, e, true) {
+		return
+	}
+
+	if err := h.DB.UpdateExpense(e); err != nil {
+		if e.ReceiptPath != oldReceipt {
+			os.Remove(filepath.Join(h.receiptDir(), e.ReceiptPath))
+		}
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	h.invalidateMetrics()
+	if oldReceipt != "" && e.ReceiptPath != oldReceipt {
+		os.Remove(filepath.Join(h.receiptDir(), oldReceipt))
+	}
+
+	triggerToast(w, "Saved "+e.Description)
+	h.renderExpenseLog(w, r, expenseFilter(r))
+}
+
+// parseExpense fills e from a multipart expense form, saving any uploaded
+// receipt, and writes a 400 itself if the form is invalid. pickProject takes
+// the project from the form's project_id (empty for overhead) rather than
+// keeping e's.
+func (h *Handler) parseExpense(w http.ResponseWriter, r *http.Request, e *models.Expense, pickProject bool) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxReceiptSize+1<<20)
+	if err := r.ParseMultipartForm(maxReceiptSize); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return false
+	}
+
+	amount, err := parseCents(r.FormValue("amount"))
+	if err != nil || amount <= 0 {
+		http.Error(w, "Amount must be a positive number", http.StatusBadRequest)
+		return false
+	}
+
+	spentOn, err := time.Parse(dateLayout, r.FormValue("spent_on"))
+	if err != nil {
+		spentOn = time.Now()
+	}
+
+	category := models.ExpenseCategory(r.FormValue("category"))
+	if category == "" {
+		category = models.ExpenseOther
+	}
+	if !category.Valid() {
+		http.Error(w, "Unknown category", http.StatusBadRequest)
+		return false
+	}
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	e.PayerID, _ = strconv.ParseInt(r.FormValue("payer"), 10, 64)
+	e.Description = strings.TrimSpace(r.FormValue("description"))
+	e.Category = category
+	e.AmountCents = amount
+	e.SpentOn = spentOn
+	if _, ok := people.Get(e.PayerID); e.Description == "" || !ok {
+		http.Error(w, "Description and payer are required", http.StatusBadRequest)
+		return false
+	}
+	if pickProject && !h.parseExpenseProject(w, r, e) {
+		return false
+	}
+
+	if file, header, err := r.FormFile("receipt"); err == nil {
+		defer file.Close()
+		if e.ReceiptPath, err = h.saveReceipt(file, header.Filename); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
+
+// parseExpenseProject sets e's project from the form's project_id (empty
+// for overhead), writing a 400 itself for an unknown project
+func (h *Handler) parseExpenseProject(w http.ResponseWriter, r *http.Request, e *models.Expense) bool {
+	e.ProjectID = 0
+	if v := r.FormValue("project_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid project", http.StatusBadRequest)
+			return false
+		}
+		p, err := h.DB.GetProject(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return false
+		}
+		if p == nil {
+			http.Error(w, "Unknown project", http.StatusBadRequest)
+			return false
+		}
+		e.ProjectID = p.ID
+	}
+	return true
+}
+
+// DeleteExpense removes an expense and its receipt file
+func (h *Handler) DeleteExpense(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.expenseFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.DeleteExpense(e.ID); err != nil {
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	h.invalidateMetrics()
+	if e.ReceiptPath != "" {
+		os.Remove(filepath.Join(h.receiptDir(), e.ReceiptPath))
+	}
+
+	if r.Header.Get("HX-Target") == "expense-log" {
+		h.renderExpenseLog(w, r, expenseFilter(r))
+		return
+	}
+	h.renderExpenses(w, r, e.ProjectID)
+}
+
+// ExpenseReceipt serves an expense's receipt file
+func (h *Handler) ExpenseReceipt(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.expenseFromURL(w, r)
+	if !ok {
+		return
+	}
+	if e.ReceiptPath == "" {
+		http.Error(w, "No receipt", http.StatusNotFound)
+		return
+	}
+
+	http.ServeFile(w, r, filepath.Join(h.receiptDir(), e.ReceiptPath))
+}
+
+// expenseFromURL loads the expense named by {id}, writing errors itself
+func (h *Handler) expenseFromURL(w http.ResponseWriter, r *http.Request) (*models.Expense, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	e, err := h.DB.GetExpense(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if e == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return nil, false
+	}
+	return e, true
+}
+
+// renderExpenses re-renders a project's expense list
+func (h *Handler) renderExpenses(w http.ResponseWriter, r *http.Request, projectID int64) {
+	expenses, err := h.DB.ListExpenses(projectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.ExpenseList(projectID, expenses, people).Render(r.Context(), w)
+}
+
+// expenseFilter reads the expenses page's year and category from the query;
+// the year defaults to this one
+func expenseFilter(r *http.Request) models.ExpenseFilter {
+	f := models.ExpenseFilter{Category: models.ExpenseCategory(r.URL.Query().Get("category"))}
+	if !f.Category.Valid() {
+		f.Category = ""
+	}
+	year, err := strconv.Atoi(r.URL.Query().Get("year"))
+	if err != nil {
+		year = time.Now().Year()
+	}
+	f.Year = year
+	return f
+}
+
+// expenseView loads the expenses page's list and totals
+func (h *Handler) expenseView(f models.ExpenseFilter) (templates.ExpenseView, error) {
+	expenses, err := h.DB.ListAllExpenses(f)
+	if err != nil {
+		return templates.ExpenseView{}, err
+	}
+	totals, err := h.DB.ExpenseTotals(f.Year)
+	if err != nil {
+		return templates.ExpenseView{}, err
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		return templates.ExpenseView{}, err
+	}
+	return templates.ExpenseView{Filter: f, Expenses: expenses, Totals: totals, People: people}, nil
+}
+
+// renderExpenseLog re-renders the expenses page's list and totals
+func (h *Handler) renderExpenseLog(w http.ResponseWriter, r *http.Request, f models.ExpenseFilter) {
+	view, err := h.expenseView(f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.ExpenseLog(view).Render(r.Context(), w)
+}
+
+// receiptDir is where receipt files are stored
+func (h *Handler) receiptDir() string {
+	return filepath.Join(h.Config.UploadDir, "receipts")
+}
+
+// saveReceipt writes an uploaded receipt under a random name and returns that name
+func (h *Handler) saveReceipt(src io.Reader, original string) (string, error) {
+	if !receiptExts[strings.ToLower(filepath.Ext(original))] {
+		return "", errUnsupportedReceipt
+	}
+	return saveUpload(h.receiptDir(), src, original)
+}
+
+// saveUpload writes an uploaded file into dir under a random name, keeping
+// its extension, and returns that name
+func saveUpload(dir string, src io.Reader, original string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(original))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	name := hex.EncodeToString(buf) + ext
+
+	dst, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return "", err
+	}
+	return name, dst.Close()
+}
@@ -0,0 +1,309 @@
+// handlers/exports.go - CSV exports of projects, payments and hours for spreadsheets and the accountant
+package handlers
+
+import (
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// ExportProjects downloads projects.csv: the projects filtered as the
+// project list filters them (see parseProjectFilter), every one rather than
+// a page; ?archived=true includes archived projects
+func (h *Handler) ExportProjects(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f, msg := parseProjectFilter(q)
+	if v := q.Get("archived"); msg == "" && v != "" {
+		var err error
+		if f.Archived, err = strconv.ParseBool(v); err != nil {
+			msg = "archived must be true or false"
+		}
+	}
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	projects, err := h.DB.ListProjects(f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeCSVHeaders(w, "projects.csv")
+	cw := newCSVWriter(w, "projects.csv")
+	cw.Write([]string{"id", "client", "description", "status", "revenue", "vat_percent", "vat", "total", "currency", "original_amount",
+		"secured_by", "source", "created", "expected", "paid", "archived", "lost_reason", "estimated_hours", "logged_hours", "stripe_payment_id"})
+	for _, p := range projects {
+		var source string
+		if p.Source != "" {
+			source = p.Source.Label()
+		}
+		cw.Write([]string{
+			strconv.FormatInt(p.ID, 10),
+			p.Client,
+			p.Description,
+			wf.Label(p.Status),
+			csvAmount(p.RevenueCents),
+			strconv.FormatFloat(p.VATPercent, 'f', -1, 64),
+			csvAmount(p.VATCents()),
+			csvAmount(p.GrossCents()),
+			cmp.Or(p.Currency, models.BaseCurrency),
+			csvAmount(cmp.Or(p.OriginalCents, p.RevenueCents)),
+			people.Names(p.SecuredBy),
+			source,
+			p.CreatedAt.Format(dateLayout),
+			csvDate(p.ExpectedOn),
+			csvDate(p.PaidAt),
+			csvDate(p.ArchivedAt),
+			p.LostReason,
+			strconv.FormatFloat(p.EstimatedHours, 'f', -1, 64),
+			strconv.FormatFloat(p.LoggedHours, 'f', -1, 64),
+			p.StripePaymentID,
+		})
+	}
+	cw.Flush()
+}
+
+// exportPayment is a payment received, booked as the SIE export books it:
+// an invoice paid, or a project paid without one. Amounts are in kronor;
+// OriginalCents is the net amount in Currency.
+type exportPayment struct {
+	Date          time.Time
+	ProjectID     int64
+	Client        string
+	Invoice       string // the invoice's label; empty for a project paid directly
+	VATPercent    float64
+	NetCents      int64
+	VATCents      int64
+	Currency      string
+	OriginalCents int64
+}
+
+// ExportPayments downloads payments.csv: the payments received, oldest
+// first, filtered by ?from= and ?to= (the day paid, YYYY-MM-DD),
+// ?client= (ignoring case) and ?project_id=
+func (h *Handler) ExportPayments(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var from, to time.Time
+	var projectID int64
+	var err error
+	if v := q.Get("from"); v != "" {
+		if from, err = time.Parse(dateLayout, v); err != nil {
+			http.Error(w, "from must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if to, err = time.Parse(dateLayout, v); err != nil {
+			http.Error(w, "to must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("project_id"); v != "" {
+		if projectID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "project_id must be a project's ID", http.StatusBadRequest)
+			return
+		}
+	}
+	client := strings.TrimSpace(q.Get("client"))
+
+	payments, err := h.payments()
+	if errors.Is(err, errNoRate) {
+		http.Error(w, err.Error()+"; add one in Admin", http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	payments = slices.DeleteFunc(payments, func(p exportPayment) bool {
+		day := p.Date.Format(dateLayout)
+		return !from.IsZero() && day < from.Format(dateLayout) ||
+			!to.IsZero() && day > to.Format(dateLayout) ||
+			client != "" && !strings.EqualFold(p.Client, client) ||
+			projectID != 0 && p.ProjectID != projectID
+	})
+
+	writeCSVHeaders(w, "payments.csv")
+	cw := newCSVWriter(w, "payments.csv")
+	cw.Write([]string{"date", "project_id", "client", "invoice", "vat_percent", "net", "vat", "total", "currency", "original_net"})
+	for _, p := range payments {
+		cw.Write([]string{
+			p.Date.Format(dateLayout),
+			strconv.FormatInt(p.ProjectID, 10),
+			p.Client,
+			p.Invoice,
+			strconv.FormatFloat(p.VATPercent, 'f', -1, 64),
+			csvAmount(p.NetCents),
+			csvAmount(p.VATCents),
+			csvAmount(p.NetCents + p.VATCents),
+			p.Currency,
+			csvAmount(p.OriginalCents),
+		})
+	}
+	cw.Flush()
+}
+
+// payments lists every payment received in date order: paid invoices,
+// converted at the stored rate when foreign, and paid projects without an
+// issued invoice
+func (h *Handler) payments() ([]exportPayment, error) {
+	invoices, err := h.DB.ListInvoices("")
+	if err != nil {
+		return nil, err
+	}
+	var out []exportPayment
+	invoiced := map[int64]bool{}
+	for _, inv := range invoices {
+		if inv.Draft() || inv.Status == models.InvoiceVoid {
+			continue
+		}
+		invoiced[inv.ProjectID] = true
+		if inv.Status != models.InvoicePaid {
+			continue
+		}
+		subtotal, vat := inv.SubtotalCents(), inv.VATCents()
+		pay := exportPayment{
+			Date:          inv.PaidOn,
+			ProjectID:     inv.ProjectID,
+			Client:        inv.Client,
+			Invoice:       inv.Label(),
+			VATPercent:    inv.VATPercent,
+			NetCents:      subtotal,
+			VATCents:      vat,
+			Currency:      cmp.Or(inv.Currency, models.BaseCurrency),
+			OriginalCents: subtotal,
+		}
+		if inv.Currency != "" {
+			rate, err := h.DB.GetExchangeRate(inv.Currency)
+			if err != nil {
+				return nil, err
+			}
+			if rate == nil {
+				return nil, fmt.Errorf("%w for %s (invoice %s)", errNoRate, inv.Currency, inv.Label())
+			}
+			pay.NetCents, pay.VATCents = rate.Convert(subtotal), rate.Convert(vat)
+		}
+		out = append(out, pay)
+	}
+
+	paid, err := h.DB.ListProjectsByStatus(models.StatusPaid)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paid {
+		if invoiced[p.ID] {
+			continue
+		}
+		out = append(out, exportPayment{
+			Date:          p.PaymentTime(),
+			ProjectID:     p.ID,
+			Client:        p.Client,
+			VATPercent:    p.VATPercent,
+			NetCents:      p.RevenueCents,
+			VATCents:      p.VATCents(),
+			Currency:      cmp.Or(p.Currency, models.BaseCurrency),
+			OriginalCents: cmp.Or(p.OriginalCents, p.RevenueCents),
+		})
+	}
+	slices.SortStableFunc(out, func(x, y exportPayment) int { return x.Date.Compare(y.Date) })
+	return out, nil
+}
+
+// ExportHours downloads hours.csv: the dated time entries, filtered as
+// /api/v1/time-entries filters them (see parseTimeEntryFilter)
+func (h *Handler) ExportHours(w http.ResponseWriter, r *http.Request) {
+	f, msg := parseTimeEntryFilter(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	entries, err := h.DB.ListTimeEntries(f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeTimeEntriesCSV(w, "hours.csv", entries)
+}
+
+// writeCSVHeaders marks the response as a CSV attachment named name
+func writeCSVHeaders(w http.ResponseWriter, name string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+}
+
+// csvWriter writes a CSV export. Cells that a spreadsheet would run as a
+// formula are written as text, and a failed write is logged, since the
+// response has started and only a cut-off file shows it.
+type csvWriter struct {
+	*csv.Writer
+	name string
+}
+
+func newCSVWriter(w io.Writer, name string) csvWriter {
+	return csvWriter{Writer: csv.NewWriter(w), name: name}
+}
+
+// Write writes one row, with csvCell applied to each cell
+func (cw csvWriter) Write(record []string) error {
+	for i, cell := range record {
+		record[i] = csvCell(cell)
+	}
+	return cw.Writer.Write(record)
+}
+
+// Flush writes the buffered rows and logs the first error writing any
+func (cw csvWriter) Flush() {
+	cw.Writer.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("[EXPORT] Writing %s: %v", cw.name, err)
+	}
+}
+
+// csvCell quotes a cell starting with =, +, -, @, a tab or a carriage
+// return with ', so a spreadsheet shows it rather than evaluating it:
+// client names come from Stripe Checkout and imports. Numbers, negative
+// amounts among them, are left as they are.
+func csvCell(s string) string {
+	if s == "" || !strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return s
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return s
+	}
+	return "'" + s
+}
+
+// csvAmount formats cents as kronor with two decimals
+func csvAmount(cents int64) string {
+	return fmt.Sprintf("%.2f", float64(cents)/100)
+}
+
+// csvDate formats an optional date, empty when unset
+func csvDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(dateLayout)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/store"
+)
+
+func TestExportProjectsFormulaCells(t *testing.T) {
+	db, err := store.New(filepath.Join(t.TempDir(), "fulldash.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	client := `=HYPERLINK("http://evil","x")`
+	p := &models.Project{Client: client, Description: "@SUM(A1)", RevenueCents: -1250, Status: models.StatusNew}
+	if err := db.CreateProject(p); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &Handler{DB: db}
+	w := httptest.NewRecorder()
+	h.ExportProjects(w, httptest.NewRequest("GET", "/export/projects.csv", nil))
+	if w.Code != 200 {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want a header and one project", len(rows))
+	}
+	row := map[string]string{}
+	for i, col := range rows[0] {
+		row[col] = rows[1][i]
+	}
+	if got, want := row["client"], "'"+client; got != want {
+		t.Errorf("client = %q, want %q", got, want)
+	}
+	if got, want := row["description"], "'@SUM(A1)"; got != want {
+		t.Errorf("description = %q, want %q", got, want)
+	}
+	if got, want := row["revenue"], "-12.50"; got != want {
+		t.Errorf("revenue = %q, want %q", got, want)
+	}
+}
+
+func TestCSVCell(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"", ""},
+		{"Acme AB", "Acme AB"},
+		{"=1+1", "'=1+1"},
+		{"+46 70 123", "'+46 70 123"},
+		{"-cmd|' /C calc'!A0", "'-cmd|' /C calc'!A0"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+		{"-12.50", "-12.50"},
+		{"+3", "+3"},
+		{"a=b", "a=b"},
+	} {
+		if got := csvCell(tt.in); got != tt.want {
+			t.Errorf("csvCell(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
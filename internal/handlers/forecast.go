@@ -0,0 +1,33 @@
+// handlers/forecast.go - The weighted pipeline forecast on the dashboard and the API
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// APIForecast returns the open pipeline weighted by each project's chance
+// of payment, and the revenue expected within the next 30, 60 and 90 days
+func (h *Handler) APIForecast(w http.ResponseWriter, r *http.Request) {
+	f, err := h.forecast()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, f)
+}
+
+// forecast weighs the projects on the board as of today
+func (h *Handler) forecast() (models.Forecast, error) {
+	wf, err := h.workflow()
+	if err != nil {
+		return models.Forecast{}, err
+	}
+	projects, err := h.DB.ListProjects(models.ProjectFilter{})
+	if err != nil {
+		return models.Forecast{}, err
+	}
+	return wf.Forecast(projects, time.Now()), nil
+}
@@ -2,74 +2,293 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/noor-latif/fulldash/internal/models"
 )
 
 // ParsedForm holds all form values for project creation/update
 type ParsedForm struct {
-	Client      string
-	Description string
-	SecuredBy   models.Owner
-	Status      models.ProjectStatus
-	Revenue     float64
-	NoorHours   float64
-	AhmadHours  float64
+	Client       string
+	Description  string
+	SecuredBy    []int64 // person IDs, sorted
+	Status       models.ProjectStatus
+	RevenueCents int64
+
+	// Hours are totals per person, from the new-project form's hours_<id>
+	// fields; the edit form logs dated time entries instead, so they're
+	// absent there and left alone
+	Hours models.PerPerson[float64]
+
+	// SplitOverride is each person's fixed percentage for this project
+	// (split_<person id>); empty splits it the usual way
+	SplitOverride models.PerPerson[float64]
+
+	// Currency is set when the amount was quoted in a foreign currency;
+	// revenue is then converted from OriginalCents (see convertForm)
+	Currency      string
+	OriginalCents int64
+
+	// Amounts are excluding VAT; one entered including it (vat_inclusive)
+	// has had the VAT taken out
+	VATPercent   float64
+	VATInclusive bool
+
+	// Probability overrides the status's chance of payment (blank follows
+	// it); ExpectedOn is when payment is expected, if known
+	Probability *int
+	ExpectedOn  *time.Time
+
+	LostReason string            // why the deal fell through; required for lost, cleared otherwise
+	Source     models.LeadSource // where the lead came from; empty when not recorded
+
+	// EstimatedHours is the expected effort (0 = not estimated), optionally
+	// per person (estimate_<person id>); see estimate
+	EstimatedHours float64
+	HourEstimates  models.PerPerson[float64]
+
+	PaymentDays int // payment terms; DefaultPaymentDays when left out
+
+	// BudgetHours or BudgetCents is the budget (budget, in budget_unit "h"
+	// or "kr"), burned at HourlyCents (hourly_rate) per hour logged
+	BudgetHours float64
+	BudgetCents int64
+	HourlyCents int64
+
+	OverrideGate bool // move past a soft checklist gate (logged)
+	Refund       bool // a paid project's payment was refunded, so it may reopen (logged)
 }
 
-// parseProjectForm extracts and validates form data
-func parseProjectForm(r *http.Request) (*ParsedForm, error) {
+// parseProjectForm extracts and validates form data; hours and split
+// percentages are read from hours_<person id> and split_<person id> for each
+// of people
+func parseProjectForm(r *http.Request, people models.People) (*ParsedForm, error) {
 	if err := r.ParseForm(); err != nil {
 		return nil, err
 	}
 
-	revenue, _ := strconv.ParseFloat(r.FormValue("revenue"), 64)
-	noorHours, _ := strconv.ParseFloat(r.FormValue("noor_hours"), 64)
-	ahmadHours, _ := strconv.ParseFloat(r.FormValue("ahmad_hours"), 64)
+	revenue, _ := parseCents(r.FormValue("revenue"))
+	securedBy, ok := parseIDs(r.Form["secured_by"])
+	if !ok {
+		return nil, errors.New("invalid secured_by")
+	}
+	hours := models.PerPerson[float64]{}
+	var split, estimates models.PerPerson[float64]
+	for _, p := range people {
+		if key := fmt.Sprintf("hours_%d", p.ID); r.Form.Has(key) {
+			hours[p.ID], _ = strconv.ParseFloat(r.FormValue(key), 64)
+		}
+		if s := r.FormValue(fmt.Sprintf("estimate_%d", p.ID)); s != "" {
+			h, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, err
+			}
+			if h != 0 {
+				if estimates == nil {
+					estimates = models.PerPerson[float64]{}
+				}
+				estimates[p.ID] = h
+			}
+		}
+		s := r.FormValue(fmt.Sprintf("split_%d", p.ID))
+		if s == "" {
+			continue
+		}
+		pct, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		if pct != 0 {
+			if split == nil {
+				split = models.PerPerson[float64]{}
+			}
+			split[p.ID] = pct
+		}
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(r.FormValue("currency")))
+	var original int64
+	if currency == models.BaseCurrency {
+		currency = ""
+	}
+	if currency != "" {
+		original, _ = parseCents(r.FormValue("original_amount"))
+	}
+
+	vat := float64(models.DefaultVATPercent)
+	if s := r.FormValue("vat_percent"); s != "" {
+		var err error
+		if vat, err = strconv.ParseFloat(s, 64); err != nil {
+			return nil, err
+		}
+	}
+	inclusive := r.FormValue("vat_inclusive") == "on"
+	if inclusive {
+		revenue, original = models.ExcludingVAT(revenue, vat), models.ExcludingVAT(original, vat)
+	}
+
+	var probability *int
+	if s := r.FormValue("probability"); s != "" {
+		pct, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		probability = &pct
+	}
+	var expected *time.Time
+	if s := r.FormValue("expected_on"); s != "" {
+		t, err := time.Parse(dateLayout, s)
+		if err != nil {
+			return nil, err
+		}
+		expected = &t
+	}
+
+	var estimated float64
+	if s := r.FormValue("estimated_hours"); s != "" {
+		var err error
+		if estimated, err = strconv.ParseFloat(s, 64); err != nil {
+			return nil, err
+		}
+	}
+
+	paymentDays := models.DefaultPaymentDays
+	if s := r.FormValue("payment_days"); s != "" {
+		var err error
+		if paymentDays, err = strconv.Atoi(s); err != nil {
+			return nil, err
+		}
+	}
+
+	var budgetHours float64
+	var budgetCents, hourly int64
+	if s := r.FormValue("budget"); s != "" {
+		var err error
+		if r.FormValue("budget_unit") == "kr" {
+			budgetCents, err = parseCents(s)
+		} else {
+			budgetHours, err = strconv.ParseFloat(s, 64)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s := r.FormValue("hourly_rate"); s != "" {
+		var err error
+		if hourly, err = parseCents(s); err != nil {
+			return nil, err
+		}
+	}
 
 	status := models.ProjectStatus(r.FormValue("status"))
 	if status == "" {
 		status = models.StatusNew
 	}
+	reason := lostReason(status, r.FormValue("lost_reason"))
 
 	return &ParsedForm{
-		Client:      r.FormValue("client"),
-		Description: r.FormValue("description"),
-		SecuredBy:   models.Owner(r.FormValue("secured_by")),
-		Status:      status,
-		Revenue:     revenue,
-		NoorHours:   noorHours,
-		AhmadHours:  ahmadHours,
+		Client:       r.FormValue("client"),
+		Description:  r.FormValue("description"),
+		SecuredBy:    securedBy,
+		Status:       status,
+		RevenueCents: revenue,
+		Hours:        hours,
+
+		SplitOverride: split,
+
+		Currency:      currency,
+		OriginalCents: original,
+
+		VATPercent:   vat,
+		VATInclusive: inclusive,
+
+		Probability: probability,
+		ExpectedOn:  expected,
+
+		LostReason: reason,
+		Source:     models.LeadSource(r.FormValue("source")),
+
+		EstimatedHours: estimate(estimated, estimates),
+		HourEstimates:  estimates,
+
+		PaymentDays: paymentDays,
+
+		BudgetHours: budgetHours,
+		BudgetCents: budgetCents,
+		HourlyCents: hourly,
+
+		OverrideGate: checked(r, "override_gate"),
+		Refund:       checked(r, "refund"),
 	}, nil
 }
 
+// checked reports whether a form's checkbox was ticked; browsers send "on"
+// for a ticked box and nothing for an unticked one
+func checked(r *http.Request, name string) bool {
+	return r.FormValue(name) == "on"
+}
+
 // toProject converts form data to Project model
 func (f *ParsedForm) toProject() *models.Project {
 	return &models.Project{
-		Client:      f.Client,
-		Description: f.Description,
-		SecuredBy:   f.SecuredBy,
-		Status:      f.Status,
-		Revenue:     f.Revenue,
+		Client:       f.Client,
+		Description:  f.Description,
+		SecuredBy:    f.SecuredBy,
+		Status:       f.Status,
+		RevenueCents: f.RevenueCents,
+
+		Currency:      f.Currency,
+		OriginalCents: f.OriginalCents,
+		SplitOverride: f.SplitOverride,
+
+		VATPercent:   f.VATPercent,
+		VATInclusive: f.VATInclusive,
+
+		Probability: f.Probability,
+		ExpectedOn:  f.ExpectedOn,
+
+		LostReason: f.LostReason,
+		Source:     f.Source,
+
+		EstimatedHours: f.EstimatedHours,
+		HourEstimates:  f.HourEstimates,
+
+		PaymentDays: f.PaymentDays,
+
+		BudgetHours: f.BudgetHours,
+		BudgetCents: f.BudgetCents,
+		HourlyCents: f.HourlyCents,
 	}
 }
 
-// contribution returns contribution for given owner (if hours > 0)
-func (f *ParsedForm) contribution(owner models.Owner, projectID int64) *models.Contribution {
-	hours := f.NoorHours
-	if owner == models.OwnerAhmad {
-		hours = f.AhmadHours
+// lostReason is the reason kept for a project in status: trimmed for lost,
+// empty for any other status
+func lostReason(status models.ProjectStatus, reason string) string {
+	if status != models.StatusLost {
+		return ""
 	}
-	
+	return strings.TrimSpace(reason)
+}
+
+// contribution returns a person's contribution (if hours > 0)
+func (f *ParsedForm) contribution(personID, projectID int64) *models.Contribution {
+	hours := f.Hours[personID]
 	if hours <= 0 {
 		return nil
 	}
-	
+
 	return &models.Contribution{
 		ProjectID: projectID,
-		Owner:     owner,
+		PersonID:  personID,
 		Hours:     hours,
 	}
 }
@@ -80,13 +299,83 @@ func (f *ParsedForm) applyTo(p *models.Project) {
 	p.Description = f.Description
 	p.SecuredBy = f.SecuredBy
 	p.Status = f.Status
-	p.Revenue = f.Revenue
+	p.RevenueCents = f.RevenueCents
+	p.Currency = f.Currency
+	p.OriginalCents = f.OriginalCents
+	p.SplitOverride = f.SplitOverride
+	p.VATPercent = f.VATPercent
+	p.VATInclusive = f.VATInclusive
+	p.Probability = f.Probability
+	p.ExpectedOn = f.ExpectedOn
+	p.LostReason = f.LostReason
+	p.Source = f.Source
+	p.EstimatedHours = f.EstimatedHours
+	p.HourEstimates = f.HourEstimates
+	p.PaymentDays = f.PaymentDays
+	p.BudgetHours = f.BudgetHours
+	p.BudgetCents = f.BudgetCents
+	p.HourlyCents = f.HourlyCents
+}
+
+// validProbability reports whether an optional probability is a whole
+// percentage; unset is valid (the status's applies)
+func validProbability(pct *int) bool {
+	return pct == nil || *pct >= 0 && *pct <= 100
+}
+
+// validSplit reports whether a split override names only known people with
+// percentages adding up to 100; an empty one (no override) is valid too
+func validSplit(split models.PerPerson[float64], people models.People) bool {
+	if len(split) == 0 {
+		return true
+	}
+	for id, pct := range split {
+		if _, ok := people.Get(id); !ok || !validPercent(pct) {
+			return false
+		}
+	}
+	return math.Abs(split.Total()-100) <= 0.01
 }
 
-// saveContributions saves both Noor and Ahmad contributions
-func (f *ParsedForm) saveContributions(db interface{ SetContribution(c *models.Contribution) error }, projectID int64) error {
-	for _, owner := range []models.Owner{models.OwnerNoor, models.OwnerAhmad} {
-		if c := f.contribution(owner, projectID); c != nil {
+// estimate is a project's estimated hours: the total given, or the sum of
+// the per-person estimates when only those were filled in
+func estimate(total float64, each models.PerPerson[float64]) float64 {
+	if total == 0 {
+		return each.Total()
+	}
+	return total
+}
+
+// validEstimate reports whether estimated hours aren't negative and any
+// per-person estimates name known people and fit within the total
+func validEstimate(total float64, each models.PerPerson[float64], people models.People) bool {
+	if total < 0 {
+		return false
+	}
+	for id, h := range each {
+		if _, ok := people.Get(id); !ok || h < 0 {
+			return false
+		}
+	}
+	return each.Total() <= total+0.01
+}
+
+// validBudget reports whether a budget isn't negative and is either hours or
+// an amount, which needs an hourly rate to burn it
+func validBudget(hours float64, cents, hourly int64) bool {
+	switch {
+	case hours < 0 || cents < 0 || hourly < 0:
+		return false
+	case hours > 0 && cents > 0:
+		return false
+	}
+	return cents == 0 || hourly > 0
+}
+
+// saveContributions saves everyone's contributions
+func (f *ParsedForm) saveContributions(db Store, projectID int64) error {
+	for _, id := range slices.Sorted(maps.Keys(f.Hours)) {
+		if c := f.contribution(id, projectID); c != nil {
 			if err := db.SetContribution(c); err != nil {
 				return err
 			}
@@ -94,3 +383,103 @@ func (f *ParsedForm) saveContributions(db interface{ SetContribution(c *models.C
 	}
 	return nil
 }
+
+// parseIDs reads repeated ID values (e.g. secured_by), sorted and deduplicated
+func parseIDs(values []string) ([]int64, bool) {
+	var ids []int64
+	for _, v := range values {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids), true
+}
+
+// parseCents converts a decimal amount ("123.45") to integer cents
+func parseCents(s string) (int64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(math.Round(f * 100)), nil
+}
+
+// parseProjectFilter reads board filters and sort order from query values:
+// search, status, secured_by, client, from and to (created, YYYY-MM-DD),
+// min_revenue, max_revenue and sort (a key, "-" prefixed for descending;
+// due_date is the expected payment date). Projects have no tags, so a tag
+// filter is refused rather than ignored.
+func parseProjectFilter(q url.Values) (models.ProjectFilter, string) {
+	if q.Has("tag") {
+		return models.ProjectFilter{}, "tag isn't supported: projects have no tags"
+	}
+	f := models.ProjectFilter{
+		Search: strings.TrimSpace(q.Get("search")),
+		Status: models.ProjectStatus(q.Get("status")),
+		Client: strings.TrimSpace(q.Get("client")),
+	}
+	if v := q.Get("secured_by"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return f, "invalid secured_by"
+		}
+		f.SecuredBy = id
+	}
+
+	var err error
+	if v := q.Get("from"); v != "" {
+		if f.From, err = time.Parse(dateLayout, v); err != nil {
+			return f, "from must be YYYY-MM-DD"
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if f.To, err = time.Parse(dateLayout, v); err != nil {
+			return f, "to must be YYYY-MM-DD"
+		}
+	}
+
+	for name, dest := range map[string]**int64{"min_revenue": &f.MinRevenueCents, "max_revenue": &f.MaxRevenueCents} {
+		v := strings.Replace(strings.TrimSpace(q.Get(name)), ",", ".", 1)
+		if v == "" {
+			continue
+		}
+		n, err := parseCents(v)
+		if err != nil {
+			return f, name + " must be a number"
+		}
+		*dest = &n
+	}
+
+	if v := q.Get("sort"); v != "" {
+		f.Sort = models.ProjectSort(strings.TrimPrefix(v, "-"))
+		f.Desc = strings.HasPrefix(v, "-")
+		if !f.Sort.Valid() {
+			return f, "sort must be one of created_at, revenue, client or due_date (the expected payment date, expected_on); prefix - for descending"
+		}
+	}
+	return f, ""
+}
+
+// parsePage reads ?limit= and ?offset=; the limit defaults to def and may not
+// exceed max
+func parsePage(q url.Values, def, max int) (models.Page, string) {
+	p := models.Page{Limit: def}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > max {
+			return p, "limit must be between 1 and " + strconv.Itoa(max)
+		}
+		p.Limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return p, "offset must be a non-negative number"
+		}
+		p.Offset = n
+	}
+	return p, ""
+}
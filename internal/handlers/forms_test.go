@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+func TestParseProjectFilter(t *testing.T) {
+	for _, tt := range []struct {
+		query   string
+		wantMsg string // part of the error; "" for none
+	}{
+		{"sort=-due_date", ""},
+		{"status=new&min_revenue=1000,50", ""},
+		{"tag=urgent", "no tags"},
+		{"tag=", "no tags"},
+		{"sort=due", "expected payment date"},
+		{"from=2026-13-01", "YYYY-MM-DD"},
+	} {
+		q, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, msg := parseProjectFilter(q)
+		if (msg == "") != (tt.wantMsg == "") || !strings.Contains(msg, tt.wantMsg) {
+			t.Errorf("%s: %q, want %q", tt.query, msg, tt.wantMsg)
+		}
+	}
+
+	f, _ := parseProjectFilter(url.Values{"sort": {"-due_date"}})
+	if f.Sort != models.SortDue || !f.Desc {
+		t.Errorf("sort=-due_date parsed as %q desc %v", f.Sort, f.Desc)
+	}
+}
@@ -0,0 +1,111 @@
+// handlers/fortnox.go - Pushing issued invoices and their payments to Fortnox
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/noor-latif/fulldash/internal/fortnox"
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// fortnoxConnected reports whether credentials for Fortnox are saved
+func (h *Handler) fortnoxConnected() (bool, error) {
+	c, err := h.DB.FortnoxCredentials()
+	return c.Connected(), err
+}
+
+// syncFortnox pushes what Fortnox is missing of an invoice: the invoice
+// once issued, then its payment once paid. The outcome, including a
+// failure, is recorded on the invoice and inv is updated to match. Nothing
+// happens while Fortnox isn't connected.
+func (h *Handler) syncFortnox(ctx context.Context, inv *models.Invoice) error {
+	connected, err := h.fortnoxConnected()
+	if err != nil || !connected || !inv.FortnoxDue() {
+		return err
+	}
+
+	s := inv.Fortnox
+	s.Error = ""
+	err = h.pushFortnox(ctx, inv, &s)
+	if err != nil {
+		s.Error = err.Error()
+		log.Printf("Fortnox sync of invoice %s: %v", inv.Label(), err)
+	}
+	if dbErr := h.DB.SetFortnoxSync(inv.ID, s); dbErr != nil {
+		return dbErr
+	}
+	fresh, dbErr := h.DB.GetInvoice(inv.ID)
+	if dbErr != nil {
+		return dbErr
+	}
+	if fresh != nil {
+		*inv = *fresh
+	}
+	return err
+}
+
+// pushFortnox does the pushing for syncFortnox, noting each step in s as it
+// succeeds so a retry picks up where a failure stopped
+func (h *Handler) pushFortnox(ctx context.Context, inv *models.Invoice, s *models.FortnoxSync) error {
+	if s.Number == "" {
+		accounts, err := h.DB.Accounts()
+		if err != nil {
+			return err
+		}
+		rows := make([]fortnox.Row, 0, len(inv.Lines))
+		for _, l := range inv.Lines {
+			rows = append(rows, fortnox.Row{
+				Description: l.Description,
+				Quantity:    l.Quantity,
+				PriceCents:  l.UnitCents,
+				VATPercent:  inv.VATPercent,
+				Account:     accounts.SalesAccount(inv.VATPercent),
+			})
+		}
+		s.Number, err = h.Fortnox.PushInvoice(ctx, fortnox.Invoice{
+			Customer:    inv.Client,
+			Reference:   inv.Number,
+			InvoiceDate: inv.IssueDate,
+			DueDate:     inv.DueDate,
+			Currency:    inv.Currency,
+			Rows:        rows,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if inv.Status == models.InvoicePaid && !s.Paid {
+		if err := h.Fortnox.PushPayment(ctx, s.Number, inv.TotalCents(), inv.PaidOn); err != nil {
+			return err
+		}
+		s.Paid = true
+	}
+	return nil
+}
+
+// SyncInvoiceFortnox retries pushing an invoice to Fortnox
+func (h *Handler) SyncInvoiceFortnox(w http.ResponseWriter, r *http.Request) {
+	inv, ok := h.invoiceFromURL(w, r)
+	if !ok {
+		return
+	}
+	connected, err := h.fortnoxConnected()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !connected {
+		http.Error(w, "Fortnox isn't connected; add credentials in Admin", http.StatusConflict)
+		return
+	}
+
+	if err := h.syncFortnox(r.Context(), inv); err != nil {
+		triggerToast(w, "Fortnox sync failed: "+err.Error())
+	} else {
+		triggerToast(w, fmt.Sprintf("Invoice %s synced to Fortnox", inv.Label()))
+	}
+	h.renderInvoice(w, r, inv)
+}
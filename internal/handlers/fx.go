@@ -0,0 +1,149 @@
+// handlers/fx.go - Exchange rates and foreign-currency project amounts
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/fx"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// errNoRate is returned for an amount in a currency without a stored rate
+var errNoRate = errors.New("no exchange rate")
+
+// SaveExchangeRate stores a currency's rate to the base currency, replacing
+// any previous one
+func (h *Handler) SaveExchangeRate(w http.ResponseWriter, r *http.Request) {
+	rate := &models.ExchangeRate{Currency: strings.ToUpper(strings.TrimSpace(r.FormValue("currency")))}
+	rate.Rate, _ = strconv.ParseFloat(r.FormValue("rate"), 64)
+
+	var msg string
+	switch {
+	case !models.ValidCurrency(rate.Currency):
+		msg = "Currencies are three-letter codes like EUR"
+	case rate.Currency == models.BaseCurrency:
+		msg = models.BaseCurrency + " is the base currency"
+	case rate.Rate <= 0:
+		msg = "The rate must be above zero"
+	}
+	if msg != "" {
+		triggerToast(w, msg)
+		h.renderExchangeRates(w, r)
+		return
+	}
+
+	if err := h.DB.SetExchangeRate(rate); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	triggerToast(w, "Saved the "+rate.Currency+" rate")
+	h.renderExchangeRates(w, r)
+}
+
+// DeleteExchangeRate removes a currency's rate
+func (h *Handler) DeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	if err := h.DB.DeleteExchangeRate(chi.URLParam(r, "currency")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderExchangeRates(w, r)
+}
+
+// renderExchangeRates renders the admin exchange rate panel
+func (h *Handler) renderExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.DB.ListExchangeRates()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.ExchangeRates(rates, h.Config.FXProvider).Render(r.Context(), w)
+}
+
+// RefreshExchangeRates updates the stored rates from the provider now,
+// rather than at the next daily refresh
+func (h *Handler) RefreshExchangeRates(w http.ResponseWriter, r *http.Request) {
+	if !h.Jobs.RunNow(r.Context(), fx.JobName) {
+		triggerToast(w, "No exchange rate provider is set up (FX_PROVIDER)")
+	} else if msg := h.jobError(fx.JobName); msg != "" {
+		triggerToast(w, "Refreshing the rates failed: "+msg)
+	} else {
+		triggerToast(w, "Refreshed the exchange rates")
+	}
+	h.renderExchangeRates(w, r)
+}
+
+// jobError is the error a job's last run ended with, "" if it succeeded
+func (h *Handler) jobError(name string) string {
+	for _, s := range h.Jobs.Statuses() {
+		if s.Name == name {
+			return s.LastErr
+		}
+	}
+	return ""
+}
+
+// ConvertAmount previews a foreign amount in the base currency as it's typed
+// into the project form, and swaps the form's revenue field to match
+func (h *Handler) ConvertAmount(w http.ResponseWriter, r *http.Request) {
+	currency := strings.ToUpper(r.FormValue("currency"))
+	amount, _ := parseCents(r.FormValue("original_amount"))
+	revenue, _ := parseCents(r.FormValue("revenue"))
+	if currency == "" || currency == models.BaseCurrency {
+		templates.Conversion("", amount, revenue, nil).Render(r.Context(), w)
+		return
+	}
+
+	rate, err := h.DB.GetExchangeRate(currency)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Conversion(currency, amount, revenue, rate).Render(r.Context(), w)
+}
+
+// foreignRevenue converts an amount in a foreign currency at the stored rate.
+// An amount unchanged from the stored project keeps its conversion, so
+// re-saving a project doesn't move its revenue with the rate.
+func (h *Handler) foreignRevenue(currency string, cents int64, stored *models.Project) (int64, error) {
+	if stored != nil && stored.Currency == currency && stored.OriginalCents == cents {
+		return stored.RevenueCents, nil
+	}
+	rate, err := h.DB.GetExchangeRate(currency)
+	if err != nil {
+		return 0, err
+	}
+	if rate == nil {
+		return 0, fmt.Errorf("%w for %s", errNoRate, currency)
+	}
+	return rate.Convert(cents), nil
+}
+
+// convertForm sets a form's revenue from its foreign amount (stored is nil
+// for a new project). A currency without a rate is answered with a toast and
+// 422; it reports false once it has written a response.
+func (h *Handler) convertForm(w http.ResponseWriter, f *ParsedForm, stored *models.Project) bool {
+	if f.Currency == "" {
+		return true
+	}
+	revenue, err := h.foreignRevenue(f.Currency, f.OriginalCents, stored)
+	if errors.Is(err, errNoRate) {
+		msg := "No exchange rate for " + f.Currency + " (add one in Admin)"
+		triggerToast(w, msg)
+		http.Error(w, msg, http.StatusUnprocessableEntity)
+		return false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	f.RevenueCents = revenue
+	return true
+}
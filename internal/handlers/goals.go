@@ -0,0 +1,110 @@
+// handlers/goals.go - Revenue goals: set in Admin, tracked on the dashboard
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// SaveGoal sets the revenue target for the month, quarter or year holding
+// the chosen month, combined or for one person; a goal already set for the
+// same period and person is replaced
+func (h *Handler) SaveGoal(w http.ResponseWriter, r *http.Request) {
+	g := &models.Goal{Granularity: models.Granularity(r.FormValue("granularity"))}
+	month, err := time.Parse(monthLayout, r.FormValue("month"))
+	if err != nil || !g.Granularity.Valid() {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	g.Start = g.Granularity.Start(month)
+	if s := r.FormValue("person_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		g.PersonID = &id
+	}
+	g.TargetCents, err = parseCents(r.FormValue("target"))
+	if err != nil || g.TargetCents <= 0 {
+		triggerToast(w, "The target must be an amount above zero")
+		h.renderGoals(w, r)
+		return
+	}
+
+	if err := h.DB.SaveGoal(g); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	triggerToast(w, "Saved the goal for "+g.Label())
+	h.renderGoals(w, r)
+}
+
+// DeleteGoal removes a goal
+func (h *Handler) DeleteGoal(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := h.DB.DeleteGoal(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderGoals(w, r)
+}
+
+// renderGoals renders the admin goals panel
+func (h *Handler) renderGoals(w http.ResponseWriter, r *http.Request) {
+	goals, err := h.DB.ListGoals()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Goals(goals, people).Render(r.Context(), w)
+}
+
+// goalProgress measures the goals whose period is under way against the
+// revenue paid in it so far: the total for a combined goal, the person's
+// share for theirs
+func (h *Handler) goalProgress(now time.Time) ([]models.GoalProgress, error) {
+	goals, err := h.DB.ListGoals()
+	if err != nil {
+		return nil, err
+	}
+
+	var progress []models.GoalProgress
+	paid := map[models.Granularity]models.RevenuePeriod{} // the current period's, by granularity
+	for _, g := range goals {
+		if !g.Current(now) {
+			continue
+		}
+		period, ok := paid[g.Granularity]
+		if !ok {
+			series, err := h.DB.RevenueSeries(g.Granularity, g.Start, g.Start)
+			if err != nil {
+				return nil, err
+			}
+			period = series[0]
+			paid[g.Granularity] = period
+		}
+		actual := period.RevenueCents
+		if g.PersonID != nil {
+			actual = period.ShareCents[*g.PersonID]
+		}
+		progress = append(progress, g.Progress(actual, now))
+	}
+	return progress, nil
+}
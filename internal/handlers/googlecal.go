@@ -0,0 +1,156 @@
+// handlers/googlecal.go - Syncing the due dates to Google Calendar, and milestone dates back
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/gcal"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// SyncGoogleCalendar brings the connected Google Calendar in line with the
+// due dates (see dueDates) and records the outcome for Admin. Nothing
+// happens while Google Calendar isn't connected.
+func (h *Handler) SyncGoogleCalendar(ctx context.Context) error {
+	creds, err := h.DB.GoogleCalendarCredentials()
+	if err != nil || !creds.Connected() {
+		return err
+	}
+
+	s := models.GoogleCalendarSync{At: time.Now()}
+	err = h.syncGoogleCalendar(ctx, creds.Calendar(), &s)
+	if err != nil {
+		s.Error = err.Error()
+	}
+	if dbErr := h.DB.SetGoogleCalendarSync(s); dbErr != nil {
+		return dbErr
+	}
+	return err
+}
+
+// syncGoogleCalendar does the syncing for SyncGoogleCalendar, counting in s
+// what it did. Each due date that isn't done gets an event, updated when
+// it changes; events of done or gone ones are deleted. A milestone's event
+// moved in Google, while the milestone kept the date it was given, moves
+// the milestone: FullDash wins when both moved. Other events are put back.
+func (h *Handler) syncGoogleCalendar(ctx context.Context, calendarID string, s *models.GoogleCalendarSync) error {
+	dates, err := h.dueDates(0)
+	if err != nil {
+		return err
+	}
+	existing, err := h.GoogleCal.Events(ctx, calendarID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	have := map[string]gcal.Event{}
+	for _, e := range existing {
+		if _, dup := have[e.Key]; dup || e.Key == "" {
+			// a second copy, e.g. from a sync cut off before it was noted
+			if err := h.GoogleCal.Delete(ctx, calendarID, e.ID); err != nil {
+				errs = append(errs, err)
+			} else {
+				s.Deleted++
+			}
+			continue
+		}
+		have[e.Key] = e
+	}
+
+	for i, d := range dates {
+		e, ok := have[d.Key]
+		if !ok || d.MilestoneID == 0 || d.Done || e.Date.Equal(e.PushedDate) || !calendarDay(d.Day).Equal(e.PushedDate) {
+			continue
+		}
+		m, err := h.DB.GetMilestone(d.MilestoneID)
+		if err != nil || m == nil {
+			errs = append(errs, err)
+			continue
+		}
+		m.DueOn = e.Date
+		if err := h.DB.UpdateMilestone(m); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Printf("Google Calendar: milestone %d moved to %s", m.ID, e.Date.Format(time.DateOnly))
+		dates[i].Day = e.Date
+		s.Pulled++
+	}
+
+	for _, d := range dates {
+		if d.Done {
+			continue
+		}
+		want := gcal.Event{
+			Key:         d.Key,
+			Date:        calendarDay(d.Day),
+			PushedDate:  calendarDay(d.Day),
+			Summary:     d.Summary,
+			Description: d.Description,
+			Recurrence:  d.Repeat,
+		}
+		if h.Config.BaseURL != "" && d.ProjectID != 0 {
+			want.URL = fmt.Sprintf("%s/#project-%d", h.Config.BaseURL, d.ProjectID)
+		}
+
+		e, ok := have[d.Key]
+		delete(have, d.Key)
+		switch {
+		case !ok:
+			if _, err := h.GoogleCal.Insert(ctx, calendarID, want); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			s.Created++
+		case !e.Same(want):
+			want.ID = e.ID
+			if err := h.GoogleCal.Update(ctx, calendarID, want); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			s.Updated++
+		}
+		s.Events++
+	}
+
+	for _, e := range have {
+		if err := h.GoogleCal.Delete(ctx, calendarID, e.ID); err != nil {
+			errs = append(errs, err)
+			s.Events++
+			continue
+		}
+		s.Deleted++
+	}
+	return errors.Join(errs...)
+}
+
+// calendarDay is t's date as a UTC midnight, as dates come back from Google
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// RunGoogleCalendarSync syncs Google Calendar immediately and re-renders
+// its status
+func (h *Handler) RunGoogleCalendarSync(w http.ResponseWriter, r *http.Request) {
+	h.Jobs.RunNow(r.Context(), GoogleCalendarJob)
+
+	creds, err := h.DB.GoogleCalendarCredentials()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s, err := h.DB.GoogleCalendarSync()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	templates.GoogleCalendarStatus(s, creds.Connected()).Render(r.Context(), w)
+}
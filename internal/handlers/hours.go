@@ -0,0 +1,94 @@
+// handlers/hours.go - The monthly hours report: hours and hourly earnings per person and project
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// HoursReportPage shows each person's hours in ?month= (YYYY-MM, default
+// this month) per project, with what they earned per hour; ?format=csv
+// downloads the rows
+func (h *Handler) HoursReportPage(w http.ResponseWriter, r *http.Request) {
+	month, msg := parseMonth(r.URL.Query(), time.Now())
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.DB.HoursReport(month)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("format") == "csv" {
+		writeHoursCSV(w, report)
+		return
+	}
+	templates.Layout("FullDash Hours", templates.HoursReportPage(report)).Render(r.Context(), w)
+}
+
+// APIHoursReport returns the report HoursReportPage shows
+func (h *Handler) APIHoursReport(w http.ResponseWriter, r *http.Request) {
+	month, msg := parseMonth(r.URL.Query(), time.Now())
+	if msg != "" {
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	report, err := h.DB.HoursReport(month)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if report.People == nil {
+		report.People = []models.PersonHours{}
+	}
+	if report.Rows == nil {
+		report.Rows = []models.ProjectHours{}
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// parseMonth reads ?month= as the first day of the month, this month by
+// default. A non-empty message means it was invalid.
+func parseMonth(q url.Values, now time.Time) (time.Time, string) {
+	if s := q.Get("month"); s != "" {
+		t, err := time.Parse(monthLayout, s)
+		if err != nil {
+			return time.Time{}, "month must be YYYY-MM"
+		}
+		return t, ""
+	}
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), ""
+}
+
+// writeHoursCSV streams a month's rows as a CSV attachment, one per person
+// and project, amounts in kronor
+func writeHoursCSV(w http.ResponseWriter, report models.HoursReport) {
+	name := fmt.Sprintf("hours-%s.csv", report.Month.Format(monthLayout))
+	writeCSVHeaders(w, name)
+
+	cw := newCSVWriter(w, name)
+	cw.Write([]string{"month", "person", "project_id", "client", "hours", "paid_this_month", "earned", "total_hours", "earned_per_hour"})
+	for _, r := range report.Rows {
+		cw.Write([]string{
+			report.Month.Format(monthLayout),
+			r.Person,
+			strconv.FormatInt(r.ProjectID, 10),
+			r.Client,
+			strconv.FormatFloat(r.Hours, 'f', 2, 64),
+			strconv.FormatBool(r.Paid),
+			fmt.Sprintf("%.2f", float64(r.EarnedCents)/100),
+			strconv.FormatFloat(r.TotalHours, 'f', 2, 64),
+			fmt.Sprintf("%.2f", float64(r.HourlyCents)/100),
+		})
+	}
+	cw.Flush()
+}
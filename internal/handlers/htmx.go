@@ -0,0 +1,41 @@
+// handlers/htmx.go - HTMX response helpers
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf16"
+)
+
+// isHTMX reports whether the request was issued by HTMX
+func isHTMX(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true"
+}
+
+// triggerToast asks the client to show a toast message via the HX-Trigger header
+func triggerToast(w http.ResponseWriter, message string) {
+	payload, err := json.Marshal(map[string]any{
+		"showToast": map[string]string{"message": message},
+	})
+	if err != nil {
+		return
+	}
+	w.Header().Set("HX-Trigger", asciiJSON(payload))
+}
+
+// asciiJSON escapes non-ASCII runes so JSON survives as an HTTP header value
+func asciiJSON(b []byte) string {
+	var sb strings.Builder
+	for _, r := range string(b) {
+		if r < 0x80 {
+			sb.WriteRune(r)
+			continue
+		}
+		for _, u := range utf16.Encode([]rune{r}) {
+			fmt.Fprintf(&sb, `\u%04x`, u)
+		}
+	}
+	return sb.String()
+}
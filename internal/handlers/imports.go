@@ -0,0 +1,358 @@
+// handlers/imports.go - The import wizard: projects in bulk from a CSV or a Trello board
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/domain"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/importer"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/service"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// maxImportSize caps an uploaded import file
+const maxImportSize = 5 << 20
+
+// importAliases are the column names each import field is guessed from,
+// lowercased with "_" and "-" as spaces; Trello's own columns are included
+var importAliases = map[string][]string{
+	"client":      {"client", "customer", "kund", "name", "card", "title", "project"},
+	"description": {"description", "desc", "notes", "beskrivning"},
+	"revenue":     {"revenue", "revenue cents", "amount", "value", "price", "belopp", "intäkt"},
+	"status":      {"status", "list", "stage", "column"},
+	"secured_by":  {"secured by", "owner", "members", "people", "person", "ansvarig"},
+	"expected_on": {"expected on", "expected", "expected payment", "due", "due date", "close date", "deadline"},
+	"source":      {"source", "lead source", "channel", "källa"},
+	"lost_reason": {"lost reason", "reason lost"},
+}
+
+// importLostReason is the reason given to lost projects imported without one
+const importLostReason = "Lost before the import"
+
+// peopleSeparators split a cell naming several people
+var peopleSeparators = regexp.MustCompile(`\s*(?:,|;|/|&|\band\b|\boch\b)\s*`)
+
+// ImportPage shows the import wizard's first step: choosing a file
+func (h *Handler) ImportPage(w http.ResponseWriter, r *http.Request) {
+	templates.Layout("FullDash Import", templates.ImportPage()).Render(r.Context(), w)
+}
+
+// UploadImport reads an uploaded CSV or Trello export (multipart, "file")
+// and shows the wizard's mapping step, with each field's column guessed
+// from the column names
+func (h *Handler) UploadImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize+1<<20)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		http.Error(w, "The file is too large or the form is invalid", http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Choose a file to import", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t, err := importer.Read(header.Filename, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	encoded, err := json.Marshal(t)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.renderImport(w, r, filepath.Base(header.Filename), string(encoded), true)
+}
+
+// CheckImport re-plans the import with the mapping as posted: the dry run
+// the wizard shows after every change
+func (h *Handler) CheckImport(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+	h.renderImport(w, r, r.FormValue("file_name"), r.FormValue("data"), false)
+}
+
+// renderImport plans an import and renders the wizard's mapping step
+func (h *Handler) renderImport(w http.ResponseWriter, r *http.Request, fileName, data string, guess bool) {
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	plan, err := planImport(r, wf, people, fileName, data, guess)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	templates.ImportWizard(plan, wf, people.Active()).Render(r.Context(), w)
+}
+
+// RunImport creates the projects of the import's valid rows, skipping the
+// rest, and shows what was done. Each project is logged and announced as
+// if it had been added on the board.
+func (h *Handler) RunImport(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	plan, err := planImport(r, wf, people, r.FormValue("file_name"), r.FormValue("data"), false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if plan.Valid() == 0 {
+		http.Error(w, "No row can be imported", http.StatusBadRequest)
+		return
+	}
+
+	var created int
+	var skipped []models.ImportRow
+	for _, row := range plan.Rows {
+		if row.Error != "" {
+			skipped = append(skipped, row)
+			continue
+		}
+		p := row.Project
+		if err := h.createProject(&p); err != nil {
+			http.Error(w, fmt.Sprintf("Row %d: %v (%d projects were imported before it)", row.Row, err, created), http.StatusInternalServerError)
+			return
+		}
+		snap := service.Snapshot(&p, nil)
+		if err := h.logActivity(r, &p, "created", nil, &snap, "imported from "+plan.FileName); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.publish(r, events.ProjectCreated, &p, "", events.SourceUser)
+		created++
+	}
+
+	triggerToast(w, fmt.Sprintf("Imported %d projects", created))
+	templates.ImportResult(plan.FileName, created, skipped).Render(r.Context(), w)
+}
+
+// planImport reads the table carried in data and maps it as the form says;
+// with guess, the mapping comes from the column names instead
+func planImport(r *http.Request, wf domain.Workflow, people models.People, fileName, data string, guess bool) (models.ImportPlan, error) {
+	var t importer.Table
+	if err := json.Unmarshal([]byte(data), &t); err != nil || len(t.Columns) == 0 {
+		return models.ImportPlan{}, errors.New("the import has expired; choose the file again")
+	}
+	for _, row := range t.Rows {
+		if len(row) != len(t.Columns) {
+			return models.ImportPlan{}, errors.New("the import is damaged; choose the file again")
+		}
+	}
+
+	plan := models.ImportPlan{FileName: fileName, Data: data, Columns: t.Columns, Mapping: map[string]int{}}
+	if guess {
+		plan.Mapping = guessMapping(t.Columns)
+	} else {
+		for _, f := range models.ImportFields {
+			if i, err := strconv.Atoi(r.FormValue("col_" + f.Key)); err == nil && i >= 0 && i < len(t.Columns) {
+				plan.Mapping[f.Key] = i
+			}
+		}
+	}
+
+	plan.DefaultStatus = models.ProjectStatus(r.FormValue("default_status"))
+	if !wf.Valid(plan.DefaultStatus) {
+		plan.DefaultStatus = wf.Initial()
+	}
+	statuses := map[string]models.ProjectStatus{}
+	if i := plan.Column("status"); i >= 0 {
+		for _, v := range t.Values(i) {
+			s := models.ProjectStatus(r.FormValue("status:" + v))
+			if !wf.Valid(s) {
+				s = guessStatus(wf, v, plan.DefaultStatus)
+			}
+			statuses[v] = s
+			plan.Statuses = append(plan.Statuses, models.ImportValue{Value: v, Status: s})
+		}
+	}
+
+	active := people.Active()
+	plan.DefaultPerson, _ = strconv.ParseInt(r.FormValue("default_person"), 10, 64)
+	if _, ok := active.Get(plan.DefaultPerson); !ok && len(active) > 0 {
+		plan.DefaultPerson = active[0].ID
+	}
+
+	for n, cells := range t.Rows {
+		row := models.ImportRow{Row: n + 1}
+		cell := func(key string) string {
+			if i := plan.Column(key); i >= 0 {
+				return cells[i]
+			}
+			return ""
+		}
+
+		in := projectInput{
+			Client:      cell("client"),
+			Description: cell("description"),
+			Status:      plan.DefaultStatus,
+			Source:      models.LeadSource(strings.ReplaceAll(strings.ToLower(cell("source")), " ", "_")),
+			LostReason:  cell("lost_reason"),
+		}
+		if s, ok := statuses[cell("status")]; ok {
+			in.Status = s
+		}
+		if in.Status == models.StatusLost && in.LostReason == "" {
+			in.LostReason = importLostReason
+		}
+		in.SecuredBy = matchPeople(active, cell("secured_by"))
+		if len(in.SecuredBy) == 0 && plan.DefaultPerson != 0 {
+			in.SecuredBy = []int64{plan.DefaultPerson}
+		}
+
+		revenue, revenueErr := parseAmount(cell("revenue"))
+		expected, expectedErr := parseImportDate(cell("expected_on"))
+		in.RevenueCents, in.ExpectedOn = revenue, expected
+		// validate fills the defaults applyTo needs, so it runs either way
+		msg := in.validate(wf, people)
+		switch {
+		case revenueErr != nil:
+			row.Error = "invalid revenue " + strconv.Quote(cell("revenue"))
+		case revenue < 0:
+			row.Error = "revenue can't be negative"
+		case expectedErr != nil:
+			row.Error = "invalid expected payment date " + strconv.Quote(cell("expected_on"))
+		default:
+			row.Error = msg
+		}
+		in.applyTo(&row.Project)
+		plan.Rows = append(plan.Rows, row)
+	}
+	return plan, nil
+}
+
+// guessMapping maps each field to the first column named like it, each
+// column at most once
+func guessMapping(columns []string) map[string]int {
+	m := map[string]int{}
+	taken := map[int]bool{}
+	for _, f := range models.ImportFields {
+		for _, alias := range importAliases[f.Key] {
+			i := columnNamed(columns, alias)
+			if i >= 0 && !taken[i] {
+				m[f.Key] = i
+				taken[i] = true
+				break
+			}
+		}
+	}
+	return m
+}
+
+// columnNamed is the index of the column called name, ignoring case and
+// "_" or "-" for spaces, or -1
+func columnNamed(columns []string, name string) int {
+	for i, c := range columns {
+		c = strings.ToLower(strings.NewReplacer("_", " ", "-", " ").Replace(c))
+		if strings.Join(strings.Fields(c), " ") == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// guessStatus is the status whose key or label is v, ignoring case, or def
+func guessStatus(wf domain.Workflow, v string, def models.ProjectStatus) models.ProjectStatus {
+	for _, s := range wf {
+		if strings.EqualFold(v, string(s.Key)) || strings.EqualFold(v, s.Label) {
+			return s.Key
+		}
+	}
+	return def
+}
+
+// matchPeople finds the people a cell names, by full or first name; names
+// matching no one are left out
+func matchPeople(people models.People, cell string) []int64 {
+	var ids []int64
+	for _, name := range peopleSeparators.Split(strings.TrimSpace(cell), -1) {
+		if name == "" {
+			continue
+		}
+		for _, p := range people {
+			first, _, _ := strings.Cut(p.Name, " ")
+			if strings.EqualFold(name, p.Name) || strings.EqualFold(name, first) || strings.EqualFold(strings.Fields(name)[0], p.Name) {
+				ids = append(ids, p.ID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// parseAmount reads an amount in kronor as spreadsheets write it: with
+// spaces or a separator between thousands, a decimal comma or point, and
+// "kr", "SEK" or ":-" after it. Blank is 0.
+func parseAmount(s string) (int64, error) {
+	s = strings.NewReplacer(" ", "", "\u00a0", "", "kr", "", "KR", "", "SEK", "", "sek", "", ":-", "").Replace(s)
+	if s == "" {
+		return 0, nil
+	}
+	comma, point := strings.LastIndex(s, ","), strings.LastIndex(s, ".")
+	switch {
+	case comma >= 0 && point >= 0 && comma < point: // 1,234.50
+		s = strings.ReplaceAll(s, ",", "")
+	case comma >= 0 && point >= 0: // 1.234,50
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.Replace(s, ",", ".", 1)
+	case strings.Count(s, ",") == 1 && len(s)-comma-1 != 3: // 1234,5
+		s = strings.Replace(s, ",", ".", 1)
+	default: // 1,234 or 1,234,567
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	return parseCents(s)
+}
+
+// parseImportDate reads a date as YYYY-MM-DD, optionally with a time after
+// it as Trello and spreadsheets add; blank is none
+func parseImportDate(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if len(s) > len(dateLayout) {
+		s = s[:len(dateLayout)]
+	}
+	t, err := time.Parse(dateLayout, strings.ReplaceAll(s, "/", "-"))
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
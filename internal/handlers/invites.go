@@ -0,0 +1,188 @@
+// handlers/invites.go - Calendar invites for meetings with a project's client
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/ical"
+	"github.com/noor-latif/fulldash/internal/mail"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// Meeting length limits, in minutes
+const (
+	inviteDefaultMinutes = 60
+	inviteMaxMinutes     = 8 * 60
+)
+
+// SendInvite emails a calendar invite for a meeting with the client and
+// records it in the project's history
+func (h *Handler) SendInvite(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.inviteProject(w, r)
+	if !ok {
+		return
+	}
+
+	inv, msg := parseInvite(r)
+	if msg == "" && len(inv.To) == 0 {
+		msg = "Add the client's email address"
+	}
+	if msg != "" {
+		triggerToast(w, msg)
+		templates.InviteForm(p.ID, h.Config.Mail.Enabled()).Render(r.Context(), w)
+		return
+	}
+
+	actor, _ := actorFrom(r)
+	err := h.sendMail(models.SentMail{Kind: models.MailInvite, ProjectID: p.ID, SentBy: actor}, mail.Message{
+		To:      inv.To,
+		Subject: inviteSummary(p, inv),
+		Body:    inviteBody(p, inv),
+		Attachments: []mail.Attachment{{
+			Name:        "invite.ics",
+			ContentType: `text/calendar; charset="utf-8"; method=` + ical.MethodRequest,
+			Data:        h.inviteCalendar(r, p, inv),
+		}},
+	})
+	if err != nil {
+		triggerToast(w, "Couldn't send the invite: "+err.Error())
+		templates.InviteForm(p.ID, h.Config.Mail.Enabled()).Render(r.Context(), w)
+		return
+	}
+
+	summary := fmt.Sprintf("%s on %s sent to %s", inv.Kind.Label(), inv.Start.Format("2006-01-02 15:04"), strings.Join(inv.To, ", "))
+	if err := h.logActivity(r, p, "invite", nil, nil, summary); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	triggerToast(w, "Invite sent to "+strings.Join(inv.To, ", "))
+	templates.InviteForm(p.ID, h.Config.Mail.Enabled()).Render(r.Context(), w)
+}
+
+// DownloadInvite returns the invite as an .ics file, to send some other way
+func (h *Handler) DownloadInvite(w http.ResponseWriter, r *http.Request) {
+	p, ok := h.inviteProject(w, r)
+	if !ok {
+		return
+	}
+
+	inv, msg := parseInvite(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", `text/calendar; charset=utf-8`)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%d.ics"`, inv.Kind, p.ID))
+	w.Write(h.inviteCalendar(r, p, inv))
+}
+
+// inviteProject loads the project named in the URL, writing the error
+// response itself when it can't
+func (h *Handler) inviteProject(w http.ResponseWriter, r *http.Request) (*models.Project, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	p, err := h.DB.GetProject(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if p == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return nil, false
+	}
+	return p, true
+}
+
+// parseInvite reads the invite form. Times are the server's local time, as
+// entered in a datetime-local input.
+func parseInvite(r *http.Request) (models.Invite, string) {
+	inv := models.Invite{
+		Kind:     models.MeetingKind(r.FormValue("kind")),
+		Location: strings.TrimSpace(r.FormValue("location")),
+		Notes:    strings.TrimSpace(r.FormValue("notes")),
+		Duration: inviteDefaultMinutes * time.Minute,
+	}
+	if !inv.Kind.Valid() {
+		return inv, "Choose a kickoff or review meeting"
+	}
+
+	start, err := time.ParseInLocation("2006-01-02T15:04", r.FormValue("start"), time.Local)
+	if err != nil {
+		return inv, "Choose when the meeting starts"
+	}
+	inv.Start = start
+
+	if s := r.FormValue("minutes"); s != "" {
+		minutes, err := strconv.Atoi(s)
+		if err != nil || minutes < 1 || minutes > inviteMaxMinutes {
+			return inv, fmt.Sprintf("A meeting lasts 1 to %d minutes", inviteMaxMinutes)
+		}
+		inv.Duration = time.Duration(minutes) * time.Minute
+	}
+
+	if to := strings.TrimSpace(r.FormValue("to")); to != "" {
+		if inv.To, err = mail.ParseAddresses(to); err != nil {
+			return inv, "Enter email addresses separated by commas"
+		}
+	}
+	return inv, ""
+}
+
+// inviteCalendar renders the invite as an iCalendar REQUEST. Each invite is
+// its own event, identified by project, kind and start.
+func (h *Handler) inviteCalendar(r *http.Request, p *models.Project, inv models.Invite) []byte {
+	host := r.Host
+	if u, err := url.Parse(h.absoluteURL(r, "/")); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+
+	var organizer string
+	if from, err := mail.ParseAddresses(h.Config.Mail.Address()); err == nil && len(from) == 1 {
+		organizer = from[0]
+	}
+	return ical.Calendar(ical.MethodRequest, ical.Event{
+		UID:         fmt.Sprintf("%s-%d-%d@%s", inv.Kind, p.ID, inv.Start.Unix(), host),
+		Start:       inv.Start,
+		End:         inv.Start.Add(inv.Duration),
+		Summary:     inviteSummary(p, inv),
+		Description: strings.TrimSpace(inviteBody(p, inv)),
+		Location:    inv.Location,
+		Organizer:   organizer,
+		Attendees:   inv.To,
+	})
+}
+
+// inviteSummary titles the meeting, e.g. "Kickoff meeting: Acme"
+func inviteSummary(p *models.Project, inv models.Invite) string {
+	return inv.Kind.Label() + ": " + p.Client
+}
+
+// inviteBody describes the meeting, for the email and the event
+func inviteBody(p *models.Project, inv models.Invite) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s for %s, %s (%d minutes).\n", inv.Kind.Label(), p.Client,
+		inv.Start.Format("Monday 2 January 2006, 15:04 MST"), int(inv.Duration.Minutes()))
+	if p.Description != "" {
+		fmt.Fprintf(&b, "\nProject: %s\n", p.Description)
+	}
+	if inv.Location != "" {
+		fmt.Fprintf(&b, "Where: %s\n", inv.Location)
+	}
+	if inv.Notes != "" {
+		fmt.Fprintf(&b, "\n%s\n", inv.Notes)
+	}
+	return b.String()
+}
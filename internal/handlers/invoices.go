@@ -0,0 +1,399 @@
+// handlers/invoices.go - Invoices: drafts generated from projects, issued with gapless numbers
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/mail"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// InvoicesPage lists invoices, filtered by ?status= (a status or "overdue")
+func (h *Handler) InvoicesPage(w http.ResponseWriter, r *http.Request) {
+	status := models.InvoiceStatus(r.URL.Query().Get("status"))
+	if status != "" && status != models.InvoiceOverdue && !status.Valid() {
+		http.Error(w, "Unknown status", http.StatusBadRequest)
+		return
+	}
+
+	invoices, err := h.DB.ListInvoices(status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fortnox, err := h.fortnoxConnected()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Layout("FullDash Invoices", templates.InvoicesPage(status, invoices, fortnox, time.Now())).Render(r.Context(), w)
+}
+
+// CreateInvoice generates a draft invoice from a project: one line for its
+// revenue (in its quoted currency) at its VAT rate, on the default payment
+// terms
+func (h *Handler) CreateInvoice(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.DB.GetProject(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	line := models.InvoiceLine{Description: p.Description, Quantity: 1, UnitCents: p.RevenueCents}
+	if line.Description == "" {
+		line.Description = p.Client
+	}
+	if p.Foreign() {
+		line.UnitCents = p.OriginalCents
+	}
+	inv := &models.Invoice{
+		ProjectID:   p.ID,
+		Client:      p.Client,
+		Lines:       []models.InvoiceLine{line},
+		Currency:    p.Currency,
+		VATPercent:  p.VATPercent,
+		PaymentDays: p.PaymentDays,
+	}
+	inv.CreatedBy, _ = actorFrom(r)
+	if err := h.DB.CreateInvoice(inv); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/invoices/%d", inv.ID))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// InvoicePage shows an invoice: a form while it's a draft, read-only once issued
+func (h *Handler) InvoicePage(w http.ResponseWriter, r *http.Request) {
+	inv, ok := h.invoiceFromURL(w, r)
+	if !ok {
+		return
+	}
+	m, err := h.invoiceMail(inv)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fortnox, err := h.fortnoxConnected()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Layout("FullDash Invoice "+inv.Label(), templates.InvoicePage(inv, m, fortnox, time.Now())).Render(r.Context(), w)
+}
+
+// InvoicePDF downloads an invoice as a PDF
+func (h *Handler) InvoicePDF(w http.ResponseWriter, r *http.Request) {
+	inv, ok := h.invoiceFromURL(w, r)
+	if !ok {
+		return
+	}
+	h.writeInvoicePDF(w, r, inv)
+}
+
+// writeInvoicePDF answers with an invoice's PDF
+func (h *Handler) writeInvoicePDF(w http.ResponseWriter, r *http.Request, inv *models.Invoice) {
+	company, err := h.DB.Company()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, templates.InvoiceFilename(inv)))
+	w.Write(templates.InvoicePDF(inv, company, h.invoicePayURL(r, inv)))
+}
+
+// invoicePayURL is a client portal link to pay an invoice online, while it
+// awaits payment and belongs to a project; otherwise ""
+func (h *Handler) invoicePayURL(r *http.Request, inv *models.Invoice) string {
+	if inv.Status != models.InvoiceIssued || inv.ProjectID == 0 {
+		return ""
+	}
+	// The link outlives the due date, so a late payer can still use it
+	expires := time.Now().Add(h.Config.PortalTTL)
+	if due := inv.DueDate.Add(h.Config.PortalTTL); due.After(expires) {
+		expires = due
+	}
+	return h.absoluteURL(r, "/portal/"+auth.SignPortalToken(h.Config.PortalSecret, inv.ProjectID, expires))
+}
+
+// EmailInvoice sends an issued invoice to the client as a PDF attachment,
+// to the addresses in the to field. Every attempt goes in the sent-mail log.
+func (h *Handler) EmailInvoice(w http.ResponseWriter, r *http.Request) {
+	inv, ok := h.invoiceFromURL(w, r)
+	if !ok {
+		return
+	}
+	if inv.Draft() {
+		http.Error(w, "Issue the invoice before sending it", http.StatusConflict)
+		return
+	}
+
+	to, err := mail.ParseAddresses(strings.TrimSpace(r.FormValue("to")))
+	if err != nil || len(to) == 0 {
+		triggerToast(w, "Enter email addresses separated by commas")
+		h.renderInvoice(w, r, inv)
+		return
+	}
+	company, err := h.DB.Company()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	payURL := h.invoicePayURL(r, inv)
+	subject, body := templates.InvoiceEmail(inv, company, payURL)
+	actor, _ := actorFrom(r)
+	err = h.sendMail(models.SentMail{Kind: models.MailInvoice, ProjectID: inv.ProjectID, InvoiceID: inv.ID, SentBy: actor}, mail.Message{
+		To:      to,
+		Subject: subject,
+		Body:    body,
+		Attachments: []mail.Attachment{{
+			Name:        templates.InvoiceFilename(inv),
+			ContentType: "application/pdf",
+			Data:        templates.InvoicePDF(inv, company, payURL),
+		}},
+	})
+	if err != nil {
+		triggerToast(w, "Couldn't send the invoice: "+err.Error())
+	} else {
+		triggerToast(w, fmt.Sprintf("Invoice %s sent to %s", inv.Number, strings.Join(to, ", ")))
+	}
+	h.renderInvoice(w, r, inv)
+}
+
+// UpdateInvoice saves a draft's client, lines, VAT and payment terms. Lines
+// come as parallel line_description, line_quantity and line_price fields;
+// blank rows are skipped.
+func (h *Handler) UpdateInvoice(w http.ResponseWriter, r *http.Request) {
+	inv, ok := h.invoiceFromURL(w, r)
+	if !ok {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	inv.Client = strings.TrimSpace(r.FormValue("client"))
+	if inv.Client == "" {
+		http.Error(w, "Client is required", http.StatusBadRequest)
+		return
+	}
+	vat, err := strconv.ParseFloat(r.FormValue("vat_percent"), 64)
+	if err != nil || vat < 0 || vat > 100 {
+		http.Error(w, "VAT must be a percentage between 0 and 100", http.StatusBadRequest)
+		return
+	}
+	days, err := strconv.Atoi(r.FormValue("payment_days"))
+	if err != nil || days < 0 {
+		http.Error(w, "Payment terms must be a number of days", http.StatusBadRequest)
+		return
+	}
+	lines, msg := parseLines(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	inv.VATPercent, inv.PaymentDays, inv.Lines = vat, days, lines
+
+	if err := h.DB.UpdateInvoice(inv); err != nil {
+		invoiceError(w, err)
+		return
+	}
+	triggerToast(w, "Draft saved")
+	h.renderInvoice(w, r, inv)
+}
+
+// parseLines reads a draft invoice's or quote's line rows; msg explains
+// what's wrong
+func parseLines(r *http.Request) (lines []models.InvoiceLine, msg string) {
+	descs, qtys, prices := r.Form["line_description"], r.Form["line_quantity"], r.Form["line_price"]
+	if len(qtys) != len(descs) || len(prices) != len(descs) {
+		return nil, "Each line needs a description, quantity and price"
+	}
+	for i, d := range descs {
+		d = strings.TrimSpace(d)
+		if d == "" && strings.TrimSpace(prices[i]) == "" {
+			continue
+		}
+		qty, err := strconv.ParseFloat(qtys[i], 64)
+		if err != nil || qty <= 0 {
+			return nil, fmt.Sprintf("Line %d: quantity must be a positive number", i+1)
+		}
+		price, err := parseCents(prices[i])
+		if err != nil {
+			return nil, fmt.Sprintf("Line %d: price must be a number", i+1)
+		}
+		if d == "" {
+			return nil, fmt.Sprintf("Line %d: description is required", i+1)
+		}
+		lines = append(lines, models.InvoiceLine{Description: d, Quantity: qty, UnitCents: price})
+	}
+	if len(lines) == 0 {
+		return nil, "Add at least one line"
+	}
+	return lines, ""
+}
+
+// IssueInvoice numbers a draft, dated today, and pushes it to Fortnox when
+// connected; a failed push leaves it issued, flagged for a retry
+func (h *Handler) IssueInvoice(w http.ResponseWriter, r *http.Request) {
+	inv, ok := h.invoiceFromURL(w, r)
+	if !ok {
+		return
+	}
+	if len(inv.Lines) == 0 {
+		http.Error(w, "An invoice needs at least one line", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.IssueInvoice(inv.ID, time.Now()); err != nil {
+		invoiceError(w, err)
+		return
+	}
+	inv, err := h.DB.GetInvoice(inv.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.syncFortnox(r.Context(), inv); err != nil {
+		triggerToast(w, "Issued invoice "+inv.Number+", but Fortnox sync failed: "+err.Error())
+	} else {
+		triggerToast(w, "Issued invoice "+inv.Number)
+	}
+	h.renderInvoice(w, r, inv)
+}
+
+// SetInvoiceStatus marks an issued invoice paid (today, or on paid_on) or
+// void, or undoes a payment marked by mistake. A payment is registered in
+// Fortnox when connected.
+func (h *Handler) SetInvoiceStatus(w http.ResponseWriter, r *http.Request) {
+	inv, ok := h.invoiceFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	to := models.InvoiceStatus(r.FormValue("status"))
+	if !inv.CanMove(to) {
+		http.Error(w, fmt.Sprintf("A %s invoice can't be marked %s", inv.Status, to), http.StatusConflict)
+		return
+	}
+	paidOn, err := time.Parse(dateLayout, r.FormValue("paid_on"))
+	if err != nil {
+		paidOn = time.Now()
+	}
+
+	if err := h.DB.SetInvoiceStatus(inv.ID, to, paidOn); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	inv, err = h.DB.GetInvoice(inv.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.syncFortnox(r.Context(), inv); err != nil {
+		triggerToast(w, fmt.Sprintf("Invoice %s is %s, but Fortnox sync failed: %v", inv.Number, inv.Status, err))
+	} else {
+		triggerToast(w, fmt.Sprintf("Invoice %s is %s", inv.Number, inv.Status))
+	}
+	h.renderInvoice(w, r, inv)
+}
+
+// DeleteInvoice discards a draft and returns to the invoice list
+func (h *Handler) DeleteInvoice(w http.ResponseWriter, r *http.Request) {
+	inv, ok := h.invoiceFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.DeleteInvoice(inv.ID); err != nil {
+		invoiceError(w, err)
+		return
+	}
+	w.Header().Set("HX-Redirect", "/invoices")
+}
+
+// renderInvoice renders an invoice's detail, with its email and Fortnox panels
+func (h *Handler) renderInvoice(w http.ResponseWriter, r *http.Request, inv *models.Invoice) {
+	m, err := h.invoiceMail(inv)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fortnox, err := h.fortnoxConnected()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.InvoiceDetail(inv, m, fortnox, time.Now()).Render(r.Context(), w)
+}
+
+// invoiceMail gathers an issued invoice's email panel: the client's
+// billing address to send it to and what has been sent so far
+func (h *Handler) invoiceMail(inv *models.Invoice) (templates.InvoiceMail, error) {
+	m := templates.InvoiceMail{Enabled: h.Config.Mail.Enabled()}
+	if inv.Draft() {
+		return m, nil
+	}
+	c, err := h.DB.GetClientByName(inv.Client)
+	if err != nil {
+		return m, err
+	}
+	if c != nil {
+		m.To = c.Email
+	}
+	m.Sent, err = h.DB.ListInvoiceMail(inv.ID)
+	return m, err
+}
+
+// invoiceFromURL loads the invoice named by the {id} URL parameter, writing
+// the error response when there is none
+func (h *Handler) invoiceFromURL(w http.ResponseWriter, r *http.Request) (*models.Invoice, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return nil, false
+	}
+	inv, err := h.DB.GetInvoice(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if inv == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return nil, false
+	}
+	return inv, true
+}
+
+// invoiceError answers a failed write: a conflict when the invoice has
+// been issued, otherwise a server error
+func invoiceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, models.ErrInvoiceIssued) {
+		triggerToast(w, err.Error())
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,147 @@
+// handlers/mail.go - Outgoing email: the sent-mail log, retries and payment receipts
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/mail"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// sentMailShown is how many emails the sent-mail log lists
+const sentMailShown = 200
+
+// MailRetryJob is the scheduler name of the job retrying failed emails
+const MailRetryJob = "mail-retry"
+
+// mailBackoff is the wait before each retry of an email that failed for a
+// reason that may pass (the relay or API unreachable, busy or rate
+// limiting). One still failing after the last is left failed.
+var mailBackoff = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+
+// mailClaimFor is how long a retry is held by the instance sending it
+const mailClaimFor = 5 * time.Minute
+
+// SentMailPage lists the most recent emails, failed ones included
+func (h *Handler) SentMailPage(w http.ResponseWriter, r *http.Request) {
+	sent, err := h.DB.ListSentMail(sentMailShown)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Layout("FullDash Sent mail", templates.SentMailPage(sent, h.Config.Mail.Enabled())).Render(r.Context(), w)
+}
+
+// sendMail sends m and records it in the sent-mail log whether or not it
+// went out; entry says what it was for and who sent it. A message that
+// failed for a reason that may pass is kept and retried by MailRetryJob;
+// the error returned then says so.
+func (h *Handler) sendMail(entry models.SentMail, m mail.Message) error {
+	entry.Recipients = strings.Join(m.To, ", ")
+	entry.Subject = m.Subject
+	entry.Provider = h.Config.Mail.Name()
+	entry.Attempts = 1
+
+	err := h.Config.Mail.Send(context.Background(), m)
+	if err != nil {
+		entry.Error = err.Error()
+		if !errors.Is(err, mail.ErrNotConfigured) {
+			log.Printf("[MAIL] %s to %s: %v", entry.Kind, entry.Recipients, err)
+		}
+		if !mail.Permanent(err) {
+			if msg, jerr := json.Marshal(m); jerr == nil {
+				next := time.Now().Add(mailBackoff[0])
+				entry.Message, entry.NextAttemptAt = string(msg), &next
+				err = fmt.Errorf("%w (will retry)", err)
+			}
+		}
+	}
+	if err := h.DB.LogSentMail(&entry); err != nil {
+		log.Printf("[MAIL] Log error: %v", err)
+	}
+	return err
+}
+
+// RetryMail resends the emails whose retry is due, recording each outcome
+// in the sent-mail log. Messages still failing are logged there, not
+// reported as the job failing.
+func (h *Handler) RetryMail(ctx context.Context) error {
+	due, err := h.DB.DueSentMail(time.Now())
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, entry := range due {
+		now := time.Now()
+		ok, err := h.DB.ClaimSentMail(entry.ID, now, now.Add(mailClaimFor))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		var m mail.Message
+		err = json.Unmarshal([]byte(entry.Message), &m)
+		if err == nil {
+			err = h.Config.Mail.Send(ctx, m)
+		}
+		entry.Attempts++
+		entry.Provider = h.Config.Mail.Name()
+		switch {
+		case err == nil:
+			entry.Error, entry.NextAttemptAt, entry.Message, entry.SentAt = "", nil, "", now
+		case mail.Permanent(err) || entry.Attempts > len(mailBackoff):
+			entry.Error, entry.NextAttemptAt, entry.Message = err.Error(), nil, ""
+			log.Printf("[MAIL] %s to %s failed after %d attempts: %v", entry.Kind, entry.Recipients, entry.Attempts, err)
+		default:
+			next := now.Add(mailBackoff[entry.Attempts-1])
+			entry.Error, entry.NextAttemptAt = err.Error(), &next
+		}
+		if err := h.DB.SaveSentMailAttempt(&entry); err != nil {
+			errs = append(errs, fmt.Errorf("mail %d: %w", entry.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendReceipt emails the client a receipt for a payment received through
+// Stripe: to the client's billing address, or else the address the payer
+// gave Stripe
+func (h *Handler) sendReceipt(p *models.Project, pay stripePayment, payerEmail string) {
+	to := payerEmail
+	if c, err := h.DB.GetClientByName(p.Client); err != nil {
+		log.Printf("[MAIL] Receipt for project %d: %v", p.ID, err)
+	} else if c != nil && c.Email != "" {
+		to = c.Email
+	}
+	if to == "" {
+		log.Printf("[MAIL] No address to send project %d's receipt to", p.ID)
+		return
+	}
+	addrs, err := mail.ParseAddresses(to)
+	if err != nil {
+		log.Printf("[MAIL] Receipt for project %d: invalid address %q", p.ID, to)
+		return
+	}
+
+	company, err := h.DB.Company()
+	if err != nil {
+		log.Printf("[MAIL] Receipt for project %d: %v", p.ID, err)
+		return
+	}
+	paid, currency := pay.Paid()
+	subject, body := templates.ReceiptEmail(p, paid, currency, time.Now(), company)
+	h.sendMail(models.SentMail{Kind: models.MailReceipt, ProjectID: p.ID, SentBy: "stripe"},
+		mail.Message{To: addrs, Subject: subject, Body: body})
+}
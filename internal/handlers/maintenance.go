@@ -0,0 +1,48 @@
+// handlers/maintenance.go - Stepping back while a restore swaps the database
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/maintenance"
+)
+
+// Maintenance answers 503 with the lock's reason while a restore holds it,
+// so nothing touches the database while its file is replaced
+func (h *Handler) Maintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.Config.Maintenance.Held() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(int(maintenance.Grace.Seconds())))
+		http.Error(w, "Down for maintenance: "+h.Config.Maintenance.Reason(), http.StatusServiceUnavailable)
+	})
+}
+
+// WatchMaintenance polls the lock until ctx ends, calling drain(true) when
+// it is taken, for the database to let go of its connections, and
+// drain(false) when it is released. The cached metrics are dropped then,
+// since they describe the database that was replaced.
+func (h *Handler) WatchMaintenance(ctx context.Context, drain func(active bool)) {
+	t := time.NewTicker(maintenance.PollInterval)
+	defer t.Stop()
+	held := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if now := h.Config.Maintenance.Held(); now != held {
+			held = now
+			drain(held)
+			if !held {
+				h.invalidateMetrics()
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// handlers/metrics.go - Cached dashboard metrics and the polled metrics row
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// Cached metrics are dropped on every write that can change them; the TTL
+// only bounds how stale a write that bypassed the handlers can leave them
+const (
+	metricsCacheKey = "metrics"
+	metricsTTL      = 5 * time.Minute
+)
+
+// metrics returns the dashboard metrics, computing them only when the cache
+// has none
+func (h *Handler) metrics() (*models.Metrics, error) {
+	if b, ok := h.Cache.Get(metricsCacheKey); ok {
+		m := &models.Metrics{}
+		if err := json.Unmarshal(b, m); err == nil {
+			return m, nil
+		}
+	}
+
+	m, err := h.DB.GetMetrics()
+	if err != nil {
+		return nil, err
+	}
+	if b, err := json.Marshal(m); err == nil {
+		h.Cache.Set(metricsCacheKey, b, metricsTTL)
+	}
+	return m, nil
+}
+
+// comparison loads the snapshots from this time last month and last year
+func (h *Handler) comparison() (models.MetricsComparison, error) {
+	now := time.Now()
+	var c models.MetricsComparison
+	var err error
+	if c.LastMonth, err = h.DB.StatsSnapshotNear(now.AddDate(0, -1, 0)); err != nil {
+		return c, err
+	}
+	c.LastYear, err = h.DB.StatsSnapshotNear(now.AddDate(-1, 0, 0))
+	return c, err
+}
+
+// invalidateMetrics drops the cached metrics after a write to projects,
+// hours, expenses, adjustments, agreements or statuses
+func (h *Handler) invalidateMetrics() {
+	h.Cache.Delete(metricsCacheKey)
+}
+
+// WatchMetrics drops the cached metrics on every event: project writes,
+// status changes (automations included) and payments all publish one
+func (h *Handler) WatchMetrics(bus *events.Bus) {
+	bus.SubscribeAll(func(context.Context, events.Event) error {
+		h.invalidateMetrics()
+		return nil
+	})
+}
+
+// MetricsRow renders the metrics row the dashboard polls. Its ETag is a hash
+// of the figures, comparisons and people, so an unchanged row answers 304 Not Modified.
+func (h *Handler) MetricsRow(w http.ResponseWriter, r *http.Request) {
+	m, err := h.metrics()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	cmp, err := h.comparison()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	b, err := json.Marshal([]any{m, cmp, people})
+	if err != nil {
+		log.Printf("[METRICS] ETag: %v", err)
+	}
+	sum := sha256.Sum256(b)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	templates.MetricsRow(m, cmp, people, false).Render(r.Context(), w)
+}
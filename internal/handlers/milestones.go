@@ -0,0 +1,305 @@
+// handlers/milestones.go - Project milestones, each billed through its own payment link
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+	"github.com/stripe/stripe-go/v84"
+)
+
+// CreateMilestone adds a billing stage to a project from the project form
+func (h *Handler) CreateMilestone(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	p, err := h.DB.GetProject(projectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	m := &models.Milestone{ProjectID: p.ID, Status: models.MilestonePlanned}
+	if !h.parseMilestone(w, r, p, m) {
+		return
+	}
+	if err := h.DB.CreateMilestone(m); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderMilestones(w, r, p)
+}
+
+// EditMilestone renders a milestone as an inline edit form
+func (h *Handler) EditMilestone(w http.ResponseWriter, r *http.Request) {
+	m, _, ok := h.milestoneFromURL(w, r)
+	if !ok {
+		return
+	}
+	templates.MilestoneEditRow(m).Render(r.Context(), w)
+}
+
+// UpdateMilestone saves an edited milestone. Changing the amount retires its
+// payment link, which would still charge the old one.
+func (h *Handler) UpdateMilestone(w http.ResponseWriter, r *http.Request) {
+	m, p, ok := h.milestoneFromURL(w, r)
+	if !ok {
+		return
+	}
+	amount, linkID := m.AmountCents, m.PaymentLinkID
+	if !h.parseMilestone(w, r, p, m) {
+		return
+	}
+	if m.AmountCents != amount {
+		m.PaymentLinkID, m.PaymentLinkURL = "", ""
+	}
+	if err := h.DB.UpdateMilestone(m); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if m.PaymentLinkID != linkID {
+		h.deactivatePaymentLink(r.Context(), linkID)
+	}
+
+	h.renderMilestones(w, r, p)
+}
+
+// DeleteMilestone removes a milestone and retires its payment link
+func (h *Handler) DeleteMilestone(w http.ResponseWriter, r *http.Request) {
+	m, p, ok := h.milestoneFromURL(w, r)
+	if !ok {
+		return
+	}
+	if err := h.DB.DeleteMilestone(m.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.deactivatePaymentLink(r.Context(), m.PaymentLinkID)
+
+	h.renderMilestones(w, r, p)
+}
+
+// CreateMilestoneLink makes a Stripe payment link for a milestone's amount,
+// which can be paid once, and marks a planned milestone billed
+func (h *Handler) CreateMilestoneLink(w http.ResponseWriter, r *http.Request) {
+	m, p, ok := h.milestoneFromURL(w, r)
+	if !ok {
+		return
+	}
+	if h.Config.StripeKey == "" {
+		http.Error(w, "Stripe isn't set up: set STRIPE_SECRET_KEY to make payment links", http.StatusBadRequest)
+		return
+	}
+	if m.Status == models.MilestonePaid {
+		http.Error(w, "This milestone is already paid", http.StatusBadRequest)
+		return
+	}
+	if m.PaymentLinkURL != "" {
+		h.renderMilestones(w, r, p)
+		return
+	}
+
+	client, err := h.DB.GetClientByName(p.Client)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	link, err := h.stripeClient().V1PaymentLinks.Create(r.Context(), milestoneLinkParams(p, m, client))
+	if err != nil {
+		log.Printf("[STRIPE] Payment link for milestone %d failed: %v", m.ID, err)
+		http.Error(w, "Stripe couldn't make the payment link; try again in a moment", http.StatusBadGateway)
+		return
+	}
+	if err := h.DB.SetMilestoneLink(m.ID, link.ID, link.URL); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderMilestones(w, r, p)
+}
+
+// milestonePayment records a Stripe payment of a milestone, named by its
+// payment intent's metadata. It is logged like any payment of the project,
+// so reconciliation counts it, but announced as MilestonePaid: rules on
+// payments usually mark the project paid, and it stays on the board until
+// the last stage is in. Payments in other currencies are converted to
+// kronor (see basePayment). An error means it wasn't marked paid.
+func (h *Handler) milestonePayment(milestoneID, paymentID string, amount int64, currency stripe.Currency, payerEmail string) error {
+	id, err := strconv.ParseInt(milestoneID, 10, 64)
+	if err != nil {
+		h.webhookFailed("Invalid milestone_id %q", milestoneID)
+		return nil
+	}
+	m, err := h.DB.GetMilestone(id)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		h.webhookFailed("Milestone %d not found", id)
+		return nil
+	}
+	p, err := h.DB.GetProject(m.ProjectID)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		h.webhookFailed("Project %d not found", m.ProjectID)
+		return nil
+	}
+	pay, err := h.basePayment(p, amount, currency)
+	if err != nil {
+		return fmt.Errorf("payment for milestone %d: %w", m.ID, err)
+	}
+	if err := h.DB.MarkMilestonePaid(m.ID, paymentID); err != nil {
+		return fmt.Errorf("marking milestone %d paid: %w", m.ID, err)
+	}
+
+	if err := h.DB.LogActivity(&models.Activity{
+		ProjectID: p.ID,
+		Client:    p.Client,
+		Action:    "payment",
+		Summary:   pay.Summary() + " for " + m.Title,
+		Actor:     "stripe",
+		Source:    events.SourceStripe,
+	}); err != nil {
+		h.webhookFailed("Audit log error: %v", err)
+	}
+	h.Events.Publish(context.Background(), events.Event{
+		Type:        events.MilestonePaid,
+		ProjectID:   p.ID,
+		Client:      p.Client,
+		AmountCents: pay.Cents,
+		Source:      events.SourceStripe,
+	})
+	h.sendReceipt(p, pay, payerEmail)
+	return nil
+}
+
+// deactivatePaymentLink switches off a milestone's old payment link so it
+// can't be paid; failures are only logged, as the milestone no longer
+// points to it
+func (h *Handler) deactivatePaymentLink(ctx context.Context, linkID string) {
+	if linkID == "" || h.Config.StripeKey == "" {
+		return
+	}
+	params := &stripe.PaymentLinkUpdateParams{Active: stripe.Bool(false)}
+	if _, err := h.stripeClient().V1PaymentLinks.Update(ctx, linkID, params); err != nil {
+		h.webhookFailed("Deactivating payment link %s failed: %v", linkID, err)
+	}
+}
+
+// parseMilestone fills m from the milestone form, writing a 400 itself if
+// the form is invalid. The project's milestones can't add up to more than
+// its revenue. Marking a milestone paid by hand records when; moving it
+// back forgets the payment.
+func (h *Handler) parseMilestone(w http.ResponseWriter, r *http.Request, p *models.Project, m *models.Milestone) bool {
+	title := strings.TrimSpace(r.FormValue("title"))
+	if title == "" {
+		http.Error(w, "Give the milestone a title", http.StatusBadRequest)
+		return false
+	}
+	amount, err := parseCents(r.FormValue("amount"))
+	if err != nil || amount <= 0 {
+		http.Error(w, "Amount must be more than 0", http.StatusBadRequest)
+		return false
+	}
+	due, err := time.Parse(dateLayout, r.FormValue("due_on"))
+	if err != nil {
+		http.Error(w, "Choose a due date", http.StatusBadRequest)
+		return false
+	}
+	status := m.Status
+	if s := r.FormValue("status"); s != "" {
+		status = models.MilestoneStatus(s)
+	}
+	if !status.Valid() {
+		http.Error(w, "Unknown status", http.StatusBadRequest)
+		return false
+	}
+
+	milestones, err := h.DB.ListMilestones(p.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	total := amount
+	for _, other := range milestones {
+		if other.ID != m.ID {
+			total += other.AmountCents
+		}
+	}
+	if total > p.RevenueCents {
+		http.Error(w, fmt.Sprintf("Milestones can't add up to more than the project's revenue, %.2f kr", float64(p.RevenueCents)/100), http.StatusBadRequest)
+		return false
+	}
+
+	m.Title = title
+	m.AmountCents = amount
+	m.DueOn = due
+	switch {
+	case status == models.MilestonePaid && m.PaidAt == nil:
+		now := time.Now()
+		m.PaidAt = &now
+	case status != models.MilestonePaid:
+		m.PaidAt = nil
+		m.StripePaymentID = ""
+	}
+	m.Status = status
+	return true
+}
+
+// milestoneFromURL loads the milestone named by {id} and its project,
+// writing errors itself
+func (h *Handler) milestoneFromURL(w http.ResponseWriter, r *http.Request) (*models.Milestone, *models.Project, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	m, err := h.DB.GetMilestone(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if m == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	p, err := h.DB.GetProject(m.ProjectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if p == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	return m, p, true
+}
+
+// renderMilestones re-renders a project's milestones
+func (h *Handler) renderMilestones(w http.ResponseWriter, r *http.Request, p *models.Project) {
+	milestones, err := h.DB.ListMilestones(p.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.MilestoneList(p, milestones, h.Config.StripeKey != "", time.Now()).Render(r.Context(), w)
+}
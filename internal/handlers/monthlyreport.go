@@ -0,0 +1,98 @@
+// handlers/monthlyreport.go - The monthly PDF report: revenue, shares, projects paid and outstanding invoices
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/mail"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// MonthlyReportPDF downloads /reports/{year}/{month}.pdf
+func (h *Handler) MonthlyReportPDF(w http.ResponseWriter, r *http.Request) {
+	year, _ := strconv.Atoi(chi.URLParam(r, "year"))
+	month, _ := strconv.Atoi(chi.URLParam(r, "month"))
+	if month < 1 || month > 12 {
+		http.Error(w, "Invalid month", http.StatusBadRequest)
+		return
+	}
+
+	att, err := h.monthlyReportPDF(time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", att.ContentType)
+	w.Header().Set("Content-Disposition", `inline; filename="`+att.Name+`"`)
+	w.Write(att.Data)
+}
+
+// monthlyReportPDF renders the report on month (its first day) as an
+// attachment, ready to download or email
+func (h *Handler) monthlyReportPDF(month time.Time) (mail.Attachment, error) {
+	rep, err := h.monthlyReport(month)
+	if err != nil {
+		return mail.Attachment{}, err
+	}
+	company, err := h.DB.Company()
+	if err != nil {
+		return mail.Attachment{}, err
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		return mail.Attachment{}, err
+	}
+	return mail.Attachment{
+		Name:        templates.MonthlyReportFilename(month),
+		ContentType: "application/pdf",
+		Data:        templates.MonthlyReportPDF(rep, company, people),
+	}, nil
+}
+
+// monthlyReport gathers month: the revenue series up to it, the projects
+// paid in it, its hours report and the invoices outstanding now
+func (h *Handler) monthlyReport(month time.Time) (models.MonthlyReport, error) {
+	rep := models.MonthlyReport{Month: month, Generated: time.Now()}
+	next := month.AddDate(0, 1, 0)
+	lastDay := next.AddDate(0, 0, -1)
+
+	g := models.GranularityMonth
+	var err error
+	if rep.Revenue, err = h.DB.RevenueSeries(g, g.Add(month, 1-revenuePeriods), lastDay); err != nil {
+		return rep, err
+	}
+
+	paid, err := h.DB.ListProjectsByStatus(models.StatusPaid)
+	if err != nil {
+		return rep, err
+	}
+	for _, p := range paid {
+		if p.PaidAt != nil && !p.PaidAt.Before(month) && p.PaidAt.Before(next) {
+			rep.Paid = append(rep.Paid, p)
+		}
+	}
+	slices.SortStableFunc(rep.Paid, func(a, b models.Project) int { return a.PaidAt.Compare(*b.PaidAt) })
+
+	created, err := h.DB.ListProjects(models.ProjectFilter{From: month, To: lastDay, Archived: true})
+	if err != nil {
+		return rep, err
+	}
+	rep.Created = len(created)
+
+	hours, err := h.DB.HoursReport(month)
+	if err != nil {
+		return rep, err
+	}
+	rep.Hours = hours.People
+
+	if rep.Outstanding, err = h.DB.ListInvoices(models.InvoiceIssued); err != nil {
+		return rep, err
+	}
+	slices.SortStableFunc(rep.Outstanding, func(a, b models.Invoice) int { return a.DueDate.Compare(b.DueDate) })
+	return rep, nil
+}
@@ -0,0 +1,105 @@
+// handlers/notifications.go - Notification inbox, delivery preferences and admin rule builder
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/notify"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// inboxSize is the number of notifications shown on the notifications page
+const inboxSize = 50
+
+// NotificationsPage shows the current user's inbox and delivery preferences
+func (h *Handler) NotificationsPage(w http.ResponseWriter, r *http.Request) {
+	u := auth.UserFrom(r.Context())
+
+	inbox, err := h.DB.ListNotifications(u.ID, inboxSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	prefs, err := h.DB.GetNotificationPrefs(u.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	templates.Layout("FullDash Notifications", templates.NotificationsPage(inbox, prefs, u)).Render(r.Context(), w)
+}
+
+// UpdateNotificationPrefs saves the current user's delivery mode per notification type
+func (h *Handler) UpdateNotificationPrefs(w http.ResponseWriter, r *http.Request) {
+	u := auth.UserFrom(r.Context())
+
+	for _, t := range events.Types {
+		mode := models.DeliveryMode(r.FormValue(string(t)))
+		if !mode.Valid() {
+			continue
+		}
+		if err := h.DB.SetNotificationPref(u.ID, string(t), mode); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	triggerToast(w, "Preferences saved")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateNotificationRule adds a rule from the admin form
+func (h *Handler) CreateNotificationRule(w http.ResponseWriter, r *http.Request) {
+	rule := models.NotificationRule{
+		Event:   r.FormValue("event"),
+		Field:   r.FormValue("field"),
+		Op:      r.FormValue("op"),
+		Value:   strings.TrimSpace(r.FormValue("value")),
+		Channel: r.FormValue("channel"),
+		Target:  strings.TrimSpace(r.FormValue("target")),
+	}
+	if rule.Field == "" {
+		rule.Op, rule.Value = "", ""
+	}
+
+	if err := notify.AddRule(h.DB, rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	triggerToast(w, "Notification rule added")
+	h.renderNotificationRules(w, r)
+}
+
+// DeleteNotificationRule removes a rule
+func (h *Handler) DeleteNotificationRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := notify.DeleteRule(h.DB, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderNotificationRules(w, r)
+}
+
+// renderNotificationRules renders the admin notification panel
+func (h *Handler) renderNotificationRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := notify.LoadRules(h.DB)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.NotificationRules(rules, h.Notify.Channels()).Render(r.Context(), w)
+}
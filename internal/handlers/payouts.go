@@ -0,0 +1,135 @@
+// handlers/payouts.go - Settlement ledger: payouts and transfers between partners
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// SettlementsPage shows each person's earned, received and outstanding
+// balance, who owes whom, and the payout ledger
+func (h *Handler) SettlementsPage(w http.ResponseWriter, r *http.Request) {
+	view, err := h.settlementView()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Layout("FullDash Settlements", templates.SettlementsPage(view)).Render(r.Context(), w)
+}
+
+// RecordPayout records money paid to a person: from the business, or from
+// another partner when from is set (a settlement)
+func (h *Handler) RecordPayout(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	amount, err := parseCents(r.FormValue("amount"))
+	if err != nil || amount <= 0 {
+		http.Error(w, "Amount must be a positive number", http.StatusBadRequest)
+		return
+	}
+
+	paidOn, err := time.Parse(dateLayout, r.FormValue("paid_on"))
+	if err != nil {
+		paidOn = time.Now()
+	}
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p := &models.Payout{AmountCents: amount, PaidOn: paidOn, Note: strings.TrimSpace(r.FormValue("note"))}
+	p.PersonID, _ = strconv.ParseInt(r.FormValue("to"), 10, 64)
+	if _, ok := people.Get(p.PersonID); !ok {
+		http.Error(w, "Pick who received it", http.StatusBadRequest)
+		return
+	}
+	if v := r.FormValue("from"); v != "" {
+		p.FromID, _ = strconv.ParseInt(v, 10, 64)
+		if _, ok := people.Get(p.FromID); !ok || p.FromID == p.PersonID {
+			http.Error(w, "A settlement is between two different people", http.StatusBadRequest)
+			return
+		}
+	}
+
+	p.CreatedBy, _ = actorFrom(r)
+	if err := h.DB.CreatePayout(p); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.invalidateMetrics()
+
+	to, _ := people.Get(p.PersonID)
+	if p.Settlement() {
+		from, _ := people.Get(p.FromID)
+		triggerToast(w, fmt.Sprintf("Recorded %s paying %s %.2f kr", from.Name, to.Name, float64(amount)/100))
+	} else {
+		triggerToast(w, "Recorded a payout to "+to.Name)
+	}
+	h.renderSettlements(w, r)
+}
+
+// DeletePayout removes a payout or settlement recorded by mistake
+func (h *Handler) DeletePayout(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.DB.GetPayout(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.DB.DeletePayout(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.invalidateMetrics()
+
+	h.renderSettlements(w, r)
+}
+
+// settlementView loads the balances (from the metrics) and the ledger
+func (h *Handler) settlementView() (templates.SettlementView, error) {
+	m, err := h.metrics()
+	if err != nil {
+		return templates.SettlementView{}, err
+	}
+	payouts, err := h.DB.ListPayouts()
+	if err != nil {
+		return templates.SettlementView{}, err
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		return templates.SettlementView{}, err
+	}
+	return templates.SettlementView{Metrics: m, Payouts: payouts, People: people}, nil
+}
+
+// renderSettlements re-renders the balances and ledger
+func (h *Handler) renderSettlements(w http.ResponseWriter, r *http.Request) {
+	view, err := h.settlementView()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.SettlementLedger(view).Render(r.Context(), w)
+}
@@ -0,0 +1,114 @@
+// handlers/people.go - Admin management of the partners who share revenue
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// CreatePerson adds a partner at the end of the display order
+func (h *Handler) CreatePerson(w http.ResponseWriter, r *http.Request) {
+	p := &models.Person{
+		Name:  strings.TrimSpace(r.FormValue("name")),
+		Color: r.FormValue("color"),
+	}
+	if p.Name == "" || !p.ValidColor() {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if nameTaken(people, p.Name, 0) {
+		triggerToast(w, "Someone named "+p.Name+" already exists")
+		h.renderPeople(w, r)
+		return
+	}
+
+	if err := h.DB.CreatePerson(p); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.invalidateMetrics()
+
+	triggerToast(w, "Added "+p.Name)
+	h.renderPeople(w, r)
+}
+
+// UpdatePerson saves a person's name, color, position and active flag
+func (h *Handler) UpdatePerson(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	p, ok := people.Get(id)
+	if !ok {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	position, err := strconv.Atoi(r.FormValue("position"))
+	p.Name = strings.TrimSpace(r.FormValue("name"))
+	p.Color = r.FormValue("color")
+	if err != nil || p.Name == "" || !p.ValidColor() {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	p.Position = position
+	p.Active = r.FormValue("active") == "on"
+
+	if nameTaken(people, p.Name, p.ID) {
+		triggerToast(w, "Someone named "+p.Name+" already exists")
+		h.renderPeople(w, r)
+		return
+	}
+	if !p.Active && len(people.Active()) == 1 && people.Active()[0].ID == p.ID {
+		triggerToast(w, "At least one person must stay active")
+		h.renderPeople(w, r)
+		return
+	}
+
+	if err := h.DB.UpdatePerson(&p); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.invalidateMetrics()
+
+	triggerToast(w, "Saved "+p.Name)
+	h.renderPeople(w, r)
+}
+
+// nameTaken reports whether someone other than id already has the name
+func nameTaken(people models.People, name string, id int64) bool {
+	for _, p := range people {
+		if p.ID != id && strings.EqualFold(p.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderPeople renders the admin people panel
+func (h *Handler) renderPeople(w http.ResponseWriter, r *http.Request) {
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.People(people).Render(r.Context(), w)
+}
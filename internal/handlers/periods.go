@@ -0,0 +1,114 @@
+// handlers/periods.go - Locking months so their payments can't change
+package handlers
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// monthLayout is the format of <input type="month"> values
+const monthLayout = "2006-01"
+
+// LockPeriod locks a month that has ended. Projects paid in it keep their
+// revenue, status, hours and expenses from then on.
+func (h *Handler) LockPeriod(w http.ResponseWriter, r *http.Request) {
+	month, err := time.Parse(monthLayout, r.FormValue("month"))
+	if err != nil {
+		http.Error(w, "Month must be YYYY-MM", http.StatusBadRequest)
+		return
+	}
+
+	lock := models.PeriodLock{Month: month}
+	if !month.AddDate(0, 1, 0).Before(time.Now()) {
+		triggerToast(w, "Only months that have ended can be locked")
+		h.renderPeriods(w, r)
+		return
+	}
+	existing, err := h.DB.PeriodLockAt(month)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing != nil {
+		triggerToast(w, lock.Label()+" is already locked")
+		h.renderPeriods(w, r)
+		return
+	}
+
+	if err := h.DB.LockPeriod(month, auth.UserFrom(r.Context()).Name); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	triggerToast(w, "Locked "+lock.Label())
+	h.renderPeriods(w, r)
+}
+
+// UnlockPeriod reopens a locked month
+func (h *Handler) UnlockPeriod(w http.ResponseWriter, r *http.Request) {
+	month, err := time.Parse(monthLayout, chi.URLParam(r, "month"))
+	if err != nil {
+		http.Error(w, "Invalid month", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.UnlockPeriod(month); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	triggerToast(w, "Reopened "+models.PeriodLock{Month: month}.Label())
+	h.renderPeriods(w, r)
+}
+
+// renderPeriods renders the admin locked periods panel
+func (h *Handler) renderPeriods(w http.ResponseWriter, r *http.Request) {
+	locks, err := h.DB.ListPeriodLocks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Periods(locks).Render(r.Context(), w)
+}
+
+// checkLocked refuses a form edit that would change the revenue, status,
+// securers or hours of a project paid in a locked month, before anything is
+// written. The store enforces the same lock; checking first keeps the edit
+// from being half applied.
+func (h *Handler) checkLocked(p *models.Project, f *ParsedForm, hours models.PerPerson[float64]) error {
+	lock, err := h.DB.ProjectLock(p)
+	if err != nil || lock == nil {
+		return err
+	}
+
+	hoursChanged := false
+	for id, form := range f.Hours {
+		hoursChanged = hoursChanged || form > 0 && form != hours[id] // zero hours aren't saved
+	}
+	if f.RevenueCents != p.RevenueCents || f.Currency != p.Currency || f.OriginalCents != p.OriginalCents || f.VATPercent != p.VATPercent ||
+		f.Status != p.Status || !slices.Equal(f.SecuredBy, p.SecuredBy) || !maps.Equal(f.SplitOverride, p.SplitOverride) ||
+		hoursChanged {
+		return &models.PeriodLockedError{Lock: *lock, Client: p.Client}
+	}
+	return nil
+}
+
+// refuseLocked answers a write refused by a period lock with a toast and
+// 409 Conflict; it reports false for any other error
+func refuseLocked(w http.ResponseWriter, err error) bool {
+	var locked *models.PeriodLockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	triggerToast(w, locked.Error())
+	http.Error(w, locked.Error(), http.StatusConflict)
+	return true
+}
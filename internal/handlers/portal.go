@@ -0,0 +1,219 @@
+// handlers/portal.go - Tokenized read-only client portal
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// CreatePortalLink issues a signed, expiring client portal link for a project
+func (h *Handler) CreatePortalLink(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.DB.GetProject(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	expires := time.Now().Add(h.Config.PortalTTL)
+	token := auth.SignPortalToken(h.Config.PortalSecret, p.ID, expires)
+	templates.PortalLink(h.absoluteURL(r, "/portal/"+token), expires).Render(r.Context(), w)
+}
+
+// Portal shows a client their project's status and amount due, without login
+func (h *Handler) Portal(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex")
+
+	id, _, err := auth.ParsePortalToken(h.Config.PortalSecret, chi.URLParam(r, "token"), time.Now())
+	if errors.Is(err, auth.ErrPortalExpired) {
+		w.WriteHeader(http.StatusGone)
+		templates.Layout("FullDash", templates.PortalError("This link has expired. Ask us for a new one.")).Render(r.Context(), w)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		templates.Layout("FullDash", templates.PortalError("This link is not valid.")).Render(r.Context(), w)
+		return
+	}
+
+	p, err := h.DB.GetProject(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		w.WriteHeader(http.StatusNotFound)
+		templates.Layout("FullDash", templates.PortalError("This project is no longer available.")).Render(r.Context(), w)
+		return
+	}
+
+	client, err := h.DB.GetClientByName(p.Client)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	milestones, err := h.DB.ListMilestones(p.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	invoices, err := h.DB.ListProjectInvoices(p.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	view := templates.PortalView{
+		Project:    p,
+		Client:     client,
+		Milestones: milestones,
+		Invoices:   slices.DeleteFunc(invoices, func(inv models.Invoice) bool { return !portalInvoice(&inv) }),
+		Path:       "/portal/" + chi.URLParam(r, "token"),
+		JustPaid:   r.URL.Query().Get("paid") != "",
+	}
+	switch {
+	case len(milestones) > 0:
+		// Billed in stages: each milestone is paid through its own link
+	case h.Config.StripeKey != "" && paymentDue(p):
+		view.CheckoutURL = view.Path + "/pay"
+	default:
+		view.PaymentURL = h.paymentURL(p)
+	}
+	templates.Layout(p.Client+" — FullDash", templates.PortalPage(view)).Render(r.Context(), w)
+}
+
+// PortalInvoicePDF downloads one of the project's invoices from its portal.
+// The token only opens the invoices of its own project, once issued.
+func (h *Handler) PortalInvoicePDF(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex")
+
+	projectID, _, err := auth.ParsePortalToken(h.Config.PortalSecret, chi.URLParam(r, "token"), time.Now())
+	if err != nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	inv, err := h.DB.GetInvoice(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if inv == nil || inv.ProjectID != projectID || !portalInvoice(inv) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	h.writeInvoicePDF(w, r, inv)
+}
+
+// portalInvoice reports whether a client sees an invoice in their portal:
+// issued ones, paid or not, but not drafts or void ones
+func portalInvoice(inv *models.Invoice) bool {
+	return inv.Status == models.InvoiceIssued || inv.Status == models.InvoicePaid
+}
+
+// PortalCheckout starts a Stripe Checkout session for the amount due,
+// branded for the client, and sends the client there. Projects billed in
+// milestones are paid through the milestones' links instead.
+func (h *Handler) PortalCheckout(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	id, _, err := auth.ParsePortalToken(h.Config.PortalSecret, token, time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		templates.Layout("FullDash", templates.PortalError("This link is not valid.")).Render(r.Context(), w)
+		return
+	}
+
+	p, err := h.DB.GetProject(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil || h.Config.StripeKey == "" || !paymentDue(p) {
+		http.Redirect(w, r, "/portal/"+token, http.StatusSeeOther)
+		return
+	}
+	if milestones, err := h.DB.ListMilestones(p.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if len(milestones) > 0 {
+		http.Redirect(w, r, "/portal/"+token, http.StatusSeeOther)
+		return
+	}
+
+	client, err := h.DB.GetClientByName(p.Client)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	params := checkoutParams(p, client, h.absoluteURL(r, "/portal/"+token))
+	session, err := h.stripeClient().V1CheckoutSessions.Create(r.Context(), params)
+	if err != nil {
+		log.Printf("[STRIPE] Checkout for project %d failed: %v", p.ID, err)
+		w.WriteHeader(http.StatusBadGateway)
+		templates.Layout("FullDash", templates.PortalError("We couldn't start the payment. Please try again in a moment.")).Render(r.Context(), w)
+		return
+	}
+	http.Redirect(w, r, session.URL, http.StatusSeeOther)
+}
+
+// paymentDue reports whether a project still has an amount to pay
+func paymentDue(p *models.Project) bool {
+	return p.Status != models.StatusPaid && p.RevenueCents > 0
+}
+
+// paymentURL returns the configured Stripe payment link tagged with the
+// project, or "" when nothing is due or no link is configured
+func (h *Handler) paymentURL(p *models.Project) string {
+	if h.Config.PaymentLinkURL == "" || !paymentDue(p) {
+		return ""
+	}
+	u, err := url.Parse(h.Config.PaymentLinkURL)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	q.Set("client_reference_id", fmt.Sprintf("project-%d", p.ID))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// absoluteURL prefixes path with BASE_URL, or the request's host when unset
+func (h *Handler) absoluteURL(r *http.Request, path string) string {
+	if h.Config.BaseURL != "" {
+		return h.Config.BaseURL + path
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + path
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/store"
+)
+
+// TestPortalInvoicePDF checks that a portal link opens its own project's
+// issued invoices, and nothing else
+func TestPortalInvoicePDF(t *testing.T) {
+	db, err := store.New(filepath.Join(t.TempDir(), "fulldash.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	project := func(client string) *models.Project {
+		p := &models.Project{Client: client, RevenueCents: 100000, Status: models.StatusDone, SecuredBy: []int64{1}}
+		if err := db.CreateProject(p); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	invoice := func(p *models.Project, issue bool) int64 {
+		inv := &models.Invoice{ProjectID: p.ID, Client: p.Client, VATPercent: 25, PaymentDays: 30,
+			Lines: []models.InvoiceLine{{Description: "Work", Quantity: 1, UnitCents: 100000}}}
+		if err := db.CreateInvoice(inv); err != nil {
+			t.Fatal(err)
+		}
+		if issue {
+			if err := db.IssueInvoice(inv.ID, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return inv.ID
+	}
+	acme, other := project("Acme"), project("Other")
+	issued, draft, others := invoice(acme, true), invoice(acme, false), invoice(other, true)
+
+	secret := []byte("portal secret")
+	h := &Handler{DB: db, Config: Config{PortalSecret: secret, PortalTTL: time.Hour}}
+	token := auth.SignPortalToken(secret, acme.ID, time.Now().Add(time.Hour))
+	expired := auth.SignPortalToken(secret, acme.ID, time.Now().Add(-time.Hour))
+
+	// The page lists the issued invoice only
+	r := portalRequest("/portal/"+token, map[string]string{"token": token})
+	w := httptest.NewRecorder()
+	h.Portal(w, r)
+	page := w.Body.String()
+	if want := "/portal/" + token + "/invoices/" + strconv.FormatInt(issued, 10) + ".pdf"; !strings.Contains(page, want) {
+		t.Errorf("portal page doesn't link %s", want)
+	}
+	for _, id := range []int64{draft, others} {
+		if strings.Contains(page, "/invoices/"+strconv.FormatInt(id, 10)+".pdf") {
+			t.Errorf("portal page links invoice %d", id)
+		}
+	}
+
+	for _, tt := range []struct {
+		name     string
+		token    string
+		invoice  int64
+		wantCode int
+	}{
+		{"issued", token, issued, 200},
+		{"draft", token, draft, 404},
+		{"another project's", token, others, 404},
+		{"expired link", expired, issued, 404},
+		{"forged link", token + "x", issued, 404},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			id := strconv.FormatInt(tt.invoice, 10)
+			r := portalRequest("/portal/"+tt.token+"/invoices/"+id+".pdf", map[string]string{"token": tt.token, "id": id})
+			w := httptest.NewRecorder()
+			h.PortalInvoicePDF(w, r)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if w.Code == 200 && w.Header().Get("Content-Type") != "application/pdf" {
+				t.Errorf("content type %q, want a PDF", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+// portalRequest is a GET of path with chi's URL parameters set
+func portalRequest(path string, params map[string]string) *http.Request {
+	r := httptest.NewRequest("GET", path, nil)
+	rctx := chi.NewRouteContext()
+	for k, v := range params {
+		rctx.URLParams.Add(k, v)
+	}
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
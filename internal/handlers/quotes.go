@@ -0,0 +1,404 @@
+// handlers/quotes.go - Quotes: drafted, shared through a portal link, converted into projects
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/service"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// QuotesPage lists quotes, filtered by ?status=
+func (h *Handler) QuotesPage(w http.ResponseWriter, r *http.Request) {
+	status := models.QuoteStatus(r.URL.Query().Get("status"))
+	if status != "" && !status.Valid() {
+		http.Error(w, "Unknown status", http.StatusBadRequest)
+		return
+	}
+
+	quotes, err := h.DB.ListQuotes(status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Layout("FullDash Quotes", templates.QuotesPage(status, quotes, time.Now())).Render(r.Context(), w)
+}
+
+// CreateQuote starts a draft quote for a client, valid for the default
+// number of days, and opens it
+func (h *Handler) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	q := &models.Quote{
+		Client:      strings.TrimSpace(r.FormValue("client")),
+		Description: strings.TrimSpace(r.FormValue("description")),
+		ValidUntil:  time.Now().AddDate(0, 0, models.DefaultQuoteDays),
+	}
+	if q.Client == "" {
+		http.Error(w, "Client is required", http.StatusBadRequest)
+		return
+	}
+	q.CreatedBy, _ = actorFrom(r)
+	if err := h.DB.CreateQuote(q); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/quotes/%d", q.ID))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// QuotePage shows a quote: a form while it's a draft, read-only once sent
+func (h *Handler) QuotePage(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.quoteFromURL(w, r)
+	if !ok {
+		return
+	}
+	v, err := h.quoteView(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Layout("FullDash Quote for "+q.Client, templates.QuotePage(v)).Render(r.Context(), w)
+}
+
+// UpdateQuote saves a draft's client, description, currency, validity,
+// who secured it and its lines (as for invoices)
+func (h *Handler) UpdateQuote(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.quoteFromURL(w, r)
+	if !ok {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	q.Client = strings.TrimSpace(r.FormValue("client"))
+	q.Description = strings.TrimSpace(r.FormValue("description"))
+	if q.Client == "" {
+		http.Error(w, "Client is required", http.StatusBadRequest)
+		return
+	}
+	validUntil, err := time.Parse(dateLayout, r.FormValue("valid_until"))
+	if err != nil {
+		http.Error(w, "Valid until must be a date", http.StatusBadRequest)
+		return
+	}
+	q.ValidUntil = validUntil
+
+	q.Currency = strings.ToUpper(strings.TrimSpace(r.FormValue("currency")))
+	if q.Currency == models.BaseCurrency {
+		q.Currency = ""
+	}
+	if q.Currency != "" && !models.ValidCurrency(q.Currency) {
+		http.Error(w, "Currencies are three-letter codes like EUR", http.StatusBadRequest)
+		return
+	}
+
+	securedBy, ok := parseIDs(r.Form["secured_by"])
+	if !ok {
+		http.Error(w, "Invalid secured_by", http.StatusBadRequest)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, id := range securedBy {
+		if _, ok := people.Get(id); !ok {
+			http.Error(w, "Unknown person in secured_by", http.StatusBadRequest)
+			return
+		}
+	}
+	q.SecuredBy = securedBy
+
+	lines, msg := parseLines(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	q.Lines = lines
+
+	if err := h.DB.UpdateQuote(q); err != nil {
+		quoteError(w, err)
+		return
+	}
+	triggerToast(w, "Draft saved")
+	h.renderQuote(w, r, q, "", time.Time{})
+}
+
+// ShareQuote creates a portal link for the client to view and accept the
+// quote. Sharing a draft sends it, after which it can't be edited.
+func (h *Handler) ShareQuote(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.quoteFromURL(w, r)
+	if !ok {
+		return
+	}
+	if len(q.Lines) == 0 {
+		http.Error(w, "Add at least one line", http.StatusBadRequest)
+		return
+	}
+
+	if q.Status == models.QuoteDraft {
+		if _, err := h.DB.SetQuoteStatus(q.ID, models.QuoteDraft, models.QuoteSent); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		q.Status = models.QuoteSent
+	}
+
+	// The link outlives the validity date, so the client can still see
+	// what they were offered
+	expires := time.Now().Add(h.Config.PortalTTL)
+	if valid := q.ValidUntil.Add(h.Config.PortalTTL); valid.After(expires) {
+		expires = valid
+	}
+	link := h.absoluteURL(r, "/portal/quotes/"+auth.SignQuoteToken(h.Config.PortalSecret, q.ID, expires))
+	h.renderQuote(w, r, q, link, expires)
+}
+
+// SetQuoteStatus records the client's answer given outside the portal, or
+// moves an answered quote back to sent
+func (h *Handler) SetQuoteStatus(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.quoteFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	to := models.QuoteStatus(r.FormValue("status"))
+	if !q.CanMove(to) {
+		http.Error(w, fmt.Sprintf("A %s quote can't be marked %s", q.Status, to), http.StatusConflict)
+		return
+	}
+	moved, err := h.DB.SetQuoteStatus(q.ID, q.Status, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !moved {
+		http.Error(w, "The quote changed meanwhile; reload it", http.StatusConflict)
+		return
+	}
+	q.Status = to
+	triggerToast(w, "Quote is "+strings.ToLower(to.Label()))
+	h.renderQuote(w, r, q, "", time.Time{})
+}
+
+// ConvertQuote turns an accepted quote into a project in the first column,
+// carrying over the client, description, amount (its total, converted at
+// the stored rate when foreign) and who secured it
+func (h *Handler) ConvertQuote(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.quoteFromURL(w, r)
+	if !ok {
+		return
+	}
+	if q.Status != models.QuoteAccepted || q.Converted() {
+		http.Error(w, "Only an accepted quote can be converted, once", http.StatusConflict)
+		return
+	}
+	if len(q.SecuredBy) == 0 {
+		triggerToast(w, "Set who secured the quote first")
+		http.Error(w, "Set who secured the quote first", http.StatusUnprocessableEntity)
+		return
+	}
+
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	form := &ParsedForm{RevenueCents: q.TotalCents()}
+	if q.Currency != "" {
+		form.Currency, form.OriginalCents = q.Currency, q.TotalCents()
+	}
+	if !h.convertForm(w, form, nil) {
+		return
+	}
+
+	p := &models.Project{
+		Client:        q.Client,
+		Description:   q.Description,
+		RevenueCents:  form.RevenueCents,
+		Status:        wf.Initial(),
+		SecuredBy:     q.SecuredBy,
+		Currency:      form.Currency,
+		OriginalCents: form.OriginalCents,
+		VATPercent:    models.DefaultVATPercent,
+		PaymentDays:   models.DefaultPaymentDays,
+	}
+	if err := h.createProject(p); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	linked, err := h.DB.SetQuoteProject(q.ID, p.ID)
+	if err == nil && !linked {
+		err = errors.New("the quote was converted meanwhile")
+	}
+	if err != nil {
+		h.DB.DeleteProject(p.ID)
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	q.ProjectID = p.ID
+
+	created := service.Snapshot(p, nil)
+	if err := h.logActivity(r, p, "created", nil, &created, "converted from a quote"); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.publish(r, events.ProjectCreated, p, "", events.SourceUser)
+
+	triggerToast(w, "Created project "+p.Client)
+	h.renderQuote(w, r, q, "", time.Time{})
+}
+
+// DeleteQuote discards a quote that hasn't become a project and returns to
+// the quote list
+func (h *Handler) DeleteQuote(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.quoteFromURL(w, r)
+	if !ok {
+		return
+	}
+	if q.Converted() {
+		http.Error(w, "A converted quote is kept with its project", http.StatusConflict)
+		return
+	}
+
+	if err := h.DB.DeleteQuote(q.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("HX-Redirect", "/quotes")
+}
+
+// renderQuote renders a quote's detail, with a freshly shared link if any
+func (h *Handler) renderQuote(w http.ResponseWriter, r *http.Request, q *models.Quote, link string, expires time.Time) {
+	v, err := h.quoteView(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	v.Link, v.Expires = link, expires
+	templates.QuoteDetail(v).Render(r.Context(), w)
+}
+
+// quoteView gathers what a quote's page shows: the people who can have
+// secured it and the currencies it can be in
+func (h *Handler) quoteView(q *models.Quote) (templates.QuoteView, error) {
+	v := templates.QuoteView{Quote: q, Now: time.Now()}
+	var err error
+	if v.People, err = h.DB.ListPeople(); err != nil {
+		return v, err
+	}
+	v.Rates, err = h.DB.ListExchangeRates()
+	return v, err
+}
+
+// quoteFromURL loads the quote named by the {id} URL parameter, writing the
+// error response when there is none
+func (h *Handler) quoteFromURL(w http.ResponseWriter, r *http.Request) (*models.Quote, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return nil, false
+	}
+	q, err := h.DB.GetQuote(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if q == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return nil, false
+	}
+	return q, true
+}
+
+// quoteError answers a failed write: a conflict when the quote has been
+// sent, otherwise a server error
+func quoteError(w http.ResponseWriter, err error) {
+	if errors.Is(err, models.ErrQuoteSent) {
+		triggerToast(w, err.Error())
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// QuotePortal shows a client the quote behind a shared link, with buttons
+// to accept or decline it while it's open
+func (h *Handler) QuotePortal(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex")
+
+	q, ok := h.quoteFromToken(w, r)
+	if !ok {
+		return
+	}
+	company, err := h.DB.Company()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Layout("Quote for "+q.Client+" — FullDash", templates.QuotePortalPage(q, company, r.URL.Path, time.Now())).Render(r.Context(), w)
+}
+
+// AnswerQuote records the client's acceptance or refusal from the portal.
+// A quote past its validity date can no longer be accepted.
+func (h *Handler) AnswerQuote(w http.ResponseWriter, r *http.Request) {
+	q, ok := h.quoteFromToken(w, r)
+	if !ok {
+		return
+	}
+
+	to := models.QuoteStatus(r.FormValue("answer"))
+	if to != models.QuoteAccepted && to != models.QuoteDeclined {
+		http.Error(w, "Accept or decline the quote", http.StatusBadRequest)
+		return
+	}
+	if q.Status == models.QuoteSent && !q.Expired(time.Now()) {
+		if _, err := h.DB.SetQuoteStatus(q.ID, models.QuoteSent, to); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	// The page shows whatever the quote's status now is
+	http.Redirect(w, r, "/portal/quotes/"+chi.URLParam(r, "token"), http.StatusSeeOther)
+}
+
+// quoteFromToken loads the quote a portal link points at, answering with
+// the portal's error page when the link is bad, expired or the quote gone
+func (h *Handler) quoteFromToken(w http.ResponseWriter, r *http.Request) (*models.Quote, bool) {
+	id, _, err := auth.ParseQuoteToken(h.Config.PortalSecret, chi.URLParam(r, "token"), time.Now())
+	if errors.Is(err, auth.ErrPortalExpired) {
+		w.WriteHeader(http.StatusGone)
+		templates.Layout("FullDash", templates.PortalError("This link has expired. Ask us for a new one.")).Render(r.Context(), w)
+		return nil, false
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		templates.Layout("FullDash", templates.PortalError("This link is not valid.")).Render(r.Context(), w)
+		return nil, false
+	}
+
+	q, err := h.DB.GetQuote(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if q == nil || q.Status == models.QuoteDraft {
+		w.WriteHeader(http.StatusNotFound)
+		templates.Layout("FullDash", templates.PortalError("This quote is no longer available.")).Render(r.Context(), w)
+		return nil, false
+	}
+	return q, true
+}
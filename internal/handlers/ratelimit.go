@@ -0,0 +1,38 @@
+// handlers/ratelimit.go - Per-client request limits
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// rateWindow is the window the configured per-minute limits count over
+const rateWindow = time.Minute
+
+// allow counts a hit against key and limit (per minute; 0 means unlimited).
+// When the limit is spent it sets Retry-After and returns the wait, rounded
+// up to whole seconds.
+func (h *Handler) allow(key string, limit int, w http.ResponseWriter) (bool, time.Duration) {
+	if limit <= 0 || h.Limiter == nil {
+		return true, 0
+	}
+	ok, wait := h.Limiter.Allow(key, limit, rateWindow)
+	if ok {
+		return true, 0
+	}
+	wait = max(wait.Truncate(time.Second)+time.Second, time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())))
+	return false, wait
+}
+
+// clientIP returns the request's remote address without the port. Behind a
+// proxy set TRUST_PROXY so it reflects X-Forwarded-For instead of the proxy.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
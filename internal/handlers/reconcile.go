@@ -0,0 +1,34 @@
+// handlers/reconcile.go - Reconciliation report
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// ReconciliationPage checks that payments, recognized revenue and the
+// partners' shares agree, listing the records that don't
+func (h *Handler) ReconciliationPage(w http.ResponseWriter, r *http.Request) {
+	rec, err := h.DB.Reconcile()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Layout("FullDash Reconciliation", templates.ReconciliationPage(rec, people)).Render(r.Context(), w)
+}
+
+// APIReconciliation returns the reconciliation report
+func (h *Handler) APIReconciliation(w http.ResponseWriter, r *http.Request) {
+	rec, err := h.DB.Reconcile()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, rec)
+}
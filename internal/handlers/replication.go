@@ -0,0 +1,56 @@
+// handlers/replication.go - Read-only replicas and the replication health probe
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/replication"
+)
+
+// ReadOnly turns away changes with 503 while this instance is a replica,
+// naming the primary when LiteFS knows it. Reads go through.
+func (h *Handler) ReadOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isSafeMethod(r.Method) || !h.Config.Replication.Replica() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		msg := "This is a read-only replica; make changes on the primary"
+		if primary := h.Config.Replication.Primary(); primary != "" {
+			msg += " (" + primary + ")"
+		}
+		http.Error(w, msg, http.StatusServiceUnavailable)
+	})
+}
+
+// ReplicationHealth reports the replication role, journal mode and lag for
+// a load balancer or uptime check: 503 when the lag passes
+// REPLICATION_MAX_LAG or the database isn't in WAL mode
+func (h *Handler) ReplicationHealth(w http.ResponseWriter, r *http.Request) {
+	s := replication.Check(h.Config.Replication, h.DB, time.Now())
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if s.OK() {
+		w.Write([]byte("OK\n"))
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "DEGRADED\n%s\n", strings.Join(s.Problems, "\n"))
+	}
+
+	mode := s.Mode
+	if mode == "" {
+		mode = "off"
+	}
+	fmt.Fprintf(w, "mode: %s\nrole: %s\n", mode, s.Role)
+	if s.Primary != "" {
+		fmt.Fprintf(w, "primary: %s\n", s.Primary)
+	}
+	fmt.Fprintf(w, "journal: %s\n", s.JournalMode)
+	if !s.HeartbeatAt.IsZero() {
+		fmt.Fprintf(w, "heartbeat: %s\n", s.HeartbeatAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(w, "lag: %s (max %s)\n", s.Lag.Round(time.Millisecond), s.MaxLag)
+}
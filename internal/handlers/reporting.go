@@ -0,0 +1,102 @@
+// handlers/reporting.go - Panics, server errors and webhook failures reported to SENTRY_DSN
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/noor-latif/fulldash/internal/sentry"
+)
+
+// reportedBodyLen is how much of a server error's body becomes the report
+const reportedBodyLen = 512
+
+// Recoverer is chi's Recoverer, reporting what it catches: a panic is
+// logged with its stack and answered 500 as before, and sent with the
+// stack and the request. Responses of 500 and up other than 503 (which
+// maintenance and replicas answer on purpose) are sent too, with the
+// start of their body as the message.
+func (h *Handler) Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		body := &prefixWriter{max: reportedBodyLen}
+		ww.Tee(body)
+
+		defer func() {
+			rvr := recover()
+			if rvr == nil {
+				return
+			}
+			if rvr == http.ErrAbortHandler {
+				panic(rvr) // the client went away; not an error
+			}
+			if entry := middleware.GetLogEntry(r); entry != nil {
+				entry.Panic(rvr, debug.Stack())
+			} else {
+				middleware.PrintPrettyStack(rvr)
+			}
+			h.report(sentry.PanicEvent(rvr, 2), r)
+			if r.Header.Get("Connection") != "Upgrade" {
+				ww.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+
+		next.ServeHTTP(ww, r)
+		if status := ww.Status(); status >= 500 && status != http.StatusServiceUnavailable {
+			msg := strings.TrimSpace(body.String())
+			if msg == "" {
+				msg = http.StatusText(status)
+			}
+			e := sentry.MessageEvent(sentry.LevelError, msg).Tag("status", strconv.Itoa(status))
+			e.Fingerprint = []string{"http", r.Method, routePattern(r), msg}
+			h.report(e, r)
+		}
+	})
+}
+
+// report adds the request to e and sends it
+func (h *Handler) report(e *sentry.Event, r *http.Request) {
+	e.Transaction = r.Method + " " + routePattern(r)
+	h.Config.Errors.Capture(e.WithRequest(r, requestID(r)))
+}
+
+// webhookFailed logs a Stripe webhook that couldn't be processed, as
+// log.Printf("[STRIPE] ...") did, and reports it; Stripe gets a 200 or a
+// 503, which the Recoverer doesn't report, so nothing else would show it
+func (h *Handler) webhookFailed(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	log.Print("[STRIPE] " + msg)
+	e := sentry.ErrorEvent(fmt.Errorf("%s", msg), 1)
+	e.Exception.Values[0].Type = "stripe webhook"
+	e.Fingerprint = []string{"stripe-webhook", format}
+	h.Config.Errors.Capture(e.Tag("webhook", "stripe"))
+}
+
+// routePattern is the route r matched, e.g. "/projects/{id}", or its path
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if p := rctx.RoutePattern(); p != "" {
+			return p
+		}
+	}
+	return r.URL.Path
+}
+
+// prefixWriter keeps the first max bytes written to it
+type prefixWriter struct {
+	strings.Builder
+	max int
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	if room := p.max - p.Len(); room > 0 {
+		p.Builder.Write(b[:min(room, len(b))])
+	}
+	return len(b), nil
+}
@@ -0,0 +1,74 @@
+// handlers/requestid.go - Request IDs, to match a reported error to the server's logs
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestIDHeader carries the ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds an ID taken from a proxy
+const maxRequestIDLen = 64
+
+// RequestID gives every request an ID: the proxy's X-Request-ID when
+// trustHeader (TRUST_PROXY) is set and it looks like one, or a fresh one.
+// It is kept where chi's logger prints it, sent back in X-Request-ID and
+// added as a last line to plain-text error bodies, so an error a user
+// reports can be found in the log.
+func RequestID(trustHeader bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !trustHeader || !validRequestID(id) {
+				id = newRequestID()
+			}
+			r = r.WithContext(context.WithValue(r.Context(), middleware.RequestIDKey, id))
+			w.Header().Set(RequestIDHeader, id)
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+			if ww.Status() >= 400 && isPlainText(ww.Header().Get("Content-Type")) {
+				if ww.BytesWritten() == 0 {
+					fmt.Fprintln(ww, http.StatusText(ww.Status()))
+				}
+				fmt.Fprintf(ww, "request %s\n", id)
+			}
+		})
+	}
+}
+
+// requestID returns the request's ID, or ""
+func requestID(r *http.Request) string {
+	return middleware.GetReqID(r.Context())
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID accepts IDs that are safe to log and echo: letters,
+// digits, '-', '_' and '.'
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	return strings.IndexFunc(id, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.')
+	}) < 0
+}
+
+// isPlainText matches http.Error's responses, and those with no body type
+// yet (Recoverer's bare 500)
+func isPlainText(contentType string) bool {
+	return contentType == "" || strings.HasPrefix(contentType, "text/plain")
+}
@@ -0,0 +1,353 @@
+// handlers/retainers.go - Retainers: clients' Stripe subscriptions and the payments of each cycle
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+	"github.com/stripe/stripe-go/v84"
+)
+
+// CreateRetainer maps a Stripe subscription to a client from the client
+// page. With Stripe set up the subscription is looked up right away, so an
+// unknown ID is refused and its status shows before the first event.
+func (h *Handler) CreateRetainer(w http.ResponseWriter, r *http.Request) {
+	clientID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	report, err := h.DB.ClientReport(clientID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if report == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ret := &models.Retainer{
+		ClientID:       clientID,
+		SubscriptionID: strings.TrimSpace(r.FormValue("subscription_id")),
+		Description:    strings.TrimSpace(r.FormValue("description")),
+	}
+	if !strings.HasPrefix(ret.SubscriptionID, "sub_") {
+		http.Error(w, "Enter the Stripe subscription ID (sub_…)", http.StatusBadRequest)
+		return
+	}
+	if existing, err := h.DB.GetRetainerBySubscription(ret.SubscriptionID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if existing != nil {
+		http.Error(w, "That subscription is already a retainer", http.StatusConflict)
+		return
+	}
+	securedBy, ok := parseIDs(r.Form["secured_by"])
+	if !ok {
+		http.Error(w, "Invalid secured_by", http.StatusBadRequest)
+		return
+	}
+	ret.ProjectID, _ = strconv.ParseInt(r.FormValue("project_id"), 10, 64)
+	if ret.ProjectID != 0 && !slices.ContainsFunc(report.Projects, func(p models.ClientProject) bool { return p.ID == ret.ProjectID }) {
+		http.Error(w, "Choose one of the client's projects", http.StatusBadRequest)
+		return
+	}
+	if ret.ProjectID == 0 && len(securedBy) == 0 {
+		http.Error(w, "Choose who the retainer's projects are secured by", http.StatusBadRequest)
+		return
+	}
+	ret.SecuredBy = append([]int64{}, securedBy...)
+
+	if h.Config.StripeKey != "" {
+		sub, err := h.stripeClient().V1Subscriptions.Retrieve(r.Context(), ret.SubscriptionID, nil)
+		if err != nil {
+			log.Printf("[STRIPE] Looking up subscription %s failed: %v", ret.SubscriptionID, err)
+			http.Error(w, "Stripe couldn't find that subscription", http.StatusBadRequest)
+			return
+		}
+		applySubscription(ret, sub)
+	}
+	if err := h.DB.CreateRetainer(ret); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderRetainers(w, r, clientID)
+}
+
+// DeleteRetainer unmaps a subscription; Stripe keeps billing it, but its
+// payments are no longer recorded
+func (h *Handler) DeleteRetainer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	ret, err := h.DB.GetRetainer(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if ret == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if err := h.DB.DeleteRetainer(ret.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderRetainers(w, r, ret.ClientID)
+}
+
+// renderRetainers re-renders a client's retainers
+func (h *Handler) renderRetainers(w http.ResponseWriter, r *http.Request, clientID int64) {
+	report, err := h.DB.ClientReport(clientID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if report == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.RetainerList(report, people).Render(r.Context(), w)
+}
+
+// handleSubscriptionChanged keeps a retainer's status, amount and period
+// in step with its subscription; subscriptions that aren't retainers are
+// ignored
+func (h *Handler) handleSubscriptionChanged(event stripe.Event) error {
+	var sub stripe.Subscription
+	if err := json.Unmarshal(event.Data.Raw, &sub); err != nil {
+		h.webhookFailed("Unmarshal error: %v", err)
+		return nil
+	}
+	ret, err := h.DB.GetRetainerBySubscription(sub.ID)
+	if err != nil {
+		return fmt.Errorf("retainer lookup: %w", err)
+	}
+	if ret == nil {
+		log.Printf("[STRIPE] Subscription %s isn't a retainer, skipping", sub.ID)
+		return nil
+	}
+
+	applySubscription(ret, &sub)
+	if err := h.DB.SetRetainerSubscription(ret); err != nil {
+		return fmt.Errorf("updating retainer %d: %w", ret.ID, err)
+	}
+	return nil
+}
+
+// applySubscription copies what a retainer shows from its subscription
+func applySubscription(ret *models.Retainer, sub *stripe.Subscription) {
+	ret.Status = string(sub.Status)
+	ret.Currency = strings.ToUpper(string(sub.Currency))
+	if ret.Currency == models.BaseCurrency {
+		ret.Currency = ""
+	}
+	ret.AmountCents, ret.Interval, ret.CurrentPeriodEnd = 0, "", nil
+	if sub.Items == nil {
+		return
+	}
+	for _, item := range sub.Items.Data {
+		if item.Price != nil {
+			ret.AmountCents += item.Price.UnitAmount * item.Quantity
+			if item.Price.Recurring != nil {
+				ret.Interval = string(item.Price.Recurring.Interval)
+			}
+		}
+		if item.CurrentPeriodEnd != 0 {
+			end := time.Unix(item.CurrentPeriodEnd, 0)
+			ret.CurrentPeriodEnd = &end
+		}
+	}
+}
+
+// invoiceSubscription returns the ID of the subscription that billed an
+// invoice, or "" for a one-off invoice
+func invoiceSubscription(inv *stripe.Invoice) string {
+	if inv.Parent == nil || inv.Parent.SubscriptionDetails == nil || inv.Parent.SubscriptionDetails.Subscription == nil {
+		return ""
+	}
+	return inv.Parent.SubscriptionDetails.Subscription.ID
+}
+
+// retainerPayment records a paid cycle of a retainer. Paying into an
+// ongoing project it is logged as a payment of that project and announced
+// as RetainerPaid, so payment rules leave the project open. Otherwise the
+// cycle becomes a new project, already paid, for the invoice's amount
+// excluding tax at the tax rate Stripe charged; its Stripe ID is the
+// invoice's, so a cycle is only recorded once. An error means the cycle
+// wasn't recorded.
+func (h *Handler) retainerPayment(ret *models.Retainer, inv *stripe.Invoice) error {
+	report, err := h.DB.ClientReport(ret.ClientID)
+	if err != nil {
+		return err
+	}
+	if report == nil {
+		h.webhookFailed("Client %d of retainer %d not found", ret.ClientID, ret.ID)
+		return nil
+	}
+	client := report.Client.Name
+
+	if ret.ProjectID != 0 {
+		p, err := h.DB.GetProject(ret.ProjectID)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			h.webhookFailed("Project %d of retainer %d not found", ret.ProjectID, ret.ID)
+			return nil
+		}
+		pay, err := h.basePayment(p, inv.AmountPaid, inv.Currency)
+		if err != nil {
+			return fmt.Errorf("retainer %d payment: %w", ret.ID, err)
+		}
+		if err := h.logStripePayment(p, pay, "Retainer "+periodLabel(inv)); err != nil {
+			return err
+		}
+		h.Events.Publish(context.Background(), events.Event{
+			Type:        events.RetainerPaid,
+			ProjectID:   p.ID,
+			Client:      p.Client,
+			AmountCents: pay.Cents,
+			Source:      events.SourceStripe,
+		})
+	} else {
+		if existing, err := h.DB.GetProjectByStripeID(inv.ID); err != nil {
+			return fmt.Errorf("project lookup: %w", err)
+		} else if existing != nil {
+			log.Printf("[STRIPE] Invoice %s already recorded as project %d", inv.ID, existing.ID)
+			return nil
+		}
+
+		p, err := h.retainerProject(ret, client, inv)
+		if err != nil {
+			return fmt.Errorf("retainer %d invoice %s: %w", ret.ID, inv.ID, err)
+		}
+		// Converted before the project is created: once it is, a retry
+		// finds the invoice recorded
+		pay, err := h.basePayment(p, inv.AmountPaid, inv.Currency)
+		if err != nil {
+			return fmt.Errorf("retainer %d payment: %w", ret.ID, err)
+		}
+		if err := h.createProject(p); err != nil {
+			return fmt.Errorf("creating project for retainer %d: %w", ret.ID, err)
+		}
+		if err := h.DB.LogActivity(&models.Activity{
+			ProjectID: p.ID,
+			Client:    p.Client,
+			Action:    "created",
+			Summary:   "Retainer " + periodLabel(inv),
+			Actor:     "stripe",
+			Source:    events.SourceStripe,
+		}); err != nil {
+			h.webhookFailed("Audit log error: %v", err)
+		}
+		if err := h.logStripePayment(p, pay, ""); err != nil {
+			h.webhookFailed("Audit log error: %v", err)
+		}
+		h.Events.Publish(context.Background(), events.Event{
+			Type:        events.ProjectCreated,
+			ProjectID:   p.ID,
+			Client:      p.Client,
+			AmountCents: p.RevenueCents,
+			To:          p.Status,
+			Source:      events.SourceStripe,
+		})
+		h.Events.Publish(context.Background(), events.Event{
+			Type:        events.PaymentReceived,
+			ProjectID:   p.ID,
+			Client:      p.Client,
+			AmountCents: pay.Cents,
+			Source:      events.SourceStripe,
+		})
+	}
+
+	if err := h.DB.MarkRetainerPaid(ret.ID); err != nil {
+		h.webhookFailed("Marking retainer %d paid: %v", ret.ID, err)
+	}
+	return nil
+}
+
+// retainerProject builds the paid project for one cycle of a retainer
+func (h *Handler) retainerProject(ret *models.Retainer, client string, inv *stripe.Invoice) (*models.Project, error) {
+	description := ret.Description
+	if description == "" {
+		description = "Retainer"
+	}
+	p := &models.Project{
+		Client:          client,
+		Description:     description + " — " + periodLabel(inv),
+		RevenueCents:    inv.TotalExcludingTax,
+		Status:          models.StatusPaid,
+		SecuredBy:       ret.SecuredBy,
+		StripePaymentID: inv.ID,
+		PaymentDays:     models.DefaultPaymentDays,
+	}
+	if tax := inv.Total - inv.TotalExcludingTax; tax > 0 && inv.TotalExcludingTax > 0 {
+		p.VATPercent = math.Round(float64(tax)*10000/float64(inv.TotalExcludingTax)) / 100
+	}
+
+	if currency := strings.ToUpper(string(inv.Currency)); currency != models.BaseCurrency {
+		revenue, err := h.foreignRevenue(currency, inv.TotalExcludingTax, nil)
+		if err != nil {
+			return nil, err
+		}
+		p.Currency, p.OriginalCents, p.RevenueCents = currency, inv.TotalExcludingTax, revenue
+	}
+	return p, nil
+}
+
+// logStripePayment logs a payment received through Stripe in the project's
+// audit log, where reconciliation counts it; note follows the amount
+func (h *Handler) logStripePayment(p *models.Project, pay stripePayment, note string) error {
+	summary := pay.Summary()
+	if note != "" {
+		summary += " for " + note
+	}
+	if err := h.DB.LogActivity(&models.Activity{
+		ProjectID: p.ID,
+		Client:    p.Client,
+		Action:    "payment",
+		Summary:   summary,
+		Actor:     "stripe",
+		Source:    events.SourceStripe,
+	}); err != nil {
+		return fmt.Errorf("logging payment for project %d: %w", p.ID, err)
+	}
+	return nil
+}
+
+// periodLabel names the billing period an invoice covers, by the month it
+// starts in
+func periodLabel(inv *stripe.Invoice) string {
+	start := inv.PeriodStart
+	if inv.Lines != nil && len(inv.Lines.Data) > 0 && inv.Lines.Data[0].Period != nil {
+		start = inv.Lines.Data[0].Period.Start
+	}
+	return time.Unix(start, 0).Format("Jan 2006")
+}
@@ -0,0 +1,190 @@
+// handlers/sie.go - SIE 4 export of a year's invoices, payments and expenses
+package handlers
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/sie"
+)
+
+// Voucher series in the SIE export, each numbered from 1 in date order
+const (
+	sieInvoices = "F" // issued invoices
+	siePayments = "B" // payments received: paid invoices and projects paid directly
+	sieExpenses = "E" // expenses the partners paid
+)
+
+// SIEExport downloads ?year= (default this year) as an SIE 4 file that
+// Fortnox, Visma and most Swedish accounting programs import as vouchers
+func (h *Handler) SIEExport(w http.ResponseWriter, r *http.Request) {
+	year := time.Now().Year()
+	if s := r.URL.Query().Get("year"); s != "" {
+		y, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+		year = y
+	}
+
+	f, err := h.sieFile(year)
+	if errors.Is(err, errNoRate) {
+		http.Error(w, err.Error()+"; add one in Admin", http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=IBM437")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="fulldash-%d.se"`, year))
+	if err := sie.Write(w, f); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// sieFile books a year: each issued invoice to receivables, its payment
+// from them to the bank, projects paid without an invoice straight to the
+// bank, and expenses as owed to the partner who paid them. Void invoices
+// are left out. Foreign invoices are converted at the stored rate.
+func (h *Handler) sieFile(year int) (sie.File, error) {
+	company, err := h.DB.Company()
+	if err != nil {
+		return sie.File{}, err
+	}
+	accounts, err := h.DB.Accounts()
+	if err != nil {
+		return sie.File{}, err
+	}
+	b := sieBooks{accounts: accounts, names: map[string]string{}}
+
+	invoices, err := h.DB.ListInvoices("")
+	if err != nil {
+		return sie.File{}, err
+	}
+	invoiced := map[int64]bool{} // projects settled through an invoice
+	for _, inv := range invoices {
+		if inv.Draft() || inv.Status == models.InvoiceVoid {
+			continue
+		}
+		invoiced[inv.ProjectID] = true
+		subtotal, vat := inv.SubtotalCents(), inv.VATCents()
+		if inv.Currency != "" {
+			rate, err := h.DB.GetExchangeRate(inv.Currency)
+			if err != nil {
+				return sie.File{}, err
+			}
+			if rate == nil {
+				return sie.File{}, fmt.Errorf("%w for %s (invoice %s)", errNoRate, inv.Currency, inv.Label())
+			}
+			subtotal, vat = rate.Convert(subtotal), rate.Convert(vat)
+		}
+		text := "Invoice " + inv.Label() + ", " + inv.Client
+		if inv.IssueDate.Year() == year {
+			b.add(sieInvoices, inv.IssueDate, text, b.sale(accounts.Receivables, "Accounts receivable", subtotal, vat, inv.VATPercent))
+		}
+		if inv.Status == models.InvoicePaid && inv.PaidOn.Year() == year {
+			b.add(siePayments, inv.PaidOn, "Payment of invoice "+inv.Label()+", "+inv.Client, []sie.Row{
+				b.row(accounts.Bank, "Bank", subtotal+vat),
+				b.row(accounts.Receivables, "Accounts receivable", -(subtotal + vat)),
+			})
+		}
+	}
+
+	paid, err := h.DB.ListProjectsByStatus(models.StatusPaid)
+	if err != nil {
+		return sie.File{}, err
+	}
+	for _, p := range paid {
+		at := p.PaymentTime()
+		if invoiced[p.ID] || at.Year() != year {
+			continue
+		}
+		b.add(siePayments, at, "Payment, "+p.Client, b.sale(accounts.Bank, "Bank", p.RevenueCents, p.VATCents(), p.VATPercent))
+	}
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		return sie.File{}, err
+	}
+	expenses, err := h.DB.ListAllExpenses(models.ExpenseFilter{Year: year})
+	if err != nil {
+		return sie.File{}, err
+	}
+	for _, e := range expenses {
+		b.add(sieExpenses, e.SpentOn, e.Description+" ("+people.Name(e.PayerID)+")", []sie.Row{
+			b.row(accounts.ExpenseAccount(e.Category), e.Category.Label()+" expenses", e.AmountCents),
+			b.row(accounts.PartnerDebt, "Owed to partners", -e.AmountCents),
+		})
+	}
+
+	return sie.File{
+		Company:   cmp.Or(company.Name, "FullDash"),
+		OrgNumber: company.OrgNumber,
+		Year:      year,
+		Generated: time.Now(),
+		Accounts:  b.accountList(),
+		Vouchers:  b.numbered(),
+	}, nil
+}
+
+// sieBooks collects vouchers and names the accounts they use
+type sieBooks struct {
+	accounts models.Accounts
+	names    map[string]string // account number → the first name it was used under
+	vouchers []sie.Voucher
+}
+
+// add records a voucher; vouchers are numbered once all are in
+func (b *sieBooks) add(series string, date time.Time, text string, rows []sie.Row) {
+	b.vouchers = append(b.vouchers, sie.Voucher{Series: series, Date: date, Text: text, Rows: rows})
+}
+
+// row books cents to an account, naming the account if it's new
+func (b *sieBooks) row(account, name string, cents int64) sie.Row {
+	if _, ok := b.names[account]; !ok {
+		b.names[account] = name
+	}
+	return sie.Row{Account: account, AmountCents: cents}
+}
+
+// sale debits the gross amount to account and credits the net to sales and
+// the VAT to output VAT at its rate
+func (b *sieBooks) sale(account, name string, netCents, vatCents int64, vatPercent float64) []sie.Row {
+	rows := []sie.Row{
+		b.row(account, name, netCents+vatCents),
+		b.row(b.accounts.SalesAccount(vatPercent), "Sales", -netCents),
+	}
+	if vatCents != 0 {
+		rows = append(rows, b.row(b.accounts.VATAccount(vatPercent), "Output VAT", -vatCents))
+	}
+	return rows
+}
+
+// numbered returns the vouchers in date order, numbered within each series
+func (b *sieBooks) numbered() []sie.Voucher {
+	slices.SortStableFunc(b.vouchers, func(x, y sie.Voucher) int { return x.Date.Compare(y.Date) })
+	next := map[string]int{}
+	for i := range b.vouchers {
+		next[b.vouchers[i].Series]++
+		b.vouchers[i].Number = next[b.vouchers[i].Series]
+	}
+	return b.vouchers
+}
+
+// accountList returns the accounts used, in number order
+func (b *sieBooks) accountList() []sie.Account {
+	list := make([]sie.Account, 0, len(b.names))
+	for number, name := range b.names {
+		list = append(list, sie.Account{Number: number, Name: name})
+	}
+	slices.SortFunc(list, func(x, y sie.Account) int { return cmp.Compare(x.Number, y.Number) })
+	return list
+}
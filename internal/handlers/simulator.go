@@ -0,0 +1,212 @@
+// handlers/simulator.go - What-if split simulator (nothing is saved)
+package handlers
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/service"
+	"github.com/noor-latif/fulldash/internal/store"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// Simulator renders the split sandbox: hypothetical hours, revenue and
+// split rules for one project (?project=) or the projects paid in a year,
+// next to the actual split. HTMX requests get only the section back.
+func (h *Handler) Simulator(w http.ResponseWriter, r *http.Request) {
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	v, msg := parseScenario(r, people)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	projects, err := h.DB.ListProjects(models.ProjectFilter{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	v.Projects = projects
+
+	rulesAt, err := h.DB.SplitRules()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if v.ProjectID != 0 {
+		err = h.simulateProject(&v, rulesAt)
+	} else {
+		err = h.simulateYear(&v, rulesAt)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if isHTMX(r) {
+		templates.Simulator(v).Render(r.Context(), w)
+		return
+	}
+	templates.Layout("FullDash Simulator", templates.Simulator(v)).Render(r.Context(), w)
+}
+
+// simulateProject applies the scenario's overrides to one project
+func (h *Handler) simulateProject(v *templates.SimulatorView, rulesAt func(time.Time) models.SplitRules) error {
+	p, err := h.DB.GetProject(v.ProjectID)
+	if err != nil || p == nil {
+		return err
+	}
+	contribs, err := h.DB.GetContributions(p.ID)
+	if err != nil {
+		return err
+	}
+	expenses, err := h.DB.ListExpenses(p.ID)
+	if err != nil {
+		return err
+	}
+
+	what := *p
+	if v.Revenue != nil {
+		what.RevenueCents = int64(math.Round(*v.Revenue * 100))
+	}
+	if len(v.SecuredBy) > 0 {
+		what.SecuredBy = v.SecuredBy
+	}
+	actual := service.HoursOf(contribs)
+	hours := maps.Clone(actual)
+	maps.Copy(hours, v.Hours)
+	var whatContribs []models.Contribution
+	for id, h := range hours {
+		whatContribs = append(whatContribs, models.Contribution{ProjectID: p.ID, PersonID: id, Hours: h})
+	}
+
+	rules := rulesAt(p.PaymentTime())
+	v.Rows = []templates.SimulatorRow{{
+		Project:   *p,
+		Hours:     actual,
+		Actual:    store.CalcRevenueSplitWith(p, contribs, expenses, rules),
+		Simulated: store.CalcRevenueSplitWith(&what, whatContribs, expenses, scenarioRules(v, rules)),
+	}}
+	return nil
+}
+
+// simulateYear applies the scenario's split rules to a year's paid projects
+func (h *Handler) simulateYear(v *templates.SimulatorView, rulesAt func(time.Time) models.SplitRules) error {
+	paid, err := h.DB.ListProjectsByStatus(models.StatusPaid)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range paid {
+		if p.PaymentTime().Year() != v.Year {
+			continue
+		}
+		contribs, err := h.DB.GetContributions(p.ID)
+		if err != nil {
+			return err
+		}
+		expenses, err := h.DB.ListExpenses(p.ID)
+		if err != nil {
+			return err
+		}
+		rules := rulesAt(p.PaymentTime())
+		v.Rows = append(v.Rows, templates.SimulatorRow{
+			Project:   p,
+			Hours:     service.HoursOf(contribs),
+			Actual:    store.CalcRevenueSplitWith(&p, contribs, expenses, rules),
+			Simulated: store.CalcRevenueSplitWith(&p, contribs, expenses, scenarioRules(v, rules)),
+		})
+	}
+	return nil
+}
+
+// parseScenario reads the sandbox inputs; empty fields keep actual values
+func parseScenario(r *http.Request, people models.People) (templates.SimulatorView, string) {
+	q := r.URL.Query()
+	v := templates.SimulatorView{People: people, Year: time.Now().Year()}
+
+	if s := q.Get("project"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return v, "Invalid project"
+		}
+		v.ProjectID = id
+	}
+	if s := q.Get("year"); s != "" {
+		year, err := strconv.Atoi(s)
+		if err != nil {
+			return v, "Invalid year"
+		}
+		v.Year = year
+	}
+
+	if len(q["secured_by"]) > 0 {
+		ids, ok := parseIDs(q["secured_by"])
+		if !ok || !people.Valid(ids) {
+			return v, "Invalid secured_by"
+		}
+		v.SecuredBy = ids
+	}
+
+	var msg string
+	num := func(name string) *float64 {
+		s := strings.Replace(strings.TrimSpace(q.Get(name)), ",", ".", 1)
+		if s == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || f < 0 {
+			msg = name + " must be a non-negative number"
+			return nil
+		}
+		return &f
+	}
+	v.Revenue = num("revenue")
+	v.Hours = models.PerPerson[float64]{}
+	percents := models.PerPerson[float64]{}
+	for _, p := range people {
+		if h := num(fmt.Sprintf("hours_%d", p.ID)); h != nil {
+			v.Hours[p.ID] = *h
+		}
+		if pct := num(fmt.Sprintf("percent_%d", p.ID)); pct != nil {
+			percents[p.ID] = *pct
+			if *pct > 100 {
+				msg = "percentages must be between 0 and 100"
+			}
+		}
+	}
+	if len(percents) > 0 {
+		if math.Abs(percents.Total()-100) > 0.01 {
+			msg = "shares of profit must add up to 100%"
+		}
+		v.Percents = percents
+	}
+	v.FinderFee = num("finder_fee")
+	if v.FinderFee != nil && *v.FinderFee > 100 {
+		msg = "percentages must be between 0 and 100"
+	}
+	return v, msg
+}
+
+// scenarioRules applies the scenario's percentage overrides to the agreed rules
+func scenarioRules(v *templates.SimulatorView, rules models.SplitRules) models.SplitRules {
+	if v.Percents != nil {
+		rules.FixedSplit = true
+		rules.Percents = v.Percents
+	}
+	if v.FinderFee != nil {
+		rules.FinderFeePercent = *v.FinderFee
+	}
+	return rules
+}
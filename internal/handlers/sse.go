@@ -0,0 +1,76 @@
+// handlers/sse.go - Server-Sent Events for live dashboard updates
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/events"
+)
+
+// sseHeartbeat keeps idle connections (and proxies) from timing out
+const sseHeartbeat = 25 * time.Second
+
+// sseRetry is the reconnect delay suggested to the browser, in milliseconds
+const sseRetry = 3000
+
+// EventStream streams project changes to the dashboard as SSE. Every message is
+// sent as the "project" event; a "reload" event asks the client to refresh
+// when it reconnects after more changes than the replay buffer holds.
+func (h *Handler) EventStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	lastID, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	ch, missed, complete, cancel := h.Stream.Subscribe(lastID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry)
+
+	if !complete {
+		fmt.Fprint(w, "event: reload\ndata: {}\n\n")
+	}
+	for _, m := range missed {
+		writeSSE(w, m)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case m, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeSSE(w, m)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// writeSSE writes one message as a "project" event
+func writeSSE(w http.ResponseWriter, m events.Message) {
+	data, _ := json.Marshal(map[string]any{
+		"type":       m.Event.Type,
+		"project_id": m.Event.ProjectID,
+		"client":     m.Event.Client,
+		"status":     m.Event.To,
+	})
+	fmt.Fprintf(w, "id: %d\nevent: project\ndata: %s\n\n", m.ID, data)
+}
@@ -0,0 +1,152 @@
+// handlers/stats.go - Quarterly revenue share and hours charts, long-term trend
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// Revenue series bounds: the periods shown by default, and the most one
+// request may ask for
+const (
+	revenuePeriods    = 12
+	maxRevenuePeriods = 240
+)
+
+// StatsPage charts each partner's share of revenue and hours per quarter of
+// ?year=, its win rate, and total revenue month by month since the history
+// began
+func (h *Handler) StatsPage(w http.ResponseWriter, r *http.Request) {
+	year, err := strconv.Atoi(r.URL.Query().Get("year"))
+	if err != nil {
+		year = time.Now().Year()
+	}
+
+	quarters, err := h.DB.QuarterlyStats(year)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	winRate, err := h.DB.WinRate(year)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	trend, err := h.DB.MonthlyStatsSnapshots()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	view := templates.StatsView{Year: year, People: people, Quarters: quarters, WinRate: winRate, Trend: trend}
+	templates.Layout("FullDash Stats", templates.StatsPage(view)).Render(r.Context(), w)
+}
+
+// APIQuarterlyStats returns the quarters of ?year= (default this year)
+func (h *Handler) APIQuarterlyStats(w http.ResponseWriter, r *http.Request) {
+	year := time.Now().Year()
+	if s := r.URL.Query().Get("year"); s != "" {
+		y, err := strconv.Atoi(s)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid year")
+			return
+		}
+		year = y
+	}
+
+	quarters, err := h.DB.QuarterlyStats(year)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, quarters)
+}
+
+// APIWinRate returns the deals won and lost in ?year= (default this year),
+// with the reasons they were lost
+func (h *Handler) APIWinRate(w http.ResponseWriter, r *http.Request) {
+	year := time.Now().Year()
+	if s := r.URL.Query().Get("year"); s != "" {
+		y, err := strconv.Atoi(s)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid year")
+			return
+		}
+		year = y
+	}
+
+	winRate, err := h.DB.WinRate(year)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, winRate)
+}
+
+// APIRevenueSeries returns paid revenue, projects and shares per period:
+// ?granularity= month (default), quarter or year, over the periods holding
+// ?from= to ?to= (dates; default the last 12 periods up to today)
+func (h *Handler) APIRevenueSeries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	g := models.GranularityMonth
+	if s := q.Get("granularity"); s != "" {
+		g = models.Granularity(s)
+	}
+	if !g.Valid() {
+		writeJSONError(w, http.StatusBadRequest, "granularity must be month, quarter or year")
+		return
+	}
+
+	to := time.Now()
+	if s := q.Get("to"); s != "" {
+		t, err := time.Parse(dateLayout, s)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid to date")
+			return
+		}
+		to = t
+	}
+	from := g.Add(g.Start(to), 1-revenuePeriods)
+	if s := q.Get("from"); s != "" {
+		t, err := time.Parse(dateLayout, s)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid from date")
+			return
+		}
+		from = t
+	}
+	if from.After(to) {
+		writeJSONError(w, http.StatusBadRequest, "from must not be after to")
+		return
+	}
+	if g.Add(g.Start(from), maxRevenuePeriods).Before(to) {
+		writeJSONError(w, http.StatusBadRequest, "at most 240 periods at a time")
+		return
+	}
+
+	periods, err := h.DB.RevenueSeries(g, from, to)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, periods)
+}
+
+// recentRevenue is the last 12 months of the revenue series, for the dashboard
+func (h *Handler) recentRevenue() ([]models.RevenuePeriod, error) {
+	now := time.Now()
+	g := models.GranularityMonth
+	return h.DB.RevenueSeries(g, g.Add(g.Start(now), 1-revenuePeriods), now)
+}
@@ -0,0 +1,166 @@
+// handlers/statuses.go - Admin management of statuses (kanban columns)
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/domain"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// CreateStatus adds a status as the last column; its key is derived from the label
+func (h *Handler) CreateStatus(w http.ResponseWriter, r *http.Request) {
+	probability, ok := statusProbability(r)
+	s := &models.Status{
+		Label:       strings.TrimSpace(r.FormValue("label")),
+		Color:       r.FormValue("color"),
+		Terminal:    r.FormValue("terminal") == "on",
+		Probability: probability,
+	}
+	s.Key = statusKey(s.Label)
+	if !ok || s.Key == "" || !s.ValidColor() {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if wf.Valid(s.Key) {
+		triggerToast(w, "A status named "+s.Label+" already exists")
+		h.renderStatuses(w, r)
+		return
+	}
+
+	if err := h.DB.CreateStatus(s); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.invalidateMetrics()
+
+	triggerToast(w, "Added status "+s.Label)
+	h.renderStatuses(w, r)
+}
+
+// statusProbability reads the win % field, 50 when left out
+func statusProbability(r *http.Request) (int, bool) {
+	s := r.FormValue("probability")
+	if s == "" {
+		return 50, true
+	}
+	pct, err := strconv.Atoi(s)
+	return pct, err == nil && pct >= 0 && pct <= 100
+}
+
+// UpdateStatus saves a status's label, color, position, win % and terminal flag
+func (h *Handler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s, ok := wf.Get(models.ProjectStatus(chi.URLParam(r, "key")))
+	if !ok {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	position, err := strconv.Atoi(r.FormValue("position"))
+	probability, ok := statusProbability(r)
+	s.Label = strings.TrimSpace(r.FormValue("label"))
+	s.Color = r.FormValue("color")
+	if err != nil || !ok || s.Label == "" || !s.ValidColor() {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	s.Position = position
+	s.Probability = probability
+	// Built-ins keep their flag: revenue and payments depend on paid being final
+	if !s.Builtin() {
+		s.Terminal = r.FormValue("terminal") == "on"
+	}
+
+	if err := h.DB.UpdateStatus(&s); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.invalidateMetrics()
+
+	triggerToast(w, "Saved status "+s.Label)
+	h.renderStatuses(w, r)
+}
+
+// DeleteStatus removes a custom status that no project is in
+func (h *Handler) DeleteStatus(w http.ResponseWriter, r *http.Request) {
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s, ok := wf.Get(models.ProjectStatus(chi.URLParam(r, "key")))
+	if !ok {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if s.Builtin() {
+		triggerToast(w, s.Label+" is built in and can't be deleted")
+		h.renderStatuses(w, r)
+		return
+	}
+	n, err := h.DB.CountProjectsByStatus(s.Key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n > 0 {
+		triggerToast(w, "Move the "+strconv.Itoa(n)+" project(s) out of "+s.Label+" first")
+		h.renderStatuses(w, r)
+		return
+	}
+
+	if err := h.DB.DeleteStatus(s.Key); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.invalidateMetrics()
+
+	triggerToast(w, "Deleted status "+s.Label)
+	h.renderStatuses(w, r)
+}
+
+// renderStatuses renders the admin status panel
+func (h *Handler) renderStatuses(w http.ResponseWriter, r *http.Request) {
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Statuses(wf).Render(r.Context(), w)
+}
+
+// workflow loads the configured statuses in board order
+func (h *Handler) workflow() (domain.Workflow, error) {
+	statuses, err := h.DB.ListStatuses()
+	return domain.Workflow(statuses), err
+}
+
+// statusKey turns a label into a status key ("On hold" → "on_hold")
+func statusKey(label string) models.ProjectStatus {
+	var b strings.Builder
+	for _, f := range strings.FieldsFunc(strings.ToLower(label), func(c rune) bool {
+		return (c < 'a' || c > 'z') && (c < '0' || c > '9')
+	}) {
+		if b.Len() > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteString(f)
+	}
+	return models.ProjectStatus(b.String())
+}
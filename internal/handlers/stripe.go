@@ -1,79 +1,236 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
+	"strconv"
+	"strings"
 
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
 	"github.com/stripe/stripe-go/v84"
 	"github.com/stripe/stripe-go/v84/webhook"
 )
 
 func (h *Handler) StripeWebhook(w http.ResponseWriter, r *http.Request) {
-	// Always return 200 to Stripe
-	w.WriteHeader(http.StatusOK)
-
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Printf("[STRIPE] Read error: %v", err)
+		h.webhookFailed("Read error: %v", err)
+		http.Error(w, "Bad request", http.StatusBadRequest)
 		return
 	}
 
-	sigHeader := r.Header.Get("Stripe-Signature")
-	webhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
-
 	var event stripe.Event
-	
-	if webhookSecret != "" {
-		event, err = webhook.ConstructEvent(body, sigHeader, webhookSecret)
+
+	switch {
+	case h.Config.StripeWebhookSecret != "":
+		event, err = webhook.ConstructEvent(body, r.Header.Get("Stripe-Signature"), h.Config.StripeWebhookSecret)
 		if err != nil {
-			log.Printf("[STRIPE] Signature verify failed: %v", err)
+			h.webhookFailed("Signature verify failed: %v", err)
+			http.Error(w, "Invalid signature", http.StatusBadRequest)
 			return
 		}
-	} else {
-		// Dev mode: parse without verification
+	case h.Config.StripeWebhookInsecure:
+		// Local testing only (STRIPE_WEBHOOK_INSECURE): parse without verification
 		if err := json.Unmarshal(body, &event); err != nil {
-			log.Printf("[STRIPE] Parse error: %v", err)
+			h.webhookFailed("Parse error: %v", err)
+			http.Error(w, "Bad request", http.StatusBadRequest)
 			return
 		}
-		log.Printf("[STRIPE] Warning: No WEBHOOK_SECRET, skipping signature verify")
+		log.Printf("[STRIPE] Warning: unsigned event accepted (STRIPE_WEBHOOK_INSECURE)")
+	default:
+		// Anyone could post an unsigned event; Stripe retries until the
+		// secret is set
+		h.webhookFailed("No STRIPE_WEBHOOK_SECRET, unsigned event refused")
+		http.Error(w, "Webhook not configured", http.StatusServiceUnavailable)
+		return
 	}
 
 	log.Printf("[STRIPE] Event: %s", event.Type)
 
+	// Stripe retries deliveries, and with several instances any of them may
+	// receive a retry; process each event once. An event is recorded once
+	// it's processed, so one that failed is processed again when retried
+	key := "stripe:" + event.ID
+	if event.ID != "" {
+		seen, err := h.DB.WebhookEventSeen(key)
+		if err != nil {
+			h.webhookFailed("Dedup error: %v", err)
+			http.Error(w, "Try again", http.StatusServiceUnavailable)
+			return
+		}
+		if seen {
+			log.Printf("[STRIPE] Duplicate event %s, skipping", event.ID)
+			return
+		}
+	}
+
+	if err := h.handleStripeEvent(event); err != nil {
+		// Not recorded; 503 has Stripe retry it
+		h.webhookFailed("Event %s (%s) not processed: %v", event.ID, event.Type, err)
+		http.Error(w, "Event not processed", http.StatusServiceUnavailable)
+		return
+	}
+	if event.ID != "" {
+		if _, err := h.DB.MarkWebhookEvent(key); err != nil {
+			h.webhookFailed("Dedup error: %v", err)
+		}
+	}
+}
+
+// handleStripeEvent processes a verified event. Errors are the ones a retry
+// may get past (the database, a missing exchange rate); events that can't
+// ever be processed, e.g. naming a project that doesn't exist, are reported
+// with webhookFailed and acknowledged.
+func (h *Handler) handleStripeEvent(event stripe.Event) error {
 	switch event.Type {
 	case "payment_intent.succeeded":
-		h.handlePaymentIntentSucceeded(event)
+		return h.handlePaymentIntentSucceeded(event)
 	case "charge.succeeded":
 		h.handleChargeSucceeded(event)
 	case "invoice.paid":
-		h.handleInvoicePaid(event)
+		return h.handleInvoicePaid(event)
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted",
+		"customer.subscription.paused", "customer.subscription.resumed":
+		return h.handleSubscriptionChanged(event)
 	}
+	return nil
 }
 
-func (h *Handler) handlePaymentIntentSucceeded(event stripe.Event) {
+func (h *Handler) handlePaymentIntentSucceeded(event stripe.Event) error {
 	var pi stripe.PaymentIntent
 	if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
-		log.Printf("[STRIPE] Unmarshal error: %v", err)
-		return
+		h.webhookFailed("Unmarshal error: %v", err)
+		return nil
 	}
 
 	projectID := pi.Metadata["project_id"]
 	if projectID == "" {
 		log.Printf("[STRIPE] No project_id in metadata")
-		return
+		return nil
 	}
 
 	// Find project by stripe_payment_id or metadata
 	// For now, we use metadata to link
-	log.Printf("[STRIPE] Payment succeeded for project %s: %.2f %s", 
-		projectID, float64(pi.AmountReceived)/100, pi.Currency)
-	
-	// TODO: Link to project and update status
-	// This requires the project to have been created with the stripe_payment_id
-	// or we look up by client name match
+	log.Printf("[STRIPE] Payment succeeded for project %s: %.2f %s",
+		projectID, float64(fromStripeAmount(pi.AmountReceived, string(pi.Currency)))/100, pi.Currency)
+
+	// A milestone's payment link pays for that stage only
+	if milestoneID := pi.Metadata["milestone_id"]; milestoneID != "" {
+		return h.milestonePayment(milestoneID, pi.ID, pi.AmountReceived, pi.Currency, pi.ReceiptEmail)
+	}
+
+	// Status changes are left to automation rules on payment_received
+	return h.publishPayment(projectID, pi.AmountReceived, pi.Currency, pi.ReceiptEmail)
+}
+
+// publishPayment announces a payment for a project ID taken from Stripe
+// metadata and emails the client a receipt; payerEmail is the address the
+// payer gave Stripe, if any. Payments in other currencies are converted to
+// kronor (see basePayment). Nothing is recorded when it fails.
+func (h *Handler) publishPayment(projectID string, amount int64, currency stripe.Currency, payerEmail string) error {
+	id, err := strconv.ParseInt(projectID, 10, 64)
+	if err != nil {
+		h.webhookFailed("Invalid project_id %q", projectID)
+		return nil
+	}
+	p, err := h.DB.GetProject(id)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		h.webhookFailed("Project %d not found", id)
+		return nil
+	}
+	pay, err := h.basePayment(p, amount, currency)
+	if err != nil {
+		return fmt.Errorf("payment for project %d: %w", p.ID, err)
+	}
+	if err := h.logStripePayment(p, pay, ""); err != nil {
+		return err
+	}
+	h.Events.Publish(context.Background(), events.Event{
+		Type:        events.PaymentReceived,
+		ProjectID:   p.ID,
+		Client:      p.Client,
+		AmountCents: pay.Cents,
+		Source:      events.SourceStripe,
+	})
+	h.sendReceipt(p, pay, payerEmail)
+	return nil
+}
+
+// stripePayment is an amount received through Stripe, in kronor and as paid
+type stripePayment struct {
+	Cents         int64  // in kronor
+	OriginalCents int64  // in Currency; 0 when paid in kronor
+	Currency      string // "" for kronor
+}
+
+// Paid is the amount in the currency it was paid in ("" for kronor)
+func (p stripePayment) Paid() (int64, string) {
+	if p.Currency == "" {
+		return p.Cents, ""
+	}
+	return p.OriginalCents, p.Currency
+}
+
+// Summary is the payment's audit log summary
+func (p stripePayment) Summary() string {
+	if p.Currency == "" {
+		return models.PaymentSummary(p.Cents)
+	}
+	return models.ForeignPaymentSummary(p.Cents, p.OriginalCents, p.Currency)
+}
+
+// basePayment converts an amount Stripe received for a project to kronor.
+// Paid in the currency the project was quoted in, it is converted at the
+// rate the project was, so paying in full comes to exactly its revenue;
+// other currencies take the stored rate, and without one it's refused.
+func (h *Handler) basePayment(p *models.Project, amount int64, currency stripe.Currency) (stripePayment, error) {
+	code := strings.ToUpper(string(currency))
+	cents := fromStripeAmount(amount, code)
+	if code == "" || code == models.BaseCurrency {
+		return stripePayment{Cents: cents}, nil
+	}
+	pay := stripePayment{OriginalCents: cents, Currency: code}
+	if p.Currency == code && p.OriginalCents > 0 {
+		pay.Cents = p.AtBookedRate(cents)
+		return pay, nil
+	}
+	converted, err := h.foreignRevenue(code, cents, nil)
+	if err != nil {
+		return stripePayment{}, err
+	}
+	pay.Cents = converted
+	return pay, nil
+}
+
+// zeroDecimal are the currencies Stripe takes in whole units rather than
+// hundredths
+var zeroDecimal = map[string]bool{
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "JPY": true, "KMF": true, "KRW": true, "MGA": true,
+	"PYG": true, "RWF": true, "UGX": true, "VND": true, "VUV": true, "XAF": true, "XOF": true, "XPF": true,
+}
+
+// stripeAmount is cents of a currency the way Stripe takes the amount
+func stripeAmount(cents int64, currency string) int64 {
+	if zeroDecimal[strings.ToUpper(currency)] {
+		return (cents + 50) / 100
+	}
+	return cents
+}
+
+// fromStripeAmount is an amount Stripe reports in a currency, in cents
+func fromStripeAmount(amount int64, currency string) int64 {
+	if zeroDecimal[strings.ToUpper(currency)] {
+		return amount * 100
+	}
+	return amount
 }
 
 func (h *Handler) handleChargeSucceeded(event stripe.Event) {
@@ -81,7 +238,7 @@ func (h *Handler) handleChargeSucceeded(event stripe.Event) {
 	if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
 		return
 	}
-	
+
 	// Try to find project by payment intent in metadata
 	if charge.PaymentIntent != nil {
 		// Look up project
@@ -89,28 +246,165 @@ func (h *Handler) handleChargeSucceeded(event stripe.Event) {
 	}
 }
 
-func (h *Handler) handleInvoicePaid(event stripe.Event) {
+func (h *Handler) handleInvoicePaid(event stripe.Event) error {
 	var invoice stripe.Invoice
 	if err := json.Unmarshal(event.Data.Raw, &invoice); err != nil {
-		return
+		return nil
+	}
+
+	// A retainer's cycle is recorded against the retainer, not metadata
+	if sub := invoiceSubscription(&invoice); sub != "" {
+		ret, err := h.DB.GetRetainerBySubscription(sub)
+		if err != nil {
+			return fmt.Errorf("retainer lookup: %w", err)
+		}
+		if ret != nil {
+			return h.retainerPayment(ret, &invoice)
+		}
 	}
-	
+
 	projectID := invoice.Metadata["project_id"]
 	if projectID == "" {
-		return
+		return nil
 	}
 
 	// Find and update project
 	// For now, log it
-	log.Printf("[STRIPE] Invoice paid for project %s: %.2f", 
+	log.Printf("[STRIPE] Invoice paid for project %s: %.2f",
 		projectID, float64(invoice.AmountPaid)/100)
+	return h.publishPayment(projectID, invoice.AmountPaid, invoice.Currency, invoice.CustomerEmail)
+}
+
+// stripeClient is a Stripe client with the secret key, against
+// Config.StripeAPIURL when it's set
+func (h *Handler) stripeClient() *stripe.Client {
+	if h.Config.StripeAPIURL == "" {
+		return stripe.NewClient(h.Config.StripeKey)
+	}
+	backends := stripe.NewBackendsWithConfig(&stripe.BackendConfig{URL: stripe.String(h.Config.StripeAPIURL)})
+	return stripe.NewClient(h.Config.StripeKey, stripe.WithBackends(backends))
+}
+
+// checkoutCurrency is the currency amounts are kept in (shown as "kr")
+const checkoutCurrency = "sek"
+
+// projectCurrency is the currency a project is charged in through Stripe:
+// the one it was quoted in
+func projectCurrency(p *models.Project) string {
+	if p.Foreign() {
+		return strings.ToLower(p.Currency)
+	}
+	return checkoutCurrency
+}
+
+// checkoutParams builds a Checkout session for a project's amount due, in
+// the currency it was quoted in, tagged like payment links so the webhook
+// finds the project, and carrying the client's branding when it is set up
+func checkoutParams(p *models.Project, c *models.Client, portalURL string) *stripe.CheckoutSessionCreateParams {
+	projectID := strconv.FormatInt(p.ID, 10)
+	name := p.Client
+	if p.Description != "" {
+		name += " — " + p.Description
+	}
+
+	currency := projectCurrency(p)
+	params := &stripe.CheckoutSessionCreateParams{
+		Mode:              stripe.String(string(stripe.CheckoutSessionModePayment)),
+		ClientReferenceID: stripe.String("project-" + projectID),
+		SuccessURL:        stripe.String(portalURL + "?paid=1"),
+		CancelURL:         stripe.String(portalURL),
+		Metadata:          map[string]string{"project_id": projectID},
+		LineItems: []*stripe.CheckoutSessionCreateLineItemParams{{
+			Quantity: stripe.Int64(1),
+			PriceData: &stripe.CheckoutSessionCreateLineItemPriceDataParams{
+				Currency:    stripe.String(currency),
+				UnitAmount:  stripe.Int64(stripeAmount(p.QuotedGrossCents(), currency)),
+				ProductData: &stripe.CheckoutSessionCreateLineItemPriceDataProductDataParams{Name: stripe.String(name)},
+			},
+		}},
+		PaymentIntentData: &stripe.CheckoutSessionCreatePaymentIntentDataParams{
+			Description: stripe.String(name),
+			Metadata:    map[string]string{"project_id": projectID},
+		},
+	}
+	if c == nil {
+		return params
+	}
+
+	if c.StatementDescriptor != "" {
+		params.PaymentIntentData.StatementDescriptorSuffix = stripe.String(c.StatementDescriptor)
+	}
+	if c.LogoURL != "" {
+		params.BrandingSettings = &stripe.CheckoutSessionCreateBrandingSettingsParams{
+			Logo: &stripe.CheckoutSessionCreateBrandingSettingsLogoParams{Type: stripe.String("url"), URL: stripe.String(c.LogoURL)},
+		}
+		params.LineItems[0].PriceData.ProductData.Images = []*string{stripe.String(c.LogoURL)}
+	}
+	if c.ThankYou != "" {
+		params.CustomText = &stripe.CheckoutSessionCreateCustomTextParams{
+			AfterSubmit: &stripe.CheckoutSessionCreateCustomTextAfterSubmitParams{Message: stripe.String(c.ThankYou)},
+		}
+	}
+	return params
+}
+
+// milestoneLinkParams builds a single-use payment link for a milestone's
+// amount with the project's VAT, tagged with the project and milestone so
+// the webhook records the payment on the milestone. Like Checkout it
+// charges the project's currency, the amount converted at the project's
+// rate.
+func milestoneLinkParams(p *models.Project, m *models.Milestone, c *models.Client) *stripe.PaymentLinkCreateParams {
+	metadata := map[string]string{
+		"project_id":   strconv.FormatInt(p.ID, 10),
+		"milestone_id": strconv.FormatInt(m.ID, 10),
+	}
+	name := p.Client + " — " + m.Title
+	currency := projectCurrency(p)
+	amount := p.InQuoted(m.AmountCents)
+	amount += models.VATOn(amount, p.VATPercent)
+
+	params := &stripe.PaymentLinkCreateParams{
+		Metadata: metadata,
+		LineItems: []*stripe.PaymentLinkCreateLineItemParams{{
+			Quantity: stripe.Int64(1),
+			PriceData: &stripe.PaymentLinkCreateLineItemPriceDataParams{
+				Currency:    stripe.String(currency),
+				UnitAmount:  stripe.Int64(stripeAmount(amount, currency)),
+				ProductData: &stripe.PaymentLinkCreateLineItemPriceDataProductDataParams{Name: stripe.String(name)},
+			},
+		}},
+		PaymentIntentData: &stripe.PaymentLinkCreatePaymentIntentDataParams{
+			Description: stripe.String(name),
+			Metadata:    metadata,
+		},
+		Restrictions: &stripe.PaymentLinkCreateRestrictionsParams{
+			CompletedSessions: &stripe.PaymentLinkCreateRestrictionsCompletedSessionsParams{Limit: stripe.Int64(1)},
+		},
+	}
+	if c == nil {
+		return params
+	}
+
+	if c.StatementDescriptor != "" {
+		params.PaymentIntentData.StatementDescriptorSuffix = stripe.String(c.StatementDescriptor)
+	}
+	if c.LogoURL != "" {
+		params.LineItems[0].PriceData.ProductData.Images = []*string{stripe.String(c.LogoURL)}
+	}
+	if c.ThankYou != "" {
+		params.AfterCompletion = &stripe.PaymentLinkCreateAfterCompletionParams{
+			Type:               stripe.String("hosted_confirmation"),
+			HostedConfirmation: &stripe.PaymentLinkCreateAfterCompletionHostedConfirmationParams{CustomMessage: stripe.String(c.ThankYou)},
+		}
+	}
+	return params
 }
 
 // CreatePaymentLink placeholder for future Stripe integration
 func (h *Handler) CreatePaymentLink(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
-		"note": "Stripe payment links not yet implemented",
+		"note":   "Stripe payment links not yet implemented",
 		"action": "Use Stripe Dashboard to create payment links",
 	})
 }
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/store"
+	"github.com/stripe/stripe-go/v84"
+	"github.com/stripe/stripe-go/v84/webhook"
+)
+
+const testWebhookSecret = "whsec_test"
+
+// stripeEvent is the body of a Stripe event; data.object is raw JSON
+func stripeEvent(id, typ, object string) []byte {
+	return fmt.Appendf(nil, `{"id":%q,"object":"event","type":%q,"api_version":%q,"data":{"object":%s}}`,
+		id, typ, stripe.APIVersion, object)
+}
+
+// postWebhook delivers body to the Stripe webhook, signed when secret isn't ""
+func postWebhook(h *Handler, body []byte, secret string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/webhook", bytes.NewReader(body))
+	if secret != "" {
+		signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Payload: body, Secret: secret})
+		r.Header.Set("Stripe-Signature", signed.Header)
+	}
+	w := httptest.NewRecorder()
+	h.StripeWebhook(w, r)
+	return w
+}
+
+func TestStripeWebhookSignature(t *testing.T) {
+	db, err := store.New(filepath.Join(t.TempDir(), "fulldash.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, tt := range []struct {
+		name     string
+		config   Config
+		secret   string // signs the event
+		wantCode int
+	}{
+		{"signed", Config{StripeWebhookSecret: testWebhookSecret}, testWebhookSecret, 200},
+		{"wrong secret", Config{StripeWebhookSecret: testWebhookSecret}, "whsec_other", 400},
+		{"unsigned", Config{StripeWebhookSecret: testWebhookSecret}, "", 400},
+		{"no secret set", Config{}, "", 503},
+		{"no secret set, signed anyway", Config{}, testWebhookSecret, 503},
+		{"insecure", Config{StripeWebhookInsecure: true}, "", 200},
+		{"insecure with a secret", Config{StripeWebhookSecret: testWebhookSecret, StripeWebhookInsecure: true}, "", 400},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{DB: db, Config: tt.config}
+			body := stripeEvent("evt_"+tt.name, "customer.created", `{"id":"cus_1"}`)
+			if w := postWebhook(h, body, tt.secret); w.Code != tt.wantCode {
+				t.Errorf("status %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+		})
+	}
+}
+
+// TestStripeWebhookRetry checks that an event that fails isn't recorded as
+// seen, so Stripe's retry processes it, and that it's then processed once
+func TestStripeWebhookRetry(t *testing.T) {
+	db, err := store.New(filepath.Join(t.TempDir(), "fulldash.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	p := &models.Project{Client: "Acme", RevenueCents: 100000, Status: models.StatusDone, SecuredBy: []int64{1}}
+	if err := db.CreateProject(p); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{DB: db, Events: events.New(), Config: Config{StripeWebhookSecret: testWebhookSecret}}
+	// Paid in euros, which have no exchange rate yet
+	body := stripeEvent("evt_retry", "payment_intent.succeeded",
+		fmt.Sprintf(`{"id":"pi_1","amount_received":5000,"currency":"eur","metadata":{"project_id":"%d"}}`, p.ID))
+
+	payments := func() int {
+		t.Helper()
+		history, err := db.ListProjectActivity(p.ID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, a := range history {
+			if a.Action == "payment" {
+				n++
+			}
+		}
+		return n
+	}
+
+	if w := postWebhook(h, body, testWebhookSecret); w.Code != 503 {
+		t.Fatalf("payment without a rate: status %d, want 503 so Stripe retries", w.Code)
+	}
+	if n := payments(); n != 0 {
+		t.Fatalf("%d payments logged by a failed event", n)
+	}
+
+	if err := db.SetExchangeRate(&models.ExchangeRate{Currency: "EUR", Rate: 11.5}); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if w := postWebhook(h, body, testWebhookSecret); w.Code != 200 {
+			t.Fatalf("retry: status %d: %s", w.Code, w.Body)
+		}
+	}
+	if n := payments(); n != 1 {
+		t.Errorf("%d payments logged after a retry and a redelivery, want 1", n)
+	}
+}
+
+// TestMilestoneLinkParams checks that a milestone's link charges the
+// project's currency, as Checkout does, with the amount converted at the
+// project's rate
+func TestMilestoneLinkParams(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		project      models.Project
+		milestone    int64 // kronor öre, excluding VAT
+		wantCurrency string
+		wantAmount   int64 // as Stripe takes it
+	}{
+		{"kronor", models.Project{RevenueCents: 100000, VATPercent: 25}, 40000, "sek", 50000},
+		{"euros", models.Project{RevenueCents: 115000, Currency: "EUR", OriginalCents: 10000, VATPercent: 25}, 46000, "eur", 5000},
+		{"whole project in dollars", models.Project{RevenueCents: 104000, Currency: "USD", OriginalCents: 10000}, 104000, "usd", 10000},
+		{"yen", models.Project{RevenueCents: 100000, Currency: "JPY", OriginalCents: 1500000, VATPercent: 25}, 50000, "jpy", 9375},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.project
+			p.ID, p.Client = 7, "Acme"
+			m := &models.Milestone{ID: 3, ProjectID: p.ID, Title: "Design", AmountCents: tt.milestone}
+			price := milestoneLinkParams(&p, m, nil).LineItems[0].PriceData
+			if got := *price.Currency; got != tt.wantCurrency {
+				t.Errorf("currency %q, want %q", got, tt.wantCurrency)
+			}
+			if got := *price.UnitAmount; got != tt.wantAmount {
+				t.Errorf("amount %d, want %d", got, tt.wantAmount)
+			}
+			if checkout := *checkoutParams(&p, nil, "").LineItems[0].PriceData.Currency; checkout != *price.Currency {
+				t.Errorf("link in %q, Checkout in %q", *price.Currency, checkout)
+			}
+		})
+	}
+}
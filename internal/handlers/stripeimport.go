@@ -0,0 +1,300 @@
+// handlers/stripeimport.go - Backfilling Stripe payments made before the webhook was set up
+package handlers
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/service"
+	"github.com/noor-latif/fulldash/internal/templates"
+	"github.com/stripe/stripe-go/v84"
+)
+
+// stripeImportActor is who imported payments are logged as, apart from
+// the webhook's ("stripe")
+const stripeImportActor = "stripe import"
+
+// ImportStripeHistory pages through the paid Stripe Checkout sessions
+// (payment links make them too) created before ?before= (a date; by default
+// when the webhook first logged a payment) and records those FullDash has
+// no record of; dry_run counts without saving. secured_by is who secures
+// the projects created for payments that name none.
+func (h *Handler) ImportStripeHistory(w http.ResponseWriter, r *http.Request) {
+	if h.Config.StripeKey == "" {
+		http.Error(w, "Set STRIPE_SECRET_KEY to import from Stripe", http.StatusBadRequest)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	res := models.StripeImport{Before: time.Now(), DryRun: r.FormValue("dry_run") != ""}
+	if v := r.FormValue("before"); v != "" {
+		t, err := time.Parse(dateLayout, v)
+		if err != nil {
+			http.Error(w, "Invalid date", http.StatusBadRequest)
+			return
+		}
+		res.Before = t
+	} else if first, err := h.DB.FirstStripePayment(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if first != nil {
+		res.Before = *first
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	person, _ := strconv.ParseInt(r.FormValue("secured_by"), 10, 64)
+	if !people.Valid([]int64{person}) {
+		http.Error(w, "Choose who secured the payments that name no project", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.importStripeHistory(r, person, &res); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !res.DryRun {
+		triggerToast(w, fmt.Sprintf("Imported %d Stripe payments", res.Payments+res.Milestones+res.Created))
+	}
+	templates.StripeImportResult(res).Render(r.Context(), w)
+}
+
+// importStripeHistory records each paid session in res's range whose
+// payment isn't on a project or milestone yet, counting in res. Old
+// payments publish no events, so no receipts or notifications go out.
+func (h *Handler) importStripeHistory(r *http.Request, person int64, res *models.StripeImport) error {
+	recorded, err := h.DB.StripePaymentIDs()
+	if err != nil {
+		return err
+	}
+
+	params := &stripe.CheckoutSessionListParams{
+		Status:       stripe.String(string(stripe.CheckoutSessionStatusComplete)),
+		CreatedRange: &stripe.RangeQueryParams{LesserThan: res.Before.Unix()},
+	}
+	params.Limit = stripe.Int64(100)
+	for s, err := range h.stripeClient().V1CheckoutSessions.List(r.Context(), params) {
+		if err != nil {
+			return err
+		}
+		// subscriptions are retainers, which invoice.paid records
+		if s.Mode != stripe.CheckoutSessionModePayment || s.PaymentStatus != stripe.CheckoutSessionPaymentStatusPaid {
+			continue
+		}
+		res.Sessions++
+		paymentID := s.ID
+		if s.PaymentIntent != nil && s.PaymentIntent.ID != "" {
+			paymentID = s.PaymentIntent.ID
+		}
+		if recorded[paymentID] {
+			res.Duplicates++
+			continue
+		}
+		recorded[paymentID] = true
+
+		if err := h.importStripeSession(r, s, paymentID, person, res); err != nil {
+			res.Skipped = append(res.Skipped, fmt.Sprintf("%s (%s): %v", paymentID, time.Unix(s.Created, 0).Format(dateLayout), err))
+		}
+	}
+	return nil
+}
+
+// importStripeSession records one paid session: on the milestone or
+// project its metadata names, as the webhook would have, or else as a new
+// paid project. Projects are marked paid on the day of the session, which
+// mustn't be in a locked month.
+func (h *Handler) importStripeSession(r *http.Request, s *stripe.CheckoutSession, paymentID string, person int64, res *models.StripeImport) error {
+	paidAt := time.Unix(s.Created, 0)
+
+	if v := s.Metadata["milestone_id"]; v != "" {
+		id, _ := strconv.ParseInt(v, 10, 64)
+		m, err := h.DB.GetMilestone(id)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			return fmt.Errorf("milestone %s no longer exists", v)
+		}
+		p, err := h.DB.GetProject(m.ProjectID)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			return fmt.Errorf("project %d no longer exists", m.ProjectID)
+		}
+		pay, err := h.basePayment(p, s.AmountTotal, s.Currency)
+		if err != nil {
+			return err
+		}
+		if !res.DryRun {
+			m.Status, m.StripePaymentID = models.MilestonePaid, paymentID
+			if m.PaidAt == nil {
+				m.PaidAt = &paidAt
+			}
+			if err := h.DB.UpdateMilestone(m); err != nil {
+				return err
+			}
+			if err := h.logImportedPayment(p, pay.Summary()+" for "+m.Title); err != nil {
+				return err
+			}
+		}
+		res.Milestones++
+		return nil
+	}
+
+	if v := s.Metadata["project_id"]; v != "" {
+		id, _ := strconv.ParseInt(v, 10, 64)
+		p, err := h.DB.GetProject(id)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			return fmt.Errorf("project %s no longer exists", v)
+		}
+		pay, err := h.basePayment(p, s.AmountTotal, s.Currency)
+		if err != nil {
+			return err
+		}
+		// a project paid by hand keeps its date; one paid otherwise already
+		// has another payment and only gets this one logged
+		marking := p.StripePaymentID == "" && p.Status != models.StatusPaid
+		if marking {
+			if err := h.unlockedPayment(p, paidAt); err != nil {
+				return err
+			}
+		}
+		if !res.DryRun {
+			if err := h.recordSessionPayment(r, p, paymentID, paidAt, marking); err != nil {
+				return err
+			}
+			if err := h.logImportedPayment(p, pay.Summary()); err != nil {
+				return err
+			}
+		}
+		res.Payments++
+		return nil
+	}
+
+	p, err := h.stripeSessionProject(s, paymentID, person)
+	if err != nil {
+		return err
+	}
+	if err := h.unlockedPayment(p, paidAt); err != nil {
+		return err
+	}
+	if !res.DryRun {
+		if err := h.createProject(p); err != nil {
+			return err
+		}
+		if err := h.DB.SetPaidAt(p.ID, paidAt); err != nil {
+			return err
+		}
+		created := service.Snapshot(p, nil)
+		if err := h.logActivity(r, p, "created", nil, &created, "imported from Stripe"); err != nil {
+			return err
+		}
+		pay, err := h.basePayment(p, s.AmountTotal, s.Currency)
+		if err != nil {
+			return err
+		}
+		if err := h.logImportedPayment(p, pay.Summary()); err != nil {
+			return err
+		}
+	}
+	res.Created++
+	return nil
+}
+
+// recordSessionPayment puts a session's payment on the project it paid
+// for; with marking, the project moves to paid as of paidAt
+func (h *Handler) recordSessionPayment(r *http.Request, p *models.Project, paymentID string, paidAt time.Time, marking bool) error {
+	if p.StripePaymentID != "" {
+		return nil
+	}
+	before := service.Snapshot(p, nil)
+	if err := h.DB.UpdateProjectStatus(p.ID, models.StatusPaid, p.RevenueCents, paymentID); err != nil {
+		return err
+	}
+	if !marking {
+		return nil
+	}
+	if err := h.DB.SetPaidAt(p.ID, paidAt); err != nil {
+		return err
+	}
+	paid, err := h.DB.GetProject(p.ID)
+	if err != nil {
+		return err
+	}
+	after := service.Snapshot(paid, nil)
+	return h.logActivity(r, paid, "updated", &before, &after, "paid through Stripe on "+paidAt.Format(dateLayout))
+}
+
+// unlockedPayment refuses to mark p paid at t when t's month is locked
+func (h *Handler) unlockedPayment(p *models.Project, t time.Time) error {
+	lock, err := h.DB.PeriodLockAt(t)
+	if err != nil || lock == nil {
+		return err
+	}
+	return &models.PeriodLockedError{Lock: *lock, Client: p.Client}
+}
+
+// stripeSessionProject builds the paid project for a session that names
+// none, for the customer Stripe has: revenue is the amount less the tax
+// Stripe added, as for a retainer's invoice
+func (h *Handler) stripeSessionProject(s *stripe.CheckoutSession, paymentID string, person int64) (*models.Project, error) {
+	client := "Stripe customer"
+	if d := s.CustomerDetails; d != nil {
+		client = cmp.Or(d.BusinessName, d.Name, d.Email, client)
+	}
+	var tax int64
+	if s.TotalDetails != nil {
+		tax = s.TotalDetails.AmountTax
+	}
+	net := fromStripeAmount(s.AmountTotal-tax, string(s.Currency))
+	p := &models.Project{
+		Client:          client,
+		Description:     "Stripe payment " + time.Unix(s.Created, 0).Format(dateLayout),
+		RevenueCents:    net,
+		Status:          models.StatusPaid,
+		SecuredBy:       []int64{person},
+		StripePaymentID: paymentID,
+		PaymentDays:     models.DefaultPaymentDays,
+	}
+	if tax > 0 && s.AmountTotal > tax {
+		p.VATPercent = math.Round(float64(tax)*10000/float64(s.AmountTotal-tax)) / 100
+	}
+
+	if currency := strings.ToUpper(string(s.Currency)); currency != "" && currency != models.BaseCurrency {
+		revenue, err := h.foreignRevenue(currency, net, nil)
+		if err != nil {
+			return nil, err
+		}
+		p.Currency, p.OriginalCents, p.RevenueCents = currency, net, revenue
+	}
+	return p, nil
+}
+
+// logImportedPayment logs an imported payment in the project's audit log,
+// where reconciliation counts it like the webhook's
+func (h *Handler) logImportedPayment(p *models.Project, summary string) error {
+	return h.DB.LogActivity(&models.Activity{
+		ProjectID: p.ID,
+		Client:    p.Client,
+		Action:    "payment",
+		Summary:   summary,
+		Actor:     stripeImportActor,
+		Source:    events.SourceStripe,
+	})
+}
@@ -0,0 +1,202 @@
+// handlers/tasks.go - Project subtasks, ticked off from the board or the project form
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// CreateTask adds a task to the end of a project's list
+func (h *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	p, err := h.DB.GetProject(projectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	t := &models.Task{ProjectID: p.ID}
+	if !h.parseTask(w, r, t) {
+		return
+	}
+	if err := h.DB.CreateTask(t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderTasks(w, r, p.ID)
+}
+
+// ToggleTask ticks or unticks a task. Ticked from a board card it re-renders
+// just that card's tasks; from the project form, the form's list and the card.
+func (h *Handler) ToggleTask(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.taskFromURL(w, r)
+	if !ok {
+		return
+	}
+	if err := h.DB.SetTaskDone(t.ID, !t.Done); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if r.FormValue("card") == "" {
+		h.renderTasks(w, r, t.ProjectID)
+		return
+	}
+	tasks, err := h.DB.ListTasks(t.ProjectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.CardTasks(t.ProjectID, tasks, people, true, false).Render(r.Context(), w)
+}
+
+// EditTask renders a task as an inline edit form
+func (h *Handler) EditTask(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.taskFromURL(w, r)
+	if !ok {
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.TaskEditRow(t, people).Render(r.Context(), w)
+}
+
+// UpdateTask saves a task's title and assignee
+func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.taskFromURL(w, r)
+	if !ok {
+		return
+	}
+	if !h.parseTask(w, r, t) {
+		return
+	}
+	if err := h.DB.UpdateTask(t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderTasks(w, r, t.ProjectID)
+}
+
+// MoveTask moves a task one place up or down its project's list
+func (h *Handler) MoveTask(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.taskFromURL(w, r)
+	if !ok {
+		return
+	}
+	dir := 1
+	switch r.FormValue("dir") {
+	case "up":
+		dir = -1
+	case "down":
+	default:
+		http.Error(w, "Move a task up or down", http.StatusBadRequest)
+		return
+	}
+	if err := h.DB.MoveTask(t, dir); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderTasks(w, r, t.ProjectID)
+}
+
+// DeleteTask removes a task
+func (h *Handler) DeleteTask(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.taskFromURL(w, r)
+	if !ok {
+		return
+	}
+	if err := h.DB.DeleteTask(t.ID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderTasks(w, r, t.ProjectID)
+}
+
+// parseTask fills t from the task form, writing a 400 itself if the form is
+// invalid. Tasks needn't be assigned, but only to someone on the team.
+func (h *Handler) parseTask(w http.ResponseWriter, r *http.Request, t *models.Task) bool {
+	title := strings.TrimSpace(r.FormValue("title"))
+	if title == "" {
+		http.Error(w, "Give the task a title", http.StatusBadRequest)
+		return false
+	}
+	var assignee int64
+	if v := r.FormValue("assignee"); v != "" {
+		people, err := h.DB.ListPeople()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return false
+		}
+		assignee, _ = strconv.ParseInt(v, 10, 64)
+		if _, ok := people.Get(assignee); !ok {
+			http.Error(w, "Unknown person", http.StatusBadRequest)
+			return false
+		}
+	}
+
+	t.Title = title
+	t.AssigneeID = assignee
+	return true
+}
+
+// taskFromURL loads the task named by {id}, writing errors itself
+func (h *Handler) taskFromURL(w http.ResponseWriter, r *http.Request) (*models.Task, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	t, err := h.DB.GetTask(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if t == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return nil, false
+	}
+	return t, true
+}
+
+// renderTasks re-renders a project's task list in the project form, and its
+// card on the board out of band
+func (h *Handler) renderTasks(w http.ResponseWriter, r *http.Request, projectID int64) {
+	tasks, err := h.DB.ListTasks(projectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.TaskList(projectID, tasks, people).Render(r.Context(), w)
+	templates.CardTasks(projectID, tasks, people, false, true).Render(r.Context(), w)
+}
@@ -0,0 +1,170 @@
+// handlers/timeentries.go - Dated time entries logged on a project
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// maxEntryHours caps a single entry: one person's day
+const maxEntryHours = 24
+
+// CreateTimeEntry logs hours on a project from the project form
+func (h *Handler) CreateTimeEntry(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	p, err := h.DB.GetProject(projectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	e := &models.TimeEntry{ProjectID: p.ID}
+	if !h.parseTimeEntry(w, r, e) {
+		return
+	}
+	if err := h.DB.CreateTimeEntry(e); err != nil {
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	h.invalidateMetrics()
+	h.checkBudget(r, p.ID, events.SourceUser)
+
+	h.renderTimeEntries(w, r, p.ID)
+}
+
+// EditTimeEntry renders an entry as an inline edit form
+func (h *Handler) EditTimeEntry(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.timeEntryFromURL(w, r)
+	if !ok {
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.TimeEntryEditRow(e, people).Render(r.Context(), w)
+}
+
+// UpdateTimeEntry saves an edited entry
+func (h *Handler) UpdateTimeEntry(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.timeEntryFromURL(w, r)
+	if !ok {
+		return
+	}
+	if !h.parseTimeEntry(w, r, e) {
+		return
+	}
+	if err := h.DB.UpdateTimeEntry(e); err != nil {
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	h.invalidateMetrics()
+	h.checkBudget(r, e.ProjectID, events.SourceUser)
+
+	h.renderTimeEntries(w, r, e.ProjectID)
+}
+
+// DeleteTimeEntry removes an entry
+func (h *Handler) DeleteTimeEntry(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.timeEntryFromURL(w, r)
+	if !ok {
+		return
+	}
+	if err := h.DB.DeleteTimeEntry(e.ID); err != nil {
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	h.invalidateMetrics()
+	h.checkBudget(r, e.ProjectID, events.SourceUser)
+
+	h.renderTimeEntries(w, r, e.ProjectID)
+}
+
+// parseTimeEntry fills e from the entry form (the date defaults to today),
+// writing a 400 itself if the form is invalid
+func (h *Handler) parseTimeEntry(w http.ResponseWriter, r *http.Request, e *models.TimeEntry) bool {
+	hours, err := strconv.ParseFloat(r.FormValue("hours"), 64)
+	if err != nil || hours <= 0 || hours > maxEntryHours {
+		http.Error(w, "Hours must be more than 0 and at most 24", http.StatusBadRequest)
+		return false
+	}
+	date := time.Now()
+	if s := r.FormValue("worked_on"); s != "" {
+		if date, err = time.Parse(dateLayout, s); err != nil {
+			http.Error(w, "Invalid date", http.StatusBadRequest)
+			return false
+		}
+	}
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	e.PersonID, _ = strconv.ParseInt(r.FormValue("person"), 10, 64)
+	if _, ok := people.Get(e.PersonID); !ok {
+		http.Error(w, "Choose who worked", http.StatusBadRequest)
+		return false
+	}
+	e.Date = date
+	e.Hours = hours
+	e.Notes = strings.TrimSpace(r.FormValue("notes"))
+	return true
+}
+
+// timeEntryFromURL loads the entry named by {id}, writing errors itself
+func (h *Handler) timeEntryFromURL(w http.ResponseWriter, r *http.Request) (*models.TimeEntry, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	e, err := h.DB.GetTimeEntry(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if e == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return nil, false
+	}
+	return e, true
+}
+
+// renderTimeEntries re-renders a project's time entries
+func (h *Handler) renderTimeEntries(w http.ResponseWriter, r *http.Request, projectID int64) {
+	entries, err := h.DB.ListTimeEntries(models.TimeEntryFilter{ProjectID: projectID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.TimeEntryList(projectID, entries, people).Render(r.Context(), w)
+}
@@ -0,0 +1,156 @@
+// handlers/timesheet.go - Weekly timesheet: a person × day grid of logged hours
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// TimesheetPage shows a week's hours per person, project and day, with each
+// day's hours editable (see SetTimesheetHours)
+func (h *Handler) TimesheetPage(w http.ResponseWriter, r *http.Request) {
+	ts, personID, msg, err := h.timesheet(r.URL.Query())
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, projects, err := h.timesheetChoices()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Layout("FullDash Timesheet", templates.TimesheetPage(ts, personID, people, projects)).Render(r.Context(), w)
+}
+
+// SetTimesheetHours sets one person's hours on a project for a day, replacing
+// that day's entries, and re-renders the week's grid
+func (h *Handler) SetTimesheetHours(w http.ResponseWriter, r *http.Request) {
+	projectID, _ := strconv.ParseInt(r.FormValue("project"), 10, 64)
+	p, err := h.DB.GetProject(projectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "Choose a project", http.StatusBadRequest)
+		return
+	}
+	day, err := time.Parse(dateLayout, r.FormValue("date"))
+	if err != nil {
+		http.Error(w, "Invalid date", http.StatusBadRequest)
+		return
+	}
+	hours := 0.0
+	if s := r.FormValue("hours"); s != "" {
+		hours, err = strconv.ParseFloat(s, 64)
+		if err != nil || hours < 0 || hours > maxEntryHours {
+			http.Error(w, "Hours must be between 0 and 24", http.StatusBadRequest)
+			return
+		}
+	}
+
+	people, projects, err := h.timesheetChoices()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	personID, _ := strconv.ParseInt(r.FormValue("person"), 10, 64)
+	if _, ok := people.Get(personID); !ok {
+		http.Error(w, "Choose who worked", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.SetDayHours(p.ID, personID, day, hours); err != nil {
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	h.invalidateMetrics()
+	h.checkBudget(r, p.ID, events.SourceUser)
+
+	// Stay on the week (and person) the grid was showing
+	ts, filter, msg, err := h.timesheet(r.Form)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.TimesheetGrid(ts, filter, people, projects).Render(r.Context(), w)
+}
+
+// APITimesheet returns the grid TimesheetPage shows
+func (h *Handler) APITimesheet(w http.ResponseWriter, r *http.Request) {
+	ts, _, msg, err := h.timesheet(r.URL.Query())
+	if msg != "" {
+		writeJSONError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if ts.Rows == nil {
+		ts.Rows = []models.TimesheetRow{}
+	}
+	if ts.Projects == nil {
+		ts.Projects = []models.TimesheetProject{}
+	}
+	writeJSON(w, http.StatusOK, ts)
+}
+
+// timesheet lays out the week the query asks for: week is any date in it
+// (default this week) and person_id narrows it to one person. A non-empty
+// message means the query was invalid.
+func (h *Handler) timesheet(q url.Values) (models.Timesheet, int64, string, error) {
+	day := time.Now()
+	if s := q.Get("week"); s != "" {
+		t, err := time.Parse(dateLayout, s)
+		if err != nil {
+			return models.Timesheet{}, 0, "week must be YYYY-MM-DD", nil
+		}
+		day = t
+	}
+	var personID int64
+	if s := q.Get("person_id"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return models.Timesheet{}, 0, "person_id must be a person's ID", nil
+		}
+		personID = id
+	}
+
+	week := models.WeekStart(day)
+	entries, err := h.DB.ListTimeEntries(models.TimeEntryFilter{PersonID: personID, From: week, To: week.AddDate(0, 0, 6)})
+	if err != nil {
+		return models.Timesheet{}, 0, "", err
+	}
+	return models.NewTimesheet(week, entries), personID, "", nil
+}
+
+// timesheetChoices loads the people and projects the grid's add-a-row form
+// offers
+func (h *Handler) timesheetChoices() (models.People, []models.Project, error) {
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		return nil, nil, err
+	}
+	projects, err := h.DB.ListProjects(models.ProjectFilter{})
+	if err != nil {
+		return nil, nil, err
+	}
+	return people, projects, nil
+}
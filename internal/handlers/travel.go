@@ -0,0 +1,185 @@
+// handlers/travel.go - Mileage/travel log for client visits
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// TravelPage renders the quick trip form and the year's log with per-owner totals
+func (h *Handler) TravelPage(w http.ResponseWriter, r *http.Request) {
+	year, err := strconv.Atoi(r.URL.Query().Get("year"))
+	if err != nil {
+		year = time.Now().Year()
+	}
+
+	view, err := h.travelView(year)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	projects, err := h.DB.ListProjects(models.ProjectFilter{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rate, err := h.mileageRate()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	templates.Layout("FullDash Travel", templates.TravelPage(view, projects, rate)).Render(r.Context(), w)
+}
+
+// CreateTravelEntry logs a trip at the current per-km rate
+func (h *Handler) CreateTravelEntry(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	km, err := strconv.ParseFloat(strings.Replace(r.FormValue("km"), ",", ".", 1), 64)
+	if err != nil || km <= 0 {
+		http.Error(w, "Distance must be a positive number", http.StatusBadRequest)
+		return
+	}
+
+	date, err := time.Parse(dateLayout, r.FormValue("date"))
+	if err != nil {
+		date = time.Now()
+	}
+
+	rate, err := h.mileageRate()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	driver, _ := strconv.ParseInt(r.FormValue("person_id"), 10, 64)
+	t := &models.TravelEntry{
+		PersonID:  driver,
+		Date:      date,
+		Client:    strings.TrimSpace(r.FormValue("client")),
+		Km:        km,
+		Purpose:   strings.TrimSpace(r.FormValue("purpose")),
+		RateCents: rate,
+	}
+	if _, ok := people.Get(t.PersonID); !ok {
+		http.Error(w, "Driver is required", http.StatusBadRequest)
+		return
+	}
+
+	// A linked project supplies the client name and receives the reimbursement
+	if id, err := strconv.ParseInt(r.FormValue("project_id"), 10, 64); err == nil && id > 0 {
+		p, err := h.DB.GetProject(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if p != nil {
+			t.ProjectID = p.ID
+			if t.Client == "" {
+				t.Client = p.Client
+			}
+		}
+	}
+	if t.Client == "" {
+		http.Error(w, "Client or project is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.CreateTravelEntry(t); err != nil {
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	h.invalidateMetrics()
+
+	triggerToast(w, "Logged "+strconv.FormatFloat(t.Km, 'f', -1, 64)+" km to "+t.Client)
+	h.renderTravelLog(w, r, t.Date.Year())
+}
+
+// DeleteTravelEntry removes a trip (and its reimbursement expense)
+func (h *Handler) DeleteTravelEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	t, err := h.DB.GetTravelEntry(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if t == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.DB.DeleteTravelEntry(id); err != nil {
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	h.invalidateMetrics()
+
+	h.renderTravelLog(w, r, t.Date.Year())
+}
+
+// travelView loads a year's trips and totals
+func (h *Handler) travelView(year int) (templates.TravelView, error) {
+	entries, err := h.DB.ListTravelEntries(year)
+	if err != nil {
+		return templates.TravelView{}, err
+	}
+	summary, err := h.DB.MileageSummary(year)
+	if err != nil {
+		return templates.TravelView{}, err
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		return templates.TravelView{}, err
+	}
+	return templates.TravelView{Year: year, People: people, Entries: entries, Summary: summary}, nil
+}
+
+// renderTravelLog re-renders the log section for a year
+func (h *Handler) renderTravelLog(w http.ResponseWriter, r *http.Request, year int) {
+	view, err := h.travelView(year)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.TravelLog(view).Render(r.Context(), w)
+}
+
+// mileageRate returns the configured per-km rate in cents
+func (h *Handler) mileageRate() (int64, error) {
+	v, err := h.DB.GetSetting(models.SettingMileageRate, "")
+	if err != nil || v == "" {
+		return models.DefaultMileageRateCents, err
+	}
+	rate, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return models.DefaultMileageRateCents, nil
+	}
+	return rate, nil
+}
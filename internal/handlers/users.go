@@ -0,0 +1,104 @@
+// handlers/users.go - User management (owners only)
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// CreateUser adds a user from the admin form
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	_, msg, err := h.newUserFromForm(r, models.Role(r.FormValue("role")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if msg != "" {
+		triggerToast(w, msg)
+	}
+	h.renderUsers(w, r)
+}
+
+// UpdateUserRole changes a user's role, keeping at least one owner
+func (h *Handler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.userFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	role := models.Role(r.FormValue("role"))
+	if !role.Valid() {
+		http.Error(w, "Invalid role", http.StatusBadRequest)
+		return
+	}
+
+	if u.Role == models.RoleOwner && role != models.RoleOwner && h.lastOwner() {
+		triggerToast(w, "There must be at least one owner")
+	} else if err := h.DB.UpdateUserRole(u.ID, role); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.renderUsers(w, r)
+}
+
+// DeleteUser removes a user, refusing to delete yourself or the last owner
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.userFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	switch {
+	case auth.UserFrom(r.Context()).ID == u.ID:
+		triggerToast(w, "You cannot delete yourself")
+	case u.Role == models.RoleOwner && h.lastOwner():
+		triggerToast(w, "There must be at least one owner")
+	default:
+		if err := h.DB.DeleteUser(u.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	h.renderUsers(w, r)
+}
+
+// lastOwner reports whether only one owner remains
+func (h *Handler) lastOwner() bool {
+	n, err := h.DB.CountOwners()
+	return err != nil || n <= 1
+}
+
+// userFromURL loads the user named by {id}, writing errors itself
+func (h *Handler) userFromURL(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	u, err := h.DB.GetUser(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if u == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return nil, false
+	}
+	return u, true
+}
+
+// renderUsers re-renders the admin user panel
+func (h *Handler) renderUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.DB.ListUsers()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.UserList(users).Render(r.Context(), w)
+}
@@ -0,0 +1,60 @@
+// handlers/vat.go - The VAT report: output VAT per quarter for the moms declaration
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// VATReportPage shows the sales and output VAT of each quarter of ?year=
+// (default this year) by rate; ?format=csv downloads the same figures
+func (h *Handler) VATReportPage(w http.ResponseWriter, r *http.Request) {
+	year := time.Now().Year()
+	if s := r.URL.Query().Get("year"); s != "" {
+		y, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+		year = y
+	}
+
+	quarters, err := h.DB.VATReport(year)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("format") == "csv" {
+		writeVATCSV(w, year, quarters)
+		return
+	}
+	templates.Layout("FullDash VAT", templates.VATReportPage(year, quarters)).Render(r.Context(), w)
+}
+
+// writeVATCSV streams a year's quarters as a CSV attachment, one row per
+// quarter and rate, amounts in kronor
+func writeVATCSV(w http.ResponseWriter, year int, quarters []models.VATQuarter) {
+	name := fmt.Sprintf("vat-%d.csv", year)
+	writeCSVHeaders(w, name)
+
+	cw := newCSVWriter(w, name)
+	cw.Write([]string{"year", "quarter", "vat_percent", "payments", "net_sales", "output_vat"})
+	for _, q := range quarters {
+		for _, rate := range q.Rates {
+			cw.Write([]string{
+				strconv.Itoa(q.Year),
+				strconv.Itoa(q.Quarter),
+				strconv.FormatFloat(rate.Percent, 'f', -1, 64),
+				strconv.Itoa(rate.Payments),
+				fmt.Sprintf("%.2f", float64(rate.NetCents)/100),
+				fmt.Sprintf("%.2f", float64(rate.VATCents)/100),
+			})
+		}
+	}
+	cw.Flush()
+}
@@ -2,127 +2,590 @@
 package handlers
 
 import (
+	"cmp"
+	"errors"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/automation"
+	"github.com/noor-latif/fulldash/internal/backup"
+	"github.com/noor-latif/fulldash/internal/cache"
+	"github.com/noor-latif/fulldash/internal/domain"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/fortnox"
+	"github.com/noor-latif/fulldash/internal/gcal"
+	"github.com/noor-latif/fulldash/internal/jobs"
+	"github.com/noor-latif/fulldash/internal/mail"
+	"github.com/noor-latif/fulldash/internal/maintenance"
 	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/notify"
+	"github.com/noor-latif/fulldash/internal/ratelimit"
+	"github.com/noor-latif/fulldash/internal/replication"
+	"github.com/noor-latif/fulldash/internal/sentry"
+	"github.com/noor-latif/fulldash/internal/service"
+	"github.com/noor-latif/fulldash/internal/store"
 	"github.com/noor-latif/fulldash/internal/templates"
 )
 
+// activityFeedSize is the number of entries shown in the dashboard feed
+const activityFeedSize = 10
+
+// boardSearchPageSize is how many matches a board search loads at a time
+const boardSearchPageSize = 50
+
 // Store defines the interface for data operations (enables mocking)
 type Store interface {
+	// Tx runs fn in one transaction (see store.DB.Tx)
+	Tx(fn func(tx *store.DB) error) error
 	CreateProject(p *models.Project) error
 	GetProject(id int64) (*models.Project, error)
+	GetProjectByStripeID(stripeID string) (*models.Project, error)
 	UpdateProject(p *models.Project) error
+	UpdateProjectStatus(id int64, status models.ProjectStatus, revenueCents int64, stripeID string) error
+	SetPaidAt(id int64, at time.Time) error
+	StripePaymentIDs() (map[string]bool, error)
 	DeleteProject(id int64) error
-	ListProjects(search string) ([]models.Project, error)
+	ListProjects(f models.ProjectFilter) ([]models.Project, error)
+	ProjectTotals(f models.ProjectFilter) (map[models.ProjectStatus]models.ArchiveTotals, error)
+	ListProjectsByStatus(status models.ProjectStatus) ([]models.Project, error)
+	SetProjectArchived(id int64, archived bool) error
+	SetBudgetWarned(id int64, percent int) error
+	ListArchivedProjects(f models.ArchiveFilter) ([]models.Project, error)
+	ArchiveTotals(f models.ArchiveFilter) (models.ArchiveTotals, error)
 	GetMetrics() (*models.Metrics, error)
+	QuarterlyStats(year int) ([]models.QuarterStats, error)
+	RevenueSeries(g models.Granularity, from, to time.Time) ([]models.RevenuePeriod, error)
+	PeriodStats(from, to time.Time) (models.PeriodStats, error)
+	WinRate(year int) (models.WinRate, error)
+	HoursReport(month time.Time) (models.HoursReport, error)
+	EstimateAccuracy() (models.EstimateAccuracy, error)
+	VATReport(year int) ([]models.VATQuarter, error)
+	Reconcile() (*models.Reconciliation, error)
+	StatsSnapshotNear(day time.Time) (*models.StatsSnapshot, error)
+	MonthlyStatsSnapshots() ([]models.StatsSnapshot, error)
+	ListClients() ([]models.Client, error)
+	GetClientByName(name string) (*models.Client, error)
+	SaveClient(c *models.Client) error
+	DeleteClient(id int64) error
+	EnsureClient(name string) error
+	ClientReports() ([]models.ClientReport, error)
+	ClientReport(id int64) (*models.ClientReport, error)
+	ListExchangeRates() ([]models.ExchangeRate, error)
+	GetExchangeRate(currency string) (*models.ExchangeRate, error)
+	SetExchangeRate(r *models.ExchangeRate) error
+	SaveFXRates(r *models.FXRates) error
+	LatestFXRates() (*models.FXRates, error)
+	DeleteExchangeRate(currency string) error
+	ListGoals() ([]models.Goal, error)
+	SaveGoal(g *models.Goal) error
+	DeleteGoal(id int64) error
 	GetContributions(projectID int64) ([]models.Contribution, error)
 	SetContribution(c *models.Contribution) error
+	ListTimeEntries(f models.TimeEntryFilter) ([]models.TimeEntry, error)
+	GetTimeEntry(id int64) (*models.TimeEntry, error)
+	CreateTimeEntry(e *models.TimeEntry) error
+	UpdateTimeEntry(e *models.TimeEntry) error
+	DeleteTimeEntry(id int64) error
+	SetDayHours(projectID, personID int64, day time.Time, hours float64) error
+
+	// Milestones
+	ListMilestones(projectID int64) (models.Milestones, error)
+	ListAllMilestones() (models.Milestones, error)
+	GetMilestone(id int64) (*models.Milestone, error)
+	CreateMilestone(m *models.Milestone) error
+	UpdateMilestone(m *models.Milestone) error
+	SetMilestoneLink(id int64, linkID, url string) error
+	MarkMilestonePaid(id int64, paymentID string) error
+	DeleteMilestone(id int64) error
+
+	// Tasks
+	ListTasks(projectID int64) (models.Tasks, error)
+	GetTask(id int64) (*models.Task, error)
+	CreateTask(t *models.Task) error
+	UpdateTask(t *models.Task) error
+	SetTaskDone(id int64, done bool) error
+	MoveTask(t *models.Task, dir int) error
+	DeleteTask(id int64) error
+
+	// Retainers
+	ListRetainers(clientID int64) ([]models.Retainer, error)
+	ListAllRetainers() ([]models.Retainer, error)
+	GetRetainer(id int64) (*models.Retainer, error)
+	GetRetainerBySubscription(subscriptionID string) (*models.Retainer, error)
+	CreateRetainer(r *models.Retainer) error
+	SetRetainerSubscription(r *models.Retainer) error
+	MarkRetainerPaid(id int64) error
+	DeleteRetainer(id int64) error
+	ListProjectDocuments(projectID int64) ([]models.Document, error)
+	ListClientDocuments(clientID int64) ([]models.Document, error)
+	DocumentsExpiring(today, before time.Time) ([]models.Document, error)
+	GetDocument(id int64) (*models.Document, error)
+	CreateDocument(d *models.Document) error
+	MarkDocumentReminded(id int64) error
+	DeleteDocument(id int64) error
+	CreateExpense(e *models.Expense) error
+	GetExpense(id int64) (*models.Expense, error)
+	ListExpenses(projectID int64) ([]models.Expense, error)
+	ListAllExpenses(f models.ExpenseFilter) ([]models.Expense, error)
+	ExpenseTotals(year int) ([]models.ExpenseTotal, error)
+	UpdateExpense(e *models.Expense) error
+	DeleteExpense(id int64) error
+	CreatePayout(p *models.Payout) error
+	GetPayout(id int64) (*models.Payout, error)
+	ListPayouts() ([]models.Payout, error)
+	DeletePayout(id int64) error
+	CreateInvoice(inv *models.Invoice) error
+	GetInvoice(id int64) (*models.Invoice, error)
+	ListInvoices(status models.InvoiceStatus) ([]models.Invoice, error)
+	ListProjectInvoices(projectID int64) ([]models.Invoice, error)
+	UpdateInvoice(inv *models.Invoice) error
+	IssueInvoice(id int64, on time.Time) error
+	SetInvoiceStatus(id int64, status models.InvoiceStatus, paidOn time.Time) error
+	DeleteInvoice(id int64) error
+	SetFortnoxSync(id int64, s models.FortnoxSync) error
+	LogSentMail(m *models.SentMail) error
+	ListSentMail(limit int) ([]models.SentMail, error)
+	ListInvoiceMail(invoiceID int64) ([]models.SentMail, error)
+	DueSentMail(now time.Time) ([]models.SentMail, error)
+	ClaimSentMail(id int64, now, until time.Time) (bool, error)
+	SaveSentMailAttempt(m *models.SentMail) error
+	CreateQuote(q *models.Quote) error
+	GetQuote(id int64) (*models.Quote, error)
+	ListQuotes(status models.QuoteStatus) ([]models.Quote, error)
+	UpdateQuote(q *models.Quote) error
+	SetQuoteStatus(id int64, from, to models.QuoteStatus) (bool, error)
+	SetQuoteProject(id, projectID int64) (bool, error)
+	DeleteQuote(id int64) error
+	CreateTravelEntry(t *models.TravelEntry) error
+	GetTravelEntry(id int64) (*models.TravelEntry, error)
+	ListTravelEntries(year int) ([]models.TravelEntry, error)
+	MileageSummary(year int) ([]models.MileageSummary, error)
+	DeleteTravelEntry(id int64) error
+	GetSetting(key, fallback string) (string, error)
+	SetSetting(key, value string) error
+	ListAgreements() ([]models.Agreement, error)
+	ProjectSplit(p *models.Project) (*models.RevenueSplit, error)
+	SplitRules() (func(time.Time) models.SplitRules, error)
+	HourlyRates() (models.PerPerson[int64], error)
+	SetHourlyRates(rates models.PerPerson[int64]) error
+	Company() (models.Company, error)
+	SetCompany(c models.Company) error
+	Accounts() (models.Accounts, error)
+	SetAccounts(a models.Accounts) error
+	FortnoxCredentials() (models.FortnoxCredentials, error)
+	SetFortnoxCredentials(c models.FortnoxCredentials) error
+	GoogleCalendarCredentials() (models.GoogleCalendarCredentials, error)
+	SetGoogleCalendarCredentials(c models.GoogleCalendarCredentials) error
+	GoogleCalendarSync() (models.GoogleCalendarSync, error)
+	SetGoogleCalendarSync(s models.GoogleCalendarSync) error
+	BudgetThresholds() ([]int, error)
+	SetBudgetThresholds(thresholds []int) error
+	GetAgreement(id int64) (*models.Agreement, error)
+	CreateAgreement(a *models.Agreement) error
+	DeleteAgreement(id int64) error
+	ListPeriodLocks() ([]models.PeriodLock, error)
+	PeriodLockAt(t time.Time) (*models.PeriodLock, error)
+	LockPeriod(month time.Time, by string) error
+	UnlockPeriod(month time.Time) error
+	ProjectLock(p *models.Project) (*models.PeriodLock, error)
+	ListAdjustments(projectID int64) ([]models.Adjustment, error)
+	CreateAdjustment(a *models.Adjustment) error
+	ListComments(projectID int64) ([]models.Comment, error)
+	GetComment(id int64) (*models.Comment, error)
+	CreateComment(c *models.Comment) error
+	DeleteComment(id int64) error
+	WebhookEventSeen(id string) (bool, error)
+	MarkWebhookEvent(id string) (bool, error)
+	ListStatuses() ([]models.Status, error)
+	CreateStatus(s *models.Status) error
+	UpdateStatus(s *models.Status) error
+	DeleteStatus(key models.ProjectStatus) error
+	CountProjectsByStatus(key models.ProjectStatus) (int, error)
+	ListPeople() (models.People, error)
+	CreatePerson(p *models.Person) error
+	UpdatePerson(p *models.Person) error
+	ListChecklistTemplates() ([]models.ChecklistTemplate, error)
+	CreateChecklistTemplate(t *models.ChecklistTemplate) error
+	DeleteChecklistTemplate(id int64) error
+	AttachChecklist(projectID int64, stage models.ChecklistStage) error
+	ListChecklist(projectID int64) ([]models.ChecklistItem, error)
+	GetChecklistItem(id int64) (*models.ChecklistItem, error)
+	SetChecklistItemDone(id int64, done bool) error
+	GetNotificationPrefs(userID int64) (map[string]models.DeliveryMode, error)
+	SetNotificationPref(userID int64, kind string, mode models.DeliveryMode) error
+	ListNotifications(userID int64, limit int) ([]models.Notification, error)
+	ListAutomationRules() ([]models.AutomationRule, error)
+	CreateAutomationRule(r *models.AutomationRule) error
+	SetAutomationRuleEnabled(id int64, enabled bool) error
+	DeleteAutomationRule(id int64) error
+	ListWebhooks() ([]models.Webhook, error)
+	GetWebhook(id int64) (*models.Webhook, error)
+	CreateWebhook(w *models.Webhook) error
+	SetWebhookEnabled(id int64, enabled bool) error
+	DeleteWebhook(id int64) error
+	ListWebhookDeliveries(webhookID int64, limit int) ([]models.WebhookDelivery, error)
+	GetWebhookDelivery(id int64) (*models.WebhookDelivery, error)
+	CreateWebhookDelivery(d *models.WebhookDelivery) error
+	CreateUser(u *models.User) error
+	GetUser(id int64) (*models.User, error)
+	GetUserByEmail(email string) (*models.User, error)
+	GetUserByCalendarToken(hash string) (*models.User, error)
+	ListUsers() ([]models.User, error)
+	CountUsers() (int, error)
+	CountOwners() (int, error)
+	UpdateUserRole(id int64, role models.Role) error
+	SetWeeklyDigest(id int64, on bool) error
+	SetMonthlyReport(id int64, on bool) error
+	SetCalendarToken(id int64, hash string) error
+	DeleteUser(id int64) error
+	LogActivity(a *models.Activity) error
+	ListActivity(limit int) ([]models.Activity, error)
+	ListProjectActivity(projectID int64, limit int) ([]models.Activity, error)
+	ListUserActivity(userID int64, limit int) ([]models.Activity, error)
+	ActivityBetween(from, to time.Time) ([]models.Activity, error)
+	FirstStripePayment() (*time.Time, error)
+	GetActivity(id int64) (*models.Activity, error)
+	MarkActivityReverted(id, by int64) (bool, error)
+	LastIntegrityCheck() (*models.IntegrityCheck, error)
+	LastDRTest() (*models.DRTest, error)
+	StorageQuota() (models.StorageQuota, error)
+	StorageReport() (*models.StorageReport, error)
+	JournalMode() (string, error)
+	Snapshot(path string) error
+	RecordBackupRun(r *models.BackupRun) error
+	ListBackupRuns(limit int) ([]models.BackupRun, error)
+	LastBackupRun(destination string, ok bool) (*models.BackupRun, error)
+	Export(w io.Writer) (*models.DumpSummary, error)
+	Import(r io.Reader) (*models.DumpSummary, error)
+	QueryStats() models.QueryStats
+	CreateAPIKey(k *models.APIKey) error
+	GetAPIKeyByHash(hash string) (*models.APIKey, error)
+	ListAPIKeys() ([]models.APIKey, error)
+	TouchAPIKey(id int64) error
+	RevokeAPIKey(id int64) error
+}
+
+// Config holds handler settings read from the environment
+type Config struct {
+	UploadDir             string             // receipts and other uploaded files
+	Backups               *backup.Runner     // database snapshots (BACKUP_DIR) and their remote copies
+	BaseURL               string             // public URL used in shared links (defaults to the request host)
+	PortalSecret          []byte             // signs client portal tokens
+	PortalTTL             time.Duration      // lifetime of a client portal link
+	PaymentLinkURL        string             // Stripe payment link shown in the client portal
+	StripeKey             string             // Stripe secret key; enables branded Checkout from the portal
+	StripeAPIURL          string             // Stripe API base, "" for Stripe's own (set for stripe-mock)
+	StripeWebhookSecret   string             // verifies Stripe webhook signatures
+	StripeWebhookInsecure bool               // accept unsigned Stripe events when there's no secret; local testing only
+	Mail                  mail.Sender        // outgoing email (invites, invoices, receipts, summaries)
+	LoginRateLimit        int                // login attempts per minute per client IP (0 = unlimited)
+	APIRateLimit          int                // API requests per minute per key (0 = unlimited)
+	FortnoxAPIURL         string             // Fortnox REST API base; credentials are set in Admin
+	FortnoxTokenURL       string             // Fortnox OAuth token endpoint
+	GoogleAPIURL          string             // Google Calendar API base; credentials are set in Admin
+	GoogleTokenURL        string             // Google OAuth token endpoint
+	FXProvider            string             // exchange rate provider refreshing the stored rates daily; "" for rates kept by hand
+	Replication           replication.Config // Litestream or LiteFS; on a replica, changes are turned away
+	Maintenance           maintenance.Lock   // held by a restore; requests get 503 meanwhile
+	Errors                *sentry.Client     // panics, server errors and webhook failures (SENTRY_DSN); nil reports nothing
 }
 
 // Handler holds dependencies
 type Handler struct {
-	DB Store
+	DB          Store
+	Jobs        *jobs.Scheduler
+	Events      *events.Bus
+	Stream      *events.Stream
+	Automations *automation.Engine
+	Notify      *notify.Dispatcher
+	Sessions    auth.SessionStore
+	Cache       cache.Cache
+	Limiter     ratelimit.Limiter
+	Fortnox     *fortnox.Client
+	GoogleCal   *gcal.Client
+	Config      Config
 }
 
 // New creates a new Handler
-func New(db Store) *Handler {
-	return &Handler{DB: db}
+func New(db Store, sched *jobs.Scheduler, bus *events.Bus, stream *events.Stream, engine *automation.Engine, notifier *notify.Dispatcher, sessions auth.SessionStore, c cache.Cache, limiter ratelimit.Limiter, cfg Config) *Handler {
+	fnx := fortnox.New(cmp.Or(cfg.FortnoxAPIURL, fortnox.DefaultAPIURL), cmp.Or(cfg.FortnoxTokenURL, fortnox.DefaultTokenURL), db)
+	gc := gcal.New(cmp.Or(cfg.GoogleAPIURL, gcal.DefaultAPIURL), cmp.Or(cfg.GoogleTokenURL, gcal.DefaultTokenURL), db)
+	return &Handler{DB: db, Jobs: sched, Events: bus, Stream: stream, Automations: engine, Notify: notifier, Sessions: sessions, Cache: c, Limiter: limiter, Fortnox: fnx, GoogleCal: gc, Config: cfg}
 }
 
-// Dashboard renders the main dashboard with kanban
+// Dashboard renders the main dashboard with kanban, filtered and sorted by
+// the board's query (see parseProjectFilter). A search loads its matches a
+// page at a time; HTMX requests for a later page (?offset=) get only its cards.
 func (h *Handler) Dashboard(w http.ResponseWriter, r *http.Request) {
-	search := r.URL.Query().Get("search")
-	
-	projects, err := h.DB.ListProjects(search)
+	q := boardQuery(r)
+	f, msg := parseProjectFilter(q)
+	if msg == "" && f.Search != "" {
+		f.Page, msg = parsePage(q, boardSearchPageSize, boardSearchPageSize)
+	}
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	// One extra row tells whether there is a next page
+	page := f.Page
+	if page.Limit > 0 {
+		f.Limit++
+	}
+	projects, err := h.DB.ListProjects(f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	next := 0
+	if page.Limit > 0 && len(projects) > page.Limit {
+		projects = projects[:page.Limit]
+		next = page.Offset + page.Limit
+	}
+	f.Page = page
+
+	wf, err := h.workflow()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	columns := wf.Board(projects)
 
-	metrics, err := h.DB.GetMetrics()
+	people, err := h.DB.ListPeople()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	new, progress, done, paid := splitByStatus(projects)
+	if isHTMX(r) && f.Offset > 0 {
+		templates.BoardRows(columns, people, next).Render(r.Context(), w)
+		return
+	}
+	if page.Limit > 0 {
+		// The column badges count every match, not just the loaded ones
+		totals, err := h.DB.ProjectTotals(f)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for i := range columns {
+			t := totals[columns[i].Status.Key]
+			columns[i].Total = &t
+		}
+	}
 
-	if r.Header.Get("HX-Request") == "true" {
-		templates.KanbanBoard(new, progress, done, paid).Render(r.Context(), w)
+	metrics, err := h.metrics()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	cmp, err := h.comparison()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	activity, err := h.DB.ListActivity(activityFeedSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	forecast, err := h.forecast()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	aging, err := h.aging()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	goals, err := h.goalProgress(time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if isHTMX(r) {
+		templates.KanbanBoard(columns, people, next).Render(r.Context(), w)
+		templates.MetricsRow(metrics, cmp, people, true).Render(r.Context(), w)
+		templates.ForecastRow(forecast, aging, true).Render(r.Context(), w)
+		templates.GoalsRow(goals, people, true).Render(r.Context(), w)
+		templates.ActivityFeed(activity, true).Render(r.Context(), w)
 	} else {
-		templates.Layout("FullDash", 
-			templates.Dashboard(metrics, new, progress, done, paid, search)).Render(r.Context(), w)
+		revenue, err := h.recentRevenue()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		templates.Layout("FullDash",
+			templates.Dashboard(metrics, cmp, columns, next, f, activity, people, revenue, forecast, aging, goals)).Render(r.Context(), w)
 	}
 }
 
-// splitByStatus groups projects by their status
-func splitByStatus(projects []models.Project) (new, progress, done, paid []models.Project) {
-	for _, p := range projects {
-		switch p.Status {
-		case models.StatusNew:
-			new = append(new, p)
-		case models.StatusProgress:
-			progress = append(progress, p)
-		case models.StatusDone:
-			done = append(done, p)
-		case models.StatusPaid:
-			paid = append(paid, p)
-		}
+// boardQuery returns the board's filter query. Mutations re-render the board
+// from a POST/PUT/DELETE, so their filters come from the page's URL instead.
+func boardQuery(r *http.Request) url.Values {
+	if r.Method == http.MethodGet {
+		return r.URL.Query()
+	}
+	u, err := url.Parse(r.Header.Get("HX-Current-URL"))
+	if err != nil {
+		return url.Values{}
 	}
-	return
+	return u.Query()
 }
 
 // ProjectForm renders the add/edit form
 func (h *Handler) ProjectForm(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
-	
+
 	var p *models.Project
-	var noorHours, ahmadHours float64
+	var lock *models.PeriodLock
+	var split *models.RevenueSplit
+	var hours models.PerPerson[float64]
+	var expenses []models.Expense
+	var entries []models.TimeEntry
+	var milestones models.Milestones
+	var adjustments []models.Adjustment
+	var checklist []models.ChecklistItem
+	var comments []models.Comment
+	var invoices []models.Invoice
+	var documents []models.Document
 	isEdit := idStr != ""
-	
+
 	if isEdit {
 		if id, err := strconv.ParseInt(idStr, 10, 64); err == nil {
 			p, _ = h.DB.GetProject(id)
 			if p != nil {
-				noorHours, ahmadHours = h.getHours(p.ID)
+				hours = h.hours(p.ID)
+				expenses, _ = h.DB.ListExpenses(p.ID)
+				entries, _ = h.DB.ListTimeEntries(models.TimeEntryFilter{ProjectID: p.ID})
+				milestones, _ = h.DB.ListMilestones(p.ID)
+				checklist, _ = h.DB.ListChecklist(p.ID)
+				lock, _ = h.DB.ProjectLock(p)
+				adjustments, _ = h.DB.ListAdjustments(p.ID)
+				comments, _ = h.DB.ListComments(p.ID)
+				invoices, _ = h.DB.ListProjectInvoices(p.ID)
+				documents, _ = h.DB.ListProjectDocuments(p.ID)
+				split, _ = h.DB.ProjectSplit(p)
 			}
 		}
 	}
-	
+
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	if p == nil {
-		p = &models.Project{Status: models.StatusNew, SecuredBy: models.OwnerBoth}
+		p = &models.Project{Status: models.StatusNew, VATPercent: models.DefaultVATPercent, PaymentDays: models.DefaultPaymentDays}
+		for _, person := range people.Active() {
+			p.SecuredBy = append(p.SecuredBy, person.ID)
+		}
+	}
+
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
-	
-	templates.ProjectForm(p, isEdit, noorHours, ahmadHours).Render(r.Context(), w)
+
+	rates, err := h.DB.ListExchangeRates()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	templates.ProjectForm(wf, p, lock, split, isEdit, people, hours, entries, milestones, expenses, adjustments, checklist, comments, invoices, documents, h.Config.Mail.Enabled(), h.Config.StripeKey != "", rates).Render(r.Context(), w)
 }
 
-// getHours retrieves contribution hours for both owners
-func (h *Handler) getHours(projectID int64) (noorHours, ahmadHours float64) {
+// hours retrieves everyone's contribution hours on a project
+func (h *Handler) hours(projectID int64) models.PerPerson[float64] {
 	contribs, _ := h.DB.GetContributions(projectID)
-	for _, c := range contribs {
-		switch c.Owner {
-		case models.OwnerNoor:
-			noorHours = c.Hours
-		case models.OwnerAhmad:
-			ahmadHours = c.Hours
-		}
+	return service.HoursOf(contribs)
+}
+
+// parseProjectRequest reads the project form against the current people;
+// a bad form has already been answered with 400 when ok is false
+func (h *Handler) parseProjectRequest(w http.ResponseWriter, r *http.Request) (form *ParsedForm, people models.People, ok bool) {
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	form, err = parseProjectForm(r, people)
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if !people.Valid(form.SecuredBy) {
+		http.Error(w, "Choose who secured the project", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if !validSplit(form.SplitOverride, people) {
+		http.Error(w, "A split override must add up to 100%", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if !validPercent(form.VATPercent) {
+		http.Error(w, "VAT must be a percentage between 0 and 100", http.StatusBadRequest)
+		return nil, nil, false
 	}
-	return
+	if !validProbability(form.Probability) {
+		http.Error(w, "Probability must be a percentage between 0 and 100", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if !form.Source.Valid() {
+		http.Error(w, "Unknown lead source", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if !validEstimate(form.EstimatedHours, form.HourEstimates, people) {
+		http.Error(w, "Estimates can't be negative, and each person's can't add up to more than the project's", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if form.PaymentDays < 0 {
+		http.Error(w, "Payment terms can't be negative", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if !validBudget(form.BudgetHours, form.BudgetCents, form.HourlyCents) {
+		http.Error(w, "The budget and hourly rate can't be negative, and a budget in kr needs an hourly rate", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if form.Status == models.StatusLost && form.LostReason == "" {
+		http.Error(w, "Give a reason the project was lost", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	return form, people, true
 }
 
 // CreateProject handles new project creation
 func (h *Handler) CreateProject(w http.ResponseWriter, r *http.Request) {
-	form, err := parseProjectForm(r)
+	form, _, ok := h.parseProjectRequest(w, r)
+	if !ok {
+		return
+	}
+
+	wf, err := h.workflow()
 	if err != nil {
-		http.Error(w, "Bad request", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !wf.Valid(form.Status) {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+	if !h.convertForm(w, form, nil) {
 		return
 	}
 
 	p := form.toProject()
-	if err := h.DB.CreateProject(p); err != nil {
+	if err := h.createProject(p); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -133,6 +596,92 @@ func (h *Handler) CreateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	created := service.Snapshot(p, form.Hours)
+	if err := h.logActivity(r, p, "created", nil, &created, ""); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.publish(r, events.ProjectCreated, p, "", events.SourceUser)
+
+	triggerToast(w, "Created "+p.Client)
+	h.Dashboard(w, r)
+}
+
+// DuplicateProject clones a project for repeat work: same client, owner,
+// description, revenue and split, named "(copy)" and back in the first column.
+// Hours are copied only with copy_hours; payment details never are.
+func (h *Handler) DuplicateProject(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	src, err := h.DB.GetProject(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if src == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	p := &models.Project{
+		Client:       src.Client + " (copy)",
+		Description:  src.Description,
+		RevenueCents: src.RevenueCents,
+		Status:       wf.Initial(),
+		SecuredBy:    src.SecuredBy,
+
+		Currency:      src.Currency,
+		OriginalCents: src.OriginalCents,
+		SplitOverride: src.SplitOverride,
+
+		VATPercent:   src.VATPercent,
+		VATInclusive: src.VATInclusive,
+
+		Probability: src.Probability,
+		Source:      src.Source,
+
+		EstimatedHours: src.EstimatedHours,
+		HourEstimates:  src.HourEstimates,
+
+		PaymentDays: src.PaymentDays,
+
+		BudgetHours: src.BudgetHours,
+		BudgetCents: src.BudgetCents,
+		HourlyCents: src.HourlyCents,
+	}
+	if err := h.createProject(p); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var hours models.PerPerson[float64]
+	if r.FormValue("copy_hours") != "" {
+		hours = h.hours(src.ID)
+		form := &ParsedForm{Hours: hours}
+		if err := form.saveContributions(h.DB, p.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	created := service.Snapshot(p, hours)
+	if err := h.logActivity(r, p, "created", nil, &created, "duplicated from "+src.Client); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.publish(r, events.ProjectCreated, p, "", events.SourceUser)
+
+	triggerToast(w, "Duplicated "+src.Client)
 	h.Dashboard(w, r)
 }
 
@@ -154,24 +703,152 @@ func (h *Handler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	form, err := parseProjectForm(r)
+	form, people, ok := h.parseProjectRequest(w, r)
+	if !ok {
+		return
+	}
+	if !h.convertForm(w, form, p) {
+		return
+	}
+
+	overridden, err := h.checkMove(p, form.Status, form.OverrideGate, form.Refund)
 	if err != nil {
-		http.Error(w, "Bad request", http.StatusBadRequest)
+		msg, blocked := moveMessage(p, err)
+		if !blocked {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		triggerToast(w, msg)
+		http.Error(w, msg, http.StatusUnprocessableEntity)
 		return
 	}
 
+	hours := h.hours(p.ID)
+	if err := h.checkLocked(p, form, hours); err != nil {
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	before := service.Snapshot(p, hours)
+
+	from := p.Status
 	form.applyTo(p)
 	if err := h.DB.UpdateProject(p); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
 	// Update contributions (even zero hours, to clear old values)
 	if err := form.saveContributions(h.DB, p.ID); err != nil {
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	after := service.Snapshot(p, h.hours(p.ID))
+	summary := service.Summary(service.Changes(people, &before, &after))
+	if err := h.logActivity(r, p, "updated", &before, &after, summary); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.logMoveNotes(r, p, from, overridden, form.Refund); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.publish(r, events.ProjectUpdated, p, from, events.SourceUser)
+	h.checkBudget(r, p.ID, events.SourceUser)
+
+	if summary == "" {
+		triggerToast(w, "No changes to "+p.Client)
+	} else {
+		triggerToast(w, "Updated "+p.Client+": "+summary)
+	}
+	h.Dashboard(w, r)
+}
+
+// MoveProject changes only a project's status (keyboard moves on the board)
+func (h *Handler) MoveProject(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.DB.GetProject(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	wf, err := h.workflow()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	to := models.ProjectStatus(r.FormValue("status"))
+	if !wf.Valid(to) {
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+	if to == p.Status {
+		msg := p.Client + " is already in " + wf.Label(to)
+		triggerToast(w, msg)
+		http.Error(w, msg, http.StatusUnprocessableEntity)
+		return
+	}
+	reason := lostReason(to, r.FormValue("lost_reason"))
+	if to == models.StatusLost && reason == "" {
+		http.Error(w, "Give a reason "+p.Client+" was lost", http.StatusBadRequest)
+		return
+	}
+
+	refund := checked(r, "refund")
+	overridden, err := h.checkMove(p, to, checked(r, "override_gate"), refund)
+	if err != nil {
+		msg, blocked := moveMessage(p, err)
+		if !blocked {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		triggerToast(w, msg)
+		http.Error(w, msg, http.StatusUnprocessableEntity)
+		return
+	}
+
+	hours := h.hours(p.ID)
+	before := service.Snapshot(p, hours)
+
+	from := p.Status
+	p.Status = to
+	p.LostReason = reason
+	if err := h.DB.UpdateProject(p); err != nil {
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	after := service.Snapshot(p, hours)
+	if err := h.logActivity(r, p, "updated", &before, &after, service.Summary(service.Diff(before, after))); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.logMoveNotes(r, p, from, overridden, refund); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.publish(r, events.ProjectUpdated, p, from, events.SourceUser)
 
+	triggerToast(w, "Moved "+p.Client+" to "+wf.Label(to))
 	h.Dashboard(w, r)
 }
 
@@ -182,11 +859,151 @@ func (h *Handler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid ID", http.StatusBadRequest)
 		return
 	}
-	
+
+	p, err := h.DB.GetProject(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if p == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	deleted := service.Snapshot(p, h.hours(p.ID))
+
 	if err := h.DB.DeleteProject(id); err != nil {
+		if !refuseLocked(w, err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.logActivity(r, p, "deleted", &deleted, nil, ""); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.publish(r, events.ProjectDeleted, p, "", events.SourceUser)
 
+	triggerToast(w, "Deleted "+p.Client)
 	h.Dashboard(w, r)
 }
+
+// checkMove validates a status change against the state machine, then the
+// checklist gates. Blocked moves return a *domain.TransitionError or
+// *service.GateError; a soft gate passed with override is returned as
+// overridden so the caller can log it.
+func (h *Handler) checkMove(p *models.Project, to models.ProjectStatus, override, refund bool) (overridden *service.GateError, err error) {
+	wf, err := h.workflow()
+	if err != nil {
+		return nil, err
+	}
+	if err := wf.CheckTransition(p.Status, to, refund); err != nil {
+		return nil, err
+	}
+	return h.checkGates(p, to, override)
+}
+
+// moveMessage explains a blocked status change for the UI; blocked is false
+// for any other error
+func moveMessage(p *models.Project, err error) (msg string, blocked bool) {
+	var gate *service.GateError
+	var tr *domain.TransitionError
+	switch {
+	case errors.As(err, &gate):
+		return gateMessage(p, gate), true
+	case errors.As(err, &tr):
+		msg = "Can't move " + p.Client + ": " + tr.Error()
+		if tr.NeedsRefund {
+			msg += " (tick refund issued to reopen it)"
+		}
+		return msg, true
+	}
+	return "", false
+}
+
+// logMoveNotes records a checklist override and a refund-driven reopen
+// alongside the change itself
+func (h *Handler) logMoveNotes(r *http.Request, p *models.Project, from models.ProjectStatus, overridden *service.GateError, refund bool) error {
+	if overridden != nil {
+		if err := h.logActivity(r, p, "overrode", nil, nil, overridden.Error()); err != nil {
+			return err
+		}
+	}
+	if !refund || from == p.Status {
+		return nil
+	}
+	wf, err := h.workflow()
+	if err != nil {
+		return err
+	}
+	if wf.NeedsRefund(from) {
+		return h.logActivity(r, p, "refunded", nil, nil, "reopened from "+wf.Label(from)+" after a refund")
+	}
+	return nil
+}
+
+// createProject inserts a project and attaches the standard checklists
+func (h *Handler) createProject(p *models.Project) error {
+	if err := h.DB.CreateProject(p); err != nil {
+		return err
+	}
+	for _, stage := range models.ChecklistStages {
+		if err := h.DB.AttachChecklist(p.ID, stage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publish announces a project change on the event bus; an update that
+// changes the status also publishes ProjectStatusChanged
+func (h *Handler) publish(r *http.Request, t events.Type, p *models.Project, from models.ProjectStatus, source string) {
+	e := events.Event{Type: t, ProjectID: p.ID, Client: p.Client, AmountCents: p.RevenueCents, From: from, To: p.Status, Source: source}
+	h.Events.Publish(r.Context(), e)
+	if t == events.ProjectUpdated && from != p.Status {
+		e.Type = events.ProjectStatusChanged
+		h.Events.Publish(r.Context(), e)
+	}
+}
+
+// logActivity records a project change in the audit log, attributed to the
+// request's user or API key. before/after are the project around the change
+// (nil = did not exist); they give the field diff and feed undo/redo.
+func (h *Handler) logActivity(r *http.Request, p *models.Project, action string, before, after *service.ProjectSnapshot, summary string) error {
+	a := actorEntry(r)
+	a.ProjectID = p.ID
+	a.Client = p.Client
+	a.Action = action
+	a.Summary = summary
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		return err
+	}
+	a.Changes = service.Changes(people, before, after)
+	a.Before = service.EncodeSnapshot(before)
+	a.After = service.EncodeSnapshot(after)
+	return h.DB.LogActivity(&a)
+}
+
+// actorEntry starts an audit entry attributed to the request's user or API
+// key, and to the request itself
+func actorEntry(r *http.Request) models.Activity {
+	a := models.Activity{RequestID: requestID(r)}
+	a.Actor, a.Source = actorFrom(r)
+	if u := auth.UserFrom(r.Context()); u != nil && a.Source == events.SourceUser {
+		a.UserID = u.ID
+	}
+	return a
+}
+
+// actorFrom names who is making a request, for the audit log
+func actorFrom(r *http.Request) (actor, source string) {
+	if k := APIKeyFrom(r.Context()); k != nil {
+		return "api:" + k.Name, events.SourceAPI
+	}
+	if u := auth.UserFrom(r.Context()); u != nil {
+		return u.Name, events.SourceUser
+	}
+	return "", events.SourceUser
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/store"
+)
+
+func TestMoveProjectToSameStatus(t *testing.T) {
+	db, err := store.New(filepath.Join(t.TempDir(), "fulldash.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	p := &models.Project{Client: "Acme", RevenueCents: 100000, Status: models.StatusNew, SecuredBy: []int64{1}}
+	if err := db.CreateProject(p); err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{"status": {string(models.StatusNew)}}
+	r := httptest.NewRequest("POST", "/projects/1/move", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", strconv.FormatInt(p.ID, 10))
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+	(&Handler{DB: db}).MoveProject(w, r)
+
+	if w.Code != 422 {
+		t.Errorf("move to the current status: %d, want 422", w.Code)
+	}
+	history, err := db.ListProjectActivity(p.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 0 {
+		t.Errorf("refused move logged %+v", history)
+	}
+}
+
+func TestChecked(t *testing.T) {
+	for value, want := range map[string]bool{"on": true, "": false, "off": false, "false": false, "1": false} {
+		r := httptest.NewRequest("POST", "/?override_gate="+url.QueryEscape(value), nil)
+		if got := checked(r, "override_gate"); got != want {
+			t.Errorf("checked(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
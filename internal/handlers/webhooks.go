@@ -0,0 +1,158 @@
+// handlers/webhooks.go - Admin management of outbound webhooks and their delivery log
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+	"github.com/noor-latif/fulldash/internal/webhooks"
+)
+
+// deliveryLogSize is the number of deliveries shown in a webhook's log
+const deliveryLogSize = 100
+
+// CreateWebhook adds a webhook from the admin form, with a new signing
+// secret that is shown once
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	hook := &models.Webhook{URL: strings.TrimSpace(r.FormValue("url")), Events: r.Form["events"], Enabled: true}
+
+	if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		http.Error(w, "The URL must be an http(s) address", http.StatusBadRequest)
+		return
+	}
+	for _, e := range hook.Events {
+		if !slices.Contains(webhooks.Events, events.Type(e)) {
+			http.Error(w, "Unknown event "+e, http.StatusBadRequest)
+			return
+		}
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	hook.Secret = secret
+	if err := h.DB.CreateWebhook(hook); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	triggerToast(w, "Webhook added")
+	h.renderWebhooks(w, r, secret)
+}
+
+// ToggleWebhook pauses or resumes a webhook
+func (h *Handler) ToggleWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.SetWebhookEnabled(id, r.FormValue("enabled") == "on"); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderWebhooks(w, r, "")
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.DeleteWebhook(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.renderWebhooks(w, r, "")
+}
+
+// WebhookPage shows a webhook's latest deliveries
+func (h *Handler) WebhookPage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	hook, err := h.DB.GetWebhook(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if hook == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	deliveries, err := h.DB.ListWebhookDeliveries(id, deliveryLogSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	templates.Layout("FullDash Webhook", templates.WebhookPage(*hook, deliveries)).Render(r.Context(), w)
+}
+
+// RedeliverWebhook sends a past delivery's payload again, as a new
+// delivery with its own retries, and re-renders the log
+func (h *Handler) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	past, err := h.DB.GetWebhookDelivery(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if past == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	del := &models.WebhookDelivery{WebhookID: past.WebhookID, Event: past.Event, Payload: past.Payload, Status: models.WebhookPending, NextAttemptAt: &now}
+	if err := h.DB.CreateWebhookDelivery(del); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.Jobs.RunNow(r.Context(), webhooks.JobName)
+
+	deliveries, err := h.DB.ListWebhookDeliveries(past.WebhookID, deliveryLogSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.WebhookDeliveries(deliveries).Render(r.Context(), w)
+}
+
+// renderWebhooks renders the admin webhook panel, with a just-created
+// webhook's secret if there is one
+func (h *Handler) renderWebhooks(w http.ResponseWriter, r *http.Request, newSecret string) {
+	hooks, err := h.DB.ListWebhooks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templates.Webhooks(hooks, newSecret).Render(r.Context(), w)
+}
@@ -0,0 +1,149 @@
+// handlers/weekly.go - The weekly summary emailed to partners
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/mail"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/templates"
+)
+
+// SendWeeklySummaries emails every partner who hasn't opted out a summary
+// of last week (Monday to Sunday). The first summary of a month carries
+// last month's report for those who asked for it. Without SMTP it sends
+// nothing.
+func (h *Handler) SendWeeklySummaries(ctx context.Context) error {
+	if !h.Config.Mail.Enabled() {
+		return nil
+	}
+	to := models.WeekStart(time.Now())
+	s, err := h.weeklySummary(to.AddDate(0, 0, -7), to)
+	if err != nil {
+		return err
+	}
+	users, err := h.DB.ListUsers()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	var report *mail.Attachment // rendered for the first who wants it
+	for _, u := range users {
+		if !u.Role.Allows(models.RolePartner) || !u.WeeklyDigest {
+			continue
+		}
+		subject, body := templates.WeeklyEmail(s, u.Name, h.Config.BaseURL)
+		msg := mail.Message{To: []string{u.Email}, Subject: subject, Body: body}
+		if u.MonthlyReport && to.Day() <= 7 {
+			if report == nil {
+				month := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+				att, err := h.monthlyReportPDF(month)
+				if err != nil {
+					return err
+				}
+				report = &att
+			}
+			msg.Attachments = []mail.Attachment{*report}
+		}
+		if err := h.sendMail(models.SentMail{Kind: models.MailWeekly, SentBy: "schedule"}, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", u.Email, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// weeklySummary gathers the week from from up to to
+func (h *Handler) weeklySummary(from, to time.Time) (models.WeeklySummary, error) {
+	s := models.WeeklySummary{From: from, To: to}
+	lastDay := to.AddDate(0, 0, -1)
+
+	paid, err := h.DB.ListProjectsByStatus(models.StatusPaid)
+	if err != nil {
+		return s, err
+	}
+	for _, p := range paid {
+		if p.PaidAt != nil && !p.PaidAt.Before(from) && p.PaidAt.Before(to) {
+			s.Paid = append(s.Paid, p)
+		}
+	}
+
+	if s.Created, err = h.DB.ListProjects(models.ProjectFilter{From: from, To: lastDay, Sort: models.SortCreated}); err != nil {
+		return s, err
+	}
+
+	activity, err := h.DB.ActivityBetween(from, to)
+	if err != nil {
+		return s, err
+	}
+	for _, a := range activity {
+		if a.Action != "created" && slices.ContainsFunc(a.Changes, func(c models.Change) bool { return c.Field == "status" }) {
+			s.Moved = append(s.Moved, a)
+		}
+	}
+
+	wf, err := h.workflow()
+	if err != nil {
+		return s, err
+	}
+	projects, err := h.DB.ListProjects(models.ProjectFilter{})
+	if err != nil {
+		return s, err
+	}
+	invoices, err := h.DB.ListInvoices("")
+	if err != nil {
+		return s, err
+	}
+	for _, r := range wf.Aging(projects, invoices, to).Receivables {
+		if r.DaysOverdue > 0 {
+			s.Overdue = append(s.Overdue, r)
+		}
+	}
+
+	entries, err := h.DB.ListTimeEntries(models.TimeEntryFilter{From: from, To: lastDay})
+	if err != nil {
+		return s, err
+	}
+	byPerson := map[int64]int{}
+	for _, e := range entries {
+		i, ok := byPerson[e.PersonID]
+		if !ok {
+			i = len(s.Hours)
+			byPerson[e.PersonID] = i
+			s.Hours = append(s.Hours, models.PersonHours{PersonID: e.PersonID, Person: e.Person})
+		}
+		s.Hours[i].Hours += e.Hours
+	}
+	slices.SortStableFunc(s.Hours, func(a, b models.PersonHours) int { return cmp.Compare(b.Hours, a.Hours) })
+	return s, nil
+}
+
+// UpdateWeeklyDigest opts the current user in to or out of the weekly
+// summary email and the monthly report attached to it
+func (h *Handler) UpdateWeeklyDigest(w http.ResponseWriter, r *http.Request) {
+	u := auth.UserFrom(r.Context())
+
+	on := r.FormValue("weekly_digest") == "on"
+	if err := h.DB.SetWeeklyDigest(u.ID, on); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.DB.SetMonthlyReport(u.ID, r.FormValue("monthly_report") == "on"); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if on {
+		triggerToast(w, "Weekly summary on")
+	} else {
+		triggerToast(w, "Weekly summary off")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,165 @@
+// handlers/workbook.go - Excel workbook of a year's projects, payments and expenses for the accountant
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/xlsx"
+)
+
+// ExportWorkbook downloads ?year= (default this year) as an .xlsx workbook:
+// a monthly summary whose figures are formulas over the Payments and
+// Expenses sheets, then those sheets and every project
+func (h *Handler) ExportWorkbook(w http.ResponseWriter, r *http.Request) {
+	year := time.Now().Year()
+	if s := r.URL.Query().Get("year"); s != "" {
+		y, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, "Invalid year", http.StatusBadRequest)
+			return
+		}
+		year = y
+	}
+
+	sheets, err := h.workbook(year)
+	if errors.Is(err, errNoRate) {
+		http.Error(w, err.Error()+"; add one in Admin", http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var buf bytes.Buffer
+	if err := xlsx.Write(&buf, sheets); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="fulldash-%d.xlsx"`, year))
+	w.Write(buf.Bytes())
+}
+
+// workbook builds the sheets of a year's workbook. Payments are those of
+// payments.csv paid in the year; projects are all of them, archived too.
+func (h *Handler) workbook(year int) ([]*xlsx.Sheet, error) {
+	all, err := h.payments()
+	if err != nil {
+		return nil, err
+	}
+	expenses, err := h.DB.ListAllExpenses(models.ExpenseFilter{Year: year})
+	if err != nil {
+		return nil, err
+	}
+	projects, err := h.DB.ListProjects(models.ProjectFilter{Archived: true})
+	if err != nil {
+		return nil, err
+	}
+	people, err := h.DB.ListPeople()
+	if err != nil {
+		return nil, err
+	}
+	wf, err := h.workflow()
+	if err != nil {
+		return nil, err
+	}
+
+	payments := &xlsx.Sheet{Name: "Payments", Header: true, Widths: []float64{12, 10, 28, 12, 8, 14, 14, 14, 10}}
+	payments.Add(xlsx.Text("Date"), xlsx.Text("Project"), xlsx.Text("Client"), xlsx.Text("Invoice"), xlsx.Text("VAT"),
+		xlsx.Text("Net"), xlsx.Text("VAT amount"), xlsx.Text("Total"), xlsx.Text("Currency"))
+	for _, p := range all {
+		if p.Date.Year() != year {
+			continue
+		}
+		row := len(payments.Rows) + 1
+		payments.Add(xlsx.Day(p.Date), xlsx.Number(float64(p.ProjectID), xlsx.Plain), xlsx.Text(p.Client), xlsx.Text(p.Invoice),
+			xlsx.Number(p.VATPercent, xlsx.Percent), xlsx.Cents(p.NetCents), xlsx.Cents(p.VATCents),
+			xlsx.Formula(fmt.Sprintf("F%d+G%d", row, row), xlsx.Amount), xlsx.Text(p.Currency))
+	}
+	paymentRows := len(payments.Rows)
+	totalRow(payments, 5, 6, 7)
+
+	spent := &xlsx.Sheet{Name: "Expenses", Header: true, Widths: []float64{12, 36, 16, 16, 28, 14}}
+	spent.Add(xlsx.Text("Date"), xlsx.Text("Description"), xlsx.Text("Category"), xlsx.Text("Paid by"), xlsx.Text("Client"), xlsx.Text("Amount"))
+	for i := len(expenses) - 1; i >= 0; i-- { // oldest first
+		e := expenses[i]
+		client := e.Client
+		if e.Overhead() {
+			client = "Overhead"
+		}
+		spent.Add(xlsx.Day(e.SpentOn), xlsx.Text(e.Description), xlsx.Text(e.Category.Label()), xlsx.Text(people.Name(e.PayerID)),
+			xlsx.Text(client), xlsx.Cents(e.AmountCents))
+	}
+	expenseRows := len(spent.Rows)
+	totalRow(spent, 5)
+
+	// Each month sums the rows (totals left out) dated from its first day up
+	// to the next month's
+	summary := &xlsx.Sheet{Name: "Summary", Header: true, Widths: []float64{12, 16, 16, 16, 16, 16}}
+	summary.Add(xlsx.Text("Month"), xlsx.Text("Sales excl. VAT"), xlsx.Text("Output VAT"), xlsx.Text("Received"),
+		xlsx.Text("Expenses"), xlsx.Text("Result"))
+	for m := time.January; m <= time.December; m++ {
+		row := len(summary.Rows) + 1
+		sumMonth := func(sheet string, col, rows int) string {
+			span := func(c int) string {
+				return fmt.Sprintf("%s!$%s$2:$%s$%d", sheet, xlsx.Column(c), xlsx.Column(c), max(rows, 2))
+			}
+			return fmt.Sprintf(`SUMIFS(%s,%s,">="&$A%d,%s,"<"&EDATE($A%d,1))`,
+				span(col), span(0), row, span(0), row)
+		}
+		summary.Add(
+			xlsx.Number(xlsx.Serial(time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)), xlsx.Month),
+			xlsx.Formula(sumMonth("Payments", 5, paymentRows), xlsx.Amount),
+			xlsx.Formula(sumMonth("Payments", 6, paymentRows), xlsx.Amount),
+			xlsx.Formula(sumMonth("Payments", 7, paymentRows), xlsx.Amount),
+			xlsx.Formula(sumMonth("Expenses", 5, expenseRows), xlsx.Amount),
+			xlsx.Formula(fmt.Sprintf("B%d-E%d", row, row), xlsx.Amount),
+		)
+	}
+	totalRow(summary, 1, 2, 3, 4, 5)
+
+	list := &xlsx.Sheet{Name: "Projects", Header: true, Widths: []float64{8, 28, 36, 14, 20, 12, 12, 14, 8, 14, 14, 10, 14}}
+	list.Add(xlsx.Text("ID"), xlsx.Text("Client"), xlsx.Text("Description"), xlsx.Text("Status"), xlsx.Text("Secured by"),
+		xlsx.Text("Created"), xlsx.Text("Paid"), xlsx.Text("Revenue"), xlsx.Text("VAT"), xlsx.Text("VAT amount"),
+		xlsx.Text("Total"), xlsx.Text("Currency"), xlsx.Text("Original"))
+	for _, p := range projects {
+		row := len(list.Rows) + 1
+		var paid time.Time
+		if p.PaidAt != nil {
+			paid = *p.PaidAt
+		}
+		original := xlsx.Cell{}
+		if p.Currency != "" {
+			original = xlsx.Cents(p.OriginalCents)
+		}
+		list.Add(xlsx.Number(float64(p.ID), xlsx.Plain), xlsx.Text(p.Client), xlsx.Text(p.Description), xlsx.Text(wf.Label(p.Status)),
+			xlsx.Text(people.Names(p.SecuredBy)), xlsx.Day(p.CreatedAt), xlsx.Day(paid), xlsx.Cents(p.RevenueCents),
+			xlsx.Number(p.VATPercent, xlsx.Percent), xlsx.Cents(p.VATCents()),
+			xlsx.Formula(fmt.Sprintf("H%d+J%d", row, row), xlsx.Amount), xlsx.Text(p.Currency), original)
+	}
+
+	return []*xlsx.Sheet{summary, payments, spent, list}, nil
+}
+
+// totalRow adds a bold "Total" row summing the given columns (0 is A) of
+// the rows below the header, in ascending order
+func totalRow(s *xlsx.Sheet, cols ...int) {
+	last := len(s.Rows)
+	row := make([]xlsx.Cell, cols[len(cols)-1]+1)
+	row[0] = xlsx.Text("Total")
+	row[0].Style = xlsx.Bold
+	for _, c := range cols {
+		if last < 2 { // no rows to sum
+			row[c] = xlsx.Number(0, xlsx.BoldAmount)
+			continue
+		}
+		row[c] = xlsx.Formula(fmt.Sprintf("SUM(%s:%s)", xlsx.Ref(c, 2), xlsx.Ref(c, last)), xlsx.BoldAmount)
+	}
+	s.Add(row...)
+}
@@ -0,0 +1,122 @@
+// ical/ical.go - iCalendar (RFC 5545) output for meeting invites and feeds
+package ical
+
+import (
+	"strings"
+	"time"
+)
+
+// Method says what a calendar is for: METHOD:REQUEST makes mail clients
+// offer to accept an invite, PUBLISH is a plain calendar
+const (
+	MethodRequest = "REQUEST"
+	MethodPublish = "PUBLISH"
+)
+
+// stampLayout is a UTC date-time in iCalendar form, dateLayout a date
+const (
+	stampLayout = "20060102T150405Z"
+	dateLayout  = "20060102"
+)
+
+// Event is a single calendar entry
+type Event struct {
+	UID         string // stable across updates, so a resent invite replaces the first
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	Organizer   string   // email address
+	Attendees   []string // email addresses
+	AllDay      bool     // Start's date only; End is ignored
+	RRule       string   // a recurrence rule such as "FREQ=MONTHLY", or ""
+	URL         string
+}
+
+// Calendar renders events as an iCalendar object with the given method
+func Calendar(method string, events ...Event) []byte {
+	return render(method, "", events)
+}
+
+// Feed renders events as a named calendar to subscribe to
+func Feed(name string, events ...Event) []byte {
+	return render(MethodPublish, name, events)
+}
+
+// render writes the calendar; a name is shown by calendar apps subscribing
+func render(method, name string, events []Event) []byte {
+	var b strings.Builder
+	line := func(name, value string) {
+		b.WriteString(fold(name + ":" + value))
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//FullDash//FullDash//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", method)
+	if name != "" {
+		line("X-WR-CALNAME", escape(name))
+	}
+	stamp := time.Now().UTC().Format(stampLayout)
+	for _, e := range events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", stamp)
+		if e.AllDay {
+			line("DTSTART;VALUE=DATE", e.Start.Format(dateLayout))
+			line("DTEND;VALUE=DATE", e.Start.AddDate(0, 0, 1).Format(dateLayout))
+		} else {
+			line("DTSTART", e.Start.UTC().Format(stampLayout))
+			line("DTEND", e.End.UTC().Format(stampLayout))
+		}
+		if e.RRule != "" {
+			line("RRULE", e.RRule)
+		}
+		line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escape(e.Description))
+		}
+		if e.Location != "" {
+			line("LOCATION", escape(e.Location))
+		}
+		if e.URL != "" {
+			line("URL", e.URL)
+		}
+		if e.Organizer != "" {
+			line("ORGANIZER", "mailto:"+e.Organizer)
+		}
+		for _, a := range e.Attendees {
+			b.WriteString(fold("ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:" + a))
+		}
+		line("STATUS", "CONFIRMED")
+		line("SEQUENCE", "0")
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return []byte(b.String())
+}
+
+// escape quotes text values: backslashes, semicolons, commas and newlines
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// fold ends a content line with CRLF, breaking it so no line exceeds 75
+// octets; continuation lines start with a space. Breaks fall between runes.
+func fold(s string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > 75 {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
@@ -0,0 +1,217 @@
+// importer/importer.go - Reads boards to import (a CSV of projects or a Trello export) as a table
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MaxRows caps the rows one import may hold
+const MaxRows = 5000
+
+// ErrEmpty means the file had a header but no rows, or nothing at all
+var ErrEmpty = errors.New("the file has no rows to import")
+
+// Table is an import's rows under its column names. Every row has a cell
+// per column.
+type Table struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// Values lists the distinct non-empty values in column i, in first-seen order
+func (t Table) Values(i int) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, row := range t.Rows {
+		if v := row[i]; v != "" && !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Read reads a file by its name: .json as a Trello board export, anything
+// else as CSV
+func Read(name string, data []byte) (Table, error) {
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		return ReadTrello(data)
+	}
+	return ReadCSV(data)
+}
+
+// ReadCSV reads a CSV whose first row names the columns. The separator is
+// a comma or, as Excel writes it in Swedish, a semicolon, whichever the
+// header has more of; a UTF-8 byte order mark is dropped.
+func ReadCSV(data []byte) (Table, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+
+	r := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		r.Comma = ';'
+	}
+	r.FieldsPerRecord = -1 // short rows are padded below
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return Table{}, fmt.Errorf("reading the CSV: %w", err)
+	}
+	if len(records) < 2 {
+		return Table{}, ErrEmpty
+	}
+
+	t := Table{Columns: make([]string, len(records[0]))}
+	for i, c := range records[0] {
+		if t.Columns[i] = strings.TrimSpace(c); t.Columns[i] == "" {
+			t.Columns[i] = fmt.Sprintf("Column %d", i+1)
+		}
+	}
+	for _, rec := range records[1:] {
+		if blank(rec) {
+			continue
+		}
+		row := make([]string, len(t.Columns))
+		for i := range row {
+			if i < len(rec) {
+				row[i] = strings.TrimSpace(rec[i])
+			}
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t.check()
+}
+
+// blank reports whether a record has no values, as spreadsheets end files
+func blank(rec []string) bool {
+	for _, v := range rec {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// trelloBoard is the part of Trello's board export (Menu > Print, export
+// and share > Export as JSON) the import reads
+type trelloBoard struct {
+	Name  string `json:"name"`
+	Lists []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Members []struct {
+		ID       string `json:"id"`
+		FullName string `json:"fullName"`
+		Username string `json:"username"`
+	} `json:"members"`
+	CustomFields []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"customFields"`
+	Cards []struct {
+		Name      string     `json:"name"`
+		Desc      string     `json:"desc"`
+		IDList    string     `json:"idList"`
+		Closed    bool       `json:"closed"`
+		Due       *time.Time `json:"due"`
+		IDMembers []string   `json:"idMembers"`
+		Labels    []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"labels"`
+		CustomFieldItems []struct {
+			IDCustomField string            `json:"idCustomField"`
+			Value         map[string]string `json:"value"`
+		} `json:"customFieldItems"`
+	} `json:"cards"`
+}
+
+// ReadTrello reads a Trello board export: a row per open card with the
+// columns Name, Description, List, Labels, Due, Members and one for each
+// custom field. Archived cards, and cards on archived lists, are left out.
+func ReadTrello(data []byte) (Table, error) {
+	var b trelloBoard
+	if err := json.Unmarshal(data, &b); err != nil {
+		return Table{}, fmt.Errorf("reading the Trello export: %w", err)
+	}
+	if b.Cards == nil && b.Lists == nil {
+		return Table{}, errors.New("not a Trello board export: it has no cards or lists")
+	}
+
+	lists := map[string]string{}
+	archived := map[string]bool{}
+	for _, l := range b.Lists {
+		lists[l.ID] = l.Name
+		archived[l.ID] = l.Closed
+	}
+	members := map[string]string{}
+	for _, m := range b.Members {
+		members[m.ID] = m.FullName
+		if m.FullName == "" {
+			members[m.ID] = m.Username
+		}
+	}
+	fields := map[string]int{}
+	t := Table{Columns: []string{"Name", "Description", "List", "Labels", "Due", "Members"}}
+	for _, f := range b.CustomFields {
+		fields[f.ID] = len(t.Columns)
+		t.Columns = append(t.Columns, f.Name)
+	}
+
+	for _, c := range b.Cards {
+		if c.Closed || archived[c.IDList] {
+			continue
+		}
+		row := make([]string, len(t.Columns))
+		row[0], row[1], row[2] = strings.TrimSpace(c.Name), strings.TrimSpace(c.Desc), lists[c.IDList]
+		var labels, names []string
+		for _, l := range c.Labels {
+			if l.Name == "" {
+				l.Name = l.Color
+			}
+			labels = append(labels, l.Name)
+		}
+		row[3] = strings.Join(labels, ", ")
+		if c.Due != nil {
+			row[4] = c.Due.Format(time.DateOnly)
+		}
+		for _, id := range c.IDMembers {
+			if name := members[id]; name != "" {
+				names = append(names, name)
+			}
+		}
+		row[5] = strings.Join(names, ", ")
+		for _, item := range c.CustomFieldItems {
+			i, ok := fields[item.IDCustomField]
+			if !ok {
+				continue
+			}
+			// a value is keyed by its type: text, number, date or checked
+			for _, v := range item.Value {
+				row[i] = v
+			}
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t.check()
+}
+
+// check rejects a table with no rows or too many
+func (t Table) check() (Table, error) {
+	switch {
+	case len(t.Rows) == 0:
+		return Table{}, ErrEmpty
+	case len(t.Rows) > MaxRows:
+		return Table{}, fmt.Errorf("the file has %d rows; an import can hold at most %d", len(t.Rows), MaxRows)
+	}
+	return t, nil
+}
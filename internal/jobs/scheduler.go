@@ -0,0 +1,177 @@
+// jobs/scheduler.go - Minimal in-process periodic job scheduler
+package jobs
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Func is the work performed by a scheduled job
+type Func func(ctx context.Context) error
+
+// Status reports the outcome of a job's most recent run
+type Status struct {
+	Name     string
+	Interval time.Duration
+	LastRun  time.Time
+	LastErr  string
+	Runs     int
+	Skipped  int // scheduled runs left to another instance, or paused
+}
+
+// Locker grants expiring, named leases shared by every instance using the
+// same database. A lease held by someone else is refused until it expires.
+type Locker interface {
+	AcquireLease(name, holder string, ttl time.Duration) (bool, error)
+}
+
+type job struct {
+	name     string
+	interval time.Duration
+	fn       Func
+}
+
+// Scheduler runs registered jobs on fixed intervals
+type Scheduler struct {
+	mu     sync.RWMutex
+	jobs   []job
+	status map[string]*Status
+
+	locker Locker
+	holder string
+	paused func() bool
+}
+
+// New creates an empty scheduler
+func New() *Scheduler {
+	return &Scheduler{status: make(map[string]*Status)}
+}
+
+// Every registers a job that runs at startup and then once per interval
+func (s *Scheduler) Every(name string, interval time.Duration, fn Func) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, job{name: name, interval: interval, fn: fn})
+	s.status[name] = &Status{Name: name, Interval: interval}
+}
+
+// UseLocker makes scheduled runs take a lease per job and interval, so with
+// several instances sharing a database each interval runs only once.
+// Manual runs (RunNow) don't take the lease.
+func (s *Scheduler) UseLocker(l Locker, holder string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locker, s.holder = l, holder
+}
+
+// PauseWhen skips scheduled runs while paused returns true, as on a
+// read-only replica where the jobs' writes would fail. Manual runs still go.
+func (s *Scheduler) PauseWhen(paused func() bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = paused
+}
+
+// Start launches all registered jobs; they stop when ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, j := range s.jobs {
+		go s.loop(ctx, j)
+	}
+}
+
+// RunNow executes a registered job immediately (e.g. from an admin action)
+func (s *Scheduler) RunNow(ctx context.Context, name string) bool {
+	s.mu.RLock()
+	var found *job
+	for i := range s.jobs {
+		if s.jobs[i].name == name {
+			found = &s.jobs[i]
+			break
+		}
+	}
+	s.mu.RUnlock()
+
+	if found == nil {
+		return false
+	}
+	s.run(ctx, *found)
+	return true
+}
+
+// Statuses returns a snapshot of all job statuses sorted by name
+func (s *Scheduler) Statuses() []Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]Status, 0, len(s.status))
+	for _, st := range s.status {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (s *Scheduler) loop(ctx context.Context, j job) {
+	s.tick(ctx, j)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx, j)
+		}
+	}
+}
+
+// tick runs a scheduled job unless the scheduler is paused or another
+// instance holds its lease. The lease isn't released after the run: it
+// lasts the whole interval.
+func (s *Scheduler) tick(ctx context.Context, j job) {
+	s.mu.RLock()
+	locker, holder, paused := s.locker, s.holder, s.paused
+	s.mu.RUnlock()
+
+	if paused != nil && paused() {
+		s.mu.Lock()
+		s.status[j.name].Skipped++
+		s.mu.Unlock()
+		return
+	}
+	if locker != nil {
+		ok, err := locker.AcquireLease("job:"+j.name, holder, j.interval)
+		if err != nil {
+			log.Printf("[JOBS] %s lease failed: %v", j.name, err)
+			return
+		}
+		if !ok {
+			s.mu.Lock()
+			s.status[j.name].Skipped++
+			s.mu.Unlock()
+			return
+		}
+	}
+	s.run(ctx, j)
+}
+
+func (s *Scheduler) run(ctx context.Context, j job) {
+	err := j.fn(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.status[j.name]
+	st.LastRun = time.Now()
+	st.Runs++
+	st.LastErr = ""
+	if err != nil {
+		st.LastErr = err.Error()
+		log.Printf("[JOBS] %s failed: %v", j.name, err)
+	}
+}
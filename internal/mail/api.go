@@ -0,0 +1,161 @@
+// mail/api.go - Email APIs: Resend and SendGrid
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Default API endpoints
+const (
+	DefaultResendURL   = "https://api.resend.com/emails"
+	DefaultSendGridURL = "https://api.sendgrid.com/v3/mail/send"
+)
+
+// Resend sends through Resend's API with an API key (MAIL_API_KEY)
+type Resend struct {
+	URL  string
+	Key  string
+	From string
+	HTTP *http.Client
+}
+
+// resendAttachment is an attachment as Resend takes it; Content is
+// base64-encoded by encoding/json
+type resendAttachment struct {
+	Filename    string `json:"filename"`
+	Content     []byte `json:"content"`
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// Name identifies Resend in the send log
+func (r *Resend) Name() string { return ProviderResend }
+
+// Enabled reports whether an API key is set
+func (r *Resend) Enabled() bool { return r.Key != "" }
+
+// Address is the sender address
+func (r *Resend) Address() string { return r.From }
+
+// Send posts m to Resend
+func (r *Resend) Send(ctx context.Context, m Message) error {
+	if !r.Enabled() {
+		return ErrNotConfigured
+	}
+	from, err := checkMessage(r.From, m)
+	if err != nil {
+		return err
+	}
+
+	attachments := make([]resendAttachment, len(m.Attachments))
+	for i, a := range m.Attachments {
+		attachments[i] = resendAttachment{Filename: a.Name, Content: a.Data, ContentType: a.ContentType}
+	}
+	return postAPI(ctx, r.HTTP, r.URL, r.Key, map[string]any{
+		"from":        from.String(),
+		"to":          m.To,
+		"subject":     m.Subject,
+		"text":        m.Body,
+		"attachments": attachments,
+	})
+}
+
+// SendGrid sends through SendGrid's v3 mail API with an API key
+// (MAIL_API_KEY)
+type SendGrid struct {
+	URL  string
+	Key  string
+	From string
+	HTTP *http.Client
+}
+
+// sendGridAddress is an address as SendGrid takes it
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+// sendGridAttachment is an attachment as SendGrid takes it; Content is
+// base64-encoded by encoding/json
+type sendGridAttachment struct {
+	Content     []byte `json:"content"`
+	Filename    string `json:"filename"`
+	Type        string `json:"type,omitempty"`
+	Disposition string `json:"disposition"`
+}
+
+// Name identifies SendGrid in the send log
+func (s *SendGrid) Name() string { return ProviderSendGrid }
+
+// Enabled reports whether an API key is set
+func (s *SendGrid) Enabled() bool { return s.Key != "" }
+
+// Address is the sender address
+func (s *SendGrid) Address() string { return s.From }
+
+// Send posts m to SendGrid
+func (s *SendGrid) Send(ctx context.Context, m Message) error {
+	if !s.Enabled() {
+		return ErrNotConfigured
+	}
+	from, err := checkMessage(s.From, m)
+	if err != nil {
+		return err
+	}
+
+	to := make([]sendGridAddress, len(m.To))
+	for i, addr := range m.To {
+		to[i] = sendGridAddress{Email: addr}
+	}
+	attachments := make([]sendGridAttachment, len(m.Attachments))
+	for i, a := range m.Attachments {
+		attachments[i] = sendGridAttachment{Content: a.Data, Filename: a.Name, Type: a.ContentType, Disposition: "attachment"}
+	}
+	body := map[string]any{
+		"personalizations": []any{map[string]any{"to": to}},
+		"from":             sendGridAddress{Email: from.Address, Name: from.Name},
+		"subject":          m.Subject,
+		"content":          []any{map[string]string{"type": "text/plain", "value": m.Body}},
+	}
+	if len(attachments) > 0 {
+		body["attachments"] = attachments
+	}
+	return postAPI(ctx, s.HTTP, s.URL, s.Key, body)
+}
+
+// postAPI POSTs v as JSON with a bearer key. A 4xx answer other than 429
+// (rate limited) is permanent: the API refused the message or the key.
+func postAPI(ctx context.Context, client *http.Client, url, key string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil
+	}
+
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	err = fmt.Errorf("status %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return &PermanentError{err}
+	}
+	return err
+}
@@ -0,0 +1,226 @@
+// mail/mail.go - Outgoing email through an SMTP relay or an email API
+package mail
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Provider names, as set in MAIL_PROVIDER
+const (
+	ProviderSMTP     = "smtp"
+	ProviderResend   = "resend"
+	ProviderSendGrid = "sendgrid"
+)
+
+// requestTimeout bounds a single call to an email API
+const requestTimeout = 30 * time.Second
+
+// ErrNotConfigured is returned by Send when no SMTP host or API key is set
+var ErrNotConfigured = errors.New("email isn't configured (set SMTP_HOST, or MAIL_PROVIDER and MAIL_API_KEY)")
+
+// ErrUnknownProvider means MAIL_PROVIDER names no provider this build has
+var ErrUnknownProvider = errors.New("unknown mail provider")
+
+// Sender delivers messages
+type Sender interface {
+	// Name identifies the provider in the send log
+	Name() string
+	// Enabled reports whether the sender is configured to send anything
+	Enabled() bool
+	// Address is the sender address, e.g. "FullDash <billing@example.com>"
+	Address() string
+	// Send delivers m to every recipient. Errors retrying won't fix are
+	// PermanentErrors.
+	Send(ctx context.Context, m Message) error
+}
+
+// New returns the named provider's sender: the SMTP relay for "" or
+// "smtp", otherwise an email API reached at apiURL ("" for its default)
+// with key, sending as relay.From
+func New(name, apiURL, key string, relay SMTP) (Sender, error) {
+	client := &http.Client{Timeout: requestTimeout}
+	switch name {
+	case "", ProviderSMTP:
+		return relay, nil
+	case ProviderResend:
+		return &Resend{URL: cmp.Or(apiURL, DefaultResendURL), Key: key, From: relay.From, HTTP: client}, nil
+	case ProviderSendGrid:
+		return &SendGrid{URL: cmp.Or(apiURL, DefaultSendGridURL), Key: key, From: relay.From, HTTP: client}, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownProvider, name)
+}
+
+// PermanentError is a failure retrying won't fix: the relay or API refused
+// the message, its addresses or the credentials
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Permanent reports whether err is not worth retrying
+func Permanent(err error) bool {
+	var p *PermanentError
+	return errors.Is(err, ErrNotConfigured) || errors.As(err, &p)
+}
+
+// SMTP sends mail through a relay. Without User it sends unauthenticated;
+// STARTTLS is used whenever the server offers it.
+type SMTP struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	From     string // sender address, e.g. "FullDash <billing@example.com>"
+}
+
+// Name identifies SMTP in the send log
+func (s SMTP) Name() string { return ProviderSMTP }
+
+// Enabled reports whether a relay is configured
+func (s SMTP) Enabled() bool {
+	return s.Host != ""
+}
+
+// Address is the sender address
+func (s SMTP) Address() string { return s.From }
+
+// Attachment is a file sent along with a message
+type Attachment struct {
+	Name        string
+	ContentType string // full MIME type, parameters included
+	Data        []byte
+}
+
+// Message is a plain-text email with optional attachments
+type Message struct {
+	To          []string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Send delivers m to every recipient. A 5xx reply from the relay is
+// permanent; anything else (unreachable, 4xx) may pass.
+func (s SMTP) Send(ctx context.Context, m Message) error {
+	if !s.Enabled() {
+		return ErrNotConfigured
+	}
+	from, err := checkMessage(s.From, m)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.User != "" {
+		auth = smtp.PlainAuth("", s.User, s.Password, s.Host)
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	err = smtp.SendMail(addr, auth, from.Address, m.To, compose(from.String(), m))
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return &PermanentError{err}
+	}
+	return err
+}
+
+// checkMessage parses the sender address and checks m has recipients
+func checkMessage(sender string, m Message) (*mail.Address, error) {
+	from, err := mail.ParseAddress(sender)
+	if err != nil {
+		return nil, &PermanentError{fmt.Errorf("invalid MAIL_FROM %q: %w", sender, err)}
+	}
+	if len(m.To) == 0 {
+		return nil, &PermanentError{errors.New("no recipients")}
+	}
+	return from, nil
+}
+
+// compose renders m as a MIME message: just the text body, or the body and
+// its attachments as multipart/mixed
+func compose(from string, m Message) []byte {
+	var b bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+	}
+	header("From", from)
+	header("To", strings.Join(m.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	if len(m.Attachments) == 0 {
+		header("Content-Type", `text/plain; charset="utf-8"`)
+		header("Content-Transfer-Encoding", "base64")
+		b.WriteString("\r\n")
+		writeBase64(&b, []byte(m.Body))
+		return b.Bytes()
+	}
+
+	boundary := newBoundary()
+	header("Content-Type", `multipart/mixed; boundary="`+boundary+`"`)
+	b.WriteString("\r\n")
+
+	fmt.Fprintf(&b, "--%s\r\n", boundary)
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "base64")
+	b.WriteString("\r\n")
+	writeBase64(&b, []byte(m.Body))
+	for _, a := range m.Attachments {
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		header("Content-Type", a.ContentType)
+		header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+		header("Content-Transfer-Encoding", "base64")
+		b.WriteString("\r\n")
+		writeBase64(&b, a.Data)
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return b.Bytes()
+}
+
+// writeBase64 writes data base64 encoded in 76-character lines
+func writeBase64(b *bytes.Buffer, data []byte) {
+	s := base64.StdEncoding.EncodeToString(data)
+	for len(s) > 76 {
+		b.WriteString(s[:76] + "\r\n")
+		s = s[76:]
+	}
+	b.WriteString(s + "\r\n")
+}
+
+// newBoundary returns a random MIME boundary
+func newBoundary() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return "fulldash-" + base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// ParseAddresses splits a comma-separated list of email addresses, returning
+// the bare addresses
+func ParseAddresses(list string) ([]string, error) {
+	parsed, err := mail.ParseAddressList(list)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, len(parsed))
+	for i, a := range parsed {
+		addrs[i] = a.Address
+	}
+	return addrs, nil
+}
@@ -0,0 +1,69 @@
+// maintenance/maintenance.go - The lock a restore holds while it swaps the database file
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrLocked is returned when another maintenance already holds the lock
+var ErrLocked = errors.New("maintenance already in progress")
+
+// Servers check the lock every PollInterval; a restore waits Grace after
+// taking it, so they have stopped using the database before the swap
+const (
+	PollInterval = time.Second
+	Grace        = 3 * time.Second
+)
+
+// Lock is a file beside the database. While it exists servers answer 503,
+// pause their jobs and close their idle connections.
+type Lock struct {
+	Path string
+}
+
+// For returns the lock for the database at dbPath
+func For(dbPath string) Lock {
+	return Lock{Path: dbPath + ".maintenance"}
+}
+
+// Acquire takes the lock, recording why and by which process
+func (l Lock) Acquire(reason string) error {
+	f, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%w (%s); remove %s if it was left behind", ErrLocked, l.Reason(), l.Path)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(f, "%s (pid %d, since %s)\n", reason, os.Getpid(), time.Now().Format(time.RFC3339)); err != nil {
+		f.Close()
+		os.Remove(l.Path)
+		return err
+	}
+	return f.Close()
+}
+
+// Release lets servers back onto the database
+func (l Lock) Release() error {
+	return os.Remove(l.Path)
+}
+
+// Held reports whether a maintenance is in progress
+func (l Lock) Held() bool {
+	_, err := os.Stat(l.Path)
+	return err == nil
+}
+
+// Reason returns what the lock was taken for, or ""
+func (l Lock) Reason() string {
+	b, err := os.ReadFile(l.Path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
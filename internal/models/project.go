@@ -1,64 +1,3184 @@
 package models
 
-import "time"
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
 
-// Owner represents who secured the project
-type Owner string
+// Person is a partner: someone who secures projects, logs hours, pays
+// expenses and takes a share of revenue. People are made inactive rather than
+// deleted, so past splits and history keep their names.
+type Person struct {
+	ID        int64     `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Color     string    `json:"color" db:"color"`       // #rrggbb
+	Position  int       `json:"position" db:"position"` // display order
+	Active    bool      `json:"active" db:"active"`     // offered in forms
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
 
-const (
-	OwnerNoor  Owner = "noor"
-	OwnerAhmad Owner = "ahmad"
-	OwnerBoth  Owner = "both"
-)
+// ValidColor reports whether Color is a #rrggbb hex color
+func (p Person) ValidColor() bool {
+	return validColor(p.Color)
+}
+
+// People is the partnership in display order, inactive people included
+type People []Person
+
+// Get returns the person with the given ID
+func (ps People) Get(id int64) (Person, bool) {
+	for _, p := range ps {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return Person{}, false
+}
+
+// Name is the person's name, or a placeholder for an unknown ID
+func (ps People) Name(id int64) string {
+	if p, ok := ps.Get(id); ok {
+		return p.Name
+	}
+	return fmt.Sprintf("Person %d", id)
+}
+
+// Names joins the names of ids in display order: "Noor", "Noor & Ahmad",
+// "Noor, Ahmad & Sara"
+func (ps People) Names(ids []int64) string {
+	var names []string
+	for _, p := range ps {
+		if slices.Contains(ids, p.ID) {
+			names = append(names, p.Name)
+		}
+	}
+	for _, id := range ids {
+		if _, ok := ps.Get(id); !ok {
+			names = append(names, ps.Name(id))
+		}
+	}
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " & " + names[len(names)-1]
+}
+
+// Active returns the people offered in forms
+func (ps People) Active() People {
+	var out People
+	for _, p := range ps {
+		if p.Active {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Valid reports whether every ID is a known person, and there is at least one
+func (ps People) Valid(ids []int64) bool {
+	if len(ids) == 0 {
+		return false
+	}
+	for _, id := range ids {
+		if _, ok := ps.Get(id); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Percents shows percentages in display order, skipping zeros:
+// "Noor 70% / Ahmad 30%", or "" if there are none
+func (ps People) Percents(m PerPerson[float64]) string {
+	var parts []string
+	for _, p := range ps {
+		if m[p.ID] != 0 {
+			parts = append(parts, p.Name+" "+strconv.FormatFloat(m[p.ID], 'f', -1, 64)+"%")
+		}
+	}
+	return strings.Join(parts, " / ")
+}
+
+// Hours shows hours in display order, skipping zeros: "Noor 20 h / Ahmad
+// 12.5 h", or "" if there are none
+func (ps People) Hours(m PerPerson[float64]) string {
+	var parts []string
+	for _, p := range ps {
+		if m[p.ID] != 0 {
+			parts = append(parts, p.Name+" "+strconv.FormatFloat(m[p.ID], 'f', -1, 64)+" h")
+		}
+	}
+	return strings.Join(parts, " / ")
+}
+
+// PerPerson maps person IDs to an amount: cents, hours or a percentage
+type PerPerson[V int64 | float64] map[int64]V
+
+// Total sums the amounts
+func (m PerPerson[V]) Total() V {
+	var t V
+	for _, v := range m {
+		t += v
+	}
+	return t
+}
+
+// Add adds o's amounts to m's; m must not be nil
+func (m PerPerson[V]) Add(o PerPerson[V]) {
+	for id, v := range o {
+		m[id] += v
+	}
+}
+
+// Sub subtracts o's amounts from m's; m must not be nil
+func (m PerPerson[V]) Sub(o PerPerson[V]) {
+	for id, v := range o {
+		m[id] -= v
+	}
+}
 
 // ProjectStatus represents the current state
 type ProjectStatus string
 
 const (
-	StatusNew       ProjectStatus = "new"
-	StatusProgress  ProjectStatus = "in_progress"
-	StatusDone      ProjectStatus = "done"
-	StatusPaid      ProjectStatus = "paid"
+	StatusNew      ProjectStatus = "new"
+	StatusProgress ProjectStatus = "in_progress"
+	StatusDone     ProjectStatus = "done"
+	StatusPaid     ProjectStatus = "paid"
+	StatusLost     ProjectStatus = "lost"
 )
 
+// BuiltinStatuses are the statuses the code relies on (checklist gates,
+// revenue, the client portal, the win rate). They can be relabelled and
+// reordered but not removed; custom statuses from the statuses table sit
+// alongside them.
+var BuiltinStatuses = []ProjectStatus{StatusNew, StatusProgress, StatusDone, StatusPaid, StatusLost}
+
+// Status is a kanban column a project can be in
+type Status struct {
+	Key      ProjectStatus `json:"key" db:"key"`
+	Label    string        `json:"label" db:"label"`
+	Color    string        `json:"color" db:"color"`             // #rrggbb
+	Position int           `json:"position" db:"position"`       // board order
+	Terminal bool          `json:"is_terminal" db:"is_terminal"` // final; leaving it needs a refund
+
+	// Probability is the chance in percent that a project in this column
+	// gets paid, weighing the forecast; a project may set its own
+	Probability int `json:"probability" db:"probability"`
+}
+
+// ValidColor reports whether Color is a #rrggbb hex color
+func (s Status) ValidColor() bool {
+	return validColor(s.Color)
+}
+
+// validColor reports whether c is a #rrggbb hex color
+func validColor(c string) bool {
+	if len(c) != 7 || c[0] != '#' {
+		return false
+	}
+	_, err := strconv.ParseUint(c[1:], 16, 32)
+	return err == nil
+}
+
+// Builtin reports whether s is one of BuiltinStatuses
+func (s Status) Builtin() bool {
+	return slices.Contains(BuiltinStatuses, s.Key)
+}
+
 // Project is the main entity
 type Project struct {
 	ID              int64         `json:"id" db:"id"`
 	Client          string        `json:"client" db:"client"`
 	Description     string        `json:"description" db:"description"`
-	Revenue         float64       `json:"revenue" db:"revenue"`
+	RevenueCents    int64         `json:"revenue_cents" db:"revenue_cents"`
 	Status          ProjectStatus `json:"status" db:"status"`
-	SecuredBy       Owner         `json:"secured_by" db:"secured_by"`
+	SecuredBy       []int64       `json:"secured_by" db:"secured_by"` // person IDs, one or more
 	StripePaymentID string        `json:"stripe_payment_id" db:"stripe_payment_id"`
 	CreatedAt       time.Time     `json:"created_at" db:"created_at"`
+	PaidAt          *time.Time    `json:"paid_at,omitempty" db:"paid_at"`             // set when the project moves to paid
+	ArchivedAt      *time.Time    `json:"archived_at,omitempty" db:"archived_at"`     // off the board, still in metrics
+	PendingSince    *time.Time    `json:"pending_since,omitempty" db:"pending_since"` // entered its status; kept on the move to paid
+
+	// Currency and OriginalCents are set for projects quoted in a foreign
+	// currency; RevenueCents then holds the amount converted when it was entered
+	Currency      string `json:"currency,omitempty" db:"currency"`
+	OriginalCents int64  `json:"original_cents,omitempty" db:"original_cents"`
+
+	// RevenueCents excludes VAT, charged on top at VATPercent. VATInclusive
+	// records that the price was agreed including VAT: the form takes and
+	// shows it that way, and revenue holds what's left once VAT is taken out.
+	VATPercent   float64 `json:"vat_percent" db:"vat_percent"`
+	VATInclusive bool    `json:"vat_inclusive" db:"vat_inclusive"`
+
+	// SplitOverride fixes each person's percentage of the profit for a deal
+	// agreed that way (adding up to 100). When set it beats hours, who
+	// secured the project and the agreement's fixed split.
+	SplitOverride PerPerson[float64] `json:"split_override,omitempty" db:"split_override"`
+
+	// Probability (percent) overrides the status's chance of payment in the
+	// forecast; nil follows the status. ExpectedOn is when payment is expected.
+	Probability *int       `json:"probability,omitempty" db:"probability"`
+	ExpectedOn  *time.Time `json:"expected_on,omitempty" db:"expected_on"`
+
+	// LostReason says why a lost project fell through; required to move a
+	// project to lost and cleared when it's reopened
+	LostReason string `json:"lost_reason,omitempty" db:"lost_reason"`
+
+	// Source is the channel the lead came through; empty when not recorded
+	Source LeadSource `json:"source,omitempty" db:"source"`
+
+	// PaymentDays are the payment terms: the client pays this many days
+	// after the project enters the status it's paid from. Invoices made
+	// from the project start with them.
+	PaymentDays int `json:"payment_days" db:"payment_days"`
+
+	// EstimatedHours is how long the work was expected to take (0 when not
+	// estimated); HourEstimates optionally breaks it down per person.
+	// LoggedHours is read-only: the hours logged on the project so far.
+	EstimatedHours float64            `json:"estimated_hours,omitempty" db:"estimated_hours"`
+	HourEstimates  PerPerson[float64] `json:"hour_estimates,omitempty" db:"hour_estimates"`
+	LoggedHours    float64            `json:"logged_hours" db:"-"`
+
+	// BudgetHours or BudgetCents caps an hourly-billed project, in hours or
+	// as an amount excluding VAT that each hour logged burns HourlyCents of;
+	// both are 0 without a budget. BudgetWarned is the highest warning
+	// threshold (percent) the burn has reached, kept as hours are logged.
+	BudgetHours  float64 `json:"budget_hours,omitempty" db:"budget_hours"`
+	BudgetCents  int64   `json:"budget_cents,omitempty" db:"budget_cents"`
+	HourlyCents  int64   `json:"hourly_cents,omitempty" db:"hourly_cents"`
+	BudgetWarned int     `json:"budget_warned,omitempty" db:"budget_warned"`
+
+	// Tasks are the project's subtasks in order, read with it for the card
+	Tasks Tasks `json:"tasks,omitempty" db:"-"`
+}
+
+// EstimateUsed is the part of the estimate logged so far, in percent; 0
+// when the project isn't estimated
+func (p Project) EstimateUsed() float64 {
+	if p.EstimatedHours <= 0 {
+		return 0
+	}
+	return p.LoggedHours / p.EstimatedHours * 100
+}
+
+// OverEstimate reports whether more hours were logged than estimated
+func (p Project) OverEstimate() bool {
+	return p.EstimatedHours > 0 && p.LoggedHours > p.EstimatedHours
+}
+
+// HasBudget reports whether the project has a budget to burn
+func (p Project) HasBudget() bool {
+	return p.BudgetHours > 0 || p.BudgetCents > 0
+}
+
+// BurnCents is what the hours logged so far come to at the hourly rate
+func (p Project) BurnCents() int64 {
+	return int64(math.Round(p.LoggedHours * float64(p.HourlyCents)))
+}
+
+// BudgetUsed is the part of the budget burned so far, in percent; 0 without
+// a budget
+func (p Project) BudgetUsed() float64 {
+	switch {
+	case p.BudgetHours > 0:
+		return p.LoggedHours / p.BudgetHours * 100
+	case p.BudgetCents > 0:
+		return float64(p.BurnCents()) / float64(p.BudgetCents) * 100
+	}
+	return 0
+}
+
+// OverBudget reports whether more than the budget is burned
+func (p Project) OverBudget() bool {
+	return p.HasBudget() && p.BudgetUsed() > 100
+}
+
+// BudgetReached is the highest of the thresholds (percent, ascending) the
+// budget's burn has reached, or 0 below all of them
+func (p Project) BudgetReached(thresholds []int) int {
+	reached := 0
+	if !p.HasBudget() {
+		return reached
+	}
+	used := p.BudgetUsed()
+	for _, t := range thresholds {
+		if used >= float64(t) {
+			reached = t
+		}
+	}
+	return reached
+}
+
+// DefaultBudgetThresholds are the percentages of a budget burned that warn
+// until others are set
+var DefaultBudgetThresholds = []int{80, 100}
+
+// LeadSource is the channel a project's lead came through
+type LeadSource string
+
+const (
+	SourceReferral LeadSource = "referral"
+	SourceWebsite  LeadSource = "website"
+	SourceOutreach LeadSource = "cold_outreach"
+	SourceRepeat   LeadSource = "repeat_client"
+)
+
+// LeadSources lists the sources in display order
+var LeadSources = []LeadSource{SourceReferral, SourceWebsite, SourceOutreach, SourceRepeat}
+
+// Valid reports whether s is a known source or left empty
+func (s LeadSource) Valid() bool {
+	return s == "" || slices.Contains(LeadSources, s)
+}
+
+// Label is the source's display name
+func (s LeadSource) Label() string {
+	switch s {
+	case SourceReferral:
+		return "Referral"
+	case SourceWebsite:
+		return "Website"
+	case SourceOutreach:
+		return "Cold outreach"
+	case SourceRepeat:
+		return "Repeat client"
+	case "":
+		return "Not recorded"
+	}
+	return string(s)
+}
+
+// Archived reports whether the project has been archived off the board
+func (p Project) Archived() bool {
+	return p.ArchivedAt != nil
+}
+
+// PaymentTime is when the project was paid, falling back to its creation for
+// projects that aren't paid (yet)
+func (p Project) PaymentTime() time.Time {
+	if p.PaidAt != nil {
+		return *p.PaidAt
+	}
+	return p.CreatedAt
+}
+
+// WaitingSince is when the project entered the status it was paid from (or
+// is in now), falling back to its creation where that wasn't recorded
+func (p Project) WaitingSince() time.Time {
+	if p.PendingSince != nil {
+		return *p.PendingSince
+	}
+	return p.CreatedAt
+}
+
+// Foreign reports whether the project was quoted in a foreign currency
+func (p Project) Foreign() bool {
+	return p.Currency != ""
+}
+
+// VATCents is the output VAT on the project's payment
+func (p Project) VATCents() int64 {
+	return VATOn(p.RevenueCents, p.VATPercent)
+}
+
+// GrossCents is what the client pays: revenue plus VAT
+func (p Project) GrossCents() int64 {
+	return p.RevenueCents + p.VATCents()
+}
+
+// QuotedGrossCents is what the client pays in the currency the project was
+// quoted in: the original amount plus VAT, or GrossCents in kronor
+func (p Project) QuotedGrossCents() int64 {
+	if !p.Foreign() {
+		return p.GrossCents()
+	}
+	return p.OriginalCents + VATOn(p.OriginalCents, p.VATPercent)
+}
+
+// AtBookedRate converts cents of the project's foreign currency to kronor at
+// the rate its revenue was converted at, so paying the quoted amount comes
+// to exactly the revenue; 0 for a project in kronor
+func (p Project) AtBookedRate(cents int64) int64 {
+	if !p.Foreign() || p.OriginalCents == 0 {
+		return 0
+	}
+	return int64(math.Round(float64(cents) * float64(p.RevenueCents) / float64(p.OriginalCents)))
+}
+
+// InQuoted converts kronor to the project's quoted currency at the rate its
+// revenue was converted at, the other way from AtBookedRate; cents as is for
+// a project in kronor
+func (p Project) InQuoted(cents int64) int64 {
+	if !p.Foreign() {
+		return cents
+	}
+	if p.RevenueCents == 0 {
+		return 0
+	}
+	return int64(math.Round(float64(cents) * float64(p.OriginalCents) / float64(p.RevenueCents)))
+}
+
+// AsEntered shows an amount excluding VAT the way the form takes it:
+// with VAT added back for a price agreed including VAT
+func (p Project) AsEntered(cents int64) int64 {
+	if p.VATInclusive {
+		return cents + VATOn(cents, p.VATPercent)
+	}
+	return cents
+}
+
+// VATOn is the VAT at percent on an amount excluding VAT, rounded to the cent
+func VATOn(cents int64, percent float64) int64 {
+	return int64(math.Round(float64(cents) * percent / 100))
+}
+
+// ExcludingVAT takes the VAT at percent out of an amount that includes it,
+// rounded to the cent; VATOn gives the VAT back exactly
+func ExcludingVAT(cents int64, percent float64) int64 {
+	return int64(math.Round(float64(cents) * 100 / (100 + percent)))
 }
 
-// Contribution tracks work per owner
+// SecuredByPerson reports whether the person is among those who secured it
+func (p Project) SecuredByPerson(id int64) bool {
+	return slices.Contains(p.SecuredBy, id)
+}
+
+// Contribution is a person's total hours on a project: the sum of their
+// time entries, kept in step by the store
 type Contribution struct {
 	ID        int64     `json:"id" db:"id"`
 	ProjectID int64     `json:"project_id" db:"project_id"`
-	Owner     Owner     `json:"owner" db:"owner"`
+	PersonID  int64     `json:"person_id" db:"person_id"`
 	Hours     float64   `json:"hours" db:"hours"`
 	Notes     string    `json:"notes" db:"notes"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TimeEntry is a dated record of hours logged by a person on a project.
+// Entries the store adds to correct a total set directly (an import, undo,
+// the API) may be negative.
+type TimeEntry struct {
+	ID        int64     `json:"id"`
+	ProjectID int64     `json:"project_id"`
+	Client    string    `json:"client"`
+	PersonID  int64     `json:"person_id"`
+	Person    string    `json:"person"` // the person's name
+	Date      time.Time `json:"date"`
+	Hours     float64   `json:"hours"`
+	Notes     string    `json:"notes"`
+}
+
+// Task is a subtask of a project, ticked off as the work gets done; unlike
+// checklist items it gates nothing
+type Task struct {
+	ID         int64  `json:"id"`
+	ProjectID  int64  `json:"project_id"`
+	Title      string `json:"title"`
+	Done       bool   `json:"done"`
+	AssigneeID int64  `json:"assignee_id,omitempty"` // 0 when unassigned
+	Position   int    `json:"position"`
+}
+
+// Tasks is a project's tasks in order
+type Tasks []Task
+
+// Done counts the finished tasks
+func (ts Tasks) Done() int {
+	n := 0
+	for _, t := range ts {
+		if t.Done {
+			n++
+		}
+	}
+	return n
+}
+
+// Milestone is a stage of a project billed on its own, so a large project
+// can be paid in parts while it stays on the board. The amount excludes VAT,
+// like the project's revenue; the client pays it with the project's VAT
+// through the milestone's own Stripe payment link.
+type Milestone struct {
+	ID              int64           `json:"id"`
+	ProjectID       int64           `json:"project_id"`
+	Title           string          `json:"title"`
+	AmountCents     int64           `json:"amount_cents"`
+	DueOn           time.Time       `json:"due_on"`
+	Status          MilestoneStatus `json:"status"`
+	PaymentLinkID   string          `json:"-"`
+	PaymentLinkURL  string          `json:"payment_link_url,omitempty"`
+	StripePaymentID string          `json:"stripe_payment_id,omitempty"` // the payment intent that paid it
+	PaidAt          *time.Time      `json:"paid_at,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// GrossCents is what the client pays for the milestone: its amount plus VAT
+// at the project's rate
+func (m Milestone) GrossCents(vatPercent float64) int64 {
+	return m.AmountCents + VATOn(m.AmountCents, vatPercent)
+}
+
+// Overdue reports whether the milestone is unpaid after its due date
+func (m Milestone) Overdue(now time.Time) bool {
+	return m.Status != MilestonePaid && now.After(m.DueOn.AddDate(0, 0, 1))
+}
+
+// MilestoneStatus is where a milestone is in its billing
+type MilestoneStatus string
+
+const (
+	MilestonePlanned MilestoneStatus = "planned"
+	MilestoneBilled  MilestoneStatus = "billed" // a payment link was made or it was invoiced
+	MilestonePaid    MilestoneStatus = "paid"
+)
+
+// MilestoneStatuses lists the statuses in display order
+var MilestoneStatuses = []MilestoneStatus{MilestonePlanned, MilestoneBilled, MilestonePaid}
+
+// Valid reports whether s is a known milestone status
+func (s MilestoneStatus) Valid() bool {
+	return slices.Contains(MilestoneStatuses, s)
+}
+
+// Label is the status's display name
+func (s MilestoneStatus) Label() string {
+	return strings.ToUpper(string(s[:1])) + string(s[1:])
+}
+
+// Milestones are a project's billing stages, by due date
+type Milestones []Milestone
+
+// AmountCents totals the milestones' amounts, excluding VAT
+func (ms Milestones) AmountCents() int64 {
+	var t int64
+	for _, m := range ms {
+		t += m.AmountCents
+	}
+	return t
+}
+
+// PaidCents totals what the client has paid for the milestones, including
+// VAT at vatPercent
+func (ms Milestones) PaidCents(vatPercent float64) int64 {
+	var t int64
+	for _, m := range ms {
+		if m.Status == MilestonePaid {
+			t += m.GrossCents(vatPercent)
+		}
+	}
+	return t
+}
+
+// ArchiveFilter narrows the archive listing; zero values mean "any"
+type ArchiveFilter struct {
+	Search    string // client or description
+	Status    ProjectStatus
+	SecuredBy int64 // person ID
+	Year      int   // year paid (or created, if never paid)
+	Page
+}
+
+// ArchiveTotals sums all projects matching a filter, across pages: the
+// archive's, or a board column's on a search
+type ArchiveTotals struct {
+	Count        int
+	RevenueCents int64
+}
+
+// Page selects a slice of a listing; a zero Limit means all of it
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// ProjectFilter narrows and orders the projects on the board; zero values
+// mean "any", and the default order is newest first
+type ProjectFilter struct {
+	Search          string // client or description
+	Status          ProjectStatus
+	SecuredBy       int64     // person ID
+	Client          string    // exact client name, ignoring case
+	From            time.Time // created on or after
+	To              time.Time // created on or before (whole day)
+	MinRevenueCents *int64
+	MaxRevenueCents *int64
+	Archived        bool // archived projects too
+	Sort            ProjectSort
+	Desc            bool
+	Page
+}
+
+// ProjectSort is a key projects can be ordered by
+type ProjectSort string
+
+const (
+	SortCreated ProjectSort = "created_at"
+	SortRevenue ProjectSort = "revenue"
+	SortClient  ProjectSort = "client"
+	// SortDue orders by ExpectedOn, the expected payment date; projects
+	// without one come last either way
+	SortDue ProjectSort = "due_date"
+)
+
+// ProjectSorts lists the sort keys, in the order offered in the UI
+var ProjectSorts = []ProjectSort{SortCreated, SortRevenue, SortClient, SortDue}
+
+// Valid reports whether s is a known sort key
+func (s ProjectSort) Valid() bool {
+	return slices.Contains(ProjectSorts, s)
+}
+
+// TimeEntryFilter narrows a time entry listing; zero values mean "any"
+type TimeEntryFilter struct {
+	ProjectID int64
+	PersonID  int64
+	From      time.Time // inclusive
+	To        time.Time // inclusive (whole day)
+}
+
+// WeekStart returns the Monday of t's week, at midnight UTC
+func WeekStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// Timesheet is a week of time entries as a grid: a row per person and
+// project with the hours on each day, Monday first, and the totals
+type Timesheet struct {
+	Week        time.Time          `json:"week"` // Monday
+	Days        [7]time.Time       `json:"days"`
+	Rows        []TimesheetRow     `json:"rows"`      // by person, then client
+	DayHours    [7]float64         `json:"day_hours"` // everyone, per day
+	PersonHours PerPerson[float64] `json:"person_hours"`
+	Projects    []TimesheetProject `json:"projects"` // most hours first
+	Hours       float64            `json:"hours"`
+}
+
+// TimesheetRow is one person's hours on one project, per day of the week
+type TimesheetRow struct {
+	PersonID  int64      `json:"person_id"`
+	Person    string     `json:"person"`
+	ProjectID int64      `json:"project_id"`
+	Client    string     `json:"client"`
+	Hours     [7]float64 `json:"hours"`
+	Total     float64    `json:"total"`
+}
+
+// TimesheetProject totals a project's hours for the week
+type TimesheetProject struct {
+	ProjectID int64   `json:"project_id"`
+	Client    string  `json:"client"`
+	Hours     float64 `json:"hours"`
+}
+
+// NewTimesheet lays out the week starting at week (a Monday); entries
+// outside it are ignored
+func NewTimesheet(week time.Time, entries []TimeEntry) Timesheet {
+	ts := Timesheet{Week: week, PersonHours: PerPerson[float64]{}}
+	for i := range ts.Days {
+		ts.Days[i] = week.AddDate(0, 0, i)
+	}
+
+	type key struct{ person, project int64 }
+	rows := map[key]int{}
+	projects := map[int64]int{}
+	for _, e := range entries {
+		day := int(e.Date.Sub(week).Hours() / 24)
+		if e.Date.Before(week) || day > 6 {
+			continue
+		}
+		k := key{e.PersonID, e.ProjectID}
+		i, ok := rows[k]
+		if !ok {
+			i = len(ts.Rows)
+			rows[k] = i
+			ts.Rows = append(ts.Rows, TimesheetRow{PersonID: e.PersonID, Person: e.Person, ProjectID: e.ProjectID, Client: e.Client})
+		}
+		ts.Rows[i].Hours[day] += e.Hours
+		ts.Rows[i].Total += e.Hours
+
+		j, ok := projects[e.ProjectID]
+		if !ok {
+			j = len(ts.Projects)
+			projects[e.ProjectID] = j
+			ts.Projects = append(ts.Projects, TimesheetProject{ProjectID: e.ProjectID, Client: e.Client})
+		}
+		ts.Projects[j].Hours += e.Hours
+
+		ts.DayHours[day] += e.Hours
+		ts.PersonHours[e.PersonID] += e.Hours
+		ts.Hours += e.Hours
+	}
+
+	slices.SortFunc(ts.Rows, func(a, b TimesheetRow) int {
+		return cmp.Or(cmp.Compare(a.Person, b.Person), cmp.Compare(a.Client, b.Client), cmp.Compare(a.ProjectID, b.ProjectID))
+	})
+	slices.SortStableFunc(ts.Projects, func(a, b TimesheetProject) int { return cmp.Compare(b.Hours, a.Hours) })
+	return ts
+}
+
+// HoursReport is a month's hours per person and project, set against what
+// each person earned from the projects paid that month
+type HoursReport struct {
+	Month  time.Time      `json:"month"` // the first day
+	People []PersonHours  `json:"people"`
+	Rows   []ProjectHours `json:"rows"` // by person, then client
+}
+
+// PersonHours is one person's month: hours logged, their shares (less
+// reimbursements) of the projects paid, and the two divided
+type PersonHours struct {
+	PersonID    int64   `json:"person_id"`
+	Person      string  `json:"person"`
+	Hours       float64 `json:"hours"`
+	EarnedCents int64   `json:"earned_cents"`
+	HourlyCents int64   `json:"hourly_cents"` // 0 without hours
 }
 
-// Metrics for dashboard
+// ProjectHours is one person's hours on a project in the month. For a
+// project paid that month it adds their earnings from it, per hour they
+// logged on it in all.
+type ProjectHours struct {
+	PersonID    int64   `json:"person_id"`
+	Person      string  `json:"person"`
+	ProjectID   int64   `json:"project_id"`
+	Client      string  `json:"client"`
+	Hours       float64 `json:"hours"` // logged in the month
+	Paid        bool    `json:"paid"`  // paid in the month
+	EarnedCents int64   `json:"earned_cents"`
+	TotalHours  float64 `json:"total_hours"` // logged on the project in all
+	HourlyCents int64   `json:"hourly_cents"`
+}
+
+// PerHour divides cents by hours, 0 without hours
+func PerHour(cents int64, hours float64) int64 {
+	if hours <= 0 {
+		return 0
+	}
+	return int64(math.Round(float64(cents) / hours))
+}
+
+// EstimateAccuracy compares estimated with logged hours across finished
+// projects (those in a terminal status other than lost)
+type EstimateAccuracy struct {
+	Projects       []EstimatedProject `json:"projects"` // most recent first
+	EstimatedHours float64            `json:"estimated_hours"`
+	ActualHours    float64            `json:"actual_hours"`
+	Over           int                `json:"over"`  // projects that took longer than estimated
+	Under          int                `json:"under"` // and shorter
+	// MeanErrorPercent is the average miss, over or under, as a percentage
+	// of the estimate
+	MeanErrorPercent float64          `json:"mean_error_percent"`
+	People           []PersonEstimate `json:"people"` // from per-person estimates
+}
+
+// EstimatedProject is one finished project's estimate against its hours
+type EstimatedProject struct {
+	ProjectID       int64         `json:"project_id"`
+	Client          string        `json:"client"`
+	Status          ProjectStatus `json:"status"`
+	EstimatedHours  float64       `json:"estimated_hours"`
+	ActualHours     float64       `json:"actual_hours"`
+	VariancePercent float64       `json:"variance_percent"` // positive when over
+}
+
+// PersonEstimate totals one person's estimated and logged hours on the
+// finished projects that estimated them
+type PersonEstimate struct {
+	PersonID       int64   `json:"person_id"`
+	Person         string  `json:"person"`
+	Projects       int     `json:"projects"`
+	EstimatedHours float64 `json:"estimated_hours"`
+	ActualHours    float64 `json:"actual_hours"`
+}
+
+// VariancePercent is how far the person's hours were off their estimates,
+// positive when over
+func (p PersonEstimate) VariancePercent() float64 {
+	return variance(p.EstimatedHours, p.ActualHours)
+}
+
+// VariancePercent is how far all the hours were off the estimates
+func (a EstimateAccuracy) VariancePercent() float64 {
+	return variance(a.EstimatedHours, a.ActualHours)
+}
+
+// variance is actual against estimated in percent, 0 without an estimate
+func variance(estimated, actual float64) float64 {
+	if estimated <= 0 {
+		return 0
+	}
+	return (actual - estimated) / estimated * 100
+}
+
+// NewEstimateAccuracy compares the estimated projects with the hours
+// logged on them, per person where they were estimated per person (hours
+// by project ID)
+func NewEstimateAccuracy(projects []Project, hours map[int64]PerPerson[float64], people People) EstimateAccuracy {
+	var a EstimateAccuracy
+	var errSum float64
+	byPerson := map[int64]*PersonEstimate{}
+	for _, p := range projects {
+		if p.EstimatedHours <= 0 {
+			continue
+		}
+		v := variance(p.EstimatedHours, p.LoggedHours)
+		a.Projects = append(a.Projects, EstimatedProject{ProjectID: p.ID, Client: p.Client, Status: p.Status,
+			EstimatedHours: p.EstimatedHours, ActualHours: p.LoggedHours, VariancePercent: v})
+		a.EstimatedHours += p.EstimatedHours
+		a.ActualHours += p.LoggedHours
+		errSum += math.Abs(v)
+		switch {
+		case p.LoggedHours > p.EstimatedHours:
+			a.Over++
+		case p.LoggedHours < p.EstimatedHours:
+			a.Under++
+		}
+
+		for id, est := range p.HourEstimates {
+			if est <= 0 {
+				continue
+			}
+			pe := byPerson[id]
+			if pe == nil {
+				pe = &PersonEstimate{PersonID: id, Person: people.Name(id)}
+				byPerson[id] = pe
+			}
+			pe.Projects++
+			pe.EstimatedHours += est
+			pe.ActualHours += hours[p.ID][id]
+		}
+	}
+	if len(a.Projects) > 0 {
+		a.MeanErrorPercent = errSum / float64(len(a.Projects))
+	}
+	for _, p := range people {
+		if pe := byPerson[p.ID]; pe != nil {
+			a.People = append(a.People, *pe)
+		}
+	}
+	return a
+}
+
+// Metrics for dashboard, amounts in integer cents (öre)
 type Metrics struct {
-	TotalRevenue   float64 `json:"total_revenue"`
-	NoorShare      float64 `json:"noor_share"`
-	AhmadShare     float64 `json:"ahmad_share"`
-	OpenProjects   int     `json:"open_projects"`
+	TotalRevenueCents int64            `json:"total_revenue_cents"`
+	ShareCents        PerPerson[int64] `json:"share_cents"`      // by person ID
+	ReimbursedCents   PerPerson[int64] `json:"reimbursed_cents"` // included in ShareCents
+	OpenProjects      int              `json:"open_projects"`
+	ExpenseCents      int64            `json:"expense_cents"`  // paid projects' expenses plus overhead
+	OverheadCents     int64            `json:"overhead_cents"` // overhead taken off the shares, included in ReimbursedCents
+	ReceivedCents     PerPerson[int64] `json:"received_cents"` // paid out so far, net of settlements (see Payout)
+	Deals             DealStats        `json:"deals"`
 }
 
-// ProjectWithContributions for UI
-type ProjectWithContributions struct {
-	Project       Project
-	Contributions []Contribution
+// DealStats sizes the paid projects and averages how long they took to pay:
+// from creation, and from entering the status they were paid from
+type DealStats struct {
+	Paid              int     `json:"paid"`
+	AverageCents      int64   `json:"average_cents"`
+	MedianCents       int64   `json:"median_cents"`
+	DaysToPaid        float64 `json:"days_to_paid"`
+	DaysPendingToPaid float64 `json:"days_pending_to_paid"`
 }
 
-// RevenueSplit result
-type RevenueSplit struct {
-	NoorShare   float64
-	AhmadShare  float64
-	Method      string // "owner" or "hours"
+// NewDealStats computes the deal stats of paid projects
+func NewDealStats(paid []Project) DealStats {
+	s := DealStats{Paid: len(paid)}
+	if s.Paid == 0 {
+		return s
+	}
+	amounts := make([]int64, len(paid))
+	var total int64
+	var toPaid, pendingToPaid time.Duration
+	for i, p := range paid {
+		amounts[i] = p.RevenueCents
+		total += p.RevenueCents
+		at := p.PaymentTime()
+		toPaid += at.Sub(p.CreatedAt)
+		pendingToPaid += max(at.Sub(p.WaitingSince()), 0)
+	}
+	slices.Sort(amounts)
+	s.AverageCents = total / int64(s.Paid)
+	s.MedianCents = amounts[s.Paid/2]
+	if s.Paid%2 == 0 {
+		s.MedianCents = (amounts[s.Paid/2-1] + amounts[s.Paid/2]) / 2
+	}
+	day := float64(24 * time.Hour)
+	s.DaysToPaid = float64(toPaid) / day / float64(s.Paid)
+	s.DaysPendingToPaid = float64(pendingToPaid) / day / float64(s.Paid)
+	return s
+}
+
+// WinRate weighs the deals won (paid) against those lost in a year, with
+// the reasons the lost ones fell through and the figures per lead source
+type WinRate struct {
+	Year      int             `json:"year"`
+	Won       int             `json:"won"`
+	Lost      int             `json:"lost"`
+	Percent   float64         `json:"win_percent"` // of the deals decided, 0–100; zero when none were
+	WonCents  int64           `json:"won_cents"`
+	LostCents int64           `json:"lost_cents"`
+	Reasons   []LostReason    `json:"reasons"` // most common first
+	Sources   []SourceWinRate `json:"sources"` // in LeadSources order, unrecorded last; only those with deals
+}
+
+// SourceWinRate is the deals won and lost through one lead source
+type SourceWinRate struct {
+	Source    LeadSource `json:"source"`
+	Won       int        `json:"won"`
+	Lost      int        `json:"lost"`
+	Percent   float64    `json:"win_percent"`
+	WonCents  int64      `json:"won_cents"`
+	LostCents int64      `json:"lost_cents"`
+}
+
+// winPercent is won as a share of the deals decided, zero when none were
+func winPercent(won, lost int) float64 {
+	if won+lost == 0 {
+		return 0
+	}
+	return float64(won) * 100 / float64(won+lost)
+}
+
+// LostReason is one reason deals were lost, with how many and their revenue
+type LostReason struct {
+	Reason       string `json:"reason"`
+	Projects     int    `json:"projects"`
+	RevenueCents int64  `json:"revenue_cents"`
+}
+
+// NewWinRate counts the projects paid and lost in year: paid by their
+// payment, lost by when they were moved to lost. Reasons are grouped
+// ignoring case and surrounding space, under the first spelling seen.
+func NewWinRate(year int, paid, lost []Project) WinRate {
+	w := WinRate{Year: year, Reasons: []LostReason{}, Sources: []SourceWinRate{}}
+	sources := map[LeadSource]*SourceWinRate{}
+	source := func(s LeadSource) *SourceWinRate {
+		if sources[s] == nil {
+			sources[s] = &SourceWinRate{Source: s}
+		}
+		return sources[s]
+	}
+	for _, p := range paid {
+		if p.PaymentTime().Year() == year {
+			w.Won++
+			w.WonCents += p.RevenueCents
+			src := source(p.Source)
+			src.Won++
+			src.WonCents += p.RevenueCents
+		}
+	}
+
+	index := map[string]int{}
+	for _, p := range lost {
+		if p.WaitingSince().Year() != year {
+			continue
+		}
+		w.Lost++
+		w.LostCents += p.RevenueCents
+		src := source(p.Source)
+		src.Lost++
+		src.LostCents += p.RevenueCents
+
+		reason := strings.TrimSpace(p.LostReason)
+		key := strings.ToLower(reason)
+		i, ok := index[key]
+		if !ok {
+			i = len(w.Reasons)
+			index[key] = i
+			w.Reasons = append(w.Reasons, LostReason{Reason: reason})
+		}
+		w.Reasons[i].Projects++
+		w.Reasons[i].RevenueCents += p.RevenueCents
+	}
+	slices.SortStableFunc(w.Reasons, func(a, b LostReason) int {
+		return cmp.Or(cmp.Compare(b.Projects, a.Projects), cmp.Compare(b.RevenueCents, a.RevenueCents))
+	})
+	w.Percent = winPercent(w.Won, w.Lost)
+
+	for _, s := range append(slices.Clone(LeadSources), "") {
+		if src := sources[s]; src != nil {
+			src.Percent = winPercent(src.Won, src.Lost)
+			w.Sources = append(w.Sources, *src)
+		}
+	}
+	return w
+}
+
+// BalanceCents is what each person is still owed: their share less what
+// they've received. Negative means they've received more than their share.
+func (m Metrics) BalanceCents() PerPerson[int64] {
+	b := PerPerson[int64]{}
+	b.Add(m.ShareCents)
+	b.Sub(m.ReceivedCents)
+	return b
+}
+
+// ProfitCents is revenue less expenses
+func (m Metrics) ProfitCents() int64 {
+	return m.TotalRevenueCents - m.ExpenseCents
+}
+
+// NetCents is what the shares divide once reimbursed expenses are taken off
+// the top
+func (m Metrics) NetCents() int64 {
+	return m.TotalRevenueCents - m.ReimbursedCents.Total()
+}
+
+// StatsSnapshot is the dashboard metrics as they stood at the end of a day
+type StatsSnapshot struct {
+	Day time.Time `json:"day"`
+	Metrics
+}
+
+// MetricsComparison holds the snapshots the dashboard compares today's
+// metrics against; each is nil until the history reaches back that far
+type MetricsComparison struct {
+	LastMonth *StatsSnapshot `json:"last_month,omitempty"`
+	LastYear  *StatsSnapshot `json:"last_year,omitempty"`
+}
+
+// QuarterStats is one quarter's paid revenue, each person's share of it and
+// the hours logged on those projects. Projects count in the quarter they
+// were paid; adjusting entries in the quarter they were posted; overhead in
+// the quarter it was spent.
+type QuarterStats struct {
+	Year            int                `json:"year"`
+	Quarter         int                `json:"quarter"` // 1-4
+	RevenueCents    int64              `json:"revenue_cents"`
+	ShareCents      PerPerson[int64]   `json:"share_cents"`
+	ReimbursedCents PerPerson[int64]   `json:"reimbursed_cents"` // expenses, overhead included; part of ShareCents
+	Hours           PerPerson[float64] `json:"hours"`
+	Projects        int                `json:"projects"`
+}
+
+// NetCents is the quarter's revenue less the expenses taken off the top
+func (q QuarterStats) NetCents() int64 {
+	return q.RevenueCents - q.ReimbursedCents.Total()
+}
+
+// Label names the quarter, e.g. "Q2 2026"
+func (q QuarterStats) Label() string {
+	return fmt.Sprintf("Q%d %d", q.Quarter, q.Year)
+}
+
+// Granularity is the length of the periods a time series is bucketed by
+type Granularity string
+
+const (
+	GranularityMonth   Granularity = "month"
+	GranularityQuarter Granularity = "quarter"
+	GranularityYear    Granularity = "year"
+)
+
+// Valid reports whether g is a known granularity
+func (g Granularity) Valid() bool {
+	return g == GranularityMonth || g == GranularityQuarter || g == GranularityYear
+}
+
+// Start returns the first day of the period t falls in
+func (g Granularity) Start(t time.Time) time.Time {
+	month := t.Month()
+	switch g {
+	case GranularityQuarter:
+		month = time.Month((QuarterOf(t)-1)*3 + 1)
+	case GranularityYear:
+		month = time.January
+	}
+	return time.Date(t.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Add moves the start of a period n periods on (back when n is negative)
+func (g Granularity) Add(start time.Time, n int) time.Time {
+	switch g {
+	case GranularityQuarter:
+		return start.AddDate(0, 3*n, 0)
+	case GranularityYear:
+		return start.AddDate(n, 0, 0)
+	}
+	return start.AddDate(0, n, 0)
+}
+
+// Label names the period starting at start: "2026-04", "Q2 2026" or "2026"
+func (g Granularity) Label(start time.Time) string {
+	switch g {
+	case GranularityQuarter:
+		return fmt.Sprintf("Q%d %d", QuarterOf(start), start.Year())
+	case GranularityYear:
+		return strconv.Itoa(start.Year())
+	}
+	return start.Format("2006-01")
+}
+
+// RevenuePeriod is one period of the revenue time series: the projects paid
+// in it and each person's share, split like the dashboard metrics, with
+// adjusting entries counted in the period they were posted
+type RevenuePeriod struct {
+	Start        time.Time        `json:"start"`
+	Label        string           `json:"label"`
+	RevenueCents int64            `json:"revenue_cents"`
+	Projects     int              `json:"projects"`
+	ShareCents   PerPerson[int64] `json:"share_cents"`
+}
+
+// ForecastHorizons are the windows, in days, the forecast looks ahead
+var ForecastHorizons = []int{30, 60, 90}
+
+// Forecast is the open pipeline: the revenue of every project not yet in a
+// terminal status, weighted by the chance it gets paid
+type Forecast struct {
+	Projects      int               `json:"projects"`
+	PipelineCents int64             `json:"pipeline_cents"` // unweighted
+	WeightedCents int64             `json:"weighted_cents"`
+	Horizons      []ForecastHorizon `json:"horizons"`
+	UndatedCents  int64             `json:"undated_cents"` // weighted, of projects without an expected date
+}
+
+// Receivable is money a client owes: an issued invoice, or a project
+// awaiting payment that hasn't been invoiced
+type Receivable struct {
+	ProjectID   int64     `json:"project_id,omitempty"`
+	InvoiceID   int64     `json:"invoice_id,omitempty"`
+	Client      string    `json:"client"`
+	Label       string    `json:"label"`        // the invoice number, or the project's description
+	AmountCents int64     `json:"amount_cents"` // including VAT
+	DueOn       time.Time `json:"due_on"`
+	DaysOverdue int       `json:"days_overdue"` // 0 until it's past due
+}
+
+// AgingBucket totals the receivables overdue by MinDays to MaxDays days;
+// MaxDays is 0 for the last, open-ended bucket
+type AgingBucket struct {
+	MinDays     int   `json:"min_days"`
+	MaxDays     int   `json:"max_days,omitempty"`
+	Count       int   `json:"count"`
+	AmountCents int64 `json:"amount_cents"`
+}
+
+// Label names the bucket by its days overdue
+func (b AgingBucket) Label() string {
+	if b.MaxDays == 0 {
+		return fmt.Sprintf("%d+ days", b.MinDays-1)
+	}
+	if b.MinDays == 1 {
+		return fmt.Sprintf("0–%d days", b.MaxDays)
+	}
+	return fmt.Sprintf("%d–%d days", b.MinDays, b.MaxDays)
+}
+
+// AgingLimits are the upper bounds, in days overdue, of the aging buckets
+// before the open-ended last one
+var AgingLimits = []int{30, 60, 90}
+
+// Aging is accounts receivable by how long they are overdue
+type Aging struct {
+	Receivables  []Receivable  `json:"receivables"`   // most overdue first
+	CurrentCents int64         `json:"current_cents"` // not yet due
+	Current      int           `json:"current"`
+	Buckets      []AgingBucket `json:"buckets"`
+	OverdueCents int64         `json:"overdue_cents"`
+	Overdue      int           `json:"overdue"`
+	TotalCents   int64         `json:"total_cents"`
+}
+
+// Add counts a receivable due on r.DueOn in the total and, once it's
+// overdue as of today, in its bucket
+func (a *Aging) Add(r Receivable, today time.Time) {
+	r.DaysOverdue = max(int(today.Sub(r.DueOn).Hours()/24), 0)
+	a.Receivables = append(a.Receivables, r)
+	a.TotalCents += r.AmountCents
+	if r.DaysOverdue == 0 {
+		a.Current++
+		a.CurrentCents += r.AmountCents
+		return
+	}
+	a.Overdue++
+	a.OverdueCents += r.AmountCents
+	for i := range a.Buckets {
+		if b := &a.Buckets[i]; r.DaysOverdue >= b.MinDays && (b.MaxDays == 0 || r.DaysOverdue <= b.MaxDays) {
+			b.Count++
+			b.AmountCents += r.AmountCents
+			return
+		}
+	}
+}
+
+// ForecastHorizon is the weighted revenue expected within Days; projects
+// expected earlier than today and still open count as due now
+type ForecastHorizon struct {
+	Days          int   `json:"days"`
+	Projects      int   `json:"projects"`
+	ExpectedCents int64 `json:"expected_cents"`
+}
+
+// Weighted is cents at a probability in percent, rounded to whole öre
+func Weighted(cents int64, probability int) int64 {
+	return int64(math.Round(float64(cents) * float64(probability) / 100))
+}
+
+// Goal is a revenue target for a month, quarter or year: the combined
+// revenue, or one person's share of it
+type Goal struct {
+	ID          int64       `json:"id" db:"id"`
+	Granularity Granularity `json:"granularity" db:"granularity"`
+	Start       time.Time   `json:"start" db:"start_on"`                // first day of the period
+	PersonID    *int64      `json:"person_id,omitempty" db:"person_id"` // nil for the combined revenue
+	TargetCents int64       `json:"target_cents" db:"target_cents"`
+	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+}
+
+// End returns the day after the goal's period
+func (g Goal) End() time.Time {
+	return g.Granularity.Add(g.Start, 1)
+}
+
+// Label names the goal's period: "April 2026", "Q2 2026" or "2026"
+func (g Goal) Label() string {
+	if g.Granularity == GranularityMonth {
+		return g.Start.Format("January 2006")
+	}
+	return g.Granularity.Label(g.Start)
+}
+
+// Current reports whether now falls within the goal's period
+func (g Goal) Current(now time.Time) bool {
+	return !now.Before(g.Start) && now.Before(g.End())
+}
+
+// GoalProgress is how far a goal has come and whether revenue is keeping
+// pace with it
+type GoalProgress struct {
+	Goal
+	ActualCents    int64 `json:"actual_cents"`
+	PaceCents      int64 `json:"pace_cents"`      // what an even pace would have reached by now
+	ProjectedCents int64 `json:"projected_cents"` // the period's total if the pace so far holds
+}
+
+// Progress measures actualCents, paid so far in the goal's period, against
+// the target; today counts as a day elapsed
+func (g Goal) Progress(actualCents int64, now time.Time) GoalProgress {
+	p := GoalProgress{Goal: g, ActualCents: actualCents}
+	days := g.End().Sub(g.Start).Hours() / 24
+	elapsed := math.Floor(now.Sub(g.Start).Hours()/24) + 1
+	elapsed = math.Max(0, math.Min(elapsed, days))
+	p.PaceCents = int64(math.Round(float64(g.TargetCents) * elapsed / days))
+	if elapsed > 0 {
+		p.ProjectedCents = int64(math.Round(float64(actualCents) * days / elapsed))
+	}
+	return p
+}
+
+// Percent is the share of the target reached, which may pass 100
+func (p GoalProgress) Percent() float64 {
+	if p.TargetCents <= 0 {
+		return 0
+	}
+	return float64(p.ActualCents) / float64(p.TargetCents) * 100
+}
+
+// OnPace reports whether revenue is at or ahead of an even pace to the target
+func (p GoalProgress) OnPace() bool {
+	return p.ActualCents >= p.PaceCents
+}
+
+// PeriodStats is what was paid between two dates: revenue (adjusting
+// entries posted in that time included), the projects paid and the hours
+// logged on them
+type PeriodStats struct {
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"` // inclusive
+	RevenueCents int64     `json:"revenue_cents"`
+	Projects     int       `json:"projects"`
+	AverageCents int64     `json:"average_cents"` // revenue per project paid
+	Hours        float64   `json:"hours"`
+}
+
+// PeriodComparison sets a period against an earlier one, such as this
+// year so far against the same dates last year
+type PeriodComparison struct {
+	Current  PeriodStats  `json:"current"`
+	Previous PeriodStats  `json:"previous"`
+	Change   PeriodChange `json:"change"`
+}
+
+// PeriodChange is how much each figure changed from the previous period,
+// in percent; nil where the previous period had none to compare with
+type PeriodChange struct {
+	Revenue  *float64 `json:"revenue_pct"`
+	Projects *float64 `json:"projects_pct"`
+	Average  *float64 `json:"average_pct"`
+	Hours    *float64 `json:"hours_pct"`
+}
+
+// ComparePeriods works out the changes from previous to current
+func ComparePeriods(current, previous PeriodStats) PeriodComparison {
+	return PeriodComparison{Current: current, Previous: previous, Change: PeriodChange{
+		Revenue:  PercentChange(float64(current.RevenueCents), float64(previous.RevenueCents)),
+		Projects: PercentChange(float64(current.Projects), float64(previous.Projects)),
+		Average:  PercentChange(float64(current.AverageCents), float64(previous.AverageCents)),
+		Hours:    PercentChange(current.Hours, previous.Hours),
+	}}
+}
+
+// PercentChange is the change from then to now in percent, or nil when
+// then is zero or negative and there's no meaningful percentage
+func PercentChange(now, then float64) *float64 {
+	if then <= 0 {
+		return nil
+	}
+	pct := (now - then) / then * 100
+	return &pct
+}
+
+// VATQuarter is one quarter's sales and the output VAT charged on them, by
+// rate, as the moms declaration asks for them. Payments count in the quarter
+// the project was paid, adjusting entries in the quarter they were posted,
+// both at the project's rate.
+type VATQuarter struct {
+	Year    int       `json:"year"`
+	Quarter int       `json:"quarter"` // 1-4
+	Rates   []VATRate `json:"rates"`   // highest rate first
+}
+
+// VATRate totals the sales at one VAT rate
+type VATRate struct {
+	Percent  float64 `json:"vat_percent"`
+	Payments int     `json:"payments"`
+	NetCents int64   `json:"net_cents"` // excluding VAT
+	VATCents int64   `json:"vat_cents"`
+}
+
+// Rate returns the quarter's totals at percent, adding them if missing
+func (q *VATQuarter) Rate(percent float64) *VATRate {
+	for i := range q.Rates {
+		if q.Rates[i].Percent == percent {
+			return &q.Rates[i]
+		}
+	}
+	q.Rates = append(q.Rates, VATRate{Percent: percent})
+	return &q.Rates[len(q.Rates)-1]
+}
+
+// Total sums the quarter's rates
+func (q VATQuarter) Total() VATRate {
+	var t VATRate
+	for _, r := range q.Rates {
+		t.Payments += r.Payments
+		t.NetCents += r.NetCents
+		t.VATCents += r.VATCents
+	}
+	return t
+}
+
+// Label names the quarter, e.g. "Q2 2026"
+func (q VATQuarter) Label() string {
+	return fmt.Sprintf("Q%d %d", q.Quarter, q.Year)
+}
+
+// QuarterOf returns the quarter (1-4) t falls in
+func QuarterOf(t time.Time) int {
+	return (int(t.Month())-1)/3 + 1
+}
+
+// Reconciliation cross-checks the books: recognized revenue against paid
+// projects and adjusting entries, the partners' shares against that revenue,
+// and each project's Stripe payments against its revenue plus VAT. Payouts
+// to the partners aren't recorded, so every share is still owed in full.
+type Reconciliation struct {
+	ReceivedCents   int64            `json:"received_cents"`   // Stripe payments logged in the audit log
+	PaidCents       int64            `json:"paid_cents"`       // revenue of paid projects
+	AdjustedCents   int64            `json:"adjusted_cents"`   // revenue change of adjusting entries
+	RecognizedCents int64            `json:"recognized_cents"` // the dashboard's total revenue
+	ShareCents      PerPerson[int64] `json:"share_cents"`      // the dashboard's shares, by person ID
+	Discrepancies   []Discrepancy    `json:"discrepancies"`
+}
+
+// Discrepancy is a project or adjusting entry that doesn't reconcile
+type Discrepancy struct {
+	ProjectID    int64  `json:"project_id"`
+	Client       string `json:"client"`
+	AdjustmentID int64  `json:"adjustment_id,omitempty"` // set when an adjusting entry is at fault
+	Problem      string `json:"problem"`
+}
+
+// RevenueBalances reports whether recognized revenue is the paid projects'
+// revenue plus adjusting entries
+func (r Reconciliation) RevenueBalances() bool {
+	return r.PaidCents+r.AdjustedCents == r.RecognizedCents
+}
+
+// SharesBalance reports whether the partners' shares add up to recognized revenue
+func (r Reconciliation) SharesBalance() bool {
+	return r.ShareCents.Total() == r.RecognizedCents
+}
+
+// Balanced reports whether the totals agree and no record is out of line
+func (r Reconciliation) Balanced() bool {
+	return r.RevenueBalances() && r.SharesBalance() && len(r.Discrepancies) == 0
+}
+
+// PaymentSummary is the audit log summary of a Stripe payment
+func PaymentSummary(cents int64) string {
+	return fmt.Sprintf("%.2f received", float64(cents)/100)
+}
+
+// ForeignPaymentSummary is the audit log summary of a Stripe payment made in
+// a foreign currency: the amount in kronor, then as paid. It parses like a
+// PaymentSummary.
+func ForeignPaymentSummary(cents, originalCents int64, currency string) string {
+	return fmt.Sprintf("%s (%.2f %s)", PaymentSummary(cents), float64(originalCents)/100, currency)
+}
+
+// ParsePaymentSummary reads the amount, in cents, back out of a PaymentSummary
+func ParsePaymentSummary(s string) (int64, bool) {
+	var amount float64
+	_, err := fmt.Sscanf(s, "%f received", &amount)
+	return int64(math.Round(amount * 100)), err == nil
+}
+
+// ProjectWithContributions for UI
+type ProjectWithContributions struct {
+	Project       Project
+	Contributions []Contribution
+}
+
+// RevenueSplit result, in integer cents by person ID; the shares always add
+// up to the project's revenue
+type RevenueSplit struct {
+	ShareCents      PerPerson[int64] // profit share + finder's fee + reimbursement
+	ReimbursedCents PerPerson[int64]
+	FeeCents        PerPerson[int64] // finder's fee, included in ShareCents
+	Method          string           // "owner", "hours", "rates", "percent" or "override"
+	Rates           PerPerson[int64] // hourly rates the hours were weighed by, when Method is "rates"
+}
+
+// SplitRules are the terms the split engine applies. The zero value is the
+// standard split: by hours when two or more people logged some, else equally
+// between whoever secured the project.
+type SplitRules struct {
+	FixedSplit       bool               `json:"fixed_split" db:"fixed_split"`               // split profit by Percents instead of hours/owner
+	Percents         PerPerson[float64] `json:"percents" db:"percents"`                     // each person's share of profit when FixedSplit (adds up to 100)
+	FinderFeePercent float64            `json:"finder_fee_percent" db:"finder_fee_percent"` // share of profit paid first to whoever secured the project
+	ExpensePolicy    ExpensePolicy      `json:"expense_policy" db:"expense_policy"`         // "" reimburses like ExpenseReimburse
+
+	// HourlyRates weigh hours by what each person's time is worth (cents per
+	// hour). They come from settings, not the agreement, and apply only when
+	// everyone who logged hours on a project has one.
+	HourlyRates PerPerson[int64] `json:"-" db:"-"`
+}
+
+// ExpensePolicy says how out-of-pocket expenses are settled
+type ExpensePolicy string
+
+const (
+	ExpenseReimburse  ExpensePolicy = "reimburse"   // paid back to the payer before the split
+	ExpensePayerBears ExpensePolicy = "payer_bears" // the payer's own cost
+)
+
+// ExpensePolicies lists the policies offered in the admin UI
+var ExpensePolicies = []ExpensePolicy{ExpenseReimburse, ExpensePayerBears}
+
+// Valid reports whether p is a known expense policy
+func (p ExpensePolicy) Valid() bool {
+	return p == ExpenseReimburse || p == ExpensePayerBears
+}
+
+// Agreement is a version of the partnership's split rules, in force from
+// EffectiveFrom until the next version. Versions are never edited, so
+// renegotiating doesn't change how past payments were split.
+type Agreement struct {
+	ID            int64     `json:"id" db:"id"`
+	EffectiveFrom time.Time `json:"effective_from" db:"effective_from"`
+	SplitRules
+	Notes     string    `json:"notes" db:"notes"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// AgreementAt returns the rules in force at t, given agreements ordered
+// newest first; before the first agreement the standard rules apply
+func AgreementAt(agreements []Agreement, t time.Time) SplitRules {
+	for _, a := range agreements {
+		if !t.Before(a.EffectiveFrom) {
+			return a.SplitRules
+		}
+	}
+	return SplitRules{}
+}
+
+// PeriodLock closes a month's books: projects paid in it can no longer
+// change their revenue, status, hours or expenses
+type PeriodLock struct {
+	Month    time.Time `json:"month" db:"month"` // first day of the month
+	LockedBy string    `json:"locked_by" db:"locked_by"`
+	LockedAt time.Time `json:"locked_at" db:"locked_at"`
+}
+
+// MonthOf returns the first day of t's month, the key of its period
+func MonthOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Label names the locked month, e.g. "March 2026"
+func (l PeriodLock) Label() string {
+	return l.Month.Format("January 2006")
+}
+
+// PeriodLockedError refuses a change to a project paid in a locked month,
+// or to an overhead expense spent in one (Client is then empty)
+type PeriodLockedError struct {
+	Lock   PeriodLock
+	Client string
+}
+
+func (e *PeriodLockedError) Error() string {
+	if e.Client == "" {
+		return fmt.Sprintf("%s is locked, so its overhead expenses can't change", e.Lock.Label())
+	}
+	return fmt.Sprintf("%s is locked, so %s's payment, revenue and split can't change; record an adjusting entry instead",
+		e.Lock.Label(), e.Client)
+}
+
+// Adjustment is a dated correction to a project's revenue and split. It is
+// posted in the period it is made rather than rewriting a locked one, and
+// balances: the partners' share changes add up to the revenue change.
+type Adjustment struct {
+	ID           int64            `json:"id" db:"id"`
+	ProjectID    int64            `json:"project_id" db:"project_id"`       // the corrected project
+	PostedOn     time.Time        `json:"posted_on" db:"posted_on"`         // the period it counts in
+	RevenueCents int64            `json:"revenue_cents" db:"revenue_cents"` // change to revenue
+	ShareCents   PerPerson[int64] `json:"share_cents" db:"share_cents"`     // changes to shares, by person ID
+	Reason       string           `json:"reason" db:"reason"`
+	CreatedBy    string           `json:"created_by" db:"created_by"`
+	CreatedAt    time.Time        `json:"created_at" db:"created_at"`
+}
+
+// Balanced reports whether the share changes add up to the revenue change
+func (a Adjustment) Balanced() bool {
+	return a.ShareCents.Total() == a.RevenueCents
+}
+
+// Payout is money that reached a person: a distribution from the business
+// or, in a settlement, a transfer from a partner (FromID), which counts as
+// received by the recipient and given back by the payer
+type Payout struct {
+	ID          int64     `json:"id" db:"id"`
+	PersonID    int64     `json:"person_id" db:"person_id"`              // who received it
+	FromID      int64     `json:"from_id,omitempty" db:"from_person_id"` // 0 = the business
+	AmountCents int64     `json:"amount_cents" db:"amount_cents"`        // always positive
+	PaidOn      time.Time `json:"paid_on" db:"paid_on"`
+	Note        string    `json:"note" db:"note"`
+	CreatedBy   string    `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// Settlement reports whether the payout is a transfer between partners
+func (p Payout) Settlement() bool {
+	return p.FromID != 0
+}
+
+// Debt is what one person owes another to even out their balances
+type Debt struct {
+	FromID      int64 `json:"from_id"`
+	ToID        int64 `json:"to_id"`
+	AmountCents int64 `json:"amount_cents"`
+}
+
+// Debts pairs off the people who've received more than their share with
+// those owed, largest first, in as few transfers as the greedy match gives.
+// Whatever is still owed once nobody is over is the business's to pay out,
+// not a debt between partners.
+func Debts(balances PerPerson[int64]) []Debt {
+	type entry struct {
+		id    int64
+		cents int64
+	}
+	var owe, owed []entry
+	for id, cents := range balances {
+		switch {
+		case cents < 0:
+			owe = append(owe, entry{id, -cents})
+		case cents > 0:
+			owed = append(owed, entry{id, cents})
+		}
+	}
+	largest := func(a, b entry) int {
+		return cmp.Or(cmp.Compare(b.cents, a.cents), cmp.Compare(a.id, b.id))
+	}
+	slices.SortFunc(owe, largest)
+	slices.SortFunc(owed, largest)
+
+	var debts []Debt
+	for i, j := 0, 0; i < len(owe) && j < len(owed); {
+		cents := min(owe[i].cents, owed[j].cents)
+		debts = append(debts, Debt{FromID: owe[i].id, ToID: owed[j].id, AmountCents: cents})
+		owe[i].cents -= cents
+		owed[j].cents -= cents
+		if owe[i].cents == 0 {
+			i++
+		}
+		if owed[j].cents == 0 {
+			j++
+		}
+	}
+	return debts
+}
+
+// MeetingKind is what a meeting with the client is for
+type MeetingKind string
+
+const (
+	MeetingKickoff MeetingKind = "kickoff"
+	MeetingReview  MeetingKind = "review"
+)
+
+// MeetingKinds lists the meetings offered in the edit modal
+var MeetingKinds = []MeetingKind{MeetingKickoff, MeetingReview}
+
+// Valid reports whether k is a known meeting kind
+func (k MeetingKind) Valid() bool {
+	return slices.Contains(MeetingKinds, k)
+}
+
+// Label names the meeting, e.g. "Kickoff meeting"
+func (k MeetingKind) Label() string {
+	if k == MeetingReview {
+		return "Review meeting"
+	}
+	return "Kickoff meeting"
+}
+
+// Invite is a meeting about a project, sent to the client as a calendar
+// invite. Invites aren't stored; sending one is logged in the project's history.
+type Invite struct {
+	Kind     MeetingKind
+	Start    time.Time
+	Duration time.Duration
+	To       []string
+	Location string
+	Notes    string
+}
+
+// Comment is a note in a project's discussion thread
+type Comment struct {
+	ID        int64     `json:"id" db:"id"`
+	ProjectID int64     `json:"project_id" db:"project_id"`
+	AuthorID  int64     `json:"-" db:"author_id"` // 0 once the user is deleted
+	Author    string    `json:"author" db:"author"`
+	Body      string    `json:"body" db:"body"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// DeletableBy reports whether u may delete the comment: its author or an owner
+func (c Comment) DeletableBy(u *User) bool {
+	return u != nil && (u.ID == c.AuthorID || u.Role.Allows(RoleOwner))
+}
+
+// Client holds per-client settings, matched to projects by client name
+// (case-insensitively). Payment branding is passed to Stripe Checkout.
+type Client struct {
+	ID                  int64     `json:"id" db:"id"`
+	Name                string    `json:"name" db:"name"`
+	StatementDescriptor string    `json:"statement_descriptor" db:"statement_descriptor"` // card statement suffix
+	LogoURL             string    `json:"logo_url" db:"logo_url"`
+	ThankYou            string    `json:"thank_you" db:"thank_you"` // shown at checkout and after paying
+	Email               string    `json:"email" db:"email"`         // where invoices and receipts go
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+}
+
+// ClientReport sums up a client's lifetime: its projects, what it has paid,
+// the hours worked for it and what is still open. Projects and Invoices are
+// only filled in for a single client's report.
+type ClientReport struct {
+	Client         Client     `json:"client"`
+	ProjectCount   int        `json:"project_count"`
+	PaidCount      int        `json:"paid_count"`
+	LifetimeCents  int64      `json:"lifetime_revenue_cents"` // paid projects, adjusting entries included
+	OpenCents      int64      `json:"open_cents"`             // projects in open statuses, not yet paid
+	UnpaidInvoices int        `json:"unpaid_invoices"`        // issued and awaiting payment
+	UnpaidCents    int64      `json:"unpaid_invoice_cents"`   // their totals incl. VAT, kronor invoices only
+	Hours          float64    `json:"hours"`
+	PaidHours      float64    `json:"paid_hours"` // logged on paid projects
+	LastPaidAt     *time.Time `json:"last_paid_at,omitempty"`
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
+
+	Projects  []ClientProject `json:"projects,omitempty"`  // newest first, archived included
+	Invoices  []Invoice       `json:"invoices,omitempty"`  // newest first
+	Retainers []Retainer      `json:"retainers,omitempty"` // oldest first
+	Documents []Document      `json:"documents,omitempty"` // the client's, then its projects'; every version
+}
+
+// ClientProject is one of a client's projects with the hours logged on it
+type ClientProject struct {
+	Project
+	Hours float64 `json:"hours"`
+}
+
+// Retainer maps a client's Stripe subscription to its billing here. Each
+// paid cycle becomes a new paid project credited to SecuredBy, or, with a
+// ProjectID, a payment on that ongoing project. Status, amount, interval and
+// period end are Stripe's, as its last subscription event reported them.
+type Retainer struct {
+	ID               int64      `json:"id"`
+	ClientID         int64      `json:"client_id"`
+	SubscriptionID   string     `json:"subscription_id"`
+	Description      string     `json:"description"` // names the projects made each cycle
+	SecuredBy        []int64    `json:"secured_by"`
+	ProjectID        int64      `json:"project_id,omitempty"` // 0: a new project per cycle
+	Status           string     `json:"status"`               // Stripe's subscription status; "" until it reports one
+	AmountCents      int64      `json:"amount_cents"`         // per cycle, excluding tax
+	Currency         string     `json:"currency"`
+	Interval         string     `json:"interval"` // day, week, month or year
+	CurrentPeriodEnd *time.Time `json:"current_period_end,omitempty"`
+	LastPaidAt       *time.Time `json:"last_paid_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// Active reports whether the subscription is still billing
+func (r Retainer) Active() bool {
+	return r.Status == "active" || r.Status == "trialing" || r.Status == "past_due"
+}
+
+// StatusLabel is the subscription's status for display
+func (r Retainer) StatusLabel() string {
+	if r.Status == "" {
+		return "waiting for Stripe"
+	}
+	return strings.ReplaceAll(r.Status, "_", " ")
+}
+
+// DocumentKind is what a stored document is
+type DocumentKind string
+
+const (
+	DocContract DocumentKind = "contract"
+	DocSOW      DocumentKind = "sow"
+	DocOther    DocumentKind = "other"
+)
+
+// DocumentKinds lists the kinds in form order
+var DocumentKinds = []DocumentKind{DocContract, DocSOW, DocOther}
+
+// Valid reports whether k is a known kind
+func (k DocumentKind) Valid() bool {
+	return slices.Contains(DocumentKinds, k)
+}
+
+// Label is the kind for display
+func (k DocumentKind) Label() string {
+	switch k {
+	case DocContract:
+		return "Contract"
+	case DocSOW:
+		return "Statement of work"
+	}
+	return "Document"
+}
+
+// DocumentReminderDays is how long before a document expires the reminder
+// is sent
+const DocumentReminderDays = 30
+
+// Document is a version of a file kept for a client, or for one of its
+// projects: a signed contract, a statement of work. Uploading under a title
+// already used there adds the next version; the latest is the current one.
+type Document struct {
+	ID         int64        `json:"id"`
+	ClientID   int64        `json:"client_id,omitempty"`  // a client's own document
+	ProjectID  int64        `json:"project_id,omitempty"` // or a project's
+	Client     string       `json:"client"`               // the client's name, either way
+	Kind       DocumentKind `json:"kind"`
+	Title      string       `json:"title"`
+	Version    int          `json:"version"`
+	FileName   string       `json:"file_name"` // as uploaded
+	Path       string       `json:"-"`         // stored name under the upload dir
+	SizeBytes  int64        `json:"size_bytes"`
+	SignedOn   *time.Time   `json:"signed_on,omitempty"`
+	ExpiresOn  *time.Time   `json:"expires_on,omitempty"`
+	UploadedBy string       `json:"uploaded_by"`
+	RemindedAt *time.Time   `json:"-"` // the expiry reminder was sent
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// Expired reports whether the document's expiry date has passed by now
+func (d Document) Expired(now time.Time) bool {
+	return d.ExpiresOn != nil && d.ExpiresOn.Before(now.Truncate(24*time.Hour))
+}
+
+// ExpiresSoon reports whether the document expires within
+// DocumentReminderDays of now, and hasn't yet
+func (d Document) ExpiresSoon(now time.Time) bool {
+	return d.ExpiresOn != nil && !d.Expired(now) && d.ExpiresOn.Before(now.AddDate(0, 0, DocumentReminderDays))
+}
+
+// DocumentHistory is a document's current version and the versions it
+// replaced, newest first
+type DocumentHistory struct {
+	Document
+	Earlier []Document `json:"earlier"`
+}
+
+// DocumentHistories groups versions, listed newest first within each title,
+// into one history per document, in the order they come
+func DocumentHistories(versions []Document) []DocumentHistory {
+	var out []DocumentHistory
+	index := map[[3]string]int{}
+	for _, d := range versions {
+		key := [3]string{strconv.FormatInt(d.ClientID, 10), strconv.FormatInt(d.ProjectID, 10), strings.ToLower(d.Title)}
+		if i, ok := index[key]; ok {
+			out[i].Earlier = append(out[i].Earlier, d)
+			continue
+		}
+		index[key] = len(out)
+		out = append(out, DocumentHistory{Document: d, Earlier: []Document{}})
+	}
+	return out
+}
+
+// HourlyCents is what the client's paid work brought in per hour logged on
+// it, 0 without hours
+func (r ClientReport) HourlyCents() int64 {
+	if r.PaidHours <= 0 {
+		return 0
+	}
+	return int64(math.Round(float64(r.LifetimeCents) / r.PaidHours))
+}
+
+// StatementDescriptorMax is Stripe's limit for a statement descriptor suffix
+const StatementDescriptorMax = 22
+
+// ValidStatementDescriptor reports whether s is acceptable to Stripe as a
+// statement descriptor suffix: up to 22 Latin characters, at least one
+// letter, none of < > \ ' " *
+func ValidStatementDescriptor(s string) bool {
+	if s == "" {
+		return true
+	}
+	if len(s) > StatementDescriptorMax {
+		return false
+	}
+	letter := false
+	for _, r := range s {
+		if r < ' ' || r > '~' || strings.ContainsRune(`<>\'"*`, r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letter = true
+		}
+	}
+	return letter
+}
+
+// BaseCurrency is the currency revenue, metrics and payouts are kept in
+const BaseCurrency = "SEK"
+
+// ExchangeRate is a stored rate for converting a foreign currency to
+// BaseCurrency
+type ExchangeRate struct {
+	Currency  string    `json:"currency" db:"currency"` // ISO 4217 code, e.g. "EUR"
+	Rate      float64   `json:"rate" db:"rate"`         // BaseCurrency per unit of Currency
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Convert converts cents of r.Currency to BaseCurrency cents, rounded to
+// the nearest öre
+func (r ExchangeRate) Convert(cents int64) int64 {
+	return int64(math.Round(float64(cents) * r.Rate))
+}
+
+// FXRates is one day's rates from an exchange rate provider, cached so the
+// provider is asked once a day
+type FXRates struct {
+	Source    string             `json:"source"` // provider name, e.g. "ecb"
+	Day       time.Time          `json:"day"`    // the day the provider published them for
+	FetchedAt time.Time          `json:"fetched_at"`
+	Rates     map[string]float64 `json:"rates"` // BaseCurrency per unit, by currency code
+}
+
+// CurrencyTotal is the revenue of the projects quoted in one currency: as
+// quoted, in BaseCurrency as booked when each was entered, and in
+// BaseCurrency at the currency's current stored rate
+type CurrencyTotal struct {
+	Currency      string  `json:"currency"`
+	Projects      int     `json:"projects"`
+	OriginalCents int64   `json:"original_cents"`
+	BookedCents   int64   `json:"booked_cents"`
+	Rate          float64 `json:"rate"`          // 0 without a stored rate
+	CurrentCents  int64   `json:"current_cents"` // 0 without a stored rate
+}
+
+// DiffCents is how much more the revenue is worth at the current rate than
+// it was booked at; 0 without a rate
+func (t CurrencyTotal) DiffCents() int64 {
+	if t.Rate == 0 {
+		return 0
+	}
+	return t.CurrentCents - t.BookedCents
+}
+
+// CurrencyReport is revenue by the currency projects were quoted in
+type CurrencyReport struct {
+	Base        CurrencyTotal   `json:"base"`    // projects quoted in BaseCurrency
+	Foreign     []CurrencyTotal `json:"foreign"` // by currency code
+	BookedCents int64           `json:"booked_cents"`
+}
+
+// NewCurrencyReport totals projects' revenue by currency, valuing foreign
+// revenue at the stored rates as well as at the rates it was booked at.
+// Lost projects are left out.
+func NewCurrencyReport(projects []Project, rates []ExchangeRate) CurrencyReport {
+	r := CurrencyReport{Base: CurrencyTotal{Currency: BaseCurrency, Rate: 1}, Foreign: []CurrencyTotal{}}
+	byCode := map[string]*CurrencyTotal{}
+	var codes []string
+	for _, p := range projects {
+		if p.Status == StatusLost {
+			continue
+		}
+		r.BookedCents += p.RevenueCents
+		if !p.Foreign() {
+			r.Base.Projects++
+			r.Base.OriginalCents += p.RevenueCents
+			r.Base.BookedCents += p.RevenueCents
+			continue
+		}
+		t := byCode[p.Currency]
+		if t == nil {
+			t = &CurrencyTotal{Currency: p.Currency}
+			byCode[p.Currency] = t
+			codes = append(codes, p.Currency)
+		}
+		t.Projects++
+		t.OriginalCents += p.OriginalCents
+		t.BookedCents += p.RevenueCents
+	}
+	r.Base.CurrentCents = r.Base.BookedCents
+	for _, rate := range rates {
+		if t := byCode[rate.Currency]; t != nil {
+			t.Rate = rate.Rate
+			t.CurrentCents = rate.Convert(t.OriginalCents)
+		}
+	}
+	slices.Sort(codes)
+	for _, code := range codes {
+		r.Foreign = append(r.Foreign, *byCode[code])
+	}
+	return r
+}
+
+// ValidCurrency reports whether s looks like an ISO 4217 code: three
+// upper-case letters
+func ValidCurrency(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Activity is an entry in the dashboard activity feed
+type Activity struct {
+	ID        int64     `json:"id" db:"id"`
+	ProjectID int64     `json:"project_id" db:"project_id"`
+	Client    string    `json:"client" db:"client"`
+	Action    string    `json:"action" db:"action"` // "created", "updated", "deleted"
+	Summary   string    `json:"summary" db:"summary"`
+	Actor     string    `json:"actor" db:"actor"`     // user name, API key name, "automation", ...
+	Source    string    `json:"source" db:"source"`   // user|api|automation|stripe
+	Changes   []Change  `json:"changes" db:"changes"` // field-level diff, stored as JSON
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	// Undo/redo: the acting user, JSON snapshots of the project around the
+	// change ("" = did not exist / not recorded) and the entry that reverted it
+	UserID     int64  `json:"-" db:"user_id"`
+	Before     string `json:"-" db:"snapshot_before"`
+	After      string `json:"-" db:"snapshot_after"`
+	RevertedBy int64  `json:"reverted_by,omitempty" db:"reverted_by"`
+
+	RequestID string `json:"request_id,omitempty" db:"request_id"` // the request that made the change, as logged; "" for jobs and webhooks
+}
+
+// Change describes a single field that differs between two snapshots
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// String renders the change as "field old → new"
+func (c Change) String() string {
+	return c.Field + " " + c.Old + " → " + c.New
+}
+
+// IntegrityCheck is the result of a PRAGMA integrity/foreign key check
+type IntegrityCheck struct {
+	ID        int64     `json:"id" db:"id"`
+	OK        bool      `json:"ok" db:"ok"`
+	Details   string    `json:"details" db:"details"` // newline-separated problems
+	CheckedAt time.Time `json:"checked_at" db:"checked_at"`
+}
+
+// APIScope is the permission level granted to an API key
+type APIScope string
+
+const (
+	ScopeRead  APIScope = "read"
+	ScopeWrite APIScope = "write"
+	ScopeAdmin APIScope = "admin"
+)
+
+// Valid reports whether s is a known scope
+func (s APIScope) Valid() bool {
+	return s == ScopeRead || s == ScopeWrite || s == ScopeAdmin
+}
+
+// Allows reports whether this scope grants the required scope (admin ⊇ write ⊇ read)
+func (s APIScope) Allows(required APIScope) bool {
+	rank := map[APIScope]int{ScopeRead: 1, ScopeWrite: 2, ScopeAdmin: 3}
+	return rank[s] >= rank[required] && rank[required] > 0
+}
+
+// APIKey is an issued bearer token; only its hash is stored
+type APIKey struct {
+	ID         int64      `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	Scope      APIScope   `json:"scope" db:"scope"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// DumpSummary describes a whole-database JSON dump written or loaded
+type DumpSummary struct {
+	SchemaVersion int       `json:"schema_version"` // migrations applied when it was made
+	ExportedAt    time.Time `json:"exported_at"`
+	Tables        int       `json:"tables"`
+	Rows          int       `json:"rows"`
+}
+
+// DRTest is the result of a disaster recovery dry run
+type DRTest struct {
+	ID         int64     `json:"id" db:"id"`
+	BackupFile string    `json:"backup_file" db:"backup_file"`
+	OK         bool      `json:"ok" db:"ok"`
+	Details    string    `json:"details" db:"details"` // newline-separated step log
+	CheckedAt  time.Time `json:"checked_at" db:"checked_at"`
+}
+
+// Backup triggers: the schedule, an owner in Admin, or the command line
+const (
+	BackupScheduled = "schedule"
+	BackupManual    = "manual"
+	BackupCLI       = "cli"
+)
+
+// BackupRun is one snapshot stored at one destination, or the failure to
+// store it
+type BackupRun struct {
+	ID          int64     `json:"id" db:"id"`
+	Destination string    `json:"destination" db:"destination"` // "local", or a remote such as "s3 bucket/prefix"
+	File        string    `json:"file" db:"file"`               // the snapshot's name; "" when none was made
+	SizeBytes   int64     `json:"size_bytes" db:"size_bytes"`
+	OK          bool      `json:"ok" db:"ok"`
+	Details     string    `json:"details" db:"details"` // the error, or the snapshots retention deleted
+	Trigger     string    `json:"trigger" db:"trigger"`
+	StartedAt   time.Time `json:"started_at" db:"started_at"`
+	FinishedAt  time.Time `json:"finished_at" db:"finished_at"`
+}
+
+// Duration is how long storing the snapshot took
+func (r BackupRun) Duration() time.Duration {
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// BackupDestination is a destination's latest success and failure, either
+// nil when there has been none
+type BackupDestination struct {
+	Name        string     `json:"name"`
+	LastSuccess *BackupRun `json:"last_success"`
+	LastFailure *BackupRun `json:"last_failure"`
+}
+
+// Failing is true when the latest run failed
+func (d BackupDestination) Failing() bool {
+	return d.LastFailure != nil && (d.LastSuccess == nil || d.LastFailure.StartedAt.After(d.LastSuccess.StartedAt))
+}
+
+// Replication roles: the primary writes, replicas only read
+const (
+	ReplicationPrimary = "primary"
+	ReplicationReplica = "replica"
+)
+
+// ReplicationStatus is what the replication health probe reports
+type ReplicationStatus struct {
+	Mode        string        `json:"mode"` // "litestream", "litefs" or "" (off)
+	Role        string        `json:"role"`
+	Primary     string        `json:"primary,omitempty"` // the primary a LiteFS replica follows
+	JournalMode string        `json:"journal_mode"`
+	HeartbeatAt time.Time     `json:"heartbeat_at"` // zero before the primary's first heartbeat
+	Lag         time.Duration `json:"lag"`
+	MaxLag      time.Duration `json:"max_lag"`
+	Problems    []string      `json:"problems"`
+}
+
+// OK is true when nothing is wrong
+func (s ReplicationStatus) OK() bool {
+	return len(s.Problems) == 0
+}
+
+// QueryStat counts the runs of one SQL statement while the query log is on
+type QueryStat struct {
+	Query  string        `json:"query"` // whitespace collapsed
+	Count  int64         `json:"count"`
+	Errors int64         `json:"errors"`
+	Slow   int64         `json:"slow"` // runs over the slow threshold
+	Total  time.Duration `json:"total"`
+	Max    time.Duration `json:"max"`
+}
+
+// Mean is the average time per run
+func (s QueryStat) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// QueryStats is what the query metrics endpoint reports
+type QueryStats struct {
+	Enabled bool          `json:"enabled"`        // false unless DB_LOG_QUERIES or DB_SLOW_QUERY is set
+	Slow    time.Duration `json:"slow_threshold"` // 0 when only logging
+	Since   time.Time     `json:"since"`          // counting started (server start)
+	Queries []QueryStat   `json:"queries"`        // most total time first
+}
+
+// Storage growth is measured over the last StorageGrowthDays, once at least
+// StorageGrowthMinDays of them have measurements
+const (
+	StorageGrowthDays    = 30
+	StorageGrowthMinDays = 7
+)
+
+// StorageUsage is the disk space in use on a day
+type StorageUsage struct {
+	Day             time.Time `json:"day" db:"day"`
+	DBBytes         int64     `json:"db_bytes" db:"db_bytes"`                 // database pages, free ones included
+	AttachmentBytes int64     `json:"attachment_bytes" db:"attachment_bytes"` // files under UPLOAD_DIR (receipts)
+}
+
+// Total is the database and attachments together
+func (u StorageUsage) Total() int64 {
+	return u.DBBytes + u.AttachmentBytes
+}
+
+// StorageQuota holds the soft limits that raise storage warnings, in
+// megabytes; zero turns a limit off
+type StorageQuota struct {
+	MaxMB       int64 // total size
+	MaxGrowthMB int64 // growth per StorageGrowthDays
+}
+
+// Default soft limits until they are changed on the admin page
+const (
+	DefaultStorageMaxMB       = 1024
+	DefaultStorageMaxGrowthMB = 100
+)
+
+// StorageReport is the latest storage measurement next to the earliest one
+// in the growth window, checked against the quota
+type StorageReport struct {
+	Latest  *StorageUsage  `json:"latest"`  // nil until the first measurement
+	Earlier *StorageUsage  `json:"earlier"` // nil if Latest is the only one in the window
+	History []StorageUsage `json:"history"` // each month's last measurement, oldest first
+	Quota   StorageQuota   `json:"quota"`
+}
+
+// Growth is how much storage grew, scaled to StorageGrowthDays; ok is false
+// until the measurements span StorageGrowthMinDays
+func (r StorageReport) Growth() (bytes int64, ok bool) {
+	if r.Latest == nil || r.Earlier == nil {
+		return 0, false
+	}
+	days := r.Latest.Day.Sub(r.Earlier.Day).Hours() / 24
+	if days < StorageGrowthMinDays {
+		return 0, false
+	}
+	return int64(float64(r.Latest.Total()-r.Earlier.Total()) * StorageGrowthDays / days), true
+}
+
+// Warnings lists the soft limits the latest measurement passes
+func (r StorageReport) Warnings() []string {
+	if r.Latest == nil {
+		return nil
+	}
+	var warnings []string
+	if max := r.Quota.MaxMB << 20; max > 0 && r.Latest.Total() > max {
+		warnings = append(warnings, fmt.Sprintf("Storage is %s, over the %s soft limit",
+			FormatBytes(r.Latest.Total()), FormatBytes(max)))
+	}
+	if growth, ok := r.Growth(); ok && r.Quota.MaxGrowthMB > 0 && growth > r.Quota.MaxGrowthMB<<20 {
+		warnings = append(warnings, fmt.Sprintf("Storage is growing %s per %d days, over the %s soft limit",
+			FormatBytes(growth), StorageGrowthDays, FormatBytes(r.Quota.MaxGrowthMB<<20)))
+	}
+	return warnings
+}
+
+// FormatBytes shows a size in B, KB, MB or GB (binary units)
+func FormatBytes(n int64) string {
+	const unit = 1 << 10
+	if n < unit && n > -unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	f, suffix := float64(n)/unit, "KB"
+	for _, s := range []string{"MB", "GB"} {
+		if f < unit && f > -unit {
+			break
+		}
+		f, suffix = f/unit, s
+	}
+	return fmt.Sprintf("%.1f %s", f, suffix)
+}
+
+// Expense is a cost paid personally by a partner, either for a project or
+// as overhead (hosting, domains, subscriptions) shared by the business
+type Expense struct {
+	ID          int64           `json:"id" db:"id"`
+	ProjectID   int64           `json:"project_id,omitempty" db:"project_id"` // 0 = overhead
+	PayerID     int64           `json:"payer_id" db:"payer_id"`               // person ID
+	Description string          `json:"description" db:"description"`
+	Category    ExpenseCategory `json:"category" db:"category"`
+	AmountCents int64           `json:"amount_cents" db:"amount_cents"`
+	SpentOn     time.Time       `json:"spent_on" db:"spent_on"`
+	ReceiptPath string          `json:"-" db:"receipt_path"`     // file name under the upload dir
+	Client      string          `json:"client,omitempty" db:"-"` // the project's client, when listed across projects
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
+
+// Amount returns the expense in whole currency units
+func (e Expense) Amount() float64 {
+	return float64(e.AmountCents) / 100
+}
+
+// Overhead reports whether the expense belongs to no project
+func (e Expense) Overhead() bool {
+	return e.ProjectID == 0
+}
+
+// ExpenseCategory groups expenses for the expense page and its totals
+type ExpenseCategory string
+
+const (
+	ExpenseHosting  ExpenseCategory = "hosting"
+	ExpenseDomains  ExpenseCategory = "domains"
+	ExpenseSoftware ExpenseCategory = "software"
+	ExpenseHardware ExpenseCategory = "hardware"
+	ExpenseTravel   ExpenseCategory = "travel"
+	ExpenseOther    ExpenseCategory = "other"
+)
+
+// ExpenseCategories lists the categories in display order
+var ExpenseCategories = []ExpenseCategory{ExpenseHosting, ExpenseDomains, ExpenseSoftware, ExpenseHardware, ExpenseTravel, ExpenseOther}
+
+// Valid reports whether c is a known category
+func (c ExpenseCategory) Valid() bool {
+	return slices.Contains(ExpenseCategories, c)
+}
+
+// Label is the category's display name
+func (c ExpenseCategory) Label() string {
+	if c == "" {
+		return "Other"
+	}
+	return strings.ToUpper(string(c[:1])) + string(c[1:])
+}
+
+// ExpenseFilter narrows the expense page's list; zero values match everything
+type ExpenseFilter struct {
+	Year     int
+	Category ExpenseCategory
+}
+
+// ExpenseTotal is one category's total for the expense page
+type ExpenseTotal struct {
+	Category    ExpenseCategory `json:"category"`
+	Count       int             `json:"count"`
+	AmountCents int64           `json:"amount_cents"`
+}
+
+// Invoice is a bill to a project's client. It starts as an unnumbered draft;
+// issuing it takes the next number in the year, after which only its status
+// may change. Amounts are excluding VAT, in Currency (BaseCurrency if empty).
+type Invoice struct {
+	ID          int64         `json:"id" db:"id"`
+	Number      string        `json:"number,omitempty" db:"number"` // "2026-0001", empty while a draft
+	Year        int           `json:"year,omitempty" db:"year"`
+	Seq         int           `json:"seq,omitempty" db:"seq"`               // gapless within Year
+	ProjectID   int64         `json:"project_id,omitempty" db:"project_id"` // 0 once the project is deleted
+	Client      string        `json:"client" db:"client"`
+	Lines       []InvoiceLine `json:"lines" db:"lines"`
+	Currency    string        `json:"currency,omitempty" db:"currency"`
+	VATPercent  float64       `json:"vat_percent" db:"vat_percent"`
+	PaymentDays int           `json:"payment_days" db:"payment_days"` // terms: due this many days after issue
+	Status      InvoiceStatus `json:"status" db:"status"`
+	IssueDate   time.Time     `json:"issue_date,omitzero" db:"issue_date"`
+	DueDate     time.Time     `json:"due_date,omitzero" db:"due_date"`
+	PaidOn      time.Time     `json:"paid_on,omitzero" db:"paid_on"`
+	CreatedBy   string        `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	Fortnox     FortnoxSync   `json:"fortnox" db:"-"`
+}
+
+// FortnoxSync is how far an issued invoice has been pushed to Fortnox
+type FortnoxSync struct {
+	Number   string     `json:"number,omitempty"` // Fortnox's invoice number, once it's there
+	Paid     bool       `json:"paid,omitempty"`   // its payment has been registered there too
+	Error    string     `json:"error,omitempty"`  // why the last push failed; cleared by one that works
+	SyncedAt *time.Time `json:"synced_at,omitempty"`
+}
+
+// FortnoxDue reports whether Fortnox is missing something of the invoice:
+// the invoice itself once issued, then its payment. Drafts and void
+// invoices are never pushed.
+func (inv Invoice) FortnoxDue() bool {
+	if inv.Draft() || inv.Status == InvoiceVoid && inv.Fortnox.Number == "" {
+		return false
+	}
+	return inv.Fortnox.Number == "" || inv.Status == InvoicePaid && !inv.Fortnox.Paid
+}
+
+// FortnoxState names the invoice's sync state for the Fortnox column:
+// "" for drafts and void invoices never pushed
+func (inv Invoice) FortnoxState() string {
+	switch {
+	case inv.Draft() || inv.Status == InvoiceVoid && inv.Fortnox.Number == "":
+		return ""
+	case inv.Fortnox.Error != "":
+		return "Failed"
+	case inv.FortnoxDue():
+		return "Not synced"
+	}
+	return "Synced"
+}
+
+// Default terms for a new project or invoice: Swedish standard VAT, 30 days
+// net
+const (
+	DefaultVATPercent  = 25
+	DefaultPaymentDays = 30
+)
+
+// PaymentTerms are the terms offered on the project form, in days; the API
+// and invoices take any
+var PaymentTerms = []int{14, DefaultPaymentDays}
+
+// ErrInvoiceIssued refuses a change to an issued invoice's contents, or the
+// deletion of one: its number is taken and it is part of the books
+var ErrInvoiceIssued = errors.New("only draft invoices can be changed or deleted; void it instead")
+
+// InvoiceLine is one row of an invoice
+type InvoiceLine struct {
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"`
+	UnitCents   int64   `json:"unit_cents"` // price per unit, excluding VAT
+}
+
+// AmountCents is the line's total, rounded to the cent
+func (l InvoiceLine) AmountCents() int64 {
+	return int64(math.Round(l.Quantity * float64(l.UnitCents)))
+}
+
+// SubtotalCents sums the lines, excluding VAT
+func (inv Invoice) SubtotalCents() int64 {
+	var t int64
+	for _, l := range inv.Lines {
+		t += l.AmountCents()
+	}
+	return t
+}
+
+// VATCents is the VAT on the subtotal, rounded to the cent
+func (inv Invoice) VATCents() int64 {
+	return VATOn(inv.SubtotalCents(), inv.VATPercent)
+}
+
+// TotalCents is what the client pays
+func (inv Invoice) TotalCents() int64 {
+	return inv.SubtotalCents() + inv.VATCents()
+}
+
+// Draft reports whether the invoice can still be edited (it has no number)
+func (inv Invoice) Draft() bool {
+	return inv.Status == InvoiceDraft
+}
+
+// Overdue reports whether an issued invoice is unpaid past its due date
+func (inv Invoice) Overdue(now time.Time) bool {
+	return inv.Status == InvoiceIssued && now.Format("2006-01-02") > inv.DueDate.Format("2006-01-02")
+}
+
+// Label is the invoice's number, or "Draft" before it's issued
+func (inv Invoice) Label() string {
+	if inv.Number == "" {
+		return "Draft"
+	}
+	return inv.Number
+}
+
+// InvoiceStatus is where an invoice is in its life
+type InvoiceStatus string
+
+const (
+	InvoiceDraft  InvoiceStatus = "draft"  // editable, unnumbered
+	InvoiceIssued InvoiceStatus = "issued" // numbered and sent, awaiting payment
+	InvoicePaid   InvoiceStatus = "paid"
+	InvoiceVoid   InvoiceStatus = "void" // cancelled; keeps its number so the series stays gapless
+
+	// InvoiceOverdue is only a filter: issued invoices past their due date
+	InvoiceOverdue InvoiceStatus = "overdue"
+)
+
+// InvoiceStatuses lists the stored statuses in display order
+var InvoiceStatuses = []InvoiceStatus{InvoiceDraft, InvoiceIssued, InvoicePaid, InvoiceVoid}
+
+// Valid reports whether s is a stored status
+func (s InvoiceStatus) Valid() bool {
+	return slices.Contains(InvoiceStatuses, s)
+}
+
+// Label is the status's display name
+func (s InvoiceStatus) Label() string {
+	if s == "" {
+		return "All"
+	}
+	return strings.ToUpper(string(s[:1])) + string(s[1:])
+}
+
+// InvoiceMoves lists the status changes allowed after issuing: paid or
+// voided, and a payment marked by mistake can be undone. Drafts are issued,
+// never moved, and a void invoice stays void.
+var InvoiceMoves = map[InvoiceStatus][]InvoiceStatus{
+	InvoiceIssued: {InvoicePaid, InvoiceVoid},
+	InvoicePaid:   {InvoiceIssued},
+}
+
+// CanMove reports whether the invoice may move to status to
+func (inv Invoice) CanMove(to InvoiceStatus) bool {
+	return slices.Contains(InvoiceMoves[inv.Status], to)
+}
+
+// Role controls what a user may do in the web UI
+type Role string
+
+const (
+	RoleViewer  Role = "viewer"  // read-only dashboard
+	RolePartner Role = "partner" // edit projects, log hours and expenses
+	RoleOwner   Role = "owner"   // delete, settings, user management
+)
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	return r == RoleViewer || r == RolePartner || r == RoleOwner
+}
+
+// Allows reports whether this role grants the required one (owner ⊇ partner ⊇ viewer)
+func (r Role) Allows(required Role) bool {
+	rank := map[Role]int{RoleViewer: 1, RolePartner: 2, RoleOwner: 3}
+	return rank[r] >= rank[required] && rank[required] > 0
+}
+
+// User is a person who can log in to the dashboard
+type User struct {
+	ID            int64     `json:"id" db:"id"`
+	Email         string    `json:"email" db:"email"`
+	Name          string    `json:"name" db:"name"`
+	PasswordHash  string    `json:"-" db:"password_hash"`
+	Role          Role      `json:"role" db:"role"`
+	WeeklyDigest  bool      `json:"weekly_digest" db:"weekly_digest"`   // emailed the weekly summary (partners)
+	MonthlyReport bool      `json:"monthly_report" db:"monthly_report"` // the month's PDF report attached to the first weekly summary after it
+	CalendarFeed  bool      `json:"calendar_feed"`                      // has a calendar feed token
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// Setting keys stored in the settings table
+const (
+	SettingMileageRate   = "mileage_rate_cents" // per km, integer cents
+	SettingKickoffGate   = "kickoff_gate"       // "1" to enforce the kickoff checklist
+	SettingDeliveryGate  = "delivery_gate"      // "1" to enforce the definition-of-done checklist
+	SettingPortalSecret  = "portal_secret"      // generated when PORTAL_SECRET is unset
+	SettingNotifyRules   = "notification_rules" // JSON []NotificationRule
+	SettingStorageMax    = "storage_max_mb"     // soft limit on total storage, MB (0 = off)
+	SettingStorageGrowth = "storage_growth_mb"  // soft limit on storage growth per 30 days, MB (0 = off)
+	SettingHourlyRates   = "hourly_rates"       // JSON person ID → cents per hour, weighing hours-based splits
+	SettingCompany       = "company"            // JSON Company, printed on invoices
+	SettingAccounts      = "accounts"           // JSON Accounts, booked to by the SIE export
+	SettingFortnox       = "fortnox"            // JSON FortnoxCredentials
+	SettingGoogleCal     = "google_calendar"    // JSON GoogleCalendarCredentials
+	SettingGoogleCalSync = "calendar_sync"      // JSON GoogleCalendarSync, the last sync's outcome
+	SettingBudgetWarn    = "budget_thresholds"  // JSON []int, percentages of a budget burned that warn
+	SettingHeartbeat     = "heartbeat"          // RFC 3339 time the replication primary last wrote, for replicas' lag
+)
+
+// DefaultMileageRateCents is the Swedish tax-free allowance (25 kr/mil = 2.50 kr/km)
+const DefaultMileageRateCents = 250
+
+// TravelEntry is a logged trip for a client visit
+type TravelEntry struct {
+	ID        int64     `json:"id" db:"id"`
+	ProjectID int64     `json:"project_id,omitempty" db:"project_id"` // 0 = not linked
+	ExpenseID int64     `json:"expense_id,omitempty" db:"expense_id"` // reimbursement created from this trip
+	PersonID  int64     `json:"person_id" db:"person_id"`
+	Date      time.Time `json:"date" db:"date"`
+	Client    string    `json:"client" db:"client"`
+	Km        float64   `json:"km" db:"km"`
+	Purpose   string    `json:"purpose" db:"purpose"`
+	RateCents int64     `json:"rate_cents" db:"rate_cents"` // per km, snapshotted at entry time
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// AmountCents is the mileage allowance for the trip
+func (t TravelEntry) AmountCents() int64 {
+	return int64(math.Round(t.Km * float64(t.RateCents)))
+}
+
+// MileageSummary totals one person's trips for a year
+type MileageSummary struct {
+	PersonID    int64   `json:"person_id"`
+	Trips       int     `json:"trips"`
+	Km          float64 `json:"km"`
+	AmountCents int64   `json:"amount_cents"`
+}
+
+// ChecklistStage groups checklist items by the transition they gate
+type ChecklistStage string
+
+const (
+	StageKickoff  ChecklistStage = "kickoff"  // gates leaving "new" (hard)
+	StageDelivery ChecklistStage = "delivery" // definition of done, gates moving to "done" (soft)
+)
+
+// ChecklistStages lists the stages in the order they are attached
+var ChecklistStages = []ChecklistStage{StageKickoff, StageDelivery}
+
+// Valid reports whether s is a known checklist stage
+func (s ChecklistStage) Valid() bool {
+	return s == StageKickoff || s == StageDelivery
+}
+
+// ChecklistTemplate is a standard item copied onto every new project
+type ChecklistTemplate struct {
+	ID       int64          `json:"id" db:"id"`
+	Stage    ChecklistStage `json:"stage" db:"stage"`
+	Title    string         `json:"title" db:"title"`
+	Critical bool           `json:"critical" db:"critical"` // blocks the gated transition while open
+	Position int            `json:"position" db:"position"`
+}
+
+// ChecklistItem is a project's copy of a checklist template
+type ChecklistItem struct {
+	ID        int64          `json:"id" db:"id"`
+	ProjectID int64          `json:"project_id" db:"project_id"`
+	Stage     ChecklistStage `json:"stage" db:"stage"`
+	Title     string         `json:"title" db:"title"`
+	Critical  bool           `json:"critical" db:"critical"`
+	Done      bool           `json:"done" db:"done"`
+	DoneAt    *time.Time     `json:"done_at,omitempty" db:"done_at"`
+	Position  int            `json:"position" db:"position"`
+}
+
+// Settings is the typed view of the settings table
+type Settings struct {
+	MileageRateCents int64
+	KickoffGate      bool // block leaving "new" while critical kickoff items are open
+	DeliveryGate     bool // require an override to mark done while critical delivery items are open
+	StorageQuota     StorageQuota
+	HourlyRates      PerPerson[int64] // cents per hour; unset for people without a rate
+	BudgetThresholds []int            // percentages of a project's budget burned that warn, ascending
+	Company          Company
+	Accounts         Accounts // with defaults filled in
+	Fortnox          FortnoxCredentials
+	GoogleCalendar   GoogleCalendarCredentials
+	GoogleCalSync    GoogleCalendarSync
+}
+
+// Company is the business's own details, printed on invoices
+type Company struct {
+	Name      string `json:"name"`
+	OrgNumber string `json:"org_number"` // organisationsnummer
+	VATNumber string `json:"vat_number"` // e.g. SE556677889901
+	Address   string `json:"address"`    // one line per line of the address
+	Email     string `json:"email"`
+	Bankgiro  string `json:"bankgiro"` // e.g. 123-4567
+}
+
+// FortnoxCredentials connect to Fortnox: the integration's client ID and
+// secret, and the OAuth tokens it was granted. Fortnox replaces the refresh
+// token every time it's used, so the current one is stored back each time.
+type FortnoxCredentials struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret"`
+	RefreshToken string    `json:"refresh_token"`
+	AccessToken  string    `json:"access_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitzero"`
+}
+
+// Connected reports whether invoices are pushed to Fortnox
+func (c FortnoxCredentials) Connected() bool {
+	return c.ClientID != "" && c.ClientSecret != "" && c.RefreshToken != ""
+}
+
+// GoogleCalendarCredentials connect to a Google Calendar: an OAuth client's
+// ID and secret, a refresh token granted with the calendar scope, and the
+// calendar to keep the events in. The access token is refreshed as needed.
+type GoogleCalendarCredentials struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret"`
+	RefreshToken string    `json:"refresh_token"`
+	CalendarID   string    `json:"calendar_id,omitempty"` // "" for the account's primary calendar
+	AccessToken  string    `json:"access_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitzero"`
+}
+
+// Connected reports whether events are synced to Google Calendar
+func (c GoogleCalendarCredentials) Connected() bool {
+	return c.ClientID != "" && c.ClientSecret != "" && c.RefreshToken != ""
+}
+
+// Calendar is the calendar's ID, "primary" unless another is set
+func (c GoogleCalendarCredentials) Calendar() string {
+	if c.CalendarID == "" {
+		return "primary"
+	}
+	return c.CalendarID
+}
+
+// GoogleCalendarSync is the outcome of the last Google Calendar sync
+type GoogleCalendarSync struct {
+	At      time.Time `json:"at"`
+	Events  int       `json:"events"` // kept in the calendar after the sync
+	Created int       `json:"created"`
+	Updated int       `json:"updated"`
+	Deleted int       `json:"deleted"`
+	Pulled  int       `json:"pulled"` // milestone dates taken from events moved in Google
+	Error   string    `json:"error,omitempty"`
+}
+
+// Accounts maps the books onto the chart of accounts the SIE export books
+// them to. The defaults are the BAS accounts most Swedish small businesses
+// use; a blank account falls back to its default.
+type Accounts struct {
+	Bank        string                     `json:"bank"`         // payments received
+	Receivables string                     `json:"receivables"`  // issued invoices until paid
+	Sales       string                     `json:"sales"`        // revenue with VAT
+	SalesExempt string                     `json:"sales_exempt"` // revenue without VAT
+	VAT25       string                     `json:"vat_25"`       // output VAT at 25%
+	VAT12       string                     `json:"vat_12"`       // output VAT at 12%
+	VAT6        string                     `json:"vat_6"`        // output VAT at 6%
+	PartnerDebt string                     `json:"partner_debt"` // expenses a partner paid, owed back
+	Expenses    map[ExpenseCategory]string `json:"expenses"`     // cost account by category
+}
+
+// DefaultAccounts are the BAS accounts booked to when none are set
+var DefaultAccounts = Accounts{
+	Bank:        "1930",
+	Receivables: "1510",
+	Sales:       "3001",
+	SalesExempt: "3004",
+	VAT25:       "2611",
+	VAT12:       "2621",
+	VAT6:        "2631",
+	PartnerDebt: "2893",
+	Expenses: map[ExpenseCategory]string{
+		ExpenseHosting:  "6540",
+		ExpenseDomains:  "6540",
+		ExpenseSoftware: "5420",
+		ExpenseHardware: "5410",
+		ExpenseTravel:   "5800",
+		ExpenseOther:    "6990",
+	},
+}
+
+// WithDefaults fills blank accounts from DefaultAccounts
+func (a Accounts) WithDefaults() Accounts {
+	d := DefaultAccounts
+	a.Bank, a.Receivables = cmp.Or(a.Bank, d.Bank), cmp.Or(a.Receivables, d.Receivables)
+	a.Sales, a.SalesExempt = cmp.Or(a.Sales, d.Sales), cmp.Or(a.SalesExempt, d.SalesExempt)
+	a.VAT25, a.VAT12, a.VAT6 = cmp.Or(a.VAT25, d.VAT25), cmp.Or(a.VAT12, d.VAT12), cmp.Or(a.VAT6, d.VAT6)
+	a.PartnerDebt = cmp.Or(a.PartnerDebt, d.PartnerDebt)
+	expenses := make(map[ExpenseCategory]string, len(ExpenseCategories))
+	for _, c := range ExpenseCategories {
+		expenses[c] = cmp.Or(a.Expenses[c], d.Expenses[c])
+	}
+	a.Expenses = expenses
+	return a
+}
+
+// SalesAccount is where revenue at a VAT rate is booked
+func (a Accounts) SalesAccount(vatPercent float64) string {
+	if vatPercent == 0 {
+		return a.SalesExempt
+	}
+	return a.Sales
+}
+
+// VATAccount is where output VAT at a rate is booked; rates other than the
+// reduced 12% and 6% go with the standard rate
+func (a Accounts) VATAccount(vatPercent float64) string {
+	switch vatPercent {
+	case 12:
+		return a.VAT12
+	case 6:
+		return a.VAT6
+	}
+	return a.VAT25
+}
+
+// ExpenseAccount is where an expense in category c is booked
+func (a Accounts) ExpenseAccount(c ExpenseCategory) string {
+	return cmp.Or(a.Expenses[c], a.Expenses[ExpenseOther])
+}
+
+// ValidAccount reports whether s is an account number: digits only
+func ValidAccount(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// AutomationTrigger names the event an automation rule reacts to
+type AutomationTrigger string
+
+// TriggerPayment fires when a payment is received; status triggers are
+// built per status with StatusTrigger and TransitionTrigger
+const TriggerPayment AutomationTrigger = "payment_received"
+
+// StatusTrigger returns the trigger fired when a project moves to s
+func StatusTrigger(s ProjectStatus) AutomationTrigger {
+	return AutomationTrigger("status:" + string(s))
+}
+
+// TransitionTrigger returns the trigger fired when a project moves from → to
+func TransitionTrigger(from, to ProjectStatus) AutomationTrigger {
+	return AutomationTrigger("transition:" + string(from) + ">" + string(to))
+}
+
+// AutomationRule runs an action, optionally delayed, when its trigger fires
+type AutomationRule struct {
+	ID        int64             `json:"id" db:"id"`
+	Trigger   AutomationTrigger `json:"trigger" db:"trigger"`
+	Action    string            `json:"action" db:"action"`
+	Param     string            `json:"param,omitempty" db:"param"` // action argument, e.g. a status
+	DelayDays int               `json:"delay_days" db:"delay_days"`
+	Enabled   bool              `json:"enabled" db:"enabled"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+}
+
+// AutomationRun is a rule application queued for a project
+type AutomationRun struct {
+	ID        int64      `json:"id" db:"id"`
+	RuleID    int64      `json:"rule_id" db:"rule_id"`
+	ProjectID int64      `json:"project_id" db:"project_id"`
+	RunAt     time.Time  `json:"run_at" db:"run_at"`
+	DoneAt    *time.Time `json:"done_at,omitempty" db:"done_at"`
+	Error     string     `json:"error,omitempty" db:"error"`
+}
+
+// NotificationRule sends matching events to a channel. An empty Field
+// matches every event of the type.
+type NotificationRule struct {
+	ID      int64  `json:"id"`
+	Event   string `json:"event"`           // events.Type, e.g. "project.status_changed"
+	Field   string `json:"field,omitempty"` // amount|client|status
+	Op      string `json:"op,omitempty"`    // > >= < <= = != contains
+	Value   string `json:"value,omitempty"`
+	Channel string `json:"channel"`          // log|webhook
+	Target  string `json:"target,omitempty"` // channel address, e.g. webhook URL
+}
+
+// DeliveryMode is how a user receives one type of notification
+type DeliveryMode string
+
+const (
+	DeliveryInstant DeliveryMode = "instant"
+	DeliveryDigest  DeliveryMode = "digest" // queued and grouped into a daily digest
+	DeliveryOff     DeliveryMode = "off"
+
+	// DefaultDeliveryMode applies to types a user hasn't configured
+	DefaultDeliveryMode = DeliveryDigest
+)
+
+// DeliveryModes lists the modes in display order
+var DeliveryModes = []DeliveryMode{DeliveryInstant, DeliveryDigest, DeliveryOff}
+
+// Valid reports whether m is a known delivery mode
+func (m DeliveryMode) Valid() bool {
+	return m == DeliveryInstant || m == DeliveryDigest || m == DeliveryOff
+}
+
+// Notification is an entry in a user's inbox, or queued for their digest
+type Notification struct {
+	ID        int64     `json:"id" db:"id"`
+	UserID    int64     `json:"user_id" db:"user_id"`
+	Kind      string    `json:"kind" db:"kind"` // event type, or "digest"
+	ProjectID int64     `json:"project_id,omitempty" db:"project_id"`
+	Body      string    `json:"body" db:"body"`
+	Queued    bool      `json:"queued" db:"queued"` // waiting for the next digest
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// WeeklySummary is what the weekly email reports, for the week from From
+// up to (not including) To
+type WeeklySummary struct {
+	From    time.Time
+	To      time.Time
+	Paid    []Project     // moved to paid in the week
+	Created []Project     // added in the week
+	Moved   []Activity    // status changes, oldest first
+	Overdue []Receivable  // past due when the week ended
+	Hours   []PersonHours // logged in the week per person (Hours only), busiest first
+}
+
+// PaidCents totals the revenue received in the week
+func (s WeeklySummary) PaidCents() int64 {
+	var n int64
+	for _, p := range s.Paid {
+		n += p.RevenueCents
+	}
+	return n
+}
+
+// OverdueCents totals what's overdue
+func (s WeeklySummary) OverdueCents() int64 {
+	var n int64
+	for _, r := range s.Overdue {
+		n += r.AmountCents
+	}
+	return n
+}
+
+// TotalHours totals the hours logged in the week
+func (s WeeklySummary) TotalHours() float64 {
+	var n float64
+	for _, h := range s.Hours {
+		n += h.Hours
+	}
+	return n
+}
+
+// Webhook is an outside endpoint POSTed signed JSON for domain events. An
+// empty Events list subscribes it to every event webhooks are sent for.
+type Webhook struct {
+	ID        int64     `json:"id" db:"id"`
+	URL       string    `json:"url" db:"url"`
+	Secret    string    `json:"-" db:"secret"`        // signs each payload (HMAC-SHA256)
+	Events    []string  `json:"events" db:"events"`   // event types, e.g. "project.created"
+	Enabled   bool      `json:"enabled" db:"enabled"` // paused webhooks get no new deliveries; queued ones wait
+	Pending   int       `json:"pending" db:"pending"` // deliveries waiting for a (re)try
+	Failed    int       `json:"failed" db:"failed"`   // deliveries that ran out of retries
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Wants reports whether the webhook subscribes to an event type
+func (w Webhook) Wants(event string) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
+// WebhookDeliveryStatus is where a delivery stands
+type WebhookDeliveryStatus string
+
+const (
+	WebhookPending   WebhookDeliveryStatus = "pending" // not yet sent, or waiting to be retried
+	WebhookDelivered WebhookDeliveryStatus = "delivered"
+	WebhookFailed    WebhookDeliveryStatus = "failed" // every retry failed
+)
+
+// WebhookDelivery is one event's payload for a webhook and how sending it went
+type WebhookDelivery struct {
+	ID            int64                 `json:"id" db:"id"`
+	WebhookID     int64                 `json:"webhook_id" db:"webhook_id"`
+	Event         string                `json:"event" db:"event"`
+	Payload       string                `json:"payload" db:"payload"` // the JSON body, the same on every attempt
+	Status        WebhookDeliveryStatus `json:"status" db:"status"`
+	Attempts      int                   `json:"attempts" db:"attempts"`
+	ResponseCode  int                   `json:"response_code,omitempty" db:"response_code"` // of the last attempt, 0 if it got no response
+	Error         string                `json:"error,omitempty" db:"error"`                 // why the last attempt failed
+	NextAttemptAt *time.Time            `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	DeliveredAt   *time.Time            `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt     time.Time             `json:"created_at" db:"created_at"`
+}
+
+// MailKind is what an email was sent for
+type MailKind string
+
+const (
+	MailInvoice MailKind = "invoice"
+	MailReceipt MailKind = "receipt" // confirms a payment received through Stripe
+	MailInvite  MailKind = "invite"
+	MailWeekly  MailKind = "weekly" // a partner's weekly summary
+)
+
+// Label is the kind's display name
+func (k MailKind) Label() string {
+	switch k {
+	case MailInvoice:
+		return "Invoice"
+	case MailReceipt:
+		return "Receipt"
+	case MailInvite:
+		return "Meeting invite"
+	case MailWeekly:
+		return "Weekly summary"
+	}
+	return string(k)
+}
+
+// SentMail is an entry in the sent-mail log. Error is set when the message
+// couldn't be sent. One that failed for a reason that may pass keeps its
+// Message until it is retried at NextAttemptAt.
+type SentMail struct {
+	ID            int64      `json:"id" db:"id"`
+	Kind          MailKind   `json:"kind" db:"kind"`
+	Recipients    string     `json:"recipients" db:"recipients"` // comma-separated
+	Subject       string     `json:"subject" db:"subject"`
+	ProjectID     int64      `json:"project_id,omitempty" db:"project_id"`
+	InvoiceID     int64      `json:"invoice_id,omitempty" db:"invoice_id"`
+	Error         string     `json:"error,omitempty" db:"error"`
+	SentBy        string     `json:"sent_by" db:"sent_by"`
+	SentAt        time.Time  `json:"sent_at" db:"sent_at"`   // logged, or delivered on a retry
+	Provider      string     `json:"provider" db:"provider"` // smtp, resend or sendgrid
+	Attempts      int        `json:"attempts" db:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty" db:"next_attempt_at"` // set while a retry is due
+	Message       string     `json:"-" db:"message"`                                 // the message as JSON, kept while retrying
+}
+
+// Sent reports whether the message went out
+func (m SentMail) Sent() bool {
+	return m.Error == ""
+}
+
+// Retrying reports whether the message failed but will be tried again
+func (m SentMail) Retrying() bool {
+	return m.Error != "" && m.NextAttemptAt != nil
+}
+
+// Quote is an estimate offered to a prospective client. It's edited as a
+// draft, shared with the client through a portal link (which marks it
+// sent), accepted or declined, and an accepted quote converts into a
+// project. Amounts are excluding VAT, in Currency (BaseCurrency if empty).
+type Quote struct {
+	ID          int64         `json:"id" db:"id"`
+	Client      string        `json:"client" db:"client"`
+	Description string        `json:"description" db:"description"`
+	Lines       []InvoiceLine `json:"lines" db:"lines"`
+	Currency    string        `json:"currency,omitempty" db:"currency"`
+	SecuredBy   []int64       `json:"secured_by" db:"secured_by"` // carried over to the project
+	ValidUntil  time.Time     `json:"valid_until" db:"valid_until"`
+	Status      QuoteStatus   `json:"status" db:"status"`
+	ProjectID   int64         `json:"project_id,omitempty" db:"project_id"` // set once converted
+	CreatedBy   string        `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+}
+
+// DefaultQuoteDays is how long a new quote is valid
+const DefaultQuoteDays = 30
+
+// ErrQuoteSent is returned when editing a quote that has been sent
+var ErrQuoteSent = errors.New("quote has been sent and can no longer be edited")
+
+// TotalCents is the sum of the quote's lines
+func (q Quote) TotalCents() int64 {
+	var total int64
+	for _, l := range q.Lines {
+		total += l.AmountCents()
+	}
+	return total
+}
+
+// Expired reports whether a sent quote is past its validity date on now's day
+func (q Quote) Expired(now time.Time) bool {
+	return q.Status == QuoteSent && now.Format("2006-01-02") > q.ValidUntil.Format("2006-01-02")
+}
+
+// SecuredByPerson reports whether the person is credited with the quote
+func (q Quote) SecuredByPerson(id int64) bool {
+	return slices.Contains(q.SecuredBy, id)
+}
+
+// Converted reports whether the quote has been turned into a project
+func (q Quote) Converted() bool {
+	return q.ProjectID != 0
+}
+
+// QuoteStatus is where a quote is in its life
+type QuoteStatus string
+
+const (
+	QuoteDraft    QuoteStatus = "draft"
+	QuoteSent     QuoteStatus = "sent"
+	QuoteAccepted QuoteStatus = "accepted"
+	QuoteDeclined QuoteStatus = "declined"
+)
+
+// QuoteStatuses lists the statuses in display order
+var QuoteStatuses = []QuoteStatus{QuoteDraft, QuoteSent, QuoteAccepted, QuoteDeclined}
+
+// Valid reports whether s is a known quote status
+func (s QuoteStatus) Valid() bool {
+	return slices.Contains(QuoteStatuses, s)
+}
+
+// Label is the status's display name; "" is all quotes
+func (s QuoteStatus) Label() string {
+	switch s {
+	case "":
+		return "All"
+	case QuoteDraft:
+		return "Draft"
+	case QuoteSent:
+		return "Sent"
+	case QuoteAccepted:
+		return "Accepted"
+	case QuoteDeclined:
+		return "Declined"
+	}
+	return string(s)
+}
+
+// QuoteMoves lists the statuses a partner can move a quote to from each
+// status. Sharing the link is what sends a draft; a decision can be undone
+// by moving the quote back to sent until it's converted.
+var QuoteMoves = map[QuoteStatus][]QuoteStatus{
+	QuoteSent:     {QuoteAccepted, QuoteDeclined},
+	QuoteAccepted: {QuoteSent},
+	QuoteDeclined: {QuoteSent},
+}
+
+// CanMove reports whether a partner may move the quote to status to
+func (q Quote) CanMove(to QuoteStatus) bool {
+	return !q.Converted() && slices.Contains(QuoteMoves[q.Status], to)
+}
+
+// ImportField is a project field a column of a board import can fill
+type ImportField struct {
+	Key      string
+	Label    string
+	Required bool
+}
+
+// ImportFields are the fields an import maps columns to, in form order
+var ImportFields = []ImportField{
+	{Key: "client", Label: "Client", Required: true},
+	{Key: "description", Label: "Description"},
+	{Key: "revenue", Label: "Revenue (kr)"},
+	{Key: "status", Label: "Status"},
+	{Key: "secured_by", Label: "Secured by"},
+	{Key: "expected_on", Label: "Expected payment"},
+	{Key: "source", Label: "Lead source"},
+	{Key: "lost_reason", Label: "Lost reason"},
+}
+
+// ImportPlan is a board import part way through the wizard: the file's
+// columns, the column each field is read from, the status each value of
+// the status column becomes, and the project each row would create
+type ImportPlan struct {
+	FileName      string
+	Data          string // the file's rows as JSON, carried between the wizard's steps
+	Columns       []string
+	Mapping       map[string]int // column index by ImportField key; absent for none
+	Statuses      []ImportValue  // the status column's values
+	DefaultStatus ProjectStatus  // for rows with no status value
+	DefaultPerson int64          // secures rows naming no one in the partnership
+	Rows          []ImportRow
+}
+
+// ImportValue is a value found in an import and the status it maps to
+type ImportValue struct {
+	Value  string
+	Status ProjectStatus
+}
+
+// ImportRow is a row of an import as the project it would create
+type ImportRow struct {
+	Row     int // 1 for the first row after the header
+	Project Project
+	Error   string // why the row can't be imported; "" if it can
+}
+
+// Column is the index of the column field key is read from, or -1
+func (p ImportPlan) Column(key string) int {
+	if i, ok := p.Mapping[key]; ok {
+		return i
+	}
+	return -1
+}
+
+// Valid counts the rows that can be imported
+func (p ImportPlan) Valid() int {
+	n := 0
+	for _, r := range p.Rows {
+		if r.Error == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// StripeImport is the outcome of backfilling the Stripe payments made
+// before the webhook was set up
+type StripeImport struct {
+	Before     time.Time // Checkout sessions from then on are left to the webhook
+	DryRun     bool      // nothing was saved; the counts are what would have been
+	Sessions   int       // paid Checkout sessions found before Before
+	Payments   int       // recorded on the projects they paid for
+	Milestones int       // recorded on the milestones they paid for
+	Created    int       // paid projects created for payments naming no project
+	Duplicates int       // already recorded: a project or milestone has their payment ID
+	Skipped    []string  // payments that couldn't be recorded, and why
+}
+
+// MonthlyReport is what the monthly PDF report shows for Month (its first
+// day). Outstanding is as of when the report was made.
+type MonthlyReport struct {
+	Month       time.Time
+	Revenue     []RevenuePeriod // the twelve months up to and including Month
+	Paid        []Project       // paid in the month, oldest first
+	Created     int             // projects added in the month
+	Hours       []PersonHours   // the month's hours report per person
+	Outstanding []Invoice       // issued and not paid, soonest due first
+	Generated   time.Time
+}
+
+// Current is the month's revenue period
+func (r MonthlyReport) Current() RevenuePeriod {
+	if len(r.Revenue) == 0 {
+		return RevenuePeriod{Start: r.Month}
+	}
+	return r.Revenue[len(r.Revenue)-1]
+}
+
+// Previous is the revenue period of the month before, zero when the
+// series doesn't reach it
+func (r MonthlyReport) Previous() RevenuePeriod {
+	if len(r.Revenue) < 2 {
+		return RevenuePeriod{}
+	}
+	return r.Revenue[len(r.Revenue)-2]
 }
@@ -0,0 +1,151 @@
+// notify/chat.go - Slack and Discord channels: formatted messages linking to the card
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/events"
+)
+
+// headlines name each event in chat messages
+var headlines = map[events.Type]string{
+	events.ProjectCreated:       "New project",
+	events.ProjectUpdated:       "Project edited",
+	events.ProjectStatusChanged: "Status changed",
+	events.ProjectDeleted:       "Project deleted",
+	events.PaymentReceived:      "Payment received",
+	events.PaymentOverdue:       "Payment overdue",
+	events.MilestonePaid:        "Milestone paid",
+	events.RetainerPaid:         "Retainer paid",
+	events.BudgetReached:        "Budget warning",
+	events.DocumentExpiring:     "Document expiring",
+}
+
+// colors are Discord embed colors per event: green for money in, red for
+// money late or gone, amber for warnings
+var colors = map[events.Type]int{
+	events.ProjectCreated:   0x3b82f6,
+	events.PaymentReceived:  0x22c55e,
+	events.MilestonePaid:    0x22c55e,
+	events.RetainerPaid:     0x22c55e,
+	events.PaymentOverdue:   0xef4444,
+	events.ProjectDeleted:   0xef4444,
+	events.BudgetReached:    0xf59e0b,
+	events.DocumentExpiring: 0xf59e0b,
+}
+
+// defaultColor is the color of other events
+const defaultColor = 0x6b7280
+
+// fact is a labelled detail shown under a chat message
+type fact struct {
+	Name, Value string
+}
+
+// facts lists the details of e worth a line of their own
+func facts(e events.Event) []fact {
+	var out []fact
+	if e.AmountCents != 0 {
+		out = append(out, fact{"Amount", fmt.Sprintf("%.2f kr", float64(e.AmountCents)/100)})
+	}
+	if e.Type == events.ProjectStatusChanged {
+		out = append(out, fact{"Status", fmt.Sprintf("%s → %s", e.From, e.To)})
+	}
+	if !e.Due.IsZero() {
+		out = append(out, fact{"Due", e.Due.Format("2006-01-02")})
+	}
+	return out
+}
+
+// cardURL links to the project's card on the board, "" without a base URL
+// or a project that still exists
+func cardURL(baseURL string, e events.Event) string {
+	if baseURL == "" || e.ProjectID == 0 || e.Type == events.ProjectDeleted {
+		return ""
+	}
+	return fmt.Sprintf("%s/#project-%d", baseURL, e.ProjectID)
+}
+
+// Slack posts to a Slack incoming webhook: the headline, the project linked
+// to its card, the message and its facts
+type Slack struct {
+	BaseURL string
+}
+
+// slackEscaper escapes Slack's mrkdwn control characters
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Send posts n to the webhook URL target
+func (s Slack) Send(ctx context.Context, target string, n Notification) error {
+	project := "*" + slackEscaper.Replace(n.Event.Client) + "*"
+	if url := cardURL(s.BaseURL, n.Event); url != "" {
+		project = "*<" + url + "|" + slackEscaper.Replace(n.Event.Client) + ">*"
+	}
+
+	blocks := []map[string]any{{
+		"type": "section",
+		"text": map[string]string{"type": "mrkdwn", "text": headline(n.Event) + " · " + project + "\n" + slackEscaper.Replace(n.Body)},
+	}}
+	if fs := facts(n.Event); len(fs) > 0 {
+		fields := make([]map[string]string, len(fs))
+		for i, f := range fs {
+			fields[i] = map[string]string{"type": "mrkdwn", "text": "*" + f.Name + "*\n" + slackEscaper.Replace(f.Value)}
+		}
+		blocks = append(blocks, map[string]any{"type": "section", "fields": fields})
+	}
+
+	// text is the fallback shown in notifications and by old clients
+	return postJSON(ctx, target, map[string]any{"text": n.Body, "blocks": blocks})
+}
+
+// Discord posts to a Discord webhook: an embed titled with the project,
+// linking to its card, colored by the kind of event
+type Discord struct {
+	BaseURL string
+}
+
+// Send posts n to the webhook URL target
+func (d Discord) Send(ctx context.Context, target string, n Notification) error {
+	embed := map[string]any{
+		"author":      map[string]string{"name": headline(n.Event)},
+		"title":       n.Event.Client,
+		"description": n.Body,
+		"color":       color(n.Event),
+		"timestamp":   n.Event.At.UTC().Format(time.RFC3339),
+	}
+	if url := cardURL(d.BaseURL, n.Event); url != "" {
+		embed["url"] = url
+	}
+	if fs := facts(n.Event); len(fs) > 0 {
+		fields := make([]map[string]any, len(fs))
+		for i, f := range fs {
+			fields[i] = map[string]any{"name": f.Name, "value": f.Value, "inline": true}
+		}
+		embed["fields"] = fields
+	}
+
+	// No mentions: a client named "@everyone" mustn't ping the server
+	return postJSON(ctx, target, map[string]any{
+		"embeds":           []any{embed},
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	})
+}
+
+// headline names e's type for a chat message
+func headline(e events.Event) string {
+	if h, ok := headlines[e.Type]; ok {
+		return h
+	}
+	return string(e.Type)
+}
+
+// color is e's embed color
+func color(e events.Event) int {
+	if c, ok := colors[e.Type]; ok {
+		return c
+	}
+	return defaultColor
+}
@@ -0,0 +1,187 @@
+// notify/dispatcher.go - Routes events to channels according to notification rules
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/events"
+)
+
+// Built-in channel names
+const (
+	ChannelLog     = "log"
+	ChannelWebhook = "webhook"
+	ChannelSlack   = "slack"
+	ChannelDiscord = "discord"
+)
+
+// sendTimeout bounds a single delivery
+const sendTimeout = 10 * time.Second
+
+// Notification is a rendered message for one event
+type Notification struct {
+	Event events.Event `json:"-"`
+	Title string       `json:"title"`
+	Body  string       `json:"body"`
+}
+
+// Channel delivers notifications to a target (URL, address, ...)
+type Channel interface {
+	Send(ctx context.Context, target string, n Notification) error
+}
+
+// ChannelFunc adapts a function to Channel
+type ChannelFunc func(ctx context.Context, target string, n Notification) error
+
+func (f ChannelFunc) Send(ctx context.Context, target string, n Notification) error {
+	return f(ctx, target, n)
+}
+
+// Dispatcher evaluates rules for every event and delivers matches, and
+// fills each user's inbox according to their delivery preferences
+type Dispatcher struct {
+	store    Store
+	channels map[string]Channel
+}
+
+// NewDispatcher creates a dispatcher with the log, webhook, Slack and
+// Discord channels; chat messages link to cards under baseURL ("" for no
+// links)
+func NewDispatcher(store Store, baseURL string) *Dispatcher {
+	d := &Dispatcher{store: store, channels: make(map[string]Channel)}
+	d.Register(ChannelLog, ChannelFunc(sendLog))
+	d.Register(ChannelWebhook, ChannelFunc(sendWebhook))
+	d.Register(ChannelSlack, Slack{BaseURL: baseURL})
+	d.Register(ChannelDiscord, Discord{BaseURL: baseURL})
+	return d
+}
+
+// Register adds a named channel
+func (d *Dispatcher) Register(name string, c Channel) {
+	d.channels[name] = c
+}
+
+// Channels lists registered channel names
+func (d *Dispatcher) Channels() []string {
+	names := make([]string, 0, len(d.channels))
+	for name := range d.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Subscribe attaches the dispatcher to every event on the bus
+func (d *Dispatcher) Subscribe(bus *events.Bus) {
+	bus.SubscribeAll(d.Dispatch)
+}
+
+// Dispatch sends e to the channel of every matching rule, then to user
+// inboxes. Channel deliveries run in the background so slow targets don't
+// hold up the request that caused e.
+func (d *Dispatcher) Dispatch(ctx context.Context, e events.Event) error {
+	n := Render(e)
+	if err := d.deliverToUsers(n); err != nil {
+		return err
+	}
+
+	rules, err := LoadRules(d.store)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rules {
+		if !Matches(r, e) {
+			continue
+		}
+		c, ok := d.channels[r.Channel]
+		if !ok {
+			log.Printf("[NOTIFY] rule %d: unknown channel %q", r.ID, r.Channel)
+			continue
+		}
+		go func(id int64, target string) {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := c.Send(ctx, target, n); err != nil {
+				log.Printf("[NOTIFY] rule %d: %v", id, err)
+			}
+		}(r.ID, r.Target)
+	}
+	return nil
+}
+
+// Render builds the human-readable message for an event
+func Render(e events.Event) Notification {
+	n := Notification{Event: e, Title: e.Client}
+	switch e.Type {
+	case events.ProjectCreated:
+		n.Body = fmt.Sprintf("New project %s (%.2f kr)", e.Client, float64(e.AmountCents)/100)
+	case events.ProjectStatusChanged:
+		n.Body = fmt.Sprintf("%s moved %s → %s", e.Client, e.From, e.To)
+	case events.ProjectDeleted:
+		n.Body = fmt.Sprintf("%s was deleted", e.Client)
+	case events.PaymentReceived:
+		n.Body = fmt.Sprintf("Payment of %.2f kr received from %s", float64(e.AmountCents)/100, e.Client)
+	case events.MilestonePaid:
+		n.Body = fmt.Sprintf("Milestone payment of %.2f kr received from %s", float64(e.AmountCents)/100, e.Client)
+	case events.RetainerPaid:
+		n.Body = fmt.Sprintf("Retainer payment of %.2f kr received from %s", float64(e.AmountCents)/100, e.Client)
+	case events.BudgetReached:
+		n.Body = fmt.Sprintf("%s has used %d%% of its budget", e.Client, e.Percent)
+	case events.PaymentOverdue:
+		n.Body = fmt.Sprintf("Payment of %.2f kr from %s was due on %s", float64(e.AmountCents)/100, e.Client, e.Due.Format("2006-01-02"))
+	case events.DocumentExpiring:
+		n.Body = fmt.Sprintf("%s's %q expires on %s", e.Client, e.Document, e.Due.Format("2006-01-02"))
+	default:
+		n.Body = fmt.Sprintf("%s was updated", e.Client)
+	}
+	return n
+}
+
+// sendLog writes the notification to the server log
+func sendLog(_ context.Context, _ string, n Notification) error {
+	log.Printf("[NOTIFY] %s", n.Body)
+	return nil
+}
+
+// sendWebhook POSTs the notification as JSON ({"text": ...} works with Slack/Discord-style hooks)
+func sendWebhook(ctx context.Context, target string, n Notification) error {
+	return postJSON(ctx, target, map[string]any{
+		"text":         n.Body,
+		"event":        n.Event.Type,
+		"project_id":   n.Event.ProjectID,
+		"client":       n.Event.Client,
+		"amount_cents": n.Event.AmountCents,
+	})
+}
+
+// postJSON POSTs v as JSON to target, failing on non-2xx answers
+func postJSON(ctx context.Context, target string, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s: %s", target, resp.Status)
+	}
+	return nil
+}
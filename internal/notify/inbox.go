@@ -0,0 +1,128 @@
+// notify/inbox.go - Per-user delivery (instant, daily digest, off)
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// KindDigest is the Kind of a composed daily digest
+const KindDigest = "digest"
+
+// DigestJob is the scheduler name of the digest composer
+const DigestJob = "notification-digest"
+
+// Store is the data access the dispatcher needs
+type Store interface {
+	SettingsStore
+	ListUsers() ([]models.User, error)
+	GetNotificationPrefs(userID int64) (map[string]models.DeliveryMode, error)
+	CreateNotification(n *models.Notification) error
+	QueuedNotifications(userID int64) ([]models.Notification, error)
+	DeliverDigest(digest *models.Notification, queued []models.Notification) error
+}
+
+// KindLabels names each notification kind for preferences and digests
+var KindLabels = map[events.Type]string{
+	events.ProjectCreated:       "New projects",
+	events.ProjectUpdated:       "Project edits",
+	events.ProjectStatusChanged: "Status changes",
+	events.ProjectDeleted:       "Deleted projects",
+	events.PaymentReceived:      "Payments received",
+	events.PaymentOverdue:       "Overdue payments",
+	events.MilestonePaid:        "Milestones paid",
+	events.RetainerPaid:         "Retainer payments",
+	events.BudgetReached:        "Budget warnings",
+	events.DocumentExpiring:     "Expiring documents",
+}
+
+// Mode returns the user's delivery mode for a kind, falling back to the default
+func Mode(prefs map[string]models.DeliveryMode, kind events.Type) models.DeliveryMode {
+	if m, ok := prefs[string(kind)]; ok && m.Valid() {
+		return m
+	}
+	return models.DefaultDeliveryMode
+}
+
+// deliverToUsers puts n in each user's inbox or digest queue per their preferences
+func (d *Dispatcher) deliverToUsers(n Notification) error {
+	users, err := d.store.ListUsers()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, u := range users {
+		prefs, err := d.store.GetNotificationPrefs(u.ID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		mode := Mode(prefs, n.Event.Type)
+		if mode == models.DeliveryOff {
+			continue
+		}
+		errs = append(errs, d.store.CreateNotification(&models.Notification{
+			UserID:    u.ID,
+			Kind:      string(n.Event.Type),
+			ProjectID: n.Event.ProjectID,
+			Body:      n.Body,
+			Queued:    mode == models.DeliveryDigest,
+		}))
+	}
+	return errors.Join(errs...)
+}
+
+// SendDigests composes one digest per user from their queued notifications
+func (d *Dispatcher) SendDigests(ctx context.Context) error {
+	users, err := d.store.ListUsers()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, u := range users {
+		queued, err := d.store.QueuedNotifications(u.ID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(queued) == 0 {
+			continue
+		}
+		digest := &models.Notification{UserID: u.ID, Kind: KindDigest, Body: ComposeDigest(queued)}
+		errs = append(errs, d.store.DeliverDigest(digest, queued))
+	}
+	return errors.Join(errs...)
+}
+
+// ComposeDigest groups queued notifications by kind into one message
+func ComposeDigest(queued []models.Notification) string {
+	groups := make(map[string][]string)
+	for _, n := range queued {
+		groups[n.Kind] = append(groups[n.Kind], n.Body)
+	}
+
+	var b strings.Builder
+	noun := "updates"
+	if len(queued) == 1 {
+		noun = "update"
+	}
+	fmt.Fprintf(&b, "Daily digest — %d %s\n", len(queued), noun)
+	for _, t := range events.Types {
+		bodies := groups[string(t)]
+		if len(bodies) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s (%d)\n", KindLabels[t], len(bodies))
+		for _, body := range bodies {
+			b.WriteString("- " + body + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
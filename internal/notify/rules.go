@@ -0,0 +1,174 @@
+// notify/rules.go - Notification rules stored as JSON in settings
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// Fields and Ops offered by the rule builder
+var (
+	Fields = []string{"amount", "client", "status"}
+	Ops    = []string{">", ">=", "<", "<=", "=", "!=", "contains"}
+)
+
+// SettingsStore is where rules are kept
+type SettingsStore interface {
+	GetSetting(key, fallback string) (string, error)
+	SetSetting(key, value string) error
+}
+
+// LoadRules reads the notification rules from settings
+func LoadRules(s SettingsStore) ([]models.NotificationRule, error) {
+	v, err := s.GetSetting(models.SettingNotifyRules, "")
+	if err != nil || v == "" {
+		return nil, err
+	}
+	var rules []models.NotificationRule
+	if err := json.Unmarshal([]byte(v), &rules); err != nil {
+		return nil, fmt.Errorf("notification rules: %w", err)
+	}
+	return rules, nil
+}
+
+// SaveRules writes the notification rules to settings
+func SaveRules(s SettingsStore, rules []models.NotificationRule) error {
+	b, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	return s.SetSetting(models.SettingNotifyRules, string(b))
+}
+
+// AddRule validates r, assigns it the next ID and appends it
+func AddRule(s SettingsStore, r models.NotificationRule) error {
+	if err := Validate(r); err != nil {
+		return err
+	}
+	rules, err := LoadRules(s)
+	if err != nil {
+		return err
+	}
+	for _, existing := range rules {
+		r.ID = max(r.ID, existing.ID)
+	}
+	r.ID++
+	return SaveRules(s, append(rules, r))
+}
+
+// DeleteRule removes the rule with the given ID
+func DeleteRule(s SettingsStore, id int64) error {
+	rules, err := LoadRules(s)
+	if err != nil {
+		return err
+	}
+	kept := rules[:0]
+	for _, r := range rules {
+		if r.ID != id {
+			kept = append(kept, r)
+		}
+	}
+	return SaveRules(s, kept)
+}
+
+// Validate rejects rules with unknown events, fields, operators or channels
+func Validate(r models.NotificationRule) error {
+	if !contains(events.Types, events.Type(r.Event)) {
+		return fmt.Errorf("unknown event %q", r.Event)
+	}
+	if r.Field != "" {
+		if !contains(Fields, r.Field) {
+			return fmt.Errorf("unknown field %q", r.Field)
+		}
+		if !contains(Ops, r.Op) {
+			return fmt.Errorf("unknown operator %q", r.Op)
+		}
+		if r.Field == "amount" && r.Op != "contains" {
+			if _, err := strconv.ParseFloat(r.Value, 64); err != nil {
+				return fmt.Errorf("amount must be a number")
+			}
+		}
+	}
+	if contains([]string{ChannelWebhook, ChannelSlack, ChannelDiscord}, r.Channel) &&
+		!strings.HasPrefix(r.Target, "https://") && !strings.HasPrefix(r.Target, "http://") {
+		return fmt.Errorf("%s target must be an http(s) URL", r.Channel)
+	}
+	return nil
+}
+
+// Matches reports whether an event satisfies a rule
+func Matches(r models.NotificationRule, e events.Event) bool {
+	if events.Type(r.Event) != e.Type {
+		return false
+	}
+	switch r.Field {
+	case "":
+		return true
+	case "amount":
+		want, err := strconv.ParseFloat(r.Value, 64)
+		if err != nil {
+			return false
+		}
+		return compare(float64(e.AmountCents)/100, want, r.Op) // rules are written in kr
+	case "client":
+		return matchString(e.Client, r.Value, r.Op)
+	case "status":
+		return matchString(string(e.To), r.Value, r.Op)
+	}
+	return false
+}
+
+// compare applies a numeric operator
+func compare(got, want float64, op string) bool {
+	switch op {
+	case ">":
+		return got > want
+	case ">=":
+		return got >= want
+	case "<":
+		return got < want
+	case "<=":
+		return got <= want
+	case "=":
+		return got == want
+	case "!=":
+		return got != want
+	}
+	return false
+}
+
+// matchString applies a case-insensitive string operator
+func matchString(got, want, op string) bool {
+	switch op {
+	case "=":
+		return strings.EqualFold(got, want)
+	case "!=":
+		return !strings.EqualFold(got, want)
+	case "contains":
+		return strings.Contains(strings.ToLower(got), strings.ToLower(want))
+	}
+	return false
+}
+
+func contains[T comparable](list []T, v T) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe renders a rule as "payment.received where amount > 5000 → webhook"
+func Describe(r models.NotificationRule) string {
+	s := r.Event
+	if r.Field != "" {
+		s += " where " + r.Field + " " + r.Op + " " + r.Value
+	}
+	return s + " → " + r.Channel
+}
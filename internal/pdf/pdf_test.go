@@ -0,0 +1,155 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDocumentStructure(t *testing.T) {
+	doc := New("Invoice (2026-001)")
+	first := doc.AddPage()
+	first.Text(50, 50, Bold, 16, "Faktura")
+	first.TextRight(545, 80, Regular, 10, "1 234,00 kr")
+	first.Line(50, 90, 545, 90, 0.5, 0.6)
+	first.Rect(500, 700, 2.5, 2.5, 0)
+	second := doc.AddPage()
+	second.Text(50, 50, Regular, 10, `a (b) c\d`)
+	data := doc.Bytes()
+
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) {
+		t.Fatalf("header %q", data[:min(len(data), 16)])
+	}
+
+	// startxref points at the cross-reference table
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	if m == nil {
+		t.Fatalf("no startxref at the end: %q", data[max(0, len(data)-64):])
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d points at %q", xref, data[xref:min(len(data), xref+16)])
+	}
+
+	// One entry per object: catalog, page tree, info, two fonts, then a
+	// page and its contents per page; each entry is 20 bytes
+	var first0, size int
+	if _, err := fmt.Sscanf(string(data[xref:]), "xref\n%d %d\n", &first0, &size); err != nil {
+		t.Fatal(err)
+	}
+	if wantObjects := 5 + 2*2; first0 != 0 || size != wantObjects+1 {
+		t.Fatalf("xref subsection %d %d, want 0 %d", first0, size, wantObjects+1)
+	}
+	table := data[xref+len(fmt.Sprintf("xref\n0 %d\n", size)):]
+	if got := string(table[:20]); got != "0000000000 65535 f \n" {
+		t.Errorf("entry 0 = %q, want the free list head", got)
+	}
+	objects := map[int]string{}
+	for i := 1; i < size; i++ {
+		entry := string(table[20*i : 20*i+20])
+		if !strings.HasSuffix(entry, " 00000 n \n") {
+			t.Fatalf("entry %d = %q", i, entry)
+		}
+		off, err := strconv.Atoi(entry[:10])
+		if err != nil {
+			t.Fatal(err)
+		}
+		head := fmt.Sprintf("%d 0 obj\n", i)
+		if !bytes.HasPrefix(data[off:], []byte(head)) {
+			t.Fatalf("entry %d: offset %d holds %q", i, off, data[off:min(len(data), off+16)])
+		}
+		end := bytes.Index(data[off:], []byte("\nendobj\n"))
+		if end < 0 {
+			t.Fatalf("object %d has no endobj", i)
+		}
+		objects[i] = string(data[off+len(head) : off+end])
+	}
+	if got := strings.Count(string(data), " 0 obj\n"); got != size-1 {
+		t.Errorf("%d objects in the file, %d in the xref table", got, size-1)
+	}
+	if !bytes.HasPrefix(table[20*size:], []byte(fmt.Sprintf("trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>", size))) {
+		t.Errorf("trailer %q", table[20*size:])
+	}
+
+	for i, want := range map[int]string{
+		1: "<< /Type /Catalog /Pages 2 0 R >>",
+		2: "<< /Type /Pages /Kids [6 0 R 8 0 R] /Count 2 >>",
+		3: `<< /Title (Invoice \(2026-001\)) /Producer (FullDash) >>`,
+		4: "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		5: "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	} {
+		if objects[i] != want {
+			t.Errorf("object %d = %q, want %q", i, objects[i], want)
+		}
+	}
+
+	// Pages point at their parent and contents; a stream's /Length is
+	// the bytes between stream and endstream
+	stream := regexp.MustCompile(`(?s)^<< /Length (\d+) >>\nstream\n(.*)endstream$`)
+	for i, page := range []*Page{first, second} {
+		obj, contents := 6+2*i, 7+2*i
+		if !strings.HasPrefix(objects[obj], "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595.28 841.89] ") ||
+			!strings.HasSuffix(objects[obj], fmt.Sprintf("/Contents %d 0 R >>", contents)) {
+			t.Errorf("page object %d = %q", obj, objects[obj])
+		}
+		m := stream.FindStringSubmatch(objects[contents])
+		if m == nil {
+			t.Fatalf("contents object %d = %q", contents, objects[contents])
+		}
+		if n, _ := strconv.Atoi(m[1]); n != len(m[2]) || m[2] != page.content.String() {
+			t.Errorf("contents object %d: /Length %d for %d bytes", contents, n, len(m[2]))
+		}
+	}
+
+	// Coordinates are from the top-left; PDF's are from the bottom-left
+	for _, want := range []string{
+		"BT /F2 16.00 Tf 50.00 791.89 Td (Faktura) Tj ET\n",
+		"0.60 G 0.50 w 50.00 751.89 m 545.00 751.89 l S\n",
+		"0.00 g 500.00 139.39 2.50 2.50 re f\n",
+	} {
+		if !strings.Contains(first.content.String(), want) {
+			t.Errorf("first page lacks %q:\n%s", want, first.content.String())
+		}
+	}
+	if want := `(a \(b\) c\\d) Tj`; !strings.Contains(second.content.String(), want) {
+		t.Errorf("second page lacks %q:\n%s", want, second.content.String())
+	}
+}
+
+func TestEscape(t *testing.T) {
+	for in, want := range map[string]string{
+		"Plain":         "Plain",
+		"(x) \\ y":      `\(x\) \\ y`,
+		"Åsa Öberg":     "\xc5sa \xd6berg",
+		"€ 100 – net":   "\x80 100 \x96 net",
+		"tab\there\n":   "tab here ",
+		"arrow → emoji": "arrow ? emoji",
+	} {
+		if got := escape(in); got != want {
+			t.Errorf("escape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWidth(t *testing.T) {
+	for _, tt := range []struct {
+		font Font
+		size float64
+		s    string
+		want float64
+	}{
+		{Regular, 10, "Hello", 22.78}, // 722+556+222+222+556
+		{Bold, 10, "Hello", 24.45},    // 722+556+278+278+611
+		{Regular, 12, "Åsa", 20.676},  // Å as A: 667+500+556
+		{Regular, 10, "a—b", 21.12},   // the em dash is 1000
+		{Regular, 10, "€", 5.56},      // unlisted: a digit's width
+	} {
+		if got := Width(tt.font, tt.size, tt.s); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Width(%d, %v, %q) = %v, want %v", tt.font, tt.size, tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// ratelimit/ratelimit.go - Fixed-window request counters
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter counts hits per key in fixed windows. Allow reports whether this
+// hit is within limit and, if not, how long until the window resets.
+type Limiter interface {
+	Allow(key string, limit int, window time.Duration) (ok bool, retryAfter time.Duration)
+}
+
+type counter struct {
+	hits  int
+	reset time.Time
+}
+
+// Memory is an in-process Limiter; each instance counts separately
+type Memory struct {
+	mu       sync.Mutex
+	counters map[string]*counter
+	swept    time.Time
+}
+
+// NewMemory creates an empty in-memory limiter
+func NewMemory() *Memory {
+	return &Memory{counters: make(map[string]*counter)}
+}
+
+// Allow counts a hit for key
+func (m *Memory) Allow(key string, limit int, window time.Duration) (bool, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.swept) > time.Minute {
+		for k, c := range m.counters {
+			if now.After(c.reset) {
+				delete(m.counters, k)
+			}
+		}
+		m.swept = now
+	}
+
+	c, ok := m.counters[key]
+	if !ok || now.After(c.reset) {
+		c = &counter{reset: now.Add(window)}
+		m.counters[key] = c
+	}
+	c.hits++
+	if c.hits > limit {
+		return false, c.reset.Sub(now)
+	}
+	return true, 0
+}
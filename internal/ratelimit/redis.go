@@ -0,0 +1,49 @@
+// ratelimit/redis.go - Redis-backed counters shared by every instance
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPrefix namespaces counter keys in a shared Redis
+const redisPrefix = "fulldash:ratelimit:"
+
+// Redis is a Limiter whose counters live in Redis, so a client gets one
+// allowance across all instances. If Redis is unreachable requests are
+// allowed rather than locking everyone out.
+type Redis struct {
+	rdb *redis.Client
+}
+
+// NewRedis creates a Redis-backed limiter
+func NewRedis(rdb *redis.Client) *Redis {
+	return &Redis{rdb: rdb}
+}
+
+// Allow counts a hit for key
+func (r *Redis) Allow(key string, limit int, window time.Duration) (bool, time.Duration) {
+	now := time.Now()
+	start := now.Truncate(window)
+	k := fmt.Sprintf("%s%s:%d", redisPrefix, key, start.Unix())
+
+	ctx := context.Background()
+	var incr *redis.IntCmd
+	_, err := r.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		incr = p.Incr(ctx, k)
+		p.Expire(ctx, k, window)
+		return nil
+	})
+	if err != nil {
+		log.Printf("[RATELIMIT] %s: %v", key, err)
+		return true, 0
+	}
+	if incr.Val() > int64(limit) {
+		return false, start.Add(window).Sub(now)
+	}
+	return true, 0
+}
@@ -0,0 +1,163 @@
+// replication/replication.go - Running under Litestream or LiteFS: roles, heartbeats and lag
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// Replication modes (REPLICATION)
+const (
+	Litestream = "litestream"
+	LiteFS     = "litefs"
+)
+
+// JobName is the heartbeat's scheduler name
+const JobName = "replication-heartbeat"
+
+// HeartbeatInterval is how often the primary stamps the database for
+// replicas to measure their lag against
+const HeartbeatInterval = 10 * time.Second
+
+// DefaultMaxLag is the lag past which the probe reports trouble
+const DefaultMaxLag = time.Minute
+
+// Store is what replication needs from the database (store.DB)
+type Store interface {
+	GetSetting(key, fallback string) (string, error)
+	SetSetting(key, value string) error
+	JournalMode() (string, error)
+}
+
+// Config is how this instance replicates
+type Config struct {
+	Mode     string        // "", Litestream or LiteFS
+	ReadOnly bool          // always a replica (READ_ONLY), e.g. a copy Litestream restores
+	DBPath   string        // LiteFS's .primary file and Litestream's shadow WAL sit beside it
+	MaxLag   time.Duration // the probe fails beyond it
+}
+
+// Validate checks the mode
+func (c Config) Validate() error {
+	switch c.Mode {
+	case "", Litestream, LiteFS:
+		return nil
+	}
+	return fmt.Errorf("unknown REPLICATION %q (litestream or litefs)", c.Mode)
+}
+
+// Enabled is true when running under Litestream or LiteFS
+func (c Config) Enabled() bool {
+	return c.Mode != ""
+}
+
+// Replica reports whether this instance must leave writes to the primary:
+// with READ_ONLY set, or while LiteFS names another node as primary. It
+// is read on every call, so a LiteFS node promoted to primary starts
+// accepting writes.
+func (c Config) Replica() bool {
+	return c.ReadOnly || c.Primary() != ""
+}
+
+// Primary returns the node a LiteFS replica follows, which LiteFS writes
+// to a .primary file in its mount on replicas only, or ""
+func (c Config) Primary() string {
+	if c.Mode != LiteFS {
+		return ""
+	}
+	b, err := os.ReadFile(filepath.Join(filepath.Dir(c.DBPath), ".primary"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// Heartbeat stamps the time into the database. It runs on the primary
+// only; replicas see the stamp once it has replicated.
+func Heartbeat(db Store) func(context.Context) error {
+	return func(ctx context.Context) error {
+		return db.SetSetting(models.SettingHeartbeat, time.Now().UTC().Format(time.RFC3339Nano))
+	}
+}
+
+// Check reports the role, journal mode and lag at now. A replica's lag is
+// the age of the latest heartbeat it has; a Litestream primary's is how
+// far Litestream's shadow WAL trails the database's WAL, which stays
+// near zero while Litestream runs.
+func Check(c Config, db Store, now time.Time) models.ReplicationStatus {
+	s := models.ReplicationStatus{Mode: c.Mode, Role: models.ReplicationPrimary, MaxLag: c.MaxLag}
+	problem := func(format string, args ...any) {
+		s.Problems = append(s.Problems, fmt.Sprintf(format, args...))
+	}
+
+	mode, err := db.JournalMode()
+	if err != nil {
+		problem("journal mode: %v", err)
+	}
+	s.JournalMode = mode
+	if c.Enabled() && mode != "wal" {
+		problem("journal mode is %s; replication needs wal", mode)
+	}
+
+	beat, err := db.GetSetting(models.SettingHeartbeat, "")
+	if err != nil {
+		problem("heartbeat: %v", err)
+	} else if beat != "" {
+		s.HeartbeatAt, _ = time.Parse(time.RFC3339Nano, beat)
+	}
+
+	switch {
+	case c.Replica():
+		s.Role, s.Primary = models.ReplicationReplica, c.Primary()
+		if s.HeartbeatAt.IsZero() {
+			problem("no heartbeat from the primary yet")
+			break
+		}
+		s.Lag = max(now.Sub(s.HeartbeatAt), 0)
+	case c.Mode == Litestream:
+		lag, err := litestreamLag(c.DBPath)
+		if err != nil {
+			problem("litestream: %v", err)
+		}
+		s.Lag = lag
+	}
+	if c.MaxLag > 0 && s.Lag > c.MaxLag {
+		problem("%s behind, over the %s limit", s.Lag.Round(time.Second), c.MaxLag)
+	}
+	return s
+}
+
+// litestreamLag compares the database's WAL with the newest file in
+// Litestream's shadow WAL, .<db>-litestream beside it
+func litestreamLag(dbPath string) (time.Duration, error) {
+	wal, err := os.Stat(dbPath + "-wal")
+	if err != nil {
+		return 0, nil // nothing written since the last checkpoint
+	}
+	meta := filepath.Join(filepath.Dir(dbPath), "."+filepath.Base(dbPath)+"-litestream")
+	var newest time.Time
+	err = filepath.WalkDir(meta, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("not replicating: no %s", meta)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return max(wal.ModTime().Sub(newest), 0), nil
+}
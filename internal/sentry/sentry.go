@@ -0,0 +1,344 @@
+// sentry/sentry.go - Error reporting to Sentry or a compatible server (GlitchTip, Bugsink) by DSN
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Levels events are reported at
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelFatal   = "fatal"
+)
+
+// queueSize is how many events wait to be sent; beyond it they are dropped
+// rather than slow the request that raised them
+const queueSize = 100
+
+// sendTimeout bounds one event's POST
+const sendTimeout = 10 * time.Second
+
+// inAppPrefix marks FullDash's own frames in a stack trace
+const inAppPrefix = "github.com/noor-latif/fulldash/"
+
+// Options describe this deployment on every event
+type Options struct {
+	Release     string // the build (SENTRY_RELEASE, or the VCS revision)
+	Environment string // e.g. production, staging
+}
+
+// Client sends events to a Sentry DSN in the background. A nil *Client
+// reports nothing, so callers needn't check whether reporting is set up.
+type Client struct {
+	endpoint string // the project's envelope endpoint
+	dsn      string
+	key      string
+	opts     Options
+	server   string
+	HTTP     *http.Client
+
+	queue chan *Event
+	done  chan struct{}
+	once  sync.Once
+}
+
+// New parses dsn (https://<key>@<host>/<project>) and starts the sender.
+// An empty dsn returns a nil client.
+func New(dsn string, o Options) (*Client, error) {
+	if dsn == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("SENTRY_DSN: %w", err)
+	}
+	project := path.Base(u.Path)
+	if u.User == nil || u.User.Username() == "" || project == "" || project == "/" || project == "." {
+		return nil, fmt.Errorf("SENTRY_DSN: want https://<key>@<host>/<project>")
+	}
+	host, _ := os.Hostname()
+	c := &Client{
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, strings.TrimSuffix(path.Dir(u.Path), "/"), project),
+		dsn:      dsn,
+		key:      u.User.Username(),
+		opts:     o,
+		server:   host,
+		HTTP:     &http.Client{Timeout: sendTimeout},
+		queue:    make(chan *Event, queueSize),
+		done:     make(chan struct{}),
+	}
+	go c.run()
+	return c, nil
+}
+
+// Capture queues e, filling in its ID, time and the deployment. It never
+// blocks: with the queue full the event is dropped and logged.
+func (c *Client) Capture(e *Event) {
+	if c == nil {
+		return
+	}
+	e.ID = newEventID()
+	e.Timestamp = time.Now().UTC()
+	e.Platform, e.Logger = "go", "fulldash"
+	e.Release, e.Environment, e.ServerName = c.opts.Release, c.opts.Environment, c.server
+	if e.Level == "" {
+		e.Level = LevelError
+	}
+	select {
+	case c.queue <- e:
+	default:
+		log.Printf("[SENTRY] Queue full, dropped: %s", e.Summary())
+	}
+}
+
+// Close sends what is queued, until ctx ends
+func (c *Client) Close(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	c.once.Do(func() { close(c.queue) })
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+	}
+}
+
+func (c *Client) run() {
+	defer close(c.done)
+	for e := range c.queue {
+		if err := c.send(e); err != nil {
+			log.Printf("[SENTRY] Sending %s failed: %v", e.ID, err)
+		}
+	}
+}
+
+// send posts e as an envelope: a header line, an item header line and the
+// event itself
+func (c *Client) send(e *Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.Encode(map[string]string{"event_id": e.ID, "dsn": c.dsn, "sent_at": time.Now().UTC().Format(time.RFC3339)})
+	enc.Encode(map[string]any{"type": "event", "length": len(payload)})
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", "Sentry sentry_version=7, sentry_client=fulldash/1.0, sentry_key="+c.key)
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.Join(strings.Fields(string(msg)), " "))
+	}
+	return nil
+}
+
+// Event is what Sentry stores for one error, in its event payload format
+type Event struct {
+	ID          string            `json:"event_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	Level       string            `json:"level"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Transaction string            `json:"transaction,omitempty"` // the route, e.g. "POST /projects/{id}"
+	Message     *Message          `json:"message,omitempty"`
+	Exception   *Exceptions       `json:"exception,omitempty"`
+	Request     *Request          `json:"request,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]any    `json:"extra,omitempty"`
+	Fingerprint []string          `json:"fingerprint,omitempty"` // groups events into one issue
+}
+
+// Message is an event's text when it has no exception
+type Message struct {
+	Formatted string `json:"formatted"`
+}
+
+// Exceptions holds an event's exception, innermost last
+type Exceptions struct {
+	Values []Exception `json:"values"`
+}
+
+// Exception is an error or panic value with where it happened
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+}
+
+// Stacktrace lists frames oldest first, as Sentry expects
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+// Frame is one call in a stack trace
+type Frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// Request is the HTTP request an event happened in. Cookies and
+// credentials are left out.
+type Request struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	QueryString string            `json:"query_string,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+}
+
+// reportedHeaders are the request headers events carry
+var reportedHeaders = []string{"User-Agent", "Referer", "Content-Type", "Accept", "Hx-Request", "Hx-Target", "X-Request-Id"}
+
+// Summary is a one-line description of e for the log
+func (e *Event) Summary() string {
+	switch {
+	case e.Exception != nil && len(e.Exception.Values) > 0:
+		x := e.Exception.Values[len(e.Exception.Values)-1]
+		return x.Type + ": " + x.Value
+	case e.Message != nil:
+		return e.Message.Formatted
+	}
+	return e.ID
+}
+
+// ErrorEvent reports err with the stack of its caller, skip frames up
+func ErrorEvent(err error, skip int) *Event {
+	return &Event{Exception: &Exceptions{Values: []Exception{{
+		Type:       fmt.Sprintf("%T", err),
+		Value:      err.Error(),
+		Stacktrace: stack(skip + 1),
+	}}}}
+}
+
+// PanicEvent reports a recovered panic value. Called from the deferred
+// function that recovered it, the stack still reaches the panic.
+func PanicEvent(v any, skip int) *Event {
+	e := &Event{Level: LevelFatal, Exception: &Exceptions{Values: []Exception{{
+		Type:       "panic",
+		Value:      fmt.Sprint(v),
+		Stacktrace: stack(skip + 1),
+	}}}}
+	if err, ok := v.(error); ok {
+		e.Exception.Values[0].Type = fmt.Sprintf("panic: %T", err)
+	}
+	return e
+}
+
+// MessageEvent reports a message without a stack
+func MessageEvent(level, msg string) *Event {
+	return &Event{Level: level, Message: &Message{Formatted: msg}}
+}
+
+// WithRequest adds r: URL, method, query, a few headers and the client
+// address, and its ID as the request_id tag
+func (e *Event) WithRequest(r *http.Request, requestID string) *Event {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	req := &Request{
+		URL:         scheme + "://" + r.Host + r.URL.Path,
+		Method:      r.Method,
+		QueryString: r.URL.RawQuery,
+		Headers:     map[string]string{},
+		Env:         map[string]string{"REMOTE_ADDR": r.RemoteAddr},
+	}
+	for _, h := range reportedHeaders {
+		if v := r.Header.Get(h); v != "" {
+			req.Headers[h] = v
+		}
+	}
+	e.Request = req
+	if requestID != "" {
+		e.Tag("request_id", requestID)
+	}
+	return e
+}
+
+// Tag sets a searchable tag
+func (e *Event) Tag(key, value string) *Event {
+	if e.Tags == nil {
+		e.Tags = map[string]string{}
+	}
+	e.Tags[key] = value
+	return e
+}
+
+// stack returns the caller's stack, skip frames up, oldest first
+func stack(skip int) *Stacktrace {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var out []Frame
+	for {
+		f, more := frames.Next()
+		module, function := splitFunction(f.Function)
+		out = append(out, Frame{
+			Function: function,
+			Module:   module,
+			Filename: strings.TrimPrefix(module, inAppPrefix) + "/" + path.Base(f.File),
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    strings.HasPrefix(f.Function, inAppPrefix),
+		})
+		if !more {
+			break
+		}
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return &Stacktrace{Frames: out}
+}
+
+// splitFunction splits "github.com/a/b/pkg.(*T).M" into the package path
+// and "(*T).M"
+func splitFunction(name string) (module, function string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	return name[:slash+1+dot], name[slash+2+dot:]
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,41 @@
+// service/apikeys.go - API and calendar token generation and hashing
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// apiTokenPrefix marks FullDash tokens so they are recognisable in scripts and logs
+const apiTokenPrefix = "fd_"
+
+// calendarTokenPrefix marks calendar feed tokens, which only read the feed
+const calendarTokenPrefix = "fdcal_"
+
+// NewAPIToken returns a fresh bearer token, its display prefix, and its hash
+func NewAPIToken() (token, prefix, hash string, err error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	token = apiTokenPrefix + hex.EncodeToString(buf)
+	return token, token[:len(apiTokenPrefix)+6], HashAPIToken(token), nil
+}
+
+// NewCalendarToken returns a fresh calendar feed token and its hash, which
+// is stored like an API key's
+func NewCalendarToken() (token, hash string, err error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = calendarTokenPrefix + hex.EncodeToString(buf)
+	return token, HashAPIToken(token), nil
+}
+
+// HashAPIToken returns the hex SHA-256 of a token; only hashes are stored
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,59 @@
+// service/checklist.go - Checklist gates on status transitions
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// GateError lists the open critical items blocking a status change.
+// Soft gates can be overridden by the user; the override is logged.
+type GateError struct {
+	Stage models.ChecklistStage
+	Open  []string
+	Soft  bool
+}
+
+func (e *GateError) Error() string {
+	return fmt.Sprintf("%s checklist incomplete: %s", e.Stage, strings.Join(e.Open, ", "))
+}
+
+// OpenCritical returns the titles of unticked critical items in a stage
+func OpenCritical(items []models.ChecklistItem, stage models.ChecklistStage) []string {
+	var open []string
+	for _, it := range items {
+		if it.Stage == stage && it.Critical && !it.Done {
+			open = append(open, it.Title)
+		}
+	}
+	return open
+}
+
+// CheckKickoffGate returns a *GateError if a project is leaving "new" while
+// critical kickoff items are still open; a deal lost before kickoff may go
+func CheckKickoffGate(from, to models.ProjectStatus, items []models.ChecklistItem) error {
+	if from != models.StatusNew || to == models.StatusNew || to == models.StatusLost {
+		return nil
+	}
+	if open := OpenCritical(items, models.StageKickoff); len(open) > 0 {
+		return &GateError{Stage: models.StageKickoff, Open: open}
+	}
+	return nil
+}
+
+// CheckDeliveryGate returns a soft *GateError if a project is moving to
+// "done" or "paid" while critical definition-of-done items are still open
+func CheckDeliveryGate(from, to models.ProjectStatus, items []models.ChecklistItem) error {
+	if from == models.StatusDone || from == models.StatusPaid {
+		return nil
+	}
+	if to != models.StatusDone && to != models.StatusPaid {
+		return nil
+	}
+	if open := OpenCritical(items, models.StageDelivery); len(open) > 0 {
+		return &GateError{Stage: models.StageDelivery, Open: open, Soft: true}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+// service/diff.go - Generic struct diffing for change summaries
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// Change describes a single field that differs between two snapshots
+type Change = models.Change
+
+// Diff compares two structs of the same type field by field.
+// Fields are labelled by their `diff` tag (falling back to the lowercased
+// field name); `diff:"-"` excludes a field, and a ",cents" option shows an
+// integer cent amount in whole units.
+func Diff[T any](before, after T) []Change {
+	bv, av := reflect.Indirect(reflect.ValueOf(before)), reflect.Indirect(reflect.ValueOf(after))
+	if bv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var changes []Change
+	t := bv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		label, opt, _ := strings.Cut(f.Tag.Get("diff"), ",")
+		if label == "-" || !f.IsExported() {
+			continue
+		}
+		if label == "" {
+			label = strings.ToLower(f.Name)
+		}
+
+		old, cur := formatValue(bv.Field(i)), formatValue(av.Field(i))
+		if opt == "cents" {
+			old, cur = formatNumber(float64(bv.Field(i).Int())/100), formatNumber(float64(av.Field(i).Int())/100)
+		}
+		if old != cur {
+			changes = append(changes, Change{Field: label, Old: old, New: cur})
+		}
+	}
+	return changes
+}
+
+// Summary joins changes into a single line: "amount 8,000 → 10,000; status new → done"
+func Summary(changes []Change) string {
+	parts := make([]string, len(changes))
+	for i, c := range changes {
+		parts[i] = c.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// formatValue renders a field value for display in a diff
+func formatValue(v reflect.Value) string {
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return "—"
+		}
+		return t.Format("2006-01-02")
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return "—"
+		}
+		return formatValue(v.Elem())
+	case reflect.Float32, reflect.Float64:
+		return formatNumber(v.Float())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return formatNumber(float64(v.Int()))
+	case reflect.String:
+		if v.String() == "" {
+			return "—"
+		}
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// formatNumber renders a number with thousands separators (8000 → "8,000")
+func formatNumber(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if f != float64(int64(f)) {
+		s = strconv.FormatFloat(f, 'f', 2, 64)
+	}
+
+	intPart, frac, _ := strings.Cut(s, ".")
+	neg := strings.HasPrefix(intPart, "-")
+	intPart = strings.TrimPrefix(intPart, "-")
+
+	var b strings.Builder
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+
+	out := b.String()
+	if frac != "" {
+		out += "." + frac
+	}
+	if neg {
+		out = "-" + out
+	}
+	return out
+}
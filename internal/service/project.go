@@ -0,0 +1,89 @@
+// service/project.go - Project snapshots used for change tracking
+package service
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// ProjectSnapshot captures the user-editable state of a project at a point in
+// time. SecuredBy, Hours, SplitOverride and HourEstimates hold person IDs,
+// so Changes diffs them by name.
+type ProjectSnapshot struct {
+	Client        string                    `diff:"client"`
+	Description   string                    `diff:"description"`
+	RevenueCents  int64                     `diff:"amount,cents"`
+	VATPercent    float64                   `diff:"vat"`
+	VATInclusive  bool                      `diff:"vat included"`
+	Status        models.ProjectStatus      `diff:"status"`
+	LostReason    string                    `diff:"lost reason"`
+	Source        models.LeadSource         `diff:"source"`
+	Probability   *int                      `diff:"probability"`
+	ExpectedOn    *time.Time                `diff:"expected"`
+	SecuredBy     []int64                   `diff:"-"`
+	Hours         models.PerPerson[float64] `diff:"-"` // non-zero hours only
+	SplitOverride models.PerPerson[float64] `diff:"-"`
+	Archived      bool                      `diff:"archived"`
+
+	EstimatedHours float64                   `diff:"estimated hours"`
+	HourEstimates  models.PerPerson[float64] `diff:"-"`
+
+	PaymentDays int `diff:"payment terms"`
+
+	BudgetHours float64 `diff:"budget hours"`
+	BudgetCents int64   `diff:"budget"`
+	HourlyCents int64   `diff:"hourly rate"`
+}
+
+// Snapshot builds a ProjectSnapshot from a project and its logged hours
+func Snapshot(p *models.Project, hours models.PerPerson[float64]) ProjectSnapshot {
+	hours = maps.Clone(hours)
+	maps.DeleteFunc(hours, func(_ int64, h float64) bool { return h == 0 })
+	return ProjectSnapshot{
+		Client:        p.Client,
+		Description:   p.Description,
+		RevenueCents:  p.RevenueCents,
+		VATPercent:    p.VATPercent,
+		VATInclusive:  p.VATInclusive,
+		Status:        p.Status,
+		LostReason:    p.LostReason,
+		Source:        p.Source,
+		Probability:   p.Probability,
+		ExpectedOn:    p.ExpectedOn,
+		SecuredBy:     slices.Clone(p.SecuredBy),
+		Hours:         hours,
+		SplitOverride: maps.Clone(p.SplitOverride),
+		Archived:      p.Archived(),
+
+		EstimatedHours: p.EstimatedHours,
+		HourEstimates:  maps.Clone(p.HourEstimates),
+
+		PaymentDays: p.PaymentDays,
+
+		BudgetHours: p.BudgetHours,
+		BudgetCents: p.BudgetCents,
+		HourlyCents: p.HourlyCents,
+	}
+}
+
+// Equal reports whether two snapshots record the same state
+func (s ProjectSnapshot) Equal(o ProjectSnapshot) bool {
+	return s.Client == o.Client && s.Description == o.Description && s.RevenueCents == o.RevenueCents &&
+		s.VATPercent == o.VATPercent && s.VATInclusive == o.VATInclusive && s.Status == o.Status && s.LostReason == o.LostReason && s.Source == o.Source && slices.Equal(s.SecuredBy, o.SecuredBy) && maps.Equal(s.Hours, o.Hours) &&
+		maps.Equal(s.SplitOverride, o.SplitOverride) && s.Archived == o.Archived &&
+		s.EstimatedHours == o.EstimatedHours && maps.Equal(s.HourEstimates, o.HourEstimates) && s.PaymentDays == o.PaymentDays &&
+		s.BudgetHours == o.BudgetHours && s.BudgetCents == o.BudgetCents && s.HourlyCents == o.HourlyCents &&
+		samePtr(s.Probability, o.Probability, func(a, b int) bool { return a == b }) &&
+		samePtr(s.ExpectedOn, o.ExpectedOn, time.Time.Equal)
+}
+
+// samePtr reports whether two optional values are both unset or equal
+func samePtr[T any](a, b *T, eq func(T, T) bool) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return eq(*a, *b)
+}
@@ -0,0 +1,281 @@
+// service/undo.go - Undo/redo: compensating operations for audit log entries
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/noor-latif/fulldash/internal/domain"
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// Audit log actions written when an entry is reverted
+const (
+	ActionUndo = "undo"
+	ActionRedo = "redo"
+)
+
+var (
+	// ErrNotRevertible is returned for entries without a restorable before/after state
+	ErrNotRevertible = errors.New("this action can't be undone")
+	// ErrAlreadyReverted is returned when an entry has already been undone or redone
+	ErrAlreadyReverted = errors.New("this action has already been reverted")
+	// ErrStale is returned when the project has changed since the entry was logged
+	ErrStale = errors.New("the project has changed since; undo the later changes first")
+)
+
+// UndoStore is the subset of the store Revert needs
+type UndoStore interface {
+	GetProject(id int64) (*models.Project, error)
+	UpdateProject(p *models.Project) error
+	DeleteProject(id int64) error
+	SetProjectArchived(id int64, archived bool) error
+	GetContributions(projectID int64) ([]models.Contribution, error)
+	SetContribution(c *models.Contribution) error
+	LogActivity(a *models.Activity) error
+	MarkActivityReverted(id, by int64) (bool, error)
+}
+
+// Changes diffs two snapshots, naming who secured the project and whose
+// hours changed from people; nil stands for a project that doesn't exist
+func Changes(people models.People, before, after *ProjectSnapshot) []Change {
+	var b, a ProjectSnapshot
+	if before != nil {
+		b = *before
+	}
+	if after != nil {
+		a = *after
+	}
+	changes := Diff(b, a)
+	if !slices.Equal(b.SecuredBy, a.SecuredBy) {
+		changes = append(changes, Change{Field: "secured by",
+			Old: formatValue(reflect.ValueOf(people.Names(b.SecuredBy))),
+			New: formatValue(reflect.ValueOf(people.Names(a.SecuredBy)))})
+	}
+	if !maps.Equal(b.SplitOverride, a.SplitOverride) {
+		changes = append(changes, Change{Field: "split override",
+			Old: formatValue(reflect.ValueOf(people.Percents(b.SplitOverride))),
+			New: formatValue(reflect.ValueOf(people.Percents(a.SplitOverride)))})
+	}
+	if !maps.Equal(b.HourEstimates, a.HourEstimates) {
+		changes = append(changes, Change{Field: "hour estimates",
+			Old: formatValue(reflect.ValueOf(people.Hours(b.HourEstimates))),
+			New: formatValue(reflect.ValueOf(people.Hours(a.HourEstimates)))})
+	}
+	for _, p := range people {
+		if old, cur := b.Hours[p.ID], a.Hours[p.ID]; old != cur {
+			changes = append(changes, Change{Field: strings.ToLower(p.Name) + " hours",
+				Old: formatNumber(old), New: formatNumber(cur)})
+		}
+	}
+	return changes
+}
+
+// EncodeSnapshot serializes a snapshot for the audit log; nil encodes as ""
+func EncodeSnapshot(s *ProjectSnapshot) string {
+	if s == nil {
+		return ""
+	}
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// decodeSnapshot parses a stored snapshot; "" decodes as nil
+func decodeSnapshot(s string) (*ProjectSnapshot, error) {
+	if s == "" {
+		return nil, nil
+	}
+	snap := &ProjectSnapshot{}
+	return snap, json.Unmarshal([]byte(s), snap)
+}
+
+// Revertible reports whether an entry can still be reverted. Entries whose
+// result is a deleted project can't: the delete cascaded to its expenses
+// and checklist, so there is nothing complete to restore.
+func Revertible(a *models.Activity) bool {
+	return a.RevertedBy == 0 && a.After != ""
+}
+
+// NextUndo picks the entry undo should revert from a user's history (newest
+// first): their latest change that hasn't been undone. Undo entries are
+// skipped (they are the redo stack); a delete blocks further undo.
+func NextUndo(history []models.Activity) *models.Activity {
+	for i := range history {
+		a := &history[i]
+		if !hasSnapshots(a) || a.Action == ActionUndo || a.RevertedBy != 0 {
+			continue
+		}
+		if Revertible(a) {
+			return a
+		}
+		return nil
+	}
+	return nil
+}
+
+// NextRedo picks the undo entry redo should revert. Any newer change other
+// than a redo clears the redo stack.
+func NextRedo(history []models.Activity) *models.Activity {
+	for i := range history {
+		a := &history[i]
+		if !hasSnapshots(a) || a.RevertedBy != 0 || a.Action == ActionRedo {
+			continue
+		}
+		if a.Action == ActionUndo && Revertible(a) {
+			return a
+		}
+		return nil
+	}
+	return nil
+}
+
+// hasSnapshots reports whether an entry recorded project state (notes such
+// as checklist overrides don't)
+func hasSnapshots(a *models.Activity) bool {
+	return a.Before != "" || a.After != ""
+}
+
+// Revert applies the compensating operation for entry a: the project goes
+// back to a's before-state, or is deleted if a created it. The project must
+// still match a's after-state, and a status change back must be a move wf
+// allows (so undoing "mark paid" needs a refund, like any move out of
+// paid; it is a *domain.TransitionError). by carries the actor fields for
+// the new entry; Revert logs it and returns it with the project as it now
+// stands (nil once deleted).
+//
+// Revert claims a before it writes the project, so run it in one
+// transaction (the store's DB.Tx): a failed claim or write rolls back the
+// lot, and a concurrent undo of the same entry gets ErrAlreadyReverted.
+func Revert(db UndoStore, wf domain.Workflow, people models.People, a *models.Activity, by models.Activity) (*models.Activity, *models.Project, error) {
+	if a.RevertedBy != 0 {
+		return nil, nil, ErrAlreadyReverted
+	}
+	if !Revertible(a) {
+		return nil, nil, ErrNotRevertible
+	}
+
+	before, err := decodeSnapshot(a.Before)
+	if err != nil {
+		return nil, nil, err
+	}
+	after, err := decodeSnapshot(a.After)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p, err := db.GetProject(a.ProjectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p == nil {
+		return nil, nil, ErrStale
+	}
+	contribs, err := db.GetContributions(p.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	current := Snapshot(p, HoursOf(contribs))
+	if !current.Equal(*after) {
+		return nil, nil, ErrStale
+	}
+	if before != nil && before.Status != current.Status {
+		if err := wf.CheckTransition(current.Status, before.Status, false); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	entry := &by
+	entry.ProjectID = p.ID
+	entry.Client = p.Client
+	entry.Action = ActionUndo
+	if a.Action == ActionUndo {
+		entry.Action = ActionRedo
+	}
+	entry.Changes = Changes(people, &current, before)
+	entry.Summary = Summary(entry.Changes)
+	entry.Before = a.After
+	entry.After = a.Before
+	if err := db.LogActivity(entry); err != nil {
+		return nil, nil, err
+	}
+
+	ok, err := db.MarkActivityReverted(a.ID, entry.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, ErrAlreadyReverted
+	}
+
+	if before == nil {
+		if err := db.DeleteProject(p.ID); err != nil {
+			return nil, nil, err
+		}
+		return entry, nil, nil
+	}
+	if err := restore(db, p, contribs, before); err != nil {
+		return nil, nil, err
+	}
+	return entry, p, nil
+}
+
+// restore writes a snapshot's fields and hours back onto a project; people
+// with hours now but none in the snapshot are set back to zero
+func restore(db UndoStore, p *models.Project, contribs []models.Contribution, s *ProjectSnapshot) error {
+	p.Client = s.Client
+	p.Description = s.Description
+	p.RevenueCents = s.RevenueCents
+	p.VATPercent = s.VATPercent
+	p.VATInclusive = s.VATInclusive
+	p.Status = s.Status
+	p.LostReason = s.LostReason
+	p.Source = s.Source
+	p.Probability = s.Probability
+	p.ExpectedOn = s.ExpectedOn
+	p.SecuredBy = s.SecuredBy
+	p.SplitOverride = s.SplitOverride
+	p.EstimatedHours = s.EstimatedHours
+	p.HourEstimates = s.HourEstimates
+	p.PaymentDays = s.PaymentDays
+	p.BudgetHours = s.BudgetHours
+	p.BudgetCents = s.BudgetCents
+	p.HourlyCents = s.HourlyCents
+	if err := db.UpdateProject(p); err != nil {
+		return err
+	}
+	if s.Archived != p.Archived() {
+		if err := db.SetProjectArchived(p.ID, s.Archived); err != nil {
+			return err
+		}
+	}
+
+	hours := models.PerPerson[float64]{}
+	for _, c := range contribs {
+		hours[c.PersonID] = 0
+	}
+	for id, h := range s.Hours {
+		hours[id] = h
+	}
+	for id, h := range hours {
+		if err := db.SetContribution(&models.Contribution{ProjectID: p.ID, PersonID: id, Hours: h}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HoursOf returns each person's logged hours from a project's contributions,
+// leaving out those with none
+func HoursOf(contribs []models.Contribution) models.PerPerson[float64] {
+	hours := models.PerPerson[float64]{}
+	for _, c := range contribs {
+		if c.Hours != 0 {
+			hours[c.PersonID] += c.Hours
+		}
+	}
+	return hours
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/noor-latif/fulldash/internal/domain"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/store"
+)
+
+// undoFixture opens a scratch database with a project moved from one
+// status to another, and the audit entry for the move
+func undoFixture(t *testing.T, from, to models.ProjectStatus) (*store.DB, domain.Workflow, models.People, *models.Activity) {
+	t.Helper()
+	db, err := store.New(filepath.Join(t.TempDir(), "undo.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	statuses, err := db.ListStatuses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	people, err := db.ListPeople()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &models.Project{Client: "Acme", RevenueCents: 100000, Status: from, SecuredBy: []int64{people[0].ID}}
+	if err := db.CreateProject(p); err != nil {
+		t.Fatal(err)
+	}
+	before := Snapshot(p, nil)
+	p.Status = to
+	if err := db.UpdateProject(p); err != nil {
+		t.Fatal(err)
+	}
+	after := Snapshot(p, nil)
+	a := &models.Activity{ProjectID: p.ID, Client: p.Client, Action: "updated", Actor: "Noor",
+		Before: EncodeSnapshot(&before), After: EncodeSnapshot(&after)}
+	if err := db.LogActivity(a); err != nil {
+		t.Fatal(err)
+	}
+	return db, domain.Workflow(statuses), people, a
+}
+
+// revert runs Revert in a transaction, as the handlers do
+func revert(db *store.DB, wf domain.Workflow, people models.People, a *models.Activity) (entry *models.Activity, err error) {
+	err = db.Tx(func(tx *store.DB) error {
+		entry, _, err = Revert(tx, wf, people, a, models.Activity{Actor: "Noor"})
+		return err
+	})
+	return entry, err
+}
+
+func TestRevertMove(t *testing.T) {
+	db, wf, people, a := undoFixture(t, models.StatusNew, models.StatusProgress)
+	entry, err := revert(db, wf, people, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := db.GetProject(a.ProjectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != models.StatusNew {
+		t.Errorf("status %s after undo, want new", p.Status)
+	}
+	if entry.Action != ActionUndo || entry.Before != a.After || entry.After != a.Before {
+		t.Errorf("undo entry %+v doesn't mirror %+v", entry, a)
+	}
+}
+
+func TestRevertPaidNeedsRefund(t *testing.T) {
+	db, wf, people, a := undoFixture(t, models.StatusDone, models.StatusPaid)
+	_, err := revert(db, wf, people, a)
+	var tr *domain.TransitionError
+	if !errors.As(err, &tr) || !tr.NeedsRefund {
+		t.Fatalf("undoing mark paid: %v, want a refund TransitionError", err)
+	}
+
+	p, err := db.GetProject(a.ProjectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != models.StatusPaid || p.PaidAt == nil {
+		t.Errorf("project %s (paid at %v) after a refused undo, want it still paid", p.Status, p.PaidAt)
+	}
+	history, err := db.ListProjectActivity(a.ProjectID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range history {
+		if h.RevertedBy != 0 || h.Action == ActionUndo {
+			t.Errorf("refused undo left %+v in the log", h)
+		}
+	}
+}
+
+// A claim that fails rolls back: nothing is written and nothing logged
+func TestRevertClaimedElsewhere(t *testing.T) {
+	db, wf, people, a := undoFixture(t, models.StatusNew, models.StatusProgress)
+	other := &models.Activity{ProjectID: a.ProjectID, Client: a.Client, Action: ActionUndo, Actor: "Ahmad"}
+	if err := db.LogActivity(other); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := db.MarkActivityReverted(a.ID, other.ID); err != nil || !ok {
+		t.Fatalf("claim: %v, %v", ok, err)
+	}
+
+	// a is as loaded before the other claim landed
+	if _, err := revert(db, wf, people, a); !errors.Is(err, ErrAlreadyReverted) {
+		t.Fatalf("revert of a claimed entry: %v, want ErrAlreadyReverted", err)
+	}
+	p, err := db.GetProject(a.ProjectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != models.StatusProgress {
+		t.Errorf("status %s after a failed claim, want in_progress", p.Status)
+	}
+	history, err := db.ListProjectActivity(a.ProjectID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Errorf("%d log entries after a failed claim, want 2", len(history))
+	}
+}
+
+func TestRevertConcurrent(t *testing.T) {
+	db, wf, people, a := undoFixture(t, models.StatusNew, models.StatusProgress)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Go(func() {
+			entry := *a
+			_, errs[i] = revert(db, wf, people, &entry)
+		})
+	}
+	wg.Wait()
+
+	reverted := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			reverted++
+		case !errors.Is(err, ErrStale) && !errors.Is(err, ErrAlreadyReverted):
+			t.Errorf("concurrent undo: %v", err)
+		}
+	}
+	if reverted != 1 {
+		t.Errorf("%d concurrent undos of one entry went through, want 1", reverted)
+	}
+	history, err := db.ListProjectActivity(a.ProjectID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Errorf("%d log entries, want the move and one undo", len(history))
+	}
+}
@@ -0,0 +1,145 @@
+// sie/sie.go - SIE type 4 output: vouchers for import into Swedish accounting
+package sie
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// dateLayout is a date in SIE form
+const dateLayout = "20060102"
+
+// File is a year of vouchers and the accounts they book to
+type File struct {
+	Company   string
+	OrgNumber string    // organisationsnummer, e.g. 556677-8899
+	Year      int       // the fiscal year, January to December
+	Generated time.Time // when the file was made
+	Accounts  []Account // every account the vouchers use
+	Vouchers  []Voucher
+}
+
+// Account is an account number and its name
+type Account struct {
+	Number string
+	Name   string
+}
+
+// Voucher is one balanced transaction (verifikation)
+type Voucher struct {
+	Series string // e.g. "F" for invoices; numbered within the series
+	Number int
+	Date   time.Time
+	Text   string
+	Rows   []Row
+}
+
+// Row books an amount to an account: positive is debit, negative credit
+type Row struct {
+	Account     string
+	AmountCents int64
+}
+
+// Balanced reports whether the voucher's rows add up to zero
+func (v Voucher) Balanced() bool {
+	var sum int64
+	for _, r := range v.Rows {
+		sum += r.AmountCents
+	}
+	return sum == 0
+}
+
+// Write renders f as an SIE 4 file in the PC8 (code page 437) encoding the
+// format prescribes. Every voucher must balance.
+func Write(w io.Writer, f File) error {
+	for _, v := range f.Vouchers {
+		if !v.Balanced() {
+			return fmt.Errorf("voucher %s %d (%s) doesn't balance", v.Series, v.Number, v.Text)
+		}
+	}
+
+	var b strings.Builder
+	line := func(label string, fields ...string) {
+		b.WriteString("#" + label)
+		for _, f := range fields {
+			b.WriteString(" " + f)
+		}
+		b.WriteString("\r\n")
+	}
+
+	line("FLAGGA", "0")
+	line("FORMAT", "PC8")
+	line("SIETYP", "4")
+	line("PROGRAM", quote("FullDash"), quote("1.0"))
+	line("GEN", f.Generated.Format(dateLayout))
+	line("FNAMN", quote(f.Company))
+	if f.OrgNumber != "" {
+		line("ORGNR", f.OrgNumber)
+	}
+	start := time.Date(f.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	line("RAR", "0", start.Format(dateLayout), start.AddDate(1, 0, -1).Format(dateLayout))
+	line("KPTYP", "BAS2014")
+	for _, a := range f.Accounts {
+		line("KONTO", a.Number, quote(a.Name))
+	}
+	for _, v := range f.Vouchers {
+		line("VER", quote(v.Series), quote(fmt.Sprint(v.Number)), v.Date.Format(dateLayout), quote(v.Text))
+		b.WriteString("{\r\n")
+		for _, r := range v.Rows {
+			b.WriteString("   ")
+			line("TRANS", r.Account, "{}", amount(r.AmountCents))
+		}
+		b.WriteString("}\r\n")
+	}
+
+	_, err := w.Write(pc8(b.String()))
+	return err
+}
+
+// quote makes a string field: in double quotes, with quotes inside escaped
+// and control characters dropped
+func quote(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// amount formats cents as kronor with a decimal point: "-1234.50"
+func amount(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// cp437 maps the letters code page 437 has above ASCII to their bytes
+var cp437 = map[rune]byte{
+	'Ç': 0x80, 'ü': 0x81, 'é': 0x82, 'â': 0x83, 'ä': 0x84, 'à': 0x85, 'å': 0x86, 'ç': 0x87,
+	'ê': 0x88, 'ë': 0x89, 'è': 0x8a, 'ï': 0x8b, 'î': 0x8c, 'ì': 0x8d, 'Ä': 0x8e, 'Å': 0x8f,
+	'É': 0x90, 'æ': 0x91, 'Æ': 0x92, 'ô': 0x93, 'ö': 0x94, 'ò': 0x95, 'û': 0x96, 'ù': 0x97,
+	'ÿ': 0x98, 'Ö': 0x99, 'Ü': 0x9a, '¢': 0x9b, '£': 0x9c, '¥': 0x9d, 'ƒ': 0x9f,
+	'á': 0xa0, 'í': 0xa1, 'ó': 0xa2, 'ú': 0xa3, 'ñ': 0xa4, 'Ñ': 0xa5,
+}
+
+// pc8 encodes s in code page 437; characters it lacks become "?"
+func pc8(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch b, ok := cp437[r]; {
+		case r < 0x80:
+			out = append(out, byte(r))
+		case ok:
+			out = append(out, b)
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
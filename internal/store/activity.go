@@ -0,0 +1,116 @@
+// store/activity.go - Audit log operations (also feeds the dashboard activity feed)
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// activityScanner for DRY row scanning
+type activityScanner struct {
+	dest *models.Activity
+}
+
+// activityFields lists an audit log entry's selected columns in scan order
+func activityFields(a *models.Activity) []field {
+	return []field{
+		{"id", &a.ID}, {"project_id", &a.ProjectID}, {"client", &a.Client}, {"action", &a.Action},
+		{"summary", null(&a.Summary)}, {"actor", &a.Actor}, {"source", &a.Source},
+		{"changes", jsonText{&a.Changes}}, {"created_at", &a.CreatedAt}, {"user_id", null(&a.UserID)},
+		{"snapshot_before", null(&a.Before)}, {"snapshot_after", null(&a.After)},
+		{"reverted_by", null(&a.RevertedBy)}, {"request_id", null(&a.RequestID)},
+	}
+}
+
+func (s activityScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, activityFields(s.dest))
+}
+
+func (s activityScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// LogActivity appends an entry to the audit log
+func (db *DB) LogActivity(a *models.Activity) error {
+	var changes any
+	if len(a.Changes) > 0 {
+		b, err := json.Marshal(a.Changes)
+		if err != nil {
+			return err
+		}
+		changes = string(b)
+	}
+	return db.QueryRow(qActivityInsert, a.ProjectID, a.Client, a.Action, a.Summary, a.Actor, a.Source, changes,
+		nullID(a.UserID), nullString(a.Before), nullString(a.After), nullString(a.RequestID)).Scan(&a.ID, &a.CreatedAt)
+}
+
+// ListActivity returns the most recent audit log entries, newest first
+func (db *DB) ListActivity(limit int) ([]models.Activity, error) {
+	return db.queryActivity(qActivityRecent, limit)
+}
+
+// ListProjectActivity returns a project's audit history, newest first
+func (db *DB) ListProjectActivity(projectID int64, limit int) ([]models.Activity, error) {
+	return db.queryActivity(qActivityByProject, projectID, limit)
+}
+
+// ListUserActivity returns the entries a user made (their undo history), newest first
+func (db *DB) ListUserActivity(userID int64, limit int) ([]models.Activity, error) {
+	return db.queryActivity(qActivityByUser, userID, limit)
+}
+
+// ActivityBetween returns the entries made from from up to to, oldest first
+func (db *DB) ActivityBetween(from, to time.Time) ([]models.Activity, error) {
+	// created_at is SQLite's CURRENT_TIMESTAMP: UTC, to the second
+	return db.queryActivity(qActivityBetween, from.UTC().Format(time.DateTime), to.UTC().Format(time.DateTime))
+}
+
+// FirstStripePayment returns when the webhook first logged a Stripe payment,
+// or nil if it never has
+func (db *DB) FirstStripePayment() (*time.Time, error) {
+	var at time.Time
+	err := db.QueryRow(qActivityFirstStripePayment).Scan(&at)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &at, nil
+}
+
+// GetActivity fetches an audit log entry by ID
+func (db *DB) GetActivity(id int64) (*models.Activity, error) {
+	a := &models.Activity{}
+	err := activityScanner{a}.scan(db.QueryRow(qActivityGet, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return a, err
+}
+
+// MarkActivityReverted records that entry id was reverted by entry by.
+// It reports false if the entry had already been reverted.
+func (db *DB) MarkActivityReverted(id, by int64) (bool, error) {
+	res, err := db.Exec(qActivityMarkReverted, by, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (db *DB) queryActivity(query string, args ...any) ([]models.Activity, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Activity { return &models.Activity{} },
+		func(a *models.Activity) scanner { return activityScanner{a} })
+}
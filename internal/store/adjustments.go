@@ -0,0 +1,73 @@
+// store/adjustments.go - Adjusting entries (corrections to locked periods)
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// adjustmentScanner for DRY row scanning
+type adjustmentScanner struct {
+	dest *models.Adjustment
+}
+
+// adjustmentFields lists an adjusting entry's selected columns in scan order
+func adjustmentFields(a *models.Adjustment) []field {
+	return []field{
+		{"id", &a.ID}, {"project_id", &a.ProjectID}, {"posted_on", &a.PostedOn}, {"revenue_cents", &a.RevenueCents},
+		{"share_cents", jsonText{&a.ShareCents}}, {"reason", &a.Reason}, {"created_by", &a.CreatedBy},
+		{"created_at", &a.CreatedAt},
+	}
+}
+
+func (s adjustmentScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, adjustmentFields(s.dest))
+}
+
+func (s adjustmentScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// ListAdjustments returns a project's adjusting entries, newest first
+func (db *DB) ListAdjustments(projectID int64) ([]models.Adjustment, error) {
+	return db.queryAdjustments(qAdjustmentsByProject, projectID)
+}
+
+// ListAdjustmentsPosted returns the adjusting entries posted between from and
+// to (inclusive), so period reports count them when they were made
+func (db *DB) ListAdjustmentsPosted(from, to time.Time) ([]models.Adjustment, error) {
+	return db.queryAdjustments(qAdjustmentsPosted, from.Format(dateLayout), to.Format(dateLayout))
+}
+
+func (db *DB) queryAdjustments(query string, args ...any) ([]models.Adjustment, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Adjustment { return &models.Adjustment{} },
+		func(a *models.Adjustment) scanner { return adjustmentScanner{a} })
+}
+
+// CreateAdjustment posts an adjusting entry. Entries are never edited or
+// deleted; a mistake is reversed with another entry. The posting date can't
+// fall in a locked month.
+func (db *DB) CreateAdjustment(a *models.Adjustment) error {
+	return db.Tx(func(tx *DB) error {
+		lock, err := tx.PeriodLockAt(a.PostedOn)
+		if err != nil {
+			return err
+		}
+		if lock != nil {
+			return fmt.Errorf("adjustments can't be posted in %s, a locked period", lock.Label())
+		}
+
+		return tx.QueryRow(qAdjustmentInsert, a.ProjectID, a.PostedOn.Format(dateLayout), a.RevenueCents,
+			jsonArg(a.ShareCents), a.Reason, a.CreatedBy).Scan(&a.ID, &a.CreatedAt)
+	})
+}
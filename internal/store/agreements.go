@@ -0,0 +1,65 @@
+// store/agreements.go - Versioned partner agreements (split rules)
+package store
+
+import (
+	"database/sql"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// agreementScanner for DRY row scanning
+type agreementScanner struct {
+	dest *models.Agreement
+}
+
+// agreementFields lists an agreement's selected columns in scan order
+func agreementFields(a *models.Agreement) []field {
+	return []field{
+		{"id", &a.ID}, {"effective_from", &a.EffectiveFrom}, {"fixed_split", &a.FixedSplit},
+		{"percents", jsonText{&a.Percents}}, {"finder_fee_percent", &a.FinderFeePercent},
+		{"expense_policy", &a.ExpensePolicy}, {"notes", null(&a.Notes)}, {"created_at", &a.CreatedAt},
+	}
+}
+
+func (s agreementScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, agreementFields(s.dest))
+}
+
+func (s agreementScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// ListAgreements returns all agreement versions, newest first
+func (db *DB) ListAgreements() ([]models.Agreement, error) {
+	rows, err := db.Query(qAgreementsAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Agreement { return &models.Agreement{} },
+		func(a *models.Agreement) scanner { return agreementScanner{a} })
+}
+
+// GetAgreement fetches an agreement version by ID
+func (db *DB) GetAgreement(id int64) (*models.Agreement, error) {
+	a := &models.Agreement{}
+	err := agreementScanner{a}.scan(db.QueryRow(qAgreementByID, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return a, err
+}
+
+// CreateAgreement adds an agreement version
+func (db *DB) CreateAgreement(a *models.Agreement) error {
+	return db.QueryRow(qAgreementInsert, a.EffectiveFrom.Format(dateLayout), a.FixedSplit, jsonArg(a.Percents),
+		a.FinderFeePercent, a.ExpensePolicy, a.Notes).Scan(&a.ID, &a.CreatedAt)
+}
+
+// DeleteAgreement removes an agreement version
+func (db *DB) DeleteAgreement(id int64) error {
+	_, err := db.Exec(qAgreementDelete, id)
+	return err
+}
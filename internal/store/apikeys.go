@@ -0,0 +1,70 @@
+// store/apikeys.go - API key persistence
+package store
+
+import (
+	"database/sql"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// apiKeyScanner for DRY row scanning
+type apiKeyScanner struct {
+	dest *models.APIKey
+}
+
+// apiKeyFields lists an API key's selected columns in scan order
+func apiKeyFields(k *models.APIKey) []field {
+	return []field{
+		{"id", &k.ID}, {"name", &k.Name}, {"prefix", &k.Prefix}, {"token_hash", &k.TokenHash},
+		{"scope", &k.Scope}, {"created_at", &k.CreatedAt}, {"last_used_at", opt(&k.LastUsedAt)},
+		{"revoked_at", opt(&k.RevokedAt)},
+	}
+}
+
+func (s apiKeyScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, apiKeyFields(s.dest))
+}
+
+func (s apiKeyScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// CreateAPIKey stores a new key (TokenHash must already be set)
+func (db *DB) CreateAPIKey(k *models.APIKey) error {
+	return db.QueryRow(qAPIKeyInsert, k.Name, k.Prefix, k.TokenHash, k.Scope).Scan(&k.ID, &k.CreatedAt)
+}
+
+// GetAPIKeyByHash returns the active key matching a token hash, or nil
+func (db *DB) GetAPIKeyByHash(hash string) (*models.APIKey, error) {
+	k := &models.APIKey{}
+	err := apiKeyScanner{k}.scan(db.QueryRow(qAPIKeyByHash, hash).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return k, err
+}
+
+// ListAPIKeys returns all keys, including revoked ones
+func (db *DB) ListAPIKeys() ([]models.APIKey, error) {
+	rows, err := db.Query(qAPIKeysAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.APIKey { return &models.APIKey{} },
+		func(k *models.APIKey) scanner { return apiKeyScanner{k} })
+}
+
+// TouchAPIKey records that a key was just used
+func (db *DB) TouchAPIKey(id int64) error {
+	_, err := db.Exec(qAPIKeyTouch, id)
+	return err
+}
+
+// RevokeAPIKey disables a key permanently
+func (db *DB) RevokeAPIKey(id int64) error {
+	_, err := db.Exec(qAPIKeyRevoke, id)
+	return err
+}
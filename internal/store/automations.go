@@ -0,0 +1,140 @@
+// store/automations.go - Automation rules and queued runs
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// automationRuleScanner for DRY row scanning
+type automationRuleScanner struct {
+	dest *models.AutomationRule
+}
+
+// automationRuleFields lists a rule's selected columns in scan order
+func automationRuleFields(r *models.AutomationRule) []field {
+	return []field{
+		{"id", &r.ID}, {"trigger", &r.Trigger}, {"action", &r.Action}, {"param", &r.Param},
+		{"delay_days", &r.DelayDays}, {"enabled", &r.Enabled}, {"created_at", &r.CreatedAt},
+	}
+}
+
+func (s automationRuleScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, automationRuleFields(s.dest))
+}
+
+func (s automationRuleScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// automationRunScanner for DRY row scanning
+type automationRunScanner struct {
+	dest *models.AutomationRun
+}
+
+// automationRunFields lists a queued run's selected columns in scan order
+func automationRunFields(r *models.AutomationRun) []field {
+	return []field{
+		{"id", &r.ID}, {"rule_id", &r.RuleID}, {"project_id", &r.ProjectID}, {"run_at", &r.RunAt},
+		{"done_at", opt(&r.DoneAt)}, {"error", null(&r.Error)},
+	}
+}
+
+func (s automationRunScanner) Scan(rows *sql.Rows) error {
+	return scanInto(rows.Scan, automationRunFields(s.dest))
+}
+
+// ListAutomationRules returns all rules
+func (db *DB) ListAutomationRules() ([]models.AutomationRule, error) {
+	return db.queryAutomationRules(qAutomationRulesAll)
+}
+
+// ListAutomationRulesFor returns the enabled rules for a trigger
+func (db *DB) ListAutomationRulesFor(trigger models.AutomationTrigger) ([]models.AutomationRule, error) {
+	return db.queryAutomationRules(qAutomationRulesByTrigger, trigger)
+}
+
+func (db *DB) queryAutomationRules(query string, args ...any) ([]models.AutomationRule, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.AutomationRule { return &models.AutomationRule{} },
+		func(r *models.AutomationRule) scanner { return automationRuleScanner{r} })
+}
+
+// GetAutomationRule fetches a rule by ID
+func (db *DB) GetAutomationRule(id int64) (*models.AutomationRule, error) {
+	r := &models.AutomationRule{}
+	err := automationRuleScanner{r}.scan(db.QueryRow(qAutomationRuleByID, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+// CreateAutomationRule inserts a rule
+func (db *DB) CreateAutomationRule(r *models.AutomationRule) error {
+	return db.QueryRow(qAutomationRuleInsert, r.Trigger, r.Action, r.Param, r.DelayDays, r.Enabled).
+		Scan(&r.ID, &r.CreatedAt)
+}
+
+// SetAutomationRuleEnabled pauses or resumes a rule
+func (db *DB) SetAutomationRuleEnabled(id int64, enabled bool) error {
+	_, err := db.Exec(qAutomationRuleSetEnabled, enabled, id)
+	return err
+}
+
+// DeleteAutomationRule removes a rule and its pending runs
+func (db *DB) DeleteAutomationRule(id int64) error {
+	_, err := db.Exec(qAutomationRuleDelete, id)
+	return err
+}
+
+// ScheduleAutomationRun queues a rule to run for a project at run.RunAt
+func (db *DB) ScheduleAutomationRun(run *models.AutomationRun) error {
+	return db.QueryRow(qAutomationRunInsert, run.RuleID, run.ProjectID, run.RunAt.UTC()).Scan(&run.ID)
+}
+
+// DueAutomationRuns returns pending runs scheduled at or before now
+func (db *DB) DueAutomationRuns(now time.Time) ([]models.AutomationRun, error) {
+	rows, err := db.Query(qAutomationRunsDue, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.AutomationRun { return &models.AutomationRun{} },
+		func(r *models.AutomationRun) scanner { return automationRunScanner{r} })
+}
+
+// ClaimAutomationRun takes a pending run for this instance; false means
+// another instance already claimed (or finished) it
+func (db *DB) ClaimAutomationRun(id int64) (bool, error) {
+	res, err := db.Exec(qAutomationRunClaim, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// FinishAutomationRun marks a run done, recording its error if any
+func (db *DB) FinishAutomationRun(id int64, runErr string) error {
+	_, err := db.Exec(qAutomationRunFinish, nullString(runErr), id)
+	return err
+}
+
+// nullString stores empty strings as NULL
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,56 @@
+// store/backups.go - The log of backups stored at each destination
+package store
+
+import (
+	"database/sql"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// backupRunScanner for DRY row scanning
+type backupRunScanner struct {
+	dest *models.BackupRun
+}
+
+// backupRunFields lists a backup run's selected columns in scan order
+func backupRunFields(r *models.BackupRun) []field {
+	return []field{
+		{"id", &r.ID}, {"destination", &r.Destination}, {"file", &r.File}, {"size_bytes", &r.SizeBytes},
+		{"ok", &r.OK}, {"details", &r.Details}, {"trigger", &r.Trigger}, {"started_at", &r.StartedAt},
+		{"finished_at", &r.FinishedAt},
+	}
+}
+
+func (s backupRunScanner) Scan(rows *sql.Rows) error {
+	return scanInto(rows.Scan, backupRunFields(s.dest))
+}
+
+// RecordBackupRun logs a backup stored, or failed, at a destination
+func (db *DB) RecordBackupRun(r *models.BackupRun) error {
+	return db.QueryRow(qBackupRunInsert, r.Destination, r.File, r.SizeBytes, r.OK, r.Details, r.Trigger,
+		r.StartedAt.UTC(), r.FinishedAt.UTC()).Scan(&r.ID)
+}
+
+// ListBackupRuns returns the most recent backup runs, newest first
+func (db *DB) ListBackupRuns(limit int) ([]models.BackupRun, error) {
+	rows, err := db.Query(qBackupRunsRecent, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.BackupRun { return &models.BackupRun{} },
+		func(r *models.BackupRun) scanner { return backupRunScanner{r} })
+}
+
+// LastBackupRun returns a destination's latest successful (ok) or failed
+// run, or nil if it has had none
+func (db *DB) LastBackupRun(destination string, ok bool) (*models.BackupRun, error) {
+	r := &models.BackupRun{}
+	err := scanInto(db.QueryRow(qBackupRunLatest, destination, ok).Scan, backupRunFields(r))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
@@ -0,0 +1,138 @@
+// store/chaos.go - Failure injection for development (CHAOS_* settings)
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// Chaos configures failure injection: every statement, transaction start and
+// commit waits a random delay up to Latency, and a share ErrorRate of them
+// fail as if another writer held the database. Never enable in production.
+type Chaos struct {
+	Latency   time.Duration
+	ErrorRate float64
+}
+
+// Enabled reports whether c injects anything
+func (c Chaos) Enabled() bool {
+	return c.Latency > 0 || c.ErrorRate > 0
+}
+
+// Errors injected by chaos mode, worded like the driver's own so callers see
+// what they would in production
+var (
+	ErrChaosBusy     = errors.New("database is locked (5) (SQLITE_BUSY) [chaos]")
+	ErrChaosSnapshot = errors.New("database is locked (517) (SQLITE_BUSY_SNAPSHOT) [chaos]")
+)
+
+// NewChaos opens the database like Open. Failures are injected once arm is
+// called, so migrations and startup run clean.
+func NewChaos(dbPath string, o Options, c Chaos) (db *DB, arm func(), err error) {
+	if err := o.Validate(); err != nil {
+		return nil, nil, err
+	}
+	conn := &chaosConnector{base: &sqliteConnector{dsn: o.dsn(dbPath)}, chaos: c}
+	db, err = open(dbPath, conn, o)
+	return db, func() { conn.armed.Store(true) }, err
+}
+
+// chaosConnector opens sqlite connections wrapped with failure injection
+type chaosConnector struct {
+	base  driver.Connector
+	chaos Chaos
+	armed atomic.Bool
+}
+
+func (c *chaosConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &chaosConn{Conn: conn, c: c}, nil
+}
+
+func (c *chaosConnector) Driver() driver.Driver {
+	return c.base.Driver()
+}
+
+// inject waits and maybe fails, once armed
+func (c *chaosConnector) inject(ctx context.Context) error {
+	if !c.armed.Load() {
+		return nil
+	}
+	if c.chaos.Latency > 0 {
+		select {
+		case <-time.After(rand.N(c.chaos.Latency)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if rand.Float64() < c.chaos.ErrorRate {
+		if rand.IntN(2) == 0 {
+			return ErrChaosBusy
+		}
+		return ErrChaosSnapshot
+	}
+	return nil
+}
+
+// chaosConn forwards to the sqlite connection after injecting
+type chaosConn struct {
+	driver.Conn
+	c *chaosConnector
+}
+
+func (cn *chaosConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := cn.c.inject(ctx); err != nil {
+		return nil, err
+	}
+	tx, err := cn.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &chaosTx{Tx: tx, c: cn.c}, nil
+}
+
+func (cn *chaosConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return cn.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (cn *chaosConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := cn.c.inject(ctx); err != nil {
+		return nil, err
+	}
+	return cn.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (cn *chaosConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := cn.c.inject(ctx); err != nil {
+		return nil, err
+	}
+	return cn.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (cn *chaosConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := cn.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// chaosTx can fail at commit, leaving the transaction rolled back
+type chaosTx struct {
+	driver.Tx
+	c *chaosConnector
+}
+
+func (t *chaosTx) Commit() error {
+	if err := t.c.inject(context.Background()); err != nil {
+		t.Tx.Rollback()
+		return err
+	}
+	return t.Tx.Commit()
+}
@@ -0,0 +1,105 @@
+// store/checklists.go - Project checklist operations
+package store
+
+import (
+	"database/sql"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// checklistTemplateScanner for DRY row scanning
+type checklistTemplateScanner struct {
+	dest *models.ChecklistTemplate
+}
+
+// checklistTemplateFields lists a template item's selected columns in scan order
+func checklistTemplateFields(t *models.ChecklistTemplate) []field {
+	return []field{
+		{"id", &t.ID}, {"stage", &t.Stage}, {"title", &t.Title}, {"critical", &t.Critical},
+		{"position", &t.Position},
+	}
+}
+
+func (s checklistTemplateScanner) Scan(rows *sql.Rows) error {
+	return scanInto(rows.Scan, checklistTemplateFields(s.dest))
+}
+
+// checklistItemScanner for DRY row scanning
+type checklistItemScanner struct {
+	dest *models.ChecklistItem
+}
+
+// checklistItemFields lists a project checklist item's selected columns in scan order
+func checklistItemFields(it *models.ChecklistItem) []field {
+	return []field{
+		{"id", &it.ID}, {"project_id", &it.ProjectID}, {"stage", &it.Stage}, {"title", &it.Title},
+		{"critical", &it.Critical}, {"done", &it.Done}, {"done_at", opt(&it.DoneAt)}, {"position", &it.Position},
+	}
+}
+
+func (s checklistItemScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, checklistItemFields(s.dest))
+}
+
+func (s checklistItemScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// ListChecklistTemplates returns the standard items for all stages
+func (db *DB) ListChecklistTemplates() ([]models.ChecklistTemplate, error) {
+	rows, err := db.Query(qChecklistTemplatesAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.ChecklistTemplate { return &models.ChecklistTemplate{} },
+		func(t *models.ChecklistTemplate) scanner { return checklistTemplateScanner{t} })
+}
+
+// CreateChecklistTemplate appends a standard item to the end of its stage
+func (db *DB) CreateChecklistTemplate(t *models.ChecklistTemplate) error {
+	return db.QueryRow(qChecklistTemplateInsert, t.Stage, t.Title, t.Critical, t.Stage).Scan(&t.ID, &t.Position)
+}
+
+// DeleteChecklistTemplate removes a standard item (existing project copies are kept)
+func (db *DB) DeleteChecklistTemplate(id int64) error {
+	_, err := db.Exec(qChecklistTemplateDelete, id)
+	return err
+}
+
+// AttachChecklist copies a stage's standard items onto a project (once)
+func (db *DB) AttachChecklist(projectID int64, stage models.ChecklistStage) error {
+	_, err := db.Exec(qChecklistAttach, projectID, stage, projectID, stage)
+	return err
+}
+
+// ListChecklist returns a project's checklist items across all stages
+func (db *DB) ListChecklist(projectID int64) ([]models.ChecklistItem, error) {
+	rows, err := db.Query(qChecklistByProject, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.ChecklistItem { return &models.ChecklistItem{} },
+		func(c *models.ChecklistItem) scanner { return checklistItemScanner{c} })
+}
+
+// GetChecklistItem fetches a checklist item by ID
+func (db *DB) GetChecklistItem(id int64) (*models.ChecklistItem, error) {
+	c := &models.ChecklistItem{}
+	err := checklistItemScanner{c}.scan(db.QueryRow(qChecklistItemByID, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return c, err
+}
+
+// SetChecklistItemDone ticks or unticks an item
+func (db *DB) SetChecklistItemDone(id int64, done bool) error {
+	_, err := db.Exec(qChecklistItemSetDone, done, done, id)
+	return err
+}
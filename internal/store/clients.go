@@ -0,0 +1,241 @@
+// store/clients.go - Clients: payment branding and lifetime reports
+package store
+
+import (
+	"cmp"
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// clientScanner for DRY row scanning
+type clientScanner struct {
+	dest *models.Client
+}
+
+// clientFields lists a client's selected columns in scan order
+func clientFields(c *models.Client) []field {
+	return []field{
+		{"id", &c.ID}, {"name", &c.Name}, {"statement_descriptor", &c.StatementDescriptor},
+		{"logo_url", &c.LogoURL}, {"thank_you", &c.ThankYou}, {"email", &c.Email}, {"created_at", &c.CreatedAt},
+	}
+}
+
+func (s clientScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, clientFields(s.dest))
+}
+
+func (s clientScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// ListClients returns all clients by name
+func (db *DB) ListClients() ([]models.Client, error) {
+	rows, err := db.Query(qClientsAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Client { return &models.Client{} },
+		func(c *models.Client) scanner { return clientScanner{c} })
+}
+
+// GetClientByName returns the client a project's client name refers to
+// (ignoring case), or nil if none is set up
+func (db *DB) GetClientByName(name string) (*models.Client, error) {
+	c := &models.Client{}
+	err := clientScanner{c}.scan(db.QueryRow(qClientByName, name).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return c, err
+}
+
+// SaveClient creates a client, or updates the one with the same name
+func (db *DB) SaveClient(c *models.Client) error {
+	return db.QueryRow(qClientUpsert, c.Name, c.StatementDescriptor, c.LogoURL, c.ThankYou, c.Email).Scan(&c.ID, &c.CreatedAt)
+}
+
+// DeleteClient removes a client's settings; its projects are unaffected
+func (db *DB) DeleteClient(id int64) error {
+	_, err := db.Exec(qClientDelete, id)
+	return err
+}
+
+// EnsureClient adds a client by name unless one exists, so every client a
+// project names has a row to report on
+func (db *DB) EnsureClient(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return nil
+	}
+	_, err := db.Exec(qClientEnsure, name)
+	return err
+}
+
+// ClientReports sums up every client, largest lifetime revenue first
+func (db *DB) ClientReports() ([]models.ClientReport, error) {
+	clients, err := db.ListClients()
+	if err != nil {
+		return nil, err
+	}
+	reports, err := db.clientReports(clients, false)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(reports, func(a, b models.ClientReport) int { return cmp.Compare(b.LifetimeCents, a.LifetimeCents) })
+	return reports, nil
+}
+
+// ClientReport sums up one client with its projects and invoices, or
+// returns nil if there is no such client
+func (db *DB) ClientReport(id int64) (*models.ClientReport, error) {
+	c := &models.Client{}
+	err := clientScanner{c}.scan(db.QueryRow(qClientByID, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	reports, err := db.clientReports([]models.Client{*c}, true)
+	if err != nil {
+		return nil, err
+	}
+	if reports[0].Retainers, err = db.ListRetainers(c.ID); err != nil {
+		return nil, err
+	}
+	if reports[0].Documents, err = db.ListClientDocuments(c.ID); err != nil {
+		return nil, err
+	}
+	return &reports[0], nil
+}
+
+// clientProjectScanner for DRY row scanning
+type clientProjectScanner struct {
+	dest *models.ClientProject
+}
+
+// clientProjectFields lists a project's columns, then the hours logged on it
+func clientProjectFields(p *models.ClientProject) []field {
+	return append(projectFields(&p.Project),
+		field{"(SELECT COALESCE(SUM(c.hours), 0) FROM contributions c WHERE c.project_id = projects.id)", &p.Hours})
+}
+
+func (s clientProjectScanner) Scan(rows *sql.Rows) error {
+	return scanInto(rows.Scan, clientProjectFields(s.dest))
+}
+
+// clientReports totals the projects, adjusting entries, invoices and
+// activity of the given clients, matching them by name regardless of case;
+// detail keeps each client's projects and invoices in its report
+func (db *DB) clientReports(clients []models.Client, detail bool) ([]models.ClientReport, error) {
+	reports := make([]models.ClientReport, len(clients))
+	index := map[string]int{}
+	for i, c := range clients {
+		reports[i].Client = c
+		index[strings.ToLower(c.Name)] = i
+	}
+	find := func(name string) *models.ClientReport {
+		if i, ok := index[strings.ToLower(name)]; ok {
+			return &reports[i]
+		}
+		return nil
+	}
+
+	statuses, err := db.ListStatuses()
+	if err != nil {
+		return nil, err
+	}
+	open := map[models.ProjectStatus]bool{}
+	for _, s := range statuses {
+		open[s.Key] = !s.Terminal
+	}
+
+	rows, err := db.Query(qClientProjects)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	projects, err := scanAll(rows,
+		func() *models.ClientProject { return &models.ClientProject{} },
+		func(p *models.ClientProject) scanner { return clientProjectScanner{p} })
+	if err != nil {
+		return nil, err
+	}
+	paidBy := map[int64]*models.ClientReport{} // paid projects' clients, for their adjusting entries
+	for _, p := range projects {
+		r := find(p.Client)
+		if r == nil {
+			continue
+		}
+		r.ProjectCount++
+		r.Hours += p.Hours
+		switch {
+		case p.Status == models.StatusPaid:
+			r.PaidCount++
+			r.LifetimeCents += p.RevenueCents
+			r.PaidHours += p.Hours
+			if at := p.PaymentTime(); r.LastPaidAt == nil || at.After(*r.LastPaidAt) {
+				r.LastPaidAt = &at
+			}
+			paidBy[p.ID] = r
+		case open[p.Status]:
+			r.OpenCents += p.RevenueCents
+		}
+		if detail {
+			r.Projects = append(r.Projects, p)
+		}
+	}
+
+	adjustments, err := db.queryAdjustments(qAdjustmentsAll)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range adjustments {
+		if r := paidBy[a.ProjectID]; r != nil {
+			r.LifetimeCents += a.RevenueCents
+		}
+	}
+
+	invoices, err := db.ListInvoices("")
+	if err != nil {
+		return nil, err
+	}
+	for _, inv := range invoices {
+		r := find(inv.Client)
+		if r == nil {
+			continue
+		}
+		if inv.Status == models.InvoiceIssued {
+			r.UnpaidInvoices++
+			if inv.Currency == "" {
+				r.UnpaidCents += inv.TotalCents()
+			}
+		}
+		if detail {
+			r.Invoices = append(r.Invoices, inv)
+		}
+	}
+
+	rows, err = db.Query(qClientLastActivity)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var at time.Time
+		if err := rows.Scan(&name, &at); err != nil {
+			return nil, err
+		}
+		if r := find(name); r != nil && (r.LastActivityAt == nil || at.After(*r.LastActivityAt)) {
+			r.LastActivityAt = &at
+		}
+	}
+	return reports, rows.Err()
+}
@@ -0,0 +1,63 @@
+// store/comments.go - Project comment threads
+package store
+
+import (
+	"database/sql"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// commentScanner for DRY row scanning
+type commentScanner struct {
+	dest *models.Comment
+}
+
+// commentFields lists a comment's selected columns in scan order
+func commentFields(c *models.Comment) []field {
+	return []field{
+		{"id", &c.ID}, {"project_id", &c.ProjectID}, {"author_id", null(&c.AuthorID)}, {"author", &c.Author},
+		{"body", &c.Body}, {"created_at", &c.CreatedAt},
+	}
+}
+
+func (s commentScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, commentFields(s.dest))
+}
+
+func (s commentScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// ListComments returns a project's comments, oldest first
+func (db *DB) ListComments(projectID int64) ([]models.Comment, error) {
+	rows, err := db.Query(qCommentsByProject, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Comment { return &models.Comment{} },
+		func(c *models.Comment) scanner { return commentScanner{c} })
+}
+
+// GetComment returns a comment by ID, or nil if it doesn't exist
+func (db *DB) GetComment(id int64) (*models.Comment, error) {
+	c := &models.Comment{}
+	err := commentScanner{c}.scan(db.QueryRow(qCommentByID, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return c, err
+}
+
+// CreateComment adds a comment to a project's thread
+func (db *DB) CreateComment(c *models.Comment) error {
+	return db.QueryRow(qCommentInsert, c.ProjectID, c.AuthorID, c.Author, c.Body).Scan(&c.ID, &c.CreatedAt)
+}
+
+// DeleteComment removes a comment
+func (db *DB) DeleteComment(id int64) error {
+	_, err := db.Exec(qCommentDelete, id)
+	return err
+}
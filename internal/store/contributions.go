@@ -1,19 +1,52 @@
-// store/contributions.go - Contribution-related database operations
+// store/contributions.go - Contributions and the dated time entries they sum
 package store
 
 import (
 	"database/sql"
+	"math"
+	"strconv"
+	"time"
 
 	"github.com/noor-latif/fulldash/internal/models"
 )
 
+// dateLayout is the SQLite date format used for range filters
+const dateLayout = "2006-01-02"
+
 // contributionScanner for DRY row scanning
 type contributionScanner struct {
 	dest *models.Contribution
 }
 
+// contributionFields lists a contribution's selected columns in scan order
+func contributionFields(c *models.Contribution) []field {
+	return []field{
+		{"id", &c.ID}, {"project_id", &c.ProjectID}, {"person_id", &c.PersonID}, {"hours", &c.Hours},
+		{"notes", &c.Notes}, {"updated_at", null(&c.UpdatedAt)},
+	}
+}
+
 func (s contributionScanner) Scan(rows *sql.Rows) error {
-	return rows.Scan(&s.dest.ID, &s.dest.ProjectID, &s.dest.Owner, &s.dest.Hours, &s.dest.Notes)
+	return scanInto(rows.Scan, contributionFields(s.dest))
+}
+
+// timeEntryScanner for DRY row scanning
+type timeEntryScanner struct {
+	dest *models.TimeEntry
+}
+
+// timeEntryFields lists a time entry's selected columns (time_entries t
+// joined with projects p and people pe) in scan order
+func timeEntryFields(e *models.TimeEntry) []field {
+	return []field{
+		{"t.id", &e.ID}, {"t.project_id", &e.ProjectID}, {"p.client", &e.Client}, {"t.person_id", &e.PersonID},
+		{"COALESCE(pe.name, '')", &e.Person},
+		{"t.worked_on", &e.Date}, {"t.hours", &e.Hours}, {"t.note", &e.Notes},
+	}
+}
+
+func (s timeEntryScanner) Scan(rows *sql.Rows) error {
+	return scanInto(rows.Scan, timeEntryFields(s.dest))
 }
 
 // GetContributions retrieves all contributions for a project
@@ -24,20 +57,157 @@ func (db *DB) GetContributions(projectID int64) ([]models.Contribution, error) {
 	}
 	defer rows.Close()
 
-	return scanAll(rows, 
+	return scanAll(rows,
 		func() *models.Contribution { return &models.Contribution{} },
 		func(c *models.Contribution) scanner { return contributionScanner{c} })
 }
 
-// SetContribution creates or updates a contribution (upsert)
+// SetContribution sets a person's total hours on a project. Hours are kept
+// as time entries, so a changed total is logged as an entry for the
+// difference, dated today. Hours on a project paid in a locked month can't
+// change.
 func (db *DB) SetContribution(c *models.Contribution) error {
-	res, err := db.Exec(qContributionUpsert, c.ProjectID, c.Owner, c.Hours, c.Notes)
-	if err != nil {
+	note := c.Notes
+	if note == "" {
+		note = "Total set to " + strconv.FormatFloat(c.Hours, 'f', -1, 64) + " h"
+	}
+	return db.writeHours(c.ProjectID, []int64{c.PersonID}, func(tx *DB) error {
+		var logged float64
+		if err := tx.QueryRow(qTimeEntryHours, c.ProjectID, c.PersonID).Scan(&logged); err != nil {
+			return err
+		}
+		diff := math.Round((c.Hours-logged)*100) / 100
+		if diff == 0 {
+			return nil
+		}
+		if err := tx.guardProject(c.ProjectID, nil); err != nil {
+			return err
+		}
+		e := &models.TimeEntry{ProjectID: c.ProjectID, PersonID: c.PersonID, Date: time.Now(), Hours: diff, Notes: note}
+		return tx.QueryRow(qTimeEntryInsert, e.ProjectID, e.PersonID, e.Date.Format(dateLayout), e.Hours, e.Notes).Scan(&e.ID)
+	})
+}
+
+// GetTimeEntry fetches a time entry by ID
+func (db *DB) GetTimeEntry(id int64) (*models.TimeEntry, error) {
+	e := &models.TimeEntry{}
+	err := scanInto(db.QueryRow(qTimeEntryByID, id).Scan, timeEntryFields(e))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
+
+// CreateTimeEntry logs hours, unless the project was paid in a locked month
+func (db *DB) CreateTimeEntry(e *models.TimeEntry) error {
+	return db.writeHours(e.ProjectID, []int64{e.PersonID}, func(tx *DB) error {
+		if err := tx.guardProject(e.ProjectID, nil); err != nil {
+			return err
+		}
+		return tx.QueryRow(qTimeEntryInsert, e.ProjectID, e.PersonID, e.Date.Format(dateLayout), e.Hours, e.Notes).Scan(&e.ID)
+	})
+}
+
+// UpdateTimeEntry saves an edited entry; the person may change, the project
+// can't
+func (db *DB) UpdateTimeEntry(e *models.TimeEntry) error {
+	stored, err := db.GetTimeEntry(e.ID)
+	if err != nil || stored == nil {
 		return err
 	}
-	if c.ID == 0 {
-		id, _ := res.LastInsertId()
-		c.ID = id
+	return db.writeHours(stored.ProjectID, []int64{stored.PersonID, e.PersonID}, func(tx *DB) error {
+		if err := tx.guardProject(stored.ProjectID, nil); err != nil {
+			return err
+		}
+		_, err := tx.Exec(qTimeEntryUpdate, e.PersonID, e.Date.Format(dateLayout), e.Hours, e.Notes, e.ID)
+		return err
+	})
+}
+
+// DeleteTimeEntry removes an entry, unless its project was paid in a locked
+// month
+func (db *DB) DeleteTimeEntry(id int64) error {
+	stored, err := db.GetTimeEntry(id)
+	if err != nil || stored == nil {
+		return err
+	}
+	return db.writeHours(stored.ProjectID, []int64{stored.PersonID}, func(tx *DB) error {
+		if err := tx.guardProject(stored.ProjectID, nil); err != nil {
+			return err
+		}
+		_, err := tx.Exec(qTimeEntryDelete, id)
+		return err
+	})
+}
+
+// SetDayHours sets a person's hours on a project for one day, as the
+// timesheet edits them: that day's entries are replaced by one with the new
+// total, keeping their notes, and zero removes them
+func (db *DB) SetDayHours(projectID, personID int64, day time.Time, hours float64) error {
+	date := day.Format(dateLayout)
+	return db.writeHours(projectID, []int64{personID}, func(tx *DB) error {
+		if err := tx.guardProject(projectID, nil); err != nil {
+			return err
+		}
+		var notes string
+		if err := tx.QueryRow(qTimeEntryDayNotes, projectID, personID, date).Scan(&notes); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(qTimeEntryDeleteDay, projectID, personID, date); err != nil {
+			return err
+		}
+		if hours == 0 {
+			return nil
+		}
+		var id int64
+		return tx.QueryRow(qTimeEntryInsert, projectID, personID, date, hours, notes).Scan(&id)
+	})
+}
+
+// writeHours runs a write to a project's time entries (and its locked
+// period check) and brings the contributions of the people it touched back
+// in step with them, in one transaction
+func (db *DB) writeHours(projectID int64, personIDs []int64, write func(tx *DB) error) error {
+	return db.Tx(func(tx *DB) error {
+		if err := write(tx); err != nil {
+			return err
+		}
+		for _, id := range personIDs {
+			if _, err := tx.Exec(qContributionSync, projectID, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListTimeEntries returns logged hours matching the filter, newest first
+func (db *DB) ListTimeEntries(f models.TimeEntryFilter) ([]models.TimeEntry, error) {
+	query, args := qTimeEntriesBase, []any{}
+	if f.ProjectID != 0 {
+		query += ` AND t.project_id = ?`
+		args = append(args, f.ProjectID)
+	}
+	if f.PersonID != 0 {
+		query += ` AND t.person_id = ?`
+		args = append(args, f.PersonID)
+	}
+	if !f.From.IsZero() {
+		query += ` AND t.worked_on >= ?`
+		args = append(args, f.From.Format(dateLayout))
+	}
+	if !f.To.IsZero() {
+		query += ` AND t.worked_on <= ?`
+		args = append(args, f.To.Format(dateLayout))
+	}
+
+	rows, err := db.Query(query+qTimeEntriesOrder, args...)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.TimeEntry { return &models.TimeEntry{} },
+		func(e *models.TimeEntry) scanner { return timeEntryScanner{e} })
 }
@@ -2,13 +2,21 @@
 package store
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/noor-latif/fulldash/internal/models"
-	_ "modernc.org/sqlite"
+	"modernc.org/sqlite"
 )
 
 // Compile-time check that DB implements Store
@@ -16,29 +24,203 @@ var _ Store = (*DB)(nil)
 
 type DB struct {
 	*sql.DB
+	queries *queryLog // nil unless Options.QueryLog is on
+	tx      *sql.Tx   // set on the DB Tx hands its function; every query joins it
 }
 
-// New creates/opens database and runs migrations
+// Tx runs fn in one transaction, committed when fn returns nil and rolled
+// back otherwise. Every query on the DB fn gets runs in the transaction,
+// and a Tx inside it joins it. Write transactions begin IMMEDIATE (see
+// dsn), so nothing fn has read can change before it writes.
+func (db *DB) Tx(fn func(tx *DB) error) error {
+	if db.tx != nil {
+		return fn(db)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&DB{DB: db.DB, queries: db.queries, tx: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Exec runs a statement, in the transaction inside Tx
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	if db.tx != nil {
+		return db.tx.Exec(query, args...)
+	}
+	return db.DB.Exec(query, args...)
+}
+
+// Query runs a query, in the transaction inside Tx
+func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	if db.tx != nil {
+		return db.tx.Query(query, args...)
+	}
+	return db.DB.Query(query, args...)
+}
+
+// QueryRow runs a single-row query, in the transaction inside Tx
+func (db *DB) QueryRow(query string, args ...any) *sql.Row {
+	if db.tx != nil {
+		return db.tx.QueryRow(query, args...)
+	}
+	return db.DB.QueryRow(query, args...)
+}
+
+// New creates/opens database with the default Options and runs migrations
 func New(dbPath string) (*DB, error) {
+	return Open(dbPath, Options{})
+}
+
+// Connection defaults, for the Options left zero
+const (
+	DefaultJournalMode = "wal"
+	DefaultSynchronous = "normal"
+	DefaultBusyTimeout = 5 * time.Second
+)
+
+// journalModes and synchronousModes are the values SQLite accepts
+var (
+	journalModes     = []string{"wal", "delete", "truncate", "persist", "memory", "off"}
+	synchronousModes = []string{"off", "normal", "full", "extra"}
+)
+
+// Options adjust how the database is opened. The zero value is WAL mode,
+// where readers don't block the writer, with synchronous=NORMAL and a 5
+// second busy_timeout, which is what concurrent requests need.
+type Options struct {
+	JournalMode    string        // journal_mode; "" for wal, which replication also needs
+	Synchronous    string        // synchronous; "" for normal
+	BusyTimeout    time.Duration // busy_timeout; 0 for 5s
+	MaxOpenConns   int           // connections open at once; 0 for no limit
+	QueryOnly      bool          // refuse writes on every connection (a read-only replica)
+	SkipMigrations bool          // leave the schema to the primary; fail if it is older than this build's
+	QueryLog       QueryLog      // log statements, flag slow ones and count them
+}
+
+// Validate checks the pragma values against what SQLite accepts
+func (o Options) Validate() error {
+	if m := o.journalMode(); !slices.Contains(journalModes, m) {
+		return fmt.Errorf("unknown journal mode %q (%s)", m, strings.Join(journalModes, ", "))
+	}
+	if m := o.synchronous(); !slices.Contains(synchronousModes, m) {
+		return fmt.Errorf("unknown synchronous %q (%s)", m, strings.Join(synchronousModes, ", "))
+	}
+	if o.BusyTimeout < 0 || o.MaxOpenConns < 0 {
+		return errors.New("busy timeout and max open connections can't be negative")
+	}
+	return nil
+}
+
+func (o Options) journalMode() string {
+	if o.JournalMode == "" {
+		return DefaultJournalMode
+	}
+	return strings.ToLower(o.JournalMode)
+}
+
+func (o Options) synchronous() string {
+	if o.Synchronous == "" {
+		return DefaultSynchronous
+	}
+	return strings.ToLower(o.Synchronous)
+}
+
+func (o Options) busyTimeout() time.Duration {
+	if o.BusyTimeout == 0 {
+		return DefaultBusyTimeout
+	}
+	return o.BusyTimeout
+}
+
+// Open opens the database with o, running migrations unless skipped
+func Open(dbPath string, o Options) (*DB, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return open(dbPath, &sqliteConnector{dsn: o.dsn(dbPath)}, o)
+}
+
+// sqliteConnector opens connections straight from the sqlite driver; the
+// query log and chaos mode wrap it
+type sqliteConnector struct {
+	dsn string
+	drv sqlite.Driver
+}
+
+func (c *sqliteConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.drv.Open(c.dsn)
+}
+
+func (c *sqliteConnector) Driver() driver.Driver {
+	return &c.drv
+}
+
+// dsn adds the connection pragmas to a database path, so every connection
+// in the pool gets them. busy_timeout waits out writes from other
+// connections and instances instead of failing with SQLITE_BUSY. In WAL
+// mode synchronous=NORMAL syncs at checkpoints only, which can lose the
+// last commits on power loss but never corrupts. Write transactions take
+// the write lock as they begin (_txlock=immediate): a check read inside
+// one, such as a locked period's, holds until its write commits.
+func (o Options) dsn(dbPath string) string {
+	dsn := dbPath + "?_pragma=foreign_keys(1)" +
+		"&_pragma=busy_timeout(" + strconv.FormatInt(o.busyTimeout().Milliseconds(), 10) + ")" +
+		"&_pragma=journal_mode(" + o.journalMode() + ")" +
+		"&_pragma=synchronous(" + o.synchronous() + ")"
+	if o.QueryOnly {
+		dsn += "&_pragma=query_only(1)"
+	} else {
+		dsn += "&_txlock=immediate"
+	}
+	return dsn
+}
+
+// open prepares the database directory, connects through conn (and the
+// query log when on) and runs migrations
+func open(dbPath string, conn driver.Connector, o Options) (*DB, error) {
 	dir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("create dir: %w", err)
 	}
 
-	sqlDB, err := sql.Open("sqlite", dbPath+"?_pragma=foreign_keys(1)")
-	if err != nil {
-		return nil, fmt.Errorf("open db: %w", err)
+	db := &DB{queries: newQueryLog(conn, o.QueryLog)}
+	if db.queries != nil {
+		conn = db.queries
 	}
-
-	db := &DB{sqlDB}
-	if err := db.migrate(); err != nil {
+	db.DB = sql.OpenDB(conn)
+	db.SetMaxOpenConns(o.MaxOpenConns)
+	// journal_mode is set as each connection opens; make sure it took (WAL
+	// can't be entered while another process has the file in another mode)
+	if mode, err := db.JournalMode(); err != nil {
+		return nil, fmt.Errorf("journal mode: %w", err)
+	} else if mode != o.journalMode() {
+		return nil, fmt.Errorf("journal mode is %s, not %s", mode, o.journalMode())
+	}
+	if o.SkipMigrations {
+		v, err := db.SchemaVersion()
+		if err != nil {
+			return nil, fmt.Errorf("schema version: %w", err)
+		}
+		if v < len(migrations) {
+			return nil, fmt.Errorf("schema is at version %d and this build needs %d: upgrade the primary first", v, len(migrations))
+		}
+		return db, nil
+	}
+	if err := db.migrate(len(migrations)); err != nil {
 		return nil, fmt.Errorf("migrate: %w", err)
 	}
 
 	return db, nil
 }
 
-func (db *DB) migrate() error {
+// migrate creates the base schema and applies the migrations up to version
+func (db *DB) migrate(version int) error {
 	schema := `
 	CREATE TABLE IF NOT EXISTS projects (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -60,11 +242,131 @@ func (db *DB) migrate() error {
 		UNIQUE(project_id, owner)
 	);
 
+	CREATE TABLE IF NOT EXISTS expenses (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+		payer TEXT NOT NULL CHECK(payer IN ('noor', 'ahmad')),
+		description TEXT NOT NULL,
+		amount_cents INTEGER NOT NULL CHECK(amount_cents >= 0),
+		spent_on DATE NOT NULL,
+		receipt_path TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS travel_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_id INTEGER REFERENCES projects(id) ON DELETE SET NULL,
+		expense_id INTEGER REFERENCES expenses(id) ON DELETE SET NULL,
+		owner TEXT NOT NULL CHECK(owner IN ('noor', 'ahmad')),
+		date DATE NOT NULL,
+		client TEXT NOT NULL,
+		km REAL NOT NULL CHECK(km > 0),
+		purpose TEXT,
+		rate_cents INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_id INTEGER NOT NULL,
+		client TEXT NOT NULL,
+		action TEXT NOT NULL,
+		summary TEXT,
+		actor TEXT NOT NULL DEFAULT '',
+		source TEXT NOT NULL DEFAULT '',
+		changes TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS integrity_checks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		ok INTEGER NOT NULL,
+		details TEXT,
+		checked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		email TEXT NOT NULL UNIQUE COLLATE NOCASE,
+		name TEXT NOT NULL,
+		password_hash TEXT NOT NULL,
+		role TEXT NOT NULL DEFAULT 'viewer' CHECK(role IN ('owner', 'partner', 'viewer')),
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		scope TEXT NOT NULL CHECK(scope IN ('read', 'write', 'admin')),
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_used_at DATETIME,
+		revoked_at DATETIME
+	);
+
+	CREATE TABLE IF NOT EXISTS dr_tests (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		backup_file TEXT NOT NULL,
+		ok INTEGER NOT NULL,
+		details TEXT,
+		checked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS automation_rules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		trigger TEXT NOT NULL,
+		action TEXT NOT NULL,
+		param TEXT NOT NULL DEFAULT '',
+		delay_days INTEGER NOT NULL DEFAULT 0 CHECK(delay_days >= 0),
+		enabled INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS automation_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		rule_id INTEGER NOT NULL REFERENCES automation_rules(id) ON DELETE CASCADE,
+		project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+		run_at DATETIME NOT NULL,
+		done_at DATETIME,
+		error TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS notification_prefs (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		kind TEXT NOT NULL,
+		mode TEXT NOT NULL CHECK(mode IN ('instant', 'digest', 'off')),
+		PRIMARY KEY (user_id, kind)
+	);
+
+	CREATE TABLE IF NOT EXISTS notifications (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		kind TEXT NOT NULL,
+		project_id INTEGER,
+		body TEXT NOT NULL,
+		queued INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
 	CREATE INDEX IF NOT EXISTS idx_projects_status ON projects(status);
 	CREATE INDEX IF NOT EXISTS idx_projects_stripe ON projects(stripe_payment_id);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_project ON audit_log(project_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_expenses_project ON expenses(project_id);
+	CREATE INDEX IF NOT EXISTS idx_travel_date ON travel_log(date);
+	CREATE INDEX IF NOT EXISTS idx_automation_runs_due ON automation_runs(done_at, run_at);
+	CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, queued, created_at);
 	`
-	_, err := db.Exec(schema)
-	return err
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	return db.applyMigrations(version)
 }
 
 // Project Scanner - DRY scan helper
@@ -72,20 +374,48 @@ type projectScanner struct {
 	dest *models.Project
 }
 
+// projectFields lists a project's selected columns in scan order
+func projectFields(p *models.Project) []field {
+	return []field{
+		{"id", &p.ID}, {"client", &p.Client}, {"description", &p.Description}, {"revenue_cents", &p.RevenueCents},
+		{"status", &p.Status}, {"secured_by", jsonText{&p.SecuredBy}}, {"stripe_payment_id", &p.StripePaymentID},
+		{"created_at", &p.CreatedAt}, {"paid_at", opt(&p.PaidAt)}, {"archived_at", opt(&p.ArchivedAt)},
+		{"pending_since", opt(&p.PendingSince)},
+		{"currency", &p.Currency}, {"original_cents", &p.OriginalCents}, {"split_override", jsonText{&p.SplitOverride}},
+		{"vat_percent", &p.VATPercent}, {"vat_inclusive", &p.VATInclusive},
+		{"probability", opt(&p.Probability)}, {"expected_on", opt(&p.ExpectedOn)}, {"lost_reason", &p.LostReason},
+		{"source", &p.Source}, {"estimated_hours", &p.EstimatedHours}, {"hour_estimates", jsonText{&p.HourEstimates}},
+		{"payment_days", &p.PaymentDays}, {"budget_hours", &p.BudgetHours}, {"budget_cents", &p.BudgetCents},
+		{"hourly_cents", &p.HourlyCents}, {"budget_warned", &p.BudgetWarned},
+		{"(SELECT COALESCE(SUM(hours), 0) FROM " + contributionTable + " WHERE project_id = " + projectTable + ".id)", &p.LoggedHours},
+		{"(SELECT json_group_array(json_object('id', id, 'project_id', project_id, 'title', title, " +
+			"'done', json(IIF(done, 'true', 'false')), 'assignee_id', assignee_id, 'position', position) ORDER BY position, id) " +
+			"FROM " + taskTable + " WHERE project_id = " + projectTable + ".id)", jsonText{&p.Tasks}},
+	}
+}
+
+func (s projectScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, projectFields(s.dest))
+}
+
 func (s projectScanner) Scan(rows *sql.Rows) error {
-	return rows.Scan(&s.dest.ID, &s.dest.Client, &s.dest.Description, &s.dest.Revenue, 
-		&s.dest.Status, &s.dest.SecuredBy, &s.dest.StripePaymentID, &s.dest.CreatedAt)
+	return s.scan(rows.Scan)
 }
 
 func (s projectScanner) ScanRow(row *sql.Row) error {
-	return row.Scan(&s.dest.ID, &s.dest.Client, &s.dest.Description, &s.dest.Revenue, 
-		&s.dest.Status, &s.dest.SecuredBy, &s.dest.StripePaymentID, &s.dest.CreatedAt)
+	return s.scan(row.Scan)
 }
 
-// CreateProject inserts a new project
+// CreateProject inserts a new project, adding its client if it's new
 func (db *DB) CreateProject(p *models.Project) error {
-	return db.QueryRow(qProjectInsert, p.Client, p.Description, p.Revenue, p.Status, 
-		p.SecuredBy, p.StripePaymentID).Scan(&p.ID, &p.CreatedAt)
+	err := db.QueryRow(qProjectInsert, p.Client, p.Description, p.RevenueCents, p.Status, jsonArg(p.SecuredBy),
+		p.StripePaymentID, p.Currency, p.OriginalCents, optJSONArg(p.SplitOverride), p.VATPercent, p.VATInclusive,
+		p.Probability, optDate(p.ExpectedOn), p.LostReason, p.Source, p.EstimatedHours, optJSONArg(p.HourEstimates),
+		p.PaymentDays, p.BudgetHours, p.BudgetCents, p.HourlyCents, p.Status == models.StatusPaid).Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return db.EnsureClient(p.Client)
 }
 
 // GetProject fetches a project by ID
@@ -108,58 +438,289 @@ func (db *DB) GetProjectByStripeID(stripeID string) (*models.Project, error) {
 	return p, err
 }
 
-// UpdateProject updates all project fields
+// UpdateProject updates all project fields, adding the client if it's new;
+// paid_at is stamped on the move to paid and cleared when a project leaves
+// it, pending_since on a move to any other status. Only the client and
+// description of a project paid in a locked month can change (its VAT rate
+// is part of the period's declaration too).
 func (db *DB) UpdateProject(p *models.Project) error {
-	_, err := db.Exec(qProjectUpdate, p.Client, p.Description, p.Revenue, p.Status, 
-		p.SecuredBy, p.StripePaymentID, p.ID)
+	return db.Tx(func(tx *DB) error {
+		err := tx.guardProject(p.ID, func(stored *models.Project) (bool, error) {
+			return paymentChanged(stored, p.Status, p.RevenueCents, p.StripePaymentID) || !slices.Equal(stored.SecuredBy, p.SecuredBy) ||
+				!maps.Equal(stored.SplitOverride, p.SplitOverride) || stored.VATPercent != p.VATPercent, nil
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(qProjectUpdate, p.Client, p.Description, p.RevenueCents, p.Status, jsonArg(p.SecuredBy),
+			p.StripePaymentID, p.Currency, p.OriginalCents, optJSONArg(p.SplitOverride), p.VATPercent, p.VATInclusive,
+			p.Probability, optDate(p.ExpectedOn), p.LostReason, p.Source, p.EstimatedHours, optJSONArg(p.HourEstimates),
+			p.PaymentDays, p.BudgetHours, p.BudgetCents, p.HourlyCents, p.Status == models.StatusPaid, p.Status, p.Status == models.StatusPaid, p.ID)
+		if err != nil {
+			return err
+		}
+		return tx.EnsureClient(p.Client)
+	})
+}
+
+// SetBudgetWarned records the highest budget threshold a project's burn has
+// reached; it is bookkeeping, so locked months don't guard it
+func (db *DB) SetBudgetWarned(id int64, percent int) error {
+	_, err := db.Exec(qProjectSetBudgetWarned, percent, id)
 	return err
 }
 
 // UpdateProjectStatus updates status and payment info (used by webhooks)
-func (db *DB) UpdateProjectStatus(id int64, status models.ProjectStatus, revenue float64, stripeID string) error {
-	_, err := db.Exec(qProjectUpdateStatus, status, revenue, stripeID, id)
-	return err
+func (db *DB) UpdateProjectStatus(id int64, status models.ProjectStatus, revenueCents int64, stripeID string) error {
+	return db.Tx(func(tx *DB) error {
+		err := tx.guardProject(id, func(stored *models.Project) (bool, error) {
+			return paymentChanged(stored, status, revenueCents, stripeID), nil
+		})
+		if err != nil {
+			return err
+		}
+		paid := status == models.StatusPaid
+		_, err = tx.Exec(qProjectUpdateStatus, status, revenueCents, stripeID, status == models.StatusLost, paid, status, paid, id)
+		return err
+	})
 }
 
-// DeleteProject removes a project (cascades to contributions)
+// SetPaidAt backdates a paid project's payment to when it was received, as
+// for payments recorded after the fact. Neither the month it was stamped in
+// nor at's may be locked.
+func (db *DB) SetPaidAt(id int64, at time.Time) error {
+	return db.Tx(func(tx *DB) error {
+		if err := tx.guardProject(id, nil); err != nil {
+			return err
+		}
+		lock, err := tx.PeriodLockAt(at)
+		if err != nil {
+			return err
+		}
+		if lock != nil {
+			p, err := tx.GetProject(id)
+			if err != nil || p == nil {
+				return err
+			}
+			return &models.PeriodLockedError{Lock: *lock, Client: p.Client}
+		}
+		// paid_at is otherwise SQLite's CURRENT_TIMESTAMP: UTC, to the second
+		_, err = tx.Exec(qProjectSetPaidAt, at.UTC().Format(time.DateTime), id)
+		return err
+	})
+}
+
+// StripePaymentIDs returns the Stripe payments recorded on projects and
+// milestones
+func (db *DB) StripePaymentIDs() (map[string]bool, error) {
+	rows, err := db.Query(qStripePaymentIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// DeleteProject removes a project (cascades to contributions), unless it
+// was paid in a locked month
 func (db *DB) DeleteProject(id int64) error {
-	_, err := db.Exec(qProjectDelete, id)
-	return err
+	return db.Tx(func(tx *DB) error {
+		if err := tx.guardProject(id, nil); err != nil {
+			return err
+		}
+		_, err := tx.Exec(qProjectDelete, id)
+		return err
+	})
 }
 
-// ListProjects returns all projects, optionally filtered by search
-func (db *DB) ListProjects(search string) ([]models.Project, error) {
-	var rows *sql.Rows
-	var err error
-	
-	if search != "" {
-		like := "%" + search + "%"
-		rows, err = db.Query(qProjectsSearch, like, like)
-	} else {
-		rows, err = db.Query(qProjectsAll)
+// paymentChanged reports whether a write changes the stored project's status
+// or payment
+func paymentChanged(stored *models.Project, status models.ProjectStatus, revenueCents int64, stripeID string) bool {
+	return stored.Status != status || stored.RevenueCents != revenueCents || stored.StripePaymentID != stripeID
+}
+
+// projectSortColumns maps sort keys to ORDER BY expressions; the direction
+// applies to the last term, so due dates keep nulls last both ways
+var projectSortColumns = map[models.ProjectSort]string{
+	models.SortCreated: "created_at",
+	models.SortRevenue: "revenue_cents",
+	models.SortClient:  "client COLLATE NOCASE",
+	models.SortDue:     "expected_on IS NULL, expected_on",
+}
+
+// ListProjects returns the projects on the board (not archived, unless the
+// filter asks for them) matching the filter, in its order
+func (db *DB) ListProjects(f models.ProjectFilter) ([]models.Project, error) {
+	where, args := projectWhere(f)
+
+	order, dir := "created_at", " DESC"
+	if col, ok := projectSortColumns[f.Sort]; ok {
+		order = col
+		if !f.Desc {
+			dir = " ASC"
+		}
 	}
-	
+
+	limit, limitArgs := limitClause(f.Page)
+	rows, err := db.Query(qProjectsBase+where+` ORDER BY `+order+dir+`, id`+dir+limit, append(args, limitArgs...)...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return scanAll(rows, func() *models.Project { return &models.Project{} }, 
+	return scanAll(rows, func() *models.Project { return &models.Project{} },
 		func(p *models.Project) scanner { return projectScanner{p} })
 }
 
-// ListProjectsByStatus returns projects filtered by status
+// ProjectTotals counts and sums the projects matching the filter in each
+// status, ignoring its page
+func (db *DB) ProjectTotals(f models.ProjectFilter) (map[models.ProjectStatus]models.ArchiveTotals, error) {
+	where, args := projectWhere(f)
+	rows, err := db.Query(qProjectsTotals+where+` GROUP BY status`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := map[models.ProjectStatus]models.ArchiveTotals{}
+	for rows.Next() {
+		var status models.ProjectStatus
+		var t models.ArchiveTotals
+		if err := rows.Scan(&status, &t.Count, &t.RevenueCents); err != nil {
+			return nil, err
+		}
+		totals[status] = t
+	}
+	return totals, rows.Err()
+}
+
+// projectWhere renders the filter's conditions as a WHERE clause
+func projectWhere(f models.ProjectFilter) (string, []any) {
+	query, args := ` WHERE archived_at IS NULL`, []any{}
+	if f.Archived {
+		query = ` WHERE 1 = 1`
+	}
+	if f.Search != "" {
+		like := "%" + f.Search + "%"
+		query += ` AND (client LIKE ? OR description LIKE ?)`
+		args = append(args, like, like)
+	}
+	if f.Status != "" {
+		query += ` AND status = ?`
+		args = append(args, f.Status)
+	}
+	if f.SecuredBy != 0 {
+		query += ` AND ` + qSecuredBy
+		args = append(args, f.SecuredBy)
+	}
+	if f.Client != "" {
+		query += ` AND client = ? COLLATE NOCASE`
+		args = append(args, f.Client)
+	}
+	if !f.From.IsZero() {
+		query += ` AND date(created_at) >= ?`
+		args = append(args, f.From.Format(dateLayout))
+	}
+	if !f.To.IsZero() {
+		query += ` AND date(created_at) <= ?`
+		args = append(args, f.To.Format(dateLayout))
+	}
+	if f.MinRevenueCents != nil {
+		query += ` AND revenue_cents >= ?`
+		args = append(args, *f.MinRevenueCents)
+	}
+	if f.MaxRevenueCents != nil {
+		query += ` AND revenue_cents <= ?`
+		args = append(args, *f.MaxRevenueCents)
+	}
+	return query, args
+}
+
+// ListProjectsByStatus returns projects filtered by status, archived included
 func (db *DB) ListProjectsByStatus(status models.ProjectStatus) ([]models.Project, error) {
 	rows, err := db.Query(qProjectsByStatus, status)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	
-	return scanAll(rows, func() *models.Project { return &models.Project{} }, 
+
+	return scanAll(rows, func() *models.Project { return &models.Project{} },
+		func(p *models.Project) scanner { return projectScanner{p} })
+}
+
+// SetProjectArchived archives a project (keeping the first archived_at) or
+// puts it back on the board
+func (db *DB) SetProjectArchived(id int64, archived bool) error {
+	_, err := db.Exec(qProjectSetArchived, archived, id)
+	return err
+}
+
+// ListArchivedProjects returns archived projects matching the filter, most
+// recently paid first
+func (db *DB) ListArchivedProjects(f models.ArchiveFilter) ([]models.Project, error) {
+	where, args := archiveWhere(f)
+	limit, limitArgs := limitClause(f.Page)
+	rows, err := db.Query(qProjectsArchivedBase+where+qProjectsArchivedOrder+limit, append(args, limitArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows, func() *models.Project { return &models.Project{} },
 		func(p *models.Project) scanner { return projectScanner{p} })
 }
 
+// ArchiveTotals counts and sums the archived projects matching the filter,
+// ignoring its page
+func (db *DB) ArchiveTotals(f models.ArchiveFilter) (models.ArchiveTotals, error) {
+	var t models.ArchiveTotals
+	where, args := archiveWhere(f)
+	err := db.QueryRow(qProjectsArchivedTotals+where, args...).Scan(&t.Count, &t.RevenueCents)
+	return t, err
+}
+
+// archiveWhere renders the filter's conditions, to follow an archive query's
+// WHERE clause
+func archiveWhere(f models.ArchiveFilter) (string, []any) {
+	var where string
+	var args []any
+	if f.Search != "" {
+		like := "%" + f.Search + "%"
+		where += ` AND (client LIKE ? OR description LIKE ?)`
+		args = append(args, like, like)
+	}
+	if f.Status != "" {
+		where += ` AND status = ?`
+		args = append(args, f.Status)
+	}
+	if f.SecuredBy != 0 {
+		where += ` AND ` + qSecuredBy
+		args = append(args, f.SecuredBy)
+	}
+	if f.Year != 0 {
+		where += ` AND strftime('%Y', COALESCE(paid_at, created_at)) = ?`
+		args = append(args, strconv.Itoa(f.Year))
+	}
+	return where, args
+}
+
+// limitClause renders a page as LIMIT/OFFSET, or nothing when it has no limit
+func limitClause(p models.Page) (string, []any) {
+	if p.Limit <= 0 {
+		return "", nil
+	}
+	return ` LIMIT ? OFFSET ?`, []any{p.Limit, p.Offset}
+}
+
 // Generic scanner interface
 type scanner interface {
 	Scan(rows *sql.Rows) error
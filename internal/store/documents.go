@@ -0,0 +1,92 @@
+// store/documents.go - Contracts and other documents kept for clients and projects
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// documentScanner for DRY row scanning
+type documentScanner struct {
+	dest *models.Document
+}
+
+// documentFields lists a document's selected columns in scan order; the
+// client is named from the client or the project it belongs to
+func documentFields(d *models.Document) []field {
+	return []field{
+		{"id", &d.ID}, {"client_id", null(&d.ClientID)}, {"project_id", null(&d.ProjectID)},
+		{"COALESCE((SELECT name FROM clients WHERE clients.id = documents.client_id), (SELECT client FROM projects WHERE projects.id = documents.project_id), '')", &d.Client},
+		{"kind", &d.Kind}, {"title", &d.Title}, {"version", &d.Version}, {"file_name", &d.FileName}, {"path", &d.Path},
+		{"size_bytes", &d.SizeBytes}, {"signed_on", opt(&d.SignedOn)}, {"expires_on", opt(&d.ExpiresOn)},
+		{"uploaded_by", &d.UploadedBy}, {"reminded_at", opt(&d.RemindedAt)}, {"created_at", &d.CreatedAt},
+	}
+}
+
+func (s documentScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, documentFields(s.dest))
+}
+
+func (s documentScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// ListProjectDocuments returns every version of a project's documents
+func (db *DB) ListProjectDocuments(projectID int64) ([]models.Document, error) {
+	return db.listDocuments(qDocumentsByProject, projectID)
+}
+
+// ListClientDocuments returns every version of a client's documents, its
+// own first, then its projects'
+func (db *DB) ListClientDocuments(clientID int64) ([]models.Document, error) {
+	return db.listDocuments(qDocumentsByClient, clientID, clientID)
+}
+
+// DocumentsExpiring returns the current versions expiring between today and
+// before that haven't been reminded of, soonest first
+func (db *DB) DocumentsExpiring(today, before time.Time) ([]models.Document, error) {
+	return db.listDocuments(qDocumentsExpiring, before.Format(dateLayout), today.Format(dateLayout))
+}
+
+func (db *DB) listDocuments(query string, args ...any) ([]models.Document, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Document { return &models.Document{} },
+		func(d *models.Document) scanner { return documentScanner{d} })
+}
+
+// GetDocument returns a document version by ID, or nil if it doesn't exist
+func (db *DB) GetDocument(id int64) (*models.Document, error) {
+	d := &models.Document{}
+	err := documentScanner{d}.scan(db.QueryRow(qDocumentByID, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return d, err
+}
+
+// CreateDocument stores a document as the next version of its title for
+// its client or project
+func (db *DB) CreateDocument(d *models.Document) error {
+	return db.QueryRow(qDocumentInsert, d.ClientID, d.ProjectID, d.Kind, d.Title, d.FileName, d.Path,
+		d.SizeBytes, optDate(d.SignedOn), optDate(d.ExpiresOn), d.UploadedBy).Scan(&d.ID, &d.Version, &d.CreatedAt)
+}
+
+// MarkDocumentReminded records that a document's expiry reminder was sent
+func (db *DB) MarkDocumentReminded(id int64) error {
+	_, err := db.Exec(qDocumentMarkReminded, id)
+	return err
+}
+
+// DeleteDocument removes a document version; the caller removes its file
+func (db *DB) DeleteDocument(id int64) error {
+	_, err := db.Exec(qDocumentDelete, id)
+	return err
+}
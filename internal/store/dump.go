@@ -0,0 +1,397 @@
+// store/dump.go - Whole-database JSON dumps, for moving between machines
+package store
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// DumpFormat identifies a FullDash dump
+const DumpFormat = "fulldash-dump"
+
+// ErrDumpVersion is returned when a dump's schema version doesn't match
+// the database it is imported into
+var ErrDumpVersion = errors.New("dump schema version mismatch")
+
+// dumpSkipped are left out of dumps: sessions, which an import clears as
+// it replaces the users, job leases and cached values
+var dumpSkipped = map[string]bool{"sessions": true, "leases": true, "cache_entries": true}
+
+// dump is a dump as read: every table's columns and rows. A value is JSON
+// null, a number, a string, or {"blob": "<base64>"}.
+type dump struct {
+	Format        string      `json:"format"`
+	SchemaVersion int         `json:"schema_version"`
+	ExportedAt    time.Time   `json:"exported_at"`
+	Tables        []dumpTable `json:"tables"`
+}
+
+type dumpTable struct {
+	Name    string              `json:"name"`
+	Columns []string            `json:"columns"`
+	Rows    [][]json.RawMessage `json:"rows"`
+}
+
+// Export writes every table but the skipped ones as JSON, with the schema
+// version, a row per line. Values are written as SQLite stores them, so
+// dates keep their exact text.
+func (db *DB) Export(w io.Writer) (*models.DumpSummary, error) {
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	tables, err := db.dumpTables()
+	if err != nil {
+		return nil, err
+	}
+
+	// One read transaction, so the dump is a consistent snapshot
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sum := &models.DumpSummary{SchemaVersion: version, ExportedAt: time.Now().UTC(), Tables: len(tables)}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "{\"format\":%q,\"schema_version\":%d,\"exported_at\":%q,\"tables\":[",
+		DumpFormat, version, sum.ExportedAt.Format(time.RFC3339))
+	for i, t := range tables {
+		columns, err := tableColumns(tx, t)
+		if err != nil {
+			return nil, err
+		}
+		name, _ := json.Marshal(t)
+		cols, _ := json.Marshal(columns)
+		if i > 0 {
+			bw.WriteString(",")
+		}
+		fmt.Fprintf(bw, "\n{\"name\":%s,\"columns\":%s,\"rows\":[", name, cols)
+
+		// Unary + drops the declared type, so the driver hands back the
+		// stored value rather than parsing DATETIME columns as times
+		exprs := make([]string, len(columns))
+		for j, c := range columns {
+			exprs[j] = "+" + quoteIdent(c)
+		}
+		n, err := dumpRows(tx, bw, fmt.Sprintf(`SELECT %s FROM %s`, strings.Join(exprs, ", "), quoteIdent(t)), len(columns))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t, err)
+		}
+		sum.Rows += n
+		bw.WriteString("]}")
+	}
+	bw.WriteString("\n]}\n")
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	return sum, nil
+}
+
+// dumpRows writes the rows of query as JSON arrays and counts them
+func dumpRows(tx *sql.Tx, w *bufio.Writer, query string, columns int) (int, error) {
+	rows, err := tx.Query(query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	values := make([]any, columns)
+	ptrs := make([]any, columns)
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	var n int
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return n, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = map[string]string{"blob": base64.StdEncoding.EncodeToString(b)}
+			}
+		}
+		row, err := json.Marshal(values)
+		if err != nil {
+			return n, err
+		}
+		if n > 0 {
+			w.WriteString(",")
+		}
+		w.WriteString("\n")
+		w.Write(row)
+		n++
+	}
+	return n, rows.Err()
+}
+
+// Import replaces everything in the database with a dump of the same
+// schema version, in one transaction: every table is emptied, sessions
+// included, and the dump's rows inserted with their ids. A dump leaving
+// foreign keys dangling is rolled back.
+func (db *DB) Import(r io.Reader) (*models.DumpSummary, error) {
+	d, err := readDump(r)
+	if err != nil {
+		return nil, err
+	}
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if d.SchemaVersion != version {
+		return nil, fmt.Errorf("%w: the dump is at %d, this database at %d; restore it into a new database instead",
+			ErrDumpVersion, d.SchemaVersion, version)
+	}
+	return db.load(d)
+}
+
+// Restore creates a new database at dbPath from a dump. Its schema is
+// built up to the dump's version, the rows loaded, and then the later
+// migrations run, so a dump from an older FullDash is upgraded as its
+// database would have been.
+func Restore(dbPath string, r io.Reader) (*models.DumpSummary, error) {
+	d, err := readDump(r)
+	if err != nil {
+		return nil, err
+	}
+	if d.SchemaVersion > len(migrations) {
+		return nil, fmt.Errorf("%w: the dump is at %d, newer than this FullDash (%d)", ErrDumpVersion, d.SchemaVersion, len(migrations))
+	}
+	if _, err := os.Stat(dbPath); err == nil {
+		return nil, fmt.Errorf("%s already exists", dbPath)
+	}
+
+	db := &DB{DB: sql.OpenDB(&sqliteConnector{dsn: Options{}.dsn(dbPath)})}
+	defer db.Close()
+	if err := db.migrate(d.SchemaVersion); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	sum, err := db.load(d)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.applyMigrations(len(migrations)); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return sum, nil
+}
+
+// readDump decodes and checks a dump
+func readDump(r io.Reader) (*dump, error) {
+	var d dump
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return nil, fmt.Errorf("read dump: %w", err)
+	}
+	if d.Format != DumpFormat {
+		return nil, fmt.Errorf("not a FullDash dump")
+	}
+	for _, t := range d.Tables {
+		for i, row := range t.Rows {
+			if len(row) != len(t.Columns) {
+				return nil, fmt.Errorf("%s row %d: %d values for %d columns", t.Name, i+1, len(row), len(t.Columns))
+			}
+		}
+	}
+	return &d, nil
+}
+
+// load empties every table but the job leases and inserts the dump's rows.
+// Like a migration it runs with foreign keys off, so rows go in in any
+// order, and checks them before committing.
+func (db *DB) load(d *dump) (*models.DumpSummary, error) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return nil, err
+	}
+	defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	existing := map[string]bool{}
+	rows, err := tx.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_stat%'`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for name := range existing {
+		if name == "leases" {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM ` + quoteIdent(name)); err != nil {
+			return nil, fmt.Errorf("empty %s: %w", name, err)
+		}
+	}
+
+	// Inserting ids moves sqlite_sequence on, so its rows go in last, over
+	// what the other tables left there
+	var tables, sequence []dumpTable
+	for _, t := range d.Tables {
+		if t.Name == "sqlite_sequence" {
+			sequence = append(sequence, t)
+		} else {
+			tables = append(tables, t)
+		}
+	}
+	tables = append(tables, sequence...)
+	sum := &models.DumpSummary{SchemaVersion: d.SchemaVersion, ExportedAt: d.ExportedAt, Tables: len(d.Tables)}
+	for _, t := range tables {
+		if !existing[t.Name] || dumpSkipped[t.Name] {
+			return nil, fmt.Errorf("the dump has a table %q this database doesn't", t.Name)
+		}
+		if t.Name == "sqlite_sequence" {
+			if _, err := tx.Exec(`DELETE FROM sqlite_sequence`); err != nil {
+				return nil, err
+			}
+		}
+		columns, err := tableColumns(tx, t.Name)
+		if err != nil {
+			return nil, err
+		}
+		known := map[string]bool{}
+		for _, c := range columns {
+			known[c] = true
+		}
+		names := make([]string, len(t.Columns))
+		for i, c := range t.Columns {
+			if !known[c] {
+				return nil, fmt.Errorf("the dump has a column %s.%s this database doesn't", t.Name, c)
+			}
+			names[i] = quoteIdent(c)
+		}
+
+		stmt, err := tx.Prepare(fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`, quoteIdent(t.Name),
+			strings.Join(names, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")))
+		if err != nil {
+			return nil, err
+		}
+		for i, row := range t.Rows {
+			args := make([]any, len(row))
+			for j, raw := range row {
+				if args[j], err = dumpValue(raw); err != nil {
+					stmt.Close()
+					return nil, fmt.Errorf("%s row %d, %s: %w", t.Name, i+1, t.Columns[j], err)
+				}
+			}
+			if _, err := stmt.Exec(args...); err != nil {
+				stmt.Close()
+				return nil, fmt.Errorf("%s row %d: %w", t.Name, i+1, err)
+			}
+		}
+		stmt.Close()
+		sum.Rows += len(t.Rows)
+	}
+
+	var dangling int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_foreign_key_check`).Scan(&dangling); err != nil {
+		return nil, err
+	}
+	if dangling > 0 {
+		return nil, fmt.Errorf("the dump has %d rows referring to rows it doesn't have", dangling)
+	}
+	return sum, tx.Commit()
+}
+
+// dumpValue turns a dumped value back into one to insert
+func dumpValue(raw json.RawMessage) (any, error) {
+	s := strings.TrimSpace(string(raw))
+	switch {
+	case s == "null":
+		return nil, nil
+	case strings.HasPrefix(s, `"`):
+		var v string
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	case strings.HasPrefix(s, "{"):
+		var v struct {
+			Blob *string `json:"blob"`
+		}
+		if err := json.Unmarshal(raw, &v); err != nil || v.Blob == nil {
+			return nil, fmt.Errorf("invalid value %s", s)
+		}
+		return base64.StdEncoding.DecodeString(*v.Blob)
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("invalid value %s", s)
+}
+
+// dumpTables lists the tables a dump holds, sqlite_sequence included so
+// AUTOINCREMENT ids carry on where they were
+func (db *DB) dumpTables() ([]string, error) {
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_stat%' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if !dumpSkipped[name] {
+			out = append(out, name)
+		}
+	}
+	return out, rows.Err()
+}
+
+// tableColumns lists a table's columns in order
+func tableColumns(tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.Query(`SELECT name FROM pragma_table_info(?) ORDER BY cid`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, rows.Err()
+}
+
+// quoteIdent quotes a table or column name for SQL
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
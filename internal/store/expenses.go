@@ -0,0 +1,183 @@
+// store/expenses.go - Expense (reimbursement) operations
+package store
+
+import (
+	"database/sql"
+	"slices"
+	"strconv"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// expenseScanner for DRY row scanning
+type expenseScanner struct {
+	dest *models.Expense
+}
+
+// expenseFields lists an expense's selected columns in scan order
+func expenseFields(e *models.Expense) []field {
+	return []field{
+		{"id", &e.ID}, {"project_id", null(&e.ProjectID)}, {"payer_id", &e.PayerID}, {"description", &e.Description},
+		{"category", &e.Category}, {"amount_cents", &e.AmountCents}, {"spent_on", &e.SpentOn},
+		{"receipt_path", null(&e.ReceiptPath)}, {"created_at", &e.CreatedAt},
+	}
+}
+
+// expenseListFields adds the project's client, for lists across projects
+func expenseListFields(e *models.Expense) []field {
+	return append(expenseFields(e),
+		field{"(SELECT client FROM " + projectTable + " WHERE " + projectTable + ".id = project_id)", null(&e.Client)})
+}
+
+func (s expenseScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, expenseFields(s.dest))
+}
+
+func (s expenseScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// expenseListScanner scans rows selected with expenseListColumns
+type expenseListScanner struct {
+	dest *models.Expense
+}
+
+func (s expenseListScanner) Scan(rows *sql.Rows) error {
+	return scanInto(rows.Scan, expenseListFields(s.dest))
+}
+
+// CreateExpense inserts a new expense, unless its project was paid (or, for
+// overhead, it was spent) in a locked month
+func (db *DB) CreateExpense(e *models.Expense) error {
+	return db.Tx(func(tx *DB) error {
+		if err := tx.guardExpense(e); err != nil {
+			return err
+		}
+		return tx.QueryRow(qExpenseInsert, nullID(e.ProjectID), e.PayerID, e.Description, e.Category, e.AmountCents,
+			e.SpentOn.Format(dateLayout), e.ReceiptPath).Scan(&e.ID, &e.CreatedAt)
+	})
+}
+
+// UpdateExpense saves changes to an expense. Both the stored expense and the
+// edited one must be outside locked periods, so an expense can't be moved
+// into or out of a locked month or project.
+func (db *DB) UpdateExpense(e *models.Expense) error {
+	return db.Tx(func(tx *DB) error {
+		stored, err := tx.GetExpense(e.ID)
+		if err != nil || stored == nil {
+			return err
+		}
+		if err := tx.guardExpense(stored); err != nil {
+			return err
+		}
+		if err := tx.guardExpense(e); err != nil {
+			return err
+		}
+		_, err = tx.Exec(qExpenseUpdate, nullID(e.ProjectID), e.PayerID, e.Description, e.Category, e.AmountCents,
+			e.SpentOn.Format(dateLayout), e.ReceiptPath, e.ID)
+		return err
+	})
+}
+
+// guardExpense refuses a write to an expense whose project was paid in a
+// locked month, or to an overhead expense spent in one
+func (db *DB) guardExpense(e *models.Expense) error {
+	if !e.Overhead() {
+		return db.guardProject(e.ProjectID, nil)
+	}
+	lock, err := db.PeriodLockAt(e.SpentOn)
+	if err != nil || lock == nil {
+		return err
+	}
+	return &models.PeriodLockedError{Lock: *lock}
+}
+
+// GetExpense fetches an expense by ID
+func (db *DB) GetExpense(id int64) (*models.Expense, error) {
+	e := &models.Expense{}
+	err := expenseScanner{e}.scan(db.QueryRow(qExpenseByID, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
+
+// ListExpenses returns a project's expenses in date order
+func (db *DB) ListExpenses(projectID int64) ([]models.Expense, error) {
+	rows, err := db.Query(qExpensesByProject, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Expense { return &models.Expense{} },
+		func(e *models.Expense) scanner { return expenseScanner{e} })
+}
+
+// listOverhead returns every expense that belongs to no project
+func (db *DB) listOverhead() ([]models.Expense, error) {
+	rows, err := db.Query(qExpensesOverhead)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Expense { return &models.Expense{} },
+		func(e *models.Expense) scanner { return expenseScanner{e} })
+}
+
+// ListAllExpenses returns a year's expenses across projects and overhead,
+// newest first, with each project's client filled in
+func (db *DB) ListAllExpenses(f models.ExpenseFilter) ([]models.Expense, error) {
+	rows, err := db.Query(qExpensesByYear, strconv.Itoa(f.Year), f.Category, f.Category)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Expense { return &models.Expense{} },
+		func(e *models.Expense) scanner { return expenseListScanner{e} })
+}
+
+// ExpenseTotals totals a year's expenses per category, in display order
+func (db *DB) ExpenseTotals(year int) ([]models.ExpenseTotal, error) {
+	rows, err := db.Query(qExpenseTotals, strconv.Itoa(year))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.ExpenseTotal
+	for rows.Next() {
+		var t models.ExpenseTotal
+		if err := rows.Scan(&t.Category, &t.Count, &t.AmountCents); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	slices.SortFunc(out, func(a, b models.ExpenseTotal) int {
+		return slices.Index(models.ExpenseCategories, a.Category) - slices.Index(models.ExpenseCategories, b.Category)
+	})
+	return out, rows.Err()
+}
+
+// DeleteExpense removes an expense, unless its project was paid (or, for
+// overhead, it was spent) in a locked month
+func (db *DB) DeleteExpense(id int64) error {
+	return db.Tx(func(tx *DB) error {
+		e, err := tx.GetExpense(id)
+		if err != nil {
+			return err
+		}
+		if e != nil {
+			if err := tx.guardExpense(e); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec(qExpenseDelete, id)
+		return err
+	})
+}
@@ -0,0 +1,115 @@
+// store/fields.go - SELECT lists built from their scan destinations
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// field pairs a selected column (or SQL expression) with the destination its
+// value scans into. Each model's fields are declared once, in order; its
+// SELECT list (xxxColumns) and its scanner both come from that declaration,
+// so a column can't be added, dropped or reordered in one and not the other.
+type field struct {
+	column string
+	dest   any
+}
+
+// columns joins the fields' columns into a SELECT list. Pass fields built on
+// a zero value; only the column names are used.
+func columns(fields []field) string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.column
+	}
+	return strings.Join(names, ", ")
+}
+
+// scanInto scans one row into the fields' destinations
+func scanInto(scan func(dest ...any) error, fields []field) error {
+	dests := make([]any, len(fields))
+	for i, f := range fields {
+		dests[i] = f.dest
+	}
+	return scan(dests...)
+}
+
+// nullable scans a nullable column into a plain value; NULL leaves the
+// zero value (0, "", zero time)
+type nullable[T any] struct {
+	dest *T
+}
+
+func null[T any](dest *T) nullable[T] {
+	return nullable[T]{dest}
+}
+
+func (n nullable[T]) Scan(v any) error {
+	var x sql.Null[T]
+	if err := x.Scan(v); err != nil {
+		return err
+	}
+	*n.dest = x.V
+	return nil
+}
+
+// optional scans a nullable column into a pointer; NULL leaves it nil
+type optional[T any] struct {
+	dest **T
+}
+
+func opt[T any](dest **T) optional[T] {
+	return optional[T]{dest}
+}
+
+func (o optional[T]) Scan(v any) error {
+	var x sql.Null[T]
+	if err := x.Scan(v); err != nil {
+		return err
+	}
+	*o.dest = nil
+	if x.Valid {
+		*o.dest = &x.V
+	}
+	return nil
+}
+
+// jsonText scans a nullable JSON text column into dest; NULL leaves it as is
+type jsonText struct {
+	dest any
+}
+
+func (j jsonText) Scan(v any) error {
+	var s sql.NullString
+	if err := s.Scan(v); err != nil || !s.Valid {
+		return err
+	}
+	return json.Unmarshal([]byte(s.String), j.dest)
+}
+
+// jsonArg marshals v for a JSON text column. It is only used on ID lists and
+// per-person amounts, which always marshal.
+func jsonArg(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// optDate formats an optional date for a DATE column: NULL when unset
+func optDate(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.Format(dateLayout)
+}
+
+// optJSONArg is jsonArg for optional per-person amounts: NULL when empty
+func optJSONArg[V int64 | float64](v models.PerPerson[V]) any {
+	if len(v) == 0 {
+		return nil
+	}
+	return jsonArg(v)
+}
@@ -0,0 +1,55 @@
+// store/goals.go - Revenue goals per month, quarter or year
+package store
+
+import (
+	"database/sql"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// goalScanner for DRY row scanning
+type goalScanner struct {
+	dest *models.Goal
+}
+
+// goalFields lists a goal's selected columns in scan order
+func goalFields(g *models.Goal) []field {
+	return []field{
+		{"id", &g.ID}, {"granularity", &g.Granularity}, {"start_on", &g.Start},
+		{"person_id", opt(&g.PersonID)}, {"target_cents", &g.TargetCents}, {"created_at", &g.CreatedAt},
+	}
+}
+
+func (s goalScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, goalFields(s.dest))
+}
+
+func (s goalScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// ListGoals returns all goals, latest period first, the combined goal
+// before each person's
+func (db *DB) ListGoals() ([]models.Goal, error) {
+	rows, err := db.Query(qGoalsAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Goal { return &models.Goal{} },
+		func(g *models.Goal) scanner { return goalScanner{g} })
+}
+
+// SaveGoal sets the target for a period and person, replacing the goal
+// already set for them
+func (db *DB) SaveGoal(g *models.Goal) error {
+	return db.QueryRow(qGoalUpsert, g.Granularity, g.Start.Format(dateLayout), g.PersonID, g.TargetCents).Scan(&g.ID, &g.CreatedAt)
+}
+
+// DeleteGoal removes a goal
+func (db *DB) DeleteGoal(id int64) error {
+	_, err := db.Exec(qGoalDelete, id)
+	return err
+}
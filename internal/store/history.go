@@ -0,0 +1,68 @@
+// store/history.go - Daily snapshots of the dashboard metrics
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// statsSnapshotSlack is how far before the asked-for day a snapshot may be
+// and still stand in for it (the server may have been down that day)
+const statsSnapshotSlack = 7
+
+// statsSnapshotScanner for DRY row scanning
+type statsSnapshotScanner struct {
+	dest *models.StatsSnapshot
+}
+
+// statsSnapshotFields lists a snapshot's columns in scan order
+func statsSnapshotFields(s *models.StatsSnapshot) []field {
+	return []field{
+		{"day", &s.Day}, {"total_revenue_cents", &s.TotalRevenueCents}, {"share_cents", jsonText{&s.ShareCents}},
+		{"reimbursed_cents", jsonText{&s.ReimbursedCents}}, {"open_projects", &s.OpenProjects},
+		{"expense_cents", &s.ExpenseCents},
+	}
+}
+
+func (s statsSnapshotScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, statsSnapshotFields(s.dest))
+}
+
+func (s statsSnapshotScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// SaveStatsSnapshot stores the metrics for s.Day, replacing any saved
+// earlier that day
+func (db *DB) SaveStatsSnapshot(s *models.StatsSnapshot) error {
+	_, err := db.Exec(qStatsSnapshotUpsert, s.Day.Format(dateLayout), s.TotalRevenueCents,
+		jsonArg(s.ShareCents), jsonArg(s.ReimbursedCents), s.OpenProjects, s.ExpenseCents)
+	return err
+}
+
+// StatsSnapshotNear returns the latest snapshot on or up to a week before
+// day, or nil if there is none
+func (db *DB) StatsSnapshotNear(day time.Time) (*models.StatsSnapshot, error) {
+	s := &models.StatsSnapshot{}
+	err := statsSnapshotScanner{s}.scan(db.QueryRow(qStatsSnapshotNear,
+		day.Format(dateLayout), day.AddDate(0, 0, -statsSnapshotSlack).Format(dateLayout)).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// MonthlyStatsSnapshots returns each month's last snapshot, oldest first
+func (db *DB) MonthlyStatsSnapshots() ([]models.StatsSnapshot, error) {
+	rows, err := db.Query(qStatsSnapshotsMonthly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.StatsSnapshot { return &models.StatsSnapshot{} },
+		func(s *models.StatsSnapshot) scanner { return statsSnapshotScanner{s} })
+}
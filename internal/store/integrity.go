@@ -0,0 +1,115 @@
+// store/integrity.go - SQLite integrity checks and recovery verification
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// CheckIntegrity runs PRAGMA integrity_check and foreign_key_check and records the result
+func (db *DB) CheckIntegrity() (*models.IntegrityCheck, error) {
+	problems, err := db.integrityProblems()
+	if err != nil {
+		return nil, err
+	}
+
+	fkProblems, err := db.foreignKeyProblems()
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, fkProblems...)
+
+	check := &models.IntegrityCheck{OK: len(problems) == 0, Details: strings.Join(problems, "\n")}
+	err = db.QueryRow(qIntegrityInsert, check.OK, check.Details).Scan(&check.ID, &check.CheckedAt)
+	return check, err
+}
+
+// integrityFields lists an integrity check's selected columns in scan order
+func integrityFields(c *models.IntegrityCheck) []field {
+	return []field{{"id", &c.ID}, {"ok", &c.OK}, {"details", null(&c.Details)}, {"checked_at", &c.CheckedAt}}
+}
+
+// LastIntegrityCheck returns the most recent check, or nil if none has run
+func (db *DB) LastIntegrityCheck() (*models.IntegrityCheck, error) {
+	c := &models.IntegrityCheck{}
+	err := scanInto(db.QueryRow(qIntegrityLatest).Scan, integrityFields(c))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return c, err
+}
+
+// RecordDRTest stores the outcome of a disaster recovery dry run
+func (db *DB) RecordDRTest(t *models.DRTest) error {
+	return db.QueryRow(qDRTestInsert, t.BackupFile, t.OK, t.Details).Scan(&t.ID, &t.CheckedAt)
+}
+
+// drTestFields lists a dry run's selected columns in scan order
+func drTestFields(t *models.DRTest) []field {
+	return []field{
+		{"id", &t.ID}, {"backup_file", &t.BackupFile}, {"ok", &t.OK}, {"details", null(&t.Details)},
+		{"checked_at", &t.CheckedAt},
+	}
+}
+
+// LastDRTest returns the most recent dry run, or nil if none has run
+func (db *DB) LastDRTest() (*models.DRTest, error) {
+	t := &models.DRTest{}
+	err := scanInto(db.QueryRow(qDRTestLatest).Scan, drTestFields(t))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// CountProjects returns the total number of projects
+func (db *DB) CountProjects() (int, error) {
+	var n int
+	err := db.QueryRow(qProjectCount).Scan(&n)
+	return n, err
+}
+
+// integrityProblems returns integrity_check output, excluding the lone "ok" row
+func (db *DB) integrityProblems() ([]string, error) {
+	rows, err := db.Query(qIntegrityCheck)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return nil, err
+		}
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+	return problems, rows.Err()
+}
+
+// foreignKeyProblems describes each row that violates a foreign key
+func (db *DB) foreignKeyProblems() ([]string, error) {
+	rows, err := db.Query(qForeignKeyCheck)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var fkID int
+		if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+			return nil, err
+		}
+		problems = append(problems, fmt.Sprintf("foreign key: %s row %d references missing %s", table, rowID.Int64, parent))
+	}
+	return problems, rows.Err()
+}
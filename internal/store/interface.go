@@ -1,23 +1,324 @@
 // store/interface.go - Store interface for testability
 package store
 
-import "github.com/noor-latif/fulldash/internal/models"
+import (
+	"io"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
 
 type Store interface {
+	// Transactions
+	Tx(fn func(tx *DB) error) error
+
 	// Projects
 	CreateProject(p *models.Project) error
 	GetProject(id int64) (*models.Project, error)
 	GetProjectByStripeID(stripeID string) (*models.Project, error)
 	UpdateProject(p *models.Project) error
-	UpdateProjectStatus(id int64, status models.ProjectStatus, revenue float64, stripeID string) error
+	UpdateProjectStatus(id int64, status models.ProjectStatus, revenueCents int64, stripeID string) error
+	SetPaidAt(id int64, at time.Time) error
+	StripePaymentIDs() (map[string]bool, error)
 	DeleteProject(id int64) error
-	ListProjects(search string) ([]models.Project, error)
+	ListProjects(f models.ProjectFilter) ([]models.Project, error)
+	ProjectTotals(f models.ProjectFilter) (map[models.ProjectStatus]models.ArchiveTotals, error)
 	ListProjectsByStatus(status models.ProjectStatus) ([]models.Project, error)
-	
+	SetProjectArchived(id int64, archived bool) error
+	SetBudgetWarned(id int64, percent int) error
+	ListArchivedProjects(f models.ArchiveFilter) ([]models.Project, error)
+	ArchiveTotals(f models.ArchiveFilter) (models.ArchiveTotals, error)
+
 	// Contributions
 	GetContributions(projectID int64) ([]models.Contribution, error)
 	SetContribution(c *models.Contribution) error
-	
+	ListTimeEntries(f models.TimeEntryFilter) ([]models.TimeEntry, error)
+	GetTimeEntry(id int64) (*models.TimeEntry, error)
+	CreateTimeEntry(e *models.TimeEntry) error
+	UpdateTimeEntry(e *models.TimeEntry) error
+	DeleteTimeEntry(id int64) error
+	SetDayHours(projectID, personID int64, day time.Time, hours float64) error
+
+	// Milestones
+	ListMilestones(projectID int64) (models.Milestones, error)
+	ListAllMilestones() (models.Milestones, error)
+	GetMilestone(id int64) (*models.Milestone, error)
+	CreateMilestone(m *models.Milestone) error
+	UpdateMilestone(m *models.Milestone) error
+	SetMilestoneLink(id int64, linkID, url string) error
+	MarkMilestonePaid(id int64, paymentID string) error
+	DeleteMilestone(id int64) error
+
+	// Tasks
+	ListTasks(projectID int64) (models.Tasks, error)
+	GetTask(id int64) (*models.Task, error)
+	CreateTask(t *models.Task) error
+	UpdateTask(t *models.Task) error
+	SetTaskDone(id int64, done bool) error
+	MoveTask(t *models.Task, dir int) error
+	DeleteTask(id int64) error
+
+	// Retainers
+	ListRetainers(clientID int64) ([]models.Retainer, error)
+	ListAllRetainers() ([]models.Retainer, error)
+	GetRetainer(id int64) (*models.Retainer, error)
+	GetRetainerBySubscription(subscriptionID string) (*models.Retainer, error)
+	CreateRetainer(r *models.Retainer) error
+	SetRetainerSubscription(r *models.Retainer) error
+	MarkRetainerPaid(id int64) error
+	DeleteRetainer(id int64) error
+	ListProjectDocuments(projectID int64) ([]models.Document, error)
+	ListClientDocuments(clientID int64) ([]models.Document, error)
+	DocumentsExpiring(today, before time.Time) ([]models.Document, error)
+	GetDocument(id int64) (*models.Document, error)
+	CreateDocument(d *models.Document) error
+	MarkDocumentReminded(id int64) error
+	DeleteDocument(id int64) error
+
+	// Expenses
+	CreateExpense(e *models.Expense) error
+	GetExpense(id int64) (*models.Expense, error)
+	ListExpenses(projectID int64) ([]models.Expense, error)
+	ListAllExpenses(f models.ExpenseFilter) ([]models.Expense, error)
+	ExpenseTotals(year int) ([]models.ExpenseTotal, error)
+	UpdateExpense(e *models.Expense) error
+	DeleteExpense(id int64) error
+
+	// Payouts and settlements
+	CreatePayout(p *models.Payout) error
+	GetPayout(id int64) (*models.Payout, error)
+	ListPayouts() ([]models.Payout, error)
+	DeletePayout(id int64) error
+
+	// Invoices
+	CreateInvoice(inv *models.Invoice) error
+	GetInvoice(id int64) (*models.Invoice, error)
+	ListInvoices(status models.InvoiceStatus) ([]models.Invoice, error)
+	ListProjectInvoices(projectID int64) ([]models.Invoice, error)
+	UpdateInvoice(inv *models.Invoice) error
+	IssueInvoice(id int64, on time.Time) error
+	SetInvoiceStatus(id int64, status models.InvoiceStatus, paidOn time.Time) error
+	DeleteInvoice(id int64) error
+	SetFortnoxSync(id int64, s models.FortnoxSync) error
+
+	// Sent mail
+	LogSentMail(m *models.SentMail) error
+	ListSentMail(limit int) ([]models.SentMail, error)
+	ListInvoiceMail(invoiceID int64) ([]models.SentMail, error)
+	DueSentMail(now time.Time) ([]models.SentMail, error)
+	ClaimSentMail(id int64, now, until time.Time) (bool, error)
+	SaveSentMailAttempt(m *models.SentMail) error
+
+	// Quotes
+	CreateQuote(q *models.Quote) error
+	GetQuote(id int64) (*models.Quote, error)
+	ListQuotes(status models.QuoteStatus) ([]models.Quote, error)
+	UpdateQuote(q *models.Quote) error
+	SetQuoteStatus(id int64, from, to models.QuoteStatus) (bool, error)
+	SetQuoteProject(id, projectID int64) (bool, error)
+	DeleteQuote(id int64) error
+
+	// Travel log
+	CreateTravelEntry(t *models.TravelEntry) error
+	GetTravelEntry(id int64) (*models.TravelEntry, error)
+	ListTravelEntries(year int) ([]models.TravelEntry, error)
+	MileageSummary(year int) ([]models.MileageSummary, error)
+	DeleteTravelEntry(id int64) error
+
+	// Agreements
+	ListAgreements() ([]models.Agreement, error)
+	GetAgreement(id int64) (*models.Agreement, error)
+	CreateAgreement(a *models.Agreement) error
+	DeleteAgreement(id int64) error
+
+	// Locked periods
+	ListPeriodLocks() ([]models.PeriodLock, error)
+	PeriodLockAt(t time.Time) (*models.PeriodLock, error)
+	LockPeriod(month time.Time, by string) error
+	UnlockPeriod(month time.Time) error
+	ProjectLock(p *models.Project) (*models.PeriodLock, error)
+
+	// Adjusting entries
+	ListAdjustments(projectID int64) ([]models.Adjustment, error)
+	ListAdjustmentsPosted(from, to time.Time) ([]models.Adjustment, error)
+	CreateAdjustment(a *models.Adjustment) error
+
+	// Comments
+	ListComments(projectID int64) ([]models.Comment, error)
+	GetComment(id int64) (*models.Comment, error)
+	CreateComment(c *models.Comment) error
+	DeleteComment(id int64) error
+
+	// Coordination between instances
+	AcquireLease(name, holder string, ttl time.Duration) (bool, error)
+	WebhookEventSeen(id string) (bool, error)
+	MarkWebhookEvent(id string) (bool, error)
+
+	// Sessions and cache
+	CreateSession(tokenHash string, userID int64, expires time.Time) error
+	SessionUser(tokenHash string) (int64, error)
+	DeleteSession(tokenHash string) error
+	CacheGet(key string) ([]byte, error)
+	CacheSet(key string, value []byte, ttl time.Duration) error
+	CacheDelete(key string) error
+
+	// Statuses
+	ListStatuses() ([]models.Status, error)
+	CreateStatus(s *models.Status) error
+	UpdateStatus(s *models.Status) error
+	DeleteStatus(key models.ProjectStatus) error
+	CountProjectsByStatus(key models.ProjectStatus) (int, error)
+
+	// People
+	ListPeople() (models.People, error)
+	CreatePerson(p *models.Person) error
+	UpdatePerson(p *models.Person) error
+
+	// Checklists
+	ListChecklistTemplates() ([]models.ChecklistTemplate, error)
+	CreateChecklistTemplate(t *models.ChecklistTemplate) error
+	DeleteChecklistTemplate(id int64) error
+	AttachChecklist(projectID int64, stage models.ChecklistStage) error
+	ListChecklist(projectID int64) ([]models.ChecklistItem, error)
+	GetChecklistItem(id int64) (*models.ChecklistItem, error)
+	SetChecklistItemDone(id int64, done bool) error
+
+	// Automations
+	ListAutomationRules() ([]models.AutomationRule, error)
+	ListAutomationRulesFor(trigger models.AutomationTrigger) ([]models.AutomationRule, error)
+	GetAutomationRule(id int64) (*models.AutomationRule, error)
+	CreateAutomationRule(r *models.AutomationRule) error
+	SetAutomationRuleEnabled(id int64, enabled bool) error
+	DeleteAutomationRule(id int64) error
+	ScheduleAutomationRun(run *models.AutomationRun) error
+	DueAutomationRuns(now time.Time) ([]models.AutomationRun, error)
+	ClaimAutomationRun(id int64) (bool, error)
+	FinishAutomationRun(id int64, runErr string) error
+
+	// Webhooks
+	ListWebhooks() ([]models.Webhook, error)
+	GetWebhook(id int64) (*models.Webhook, error)
+	CreateWebhook(w *models.Webhook) error
+	SetWebhookEnabled(id int64, enabled bool) error
+	DeleteWebhook(id int64) error
+	ListWebhookDeliveries(webhookID int64, limit int) ([]models.WebhookDelivery, error)
+	DueWebhookDeliveries(now time.Time) ([]models.WebhookDelivery, error)
+	GetWebhookDelivery(id int64) (*models.WebhookDelivery, error)
+	CreateWebhookDelivery(d *models.WebhookDelivery) error
+	ClaimWebhookDelivery(id int64, now, until time.Time) (bool, error)
+	SaveWebhookAttempt(d *models.WebhookDelivery) error
+	PruneWebhookDeliveries(days int) error
+
+	// Notifications
+	GetNotificationPrefs(userID int64) (map[string]models.DeliveryMode, error)
+	SetNotificationPref(userID int64, kind string, mode models.DeliveryMode) error
+	CreateNotification(n *models.Notification) error
+	ListNotifications(userID int64, limit int) ([]models.Notification, error)
+	QueuedNotifications(userID int64) ([]models.Notification, error)
+	DeliverDigest(digest *models.Notification, queued []models.Notification) error
+	ClaimOverdueNotice(r models.Receivable) (bool, error)
+
+	// Settings
+	GetSetting(key, fallback string) (string, error)
+	SetSetting(key, value string) error
+
+	// Activity
+	LogActivity(a *models.Activity) error
+	ListActivity(limit int) ([]models.Activity, error)
+	ListProjectActivity(projectID int64, limit int) ([]models.Activity, error)
+	ListUserActivity(userID int64, limit int) ([]models.Activity, error)
+	ActivityBetween(from, to time.Time) ([]models.Activity, error)
+	FirstStripePayment() (*time.Time, error)
+	GetActivity(id int64) (*models.Activity, error)
+	MarkActivityReverted(id, by int64) (bool, error)
+
+	// Users
+	CreateUser(u *models.User) error
+	GetUser(id int64) (*models.User, error)
+	GetUserByEmail(email string) (*models.User, error)
+	GetUserByCalendarToken(hash string) (*models.User, error)
+	ListUsers() ([]models.User, error)
+	CountUsers() (int, error)
+	CountOwners() (int, error)
+	UpdateUserRole(id int64, role models.Role) error
+	SetWeeklyDigest(id int64, on bool) error
+	SetMonthlyReport(id int64, on bool) error
+	SetCalendarToken(id int64, hash string) error
+	DeleteUser(id int64) error
+
+	// API keys
+	CreateAPIKey(k *models.APIKey) error
+	GetAPIKeyByHash(hash string) (*models.APIKey, error)
+	ListAPIKeys() ([]models.APIKey, error)
+	TouchAPIKey(id int64) error
+	RevokeAPIKey(id int64) error
+
+	// Maintenance
+	CheckIntegrity() (*models.IntegrityCheck, error)
+	LastIntegrityCheck() (*models.IntegrityCheck, error)
+	RecordDRTest(t *models.DRTest) error
+	LastDRTest() (*models.DRTest, error)
+	CountProjects() (int, error)
+	DatabaseSize() (int64, error)
+	JournalMode() (string, error)
+	Snapshot(path string) error
+	RecordBackupRun(r *models.BackupRun) error
+	ListBackupRuns(limit int) ([]models.BackupRun, error)
+	LastBackupRun(destination string, ok bool) (*models.BackupRun, error)
+	SaveStorageUsage(u *models.StorageUsage) error
+	StorageQuota() (models.StorageQuota, error)
+	StorageReport() (*models.StorageReport, error)
+	Export(w io.Writer) (*models.DumpSummary, error)
+	Import(r io.Reader) (*models.DumpSummary, error)
+	QueryStats() models.QueryStats
+
 	// Metrics
 	GetMetrics() (*models.Metrics, error)
+	ProjectSplit(p *models.Project) (*models.RevenueSplit, error)
+	SplitRules() (func(time.Time) models.SplitRules, error)
+	HourlyRates() (models.PerPerson[int64], error)
+	SetHourlyRates(rates models.PerPerson[int64]) error
+	Company() (models.Company, error)
+	SetCompany(c models.Company) error
+	Accounts() (models.Accounts, error)
+	SetAccounts(a models.Accounts) error
+	FortnoxCredentials() (models.FortnoxCredentials, error)
+	SetFortnoxCredentials(c models.FortnoxCredentials) error
+	GoogleCalendarCredentials() (models.GoogleCalendarCredentials, error)
+	SetGoogleCalendarCredentials(c models.GoogleCalendarCredentials) error
+	GoogleCalendarSync() (models.GoogleCalendarSync, error)
+	SetGoogleCalendarSync(s models.GoogleCalendarSync) error
+	BudgetThresholds() ([]int, error)
+	SetBudgetThresholds(thresholds []int) error
+	QuarterlyStats(year int) ([]models.QuarterStats, error)
+	RevenueSeries(g models.Granularity, from, to time.Time) ([]models.RevenuePeriod, error)
+	PeriodStats(from, to time.Time) (models.PeriodStats, error)
+	WinRate(year int) (models.WinRate, error)
+	HoursReport(month time.Time) (models.HoursReport, error)
+	EstimateAccuracy() (models.EstimateAccuracy, error)
+	VATReport(year int) ([]models.VATQuarter, error)
+	Reconcile() (*models.Reconciliation, error)
+	SaveStatsSnapshot(s *models.StatsSnapshot) error
+	StatsSnapshotNear(day time.Time) (*models.StatsSnapshot, error)
+	MonthlyStatsSnapshots() ([]models.StatsSnapshot, error)
+
+	// Clients
+	ListClients() ([]models.Client, error)
+	GetClientByName(name string) (*models.Client, error)
+	SaveClient(c *models.Client) error
+	DeleteClient(id int64) error
+	EnsureClient(name string) error
+	ClientReports() ([]models.ClientReport, error)
+	ClientReport(id int64) (*models.ClientReport, error)
+
+	// Exchange rates
+	ListExchangeRates() ([]models.ExchangeRate, error)
+	GetExchangeRate(currency string) (*models.ExchangeRate, error)
+	SetExchangeRate(r *models.ExchangeRate) error
+	DeleteExchangeRate(currency string) error
+	SaveFXRates(r *models.FXRates) error
+	LatestFXRates() (*models.FXRates, error)
+	ListGoals() ([]models.Goal, error)
+	SaveGoal(g *models.Goal) error
+	DeleteGoal(id int64) error
 }
@@ -0,0 +1,145 @@
+// store/invoices.go - Invoices and their gapless numbering
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// invoiceScanner for DRY row scanning
+type invoiceScanner struct {
+	dest *models.Invoice
+}
+
+// invoiceFields lists an invoice's selected columns in scan order
+func invoiceFields(inv *models.Invoice) []field {
+	return []field{
+		{"id", &inv.ID}, {"number", null(&inv.Number)}, {"year", null(&inv.Year)}, {"seq", null(&inv.Seq)},
+		{"project_id", null(&inv.ProjectID)}, {"client", &inv.Client}, {"lines", jsonText{&inv.Lines}},
+		{"currency", &inv.Currency}, {"vat_percent", &inv.VATPercent}, {"payment_days", &inv.PaymentDays},
+		{"status", &inv.Status}, {"issue_date", null(&inv.IssueDate)}, {"due_date", null(&inv.DueDate)},
+		{"paid_on", null(&inv.PaidOn)}, {"created_by", &inv.CreatedBy}, {"created_at", &inv.CreatedAt},
+		{"fortnox_number", &inv.Fortnox.Number}, {"fortnox_paid", &inv.Fortnox.Paid}, {"fortnox_error", &inv.Fortnox.Error},
+		{"fortnox_synced_at", opt(&inv.Fortnox.SyncedAt)},
+	}
+}
+
+func (s invoiceScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, invoiceFields(s.dest))
+}
+
+func (s invoiceScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// CreateInvoice saves a new draft invoice
+func (db *DB) CreateInvoice(inv *models.Invoice) error {
+	return db.QueryRow(qInvoiceInsert, nullID(inv.ProjectID), inv.Client, jsonArg(inv.Lines), inv.Currency,
+		inv.VATPercent, inv.PaymentDays, inv.CreatedBy).Scan(&inv.ID, &inv.Status, &inv.CreatedAt)
+}
+
+// GetInvoice fetches an invoice by ID
+func (db *DB) GetInvoice(id int64) (*models.Invoice, error) {
+	inv := &models.Invoice{}
+	err := invoiceScanner{inv}.scan(db.QueryRow(qInvoiceByID, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return inv, err
+}
+
+// ListInvoices returns the invoices with the given status (all when empty,
+// or models.InvoiceOverdue): drafts first, then newest number first
+func (db *DB) ListInvoices(status models.InvoiceStatus) ([]models.Invoice, error) {
+	today := time.Now().Format(dateLayout)
+	rows, err := db.Query(qInvoicesList, status, status, status, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Invoice { return &models.Invoice{} },
+		func(inv *models.Invoice) scanner { return invoiceScanner{inv} })
+}
+
+// ListProjectInvoices returns a project's invoices, oldest first
+func (db *DB) ListProjectInvoices(projectID int64) ([]models.Invoice, error) {
+	rows, err := db.Query(qInvoicesByProject, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Invoice { return &models.Invoice{} },
+		func(inv *models.Invoice) scanner { return invoiceScanner{inv} })
+}
+
+// UpdateInvoice saves a draft's client, lines and terms. An issued invoice
+// is refused with models.ErrInvoiceIssued.
+func (db *DB) UpdateInvoice(inv *models.Invoice) error {
+	if err := db.guardInvoice(inv.ID); err != nil {
+		return err
+	}
+	_, err := db.Exec(qInvoiceUpdate, inv.Client, jsonArg(inv.Lines), inv.VATPercent, inv.PaymentDays, inv.ID)
+	return err
+}
+
+// IssueInvoice numbers a draft with the next number in on's year, dates it
+// on and sets it due after its payment terms
+func (db *DB) IssueInvoice(id int64, on time.Time) error {
+	inv, err := db.GetInvoice(id)
+	if err != nil || inv == nil {
+		return err
+	}
+	if !inv.Draft() {
+		return models.ErrInvoiceIssued
+	}
+	due := on.AddDate(0, 0, inv.PaymentDays)
+	year := on.Year()
+	_, err = db.Exec(qInvoiceIssue, on.Format(dateLayout), due.Format(dateLayout), year, year, year, year, id)
+	return err
+}
+
+// SetInvoiceStatus moves an issued invoice to paid (on the given day), void
+// or back to issued; the caller checks the move with Invoice.CanMove
+func (db *DB) SetInvoiceStatus(id int64, status models.InvoiceStatus, paidOn time.Time) error {
+	var paid any
+	if status == models.InvoicePaid {
+		paid = paidOn.Format(dateLayout)
+	}
+	_, err := db.Exec(qInvoiceStatus, status, paid, id)
+	return err
+}
+
+// DeleteInvoice removes a draft. Issued invoices are never deleted, so
+// numbers aren't reused or skipped; they are voided instead.
+func (db *DB) DeleteInvoice(id int64) error {
+	if err := db.guardInvoice(id); err != nil {
+		return err
+	}
+	_, err := db.Exec(qInvoiceDelete, id)
+	return err
+}
+
+// guardInvoice refuses a change to an invoice that's been issued
+func (db *DB) guardInvoice(id int64) error {
+	inv, err := db.GetInvoice(id)
+	if err != nil || inv == nil {
+		return err
+	}
+	if !inv.Draft() {
+		return models.ErrInvoiceIssued
+	}
+	return nil
+}
+
+// SetFortnoxSync records how far an invoice has been pushed to Fortnox,
+// stamping the time unless the push failed
+func (db *DB) SetFortnoxSync(id int64, s models.FortnoxSync) error {
+	_, err := db.Exec(qInvoiceFortnoxSync, s.Number, s.Paid, s.Error, s.Error == "", id)
+	return err
+}
@@ -0,0 +1,37 @@
+// store/leases.go - Coordination between instances sharing the database
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// AcquireLease takes (or renews) the named lease for holder until ttl from
+// now. It reports false while another holder's lease is still valid.
+func (db *DB) AcquireLease(name, holder string, ttl time.Duration) (bool, error) {
+	res, err := db.Exec(qLeaseAcquire, name, holder, fmt.Sprintf("+%d seconds", int(ttl.Seconds())))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// WebhookEventSeen reports whether a webhook event ID was recorded, by this
+// or any other instance
+func (db *DB) WebhookEventSeen(id string) (bool, error) {
+	var seen bool
+	err := db.QueryRow(qWebhookEventSeen, id).Scan(&seen)
+	return seen, err
+}
+
+// MarkWebhookEvent records a webhook event ID once it has been processed;
+// it reports false if the event was already recorded
+func (db *DB) MarkWebhookEvent(id string) (bool, error) {
+	res, err := db.Exec(qWebhookEventInsert, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
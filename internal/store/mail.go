@@ -0,0 +1,95 @@
+// store/mail.go - The sent-mail log
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// sentMailScanner for DRY row scanning
+type sentMailScanner struct {
+	dest *models.SentMail
+}
+
+// sentMailFields lists a log entry's selected columns in scan order
+func sentMailFields(m *models.SentMail) []field {
+	return []field{
+		{"id", &m.ID}, {"kind", &m.Kind}, {"recipients", &m.Recipients}, {"subject", &m.Subject},
+		{"project_id", null(&m.ProjectID)}, {"invoice_id", null(&m.InvoiceID)}, {"error", &m.Error},
+		{"sent_by", &m.SentBy}, {"sent_at", &m.SentAt}, {"provider", &m.Provider}, {"attempts", &m.Attempts},
+		{"next_attempt_at", &m.NextAttemptAt}, {"message", null(&m.Message)},
+	}
+}
+
+func (s sentMailScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, sentMailFields(s.dest))
+}
+
+func (s sentMailScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// LogSentMail records an email, sent or not
+func (db *DB) LogSentMail(m *models.SentMail) error {
+	return db.QueryRow(qSentMailInsert, m.Kind, m.Recipients, m.Subject, nullID(m.ProjectID), nullID(m.InvoiceID),
+		m.Error, m.SentBy, m.Provider, m.Attempts, optUTC(m.NextAttemptAt), nullString(m.Message)).Scan(&m.ID, &m.SentAt)
+}
+
+// ListSentMail returns the most recent emails, newest first
+func (db *DB) ListSentMail(limit int) ([]models.SentMail, error) {
+	rows, err := db.Query(qSentMailRecent, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.SentMail { return &models.SentMail{} },
+		func(m *models.SentMail) scanner { return sentMailScanner{m} })
+}
+
+// ListInvoiceMail returns the emails sent with an invoice, newest first
+func (db *DB) ListInvoiceMail(invoiceID int64) ([]models.SentMail, error) {
+	rows, err := db.Query(qSentMailByInvoice, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.SentMail { return &models.SentMail{} },
+		func(m *models.SentMail) scanner { return sentMailScanner{m} })
+}
+
+// DueSentMail returns the emails whose retry is due
+func (db *DB) DueSentMail(now time.Time) ([]models.SentMail, error) {
+	rows, err := db.Query(qSentMailDue, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.SentMail { return &models.SentMail{} },
+		func(m *models.SentMail) scanner { return sentMailScanner{m} })
+}
+
+// ClaimSentMail takes a due retry for this instance until until; false
+// means another instance already claimed (or finished) it
+func (db *DB) ClaimSentMail(id int64, now, until time.Time) (bool, error) {
+	res, err := db.Exec(qSentMailClaim, until.UTC(), id, now.UTC())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// SaveSentMailAttempt records the outcome of a retry
+func (db *DB) SaveSentMailAttempt(m *models.SentMail) error {
+	_, err := db.Exec(qSentMailSaveAttempt, m.Error, m.Provider, m.Attempts, optUTC(m.NextAttemptAt), nullString(m.Message),
+		m.SentAt.UTC(), m.ID)
+	return err
+}
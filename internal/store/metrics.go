@@ -2,16 +2,19 @@
 package store
 
 import (
+	"cmp"
+	"database/sql"
+	"math"
+	"slices"
+	"time"
+
 	"github.com/noor-latif/fulldash/internal/models"
 )
 
 // GetMetrics calculates dashboard metrics including revenue splits
 func (db *DB) GetMetrics() (*models.Metrics, error) {
-	m := &models.Metrics{}
-	
-	// Total revenue from paid projects
-	var paidCount int
-	err := db.QueryRow(qMetricsTotalRevenue).Scan(&m.TotalRevenue, &paidCount)
+	m := &models.Metrics{ShareCents: models.PerPerson[int64]{}, ReimbursedCents: models.PerPerson[int64]{}}
+	rulesAt, err := db.SplitRules()
 	if err != nil {
 		return nil, err
 	}
@@ -22,70 +25,649 @@ func (db *DB) GetMetrics() (*models.Metrics, error) {
 		return nil, err
 	}
 
-	// Calculate shares from paid projects
-	if err := db.calcRevenueShares(m); err != nil {
+	// Revenue and shares from paid projects
+	if err := db.calcRevenueShares(m, rulesAt); err != nil {
+		return nil, err
+	}
+
+	// Overhead comes off the top of those shares
+	overhead, err := db.listOverhead()
+	if err != nil {
+		return nil, err
+	}
+	m.OverheadCents = deductOverhead(m.ShareCents, m.ReimbursedCents, overhead, rulesAt)
+
+	// What has been paid out against those shares
+	if m.ReceivedCents, err = db.Received(); err != nil {
+		return nil, err
+	}
+
+	// Expenses on paid projects and overhead
+	if err := db.QueryRow(qMetricsExpenses).Scan(&m.ExpenseCents); err != nil {
 		return nil, err
 	}
 
+	// Adjusting entries correct revenue and shares in whole
+	adjustments, err := db.queryAdjustments(qAdjustmentsAll)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range adjustments {
+		m.TotalRevenueCents += a.RevenueCents
+		m.ShareCents.Add(a.ShareCents)
+	}
+
 	return m, nil
 }
 
-// calcRevenueShares calculates Noor/Ahmad shares from paid projects
-func (db *DB) calcRevenueShares(m *models.Metrics) error {
+// QuarterlyStats returns a year's four quarters of paid revenue, shares and
+// hours, split like GetMetrics
+func (db *DB) QuarterlyStats(year int) ([]models.QuarterStats, error) {
+	quarters := make([]models.QuarterStats, 4)
+	for i := range quarters {
+		quarters[i] = models.QuarterStats{Year: year, Quarter: i + 1, ShareCents: models.PerPerson[int64]{},
+			ReimbursedCents: models.PerPerson[int64]{}, Hours: models.PerPerson[float64]{}}
+	}
+
+	paid, err := db.paidProjects()
+	if err != nil {
+		return nil, err
+	}
+	rulesAt, err := db.SplitRules()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range paid {
+		at := p.PaymentTime()
+		if at.Year() != year {
+			continue
+		}
+		split := p.split(rulesAt(at))
+
+		q := &quarters[models.QuarterOf(at)-1]
+		q.Projects++
+		q.RevenueCents += p.RevenueCents
+		q.ShareCents.Add(split.ShareCents)
+		q.ReimbursedCents.Add(split.ReimbursedCents)
+		q.Hours.Add(p.hours)
+	}
+
+	overhead, err := db.listOverhead()
+	if err != nil {
+		return nil, err
+	}
+	byQuarter := make([][]models.Expense, 4)
+	for _, e := range overhead {
+		if e.SpentOn.Year() == year {
+			i := models.QuarterOf(e.SpentOn) - 1
+			byQuarter[i] = append(byQuarter[i], e)
+		}
+	}
+	for i := range quarters {
+		deductOverhead(quarters[i].ShareCents, quarters[i].ReimbursedCents, byQuarter[i], rulesAt)
+	}
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	adjustments, err := db.ListAdjustmentsPosted(from, from.AddDate(1, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range adjustments {
+		q := &quarters[models.QuarterOf(a.PostedOn)-1]
+		q.RevenueCents += a.RevenueCents
+		q.ShareCents.Add(a.ShareCents)
+	}
+	return quarters, nil
+}
+
+// RevenueSeries returns the paid revenue, projects and shares of each
+// period, whole periods from the one holding from to the one holding to,
+// split like QuarterlyStats. Periods with nothing paid are included, as zeros.
+func (db *DB) RevenueSeries(g models.Granularity, from, to time.Time) ([]models.RevenuePeriod, error) {
+	var periods []models.RevenuePeriod
+	var reimbursed []models.PerPerson[int64] // each period's part of the shares, weighing overhead
+	index := map[time.Time]int{}
+	for start := g.Start(from); !start.After(to); start = g.Add(start, 1) {
+		index[start] = len(periods)
+		periods = append(periods, models.RevenuePeriod{Start: start, Label: g.Label(start), ShareCents: models.PerPerson[int64]{}})
+		reimbursed = append(reimbursed, models.PerPerson[int64]{})
+	}
+	period := func(t time.Time) int {
+		if i, ok := index[g.Start(t)]; ok {
+			return i
+		}
+		return -1
+	}
+
+	paid, err := db.paidProjects()
+	if err != nil {
+		return nil, err
+	}
+	rulesAt, err := db.SplitRules()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paid {
+		at := p.PaymentTime()
+		i := period(at)
+		if i < 0 {
+			continue
+		}
+		split := p.split(rulesAt(at))
+		periods[i].Projects++
+		periods[i].RevenueCents += p.RevenueCents
+		periods[i].ShareCents.Add(split.ShareCents)
+		reimbursed[i].Add(split.ReimbursedCents)
+	}
+
+	overhead, err := db.listOverhead()
+	if err != nil {
+		return nil, err
+	}
+	byPeriod := make([][]models.Expense, len(periods))
+	for _, e := range overhead {
+		if i := period(e.SpentOn); i >= 0 {
+			byPeriod[i] = append(byPeriod[i], e)
+		}
+	}
+	for i := range periods {
+		deductOverhead(periods[i].ShareCents, reimbursed[i], byPeriod[i], rulesAt)
+	}
+
+	if len(periods) == 0 {
+		return periods, nil
+	}
+	end := g.Add(periods[len(periods)-1].Start, 1).AddDate(0, 0, -1)
+	adjustments, err := db.ListAdjustmentsPosted(periods[0].Start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range adjustments {
+		if i := period(a.PostedOn); i >= 0 {
+			periods[i].RevenueCents += a.RevenueCents
+			periods[i].ShareCents.Add(a.ShareCents)
+		}
+	}
+	return periods, nil
+}
+
+// PeriodStats totals the projects paid between from and to (inclusive
+// dates) and the hours logged on them, with the adjusting entries posted in
+// that time, counted as QuarterlyStats counts them
+func (db *DB) PeriodStats(from, to time.Time) (models.PeriodStats, error) {
+	s := models.PeriodStats{From: from, To: to}
+	paid, err := db.paidProjects()
+	if err != nil {
+		return s, err
+	}
+	end := to.AddDate(0, 0, 1)
+	for _, p := range paid {
+		if at := p.PaymentTime(); at.Before(from) || !at.Before(end) {
+			continue
+		}
+		s.Projects++
+		s.RevenueCents += p.RevenueCents
+		s.Hours += p.hours.Total()
+	}
+
+	adjustments, err := db.ListAdjustmentsPosted(from, to)
+	if err != nil {
+		return s, err
+	}
+	for _, a := range adjustments {
+		s.RevenueCents += a.RevenueCents
+	}
+	if s.Projects > 0 {
+		s.AverageCents = s.RevenueCents / int64(s.Projects)
+	}
+	return s, nil
+}
+
+// HoursReport sets the hours logged in the month starting at month against
+// each person's share, less reimbursements, of the projects paid in it,
+// split like GetMetrics
+func (db *DB) HoursReport(month time.Time) (models.HoursReport, error) {
+	r := models.HoursReport{Month: month}
+	end := month.AddDate(0, 1, 0)
+	entries, err := db.ListTimeEntries(models.TimeEntryFilter{From: month, To: end.AddDate(0, 0, -1)})
+	if err != nil {
+		return r, err
+	}
+	paid, err := db.paidProjects()
+	if err != nil {
+		return r, err
+	}
+	rulesAt, err := db.SplitRules()
+	if err != nil {
+		return r, err
+	}
+	people, err := db.ListPeople()
+	if err != nil {
+		return r, err
+	}
+
+	type key struct{ person, project int64 }
+	rows := map[key]*models.ProjectHours{}
+	row := func(personID, projectID int64, client string) *models.ProjectHours {
+		k := key{personID, projectID}
+		if rows[k] == nil {
+			rows[k] = &models.ProjectHours{PersonID: personID, Person: people.Name(personID), ProjectID: projectID, Client: client}
+		}
+		return rows[k]
+	}
+	for _, e := range entries {
+		row(e.PersonID, e.ProjectID, e.Client).Hours += e.Hours
+	}
+	for _, p := range paid {
+		if at := p.PaymentTime(); at.Before(month) || !at.Before(end) {
+			continue
+		}
+		split := p.split(rulesAt(p.PaymentTime()))
+		for id, share := range split.ShareCents {
+			earned := share - split.ReimbursedCents[id]
+			if earned == 0 && p.hours[id] == 0 {
+				continue
+			}
+			pr := row(id, p.ID, p.Client)
+			pr.Paid = true
+			pr.EarnedCents = earned
+			pr.TotalHours = p.hours[id]
+			pr.HourlyCents = models.PerHour(earned, pr.TotalHours)
+		}
+	}
+
+	totals := map[int64]*models.PersonHours{}
+	for _, pr := range rows {
+		r.Rows = append(r.Rows, *pr)
+		if totals[pr.PersonID] == nil {
+			totals[pr.PersonID] = &models.PersonHours{PersonID: pr.PersonID, Person: pr.Person}
+		}
+		t := totals[pr.PersonID]
+		t.Hours += pr.Hours
+		t.EarnedCents += pr.EarnedCents
+	}
+	for _, p := range people {
+		if t := totals[p.ID]; t != nil {
+			t.HourlyCents = models.PerHour(t.EarnedCents, t.Hours)
+			r.People = append(r.People, *t)
+		}
+	}
+	order := map[int64]int{}
+	for i, p := range people {
+		order[p.ID] = i
+	}
+	slices.SortFunc(r.Rows, func(a, b models.ProjectHours) int {
+		return cmp.Or(cmp.Compare(order[a.PersonID], order[b.PersonID]), cmp.Compare(a.Client, b.Client), cmp.Compare(a.ProjectID, b.ProjectID))
+	})
+	return r, nil
+}
+
+// EstimateAccuracy compares the estimates of finished projects (in a
+// terminal status other than lost, archived included) with the hours logged
+// on them
+func (db *DB) EstimateAccuracy() (models.EstimateAccuracy, error) {
+	rows, err := db.Query(qEstimatedProjects)
+	if err != nil {
+		return models.EstimateAccuracy{}, err
+	}
+	defer rows.Close()
+	projects, err := scanAll(rows, func() *models.Project { return &models.Project{} },
+		func(p *models.Project) scanner { return projectScanner{p} })
+	if err != nil {
+		return models.EstimateAccuracy{}, err
+	}
+
+	hourRows, err := db.Query(qEstimatedHours)
+	if err != nil {
+		return models.EstimateAccuracy{}, err
+	}
+	defer hourRows.Close()
+	hours := map[int64]models.PerPerson[float64]{}
+	for hourRows.Next() {
+		var projectID, personID int64
+		var h float64
+		if err := hourRows.Scan(&projectID, &personID, &h); err != nil {
+			return models.EstimateAccuracy{}, err
+		}
+		if hours[projectID] == nil {
+			hours[projectID] = models.PerPerson[float64]{}
+		}
+		hours[projectID][personID] = h
+	}
+	if err := hourRows.Err(); err != nil {
+		return models.EstimateAccuracy{}, err
+	}
+
+	people, err := db.ListPeople()
+	if err != nil {
+		return models.EstimateAccuracy{}, err
+	}
+	return models.NewEstimateAccuracy(projects, hours, people), nil
+}
+
+// WinRate weighs the projects paid in year against those lost in it, with
+// the reasons they were lost; archived projects count too
+func (db *DB) WinRate(year int) (models.WinRate, error) {
 	paid, err := db.ListProjectsByStatus(models.StatusPaid)
+	if err != nil {
+		return models.WinRate{}, err
+	}
+	lost, err := db.ListProjectsByStatus(models.StatusLost)
+	if err != nil {
+		return models.WinRate{}, err
+	}
+	return models.NewWinRate(year, paid, lost), nil
+}
+
+// calcRevenueShares totals revenue and each person's share from paid
+// projects, each under the agreement in force when it was paid, and sizes
+// the deals
+func (db *DB) calcRevenueShares(m *models.Metrics, rulesAt func(time.Time) models.SplitRules) error {
+	paid, err := db.paidProjects()
 	if err != nil {
 		return err
 	}
 
-	for _, p := range paid {
-		contribs, _ := db.GetContributions(p.ID)
-		split := CalcRevenueSplit(&p, contribs)
-		m.NoorShare += split.NoorShare
-		m.AhmadShare += split.AhmadShare
+	projects := make([]models.Project, len(paid))
+	for i, p := range paid {
+		split := p.split(rulesAt(p.PaymentTime()))
+		m.TotalRevenueCents += p.RevenueCents
+		m.ShareCents.Add(split.ShareCents)
+		m.ReimbursedCents.Add(split.ReimbursedCents)
+		projects[i] = p.Project
 	}
+	m.Deals = models.NewDealStats(projects)
 	return nil
 }
 
-// CalcRevenueSplit determines revenue sharing based on hours or ownership
-func CalcRevenueSplit(p *models.Project, contribs []models.Contribution) *models.RevenueSplit {
-	if p.Revenue <= 0 {
+// SplitRules loads the terms splits are made under: the agreements and the
+// hourly rates. The returned func gives the rules in force at a time.
+func (db *DB) SplitRules() (func(time.Time) models.SplitRules, error) {
+	agreements, err := db.ListAgreements()
+	if err != nil {
+		return nil, err
+	}
+	rates, err := db.HourlyRates()
+	if err != nil {
+		return nil, err
+	}
+	return func(t time.Time) models.SplitRules {
+		rules := models.AgreementAt(agreements, t)
+		rules.HourlyRates = rates
+		return rules
+	}, nil
+}
+
+// paidProject is a paid project with its hours and expenses totalled per
+// person: all the split needs, read in one query (qPaidProjects)
+type paidProject struct {
+	models.Project
+	hours    models.PerPerson[float64]
+	expenses models.PerPerson[int64]
+}
+
+// paidProjectScanner for DRY row scanning
+type paidProjectScanner struct {
+	dest *paidProject
+}
+
+// paidProjectFields lists a project's columns, then the per-person totals
+// joined in by qPaidProjects as JSON objects keyed by person ID
+func paidProjectFields(p *paidProject) []field {
+	return append(projectFields(&p.Project),
+		field{"c.hours", jsonText{&p.hours}}, field{"e.expenses", jsonText{&p.expenses}})
+}
+
+func (s paidProjectScanner) Scan(rows *sql.Rows) error {
+	return scanInto(rows.Scan, paidProjectFields(s.dest))
+}
+
+// paidProjects returns every paid project with its per-person totals
+func (db *DB) paidProjects() ([]paidProject, error) {
+	rows, err := db.Query(qPaidProjects)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows, func() *paidProject { return &paidProject{} },
+		func(p *paidProject) scanner { return paidProjectScanner{p} })
+}
+
+// split divides the project under rules, as CalcRevenueSplitWith would with
+// its contribution and expense rows
+func (p *paidProject) split(rules models.SplitRules) *models.RevenueSplit {
+	var contribs []models.Contribution
+	for id, hours := range p.hours {
+		contribs = append(contribs, models.Contribution{ProjectID: p.ID, PersonID: id, Hours: hours})
+	}
+	var expenses []models.Expense
+	for id, cents := range p.expenses {
+		expenses = append(expenses, models.Expense{ProjectID: p.ID, PayerID: id, AmountCents: cents})
+	}
+	return CalcRevenueSplitWith(&p.Project, contribs, expenses, rules)
+}
+
+// ProjectSplit divides one project as the metrics do: under the agreement in
+// force when it was paid (or created, if it isn't yet), with its hours and
+// expenses
+func (db *DB) ProjectSplit(p *models.Project) (*models.RevenueSplit, error) {
+	contribs, err := db.GetContributions(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	expenses, err := db.ListExpenses(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	rulesAt, err := db.SplitRules()
+	if err != nil {
+		return nil, err
+	}
+	return CalcRevenueSplitWith(p, contribs, expenses, rulesAt(p.PaymentTime())), nil
+}
+
+// CalcRevenueSplit determines revenue sharing based on the project's split
+// override, hours or ownership. Out-of-pocket expenses are reimbursed to
+// their payer first; only the remaining profit is split.
+func CalcRevenueSplit(p *models.Project, contribs []models.Contribution, expenses []models.Expense) *models.RevenueSplit {
+	return CalcRevenueSplitWith(p, contribs, expenses, models.SplitRules{})
+}
+
+// CalcRevenueSplitWith is CalcRevenueSplit under the given rules. It is a
+// pure calculation, so it also serves what-if scenarios. It works in whole
+// öre throughout and the shares always add up to the revenue (see allocate).
+func CalcRevenueSplitWith(p *models.Project, contribs []models.Contribution, expenses []models.Expense, rules models.SplitRules) *models.RevenueSplit {
+	if p.RevenueCents <= 0 {
+		return &models.RevenueSplit{Method: "none"}
+	}
+
+	paid := models.PerPerson[int64]{}
+	if rules.ExpensePolicy != models.ExpensePayerBears {
+		paid = reimburse(p, reimbursements(expenses))
+	}
+
+	profit := *p
+	profit.RevenueCents = p.RevenueCents - paid.Total()
+	fees := splitFee(p, int64(math.Round(float64(profit.RevenueCents)*rules.FinderFeePercent/100)))
+	profit.RevenueCents -= fees.Total()
+
+	var split *models.RevenueSplit
+	switch {
+	case profit.RevenueCents > 0 && p.SplitOverride.Total() > 0:
+		split = &models.RevenueSplit{ShareCents: allocate(p, profit.RevenueCents, p.SplitOverride), Method: "override"}
+	case rules.FixedSplit && profit.RevenueCents > 0 && rules.Percents.Total() > 0:
+		split = &models.RevenueSplit{ShareCents: allocate(p, profit.RevenueCents, rules.Percents), Method: "percent"}
+	default:
+		split = splitProfit(&profit, contribs, rules.HourlyRates)
+	}
+	if split.ShareCents == nil {
+		split.ShareCents = models.PerPerson[int64]{}
+	}
+	split.ShareCents.Add(paid)
+	split.ShareCents.Add(fees)
+	split.ReimbursedCents = paid
+	split.FeeCents = fees
+	return split
+}
+
+// deductOverhead takes overhead expenses off the top of shares (which must
+// not include adjusting entries yet) and returns how much it took. Each
+// expense is reimbursed to its payer, unless the agreement in force when it
+// was spent has payers bear their expenses, and its cost comes off everyone's
+// profit (share less reimbursements) pro rata, so the shares still add up to
+// the revenue. With no profit to take it from, the payer bears it.
+func deductOverhead(shares, reimbursed models.PerPerson[int64], overhead []models.Expense, rulesAt func(time.Time) models.SplitRules) int64 {
+	weights := models.PerPerson[float64]{}
+	var ids []int64
+	for id, cents := range shares {
+		if profit := cents - reimbursed[id]; profit > 0 {
+			weights[id] = float64(profit)
+			ids = append(ids, id)
+		}
+	}
+
+	var total int64
+	for _, e := range overhead {
+		if e.AmountCents <= 0 || rulesAt(e.SpentOn).ExpensePolicy == models.ExpensePayerBears {
+			continue
+		}
+		// Leftover öre rotate between everyone with a share, by expense ID
+		p, w := &models.Project{ID: e.ID, SecuredBy: ids}, weights
+		if len(ids) == 0 {
+			p.SecuredBy, w = []int64{e.PayerID}, models.PerPerson[float64]{e.PayerID: 1}
+		}
+		paid := models.PerPerson[int64]{e.PayerID: e.AmountCents}
+		shares.Add(paid)
+		reimbursed.Add(paid)
+		shares.Sub(allocate(p, e.AmountCents, w))
+		total += e.AmountCents
+	}
+	return total
+}
+
+// allocate divides cents between people in proportion to their weights.
+// Every part is rounded down to whole öre and the öre left over (fewer than
+// one per person) go one each to whoever secured the project, then to the
+// others. When several secured it, who goes first rotates with the project
+// ID, so the leftovers even out over projects.
+func allocate(p *models.Project, cents int64, weights models.PerPerson[float64]) models.PerPerson[int64] {
+	total := weights.Total()
+	if total <= 0 {
+		return models.PerPerson[int64]{}
+	}
+
+	parts := models.PerPerson[int64]{}
+	var securers, others []int64
+	rest := cents
+	for id, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		parts[id] = int64(math.Floor(float64(cents) * w / total))
+		rest -= parts[id]
+		if p.SecuredByPerson(id) {
+			securers = append(securers, id)
+		} else {
+			others = append(others, id)
+		}
+	}
+
+	slices.Sort(securers)
+	slices.Sort(others)
+	if n := int64(len(securers)); n > 0 {
+		k := ((p.ID-1)%n + n) % n
+		securers = append(securers[k:], securers[:k]...)
+	}
+	order := append(securers, others...)
+	for i := 0; rest > 0; i++ {
+		parts[order[i%len(order)]]++
+		rest--
+	}
+	return parts
+}
+
+// securerWeights weighs everyone who secured the project equally
+func securerWeights(p *models.Project) models.PerPerson[float64] {
+	weights := models.PerPerson[float64]{}
+	for _, id := range p.SecuredBy {
+		weights[id] = 1
+	}
+	return weights
+}
+
+// splitFee pays a finder's fee to whoever secured the project, shared
+// equally when several did
+func splitFee(p *models.Project, fee int64) models.PerPerson[int64] {
+	return allocate(p, fee, securerWeights(p))
+}
+
+// reimbursements totals expenses per payer
+func reimbursements(expenses []models.Expense) models.PerPerson[int64] {
+	owed := models.PerPerson[int64]{}
+	for _, e := range expenses {
+		if e.AmountCents > 0 {
+			owed[e.PayerID] += e.AmountCents
+		}
+	}
+	return owed
+}
+
+// reimburse pays back expenses out of revenue; if revenue cannot cover them
+// all, it is shared pro rata to what each payer is owed
+func reimburse(p *models.Project, owed models.PerPerson[int64]) models.PerPerson[int64] {
+	if owed.Total() <= p.RevenueCents {
+		return owed
+	}
+	weights := models.PerPerson[float64]{}
+	for id, cents := range owed {
+		weights[id] = float64(cents)
+	}
+	return allocate(p, p.RevenueCents, weights)
+}
+
+// splitProfit splits revenue (after reimbursements) by hours, weighed by
+// hourly rate when everyone who logged hours has one, or by ownership
+func splitProfit(p *models.Project, contribs []models.Contribution, rates models.PerPerson[int64]) *models.RevenueSplit {
+	if p.RevenueCents <= 0 {
 		return &models.RevenueSplit{Method: "none"}
 	}
 
 	// Extract hours
-	var noorHours, ahmadHours float64
+	hours := models.PerPerson[float64]{}
 	for _, c := range contribs {
-		switch c.Owner {
-		case models.OwnerNoor:
-			noorHours = c.Hours
-		case models.OwnerAhmad:
-			ahmadHours = c.Hours
+		if c.Hours > 0 {
+			hours[c.PersonID] += c.Hours
 		}
 	}
 
-	// If both logged hours, use hours-based split
-	if noorHours > 0 && ahmadHours > 0 {
-		total := noorHours + ahmadHours
-		return &models.RevenueSplit{
-			NoorShare:  p.Revenue * (noorHours / total),
-			AhmadShare: p.Revenue * (ahmadHours / total),
-			Method:     "hours",
+	// If two or more people logged hours, use hours-based split
+	if len(hours) > 1 {
+		if value, ok := hoursValue(hours, rates); ok {
+			return &models.RevenueSplit{ShareCents: allocate(p, p.RevenueCents, value), Method: "rates", Rates: rates}
 		}
+		return &models.RevenueSplit{ShareCents: allocate(p, p.RevenueCents, hours), Method: "hours"}
 	}
 
 	// Fall back to ownership-based split
 	return splitByOwner(p)
 }
 
-// splitByOwner calculates revenue based on who secured the project
-func splitByOwner(p *models.Project) *models.RevenueSplit {
-	switch p.SecuredBy {
-	case models.OwnerNoor:
-		return &models.RevenueSplit{NoorShare: p.Revenue, AhmadShare: 0, Method: "owner"}
-	case models.OwnerAhmad:
-		return &models.RevenueSplit{NoorShare: 0, AhmadShare: p.Revenue, Method: "owner"}
-	default: // both
-		half := p.Revenue / 2
-		return &models.RevenueSplit{NoorShare: half, AhmadShare: half, Method: "owner"}
+// hoursValue weighs each person's hours by their hourly rate; ok is false if
+// anyone who logged hours has no rate
+func hoursValue(hours models.PerPerson[float64], rates models.PerPerson[int64]) (models.PerPerson[float64], bool) {
+	value := models.PerPerson[float64]{}
+	for id, h := range hours {
+		if rates[id] <= 0 {
+			return nil, false
+		}
+		value[id] = h * float64(rates[id])
 	}
+	return value, true
+}
+
+// splitByOwner shares revenue equally between whoever secured the project
+func splitByOwner(p *models.Project) *models.RevenueSplit {
+	return &models.RevenueSplit{ShareCents: allocate(p, p.RevenueCents, securerWeights(p)), Method: "owner"}
 }
@@ -0,0 +1,228 @@
+package store
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+func TestAllocate(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		project models.Project
+		cents   int64
+		weights models.PerPerson[float64]
+		want    models.PerPerson[int64]
+	}{
+		{"even", models.Project{ID: 1, SecuredBy: []int64{1, 2}}, 100,
+			models.PerPerson[float64]{1: 1, 2: 1}, models.PerPerson[int64]{1: 50, 2: 50}},
+		{"leftover to the first securer", models.Project{ID: 1, SecuredBy: []int64{1, 2}}, 101,
+			models.PerPerson[float64]{1: 1, 2: 1}, models.PerPerson[int64]{1: 51, 2: 50}},
+		{"leftover rotated by project ID", models.Project{ID: 2, SecuredBy: []int64{1, 2}}, 101,
+			models.PerPerson[float64]{1: 1, 2: 1}, models.PerPerson[int64]{1: 50, 2: 51}},
+		{"securers before others", models.Project{ID: 1, SecuredBy: []int64{2}}, 101,
+			models.PerPerson[float64]{1: 1, 2: 1}, models.PerPerson[int64]{1: 50, 2: 51}},
+		{"thirds", models.Project{ID: 2, SecuredBy: []int64{1, 2, 3}}, 100,
+			models.PerPerson[float64]{1: 1, 2: 1, 3: 1}, models.PerPerson[int64]{1: 33, 2: 34, 3: 33}},
+		{"two leftover öre", models.Project{ID: 3, SecuredBy: []int64{1, 2, 3}}, 101,
+			models.PerPerson[float64]{1: 1, 2: 1, 3: 1}, models.PerPerson[int64]{1: 34, 2: 33, 3: 34}},
+		{"weighted", models.Project{ID: 1, SecuredBy: []int64{1}}, 1000,
+			models.PerPerson[float64]{1: 3, 2: 1}, models.PerPerson[int64]{1: 750, 2: 250}},
+		{"zero weights left out", models.Project{ID: 1, SecuredBy: []int64{1}}, 100,
+			models.PerPerson[float64]{1: 1, 2: 0}, models.PerPerson[int64]{1: 100}},
+		{"nothing to divide", models.Project{ID: 1, SecuredBy: []int64{1, 2}}, 0,
+			models.PerPerson[float64]{1: 1, 2: 1}, models.PerPerson[int64]{1: 0, 2: 0}},
+		{"negative amount", models.Project{ID: 1, SecuredBy: []int64{1, 2}}, -101,
+			models.PerPerson[float64]{1: 1, 2: 1}, models.PerPerson[int64]{1: -50, 2: -51}},
+		{"no weights", models.Project{ID: 1, SecuredBy: []int64{1}}, 100,
+			models.PerPerson[float64]{}, models.PerPerson[int64]{}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := allocate(&tt.project, tt.cents, tt.weights)
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("allocate(%d) = %v, want %v", tt.cents, got, tt.want)
+			}
+			if len(tt.want) > 0 && got.Total() != tt.cents {
+				t.Errorf("parts add up to %d, want %d", got.Total(), tt.cents)
+			}
+		})
+	}
+}
+
+// TestAllocateRotation checks that the leftover öre go round the securers
+// in project ID order, the same on every run
+func TestAllocateRotation(t *testing.T) {
+	weights := models.PerPerson[float64]{1: 1, 2: 1, 3: 1}
+	for range 20 {
+		for id, want := range map[int64]int64{1: 1, 2: 2, 3: 3, 4: 1, 5: 2, 6: 3} {
+			parts := allocate(&models.Project{ID: id, SecuredBy: []int64{3, 1, 2}}, 100, weights)
+			for person, cents := range parts {
+				if (cents == 34) != (person == want) {
+					t.Fatalf("project %d: %v, want the öre to go to %d", id, parts, want)
+				}
+			}
+		}
+	}
+}
+
+func TestCalcRevenueSplitWith(t *testing.T) {
+	hours := func(h1, h2 float64) []models.Contribution {
+		return []models.Contribution{{PersonID: 1, Hours: h1}, {PersonID: 2, Hours: h2}}
+	}
+	for _, tt := range []struct {
+		name       string
+		revenue    int64
+		contribs   []models.Contribution
+		expenses   []models.Expense
+		rules      models.SplitRules
+		securedBy  []int64 // nil: both
+		wantMethod string
+		want       models.PerPerson[int64]
+	}{
+		{"no revenue", 0, hours(1, 1), nil, models.SplitRules{}, nil, "none", nil},
+		{"negative revenue", -5000, hours(1, 1), nil, models.SplitRules{}, nil, "none", nil},
+		{"owners", 100001, nil, nil, models.SplitRules{}, nil, "owner", models.PerPerson[int64]{1: 50001, 2: 50000}},
+		{"hours", 100000, hours(3, 1), nil, models.SplitRules{}, nil, "hours", models.PerPerson[int64]{1: 75000, 2: 25000}},
+		{"rates", 100000, hours(1, 1), nil, models.SplitRules{HourlyRates: models.PerPerson[int64]{1: 30000, 2: 10000}},
+			nil, "rates", models.PerPerson[int64]{1: 75000, 2: 25000}},
+		{"percent", 100000, hours(3, 1), nil, models.SplitRules{FixedSplit: true, Percents: models.PerPerson[float64]{1: 60, 2: 40}},
+			nil, "percent", models.PerPerson[int64]{1: 60000, 2: 40000}},
+		{"expense reimbursed first", 100000, hours(1, 1), []models.Expense{{PayerID: 2, AmountCents: 10000}},
+			models.SplitRules{}, nil, "hours", models.PerPerson[int64]{1: 45000, 2: 55000}},
+		{"payer bears the expense", 100000, hours(1, 1), []models.Expense{{PayerID: 2, AmountCents: 10000}},
+			models.SplitRules{ExpensePolicy: models.ExpensePayerBears}, nil, "hours", models.PerPerson[int64]{1: 50000, 2: 50000}},
+		{"finder's fee", 100000, hours(1, 1), []models.Expense{{PayerID: 2, AmountCents: 10000}},
+			models.SplitRules{FinderFeePercent: 10}, []int64{1}, "hours", models.PerPerson[int64]{1: 49500, 2: 50500}},
+		{"expenses beyond revenue", 1001, hours(1, 1), []models.Expense{{PayerID: 1, AmountCents: 700}, {PayerID: 2, AmountCents: 700}},
+			models.SplitRules{}, nil, "none", models.PerPerson[int64]{1: 501, 2: 500}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &models.Project{ID: 1, RevenueCents: tt.revenue, SecuredBy: tt.securedBy}
+			if p.SecuredBy == nil {
+				p.SecuredBy = []int64{1, 2}
+			}
+			split := CalcRevenueSplitWith(p, tt.contribs, tt.expenses, tt.rules)
+			if split.Method != tt.wantMethod {
+				t.Errorf("method %q, want %q", split.Method, tt.wantMethod)
+			}
+			if !maps.Equal(split.ShareCents, tt.want) {
+				t.Errorf("shares %v, want %v", split.ShareCents, tt.want)
+			}
+			if tt.revenue > 0 && split.ShareCents.Total() != tt.revenue {
+				t.Errorf("shares add up to %d, want the revenue %d", split.ShareCents.Total(), tt.revenue)
+			}
+		})
+	}
+}
+
+func TestDeductOverhead(t *testing.T) {
+	reimburse := func(time.Time) models.SplitRules { return models.SplitRules{} }
+	payerBears := func(time.Time) models.SplitRules {
+		return models.SplitRules{ExpensePolicy: models.ExpensePayerBears}
+	}
+	for _, tt := range []struct {
+		name       string
+		shares     models.PerPerson[int64]
+		reimbursed models.PerPerson[int64]
+		overhead   []models.Expense
+		rulesAt    func(time.Time) models.SplitRules
+		want       models.PerPerson[int64]
+		wantTotal  int64
+	}{
+		{"pro rata to profit", models.PerPerson[int64]{1: 60000, 2: 40000}, models.PerPerson[int64]{},
+			[]models.Expense{{ID: 1, PayerID: 2, AmountCents: 10000}}, reimburse,
+			models.PerPerson[int64]{1: 54000, 2: 46000}, 10000},
+		{"reimbursements aren't profit", models.PerPerson[int64]{1: 60000, 2: 40000}, models.PerPerson[int64]{2: 20000},
+			[]models.Expense{{ID: 1, PayerID: 1, AmountCents: 8000}}, reimburse,
+			models.PerPerson[int64]{1: 62000, 2: 38000}, 8000},
+		{"overhead beyond revenue", models.PerPerson[int64]{1: 500, 2: 500}, models.PerPerson[int64]{},
+			[]models.Expense{{ID: 1, PayerID: 1, AmountCents: 5000}}, reimburse,
+			models.PerPerson[int64]{1: 3000, 2: -2000}, 5000},
+		{"no profit: the payer bears it", models.PerPerson[int64]{1: 1000}, models.PerPerson[int64]{1: 1000},
+			[]models.Expense{{ID: 1, PayerID: 1, AmountCents: 300}}, reimburse,
+			models.PerPerson[int64]{1: 1000}, 300},
+		{"payers bear their expenses", models.PerPerson[int64]{1: 600, 2: 400}, models.PerPerson[int64]{},
+			[]models.Expense{{ID: 1, PayerID: 2, AmountCents: 100}}, payerBears,
+			models.PerPerson[int64]{1: 600, 2: 400}, 0},
+		{"zero and negative amounts skipped", models.PerPerson[int64]{1: 600, 2: 400}, models.PerPerson[int64]{},
+			[]models.Expense{{ID: 1, PayerID: 2}, {ID: 2, PayerID: 1, AmountCents: -100}}, reimburse,
+			models.PerPerson[int64]{1: 600, 2: 400}, 0},
+		{"leftover öre rotate by expense ID", models.PerPerson[int64]{1: 500, 2: 500}, models.PerPerson[int64]{},
+			[]models.Expense{{ID: 1, PayerID: 1, AmountCents: 3}, {ID: 2, PayerID: 1, AmountCents: 3}}, reimburse,
+			models.PerPerson[int64]{1: 503, 2: 497}, 6},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			before := tt.shares.Total()
+			total := deductOverhead(tt.shares, tt.reimbursed, tt.overhead, tt.rulesAt)
+			if total != tt.wantTotal {
+				t.Errorf("took %d, want %d", total, tt.wantTotal)
+			}
+			if !maps.Equal(tt.shares, tt.want) {
+				t.Errorf("shares %v, want %v", tt.shares, tt.want)
+			}
+			if tt.shares.Total() != before {
+				t.Errorf("shares add up to %d after overhead, want %d as before", tt.shares.Total(), before)
+			}
+		})
+	}
+}
+
+// BenchmarkGetMetrics times the dashboard's metrics over paid projects, each
+// with hours from every partner and an expense, so a regression to
+// per-project queries shows up as time growing with the project count
+func BenchmarkGetMetrics(b *testing.B) {
+	for _, n := range []int{200, 2000} {
+		b.Run(fmt.Sprintf("projects=%d", n), func(b *testing.B) {
+			db := seedPaidProjects(b, n)
+			for b.Loop() {
+				if _, err := db.GetMetrics(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// seedPaidProjects opens a scratch database with n paid projects
+func seedPaidProjects(tb testing.TB, n int) *DB {
+	tb.Helper()
+	db, err := New(filepath.Join(tb.TempDir(), "bench.db"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	people, err := db.ListPeople()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if len(people) == 0 {
+		tb.Fatal("no people seeded")
+	}
+	var everyone []int64
+	for _, person := range people {
+		everyone = append(everyone, person.ID)
+	}
+	for i := range n {
+		p := &models.Project{Client: fmt.Sprintf("Client %d", i%50), RevenueCents: int64(1000+i%9000) * 100,
+			Status: models.StatusPaid, SecuredBy: everyone}
+		if err := db.CreateProject(p); err != nil {
+			tb.Fatal(err)
+		}
+		for k, person := range people {
+			c := models.Contribution{ProjectID: p.ID, PersonID: person.ID, Hours: float64(1 + i%(7-2*(k%2)))}
+			if err := db.SetContribution(&c); err != nil {
+				tb.Fatal(err)
+			}
+		}
+		e := &models.Expense{ProjectID: p.ID, PayerID: people[0].ID, Description: "bench", AmountCents: 5000, SpentOn: time.Now()}
+		if err := db.CreateExpense(e); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return db
+}
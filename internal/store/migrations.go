@@ -0,0 +1,753 @@
+// store/migrations.go - Versioned schema changes (PRAGMA user_version)
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrations are applied in order on top of the base schema in migrate().
+// Never edit or reorder an entry once released; append a new one instead.
+var migrations = []string{
+	// 1: date contributions so they can be exported as time entries
+	`ALTER TABLE contributions ADD COLUMN updated_at DATETIME;
+	UPDATE contributions SET updated_at = (SELECT created_at FROM projects WHERE projects.id = contributions.project_id);`,
+
+	// 2: project checklists, seeded with the standard kickoff items
+	`CREATE TABLE checklist_templates (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		stage TEXT NOT NULL,
+		title TEXT NOT NULL,
+		critical INTEGER NOT NULL DEFAULT 0,
+		position INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE TABLE checklist_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+		stage TEXT NOT NULL,
+		title TEXT NOT NULL,
+		critical INTEGER NOT NULL DEFAULT 0,
+		done INTEGER NOT NULL DEFAULT 0,
+		done_at DATETIME,
+		position INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX idx_checklist_items_project ON checklist_items(project_id);
+	INSERT INTO checklist_templates (stage, title, critical, position) VALUES
+		('kickoff', 'Contract signed', 1, 1),
+		('kickoff', 'Deposit invoiced', 1, 2),
+		('kickoff', 'Repo created', 0, 3),
+		('kickoff', 'Access received', 0, 4);`,
+
+	// 3: standard definition-of-done items
+	`INSERT INTO checklist_templates (stage, title, critical, position) VALUES
+		('delivery', 'Tests pass', 1, 1),
+		('delivery', 'Handover doc sent', 1, 2),
+		('delivery', 'Invoice issued', 1, 3);`,
+
+	// 4: the activity feed becomes the audit log (base schema creates audit_log);
+	// activity is created first so fresh databases can run this too
+	`CREATE TABLE IF NOT EXISTS activity (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_id INTEGER NOT NULL,
+		client TEXT NOT NULL,
+		action TEXT NOT NULL,
+		summary TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO audit_log (id, project_id, client, action, summary, created_at)
+		SELECT id, project_id, client, action, summary, created_at FROM activity;
+	DROP TABLE activity;`,
+
+	// 5: undo/redo history on top of the audit log
+	`ALTER TABLE audit_log ADD COLUMN user_id INTEGER;
+	ALTER TABLE audit_log ADD COLUMN snapshot_before TEXT;
+	ALTER TABLE audit_log ADD COLUMN snapshot_after TEXT;
+	ALTER TABLE audit_log ADD COLUMN reverted_by INTEGER;
+	CREATE INDEX idx_audit_log_user ON audit_log(user_id);`,
+
+	// 6: configurable statuses; projects is rebuilt to swap the hard-coded
+	// CHECK for a reference to the statuses table
+	`CREATE TABLE statuses (
+		key TEXT PRIMARY KEY,
+		label TEXT NOT NULL,
+		color TEXT NOT NULL DEFAULT '',
+		position INTEGER NOT NULL DEFAULT 0,
+		is_terminal INTEGER NOT NULL DEFAULT 0
+	);
+	INSERT INTO statuses (key, label, color, position, is_terminal) VALUES
+		('new', 'New', '#4a90e2', 1, 0),
+		('in_progress', 'In Progress', '#f5a623', 2, 0),
+		('done', 'Done', '#7ed321', 3, 0),
+		('paid', 'Paid', '#2ecc71', 4, 1);
+	CREATE TABLE projects_new (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		client TEXT NOT NULL,
+		description TEXT,
+		revenue REAL NOT NULL DEFAULT 0.0,
+		status TEXT NOT NULL DEFAULT 'new' REFERENCES statuses(key),
+		secured_by TEXT NOT NULL CHECK(secured_by IN ('noor', 'ahmad', 'both')),
+		stripe_payment_id TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO projects_new (id, client, description, revenue, status, secured_by, stripe_payment_id, created_at)
+		SELECT id, client, description, revenue, status, secured_by, stripe_payment_id, created_at FROM projects;
+	DROP TABLE projects;
+	ALTER TABLE projects_new RENAME TO projects;
+	CREATE INDEX idx_projects_status ON projects(status);
+	CREATE INDEX idx_projects_stripe ON projects(stripe_payment_id);`,
+
+	// 7: versioned owner agreements, picked by payment time. Existing paid
+	// projects count as paid when created; the seed is the original terms.
+	`ALTER TABLE projects ADD COLUMN paid_at DATETIME;
+	UPDATE projects SET paid_at = created_at WHERE status = 'paid';
+	CREATE TABLE agreements (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		effective_from DATE NOT NULL UNIQUE,
+		fixed_split INTEGER NOT NULL DEFAULT 0,
+		noor_percent REAL NOT NULL DEFAULT 0,
+		finder_fee_percent REAL NOT NULL DEFAULT 0,
+		expense_policy TEXT NOT NULL DEFAULT 'reimburse' CHECK(expense_policy IN ('reimburse', 'payer_bears')),
+		notes TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO agreements (effective_from, notes)
+		VALUES ('2000-01-01', 'Original terms: split by hours, else by who secured the project; expenses reimbursed first');`,
+
+	// 8: archiving takes old projects off the board but keeps them in metrics
+	`ALTER TABLE projects ADD COLUMN archived_at DATETIME;
+	CREATE INDEX idx_projects_archived ON projects(archived_at);`,
+
+	// 9: locked months; projects paid in them can't change (see periods.go)
+	`CREATE TABLE period_locks (
+		month DATE PRIMARY KEY,
+		locked_by TEXT NOT NULL DEFAULT '',
+		locked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+
+	// 10: adjusting entries, corrections to locked periods posted when made
+	`CREATE TABLE adjustments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+		posted_on DATE NOT NULL,
+		revenue REAL NOT NULL DEFAULT 0,
+		noor_share REAL NOT NULL DEFAULT 0,
+		ahmad_share REAL NOT NULL DEFAULT 0,
+		reason TEXT NOT NULL,
+		created_by TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX idx_adjustments_project ON adjustments(project_id);
+	CREATE INDEX idx_adjustments_posted ON adjustments(posted_on);`,
+
+	// 11: coordination between instances sharing the database: expiring
+	// leases (one job runner per interval) and processed webhook event IDs
+	`CREATE TABLE leases (
+		name TEXT PRIMARY KEY,
+		holder TEXT NOT NULL,
+		expires_at DATETIME NOT NULL
+	);
+	CREATE TABLE webhook_events (
+		id TEXT PRIMARY KEY,
+		received_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+
+	// 12: shared state so restarts and extra instances keep users logged in
+	// (sessions, by token hash) and see the same cached values
+	`CREATE TABLE sessions (
+		token_hash TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		expires_at DATETIME NOT NULL
+	);
+	CREATE INDEX idx_sessions_expires ON sessions(expires_at);
+	CREATE TABLE cache_entries (
+		key TEXT PRIMARY KEY,
+		value BLOB NOT NULL,
+		expires_at DATETIME NOT NULL
+	);`,
+
+	// 13: per-project comment threads; the author's name is kept if the user
+	// is deleted
+	`CREATE TABLE comments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+		author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		author TEXT NOT NULL,
+		body TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX idx_comments_project ON comments(project_id, created_at);`,
+
+	// 14: per-client payment branding, matched to projects by client name
+	`CREATE TABLE clients (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+		statement_descriptor TEXT NOT NULL DEFAULT '',
+		logo_url TEXT NOT NULL DEFAULT '',
+		thank_you TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+
+	// 15: projects quoted in a foreign currency keep the original amount next
+	// to the converted revenue; rates are entered by owners
+	`ALTER TABLE projects ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+	ALTER TABLE projects ADD COLUMN original_amount REAL NOT NULL DEFAULT 0;
+	CREATE TABLE exchange_rates (
+		currency TEXT PRIMARY KEY,
+		rate REAL NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+
+	// 16: a copy of the dashboard metrics per day, for comparisons and trends
+	`CREATE TABLE stats_history (
+		day DATE PRIMARY KEY,
+		total_revenue REAL NOT NULL DEFAULT 0,
+		noor_share REAL NOT NULL DEFAULT 0,
+		ahmad_share REAL NOT NULL DEFAULT 0,
+		noor_reimbursed REAL NOT NULL DEFAULT 0,
+		ahmad_reimbursed REAL NOT NULL DEFAULT 0,
+		open_projects INTEGER NOT NULL DEFAULT 0
+	);`,
+
+	// 17: amounts in integer cents (öre) instead of floating-point kronor,
+	// rounded to the öre once here. Adjusting entries that balanced to the
+	// öre still balance exactly, undo snapshots are converted to match and
+	// cached metrics in the old shape are dropped.
+	`ALTER TABLE projects ADD COLUMN revenue_cents INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE projects ADD COLUMN original_cents INTEGER NOT NULL DEFAULT 0;
+	UPDATE projects SET revenue_cents = CAST(ROUND(revenue * 100) AS INTEGER),
+		original_cents = CAST(ROUND(original_amount * 100) AS INTEGER);
+	ALTER TABLE projects DROP COLUMN revenue;
+	ALTER TABLE projects DROP COLUMN original_amount;
+
+	ALTER TABLE adjustments ADD COLUMN revenue_cents INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE adjustments ADD COLUMN noor_share_cents INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE adjustments ADD COLUMN ahmad_share_cents INTEGER NOT NULL DEFAULT 0;
+	UPDATE adjustments SET revenue_cents = CAST(ROUND(revenue * 100) AS INTEGER),
+		noor_share_cents = CAST(ROUND(noor_share * 100) AS INTEGER);
+	UPDATE adjustments SET ahmad_share_cents = CASE
+		WHEN ABS(noor_share + ahmad_share - revenue) < 0.005 THEN revenue_cents - noor_share_cents
+		ELSE CAST(ROUND(ahmad_share * 100) AS INTEGER) END;
+	ALTER TABLE adjustments DROP COLUMN revenue;
+	ALTER TABLE adjustments DROP COLUMN noor_share;
+	ALTER TABLE adjustments DROP COLUMN ahmad_share;
+
+	CREATE TABLE stats_history_new (
+		day DATE PRIMARY KEY,
+		total_revenue_cents INTEGER NOT NULL DEFAULT 0,
+		noor_share_cents INTEGER NOT NULL DEFAULT 0,
+		ahmad_share_cents INTEGER NOT NULL DEFAULT 0,
+		noor_reimbursed_cents INTEGER NOT NULL DEFAULT 0,
+		ahmad_reimbursed_cents INTEGER NOT NULL DEFAULT 0,
+		open_projects INTEGER NOT NULL DEFAULT 0
+	);
+	INSERT INTO stats_history_new SELECT day, CAST(ROUND(total_revenue * 100) AS INTEGER),
+		CAST(ROUND(noor_share * 100) AS INTEGER), CAST(ROUND(ahmad_share * 100) AS INTEGER),
+		CAST(ROUND(noor_reimbursed * 100) AS INTEGER), CAST(ROUND(ahmad_reimbursed * 100) AS INTEGER),
+		open_projects FROM stats_history;
+	DROP TABLE stats_history;
+	ALTER TABLE stats_history_new RENAME TO stats_history;
+
+	UPDATE audit_log SET snapshot_before = json_remove(json_set(snapshot_before, '$.RevenueCents',
+		CAST(ROUND(json_extract(snapshot_before, '$.Revenue') * 100) AS INTEGER)), '$.Revenue')
+		WHERE json_valid(snapshot_before) AND json_type(snapshot_before, '$.Revenue') IS NOT NULL;
+	UPDATE audit_log SET snapshot_after = json_remove(json_set(snapshot_after, '$.RevenueCents',
+		CAST(ROUND(json_extract(snapshot_after, '$.Revenue') * 100) AS INTEGER)), '$.Revenue')
+		WHERE json_valid(snapshot_after) AND json_type(snapshot_after, '$.Revenue') IS NOT NULL;
+	DELETE FROM cache_entries;`,
+
+	// 18: partners move from the hard-coded noor/ahmad to a people table.
+	// Noor and Ahmad are seeded as people 1 and 2 and every owner column
+	// becomes a person ID: secured_by a JSON array ('both' is [1,2]),
+	// contributions (rebuilt for the new unique key), expense payers and
+	// drivers by ID, and the per-owner amounts on adjustments, agreements and
+	// stats history JSON objects keyed by person ID. Undo snapshots are
+	// converted to match and cached metrics in the old shape are dropped.
+	`CREATE TABLE people (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+		color TEXT NOT NULL DEFAULT '',
+		position INTEGER NOT NULL DEFAULT 0,
+		active INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO people (id, name, color, position) VALUES (1, 'Noor', '#4a90e2', 1), (2, 'Ahmad', '#ff9500', 2);
+
+	ALTER TABLE projects ADD COLUMN secured_by_ids TEXT NOT NULL DEFAULT '[]';
+	UPDATE projects SET secured_by_ids = CASE secured_by WHEN 'noor' THEN '[1]' WHEN 'ahmad' THEN '[2]' ELSE '[1,2]' END;
+	ALTER TABLE projects DROP COLUMN secured_by;
+	ALTER TABLE projects RENAME COLUMN secured_by_ids TO secured_by;
+
+	CREATE TABLE contributions_new (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+		person_id INTEGER NOT NULL REFERENCES people(id),
+		hours REAL DEFAULT 0.0,
+		notes TEXT,
+		updated_at DATETIME,
+		UNIQUE(project_id, person_id)
+	);
+	INSERT INTO contributions_new (id, project_id, person_id, hours, notes, updated_at)
+		SELECT id, project_id, CASE owner WHEN 'noor' THEN 1 ELSE 2 END, hours, notes, updated_at FROM contributions;
+	DROP TABLE contributions;
+	ALTER TABLE contributions_new RENAME TO contributions;
+
+	ALTER TABLE expenses ADD COLUMN payer_id INTEGER REFERENCES people(id);
+	UPDATE expenses SET payer_id = CASE payer WHEN 'noor' THEN 1 ELSE 2 END;
+	ALTER TABLE expenses DROP COLUMN payer;
+	ALTER TABLE travel_log ADD COLUMN person_id INTEGER REFERENCES people(id);
+	UPDATE travel_log SET person_id = CASE owner WHEN 'noor' THEN 1 ELSE 2 END;
+	ALTER TABLE travel_log DROP COLUMN owner;
+
+	ALTER TABLE adjustments ADD COLUMN share_cents TEXT NOT NULL DEFAULT '{}';
+	UPDATE adjustments SET share_cents = json_object('1', noor_share_cents, '2', ahmad_share_cents);
+	ALTER TABLE adjustments DROP COLUMN noor_share_cents;
+	ALTER TABLE adjustments DROP COLUMN ahmad_share_cents;
+	ALTER TABLE agreements ADD COLUMN percents TEXT NOT NULL DEFAULT '{}';
+	UPDATE agreements SET percents = json_object('1', noor_percent, '2', 100 - noor_percent) WHERE fixed_split;
+	ALTER TABLE agreements DROP COLUMN noor_percent;
+	ALTER TABLE stats_history ADD COLUMN share_cents TEXT NOT NULL DEFAULT '{}';
+	ALTER TABLE stats_history ADD COLUMN reimbursed_cents TEXT NOT NULL DEFAULT '{}';
+	UPDATE stats_history SET share_cents = json_object('1', noor_share_cents, '2', ahmad_share_cents),
+		reimbursed_cents = json_object('1', noor_reimbursed_cents, '2', ahmad_reimbursed_cents);
+	ALTER TABLE stats_history DROP COLUMN noor_share_cents;
+	ALTER TABLE stats_history DROP COLUMN ahmad_share_cents;
+	ALTER TABLE stats_history DROP COLUMN noor_reimbursed_cents;
+	ALTER TABLE stats_history DROP COLUMN ahmad_reimbursed_cents;
+
+	UPDATE audit_log SET snapshot_before = json_remove(json_set(snapshot_before,
+		'$.SecuredBy', json(CASE json_extract(snapshot_before, '$.SecuredBy')
+			WHEN 'noor' THEN '[1]' WHEN 'ahmad' THEN '[2]' ELSE '[1,2]' END),
+		'$.Hours', json((SELECT json_group_object(key, value) FROM json_each(json_object(
+			'1', json_extract(snapshot_before, '$.NoorHours'), '2', json_extract(snapshot_before, '$.AhmadHours')))
+			WHERE value != 0))), '$.NoorHours', '$.AhmadHours')
+		WHERE json_valid(snapshot_before) AND json_type(snapshot_before, '$.NoorHours') IS NOT NULL;
+	UPDATE audit_log SET snapshot_after = json_remove(json_set(snapshot_after,
+		'$.SecuredBy', json(CASE json_extract(snapshot_after, '$.SecuredBy')
+			WHEN 'noor' THEN '[1]' WHEN 'ahmad' THEN '[2]' ELSE '[1,2]' END),
+		'$.Hours', json((SELECT json_group_object(key, value) FROM json_each(json_object(
+			'1', json_extract(snapshot_after, '$.NoorHours'), '2', json_extract(snapshot_after, '$.AhmadHours')))
+			WHERE value != 0))), '$.NoorHours', '$.AhmadHours')
+		WHERE json_valid(snapshot_after) AND json_type(snapshot_after, '$.NoorHours') IS NOT NULL;
+	DELETE FROM cache_entries;`,
+
+	// 19: disk use per day (database file and attachments), for storage
+	// quota warnings
+	`CREATE TABLE storage_history (
+		day DATE PRIMARY KEY,
+		db_bytes INTEGER NOT NULL DEFAULT 0,
+		attachment_bytes INTEGER NOT NULL DEFAULT 0
+	);`,
+
+	// 20: per-project split percentages, for deals agreed regardless of hours
+	`ALTER TABLE projects ADD COLUMN split_override TEXT;`,
+
+	// 21: expenses get a category and may be overhead (no project). The
+	// table is rebuilt to drop NOT NULL from project_id; expenses created
+	// from the travel log are categorised as travel, the rest as other.
+	// Metrics (and their daily snapshots) gain an expense total, so cached
+	// metrics are dropped.
+	`CREATE TABLE expenses_new (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+		payer_id INTEGER REFERENCES people(id),
+		description TEXT NOT NULL,
+		category TEXT NOT NULL DEFAULT 'other',
+		amount_cents INTEGER NOT NULL CHECK(amount_cents >= 0),
+		spent_on DATE NOT NULL,
+		receipt_path TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO expenses_new (id, project_id, payer_id, description, category, amount_cents, spent_on, receipt_path, created_at)
+		SELECT id, project_id, payer_id, description,
+			CASE WHEN id IN (SELECT expense_id FROM travel_log WHERE expense_id IS NOT NULL) THEN 'travel' ELSE 'other' END,
+			amount_cents, spent_on, receipt_path, created_at FROM expenses;
+	DROP TABLE expenses;
+	ALTER TABLE expenses_new RENAME TO expenses;
+	CREATE INDEX idx_expenses_project ON expenses(project_id);
+	CREATE INDEX idx_expenses_spent ON expenses(spent_on);
+	ALTER TABLE stats_history ADD COLUMN expense_cents INTEGER NOT NULL DEFAULT 0;
+	DELETE FROM cache_entries;`,
+
+	// 22: money actually paid out to partners, from the business or between
+	// them (settlements); metrics gain what each has received
+	`CREATE TABLE payouts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		person_id INTEGER NOT NULL REFERENCES people(id),
+		from_person_id INTEGER REFERENCES people(id),
+		amount_cents INTEGER NOT NULL CHECK(amount_cents > 0),
+		paid_on DATE NOT NULL,
+		note TEXT NOT NULL DEFAULT '',
+		created_by TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		CHECK(from_person_id IS NULL OR from_person_id != person_id)
+	);
+	CREATE INDEX idx_payouts_paid ON payouts(paid_on);
+	DELETE FROM cache_entries;`,
+
+	// 23: invoices, generated from a project as unnumbered drafts. Issuing
+	// one takes the next number in its year; numbered invoices are never
+	// deleted, so each year's series has no gaps.
+	`CREATE TABLE invoices (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		number TEXT UNIQUE,
+		year INTEGER,
+		seq INTEGER,
+		project_id INTEGER REFERENCES projects(id) ON DELETE SET NULL,
+		client TEXT NOT NULL,
+		lines TEXT NOT NULL DEFAULT '[]',
+		currency TEXT NOT NULL DEFAULT '',
+		vat_percent REAL NOT NULL DEFAULT 25 CHECK(vat_percent >= 0),
+		payment_days INTEGER NOT NULL DEFAULT 30 CHECK(payment_days >= 0),
+		status TEXT NOT NULL DEFAULT 'draft',
+		issue_date DATE,
+		due_date DATE,
+		paid_on DATE,
+		created_by TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(year, seq)
+	);
+	CREATE INDEX idx_invoices_project ON invoices(project_id);
+	CREATE INDEX idx_invoices_status ON invoices(status);`,
+
+	// 24: a billing address per client, and a log of every email sent
+	// (invoices, receipts, invites), failed attempts included
+	`ALTER TABLE clients ADD COLUMN email TEXT NOT NULL DEFAULT '';
+	CREATE TABLE sent_mail (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		recipients TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		project_id INTEGER REFERENCES projects(id) ON DELETE SET NULL,
+		invoice_id INTEGER REFERENCES invoices(id) ON DELETE SET NULL,
+		error TEXT NOT NULL DEFAULT '',
+		sent_by TEXT NOT NULL DEFAULT '',
+		sent_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX idx_sent_mail_invoice ON sent_mail(invoice_id);`,
+
+	// 25: quotes, shared with prospective clients and converted into
+	// projects once accepted
+	`CREATE TABLE quotes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		client TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		lines TEXT NOT NULL DEFAULT '[]',
+		currency TEXT NOT NULL DEFAULT '',
+		secured_by TEXT NOT NULL DEFAULT '[]',
+		valid_until DATE NOT NULL,
+		status TEXT NOT NULL DEFAULT 'draft',
+		project_id INTEGER REFERENCES projects(id) ON DELETE SET NULL,
+		created_by TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX idx_quotes_status ON quotes(status);`,
+
+	// 26: projects carry a VAT rate, and whether their price was agreed
+	// including VAT. Revenue has always been entered excluding VAT (invoices
+	// add 25% on top), so existing projects and their audit snapshots get the
+	// standard rate.
+	`ALTER TABLE projects ADD COLUMN vat_percent REAL NOT NULL DEFAULT 25 CHECK(vat_percent >= 0);
+	ALTER TABLE projects ADD COLUMN vat_inclusive INTEGER NOT NULL DEFAULT 0;
+	UPDATE audit_log SET snapshot_before = json_set(snapshot_before, '$.VATPercent', 25)
+		WHERE json_valid(snapshot_before) AND json_type(snapshot_before, '$.RevenueCents') IS NOT NULL;
+	UPDATE audit_log SET snapshot_after = json_set(snapshot_after, '$.VATPercent', 25)
+		WHERE json_valid(snapshot_after) AND json_type(snapshot_after, '$.RevenueCents') IS NOT NULL;`,
+
+	// 27: how far each invoice has been pushed to Fortnox
+	`ALTER TABLE invoices ADD COLUMN fortnox_number TEXT NOT NULL DEFAULT '';
+	ALTER TABLE invoices ADD COLUMN fortnox_paid INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE invoices ADD COLUMN fortnox_error TEXT NOT NULL DEFAULT '';
+	ALTER TABLE invoices ADD COLUMN fortnox_synced_at DATETIME;`,
+
+	// 28: the forecast: each column's chance of payment, and a project's own
+	// chance (NULL follows its column) and expected payment date
+	`ALTER TABLE statuses ADD COLUMN probability INTEGER NOT NULL DEFAULT 50;
+	UPDATE statuses SET probability = CASE key WHEN 'new' THEN 20 WHEN 'in_progress' THEN 60 WHEN 'done' THEN 90 ELSE 100 END
+		WHERE key IN ('new', 'in_progress', 'done') OR is_terminal = 1;
+	ALTER TABLE projects ADD COLUMN probability INTEGER;
+	ALTER TABLE projects ADD COLUMN expected_on DATE;`,
+
+	// 29: revenue goals, one per period and person (NULL: the combined revenue)
+	`CREATE TABLE goals (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		granularity TEXT NOT NULL CHECK(granularity IN ('month', 'quarter', 'year')),
+		start_on DATE NOT NULL,
+		person_id INTEGER REFERENCES people(id) ON DELETE CASCADE,
+		target_cents INTEGER NOT NULL CHECK(target_cents > 0),
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE UNIQUE INDEX idx_goals_period ON goals(granularity, start_on, COALESCE(person_id, 0));`,
+
+	// 30: when each project entered its status, kept through the move to paid
+	// for the time from pending to paid. Existing projects take their last
+	// logged move into a status other than paid, or their creation.
+	`ALTER TABLE projects ADD COLUMN pending_since DATETIME;
+	UPDATE projects SET pending_since = COALESCE((
+		SELECT MAX(a.created_at) FROM audit_log a
+		WHERE a.project_id = projects.id AND json_valid(a.snapshot_after)
+			AND json_extract(a.snapshot_after, '$.Status') IS NOT 'paid'
+			AND (a.snapshot_before IS NULL OR NOT json_valid(a.snapshot_before)
+				OR json_extract(a.snapshot_before, '$.Status') IS NOT json_extract(a.snapshot_after, '$.Status'))
+	), created_at);`,
+
+	// 31: every client a project or invoice names gets a row, for its report
+	`INSERT INTO clients (name)
+		SELECT client FROM projects WHERE trim(client) != ''
+		UNION SELECT client FROM invoices WHERE trim(client) != ''
+		ON CONFLICT(name) DO NOTHING;`,
+
+	// 32: the built-in lost status, last on the board and never paid, and
+	// why each lost project fell through. A custom status already keyed
+	// lost becomes the built-in one.
+	`INSERT INTO statuses (key, label, color, position, is_terminal, probability)
+		VALUES ('lost', 'Lost', '#e74c3c', (SELECT COALESCE(MAX(position), 0) + 1 FROM statuses), 1, 0)
+		ON CONFLICT(key) DO UPDATE SET is_terminal = 1, probability = 0;
+	ALTER TABLE projects ADD COLUMN lost_reason TEXT NOT NULL DEFAULT '';`,
+
+	// 33: the channel each project's lead came through ('' not recorded)
+	`ALTER TABLE projects ADD COLUMN source TEXT NOT NULL DEFAULT ''
+		CHECK(source IN ('', 'referral', 'website', 'cold_outreach', 'repeat_client'));`,
+
+	// 34: dated time entries; contributions become each person's sum of
+	// them. Every existing contribution is carried over as one entry, dated
+	// when its hours last changed.
+	`CREATE TABLE time_entries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+		person_id INTEGER NOT NULL REFERENCES people(id),
+		worked_on DATE NOT NULL,
+		hours REAL NOT NULL,
+		note TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX idx_time_entries_project ON time_entries(project_id, person_id);
+	CREATE INDEX idx_time_entries_worked ON time_entries(worked_on);
+	INSERT INTO time_entries (project_id, person_id, worked_on, hours, note)
+		SELECT c.project_id, c.person_id, date(COALESCE(c.updated_at, p.created_at)), c.hours, COALESCE(c.notes, '')
+		FROM contributions c JOIN projects p ON p.id = c.project_id
+		WHERE c.hours != 0;`,
+
+	// 35: estimated hours per project, optionally per person (JSON by person ID)
+	`ALTER TABLE projects ADD COLUMN estimated_hours REAL NOT NULL DEFAULT 0 CHECK(estimated_hours >= 0);
+	ALTER TABLE projects ADD COLUMN hour_estimates TEXT;`,
+
+	// 36: billing stages of a project, each paid through its own payment link
+	`CREATE TABLE milestones (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+		title TEXT NOT NULL,
+		amount_cents INTEGER NOT NULL CHECK(amount_cents > 0),
+		due_on DATE NOT NULL,
+		status TEXT NOT NULL DEFAULT 'planned' CHECK(status IN ('planned', 'billed', 'paid')),
+		payment_link_id TEXT NOT NULL DEFAULT '',
+		payment_link_url TEXT NOT NULL DEFAULT '',
+		stripe_payment_id TEXT NOT NULL DEFAULT '',
+		paid_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX idx_milestones_project ON milestones(project_id, due_on);`,
+
+	// 37: subtasks of a project, in order, optionally assigned to a person
+	`CREATE TABLE tasks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+		title TEXT NOT NULL,
+		done BOOLEAN NOT NULL DEFAULT 0,
+		assignee_id INTEGER REFERENCES people(id) ON DELETE SET NULL,
+		position INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX idx_tasks_project ON tasks(project_id, position);`,
+
+	// 38: clients' Stripe subscriptions, billed as retainers
+	`CREATE TABLE retainers (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		client_id INTEGER NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+		subscription_id TEXT NOT NULL UNIQUE,
+		description TEXT NOT NULL DEFAULT '',
+		secured_by TEXT NOT NULL DEFAULT '[]',
+		project_id INTEGER REFERENCES projects(id) ON DELETE SET NULL,
+		status TEXT NOT NULL DEFAULT '',
+		amount_cents INTEGER NOT NULL DEFAULT 0,
+		currency TEXT NOT NULL DEFAULT '',
+		interval TEXT NOT NULL DEFAULT '',
+		current_period_end DATETIME,
+		last_paid_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX idx_retainers_client ON retainers(client_id);`,
+
+	// 39: payment terms per project, for accounts-receivable aging
+	`ALTER TABLE projects ADD COLUMN payment_days INTEGER NOT NULL DEFAULT 30 CHECK(payment_days >= 0);`,
+
+	// 40: budgets of hourly-billed projects and the burn warned about
+	`ALTER TABLE projects ADD COLUMN budget_hours REAL NOT NULL DEFAULT 0 CHECK(budget_hours >= 0);
+	ALTER TABLE projects ADD COLUMN budget_cents INTEGER NOT NULL DEFAULT 0 CHECK(budget_cents >= 0);
+	ALTER TABLE projects ADD COLUMN hourly_cents INTEGER NOT NULL DEFAULT 0 CHECK(hourly_cents >= 0);
+	ALTER TABLE projects ADD COLUMN budget_warned INTEGER NOT NULL DEFAULT 0;`,
+
+	// 41: daily rates fetched from an exchange rate provider, kept so each
+	// day's are fetched once; the stored rates above are updated from them
+	`CREATE TABLE fx_rates (
+		day DATE NOT NULL,
+		currency TEXT NOT NULL,
+		rate REAL NOT NULL CHECK(rate > 0),
+		source TEXT NOT NULL,
+		fetched_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (day, currency)
+	);`,
+
+	// 42: contracts and other documents kept for a client or a project,
+	// versioned by title
+	`CREATE TABLE documents (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		client_id INTEGER REFERENCES clients(id) ON DELETE CASCADE,
+		project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+		kind TEXT NOT NULL DEFAULT 'other',
+		title TEXT NOT NULL,
+		version INTEGER NOT NULL DEFAULT 1,
+		file_name TEXT NOT NULL,
+		path TEXT NOT NULL,
+		size_bytes INTEGER NOT NULL DEFAULT 0,
+		signed_on DATE,
+		expires_on DATE,
+		uploaded_by TEXT NOT NULL DEFAULT '',
+		reminded_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		CHECK ((client_id IS NULL) != (project_id IS NULL))
+	);
+	CREATE INDEX idx_documents_client ON documents(client_id);
+	CREATE INDEX idx_documents_project ON documents(project_id);`,
+
+	// 43: outbound webhooks and the log of what was sent to them, retried
+	// until delivered or out of attempts
+	`CREATE TABLE webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT NOT NULL DEFAULT '[]',
+		enabled INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+		event TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'delivered', 'failed')),
+		attempts INTEGER NOT NULL DEFAULT 0,
+		response_code INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		next_attempt_at DATETIME,
+		delivered_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id);
+	CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);`,
+
+	// 44: receivables already announced as overdue, so each is announced
+	// once per due date (invoice_id 0 for an uninvoiced project)
+	`CREATE TABLE overdue_notices (
+		project_id INTEGER NOT NULL,
+		invoice_id INTEGER NOT NULL DEFAULT 0,
+		due_on DATE NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (project_id, invoice_id, due_on)
+	);`,
+
+	// 45: opting out of the weekly summary email
+	`ALTER TABLE users ADD COLUMN weekly_digest INTEGER NOT NULL DEFAULT 1;`,
+
+	// 46: the provider each email went through, and retries of those that
+	// failed for a reason that may pass (the message is kept until then)
+	`ALTER TABLE sent_mail ADD COLUMN provider TEXT NOT NULL DEFAULT 'smtp';
+	ALTER TABLE sent_mail ADD COLUMN attempts INTEGER NOT NULL DEFAULT 1;
+	ALTER TABLE sent_mail ADD COLUMN next_attempt_at DATETIME;
+	ALTER TABLE sent_mail ADD COLUMN message TEXT;
+	CREATE INDEX idx_sent_mail_retry ON sent_mail(next_attempt_at) WHERE next_attempt_at IS NOT NULL;`,
+
+	// 47: each user's calendar feed token, hashed like API keys; NULL when
+	// the feed is off
+	`ALTER TABLE users ADD COLUMN calendar_token_hash TEXT;
+	CREATE UNIQUE INDEX idx_users_calendar_token ON users(calendar_token_hash) WHERE calendar_token_hash IS NOT NULL;`,
+
+	// 48: attaching the monthly PDF report to the weekly summary
+	`ALTER TABLE users ADD COLUMN monthly_report INTEGER NOT NULL DEFAULT 0;`,
+
+	// 49: each backup stored, or failed, per destination
+	`CREATE TABLE backup_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		destination TEXT NOT NULL,
+		file TEXT NOT NULL DEFAULT '',
+		size_bytes INTEGER NOT NULL DEFAULT 0,
+		ok INTEGER NOT NULL,
+		details TEXT NOT NULL DEFAULT '',
+		trigger TEXT NOT NULL,
+		started_at DATETIME NOT NULL,
+		finished_at DATETIME NOT NULL
+	);
+	CREATE INDEX idx_backup_runs_destination ON backup_runs(destination, ok, started_at);`,
+
+	// 50: the request behind each audit entry, to find it in the server log
+	`ALTER TABLE audit_log ADD COLUMN request_id TEXT;`,
+}
+
+// SchemaVersion returns the number of migrations applied to the database
+func (db *DB) SchemaVersion() (int, error) {
+	var v int
+	err := db.QueryRow(`PRAGMA user_version`).Scan(&v)
+	return v, err
+}
+
+// applyMigrations runs the migrations newer than the stored user_version,
+// up to target. Foreign keys are switched off on the migrating connection
+// so a table can be rebuilt without DROP TABLE cascading into its children
+// (the rebuilt table keeps its ids, so the children's references stay
+// valid).
+func (db *DB) applyMigrations(target int) error {
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version >= target {
+		return nil
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+
+	for i := version; i < target; i++ {
+		if err := applyMigration(ctx, conn, i); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// applyMigration runs migrations[i] in a transaction and bumps user_version
+func applyMigration(ctx context.Context, conn *sql.Conn, i int) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migrations[i]); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
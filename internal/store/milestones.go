@@ -0,0 +1,100 @@
+// store/milestones.go - Project milestones billed in stages
+package store
+
+import (
+	"database/sql"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// milestoneScanner for DRY row scanning
+type milestoneScanner struct {
+	dest *models.Milestone
+}
+
+// milestoneFields lists a milestone's selected columns in scan order
+func milestoneFields(m *models.Milestone) []field {
+	return []field{
+		{"id", &m.ID}, {"project_id", &m.ProjectID}, {"title", &m.Title}, {"amount_cents", &m.AmountCents},
+		{"due_on", &m.DueOn}, {"status", &m.Status}, {"payment_link_id", &m.PaymentLinkID},
+		{"payment_link_url", &m.PaymentLinkURL}, {"stripe_payment_id", &m.StripePaymentID},
+		{"paid_at", opt(&m.PaidAt)}, {"created_at", &m.CreatedAt},
+	}
+}
+
+func (s milestoneScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, milestoneFields(s.dest))
+}
+
+func (s milestoneScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// ListMilestones returns a project's milestones, soonest due first
+func (db *DB) ListMilestones(projectID int64) (models.Milestones, error) {
+	rows, err := db.Query(qMilestonesByProject, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Milestone { return &models.Milestone{} },
+		func(m *models.Milestone) scanner { return milestoneScanner{m} })
+}
+
+// ListAllMilestones returns every project's milestones, soonest due first
+func (db *DB) ListAllMilestones() (models.Milestones, error) {
+	rows, err := db.Query(qMilestonesAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Milestone { return &models.Milestone{} },
+		func(m *models.Milestone) scanner { return milestoneScanner{m} })
+}
+
+// GetMilestone returns a milestone by ID, or nil if it doesn't exist
+func (db *DB) GetMilestone(id int64) (*models.Milestone, error) {
+	m := &models.Milestone{}
+	err := milestoneScanner{m}.scan(db.QueryRow(qMilestoneByID, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return m, err
+}
+
+// CreateMilestone adds a milestone to a project
+func (db *DB) CreateMilestone(m *models.Milestone) error {
+	return db.QueryRow(qMilestoneInsert, m.ProjectID, m.Title, m.AmountCents, m.DueOn.Format(dateLayout),
+		m.Status, m.PaidAt).Scan(&m.ID, &m.CreatedAt)
+}
+
+// UpdateMilestone saves an edited milestone, its payment link and payment
+// included
+func (db *DB) UpdateMilestone(m *models.Milestone) error {
+	_, err := db.Exec(qMilestoneUpdate, m.Title, m.AmountCents, m.DueOn.Format(dateLayout), m.Status,
+		m.PaymentLinkID, m.PaymentLinkURL, m.StripePaymentID, m.PaidAt, m.ID)
+	return err
+}
+
+// SetMilestoneLink records the payment link made for a milestone; a planned
+// milestone becomes billed
+func (db *DB) SetMilestoneLink(id int64, linkID, url string) error {
+	_, err := db.Exec(qMilestoneSetLink, linkID, url, id)
+	return err
+}
+
+// MarkMilestonePaid records a Stripe payment of a milestone
+func (db *DB) MarkMilestonePaid(id int64, paymentID string) error {
+	_, err := db.Exec(qMilestoneMarkPaid, paymentID, id)
+	return err
+}
+
+// DeleteMilestone removes a milestone
+func (db *DB) DeleteMilestone(id int64) error {
+	_, err := db.Exec(qMilestoneDelete, id)
+	return err
+}
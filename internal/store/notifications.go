@@ -0,0 +1,110 @@
+// store/notifications.go - Per-user notification inbox and delivery preferences
+package store
+
+import (
+	"database/sql"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// notificationScanner for DRY row scanning
+type notificationScanner struct {
+	dest *models.Notification
+}
+
+// notificationFields lists a notification's selected columns in scan order
+func notificationFields(n *models.Notification) []field {
+	return []field{
+		{"id", &n.ID}, {"user_id", &n.UserID}, {"kind", &n.Kind}, {"project_id", null(&n.ProjectID)},
+		{"body", &n.Body}, {"queued", &n.Queued}, {"created_at", &n.CreatedAt},
+	}
+}
+
+func (s notificationScanner) Scan(rows *sql.Rows) error {
+	return scanInto(rows.Scan, notificationFields(s.dest))
+}
+
+// GetNotificationPrefs returns a user's configured delivery mode per kind
+func (db *DB) GetNotificationPrefs(userID int64) (map[string]models.DeliveryMode, error) {
+	rows, err := db.Query(qNotificationPrefs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := make(map[string]models.DeliveryMode)
+	for rows.Next() {
+		var kind string
+		var mode models.DeliveryMode
+		if err := rows.Scan(&kind, &mode); err != nil {
+			return nil, err
+		}
+		prefs[kind] = mode
+	}
+	return prefs, rows.Err()
+}
+
+// SetNotificationPref sets how a user receives one kind of notification
+func (db *DB) SetNotificationPref(userID int64, kind string, mode models.DeliveryMode) error {
+	_, err := db.Exec(qNotificationPrefUpsert, userID, kind, mode)
+	return err
+}
+
+// CreateNotification adds an inbox entry, or queues it for the digest
+func (db *DB) CreateNotification(n *models.Notification) error {
+	return db.QueryRow(qNotificationInsert, n.UserID, n.Kind, nullID(n.ProjectID), n.Body, n.Queued).
+		Scan(&n.ID, &n.CreatedAt)
+}
+
+// ListNotifications returns a user's delivered notifications, newest first
+func (db *DB) ListNotifications(userID int64, limit int) ([]models.Notification, error) {
+	return db.queryNotifications(qNotificationsInbox, userID, limit)
+}
+
+// QueuedNotifications returns a user's notifications waiting for the digest, oldest first
+func (db *DB) QueuedNotifications(userID int64) ([]models.Notification, error) {
+	return db.queryNotifications(qNotificationsQueued, userID)
+}
+
+func (db *DB) queryNotifications(query string, args ...any) ([]models.Notification, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Notification { return &models.Notification{} },
+		func(n *models.Notification) scanner { return notificationScanner{n} })
+}
+
+// DeliverDigest stores the composed digest and removes the queued entries it covers
+func (db *DB) DeliverDigest(digest *models.Notification, queued []models.Notification) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow(qNotificationInsert, digest.UserID, digest.Kind, nil, digest.Body, false).
+		Scan(&digest.ID, &digest.CreatedAt); err != nil {
+		return err
+	}
+	for _, n := range queued {
+		if _, err := tx.Exec(qNotificationDelete, n.ID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ClaimOverdueNotice records that r was announced as overdue; false means
+// it already was, for the same due date
+func (db *DB) ClaimOverdueNotice(r models.Receivable) (bool, error) {
+	res, err := db.Exec(qOverdueNoticeInsert, r.ProjectID, r.InvoiceID, r.DueOn.Format(dateLayout))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,84 @@
+// store/payouts.go - Payouts and settlements between partners
+package store
+
+import (
+	"database/sql"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// payoutScanner for DRY row scanning
+type payoutScanner struct {
+	dest *models.Payout
+}
+
+// payoutFields lists a payout's selected columns in scan order
+func payoutFields(p *models.Payout) []field {
+	return []field{
+		{"id", &p.ID}, {"person_id", &p.PersonID}, {"from_person_id", null(&p.FromID)},
+		{"amount_cents", &p.AmountCents}, {"paid_on", &p.PaidOn}, {"note", &p.Note},
+		{"created_by", &p.CreatedBy}, {"created_at", &p.CreatedAt},
+	}
+}
+
+func (s payoutScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, payoutFields(s.dest))
+}
+
+func (s payoutScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// CreatePayout records a payout or, with FromID set, a settlement
+func (db *DB) CreatePayout(p *models.Payout) error {
+	return db.QueryRow(qPayoutInsert, p.PersonID, nullID(p.FromID), p.AmountCents,
+		p.PaidOn.Format(dateLayout), p.Note, p.CreatedBy).Scan(&p.ID, &p.CreatedAt)
+}
+
+// GetPayout fetches a payout by ID
+func (db *DB) GetPayout(id int64) (*models.Payout, error) {
+	p := &models.Payout{}
+	err := payoutScanner{p}.scan(db.QueryRow(qPayoutByID, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// ListPayouts returns every payout and settlement, newest first
+func (db *DB) ListPayouts() ([]models.Payout, error) {
+	rows, err := db.Query(qPayoutsAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Payout { return &models.Payout{} },
+		func(p *models.Payout) scanner { return payoutScanner{p} })
+}
+
+// Received totals what each person has received, net of settlements
+func (db *DB) Received() (models.PerPerson[int64], error) {
+	rows, err := db.Query(qPayoutsReceived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	received := models.PerPerson[int64]{}
+	for rows.Next() {
+		var id, cents int64
+		if err := rows.Scan(&id, &cents); err != nil {
+			return nil, err
+		}
+		received[id] = cents
+	}
+	return received, rows.Err()
+}
+
+// DeletePayout removes a payout recorded by mistake
+func (db *DB) DeletePayout(id int64) error {
+	_, err := db.Exec(qPayoutDelete, id)
+	return err
+}
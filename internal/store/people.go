@@ -0,0 +1,72 @@
+// store/people.go - Partners, who secure projects and share revenue
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// personScanner for DRY row scanning
+type personScanner struct {
+	dest *models.Person
+}
+
+// personFields lists a person's selected columns in scan order
+func personFields(p *models.Person) []field {
+	return []field{
+		{"id", &p.ID}, {"name", &p.Name}, {"color", &p.Color}, {"position", &p.Position},
+		{"active", &p.Active}, {"created_at", &p.CreatedAt},
+	}
+}
+
+func (s personScanner) Scan(rows *sql.Rows) error {
+	return scanInto(rows.Scan, personFields(s.dest))
+}
+
+// ListPeople returns everyone, inactive people included, in display order
+func (db *DB) ListPeople() (models.People, error) {
+	rows, err := db.Query(qPeopleAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Person { return &models.Person{} },
+		func(p *models.Person) scanner { return personScanner{p} })
+}
+
+// CreatePerson adds an active person at the end of the display order
+func (db *DB) CreatePerson(p *models.Person) error {
+	p.Active = true
+	return db.QueryRow(qPersonInsert, p.Name, p.Color).Scan(&p.ID, &p.Position, &p.CreatedAt)
+}
+
+// UpdatePerson saves a person's name, color, position and active flag.
+// There is no delete: splits and history keep referring to past partners.
+func (db *DB) UpdatePerson(p *models.Person) error {
+	_, err := db.Exec(qPersonUpdate, p.Name, p.Color, p.Position, p.Active, p.ID)
+	return err
+}
+
+// HourlyRates returns each person's hourly rate in cents, from settings;
+// people without one are left out
+func (db *DB) HourlyRates() (models.PerPerson[int64], error) {
+	v, err := db.GetSetting(models.SettingHourlyRates, "")
+	if err != nil || v == "" {
+		return models.PerPerson[int64]{}, err
+	}
+	rates := models.PerPerson[int64]{}
+	if err := json.Unmarshal([]byte(v), &rates); err != nil {
+		return nil, fmt.Errorf("hourly rates: %w", err)
+	}
+	return rates, nil
+}
+
+// SetHourlyRates replaces everyone's hourly rates
+func (db *DB) SetHourlyRates(rates models.PerPerson[int64]) error {
+	return db.SetSetting(models.SettingHourlyRates, jsonArg(rates))
+}
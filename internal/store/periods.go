@@ -0,0 +1,94 @@
+// store/periods.go - Locked accounting periods (months)
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// periodLockScanner for DRY row scanning
+type periodLockScanner struct {
+	dest *models.PeriodLock
+}
+
+// periodLockFields lists a lock's selected columns in scan order
+func periodLockFields(l *models.PeriodLock) []field {
+	return []field{{"month", &l.Month}, {"locked_by", &l.LockedBy}, {"locked_at", &l.LockedAt}}
+}
+
+func (s periodLockScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, periodLockFields(s.dest))
+}
+
+func (s periodLockScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// ListPeriodLocks returns the locked months, newest first
+func (db *DB) ListPeriodLocks() ([]models.PeriodLock, error) {
+	rows, err := db.Query(qPeriodLocksAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.PeriodLock { return &models.PeriodLock{} },
+		func(l *models.PeriodLock) scanner { return periodLockScanner{l} })
+}
+
+// PeriodLockAt returns the lock covering t's month, or nil if it is open
+func (db *DB) PeriodLockAt(t time.Time) (*models.PeriodLock, error) {
+	l := &models.PeriodLock{}
+	err := periodLockScanner{l}.scan(db.QueryRow(qPeriodLockByMonth, models.MonthOf(t).Format(dateLayout)).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return l, err
+}
+
+// LockPeriod locks the month containing month
+func (db *DB) LockPeriod(month time.Time, by string) error {
+	_, err := db.Exec(qPeriodLockInsert, models.MonthOf(month).Format(dateLayout), by)
+	return err
+}
+
+// UnlockPeriod reopens the month containing month
+func (db *DB) UnlockPeriod(month time.Time) error {
+	_, err := db.Exec(qPeriodLockDelete, models.MonthOf(month).Format(dateLayout))
+	return err
+}
+
+// ProjectLock returns the lock covering p's payment, or nil unless p was
+// paid in a locked month
+func (db *DB) ProjectLock(p *models.Project) (*models.PeriodLock, error) {
+	if p.PaidAt == nil {
+		return nil, nil
+	}
+	return db.PeriodLockAt(*p.PaidAt)
+}
+
+// guardProject refuses a write to a project paid in a locked month with a
+// *models.PeriodLockedError. changed reports whether the write touches the
+// stored project's payment, revenue or split; nil means any write does.
+// Writers call it inside Tx, so a month can't be locked between the check
+// and the write.
+func (db *DB) guardProject(id int64, changed func(stored *models.Project) (bool, error)) error {
+	stored, err := db.GetProject(id)
+	if err != nil || stored == nil {
+		return err
+	}
+	lock, err := db.ProjectLock(stored)
+	if err != nil || lock == nil {
+		return err
+	}
+	if changed != nil {
+		ok, err := changed(stored)
+		if err != nil || !ok {
+			return err
+		}
+	}
+	return &models.PeriodLockedError{Lock: *lock, Client: stored.Client}
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// TestLockedPeriod checks that a project paid in a locked month keeps its
+// revenue, hours and expenses, and takes them again once the month reopens
+func TestLockedPeriod(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "locks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	people, err := db.ListPeople()
+	if err != nil || len(people) == 0 {
+		t.Fatalf("people: %v, %v", people, err)
+	}
+	payer := people[0].ID
+	p := &models.Project{Client: "Acme", RevenueCents: 100000, Status: models.StatusPaid, SecuredBy: []int64{payer}}
+	if err := db.CreateProject(p); err != nil {
+		t.Fatal(err)
+	}
+	// paid_at is stamped in UTC
+	month := time.Now().UTC()
+	if err := db.LockPeriod(month, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	locked := func(what string, err error) {
+		t.Helper()
+		var lockErr *models.PeriodLockedError
+		if !errors.As(err, &lockErr) {
+			t.Errorf("%s in a locked month: %v, want a PeriodLockedError", what, err)
+		}
+	}
+
+	edit := *p
+	edit.RevenueCents = 200000
+	locked("revenue edit", db.UpdateProject(&edit))
+	locked("hours", db.SetContribution(&models.Contribution{ProjectID: p.ID, PersonID: payer, Hours: 3}))
+	locked("expense", db.CreateExpense(&models.Expense{ProjectID: p.ID, PayerID: payer, Description: "Train",
+		AmountCents: 5000, SpentOn: month}))
+	locked("overhead expense", db.CreateExpense(&models.Expense{PayerID: payer, Description: "Hosting",
+		Category: models.ExpenseHosting, AmountCents: 5000, SpentOn: month}))
+	locked("delete", db.DeleteProject(p.ID))
+
+	// Nothing was written
+	stored, err := db.GetProject(p.ID)
+	if err != nil || stored == nil {
+		t.Fatalf("project after refused writes: %v, %v", stored, err)
+	}
+	if stored.RevenueCents != 100000 {
+		t.Errorf("revenue %d after a refused edit, want 100000", stored.RevenueCents)
+	}
+	contribs, err := db.GetContributions(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range contribs {
+		if c.Hours != 0 {
+			t.Errorf("person %d has %v hours after a refused change", c.PersonID, c.Hours)
+		}
+	}
+	expenses, err := db.ListExpenses(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expenses) != 0 {
+		t.Errorf("%d expenses after a refused insert", len(expenses))
+	}
+
+	// The client and description aren't part of the period's figures
+	edit = *stored
+	edit.Description = "Renamed"
+	if err := db.UpdateProject(&edit); err != nil {
+		t.Errorf("description edit in a locked month: %v", err)
+	}
+
+	if err := db.UnlockPeriod(month); err != nil {
+		t.Fatal(err)
+	}
+	edit.RevenueCents = 200000
+	if err := db.UpdateProject(&edit); err != nil {
+		t.Errorf("revenue edit once reopened: %v", err)
+	}
+	if err := db.SetContribution(&models.Contribution{ProjectID: p.ID, PersonID: payer, Hours: 3}); err != nil {
+		t.Errorf("hours once reopened: %v", err)
+	}
+	if err := db.CreateExpense(&models.Expense{ProjectID: p.ID, PayerID: payer, Description: "Train",
+		AmountCents: 5000, SpentOn: month}); err != nil {
+		t.Errorf("expense once reopened: %v", err)
+	}
+}
+
+// TestLockWaitsForWrite checks that closing a month waits for a write that
+// has passed its locked period check, rather than landing in between
+func TestLockWaitsForWrite(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "race.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	p := &models.Project{Client: "Acme", RevenueCents: 100000, Status: models.StatusPaid, SecuredBy: []int64{1}}
+	if err := db.CreateProject(p); err != nil {
+		t.Fatal(err)
+	}
+	month := time.Now().UTC()
+
+	locked := make(chan error, 1)
+	err = db.Tx(func(tx *DB) error {
+		if err := tx.guardProject(p.ID, nil); err != nil {
+			return err
+		}
+		go func() { locked <- db.LockPeriod(month, "test") }()
+		select {
+		case err := <-locked:
+			t.Errorf("month locked (%v) between the check and the write", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+		_, err := tx.Exec(qProjectSetPaidAt, time.Now().UTC().Format(time.DateTime), p.ID)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-locked; err != nil {
+		t.Fatalf("lock after the write: %v", err)
+	}
+	if err := db.SetPaidAt(p.ID, month); !errors.As(err, new(*models.PeriodLockedError)) {
+		t.Errorf("write after the lock: %v, want a PeriodLockedError", err)
+	}
+}
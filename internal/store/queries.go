@@ -1,49 +1,882 @@
 // store/queries.go - Centralized SQL queries for DRY
 package store
 
-// Project columns for SELECT statements
+import "github.com/noor-latif/fulldash/internal/models"
+
+// Table names
 const (
-	projectColumns = `id, client, description, revenue, status, secured_by, stripe_payment_id, created_at`
-	projectTable   = `projects`
-	
-	contributionColumns = `id, project_id, owner, hours, notes`
-	contributionTable   = `contributions`
+	projectTable           = `projects`
+	contributionTable      = `contributions`
+	timeEntryTable         = `time_entries`
+	milestoneTable         = `milestones`
+	taskTable              = `tasks`
+	retainerTable          = `retainers`
+	expenseTable           = `expenses`
+	travelTable            = `travel_log`
+	settingsTable          = `settings`
+	agreementTable         = `agreements`
+	periodLockTable        = `period_locks`
+	adjustmentTable        = `adjustments`
+	commentTable           = `comments`
+	clientTable            = `clients`
+	exchangeRateTable      = `exchange_rates`
+	fxRateTable            = `fx_rates`
+	documentTable          = `documents`
+	webhookTable           = `webhooks`
+	webhookDeliveryTable   = `webhook_deliveries`
+	overdueNoticeTable     = `overdue_notices`
+	goalTable              = `goals`
+	statsHistoryTable      = `stats_history`
+	storageHistoryTable    = `storage_history`
+	payoutTable            = `payouts`
+	invoiceTable           = `invoices`
+	sentMailTable          = `sent_mail`
+	quoteTable             = `quotes`
+	leaseTable             = `leases`
+	webhookEventTable      = `webhook_events`
+	sessionTable           = `sessions`
+	cacheTable             = `cache_entries`
+	statusTable            = `statuses`
+	personTable            = `people`
+	checklistTemplateTable = `checklist_templates`
+	checklistItemTable     = `checklist_items`
+	automationRuleTable    = `automation_rules`
+	automationRunTable     = `automation_runs`
+	notificationTable      = `notifications`
+	notificationPrefTable  = `notification_prefs`
+	activityTable          = `audit_log`
+	integrityTable         = `integrity_checks`
+	userTable              = `users`
+	apiKeyTable            = `api_keys`
+	drTestTable            = `dr_tests`
+	backupRunTable         = `backup_runs`
+)
+
+// SELECT lists, built from each model's field list (see fields.go) so they
+// always match the scanners
+var (
+	projectColumns           = columns(projectFields(&models.Project{}))
+	contributionColumns      = columns(contributionFields(&models.Contribution{}))
+	expenseColumns           = columns(expenseFields(&models.Expense{}))
+	expenseListColumns       = columns(expenseListFields(&models.Expense{}))
+	travelColumns            = columns(travelFields(&models.TravelEntry{}))
+	agreementColumns         = columns(agreementFields(&models.Agreement{}))
+	periodLockColumns        = columns(periodLockFields(&models.PeriodLock{}))
+	adjustmentColumns        = columns(adjustmentFields(&models.Adjustment{}))
+	commentColumns           = columns(commentFields(&models.Comment{}))
+	clientColumns            = columns(clientFields(&models.Client{}))
+	clientProjectColumns     = columns(clientProjectFields(&models.ClientProject{}))
+	exchangeRateColumns      = columns(exchangeRateFields(&models.ExchangeRate{}))
+	goalColumns              = columns(goalFields(&models.Goal{}))
+	statsSnapshotColumns     = columns(statsSnapshotFields(&models.StatsSnapshot{}))
+	storageUsageColumns      = columns(storageUsageFields(&models.StorageUsage{}))
+	payoutColumns            = columns(payoutFields(&models.Payout{}))
+	invoiceColumns           = columns(invoiceFields(&models.Invoice{}))
+	sentMailColumns          = columns(sentMailFields(&models.SentMail{}))
+	quoteColumns             = columns(quoteFields(&models.Quote{}))
+	statusColumns            = columns(statusFields(&models.Status{}))
+	personColumns            = columns(personFields(&models.Person{}))
+	checklistTemplateColumns = columns(checklistTemplateFields(&models.ChecklistTemplate{}))
+	checklistItemColumns     = columns(checklistItemFields(&models.ChecklistItem{}))
+	automationRuleColumns    = columns(automationRuleFields(&models.AutomationRule{}))
+	automationRunColumns     = columns(automationRunFields(&models.AutomationRun{}))
+	notificationColumns      = columns(notificationFields(&models.Notification{}))
+	activityColumns          = columns(activityFields(&models.Activity{}))
+	integrityColumns         = columns(integrityFields(&models.IntegrityCheck{}))
+	userColumns              = columns(userFields(&models.User{}))
+	apiKeyColumns            = columns(apiKeyFields(&models.APIKey{}))
+	drTestColumns            = columns(drTestFields(&models.DRTest{}))
+	backupRunColumns         = columns(backupRunFields(&models.BackupRun{}))
+	timeEntryColumns         = columns(timeEntryFields(&models.TimeEntry{}))
+	milestoneColumns         = columns(milestoneFields(&models.Milestone{}))
+	taskColumns              = columns(taskFields(&models.Task{}))
+	retainerColumns          = columns(retainerFields(&models.Retainer{}))
+	documentColumns          = columns(documentFields(&models.Document{}))
+	webhookColumns           = columns(webhookFields(&models.Webhook{}))
+	webhookDeliveryColumns   = columns(webhookDeliveryFields(&models.WebhookDelivery{}))
+	paidProjectColumns       = columns(paidProjectFields(&paidProject{}))
 )
 
 // SQL query templates
 // Metrics queries
 const (
-	qMetricsTotalRevenue = `SELECT COALESCE(SUM(revenue), 0), COUNT(*) FROM ` + projectTable + ` WHERE status = 'paid'`
-	qMetricsOpenProjects = `SELECT COUNT(*) FROM ` + projectTable + ` WHERE status != 'paid'`
+	qMetricsOpenProjects = `SELECT COUNT(*) FROM ` + projectTable + ` WHERE status IN (SELECT key FROM ` + statusTable + ` WHERE is_terminal = 0)`
+
+	// Expenses count once their project is paid; overhead counts as spent
+	qMetricsExpenses = `SELECT COALESCE(SUM(amount_cents), 0) FROM ` + expenseTable + `
+		WHERE project_id IS NULL OR project_id IN (SELECT id FROM ` + projectTable + ` WHERE status = 'paid')`
 )
 
-const (
+// Paid projects with their hours and expenses totalled per person (JSON
+// objects keyed by person ID), so every split comes from one query instead
+// of two more per project
+var qPaidProjects = `SELECT ` + paidProjectColumns + ` FROM ` + projectTable + `
+	LEFT JOIN (SELECT project_id, json_group_object(person_id, hours) AS hours
+		FROM (SELECT project_id, person_id, SUM(hours) AS hours FROM ` + contributionTable + `
+			GROUP BY project_id, person_id)
+		GROUP BY project_id) c ON c.project_id = projects.id
+	LEFT JOIN (SELECT project_id, json_group_object(payer_id, amount_cents) AS expenses
+		FROM (SELECT project_id, payer_id, SUM(amount_cents) AS amount_cents FROM ` + expenseTable + `
+			GROUP BY project_id, payer_id)
+		GROUP BY project_id) e ON e.project_id = projects.id
+	WHERE status = 'paid'`
+
+// qSecuredBy filters projects to those a person (the argument) helped secure
+const qSecuredBy = `EXISTS (SELECT 1 FROM json_each(secured_by) WHERE value = ?)`
+
+var (
 	qProjectByID = `SELECT ` + projectColumns + ` FROM ` + projectTable + ` WHERE id = ?`
-	
+
 	qProjectByStripeID = `SELECT ` + projectColumns + ` FROM ` + projectTable + ` WHERE stripe_payment_id = ?`
-	
+
 	qProjectsByStatus = `SELECT ` + projectColumns + ` FROM ` + projectTable + ` WHERE status = ? ORDER BY created_at DESC`
-	
-	qProjectsAll = `SELECT ` + projectColumns + ` FROM ` + projectTable + ` ORDER BY created_at DESC`
-	
-	qProjectsSearch = `SELECT ` + projectColumns + ` FROM ` + projectTable + 
-		` WHERE client LIKE ? OR description LIKE ? ORDER BY created_at DESC`
-	
-	qProjectInsert = `INSERT INTO ` + projectTable + 
-		` (client, description, revenue, status, secured_by, stripe_payment_id) 
-		VALUES (?, ?, ?, ?, ?, ?) RETURNING id, created_at`
-	
-	qProjectUpdate = `UPDATE ` + projectTable + 
-		` SET client=?, description=?, revenue=?, status=?, secured_by=?, stripe_payment_id=? WHERE id=?`
-	
-	qProjectUpdateStatus = `UPDATE ` + projectTable + 
-		` SET status=?, revenue=?, stripe_payment_id=? WHERE id=?`
-	
+
+	// Finished (terminal, not lost) projects with an estimate, archived too
+	qEstimatedProjects = `SELECT ` + projectColumns + ` FROM ` + projectTable + `
+		WHERE estimated_hours > 0 AND status != 'lost' AND status IN (SELECT key FROM ` + statusTable + ` WHERE is_terminal = 1)
+		ORDER BY COALESCE(paid_at, created_at) DESC`
+
+	// The hours of qEstimatedProjects per person
+	qEstimatedHours = `SELECT project_id, person_id, SUM(hours) FROM ` + contributionTable + `
+		WHERE project_id IN (SELECT id FROM ` + projectTable + ` WHERE estimated_hours > 0) GROUP BY project_id, person_id`
+
+	// ListProjects and ProjectTotals append projectWhere: the board lists only
+	// projects that aren't archived; exports may list all
+	qProjectsBase   = `SELECT ` + projectColumns + ` FROM ` + projectTable
+	qProjectsTotals = `SELECT status, COUNT(*), COALESCE(SUM(revenue_cents), 0) FROM ` + projectTable
+
+	// Archived projects: ListArchivedProjects appends the filter, then the order
+	qProjectsArchivedBase = `SELECT ` + projectColumns + ` FROM ` + projectTable + ` WHERE archived_at IS NOT NULL`
+
+	qProjectsArchivedTotals = `SELECT COUNT(*), COALESCE(SUM(revenue_cents), 0) FROM ` + projectTable + ` WHERE archived_at IS NOT NULL`
+
+	qProjectsArchivedOrder = ` ORDER BY COALESCE(paid_at, created_at) DESC`
+
+	qProjectSetArchived = `UPDATE ` + projectTable +
+		` SET archived_at = CASE WHEN ? THEN COALESCE(archived_at, CURRENT_TIMESTAMP) END WHERE id = ?`
+
+	qProjectInsert = `INSERT INTO ` + projectTable +
+		` (client, description, revenue_cents, status, secured_by, stripe_payment_id, currency, original_cents, split_override,
+		vat_percent, vat_inclusive, probability, expected_on, lost_reason, source, estimated_hours, hour_estimates, payment_days,
+		budget_hours, budget_cents, hourly_cents, paid_at, pending_since)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CASE WHEN ? THEN CURRENT_TIMESTAMP END, CURRENT_TIMESTAMP) RETURNING id, created_at`
+
+	qProjectUpdate = `UPDATE ` + projectTable +
+		` SET client=?, description=?, revenue_cents=?, status=?, secured_by=?, stripe_payment_id=?, currency=?, original_cents=?,
+		split_override=?, vat_percent=?, vat_inclusive=?, probability=?, expected_on=?, lost_reason=?, source=?,
+		estimated_hours=?, hour_estimates=?, payment_days=?, budget_hours=?, budget_cents=?, hourly_cents=?, paid_at = CASE WHEN ? THEN COALESCE(paid_at, CURRENT_TIMESTAMP) END,
+		` + qPendingSince + ` WHERE id=?`
+
+	qProjectSetPaidAt = `UPDATE ` + projectTable + ` SET paid_at = ? WHERE id = ? AND status = 'paid'`
+
+	qStripePaymentIDs = `SELECT stripe_payment_id FROM ` + projectTable + ` WHERE stripe_payment_id != ''
+		UNION SELECT stripe_payment_id FROM ` + milestoneTable + ` WHERE stripe_payment_id != ''`
+
+	qProjectSetBudgetWarned = `UPDATE ` + projectTable + ` SET budget_warned = ? WHERE id = ?`
+
+	qProjectUpdateStatus = `UPDATE ` + projectTable +
+		` SET status=?, revenue_cents=?, stripe_payment_id=?, lost_reason = CASE WHEN ? THEN lost_reason ELSE '' END,
+		paid_at = CASE WHEN ? THEN COALESCE(paid_at, CURRENT_TIMESTAMP) END, ` + qPendingSince + ` WHERE id=?`
+
+	// qPendingSince restamps pending_since when a project moves to another
+	// status short of paid (args: the new status, whether it is paid). The
+	// right-hand side sees the row as it was, so status is the old one.
+	qPendingSince = `pending_since = CASE WHEN status IS NOT ? AND NOT ? THEN CURRENT_TIMESTAMP ELSE pending_since END`
+
 	qProjectDelete = `DELETE FROM ` + projectTable + ` WHERE id = ?`
-	
+
 	qContributionByProject = `SELECT ` + contributionColumns + ` FROM ` + contributionTable + ` WHERE project_id = ?`
-	
-	qContributionUpsert = `INSERT INTO ` + contributionTable + 
-		` (project_id, owner, hours, notes) VALUES (?, ?, ?, ?)
-		ON CONFLICT(project_id, owner) DO UPDATE SET hours=excluded.hours, notes=excluded.notes`
+
+	// qContributionSync sets a person's contribution on a project (args:
+	// project, person) to the sum of their time entries
+	qContributionSync = `INSERT INTO ` + contributionTable + ` (project_id, person_id, hours, notes, updated_at)
+		VALUES (?1, ?2, (SELECT COALESCE(SUM(hours), 0) FROM ` + timeEntryTable + ` WHERE project_id = ?1 AND person_id = ?2), '', CURRENT_TIMESTAMP)
+		ON CONFLICT(project_id, person_id) DO UPDATE SET hours=excluded.hours,
+		updated_at=CASE WHEN hours != excluded.hours THEN excluded.updated_at ELSE updated_at END`
+
+	// Time entries joined with their project and person, filtered in code
+	qTimeEntriesBase = `SELECT ` + timeEntryColumns + `
+		FROM ` + timeEntryTable + ` t JOIN ` + projectTable + ` p ON p.id = t.project_id
+		LEFT JOIN ` + personTable + ` pe ON pe.id = t.person_id
+		WHERE t.hours != 0`
+
+	qTimeEntriesOrder = ` ORDER BY t.worked_on DESC, t.id DESC`
+
+	qTimeEntryByID = qTimeEntriesBase + ` AND t.id = ?`
+
+	qTimeEntryHours = `SELECT COALESCE(SUM(hours), 0) FROM ` + timeEntryTable + ` WHERE project_id = ? AND person_id = ?`
+
+	qTimeEntryInsert = `INSERT INTO ` + timeEntryTable + ` (project_id, person_id, worked_on, hours, note)
+		VALUES (?, ?, ?, ?, ?) RETURNING id`
+
+	qTimeEntryUpdate = `UPDATE ` + timeEntryTable + ` SET person_id = ?, worked_on = ?, hours = ?, note = ? WHERE id = ?`
+
+	qTimeEntryDelete = `DELETE FROM ` + timeEntryTable + ` WHERE id = ?`
+
+	// A person's entries on a project on one day (args: project, person, date)
+	qTimeEntryDayNotes = `SELECT COALESCE(GROUP_CONCAT(NULLIF(note, ''), '; '), '') FROM ` + timeEntryTable + `
+		WHERE project_id = ? AND person_id = ? AND worked_on = ?`
+
+	qTimeEntryDeleteDay = `DELETE FROM ` + timeEntryTable + ` WHERE project_id = ? AND person_id = ? AND worked_on = ?`
+)
+
+// Audit log queries (the activity feed is the most recent entries)
+var (
+	qActivityInsert = `INSERT INTO ` + activityTable +
+		` (project_id, client, action, summary, actor, source, changes, user_id, snapshot_before, snapshot_after, request_id)` +
+		` VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, created_at`
+
+	qActivityRecent = `SELECT ` + activityColumns + ` FROM ` + activityTable + ` ORDER BY created_at DESC, id DESC LIMIT ?`
+
+	qActivityByProject = `SELECT ` + activityColumns + ` FROM ` + activityTable +
+		` WHERE project_id = ? ORDER BY created_at DESC, id DESC LIMIT ?`
+
+	qActivityByUser = `SELECT ` + activityColumns + ` FROM ` + activityTable +
+		` WHERE user_id = ? ORDER BY id DESC LIMIT ?`
+
+	qActivityBetween = `SELECT ` + activityColumns + ` FROM ` + activityTable +
+		` WHERE created_at >= ? AND created_at < ? ORDER BY created_at, id`
+
+	// Stripe payments, for reconciliation
+	qActivityPayments = `SELECT project_id, summary FROM ` + activityTable + ` WHERE action = 'payment'`
+
+	qActivityFirstStripePayment = `SELECT created_at FROM ` + activityTable +
+		` WHERE action = 'payment' AND actor = 'stripe' ORDER BY created_at LIMIT 1`
+
+	qActivityGet = `SELECT ` + activityColumns + ` FROM ` + activityTable + ` WHERE id = ?`
+
+	qActivityMarkReverted = `UPDATE ` + activityTable + ` SET reverted_by = ? WHERE id = ? AND reverted_by IS NULL`
+)
+
+// Integrity check queries
+var (
+	qIntegrityCheck  = `PRAGMA integrity_check`
+	qForeignKeyCheck = `PRAGMA foreign_key_check`
+
+	qIntegrityInsert = `INSERT INTO ` + integrityTable + ` (ok, details) VALUES (?, ?) RETURNING id, checked_at`
+
+	qIntegrityLatest = `SELECT ` + integrityColumns + ` FROM ` + integrityTable + ` ORDER BY id DESC LIMIT 1`
+
+	qDRTestInsert = `INSERT INTO ` + drTestTable + ` (backup_file, ok, details) VALUES (?, ?, ?) RETURNING id, checked_at`
+
+	qDRTestLatest = `SELECT ` + drTestColumns + ` FROM ` + drTestTable + ` ORDER BY id DESC LIMIT 1`
+
+	qBackupRunInsert = `INSERT INTO ` + backupRunTable + ` (destination, file, size_bytes, ok, details, trigger, started_at, finished_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`
+
+	qBackupRunsRecent = `SELECT ` + backupRunColumns + ` FROM ` + backupRunTable + ` ORDER BY started_at DESC, id DESC LIMIT ?`
+
+	qBackupRunLatest = `SELECT ` + backupRunColumns + ` FROM ` + backupRunTable + `
+		WHERE destination = ? AND ok = ? ORDER BY started_at DESC, id DESC LIMIT 1`
+
+	qProjectCount = `SELECT COUNT(*) FROM ` + projectTable
+)
+
+// API key queries
+var (
+	qAPIKeyInsert = `INSERT INTO ` + apiKeyTable +
+		` (name, prefix, token_hash, scope) VALUES (?, ?, ?, ?) RETURNING id, created_at`
+
+	qAPIKeyByHash = `SELECT ` + apiKeyColumns + ` FROM ` + apiKeyTable + ` WHERE token_hash = ? AND revoked_at IS NULL`
+
+	qAPIKeysAll = `SELECT ` + apiKeyColumns + ` FROM ` + apiKeyTable + ` ORDER BY created_at DESC, id DESC`
+
+	qAPIKeyTouch = `UPDATE ` + apiKeyTable + ` SET last_used_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	qAPIKeyRevoke = `UPDATE ` + apiKeyTable + ` SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND revoked_at IS NULL`
+)
+
+// Expense queries
+var (
+	qExpenseInsert = `INSERT INTO ` + expenseTable +
+		` (project_id, payer_id, description, category, amount_cents, spent_on, receipt_path) VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id, created_at`
+
+	qExpenseUpdate = `UPDATE ` + expenseTable +
+		` SET project_id=?, payer_id=?, description=?, category=?, amount_cents=?, spent_on=?, receipt_path=? WHERE id=?`
+
+	qExpenseByID = `SELECT ` + expenseColumns + ` FROM ` + expenseTable + ` WHERE id = ?`
+
+	qExpensesByProject = `SELECT ` + expenseColumns + ` FROM ` + expenseTable + ` WHERE project_id = ? ORDER BY spent_on, id`
+
+	qExpensesOverhead = `SELECT ` + expenseColumns + ` FROM ` + expenseTable + ` WHERE project_id IS NULL ORDER BY spent_on, id`
+
+	// A year's expenses across projects and overhead, newest first, with
+	// each project's client; the category filter is skipped when empty
+	qExpensesByYear = `SELECT ` + expenseListColumns + ` FROM ` + expenseTable + `
+		WHERE strftime('%Y', spent_on) = ? AND (? = '' OR category = ?) ORDER BY spent_on DESC, id DESC`
+
+	qExpenseTotals = `SELECT category, COUNT(*), COALESCE(SUM(amount_cents), 0) FROM ` + expenseTable + `
+		WHERE strftime('%Y', spent_on) = ? GROUP BY category`
+
+	qExpenseDelete = `DELETE FROM ` + expenseTable + ` WHERE id = ?`
+)
+
+// User queries
+var (
+	qUserInsert = `INSERT INTO ` + userTable +
+		` (email, name, password_hash, role) VALUES (?, ?, ?, ?) RETURNING id, created_at`
+
+	qUserByID = `SELECT ` + userColumns + ` FROM ` + userTable + ` WHERE id = ?`
+
+	qUserByEmail = `SELECT ` + userColumns + ` FROM ` + userTable + ` WHERE email = ?`
+
+	qUsersAll = `SELECT ` + userColumns + ` FROM ` + userTable + ` ORDER BY name`
+
+	qUserCount = `SELECT COUNT(*) FROM ` + userTable
+
+	qUserOwnerCount = `SELECT COUNT(*) FROM ` + userTable + ` WHERE role = 'owner'`
+
+	qUserUpdateRole = `UPDATE ` + userTable + ` SET role = ? WHERE id = ?`
+
+	qUserSetWeeklyDigest  = `UPDATE ` + userTable + ` SET weekly_digest = ? WHERE id = ?`
+	qUserSetMonthlyReport = `UPDATE ` + userTable + ` SET monthly_report = ? WHERE id = ?`
+
+	qUserByCalendarToken = `SELECT ` + userColumns + ` FROM ` + userTable + ` WHERE calendar_token_hash = ?`
+
+	qUserSetCalendarToken = `UPDATE ` + userTable + ` SET calendar_token_hash = ? WHERE id = ?`
+
+	qUserDelete = `DELETE FROM ` + userTable + ` WHERE id = ?`
+)
+
+// Travel log queries
+var (
+	qTravelInsert = `INSERT INTO ` + travelTable +
+		` (project_id, expense_id, person_id, date, client, km, purpose, rate_cents) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id, created_at`
+
+	qTravelByID = `SELECT ` + travelColumns + ` FROM ` + travelTable + ` WHERE id = ?`
+
+	qTravelByYear = `SELECT ` + travelColumns + ` FROM ` + travelTable +
+		` WHERE strftime('%Y', date) = ? ORDER BY date DESC, id DESC`
+
+	qTravelSummary = `SELECT person_id, COUNT(*), COALESCE(SUM(km), 0), COALESCE(SUM(CAST(ROUND(km * rate_cents) AS INTEGER)), 0)
+		FROM ` + travelTable + ` WHERE strftime('%Y', date) = ? GROUP BY person_id ORDER BY person_id`
+
+	qTravelDelete = `DELETE FROM ` + travelTable + ` WHERE id = ?`
+)
+
+// Settings queries
+const (
+	qSettingGet = `SELECT value FROM ` + settingsTable + ` WHERE key = ?`
+
+	qSettingSet = `INSERT INTO ` + settingsTable + ` (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`
+)
+
+// Agreement queries
+var (
+	qAgreementsAll = `SELECT ` + agreementColumns + ` FROM ` + agreementTable + ` ORDER BY effective_from DESC`
+
+	qAgreementByID = `SELECT ` + agreementColumns + ` FROM ` + agreementTable + ` WHERE id = ?`
+
+	qAgreementInsert = `INSERT INTO ` + agreementTable +
+		` (effective_from, fixed_split, percents, finder_fee_percent, expense_policy, notes)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING id, created_at`
+
+	qAgreementDelete = `DELETE FROM ` + agreementTable + ` WHERE id = ?`
+)
+
+// Period lock queries
+var (
+	qPeriodLocksAll = `SELECT ` + periodLockColumns + ` FROM ` + periodLockTable + ` ORDER BY month DESC`
+
+	qPeriodLockByMonth = `SELECT ` + periodLockColumns + ` FROM ` + periodLockTable + ` WHERE month = ?`
+
+	qPeriodLockInsert = `INSERT INTO ` + periodLockTable + ` (month, locked_by) VALUES (?, ?)`
+
+	qPeriodLockDelete = `DELETE FROM ` + periodLockTable + ` WHERE month = ?`
+)
+
+// Adjustment queries
+var (
+	qAdjustmentsByProject = `SELECT ` + adjustmentColumns + ` FROM ` + adjustmentTable + ` WHERE project_id = ? ORDER BY posted_on DESC, id DESC`
+
+	// Adjustments posted in a date range (inclusive), for period reports
+	qAdjustmentsPosted = `SELECT ` + adjustmentColumns + ` FROM ` + adjustmentTable +
+		` WHERE posted_on >= ? AND posted_on <= ? ORDER BY posted_on, id`
+
+	qAdjustmentsAll = `SELECT ` + adjustmentColumns + ` FROM ` + adjustmentTable + ` ORDER BY posted_on, id`
+
+	qAdjustmentInsert = `INSERT INTO ` + adjustmentTable +
+		` (project_id, posted_on, revenue_cents, share_cents, reason, created_by)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING id, created_at`
+)
+
+// Comment queries
+var (
+	qCommentsByProject = `SELECT ` + commentColumns + ` FROM ` + commentTable + ` WHERE project_id = ? ORDER BY created_at, id`
+
+	qCommentByID = `SELECT ` + commentColumns + ` FROM ` + commentTable + ` WHERE id = ?`
+
+	qCommentInsert = `INSERT INTO ` + commentTable + ` (project_id, author_id, author, body) VALUES (?, NULLIF(?, 0), ?, ?) RETURNING id, created_at`
+
+	qCommentDelete = `DELETE FROM ` + commentTable + ` WHERE id = ?`
+)
+
+// Milestone queries
+var (
+	qMilestonesByProject = `SELECT ` + milestoneColumns + ` FROM ` + milestoneTable + ` WHERE project_id = ? ORDER BY due_on, id`
+
+	qMilestonesAll = `SELECT ` + milestoneColumns + ` FROM ` + milestoneTable + ` ORDER BY due_on, id`
+
+	qMilestoneByID = `SELECT ` + milestoneColumns + ` FROM ` + milestoneTable + ` WHERE id = ?`
+
+	qMilestoneInsert = `INSERT INTO ` + milestoneTable + ` (project_id, title, amount_cents, due_on, status, paid_at)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING id, created_at`
+
+	qMilestoneUpdate = `UPDATE ` + milestoneTable + ` SET title = ?, amount_cents = ?, due_on = ?, status = ?,
+		payment_link_id = ?, payment_link_url = ?, stripe_payment_id = ?, paid_at = ? WHERE id = ?`
+
+	qMilestoneSetLink = `UPDATE ` + milestoneTable + ` SET payment_link_id = ?, payment_link_url = ?,
+		status = CASE status WHEN 'planned' THEN 'billed' ELSE status END WHERE id = ?`
+
+	qMilestoneMarkPaid = `UPDATE ` + milestoneTable + ` SET status = 'paid', stripe_payment_id = ?,
+		paid_at = COALESCE(paid_at, CURRENT_TIMESTAMP) WHERE id = ?`
+
+	qMilestoneDelete = `DELETE FROM ` + milestoneTable + ` WHERE id = ?`
+)
+
+// Task queries
+var (
+	qTasksByProject = `SELECT ` + taskColumns + ` FROM ` + taskTable + ` WHERE project_id = ? ORDER BY position, id`
+
+	qTaskByID = `SELECT ` + taskColumns + ` FROM ` + taskTable + ` WHERE id = ?`
+
+	qTaskInsert = `INSERT INTO ` + taskTable + ` (project_id, title, assignee_id, position)
+		VALUES (?, ?, NULLIF(?, 0), (SELECT COALESCE(MAX(position), 0) + 1 FROM ` + taskTable + ` WHERE project_id = ?)) RETURNING id, position`
+
+	qTaskUpdate = `UPDATE ` + taskTable + ` SET title = ?, assignee_id = NULLIF(?, 0) WHERE id = ?`
+
+	qTaskSetDone = `UPDATE ` + taskTable + ` SET done = ? WHERE id = ?`
+
+	// qTaskNeighbour finds the task just before (?3 < 0) or after a
+	// position in the project
+	qTaskNeighbour = `SELECT id, position FROM ` + taskTable + ` WHERE project_id = ?1
+		AND ((?3 < 0 AND position < ?2) OR (?3 > 0 AND position > ?2))
+		ORDER BY CASE WHEN ?3 < 0 THEN -position ELSE position END LIMIT 1`
+
+	qTaskSetPosition = `UPDATE ` + taskTable + ` SET position = ? WHERE id = ?`
+
+	qTaskDelete = `DELETE FROM ` + taskTable + ` WHERE id = ?`
+)
+
+// Retainer queries
+var (
+	qRetainersByClient = `SELECT ` + retainerColumns + ` FROM ` + retainerTable + ` WHERE client_id = ? ORDER BY created_at, id`
+
+	qRetainersAll = `SELECT ` + retainerColumns + ` FROM ` + retainerTable + ` ORDER BY created_at, id`
+
+	qRetainerByID = `SELECT ` + retainerColumns + ` FROM ` + retainerTable + ` WHERE id = ?`
+
+	qRetainerBySubscription = `SELECT ` + retainerColumns + ` FROM ` + retainerTable + ` WHERE subscription_id = ?`
+
+	qRetainerInsert = `INSERT INTO ` + retainerTable + ` (client_id, subscription_id, description, secured_by, project_id,
+		status, amount_cents, currency, interval, current_period_end)
+		VALUES (?, ?, ?, ?, NULLIF(?, 0), ?, ?, ?, ?, ?) RETURNING id, created_at`
+
+	qRetainerSetSubscription = `UPDATE ` + retainerTable + ` SET status = ?, amount_cents = ?, currency = ?, interval = ?,
+		current_period_end = ? WHERE id = ?`
+
+	qRetainerPaysInto = `SELECT EXISTS(SELECT 1 FROM ` + retainerTable + ` WHERE project_id = ?)`
+
+	qRetainerMarkPaid = `UPDATE ` + retainerTable + ` SET last_paid_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	qRetainerDelete = `DELETE FROM ` + retainerTable + ` WHERE id = ?`
+)
+
+// Document queries; versions of a title are listed newest first
+var (
+	qDocumentsByProject = `SELECT ` + documentColumns + ` FROM ` + documentTable + `
+		WHERE project_id = ? ORDER BY lower(title), version DESC`
+
+	// A client's own documents, then those of its projects (matched by name)
+	qDocumentsByClient = `SELECT ` + documentColumns + ` FROM ` + documentTable + `
+		WHERE client_id = ? OR project_id IN (SELECT id FROM projects
+			WHERE lower(client) = lower((SELECT name FROM clients WHERE id = ?)))
+		ORDER BY project_id IS NOT NULL, project_id, lower(title), version DESC`
+
+	qDocumentByID = `SELECT ` + documentColumns + ` FROM ` + documentTable + ` WHERE id = ?`
+
+	// The next version of the title for the same client or project
+	qDocumentInsert = `INSERT INTO ` + documentTable + ` (client_id, project_id, kind, title, version, file_name, path,
+		size_bytes, signed_on, expires_on, uploaded_by)
+		VALUES (NULLIF(?1, 0), NULLIF(?2, 0), ?3, ?4, (SELECT COALESCE(MAX(version), 0) + 1 FROM ` + documentTable + `
+			WHERE client_id IS NULLIF(?1, 0) AND project_id IS NULLIF(?2, 0) AND lower(title) = lower(?4)),
+		?5, ?6, ?7, ?8, ?9, ?10) RETURNING id, version, created_at`
+
+	// Current versions expiring by a date that haven't been reminded of
+	qDocumentsExpiring = `SELECT ` + documentColumns + ` FROM ` + documentTable + `
+		WHERE expires_on IS NOT NULL AND expires_on <= ? AND expires_on >= ? AND reminded_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM ` + documentTable + ` n WHERE n.client_id IS documents.client_id
+			AND n.project_id IS documents.project_id AND lower(n.title) = lower(documents.title) AND n.version > documents.version)
+		ORDER BY expires_on, id`
+
+	qDocumentMarkReminded = `UPDATE ` + documentTable + ` SET reminded_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	qDocumentDelete = `DELETE FROM ` + documentTable + ` WHERE id = ?`
+)
+
+// Client queries
+var (
+	qClientsAll = `SELECT ` + clientColumns + ` FROM ` + clientTable + ` ORDER BY name`
+
+	qClientByName = `SELECT ` + clientColumns + ` FROM ` + clientTable + ` WHERE name = ?`
+
+	qClientUpsert = `INSERT INTO ` + clientTable + ` (name, statement_descriptor, logo_url, thank_you, email) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET statement_descriptor = excluded.statement_descriptor,
+		logo_url = excluded.logo_url, thank_you = excluded.thank_you, email = excluded.email RETURNING id, created_at`
+
+	qClientDelete = `DELETE FROM ` + clientTable + ` WHERE id = ?`
+
+	qClientByID = `SELECT ` + clientColumns + ` FROM ` + clientTable + ` WHERE id = ?`
+
+	qClientEnsure = `INSERT INTO ` + clientTable + ` (name) VALUES (?) ON CONFLICT(name) DO NOTHING`
+
+	qClientProjects = `SELECT ` + clientProjectColumns + ` FROM ` + projectTable + ` ORDER BY created_at DESC, id DESC`
+
+	// Each client's latest audit entry (the id tracks time; MAX() would lose
+	// created_at's type)
+	qClientLastActivity = `SELECT client, created_at FROM ` + activityTable +
+		` WHERE id IN (SELECT MAX(id) FROM ` + activityTable + ` GROUP BY client COLLATE NOCASE)`
+)
+
+// Exchange rate queries
+var (
+	qExchangeRatesAll = `SELECT ` + exchangeRateColumns + ` FROM ` + exchangeRateTable + ` ORDER BY currency`
+
+	qExchangeRateByCurrency = `SELECT ` + exchangeRateColumns + ` FROM ` + exchangeRateTable + ` WHERE currency = ?`
+
+	qExchangeRateUpsert = `INSERT INTO ` + exchangeRateTable + ` (currency, rate) VALUES (?, ?)
+		ON CONFLICT(currency) DO UPDATE SET rate = excluded.rate, updated_at = CURRENT_TIMESTAMP RETURNING updated_at`
+
+	qExchangeRateDelete = `DELETE FROM ` + exchangeRateTable + ` WHERE currency = ?`
+
+	// The most recent day's provider rates
+	qFXRatesLatest = `SELECT day, currency, rate, source, fetched_at FROM ` + fxRateTable + `
+		WHERE day = (SELECT MAX(day) FROM ` + fxRateTable + `) ORDER BY currency`
+
+	qFXRateUpsert = `INSERT INTO ` + fxRateTable + ` (day, currency, rate, source) VALUES (?, ?, ?, ?)
+		ON CONFLICT(day, currency) DO UPDATE SET rate = excluded.rate, source = excluded.source, fetched_at = CURRENT_TIMESTAMP`
+)
+
+// Goal queries
+var (
+	qGoalsAll = `SELECT ` + goalColumns + ` FROM ` + goalTable + ` ORDER BY start_on DESC, granularity, person_id IS NOT NULL, person_id`
+
+	qGoalUpsert = `INSERT INTO ` + goalTable + ` (granularity, start_on, person_id, target_cents) VALUES (?, ?, ?, ?)
+		ON CONFLICT(granularity, start_on, COALESCE(person_id, 0)) DO UPDATE SET target_cents = excluded.target_cents RETURNING id, created_at`
+
+	qGoalDelete = `DELETE FROM ` + goalTable + ` WHERE id = ?`
+)
+
+// Stats history queries
+var (
+	qStatsSnapshotUpsert = `INSERT INTO ` + statsHistoryTable + ` (` + statsSnapshotColumns + `) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(day) DO UPDATE SET total_revenue_cents = excluded.total_revenue_cents,
+		share_cents = excluded.share_cents, reimbursed_cents = excluded.reimbursed_cents,
+		open_projects = excluded.open_projects, expense_cents = excluded.expense_cents`
+
+	// The latest snapshot in a window (on or before the first day, after the second)
+	qStatsSnapshotNear = `SELECT ` + statsSnapshotColumns + ` FROM ` + statsHistoryTable + `
+		WHERE day <= ? AND day > ? ORDER BY day DESC LIMIT 1`
+
+	// Each month's last snapshot
+	qStatsSnapshotsMonthly = `SELECT ` + statsSnapshotColumns + ` FROM ` + statsHistoryTable + `
+		WHERE day IN (SELECT MAX(day) FROM ` + statsHistoryTable + ` GROUP BY strftime('%Y-%m', day)) ORDER BY day`
+)
+
+// Storage queries
+var (
+	// Every page of the database file, free pages included
+	qDatabaseSize = `SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()`
+	qJournalMode  = `PRAGMA journal_mode`
+
+	// A compacted copy of the whole database into a new file
+	qSnapshot = `VACUUM INTO ?`
+
+	qStorageUsageUpsert = `INSERT INTO ` + storageHistoryTable + ` (` + storageUsageColumns + `) VALUES (?, ?, ?)
+		ON CONFLICT(day) DO UPDATE SET db_bytes = excluded.db_bytes, attachment_bytes = excluded.attachment_bytes`
+
+	// The latest measurement in a window (on or before the first day, after the second)
+	qStorageUsageNear = `SELECT ` + storageUsageColumns + ` FROM ` + storageHistoryTable + `
+		WHERE day <= ? AND day > ? ORDER BY day DESC LIMIT 1`
+
+	// The earliest measurement on or after a day
+	qStorageUsageSince = `SELECT ` + storageUsageColumns + ` FROM ` + storageHistoryTable + `
+		WHERE day >= ? ORDER BY day LIMIT 1`
+
+	// Each month's last measurement
+	qStorageUsageMonthly = `SELECT ` + storageUsageColumns + ` FROM ` + storageHistoryTable + `
+		WHERE day IN (SELECT MAX(day) FROM ` + storageHistoryTable + ` GROUP BY strftime('%Y-%m', day)) ORDER BY day`
+)
+
+// Payout queries
+var (
+	qPayoutInsert = `INSERT INTO ` + payoutTable +
+		` (person_id, from_person_id, amount_cents, paid_on, note, created_by) VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id, created_at`
+
+	qPayoutByID = `SELECT ` + payoutColumns + ` FROM ` + payoutTable + ` WHERE id = ?`
+
+	qPayoutsAll = `SELECT ` + payoutColumns + ` FROM ` + payoutTable + ` ORDER BY paid_on DESC, id DESC`
+
+	// What each person has received: payouts to them, less what they passed
+	// on in settlements
+	qPayoutsReceived = `SELECT person_id, SUM(amount_cents) FROM (
+		SELECT person_id, amount_cents FROM ` + payoutTable + `
+		UNION ALL SELECT from_person_id, -amount_cents FROM ` + payoutTable + ` WHERE from_person_id IS NOT NULL)
+		GROUP BY person_id`
+
+	qPayoutDelete = `DELETE FROM ` + payoutTable + ` WHERE id = ?`
+)
+
+// Invoice queries
+var (
+	qInvoiceInsert = `INSERT INTO ` + invoiceTable +
+		` (project_id, client, lines, currency, vat_percent, payment_days, created_by) VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id, status, created_at`
+
+	qInvoiceByID = `SELECT ` + invoiceColumns + ` FROM ` + invoiceTable + ` WHERE id = ?`
+
+	// Drafts first, then by number, newest first. The status filter is
+	// empty for all; overdue matches issued invoices past their due date.
+	qInvoicesList = `SELECT ` + invoiceColumns + ` FROM ` + invoiceTable + `
+		WHERE (? = '' OR status = ? OR (? = 'overdue' AND status = 'issued' AND due_date < ?))
+		ORDER BY seq IS NOT NULL, year DESC, seq DESC, id DESC`
+
+	qInvoicesByProject = `SELECT ` + invoiceColumns + ` FROM ` + invoiceTable + ` WHERE project_id = ? ORDER BY id`
+
+	qInvoiceUpdate = `UPDATE ` + invoiceTable +
+		` SET client=?, lines=?, vat_percent=?, payment_days=? WHERE id=? AND status = 'draft'`
+
+	// Issuing takes the year's next number in the same statement, so two
+	// invoices issued at once can't both take it
+	qInvoiceIssue = `UPDATE ` + invoiceTable + ` SET status = 'issued', issue_date = ?, due_date = ?, year = ?,
+		seq = (SELECT COALESCE(MAX(seq), 0) + 1 FROM ` + invoiceTable + ` WHERE year = ?),
+		number = printf('%d-%04d', ?, (SELECT COALESCE(MAX(seq), 0) + 1 FROM ` + invoiceTable + ` WHERE year = ?))
+		WHERE id = ? AND status = 'draft'`
+
+	qInvoiceStatus = `UPDATE ` + invoiceTable + ` SET status = ?, paid_on = ? WHERE id = ? AND status != 'draft'`
+
+	qInvoiceDelete = `DELETE FROM ` + invoiceTable + ` WHERE id = ? AND status = 'draft'`
+
+	qInvoiceFortnoxSync = `UPDATE ` + invoiceTable + ` SET fortnox_number = ?, fortnox_paid = ?, fortnox_error = ?,
+		fortnox_synced_at = CASE WHEN ? THEN CURRENT_TIMESTAMP ELSE fortnox_synced_at END WHERE id = ?`
+)
+
+// Sent mail queries
+var (
+	qSentMailInsert = `INSERT INTO ` + sentMailTable +
+		` (kind, recipients, subject, project_id, invoice_id, error, sent_by, provider, attempts, next_attempt_at, message)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, sent_at`
+
+	qSentMailRecent = `SELECT ` + sentMailColumns + ` FROM ` + sentMailTable + ` ORDER BY id DESC LIMIT ?`
+
+	qSentMailByInvoice = `SELECT ` + sentMailColumns + ` FROM ` + sentMailTable + ` WHERE invoice_id = ? ORDER BY id DESC`
+
+	qSentMailDue = `SELECT ` + sentMailColumns + ` FROM ` + sentMailTable +
+		` WHERE next_attempt_at IS NOT NULL AND next_attempt_at <= ? ORDER BY next_attempt_at, id`
+
+	// Claims a retry by pushing next_attempt_at out while it's being sent
+	qSentMailClaim = `UPDATE ` + sentMailTable + ` SET next_attempt_at = ?
+		WHERE id = ? AND next_attempt_at IS NOT NULL AND next_attempt_at <= ?`
+
+	qSentMailSaveAttempt = `UPDATE ` + sentMailTable + `
+		SET error = ?, provider = ?, attempts = ?, next_attempt_at = ?, message = ?, sent_at = ? WHERE id = ?`
+)
+
+// Quote queries
+var (
+	qQuoteInsert = `INSERT INTO ` + quoteTable +
+		` (client, description, lines, currency, secured_by, valid_until, created_by) VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id, status, created_at`
+
+	qQuoteByID = `SELECT ` + quoteColumns + ` FROM ` + quoteTable + ` WHERE id = ?`
+
+	qQuotesList = `SELECT ` + quoteColumns + ` FROM ` + quoteTable + ` WHERE (? = '' OR status = ?) ORDER BY id DESC`
+
+	qQuoteUpdate = `UPDATE ` + quoteTable +
+		` SET client=?, description=?, lines=?, currency=?, secured_by=?, valid_until=? WHERE id=? AND status = 'draft'`
+
+	// Moves only from the status the caller saw, so a client accepting
+	// while a partner declines can't both win
+	qQuoteStatus = `UPDATE ` + quoteTable + ` SET status = ? WHERE id = ? AND status = ? AND project_id IS NULL`
+
+	qQuoteProject = `UPDATE ` + quoteTable + ` SET project_id = ? WHERE id = ? AND status = 'accepted' AND project_id IS NULL`
+
+	qQuoteDelete = `DELETE FROM ` + quoteTable + ` WHERE id = ? AND project_id IS NULL`
+)
+
+// Status queries
+var (
+	qStatusesAll = `SELECT ` + statusColumns + ` FROM ` + statusTable + ` ORDER BY position, key`
+
+	qStatusInsert = `INSERT INTO ` + statusTable + ` (key, label, color, position, is_terminal, probability)
+		VALUES (?, ?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM ` + statusTable + `), ?, ?)
+		RETURNING position`
+
+	qStatusUpdate = `UPDATE ` + statusTable + ` SET label = ?, color = ?, position = ?, is_terminal = ?, probability = ? WHERE key = ?`
+
+	qStatusDelete = `DELETE FROM ` + statusTable + ` WHERE key = ?`
+
+	qStatusProjectCount = `SELECT COUNT(*) FROM ` + projectTable + ` WHERE status = ?`
+)
+
+// People queries
+var (
+	qPeopleAll = `SELECT ` + personColumns + ` FROM ` + personTable + ` ORDER BY position, id`
+
+	qPersonInsert = `INSERT INTO ` + personTable + ` (name, color, position, active)
+		VALUES (?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM ` + personTable + `), 1)
+		RETURNING id, position, created_at`
+
+	qPersonUpdate = `UPDATE ` + personTable + ` SET name = ?, color = ?, position = ?, active = ? WHERE id = ?`
+)
+
+// Checklist queries
+var (
+	qChecklistTemplatesAll = `SELECT ` + checklistTemplateColumns + ` FROM ` + checklistTemplateTable +
+		` ORDER BY stage, position, id`
+
+	qChecklistTemplateInsert = `INSERT INTO ` + checklistTemplateTable + ` (stage, title, critical, position)
+		VALUES (?, ?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM ` + checklistTemplateTable + ` WHERE stage = ?))
+		RETURNING id, position`
+
+	qChecklistTemplateDelete = `DELETE FROM ` + checklistTemplateTable + ` WHERE id = ?`
+
+	qChecklistAttach = `INSERT INTO ` + checklistItemTable + ` (project_id, stage, title, critical, position)
+		SELECT ?, stage, title, critical, position FROM ` + checklistTemplateTable + ` WHERE stage = ?
+		AND NOT EXISTS (SELECT 1 FROM ` + checklistItemTable + ` WHERE project_id = ? AND stage = ?)`
+
+	qChecklistByProject = `SELECT ` + checklistItemColumns + ` FROM ` + checklistItemTable +
+		` WHERE project_id = ? ORDER BY stage, position, id`
+
+	qChecklistItemByID = `SELECT ` + checklistItemColumns + ` FROM ` + checklistItemTable + ` WHERE id = ?`
+
+	qChecklistItemSetDone = `UPDATE ` + checklistItemTable +
+		` SET done = ?, done_at = CASE WHEN ? THEN CURRENT_TIMESTAMP ELSE NULL END WHERE id = ?`
+)
+
+// Automation queries
+var (
+	qAutomationRulesAll = `SELECT ` + automationRuleColumns + ` FROM ` + automationRuleTable + ` ORDER BY trigger, id`
+
+	qAutomationRulesByTrigger = `SELECT ` + automationRuleColumns + ` FROM ` + automationRuleTable +
+		` WHERE trigger = ? AND enabled = 1 ORDER BY id`
+
+	qAutomationRuleByID = `SELECT ` + automationRuleColumns + ` FROM ` + automationRuleTable + ` WHERE id = ?`
+
+	qAutomationRuleInsert = `INSERT INTO ` + automationRuleTable + ` (trigger, action, param, delay_days, enabled)
+		VALUES (?, ?, ?, ?, ?) RETURNING id, created_at`
+
+	qAutomationRuleSetEnabled = `UPDATE ` + automationRuleTable + ` SET enabled = ? WHERE id = ?`
+
+	qAutomationRuleDelete = `DELETE FROM ` + automationRuleTable + ` WHERE id = ?`
+
+	qAutomationRunInsert = `INSERT INTO ` + automationRunTable + ` (rule_id, project_id, run_at)
+		VALUES (?, ?, ?) RETURNING id`
+
+	qAutomationRunsDue = `SELECT ` + automationRunColumns + ` FROM ` + automationRunTable +
+		` WHERE done_at IS NULL AND run_at <= ? ORDER BY run_at, id`
+
+	// Claiming marks a run done up front, so only one instance executes it
+	qAutomationRunClaim = `UPDATE ` + automationRunTable + ` SET done_at = CURRENT_TIMESTAMP WHERE id = ? AND done_at IS NULL`
+
+	qAutomationRunFinish = `UPDATE ` + automationRunTable + ` SET done_at = CURRENT_TIMESTAMP, error = ? WHERE id = ?`
+)
+
+// Webhook queries
+var (
+	qWebhooksAll = `SELECT ` + webhookColumns + ` FROM ` + webhookTable + ` ORDER BY id`
+
+	qWebhookByID = `SELECT ` + webhookColumns + ` FROM ` + webhookTable + ` WHERE id = ?`
+
+	qWebhookInsert = `INSERT INTO ` + webhookTable + ` (url, secret, events, enabled) VALUES (?, ?, ?, ?)
+		RETURNING id, created_at`
+
+	qWebhookSetEnabled = `UPDATE ` + webhookTable + ` SET enabled = ? WHERE id = ?`
+
+	qWebhookDelete = `DELETE FROM ` + webhookTable + ` WHERE id = ?`
+
+	qWebhookDeliveriesByWebhook = `SELECT ` + webhookDeliveryColumns + ` FROM ` + webhookDeliveryTable +
+		` WHERE webhook_id = ? ORDER BY id DESC LIMIT ?`
+
+	qWebhookDeliveryByID = `SELECT ` + webhookDeliveryColumns + ` FROM ` + webhookDeliveryTable + ` WHERE id = ?`
+
+	qWebhookDeliveryInsert = `INSERT INTO ` + webhookDeliveryTable + ` (webhook_id, event, payload, status, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?) RETURNING id, created_at`
+
+	// Paused webhooks' deliveries wait until they're resumed
+	qWebhookDeliveriesDue = `SELECT ` + webhookDeliveryColumns + ` FROM ` + webhookDeliveryTable + `
+		WHERE status = 'pending' AND next_attempt_at <= ?
+			AND webhook_id IN (SELECT id FROM ` + webhookTable + ` WHERE enabled = 1)
+		ORDER BY next_attempt_at, id`
+
+	// Claiming pushes the next attempt out while one instance sends it; if
+	// that instance dies mid-send, the delivery is due again afterwards
+	qWebhookDeliveryClaim = `UPDATE ` + webhookDeliveryTable + ` SET next_attempt_at = ?
+		WHERE id = ? AND status = 'pending' AND next_attempt_at <= ?`
+
+	qWebhookDeliverySaveAttempt = `UPDATE ` + webhookDeliveryTable + ` SET status = ?, attempts = ?, response_code = ?,
+		error = ?, next_attempt_at = ?, delivered_at = ? WHERE id = ?`
+
+	// Finished deliveries older than the argument ("-30 days") leave the log
+	qWebhookDeliveriesPrune = `DELETE FROM ` + webhookDeliveryTable + `
+		WHERE status != 'pending' AND created_at < datetime('now', ?)`
+)
+
+// Overdue notice queries: inserting claims the notice, RowsAffected tells
+// whether this is the first
+const qOverdueNoticeInsert = `INSERT OR IGNORE INTO ` + overdueNoticeTable + ` (project_id, invoice_id, due_on) VALUES (?, ?, ?)`
+
+// Lease queries: a lease is taken if free, expired or already held by the
+// same holder; RowsAffected tells whether it was granted
+const (
+	qLeaseAcquire = `INSERT INTO ` + leaseTable + ` (name, holder, expires_at) VALUES (?, ?, datetime('now', ?))
+		ON CONFLICT(name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+		WHERE ` + leaseTable + `.holder = excluded.holder OR ` + leaseTable + `.expires_at <= datetime('now')`
+
+	qWebhookEventInsert = `INSERT OR IGNORE INTO ` + webhookEventTable + ` (id) VALUES (?)`
+	qWebhookEventSeen   = `SELECT EXISTS(SELECT 1 FROM ` + webhookEventTable + ` WHERE id = ?)`
+)
+
+// Session and cache queries; expired rows read as missing and are purged
+// when new ones are written
+const (
+	qSessionInsert = `INSERT INTO ` + sessionTable + ` (token_hash, user_id, expires_at) VALUES (?, ?, ?)`
+
+	qSessionUser = `SELECT user_id FROM ` + sessionTable + ` WHERE token_hash = ? AND expires_at > ?`
+
+	qSessionDelete = `DELETE FROM ` + sessionTable + ` WHERE token_hash = ?`
+
+	qSessionsPurge = `DELETE FROM ` + sessionTable + ` WHERE expires_at <= ?`
+
+	qCacheGet = `SELECT value FROM ` + cacheTable + ` WHERE key = ? AND expires_at > ?`
+
+	qCacheSet = `INSERT INTO ` + cacheTable + ` (key, value, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at`
+
+	qCacheDelete = `DELETE FROM ` + cacheTable + ` WHERE key = ?`
+
+	qCachePurge = `DELETE FROM ` + cacheTable + ` WHERE expires_at <= ?`
+)
+
+// Notification queries
+var (
+	qNotificationPrefs = `SELECT kind, mode FROM ` + notificationPrefTable + ` WHERE user_id = ?`
+
+	qNotificationPrefUpsert = `INSERT INTO ` + notificationPrefTable + ` (user_id, kind, mode) VALUES (?, ?, ?)
+		ON CONFLICT(user_id, kind) DO UPDATE SET mode = excluded.mode`
+
+	qNotificationInsert = `INSERT INTO ` + notificationTable + ` (user_id, kind, project_id, body, queued)
+		VALUES (?, ?, ?, ?, ?) RETURNING id, created_at`
+
+	qNotificationsInbox = `SELECT ` + notificationColumns + ` FROM ` + notificationTable +
+		` WHERE user_id = ? AND queued = 0 ORDER BY created_at DESC, id DESC LIMIT ?`
+
+	qNotificationsQueued = `SELECT ` + notificationColumns + ` FROM ` + notificationTable +
+		` WHERE user_id = ? AND queued = 1 ORDER BY created_at, id`
+
+	qNotificationDelete = `DELETE FROM ` + notificationTable + ` WHERE id = ?`
 )
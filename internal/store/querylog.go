@@ -0,0 +1,229 @@
+// store/querylog.go - Statement logging, slow queries and per-query counters (DB_LOG_QUERIES, DB_SLOW_QUERY)
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// QueryLog configures the statement log. With All every statement is
+// logged with its duration and arguments; those taking longer than Slow
+// are logged flagged SLOW either way. Either one counts every statement
+// for QueryStats. For debugging performance; it costs a little per call.
+type QueryLog struct {
+	All  bool
+	Slow time.Duration
+}
+
+// Enabled reports whether q wraps the connections
+func (q QueryLog) Enabled() bool {
+	return q.All || q.Slow > 0
+}
+
+// maxTrackedQueries bounds the counters; statements past it count as
+// otherQueries (only statements built at runtime vary)
+const (
+	maxTrackedQueries = 500
+	otherQueries      = "(other)"
+)
+
+// logLineQuery is how much of a statement a log line shows
+const logLineQuery = 200
+
+// queryLog opens connections that log and count their statements
+type queryLog struct {
+	base  driver.Connector
+	cfg   QueryLog
+	since time.Time
+
+	mu    sync.Mutex
+	stats map[string]*models.QueryStat
+}
+
+// newQueryLog wraps base, or returns nil when q is off
+func newQueryLog(base driver.Connector, q QueryLog) *queryLog {
+	if !q.Enabled() {
+		return nil
+	}
+	return &queryLog{base: base, cfg: q, since: time.Now(), stats: map[string]*models.QueryStat{}}
+}
+
+func (l *queryLog) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := l.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &queryLogConn{Conn: conn, l: l}, nil
+}
+
+func (l *queryLog) Driver() driver.Driver {
+	return l.base.Driver()
+}
+
+// observe logs and counts one statement. ErrSkip isn't a run: database/sql
+// prepares the statement and runs it again.
+func (l *queryLog) observe(query string, args []driver.NamedValue, start time.Time, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	took := time.Since(start)
+	query = strings.Join(strings.Fields(query), " ")
+	slow := l.cfg.Slow > 0 && took > l.cfg.Slow
+
+	l.mu.Lock()
+	key := query
+	if _, ok := l.stats[key]; !ok && len(l.stats) >= maxTrackedQueries {
+		key = otherQueries
+	}
+	s := l.stats[key]
+	if s == nil {
+		s = &models.QueryStat{Query: key}
+		l.stats[key] = s
+	}
+	s.Count++
+	s.Total += took
+	s.Max = max(s.Max, took)
+	if err != nil {
+		s.Errors++
+	}
+	if slow {
+		s.Slow++
+	}
+	l.mu.Unlock()
+
+	if !slow && !l.cfg.All {
+		return
+	}
+	prefix := "[SQL]"
+	if slow {
+		prefix = "[SQL] SLOW"
+	}
+	line := fmt.Sprintf("%s %s %s %s", prefix, took.Round(time.Microsecond), truncate(query, logLineQuery), redactArgs(args))
+	if err != nil {
+		line += " error: " + err.Error()
+	}
+	log.Print(line)
+}
+
+// snapshot returns the counters, most total time first
+func (l *queryLog) snapshot() models.QueryStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := models.QueryStats{Enabled: true, Slow: l.cfg.Slow, Since: l.since, Queries: make([]models.QueryStat, 0, len(l.stats))}
+	for _, s := range l.stats {
+		out.Queries = append(out.Queries, *s)
+	}
+	slices.SortFunc(out.Queries, func(a, b models.QueryStat) int {
+		return cmp.Or(cmp.Compare(b.Total, a.Total), strings.Compare(a.Query, b.Query))
+	})
+	return out
+}
+
+// QueryStats returns the per-query counters, or Enabled false when the
+// query log is off
+func (db *DB) QueryStats() models.QueryStats {
+	if db.queries == nil {
+		return models.QueryStats{Queries: []models.QueryStat{}}
+	}
+	return db.queries.snapshot()
+}
+
+// redactArgs shows numbers, booleans, times and NULLs, and text and blobs
+// by length only: arguments carry password hashes, tokens and client details
+func redactArgs(args []driver.NamedValue) string {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		switch v := a.Value.(type) {
+		case nil:
+			parts[i] = "NULL"
+		case string:
+			parts[i] = fmt.Sprintf("text(%d)", len(v))
+		case []byte:
+			parts[i] = fmt.Sprintf("blob(%d)", len(v))
+		case time.Time:
+			parts[i] = v.Format(time.RFC3339)
+		default:
+			parts[i] = fmt.Sprint(v)
+		}
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
+
+// queryLogConn times the statements run on a sqlite connection
+type queryLogConn struct {
+	driver.Conn
+	l *queryLog
+}
+
+func (cn *queryLogConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return cn.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (cn *queryLogConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := cn.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &queryLogStmt{Stmt: stmt, query: query, l: cn.l}, nil
+}
+
+func (cn *queryLogConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	res, err := cn.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+	cn.l.observe(query, args, start, err)
+	return res, err
+}
+
+// QueryContext is timed until the statement returns its rows, not while
+// they are read
+func (cn *queryLogConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := cn.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+	cn.l.observe(query, args, start, err)
+	return rows, err
+}
+
+func (cn *queryLogConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := cn.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// queryLogStmt times a prepared statement's runs
+type queryLogStmt struct {
+	driver.Stmt
+	query string
+	l     *queryLog
+}
+
+func (s *queryLogStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	res, err := s.Stmt.(driver.StmtExecContext).ExecContext(ctx, args)
+	s.l.observe(s.query, args, start, err)
+	return res, err
+}
+
+func (s *queryLogStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := s.Stmt.(driver.StmtQueryContext).QueryContext(ctx, args)
+	s.l.observe(s.query, args, start, err)
+	return rows, err
+}
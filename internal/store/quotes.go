@@ -0,0 +1,103 @@
+// store/quotes.go - Quotes offered to clients, converted into projects
+package store
+
+import (
+	"database/sql"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// quoteScanner for DRY row scanning
+type quoteScanner struct {
+	dest *models.Quote
+}
+
+// quoteFields lists a quote's selected columns in scan order
+func quoteFields(q *models.Quote) []field {
+	return []field{
+		{"id", &q.ID}, {"client", &q.Client}, {"description", &q.Description}, {"lines", jsonText{&q.Lines}},
+		{"currency", &q.Currency}, {"secured_by", jsonText{&q.SecuredBy}}, {"valid_until", &q.ValidUntil},
+		{"status", &q.Status}, {"project_id", null(&q.ProjectID)}, {"created_by", &q.CreatedBy},
+		{"created_at", &q.CreatedAt},
+	}
+}
+
+func (s quoteScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, quoteFields(s.dest))
+}
+
+func (s quoteScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// CreateQuote saves a new draft quote
+func (db *DB) CreateQuote(q *models.Quote) error {
+	return db.QueryRow(qQuoteInsert, q.Client, q.Description, jsonArg(q.Lines), q.Currency, jsonArg(q.SecuredBy),
+		q.ValidUntil.Format(dateLayout), q.CreatedBy).Scan(&q.ID, &q.Status, &q.CreatedAt)
+}
+
+// GetQuote fetches a quote by ID
+func (db *DB) GetQuote(id int64) (*models.Quote, error) {
+	q := &models.Quote{}
+	err := quoteScanner{q}.scan(db.QueryRow(qQuoteByID, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return q, err
+}
+
+// ListQuotes returns the quotes with the given status (all when empty),
+// newest first
+func (db *DB) ListQuotes(status models.QuoteStatus) ([]models.Quote, error) {
+	rows, err := db.Query(qQuotesList, status, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Quote { return &models.Quote{} },
+		func(q *models.Quote) scanner { return quoteScanner{q} })
+}
+
+// UpdateQuote saves a draft's contents. A sent quote is refused with
+// models.ErrQuoteSent.
+func (db *DB) UpdateQuote(q *models.Quote) error {
+	res, err := db.Exec(qQuoteUpdate, q.Client, q.Description, jsonArg(q.Lines), q.Currency, jsonArg(q.SecuredBy),
+		q.ValidUntil.Format(dateLayout), q.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return models.ErrQuoteSent
+	}
+	return nil
+}
+
+// SetQuoteStatus moves an unconverted quote from one status to another,
+// reporting false when it's no longer in from
+func (db *DB) SetQuoteStatus(id int64, from, to models.QuoteStatus) (bool, error) {
+	res, err := db.Exec(qQuoteStatus, to, id, from)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// SetQuoteProject records the project an accepted quote became, reporting
+// false when it's already been converted (or is no longer accepted)
+func (db *DB) SetQuoteProject(id, projectID int64) (bool, error) {
+	res, err := db.Exec(qQuoteProject, projectID, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// DeleteQuote removes a quote that hasn't been converted into a project
+func (db *DB) DeleteQuote(id int64) error {
+	_, err := db.Exec(qQuoteDelete, id)
+	return err
+}
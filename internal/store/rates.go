@@ -0,0 +1,111 @@
+// store/rates.go - Exchange rates for projects quoted in foreign currencies
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// exchangeRateScanner for DRY row scanning
+type exchangeRateScanner struct {
+	dest *models.ExchangeRate
+}
+
+// exchangeRateFields lists a rate's selected columns in scan order
+func exchangeRateFields(r *models.ExchangeRate) []field {
+	return []field{{"currency", &r.Currency}, {"rate", &r.Rate}, {"updated_at", &r.UpdatedAt}}
+}
+
+func (s exchangeRateScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, exchangeRateFields(s.dest))
+}
+
+func (s exchangeRateScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// ListExchangeRates returns all stored rates by currency
+func (db *DB) ListExchangeRates() ([]models.ExchangeRate, error) {
+	rows, err := db.Query(qExchangeRatesAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.ExchangeRate { return &models.ExchangeRate{} },
+		func(r *models.ExchangeRate) scanner { return exchangeRateScanner{r} })
+}
+
+// GetExchangeRate returns the stored rate for a currency, or nil if there is none
+func (db *DB) GetExchangeRate(currency string) (*models.ExchangeRate, error) {
+	r := &models.ExchangeRate{}
+	err := exchangeRateScanner{r}.scan(db.QueryRow(qExchangeRateByCurrency, currency).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+// SetExchangeRate stores a currency's rate, replacing any previous one
+func (db *DB) SetExchangeRate(r *models.ExchangeRate) error {
+	return db.QueryRow(qExchangeRateUpsert, r.Currency, r.Rate).Scan(&r.UpdatedAt)
+}
+
+// DeleteExchangeRate removes a currency's rate; projects already converted
+// keep their amounts
+func (db *DB) DeleteExchangeRate(currency string) error {
+	_, err := db.Exec(qExchangeRateDelete, currency)
+	return err
+}
+
+// SaveFXRates caches a day's provider rates, replacing any fetched before
+// for that day
+func (db *DB) SaveFXRates(r *models.FXRates) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	day := r.Day.Format(dateLayout)
+	for currency, rate := range r.Rates {
+		if _, err := tx.Exec(qFXRateUpsert, day, currency, rate, r.Source); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LatestFXRates returns the most recent day's cached provider rates, or nil
+// if none have been fetched
+func (db *DB) LatestFXRates() (*models.FXRates, error) {
+	rows, err := db.Query(qFXRatesLatest)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var r *models.FXRates
+	for rows.Next() {
+		var (
+			day, fetchedAt time.Time
+			currency       string
+			rate           float64
+			source         string
+		)
+		if err := rows.Scan(&day, &currency, &rate, &source, &fetchedAt); err != nil {
+			return nil, err
+		}
+		if r == nil {
+			r = &models.FXRates{Source: source, Day: day, Rates: map[string]float64{}}
+		}
+		r.Rates[currency] = rate
+		if fetchedAt.After(r.FetchedAt) {
+			r.FetchedAt = fetchedAt
+		}
+	}
+	return r, rows.Err()
+}
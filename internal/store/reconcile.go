@@ -0,0 +1,149 @@
+// store/reconcile.go - Reconciling payments, revenue and splits
+package store
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// Reconcile checks the dashboard's totals against the records behind them,
+// listing every project and adjusting entry that doesn't add up
+func (db *DB) Reconcile() (*models.Reconciliation, error) {
+	m, err := db.GetMetrics()
+	if err != nil {
+		return nil, err
+	}
+	r := &models.Reconciliation{RecognizedCents: m.TotalRevenueCents, ShareCents: m.ShareCents}
+
+	received, err := db.stripePayments()
+	if err != nil {
+		return nil, err
+	}
+	for _, amount := range received {
+		r.ReceivedCents += amount
+	}
+
+	paid, err := db.paidProjects()
+	if err != nil {
+		return nil, err
+	}
+	rulesAt, err := db.SplitRules()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paid {
+		r.PaidCents += p.RevenueCents
+		flag := func(format string, args ...any) {
+			r.Discrepancies = append(r.Discrepancies, models.Discrepancy{
+				ProjectID: p.ID, Client: p.Client, Problem: fmt.Sprintf(format, args...),
+			})
+		}
+
+		if p.PaidAt == nil {
+			flag("Paid, but has no payment date, so no period lock covers it")
+		}
+		split := p.split(rulesAt(p.PaymentTime()))
+		if shares := split.ShareCents.Total(); p.RevenueCents > 0 && shares != p.RevenueCents {
+			flag("Shares total %s, but revenue is %s", kronor(shares), kronor(p.RevenueCents))
+		}
+		if amount, ok := received[p.ID]; ok {
+			delete(received, p.ID)
+			if amount != p.GrossCents() {
+				flag("Stripe received %s, but %s was due including VAT", kronor(amount), kronor(p.GrossCents()))
+			}
+		}
+	}
+
+	// Payments left over went to projects that aren't marked paid, which is
+	// only expected of the milestones paid so far on one billed in stages,
+	// or of an ongoing project a retainer pays into
+	ids := make([]int64, 0, len(received))
+	for id := range received {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		d := models.Discrepancy{ProjectID: id}
+		p, err := db.GetProject(id)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			d.Problem = fmt.Sprintf("Stripe received %s for a project that has been deleted", kronor(received[id]))
+		} else {
+			milestones, err := db.ListMilestones(id)
+			if err != nil {
+				return nil, err
+			}
+			var stages int64 // paid through the milestones' payment links
+			for _, m := range milestones {
+				if m.StripePaymentID != "" {
+					stages += m.GrossCents(p.VATPercent)
+				}
+			}
+			if stages > 0 && received[id] == stages {
+				continue
+			}
+			var retained bool
+			if err := db.QueryRow(qRetainerPaysInto, id).Scan(&retained); err != nil {
+				return nil, err
+			}
+			if retained {
+				continue
+			}
+			d.Client = p.Client
+			d.Problem = fmt.Sprintf("Stripe received %s, but the project isn't marked paid", kronor(received[id]))
+		}
+		r.Discrepancies = append(r.Discrepancies, d)
+	}
+
+	adjustments, err := db.queryAdjustments(qAdjustmentsAll)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range adjustments {
+		r.AdjustedCents += a.RevenueCents
+		if a.Balanced() {
+			continue
+		}
+		d := models.Discrepancy{ProjectID: a.ProjectID, AdjustmentID: a.ID, Problem: fmt.Sprintf(
+			"Adjusting entry of %s changes the shares by %s, but revenue by %s",
+			a.PostedOn.Format(dateLayout), kronor(a.ShareCents.Total()), kronor(a.RevenueCents))}
+		if p, err := db.GetProject(a.ProjectID); err != nil {
+			return nil, err
+		} else if p != nil {
+			d.Client = p.Client
+		}
+		r.Discrepancies = append(r.Discrepancies, d)
+	}
+	return r, nil
+}
+
+// stripePayments totals the Stripe payments logged in the audit log, by project
+func (db *DB) stripePayments() (map[int64]int64, error) {
+	rows, err := db.Query(qActivityPayments)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	received := make(map[int64]int64)
+	for rows.Next() {
+		var projectID int64
+		var summary string
+		if err := rows.Scan(&projectID, &summary); err != nil {
+			return nil, err
+		}
+		if amount, ok := models.ParsePaymentSummary(summary); ok {
+			received[projectID] += amount
+		}
+	}
+	return received, rows.Err()
+}
+
+// kronor formats cents for discrepancy messages
+func kronor(cents int64) string {
+	return fmt.Sprintf("%.2f kr", float64(cents)/100)
+}
@@ -0,0 +1,102 @@
+// store/retainers.go - Clients' Stripe subscriptions, billed as retainers
+package store
+
+import (
+	"database/sql"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// retainerScanner for DRY row scanning
+type retainerScanner struct {
+	dest *models.Retainer
+}
+
+// retainerFields lists a retainer's selected columns in scan order
+func retainerFields(r *models.Retainer) []field {
+	return []field{
+		{"id", &r.ID}, {"client_id", &r.ClientID}, {"subscription_id", &r.SubscriptionID},
+		{"description", &r.Description}, {"secured_by", jsonText{&r.SecuredBy}}, {"project_id", null(&r.ProjectID)},
+		{"status", &r.Status}, {"amount_cents", &r.AmountCents}, {"currency", &r.Currency}, {"interval", &r.Interval},
+		{"current_period_end", opt(&r.CurrentPeriodEnd)}, {"last_paid_at", opt(&r.LastPaidAt)}, {"created_at", &r.CreatedAt},
+	}
+}
+
+func (s retainerScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, retainerFields(s.dest))
+}
+
+func (s retainerScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// ListRetainers returns a client's retainers, oldest first
+func (db *DB) ListRetainers(clientID int64) ([]models.Retainer, error) {
+	rows, err := db.Query(qRetainersByClient, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Retainer { return &models.Retainer{} },
+		func(r *models.Retainer) scanner { return retainerScanner{r} })
+}
+
+// ListAllRetainers returns every client's retainers, oldest first
+func (db *DB) ListAllRetainers() ([]models.Retainer, error) {
+	rows, err := db.Query(qRetainersAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Retainer { return &models.Retainer{} },
+		func(r *models.Retainer) scanner { return retainerScanner{r} })
+}
+
+// GetRetainer returns a retainer by ID, or nil if it doesn't exist
+func (db *DB) GetRetainer(id int64) (*models.Retainer, error) {
+	return db.getRetainer(qRetainerByID, id)
+}
+
+// GetRetainerBySubscription returns the retainer a Stripe subscription is
+// mapped to, or nil if it isn't
+func (db *DB) GetRetainerBySubscription(subscriptionID string) (*models.Retainer, error) {
+	return db.getRetainer(qRetainerBySubscription, subscriptionID)
+}
+
+func (db *DB) getRetainer(query string, arg any) (*models.Retainer, error) {
+	r := &models.Retainer{}
+	err := retainerScanner{r}.scan(db.QueryRow(query, arg).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+// CreateRetainer maps a Stripe subscription to a client
+func (db *DB) CreateRetainer(r *models.Retainer) error {
+	return db.QueryRow(qRetainerInsert, r.ClientID, r.SubscriptionID, r.Description, jsonArg(r.SecuredBy), r.ProjectID,
+		r.Status, r.AmountCents, r.Currency, r.Interval, r.CurrentPeriodEnd).Scan(&r.ID, &r.CreatedAt)
+}
+
+// SetRetainerSubscription saves what Stripe last reported about a retainer's
+// subscription
+func (db *DB) SetRetainerSubscription(r *models.Retainer) error {
+	_, err := db.Exec(qRetainerSetSubscription, r.Status, r.AmountCents, r.Currency, r.Interval, r.CurrentPeriodEnd, r.ID)
+	return err
+}
+
+// MarkRetainerPaid records that a retainer's cycle was just paid
+func (db *DB) MarkRetainerPaid(id int64) error {
+	_, err := db.Exec(qRetainerMarkPaid, id)
+	return err
+}
+
+// DeleteRetainer unmaps a subscription; projects it paid are kept
+func (db *DB) DeleteRetainer(id int64) error {
+	_, err := db.Exec(qRetainerDelete, id)
+	return err
+}
@@ -0,0 +1,58 @@
+// store/sessions.go - Browser sessions and cached values shared by all instances
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// CreateSession stores a session by token hash, purging expired ones
+func (db *DB) CreateSession(tokenHash string, userID int64, expires time.Time) error {
+	if _, err := db.Exec(qSessionsPurge, time.Now().UTC()); err != nil {
+		return err
+	}
+	_, err := db.Exec(qSessionInsert, tokenHash, userID, expires.UTC())
+	return err
+}
+
+// SessionUser returns the user of a live session, or 0 if there is none
+func (db *DB) SessionUser(tokenHash string) (int64, error) {
+	var userID int64
+	err := db.QueryRow(qSessionUser, tokenHash, time.Now().UTC()).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return userID, err
+}
+
+// DeleteSession ends a session
+func (db *DB) DeleteSession(tokenHash string) error {
+	_, err := db.Exec(qSessionDelete, tokenHash)
+	return err
+}
+
+// CacheGet returns a cached value, or nil if it is missing or expired
+func (db *DB) CacheGet(key string) ([]byte, error) {
+	var value []byte
+	err := db.QueryRow(qCacheGet, key, time.Now().UTC()).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return value, err
+}
+
+// CacheSet stores a value until ttl from now, purging expired entries
+func (db *DB) CacheSet(key string, value []byte, ttl time.Duration) error {
+	now := time.Now().UTC()
+	if _, err := db.Exec(qCachePurge, now); err != nil {
+		return err
+	}
+	_, err := db.Exec(qCacheSet, key, value, now.Add(ttl))
+	return err
+}
+
+// CacheDelete drops a cached value
+func (db *DB) CacheDelete(key string) error {
+	_, err := db.Exec(qCacheDelete, key)
+	return err
+}
@@ -0,0 +1,140 @@
+// store/settings.go - Key/value settings
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// GetSetting returns a setting's value, or fallback if it has never been set
+func (db *DB) GetSetting(key, fallback string) (string, error) {
+	var v string
+	err := db.QueryRow(qSettingGet, key).Scan(&v)
+	if err == sql.ErrNoRows {
+		return fallback, nil
+	}
+	return v, err
+}
+
+// SetSetting creates or replaces a setting
+func (db *DB) SetSetting(key, value string) error {
+	_, err := db.Exec(qSettingSet, key, value)
+	return err
+}
+
+// Company returns the business's details for invoices, empty until set
+func (db *DB) Company() (models.Company, error) {
+	var c models.Company
+	v, err := db.GetSetting(models.SettingCompany, "")
+	if err != nil || v == "" {
+		return c, err
+	}
+	if err := json.Unmarshal([]byte(v), &c); err != nil {
+		return c, fmt.Errorf("company: %w", err)
+	}
+	return c, nil
+}
+
+// SetCompany replaces the business's details
+func (db *DB) SetCompany(c models.Company) error {
+	return db.SetSetting(models.SettingCompany, jsonArg(c))
+}
+
+// Accounts returns the chart of accounts the SIE export books to, the BAS
+// defaults standing in for any left blank
+func (db *DB) Accounts() (models.Accounts, error) {
+	var a models.Accounts
+	v, err := db.GetSetting(models.SettingAccounts, "")
+	if err != nil {
+		return a, err
+	}
+	if v != "" {
+		if err := json.Unmarshal([]byte(v), &a); err != nil {
+			return a, fmt.Errorf("accounts: %w", err)
+		}
+	}
+	return a.WithDefaults(), nil
+}
+
+// SetAccounts replaces the chart of accounts
+func (db *DB) SetAccounts(a models.Accounts) error {
+	return db.SetSetting(models.SettingAccounts, jsonArg(a))
+}
+
+// BudgetThresholds returns the percentages of a budget burned that warn,
+// ascending, the defaults until set
+func (db *DB) BudgetThresholds() ([]int, error) {
+	v, err := db.GetSetting(models.SettingBudgetWarn, "")
+	if err != nil || v == "" {
+		return models.DefaultBudgetThresholds, err
+	}
+	var thresholds []int
+	if err := json.Unmarshal([]byte(v), &thresholds); err != nil {
+		return nil, fmt.Errorf("budget thresholds: %w", err)
+	}
+	return thresholds, nil
+}
+
+// SetBudgetThresholds replaces the budget warning thresholds
+func (db *DB) SetBudgetThresholds(thresholds []int) error {
+	return db.SetSetting(models.SettingBudgetWarn, jsonArg(thresholds))
+}
+
+// FortnoxCredentials returns what connects to Fortnox, empty until set
+func (db *DB) FortnoxCredentials() (models.FortnoxCredentials, error) {
+	var c models.FortnoxCredentials
+	v, err := db.GetSetting(models.SettingFortnox, "")
+	if err != nil || v == "" {
+		return c, err
+	}
+	if err := json.Unmarshal([]byte(v), &c); err != nil {
+		return c, fmt.Errorf("fortnox: %w", err)
+	}
+	return c, nil
+}
+
+// SetFortnoxCredentials replaces what connects to Fortnox
+func (db *DB) SetFortnoxCredentials(c models.FortnoxCredentials) error {
+	return db.SetSetting(models.SettingFortnox, jsonArg(c))
+}
+
+// GoogleCalendarCredentials returns what connects to Google Calendar, empty
+// until set
+func (db *DB) GoogleCalendarCredentials() (models.GoogleCalendarCredentials, error) {
+	var c models.GoogleCalendarCredentials
+	v, err := db.GetSetting(models.SettingGoogleCal, "")
+	if err != nil || v == "" {
+		return c, err
+	}
+	if err := json.Unmarshal([]byte(v), &c); err != nil {
+		return c, fmt.Errorf("google calendar: %w", err)
+	}
+	return c, nil
+}
+
+// SetGoogleCalendarCredentials replaces what connects to Google Calendar
+func (db *DB) SetGoogleCalendarCredentials(c models.GoogleCalendarCredentials) error {
+	return db.SetSetting(models.SettingGoogleCal, jsonArg(c))
+}
+
+// GoogleCalendarSync returns the last Google Calendar sync's outcome, zero
+// before the first
+func (db *DB) GoogleCalendarSync() (models.GoogleCalendarSync, error) {
+	var s models.GoogleCalendarSync
+	v, err := db.GetSetting(models.SettingGoogleCalSync, "")
+	if err != nil || v == "" {
+		return s, err
+	}
+	if err := json.Unmarshal([]byte(v), &s); err != nil {
+		return s, fmt.Errorf("google calendar sync: %w", err)
+	}
+	return s, nil
+}
+
+// SetGoogleCalendarSync records a Google Calendar sync's outcome
+func (db *DB) SetGoogleCalendarSync(s models.GoogleCalendarSync) error {
+	return db.SetSetting(models.SettingGoogleCalSync, jsonArg(s))
+}
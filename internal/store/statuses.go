@@ -0,0 +1,63 @@
+// store/statuses.go - Configurable project statuses (kanban columns)
+package store
+
+import (
+	"database/sql"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// statusScanner for DRY row scanning
+type statusScanner struct {
+	dest *models.Status
+}
+
+// statusFields lists a status's selected columns in scan order
+func statusFields(st *models.Status) []field {
+	return []field{
+		{"key", &st.Key}, {"label", &st.Label}, {"color", &st.Color}, {"position", &st.Position},
+		{"is_terminal", &st.Terminal}, {"probability", &st.Probability},
+	}
+}
+
+func (s statusScanner) Scan(rows *sql.Rows) error {
+	return scanInto(rows.Scan, statusFields(s.dest))
+}
+
+// ListStatuses returns all statuses in board order
+func (db *DB) ListStatuses() ([]models.Status, error) {
+	rows, err := db.Query(qStatusesAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Status { return &models.Status{} },
+		func(s *models.Status) scanner { return statusScanner{s} })
+}
+
+// CreateStatus appends a status as the last column
+func (db *DB) CreateStatus(s *models.Status) error {
+	return db.QueryRow(qStatusInsert, s.Key, s.Label, s.Color, s.Terminal, s.Probability).Scan(&s.Position)
+}
+
+// UpdateStatus saves a status's label, color, position, terminal flag and
+// probability
+func (db *DB) UpdateStatus(s *models.Status) error {
+	_, err := db.Exec(qStatusUpdate, s.Label, s.Color, s.Position, s.Terminal, s.Probability, s.Key)
+	return err
+}
+
+// DeleteStatus removes a status; it fails while projects still use it
+func (db *DB) DeleteStatus(key models.ProjectStatus) error {
+	_, err := db.Exec(qStatusDelete, key)
+	return err
+}
+
+// CountProjectsByStatus returns how many projects are in a status
+func (db *DB) CountProjectsByStatus(key models.ProjectStatus) (int, error) {
+	var n int
+	err := db.QueryRow(qStatusProjectCount, key).Scan(&n)
+	return n, err
+}
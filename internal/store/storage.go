@@ -0,0 +1,130 @@
+// store/storage.go - Disk use history and the soft storage quota
+package store
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// storageUsageScanner for DRY row scanning
+type storageUsageScanner struct {
+	dest *models.StorageUsage
+}
+
+// storageUsageFields lists a measurement's columns in scan order
+func storageUsageFields(u *models.StorageUsage) []field {
+	return []field{{"day", &u.Day}, {"db_bytes", &u.DBBytes}, {"attachment_bytes", &u.AttachmentBytes}}
+}
+
+func (s storageUsageScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, storageUsageFields(s.dest))
+}
+
+func (s storageUsageScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// DatabaseSize returns the size of the database file, including free pages
+// that only VACUUM would reclaim
+func (db *DB) DatabaseSize() (int64, error) {
+	var n int64
+	err := db.QueryRow(qDatabaseSize).Scan(&n)
+	return n, err
+}
+
+// JournalMode returns the database's journal mode, e.g. "wal" or "delete"
+func (db *DB) JournalMode() (string, error) {
+	var mode string
+	err := db.QueryRow(qJournalMode).Scan(&mode)
+	return mode, err
+}
+
+// Snapshot writes a consistent copy of the database to path, which must
+// not exist, with VACUUM INTO. It reads in one transaction, so it is safe
+// while the server writes.
+func (db *DB) Snapshot(path string) error {
+	_, err := db.Exec(qSnapshot, path)
+	return err
+}
+
+// SaveStorageUsage stores the disk use for u.Day, replacing any saved
+// earlier that day
+func (db *DB) SaveStorageUsage(u *models.StorageUsage) error {
+	_, err := db.Exec(qStorageUsageUpsert, u.Day.Format(dateLayout), u.DBBytes, u.AttachmentBytes)
+	return err
+}
+
+// StorageReport compares the latest storage measurement with the earliest
+// in the growth window before it, under the quota in settings
+func (db *DB) StorageReport() (*models.StorageReport, error) {
+	quota, err := db.StorageQuota()
+	if err != nil {
+		return nil, err
+	}
+	r := &models.StorageReport{Quota: quota}
+
+	if r.Latest, err = db.storageUsageNear(time.Now()); err != nil || r.Latest == nil {
+		return r, err
+	}
+	r.Earlier = &models.StorageUsage{}
+	err = storageUsageScanner{r.Earlier}.scan(db.QueryRow(qStorageUsageSince,
+		r.Latest.Day.AddDate(0, 0, -models.StorageGrowthDays).Format(dateLayout)).Scan)
+	if err == sql.ErrNoRows || (err == nil && r.Earlier.Day.Equal(r.Latest.Day)) {
+		r.Earlier = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(qStorageUsageMonthly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	r.History, err = scanAll(rows,
+		func() *models.StorageUsage { return &models.StorageUsage{} },
+		func(u *models.StorageUsage) scanner { return storageUsageScanner{u} })
+	return r, err
+}
+
+// storageUsageNear returns the latest measurement on or up to a week before
+// day, or nil if there is none
+func (db *DB) storageUsageNear(day time.Time) (*models.StorageUsage, error) {
+	u := &models.StorageUsage{}
+	err := storageUsageScanner{u}.scan(db.QueryRow(qStorageUsageNear,
+		day.Format(dateLayout), day.AddDate(0, 0, -statsSnapshotSlack).Format(dateLayout)).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return u, err
+}
+
+// StorageQuota returns the soft storage limits from settings, with the
+// defaults for any never set
+func (db *DB) StorageQuota() (models.StorageQuota, error) {
+	maxMB, err := db.settingInt(models.SettingStorageMax, models.DefaultStorageMaxMB)
+	if err != nil {
+		return models.StorageQuota{}, err
+	}
+	growthMB, err := db.settingInt(models.SettingStorageGrowth, models.DefaultStorageMaxGrowthMB)
+	if err != nil {
+		return models.StorageQuota{}, err
+	}
+	return models.StorageQuota{MaxMB: maxMB, MaxGrowthMB: growthMB}, nil
+}
+
+// settingInt reads an integer setting, falling back to def if it is unset
+// or unparsable
+func (db *DB) settingInt(key string, def int64) (int64, error) {
+	v, err := db.GetSetting(key, "")
+	if err != nil || v == "" {
+		return def, err
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return def, nil
+	}
+	return n, nil
+}
@@ -0,0 +1,102 @@
+// store/tasks.go - Project subtasks
+package store
+
+import (
+	"database/sql"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// taskScanner for DRY row scanning
+type taskScanner struct {
+	dest *models.Task
+}
+
+// taskFields lists a task's selected columns in scan order
+func taskFields(t *models.Task) []field {
+	return []field{
+		{"id", &t.ID}, {"project_id", &t.ProjectID}, {"title", &t.Title}, {"done", &t.Done},
+		{"assignee_id", null(&t.AssigneeID)}, {"position", &t.Position},
+	}
+}
+
+func (s taskScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, taskFields(s.dest))
+}
+
+func (s taskScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// ListTasks returns a project's tasks in order
+func (db *DB) ListTasks(projectID int64) (models.Tasks, error) {
+	rows, err := db.Query(qTasksByProject, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Task { return &models.Task{} },
+		func(t *models.Task) scanner { return taskScanner{t} })
+}
+
+// GetTask returns a task by ID, or nil if it doesn't exist
+func (db *DB) GetTask(id int64) (*models.Task, error) {
+	t := &models.Task{}
+	err := taskScanner{t}.scan(db.QueryRow(qTaskByID, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// CreateTask appends a task to the end of its project's list
+func (db *DB) CreateTask(t *models.Task) error {
+	return db.QueryRow(qTaskInsert, t.ProjectID, t.Title, t.AssigneeID, t.ProjectID).Scan(&t.ID, &t.Position)
+}
+
+// UpdateTask saves a task's title and assignee
+func (db *DB) UpdateTask(t *models.Task) error {
+	_, err := db.Exec(qTaskUpdate, t.Title, t.AssigneeID, t.ID)
+	return err
+}
+
+// SetTaskDone ticks or unticks a task
+func (db *DB) SetTaskDone(id int64, done bool) error {
+	_, err := db.Exec(qTaskSetDone, done, id)
+	return err
+}
+
+// MoveTask swaps a task with the one before it (dir < 0) or after it;
+// the first task can't move up, nor the last one down
+func (db *DB) MoveTask(t *models.Task, dir int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var otherID int64
+	var otherPosition int
+	err = tx.QueryRow(qTaskNeighbour, t.ProjectID, t.Position, dir).Scan(&otherID, &otherPosition)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(qTaskSetPosition, otherPosition, t.ID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(qTaskSetPosition, t.Position, otherID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteTask removes a task
+func (db *DB) DeleteTask(id int64) error {
+	_, err := db.Exec(qTaskDelete, id)
+	return err
+}
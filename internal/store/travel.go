@@ -0,0 +1,134 @@
+// store/travel.go - Mileage/travel log operations
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// travelScanner for DRY row scanning
+type travelScanner struct {
+	dest *models.TravelEntry
+}
+
+// travelFields lists a travel log entry's selected columns in scan order
+func travelFields(t *models.TravelEntry) []field {
+	return []field{
+		{"id", &t.ID}, {"project_id", null(&t.ProjectID)}, {"expense_id", null(&t.ExpenseID)},
+		{"person_id", &t.PersonID}, {"date", &t.Date}, {"client", &t.Client}, {"km", &t.Km},
+		{"purpose", null(&t.Purpose)}, {"rate_cents", &t.RateCents}, {"created_at", &t.CreatedAt},
+	}
+}
+
+func (s travelScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, travelFields(s.dest))
+}
+
+func (s travelScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// CreateTravelEntry logs a trip. Trips linked to a project also create a
+// reimbursable expense for the driver, in the same transaction, unless the
+// project was paid in a locked month.
+func (db *DB) CreateTravelEntry(t *models.TravelEntry) error {
+	return db.Tx(func(tx *DB) error {
+		if t.ProjectID != 0 {
+			if err := tx.guardProject(t.ProjectID, nil); err != nil {
+				return err
+			}
+			desc := fmt.Sprintf("Mileage: %s km", strconv.FormatFloat(t.Km, 'f', -1, 64))
+			if t.Purpose != "" {
+				desc += " — " + t.Purpose
+			}
+			var createdAt any
+			err := tx.QueryRow(qExpenseInsert, t.ProjectID, t.PersonID, desc, models.ExpenseTravel, t.AmountCents(),
+				t.Date.Format(dateLayout), "").Scan(&t.ExpenseID, &createdAt)
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.QueryRow(qTravelInsert, nullID(t.ProjectID), nullID(t.ExpenseID), t.PersonID, t.Date.Format(dateLayout),
+			t.Client, t.Km, t.Purpose, t.RateCents).Scan(&t.ID, &t.CreatedAt)
+	})
+}
+
+// GetTravelEntry fetches a trip by ID
+func (db *DB) GetTravelEntry(id int64) (*models.TravelEntry, error) {
+	t := &models.TravelEntry{}
+	err := travelScanner{t}.scan(db.QueryRow(qTravelByID, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// ListTravelEntries returns a year's trips, newest first
+func (db *DB) ListTravelEntries(year int) ([]models.TravelEntry, error) {
+	rows, err := db.Query(qTravelByYear, strconv.Itoa(year))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.TravelEntry { return &models.TravelEntry{} },
+		func(t *models.TravelEntry) scanner { return travelScanner{t} })
+}
+
+// MileageSummary totals km and allowance per person for a year (for the tax return)
+func (db *DB) MileageSummary(year int) ([]models.MileageSummary, error) {
+	rows, err := db.Query(qTravelSummary, strconv.Itoa(year))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.MileageSummary
+	for rows.Next() {
+		var m models.MileageSummary
+		if err := rows.Scan(&m.PersonID, &m.Trips, &m.Km, &m.AmountCents); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// DeleteTravelEntry removes a trip and the expense it created, unless that
+// expense's project was paid in a locked month
+func (db *DB) DeleteTravelEntry(id int64) error {
+	return db.Tx(func(tx *DB) error {
+		t, err := tx.GetTravelEntry(id)
+		if err != nil || t == nil {
+			return err
+		}
+		if t.ExpenseID != 0 {
+			if err := tx.guardProject(t.ProjectID, nil); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec(qTravelDelete, id); err != nil {
+			return err
+		}
+		if t.ExpenseID != 0 {
+			if _, err := tx.Exec(qExpenseDelete, t.ExpenseID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// nullID maps a zero ID to SQL NULL
+func nullID(id int64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,119 @@
+// store/users.go - User account operations
+package store
+
+import (
+	"database/sql"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// userScanner for DRY row scanning
+type userScanner struct {
+	dest *models.User
+}
+
+// userFields lists a user's selected columns in scan order
+func userFields(u *models.User) []field {
+	return []field{
+		{"id", &u.ID}, {"email", &u.Email}, {"name", &u.Name}, {"password_hash", &u.PasswordHash},
+		{"role", &u.Role}, {"weekly_digest", &u.WeeklyDigest}, {"monthly_report", &u.MonthlyReport},
+		{"calendar_token_hash IS NOT NULL", &u.CalendarFeed}, {"created_at", &u.CreatedAt},
+	}
+}
+
+func (s userScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, userFields(s.dest))
+}
+
+func (s userScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// CreateUser inserts a user (PasswordHash must already be set)
+func (db *DB) CreateUser(u *models.User) error {
+	return db.QueryRow(qUserInsert, u.Email, u.Name, u.PasswordHash, u.Role).Scan(&u.ID, &u.CreatedAt)
+}
+
+// GetUser fetches a user by ID
+func (db *DB) GetUser(id int64) (*models.User, error) {
+	return db.getUser(qUserByID, id)
+}
+
+// GetUserByEmail fetches a user by email (case-insensitive)
+func (db *DB) GetUserByEmail(email string) (*models.User, error) {
+	return db.getUser(qUserByEmail, email)
+}
+
+// GetUserByCalendarToken fetches the user whose calendar feed token hashes
+// to hash, or nil
+func (db *DB) GetUserByCalendarToken(hash string) (*models.User, error) {
+	return db.getUser(qUserByCalendarToken, hash)
+}
+
+func (db *DB) getUser(query string, arg any) (*models.User, error) {
+	u := &models.User{}
+	err := userScanner{u}.scan(db.QueryRow(query, arg).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return u, err
+}
+
+// ListUsers returns all users by name
+func (db *DB) ListUsers() ([]models.User, error) {
+	rows, err := db.Query(qUsersAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.User { return &models.User{} },
+		func(u *models.User) scanner { return userScanner{u} })
+}
+
+// CountUsers returns the number of users (0 means setup is pending)
+func (db *DB) CountUsers() (int, error) {
+	var n int
+	err := db.QueryRow(qUserCount).Scan(&n)
+	return n, err
+}
+
+// CountOwners returns the number of users with the owner role
+func (db *DB) CountOwners() (int, error) {
+	var n int
+	err := db.QueryRow(qUserOwnerCount).Scan(&n)
+	return n, err
+}
+
+// UpdateUserRole changes a user's role
+func (db *DB) UpdateUserRole(id int64, role models.Role) error {
+	_, err := db.Exec(qUserUpdateRole, role, id)
+	return err
+}
+
+// SetWeeklyDigest opts a user in to or out of the weekly summary email
+func (db *DB) SetWeeklyDigest(id int64, on bool) error {
+	_, err := db.Exec(qUserSetWeeklyDigest, on, id)
+	return err
+}
+
+// SetCalendarToken stores the hash of a user's calendar feed token; ""
+// turns the feed off
+func (db *DB) SetCalendarToken(id int64, hash string) error {
+	_, err := db.Exec(qUserSetCalendarToken, nullString(hash), id)
+	return err
+}
+
+// SetMonthlyReport sets whether a user's weekly summary carries the monthly
+// PDF report
+func (db *DB) SetMonthlyReport(id int64, on bool) error {
+	_, err := db.Exec(qUserSetMonthlyReport, on, id)
+	return err
+}
+
+// DeleteUser removes a user
+func (db *DB) DeleteUser(id int64) error {
+	_, err := db.Exec(qUserDelete, id)
+	return err
+}
@@ -0,0 +1,65 @@
+// store/vat.go - Output VAT on payments, by quarter and rate
+package store
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// VATReport returns a year's four quarters of sales and output VAT, by rate.
+// Each paid project is one payment, at its own rate; adjusting entries
+// correct the quarter they were posted in at their project's rate.
+func (db *DB) VATReport(year int) ([]models.VATQuarter, error) {
+	quarters := make([]models.VATQuarter, 4)
+	for i := range quarters {
+		quarters[i] = models.VATQuarter{Year: year, Quarter: i + 1}
+	}
+
+	paid, err := db.paidProjects()
+	if err != nil {
+		return nil, err
+	}
+	rates := make(map[int64]float64, len(paid))
+	for _, p := range paid {
+		rates[p.ID] = p.VATPercent
+		at := p.PaymentTime()
+		if at.Year() != year {
+			continue
+		}
+		r := quarters[models.QuarterOf(at)-1].Rate(p.VATPercent)
+		r.Payments++
+		r.NetCents += p.RevenueCents
+		r.VATCents += p.VATCents()
+	}
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	adjustments, err := db.ListAdjustmentsPosted(from, from.AddDate(1, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range adjustments {
+		pct, ok := rates[a.ProjectID]
+		if !ok {
+			p, err := db.GetProject(a.ProjectID)
+			if err != nil {
+				return nil, err
+			}
+			pct = models.DefaultVATPercent
+			if p != nil {
+				pct = p.VATPercent
+			}
+			rates[a.ProjectID] = pct
+		}
+		r := quarters[models.QuarterOf(a.PostedOn)-1].Rate(pct)
+		r.NetCents += a.RevenueCents
+		r.VATCents += models.VATOn(a.RevenueCents, pct)
+	}
+
+	for i := range quarters {
+		slices.SortFunc(quarters[i].Rates, func(a, b models.VATRate) int { return cmp.Compare(b.Percent, a.Percent) })
+	}
+	return quarters, nil
+}
@@ -0,0 +1,172 @@
+// store/webhooks.go - Outbound webhooks and their delivery log
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// webhookScanner for DRY row scanning
+type webhookScanner struct {
+	dest *models.Webhook
+}
+
+// webhookFields lists a webhook's selected columns in scan order, with its
+// pending and failed deliveries counted
+func webhookFields(w *models.Webhook) []field {
+	return []field{
+		{"id", &w.ID}, {"url", &w.URL}, {"secret", &w.Secret}, {"events", jsonText{&w.Events}}, {"enabled", &w.Enabled},
+		{"(SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = webhooks.id AND status = 'pending')", &w.Pending},
+		{"(SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = webhooks.id AND status = 'failed')", &w.Failed},
+		{"created_at", &w.CreatedAt},
+	}
+}
+
+func (s webhookScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, webhookFields(s.dest))
+}
+
+func (s webhookScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// webhookDeliveryScanner for DRY row scanning
+type webhookDeliveryScanner struct {
+	dest *models.WebhookDelivery
+}
+
+// webhookDeliveryFields lists a delivery's selected columns in scan order
+func webhookDeliveryFields(d *models.WebhookDelivery) []field {
+	return []field{
+		{"id", &d.ID}, {"webhook_id", &d.WebhookID}, {"event", &d.Event}, {"payload", &d.Payload}, {"status", &d.Status},
+		{"attempts", &d.Attempts}, {"response_code", &d.ResponseCode}, {"error", &d.Error},
+		{"next_attempt_at", opt(&d.NextAttemptAt)}, {"delivered_at", opt(&d.DeliveredAt)}, {"created_at", &d.CreatedAt},
+	}
+}
+
+func (s webhookDeliveryScanner) scan(scan func(dest ...any) error) error {
+	return scanInto(scan, webhookDeliveryFields(s.dest))
+}
+
+func (s webhookDeliveryScanner) Scan(rows *sql.Rows) error {
+	return s.scan(rows.Scan)
+}
+
+// ListWebhooks returns every webhook, paused ones too
+func (db *DB) ListWebhooks() ([]models.Webhook, error) {
+	rows, err := db.Query(qWebhooksAll)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.Webhook { return &models.Webhook{} },
+		func(w *models.Webhook) scanner { return webhookScanner{w} })
+}
+
+// GetWebhook fetches a webhook by ID
+func (db *DB) GetWebhook(id int64) (*models.Webhook, error) {
+	w := &models.Webhook{}
+	err := webhookScanner{w}.scan(db.QueryRow(qWebhookByID, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return w, err
+}
+
+// CreateWebhook inserts a webhook
+func (db *DB) CreateWebhook(w *models.Webhook) error {
+	if w.Events == nil {
+		w.Events = []string{}
+	}
+	return db.QueryRow(qWebhookInsert, w.URL, w.Secret, jsonArg(w.Events), w.Enabled).Scan(&w.ID, &w.CreatedAt)
+}
+
+// SetWebhookEnabled pauses or resumes a webhook
+func (db *DB) SetWebhookEnabled(id int64, enabled bool) error {
+	_, err := db.Exec(qWebhookSetEnabled, enabled, id)
+	return err
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func (db *DB) DeleteWebhook(id int64) error {
+	_, err := db.Exec(qWebhookDelete, id)
+	return err
+}
+
+// ListWebhookDeliveries returns a webhook's latest deliveries, newest first
+func (db *DB) ListWebhookDeliveries(webhookID int64, limit int) ([]models.WebhookDelivery, error) {
+	return db.queryWebhookDeliveries(qWebhookDeliveriesByWebhook, webhookID, limit)
+}
+
+// DueWebhookDeliveries returns the pending deliveries of enabled webhooks
+// whose next attempt is at or before now
+func (db *DB) DueWebhookDeliveries(now time.Time) ([]models.WebhookDelivery, error) {
+	return db.queryWebhookDeliveries(qWebhookDeliveriesDue, now.UTC())
+}
+
+func (db *DB) queryWebhookDeliveries(query string, args ...any) ([]models.WebhookDelivery, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAll(rows,
+		func() *models.WebhookDelivery { return &models.WebhookDelivery{} },
+		func(d *models.WebhookDelivery) scanner { return webhookDeliveryScanner{d} })
+}
+
+// GetWebhookDelivery fetches a delivery by ID
+func (db *DB) GetWebhookDelivery(id int64) (*models.WebhookDelivery, error) {
+	d := &models.WebhookDelivery{}
+	err := webhookDeliveryScanner{d}.scan(db.QueryRow(qWebhookDeliveryByID, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return d, err
+}
+
+// CreateWebhookDelivery queues a payload for a webhook
+func (db *DB) CreateWebhookDelivery(d *models.WebhookDelivery) error {
+	return db.QueryRow(qWebhookDeliveryInsert, d.WebhookID, d.Event, d.Payload, d.Status, optUTC(d.NextAttemptAt)).
+		Scan(&d.ID, &d.CreatedAt)
+}
+
+// ClaimWebhookDelivery takes a due delivery for this instance until until;
+// false means another instance already claimed (or finished) it
+func (db *DB) ClaimWebhookDelivery(id int64, now, until time.Time) (bool, error) {
+	res, err := db.Exec(qWebhookDeliveryClaim, until.UTC(), id, now.UTC())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// SaveWebhookAttempt records how an attempt at d went: its status, count,
+// response and, for a pending delivery, when to try again
+func (db *DB) SaveWebhookAttempt(d *models.WebhookDelivery) error {
+	_, err := db.Exec(qWebhookDeliverySaveAttempt, d.Status, d.Attempts, d.ResponseCode, d.Error,
+		optUTC(d.NextAttemptAt), optUTC(d.DeliveredAt), d.ID)
+	return err
+}
+
+// PruneWebhookDeliveries drops delivered and failed deliveries older than
+// days from the log
+func (db *DB) PruneWebhookDeliveries(days int) error {
+	_, err := db.Exec(qWebhookDeliveriesPrune, fmt.Sprintf("-%d days", days))
+	return err
+}
+
+// optUTC stores an optional time in UTC, NULL when unset
+func optUTC(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
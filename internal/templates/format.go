@@ -0,0 +1,479 @@
+// templates/format.go - Display formatting helpers shared by templates
+package templates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/noor-latif/fulldash/internal/auth"
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// formatCents renders an integer cent amount as kronor with two decimals
+func formatCents(cents int64) string {
+	return fmt.Sprintf("%.2f kr", float64(cents)/100)
+}
+
+// wholeKr renders a cent amount rounded to whole kronor, for totals
+func wholeKr(cents int64) string {
+	return fmt.Sprintf("%.0f kr", kronor(cents))
+}
+
+// kronor converts cents to kronor for chart scaling and form values
+func kronor(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+// csrfHeaders renders the hx-headers JSON that makes HTMX send the CSRF token
+func csrfHeaders(ctx context.Context) string {
+	b, _ := json.Marshal(map[string]string{auth.CSRFHeader: auth.CSRFTokenFrom(ctx)})
+	return string(b)
+}
+
+// portalStatus renders a project status in client-facing wording
+func portalStatus(s models.ProjectStatus) string {
+	switch s {
+	case models.StatusNew:
+		return "Not started"
+	case models.StatusProgress:
+		return "In progress"
+	case models.StatusDone:
+		return "Delivered — awaiting payment"
+	case models.StatusPaid:
+		return "Paid"
+	}
+	return strings.ReplaceAll(string(s), "_", " ")
+}
+
+// triggerLabel renders an automation trigger as "moves to done",
+// "moves done → paid" or "payment received"
+func triggerLabel(t models.AutomationTrigger) string {
+	if s, ok := strings.CutPrefix(string(t), "status:"); ok {
+		return "moves to " + s
+	}
+	if s, ok := strings.CutPrefix(string(t), "transition:"); ok {
+		from, to, _ := strings.Cut(s, ">")
+		return "moves " + from + " → " + to
+	}
+	return strings.ReplaceAll(string(t), "_", " ")
+}
+
+// deliveryLabel renders a delivery mode for the preferences form
+func deliveryLabel(m models.DeliveryMode) string {
+	switch m {
+	case models.DeliveryInstant:
+		return "Instant"
+	case models.DeliveryDigest:
+		return "Daily digest"
+	case models.DeliveryOff:
+		return "Off"
+	}
+	return string(m)
+}
+
+// hasRate reports whether a stored exchange rate exists for currency
+func hasRate(rates []models.ExchangeRate, currency string) bool {
+	for _, r := range rates {
+		if r.Currency == currency {
+			return true
+		}
+	}
+	return false
+}
+
+// hasProject reports whether projects includes the project with id
+func hasProject(projects []models.Project, id int64) bool {
+	for _, p := range projects {
+		if p.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// expenseQuery is the filter as a query string, for links and for requests
+// that re-render the log
+func expenseQuery(f models.ExpenseFilter) string {
+	q := url.Values{"year": {strconv.Itoa(f.Year)}}
+	if f.Category != "" {
+		q.Set("category", string(f.Category))
+	}
+	return q.Encode()
+}
+
+// formatMoney renders cents in a currency; "" is the base currency (kronor)
+func formatMoney(cents int64, currency string) string {
+	if currency == "" {
+		return formatCents(cents)
+	}
+	return fmt.Sprintf("%.2f %s", kronor(cents), currency)
+}
+
+// currencyLabel names a currency for form labels; "" is kronor
+func currencyLabel(currency string) string {
+	if currency == "" {
+		return "kr"
+	}
+	return currency
+}
+
+// invoiceListURL links the invoice list filtered by status ("" for all)
+func invoiceListURL(s models.InvoiceStatus) string {
+	if s == "" {
+		return "/invoices"
+	}
+	return "/invoices?" + url.Values{"status": {string(s)}}.Encode()
+}
+
+// invoiceDate shows an invoice date, or a dash while it's unset (a draft)
+func invoiceDate(t time.Time) string {
+	if t.IsZero() {
+		return "—"
+	}
+	return t.Format("2006-01-02")
+}
+
+// invoiceMoveLabel is the button that moves an invoice to a status
+func invoiceMoveLabel(to models.InvoiceStatus) string {
+	switch to {
+	case models.InvoicePaid:
+		return "Mark paid"
+	case models.InvoiceVoid:
+		return "Void"
+	default:
+		return "Undo payment"
+	}
+}
+
+// quoteListURL links the quote list filtered by status ("" for all)
+func quoteListURL(s models.QuoteStatus) string {
+	if s == "" {
+		return "/quotes"
+	}
+	return "/quotes?" + url.Values{"status": {string(s)}}.Encode()
+}
+
+// quoteMoveLabel is the button that moves a quote to a status
+func quoteMoveLabel(to models.QuoteStatus) string {
+	switch to {
+	case models.QuoteAccepted:
+		return "Mark accepted"
+	case models.QuoteDeclined:
+		return "Mark declined"
+	default:
+		return "Undo answer"
+	}
+}
+
+// lineQuantity and linePrice fill a draft's line inputs; blank rows stay empty
+func lineQuantity(l models.InvoiceLine) string {
+	if l.Quantity == 0 {
+		return "1"
+	}
+	return strconv.FormatFloat(l.Quantity, 'f', -1, 64)
+}
+
+func linePrice(l models.InvoiceLine) string {
+	if l.Description == "" && l.UnitCents == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(kronor(l.UnitCents), 'f', 2, 64)
+}
+
+// orDash shows an empty audit value as a dash
+func orDash(s string) string {
+	if s == "" {
+		return "—"
+	}
+	return s
+}
+
+// optFloat shows an optional simulator override, "" when unset
+func optFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', -1, 64)
+}
+
+// optCents shows an optional cent amount in kronor, "" when unset
+func optCents(c *int64) string {
+	if c == nil {
+		return ""
+	}
+	return strconv.FormatFloat(kronor(*c), 'f', -1, 64)
+}
+
+// optInt shows an optional whole number, "" when unset
+func optInt(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
+
+// paymentTerms are the terms to offer a project on, including its current
+// ones when they aren't among the usual
+func paymentTerms(current int) []int {
+	if slices.Contains(models.PaymentTerms, current) {
+		return models.PaymentTerms
+	}
+	return append([]int{current}, models.PaymentTerms...)
+}
+
+// percentList shows percentages as "80, 100" for a text input
+func percentList(pcts []int) string {
+	parts := make([]string, len(pcts))
+	for i, p := range pcts {
+		parts[i] = strconv.Itoa(p)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// budgetValue shows a project's budget for the form's input, in its unit
+func budgetValue(p *models.Project) string {
+	switch {
+	case p.BudgetHours > 0:
+		return strconv.FormatFloat(p.BudgetHours, 'f', -1, 64)
+	case p.BudgetCents > 0:
+		return strconv.FormatFloat(kronor(p.BudgetCents), 'f', -1, 64)
+	}
+	return ""
+}
+
+// budgetBurn shows what of a project's budget is burned, in its unit
+func budgetBurn(p models.Project) string {
+	if p.BudgetHours > 0 {
+		return fmt.Sprintf("%.1f / %.1f h", p.LoggedHours, p.BudgetHours)
+	}
+	return wholeKr(p.BurnCents()) + " / " + wholeKr(p.BudgetCents)
+}
+
+// optDate shows an optional date for a date input, "" when unset
+func optDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// goalOwner names whose revenue a goal counts: a person's share, or everyone's
+func goalOwner(g models.Goal, people models.People) string {
+	if g.PersonID == nil {
+		return "Combined"
+	}
+	return people.Name(*g.PersonID)
+}
+
+// goalStyle tints a person's goal with their color
+func goalStyle(g models.Goal, people models.People) templ.SafeCSS {
+	if g.PersonID == nil {
+		return ""
+	}
+	p, _ := people.Get(*g.PersonID)
+	return personStyle(p)
+}
+
+// paceStyle places the pace marker where an even pace would be by now
+func paceStyle(p models.GoalProgress) string {
+	return fmt.Sprintf("left: %.1f%%", min(float64(p.PaceCents)/float64(p.TargetCents), 1)*100)
+}
+
+// signedKr renders a difference in cents as whole kronor with an explicit sign
+func signedKr(cents int64) string {
+	return fmt.Sprintf("%+.0f kr", kronor(cents))
+}
+
+// expensePolicyLabel renders an agreement's expense policy
+func expensePolicyLabel(p models.ExpensePolicy) string {
+	switch p {
+	case models.ExpenseReimburse:
+		return "Reimbursed first"
+	case models.ExpensePayerBears:
+		return "Payer's own cost"
+	}
+	return string(p)
+}
+
+// splitMethod explains how a project's profit was divided
+func splitMethod(split *models.RevenueSplit, p *models.Project, people models.People) string {
+	switch split.Method {
+	case "override":
+		return "Split override for this project: " + people.Percents(p.SplitOverride)
+	case "percent":
+		return "Fixed percentages from the owner agreement"
+	case "hours":
+		return "By hours logged"
+	case "rates":
+		return "By hours logged, each weighed by its hourly rate"
+	case "owner":
+		return "Equally between who secured it: " + people.Names(p.SecuredBy)
+	}
+	return "Nothing to split yet"
+}
+
+// effectiveRate is what a person earned per hour logged: their share less
+// reimbursements, over their hours
+func effectiveRate(split *models.RevenueSplit, id int64, hours float64) string {
+	if hours <= 0 {
+		return "—"
+	}
+	return formatCents(int64(math.Round(float64(split.ShareCents[id]-split.ReimbursedCents[id])/hours))) + "/h"
+}
+
+// agreementState says whether list[i] (ordered newest first) is upcoming,
+// in force or superseded
+func agreementState(list []models.Agreement, i int) string {
+	now := time.Now()
+	switch {
+	case list[i].EffectiveFrom.After(now):
+		return "upcoming"
+	case i == 0 || list[i-1].EffectiveFrom.After(now):
+		return "in force"
+	}
+	return "superseded"
+}
+
+// archiveYear shows the archive's year filter, "" for any year
+func archiveYear(year int) string {
+	if year == 0 {
+		return ""
+	}
+	return strconv.Itoa(year)
+}
+
+// barStyle sizes a stacked bar segment as its share of scale
+func barStyle(value, scale float64) string {
+	if value <= 0 || scale <= 0 {
+		return "width: 0%"
+	}
+	return fmt.Sprintf("width: %.1f%%", value/scale*100)
+}
+
+// columnStyle sizes a trend chart column to value's share of scale
+func columnStyle(value, scale float64) string {
+	if value <= 0 || scale <= 0 {
+		return "height: 0%"
+	}
+	return fmt.Sprintf("height: %.1f%%", value/scale*100)
+}
+
+// growth renders the change from then to now as " (+12%)", or "" when there
+// is nothing to compare with
+func growth(now, then float64) string {
+	if then <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%+.0f%%)", (now-then)/then*100)
+}
+
+// personStyle sets the --person-color the person's tags and cards are
+// tinted with; the color is validated, so it is safe to render as is
+func personStyle(p models.Person) templ.SafeCSS {
+	if !p.ValidColor() {
+		return ""
+	}
+	return templ.SafeCSS("--person-color: " + p.Color)
+}
+
+// personColor is a person's color for charts, gray if they have none
+func personColor(p models.Person) string {
+	if !p.ValidColor() {
+		return "var(--text-secondary)"
+	}
+	return p.Color
+}
+
+// pieStyle draws each person's part of a total as a conic gradient, in
+// display order
+func pieStyle(people models.People, values models.PerPerson[float64]) string {
+	var total float64
+	for _, p := range people {
+		total += max(values[p.ID], 0)
+	}
+	if total == 0 {
+		return "background: var(--bg-tertiary)"
+	}
+	var stops []string
+	var at float64
+	for _, p := range people {
+		at += max(values[p.ID], 0)
+		stops = append(stops, fmt.Sprintf("%s 0 %.1f%%", personColor(p), at/total*100))
+	}
+	return "background: conic-gradient(" + strings.Join(stops, ", ") + ")"
+}
+
+// stackStyle sizes a person's part of a stacked trend column and colors it
+func stackStyle(p models.Person, value, scale float64) string {
+	return columnStyle(value, scale) + "; background: " + personColor(p)
+}
+
+// segmentStyle sizes a person's part of a stacked bar and colors it
+func segmentStyle(p models.Person, value, scale float64) string {
+	return barStyle(value, scale) + "; background: " + personColor(p)
+}
+
+// involved returns the active people plus anyone inactive who has a non-zero
+// amount, so past partners still show where they had a share
+func involved[V int64 | float64](people models.People, amounts ...models.PerPerson[V]) models.People {
+	var out models.People
+	for _, p := range people {
+		keep := p.Active
+		for _, m := range amounts {
+			keep = keep || m[p.ID] != 0
+		}
+		if keep {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// kronorEach converts per-person cents to kronor for charts
+func kronorEach(cents models.PerPerson[int64]) models.PerPerson[float64] {
+	out := models.PerPerson[float64]{}
+	for id, c := range cents {
+		out[id] = kronor(c)
+	}
+	return out
+}
+
+// personFigures lists each person's value for a label: "Noor 10 kr, Ahmad 5 kr"
+func personFigures(people models.People, values models.PerPerson[float64], format string) string {
+	parts := make([]string, len(people))
+	for i, p := range people {
+		parts[i] = p.Name + " " + fmt.Sprintf(format, values[p.ID])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// boardSorts are the card orders offered on the board, as sort query values
+var boardSorts = []struct{ value, label string }{
+	{"", "Newest first"},
+	{"created_at", "Oldest first"},
+	{"-revenue", "Highest revenue"},
+	{"revenue", "Lowest revenue"},
+	{"client", "Client A–Z"},
+	{"-client", "Client Z–A"},
+	{"due_date", "Payment expected soonest"},
+	{"-due_date", "Payment expected latest"},
+}
+
+// sortValue is the filter's sort order as a sort query value
+func sortValue(f models.ProjectFilter) string {
+	if f.Sort == "" {
+		return ""
+	}
+	if f.Desc {
+		return "-" + string(f.Sort)
+	}
+	return string(f.Sort)
+}
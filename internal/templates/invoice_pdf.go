@@ -0,0 +1,227 @@
+// templates/invoice_pdf.go - Invoices as PDF documents
+package templates
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/pdf"
+)
+
+// Invoice page layout, in points
+const (
+	pdfMargin = 50.0
+	pdfRight  = pdf.PageWidth - pdfMargin
+	pdfBottom = pdf.PageHeight - 70 // lines stop here; the footer sits below
+	pdfRow    = 18.0
+	qrModule  = 2.5 // a QR code module's size
+)
+
+// Line table columns: the description starts at the margin, the rest are
+// right-aligned at these x positions
+var pdfColumns = struct{ qty, price, amount float64 }{360, 450, pdfRight}
+
+// InvoicePDF renders an invoice with the company's details, its lines and
+// VAT breakdown, and how to pay: bankgiro with the invoice number as
+// reference and, when payURL is set, a link and QR code to pay online
+func InvoicePDF(inv *models.Invoice, company models.Company, payURL string) []byte {
+	title := "Invoice " + inv.Label()
+	doc := pdf.New(title)
+	page := doc.AddPage()
+
+	// Sender, top left; the invoice's number and dates, top right
+	y := pdfMargin + 18
+	page.Text(pdfMargin, y, pdf.Bold, 18, company.Name)
+	for _, l := range strings.Split(company.Address, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			y += 14
+			page.Text(pdfMargin, y, pdf.Regular, 10, l)
+		}
+	}
+	if company.Email != "" {
+		y += 14
+		page.Text(pdfMargin, y, pdf.Regular, 10, company.Email)
+	}
+
+	heading := "INVOICE"
+	switch inv.Status {
+	case models.InvoiceDraft:
+		heading = "DRAFT INVOICE"
+	case models.InvoiceVoid:
+		heading = "VOID INVOICE"
+	}
+	page.TextRight(pdfRight, pdfMargin+18, pdf.Bold, 20, heading)
+	ry := pdfMargin + 18
+	for _, kv := range [][2]string{
+		{"Invoice number", orDash(inv.Number)},
+		{"Invoice date", invoiceDate(inv.IssueDate)},
+		{"Due date", invoiceDate(inv.DueDate)},
+		{"Payment terms", strconv.Itoa(inv.PaymentDays) + " days"},
+	} {
+		ry += 16
+		page.Text(pdfColumns.price-60, ry, pdf.Regular, 10, kv[0])
+		page.TextRight(pdfRight, ry, pdf.Bold, 10, kv[1])
+	}
+
+	// Recipient
+	y = max(y, ry) + 40
+	page.Text(pdfMargin, y, pdf.Regular, 9, "BILL TO")
+	y += 16
+	page.Text(pdfMargin, y, pdf.Bold, 12, inv.Client)
+
+	// Lines, continuing on new pages as needed
+	y += 40
+	header := func() {
+		page.Text(pdfMargin, y, pdf.Bold, 10, "Description")
+		page.TextRight(pdfColumns.qty, y, pdf.Bold, 10, "Quantity")
+		page.TextRight(pdfColumns.price, y, pdf.Bold, 10, "Unit price")
+		page.TextRight(pdfColumns.amount, y, pdf.Bold, 10, "Amount")
+		page.Line(pdfMargin, y+6, pdfRight, y+6, 0.75, 0)
+		y += 6
+	}
+	header()
+	for _, l := range inv.Lines {
+		if y+pdfRow > pdfBottom {
+			invoiceFooter(page, company)
+			page = doc.AddPage()
+			y = pdfMargin + 18
+			header()
+		}
+		y += pdfRow
+		page.Text(pdfMargin, y, pdf.Regular, 10, fitText(l.Description, pdf.Regular, 10, pdfColumns.qty-pdfMargin-70))
+		page.TextRight(pdfColumns.qty, y, pdf.Regular, 10, strconv.FormatFloat(l.Quantity, 'f', -1, 64))
+		page.TextRight(pdfColumns.price, y, pdf.Regular, 10, formatMoney(l.UnitCents, inv.Currency))
+		page.TextRight(pdfColumns.amount, y, pdf.Regular, 10, formatMoney(l.AmountCents(), inv.Currency))
+	}
+	page.Line(pdfMargin, y+8, pdfRight, y+8, 0.5, 0.6)
+
+	// Totals and payment details (with the QR code) need about 300pt;
+	// start a page if they don't fit
+	if y+300 > pdfBottom {
+		invoiceFooter(page, company)
+		page = doc.AddPage()
+		y = pdfMargin
+	}
+	y += 10
+	for _, t := range []struct {
+		label string
+		cents int64
+		font  pdf.Font
+	}{
+		{"Subtotal", inv.SubtotalCents(), pdf.Regular},
+		{"VAT " + strconv.FormatFloat(inv.VATPercent, 'f', -1, 64) + "%", inv.VATCents(), pdf.Regular},
+		{"Total to pay", inv.TotalCents(), pdf.Bold},
+	} {
+		y += pdfRow
+		page.Text(pdfColumns.qty, y, t.font, 11, t.label)
+		page.TextRight(pdfRight, y, t.font, 11, formatMoney(t.cents, inv.Currency))
+	}
+
+	// How to pay
+	y += 40
+	page.Rect(pdfMargin, y-14, pdfRight-pdfMargin, 1, 0.8)
+	y += 6
+	page.Text(pdfMargin, y, pdf.Bold, 11, "Payment")
+	py := y
+	for _, kv := range [][2]string{
+		{"Bankgiro", orDash(company.Bankgiro)},
+		{"Reference", orDash(inv.Number)},
+		{"Amount", formatMoney(inv.TotalCents(), inv.Currency)},
+		{"Due date", invoiceDate(inv.DueDate)},
+	} {
+		py += 16
+		page.Text(pdfMargin, py, pdf.Regular, 10, kv[0])
+		page.Text(pdfMargin+80, py, pdf.Bold, 10, kv[1])
+	}
+	if payURL != "" {
+		py += 24
+		page.Text(pdfMargin, py, pdf.Regular, 10, "Pay online:")
+		for _, l := range breakText(payURL, pdf.Regular, 8, pdfColumns.price-pdfMargin) {
+			py += 12
+			page.Text(pdfMargin, py, pdf.Regular, 8, l)
+		}
+		if code, err := qr.Encode(payURL, qr.M, qr.Unicode); err == nil {
+			drawQR(page, code, pdfRight-float64(code.Bounds().Dx()+8)*qrModule, y-10)
+		}
+	}
+
+	invoiceFooter(page, company)
+	return doc.Bytes()
+}
+
+// invoiceFooter prints the company's registration details along the bottom
+func invoiceFooter(page *pdf.Page, c models.Company) {
+	var parts []string
+	if c.Name != "" {
+		parts = append(parts, c.Name)
+	}
+	if c.OrgNumber != "" {
+		parts = append(parts, "Org. no. "+c.OrgNumber)
+	}
+	if c.VATNumber != "" {
+		parts = append(parts, "VAT no. "+c.VATNumber)
+	}
+	if c.Bankgiro != "" {
+		parts = append(parts, "Bankgiro "+c.Bankgiro)
+	}
+	if len(parts) == 0 {
+		return
+	}
+	y := pdf.PageHeight - 40
+	page.Line(pdfMargin, y-14, pdfRight, y-14, 0.5, 0.6)
+	line := strings.Join(parts, "  ·  ")
+	page.Text((pdf.PageWidth-pdf.Width(pdf.Regular, 8, line))/2, y, pdf.Regular, 8, line)
+}
+
+// drawQR draws a QR code with its top-left corner at x, y, inside the
+// four-module quiet zone scanners need
+func drawQR(page *pdf.Page, code barcode.Barcode, x, y float64) {
+	quiet := 4 * qrModule
+	size := code.Bounds().Dx()
+	for row := range size {
+		for col := range size {
+			if color.GrayModel.Convert(code.At(col, row)).(color.Gray).Y < 128 {
+				page.Rect(x+quiet+float64(col)*qrModule, y+quiet+float64(row)*qrModule, qrModule, qrModule, 0)
+			}
+		}
+	}
+}
+
+// fitText shortens s with an ellipsis until it fits width
+func fitText(s string, font pdf.Font, size, width float64) string {
+	if pdf.Width(font, size, s) <= width {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && pdf.Width(font, size, string(r)+"…") > width {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "…"
+}
+
+// breakText splits s into lines that fit width, anywhere (it's for URLs)
+func breakText(s string, font pdf.Font, size, width float64) []string {
+	var lines []string
+	line := ""
+	for _, r := range s {
+		if line != "" && pdf.Width(font, size, line+string(r)) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		line += string(r)
+	}
+	return append(lines, line)
+}
+
+// InvoiceFilename is the download name of an invoice's PDF
+func InvoiceFilename(inv *models.Invoice) string {
+	if inv.Number == "" {
+		return fmt.Sprintf("invoice-draft-%d.pdf", inv.ID)
+	}
+	return "invoice-" + inv.Number + ".pdf"
+}
@@ -0,0 +1,117 @@
+// templates/mail.go - Plain-text emails: invoices, payment receipts and the weekly summary
+package templates
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// InvoiceEmail is the message an invoice's PDF is attached to: what's owed,
+// by when, and how to pay
+func InvoiceEmail(inv *models.Invoice, company models.Company, payURL string) (subject, body string) {
+	subject = "Invoice " + inv.Label()
+	if company.Name != "" {
+		subject += " from " + company.Name
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hello %s,\n\n", inv.Client)
+	fmt.Fprintf(&b, "Please find invoice %s attached.\n\n", inv.Label())
+	fmt.Fprintf(&b, "Amount due: %s\n", formatMoney(inv.TotalCents(), inv.Currency))
+	fmt.Fprintf(&b, "Due date:   %s\n", invoiceDate(inv.DueDate))
+	if company.Bankgiro != "" {
+		fmt.Fprintf(&b, "Bankgiro:   %s (reference %s)\n", company.Bankgiro, orDash(inv.Number))
+	}
+	if payURL != "" {
+		fmt.Fprintf(&b, "\nPay online: %s\n", payURL)
+	}
+	writeSignature(&b, company)
+	return subject, b.String()
+}
+
+// ReceiptEmail confirms a payment received for a project
+func ReceiptEmail(p *models.Project, amountCents int64, currency string, paidOn time.Time, company models.Company) (subject, body string) {
+	subject = "Payment received"
+	if company.Name != "" {
+		subject += " — " + company.Name
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hello %s,\n\n", p.Client)
+	b.WriteString("Thank you, we have received your payment.\n\n")
+	fmt.Fprintf(&b, "Amount:  %s\n", formatMoney(amountCents, currency))
+	if vat := amountCents - models.ExcludingVAT(amountCents, p.VATPercent); vat != 0 {
+		fmt.Fprintf(&b, "VAT:     %s (%s%%)\n", formatMoney(vat, currency), strconv.FormatFloat(p.VATPercent, 'f', -1, 64))
+	}
+	fmt.Fprintf(&b, "Date:    %s\n", paidOn.Format("2006-01-02"))
+	if p.Description != "" {
+		fmt.Fprintf(&b, "Project: %s\n", p.Description)
+	}
+	writeSignature(&b, company)
+	return subject, b.String()
+}
+
+// WeeklyEmail is a partner's summary of the week: revenue received,
+// projects added and moved, what's overdue and the hours logged.
+// dashboardURL is linked when set.
+func WeeklyEmail(s models.WeeklySummary, name, dashboardURL string) (subject, body string) {
+	last := s.To.AddDate(0, 0, -1)
+	subject = "Weekly summary: " + wholeKr(s.PaidCents()) + " received"
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hello %s,\n\n", name)
+	fmt.Fprintf(&b, "Here's the week of %s to %s.\n", s.From.Format("2 Jan"), last.Format("2 Jan 2006"))
+
+	fmt.Fprintf(&b, "\nRevenue received: %s\n", formatCents(s.PaidCents()))
+	for _, p := range s.Paid {
+		fmt.Fprintf(&b, "- %s: %s\n", p.Client, formatCents(p.RevenueCents))
+	}
+
+	fmt.Fprintf(&b, "\nNew projects: %d\n", len(s.Created))
+	for _, p := range s.Created {
+		fmt.Fprintf(&b, "- %s: %s\n", p.Client, formatCents(p.RevenueCents))
+	}
+
+	fmt.Fprintf(&b, "\nProjects moved: %d\n", len(s.Moved))
+	for _, a := range s.Moved {
+		for _, c := range a.Changes {
+			if c.Field == "status" {
+				fmt.Fprintf(&b, "- %s: %s → %s (%s)\n", a.Client, c.Old, c.New, a.CreatedAt.Local().Format("Mon"))
+			}
+		}
+	}
+
+	fmt.Fprintf(&b, "\nOverdue: %s\n", formatCents(s.OverdueCents()))
+	for _, r := range s.Overdue {
+		fmt.Fprintf(&b, "- %s: %s, due %s (%d days)\n", r.Client, formatCents(r.AmountCents), r.DueOn.Format("2006-01-02"), r.DaysOverdue)
+	}
+
+	fmt.Fprintf(&b, "\nHours logged: %.1f\n", s.TotalHours())
+	for _, h := range s.Hours {
+		fmt.Fprintf(&b, "- %s: %.1f\n", h.Person, h.Hours)
+	}
+
+	if dashboardURL != "" {
+		fmt.Fprintf(&b, "\nOpen the dashboard: %s\n", dashboardURL)
+	}
+	b.WriteString("\nYou can turn this email off under Notifications.\n")
+	return subject, b.String()
+}
+
+// writeSignature signs off with the company's name and registration details
+func writeSignature(b *strings.Builder, c models.Company) {
+	if c.Name == "" {
+		return
+	}
+	fmt.Fprintf(b, "\nKind regards,\n%s\n", c.Name)
+	if c.OrgNumber != "" {
+		fmt.Fprintf(b, "Org. no. %s\n", c.OrgNumber)
+	}
+	if c.Email != "" {
+		fmt.Fprintf(b, "%s\n", c.Email)
+	}
+}
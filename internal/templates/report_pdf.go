@@ -0,0 +1,242 @@
+// templates/report_pdf.go - The monthly report as a PDF document
+package templates
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/models"
+	"github.com/noor-latif/fulldash/internal/pdf"
+)
+
+// reportChartHeight is the revenue chart's tallest bar, in points
+const reportChartHeight = 120.0
+
+// MonthlyReportPDF renders a month's report: key figures, the last twelve
+// months' revenue as a bar chart, each person's share and hours, the
+// projects paid and the invoices outstanding
+func MonthlyReportPDF(r models.MonthlyReport, company models.Company, people models.People) []byte {
+	month := r.Month.Format("January 2006")
+	doc := pdf.New("Monthly report " + month)
+	page := doc.AddPage()
+	pages := 1
+	footer := func() {
+		line := fmt.Sprintf("%s  ·  Monthly report %s  ·  Page %d", cmp.Or(company.Name, "FullDash"), month, pages)
+		y := pdf.PageHeight - 40
+		page.Line(pdfMargin, y-14, pdfRight, y-14, 0.5, 0.6)
+		page.Text((pdf.PageWidth-pdf.Width(pdf.Regular, 8, line))/2, y, pdf.Regular, 8, line)
+	}
+
+	y := pdfMargin + 18
+	page.Text(pdfMargin, y, pdf.Bold, 18, cmp.Or(company.Name, "FullDash"))
+	page.TextRight(pdfRight, y, pdf.Bold, 20, "MONTHLY REPORT")
+	y += 20
+	page.TextRight(pdfRight, y, pdf.Regular, 12, month)
+	y += 14
+	page.TextRight(pdfRight, y, pdf.Regular, 8, "Made "+r.Generated.Format("2006-01-02 15:04"))
+
+	// Key figures, in boxes across the page
+	cur, prev := r.Current(), r.Previous()
+	y += 20
+	figures := [][2]string{
+		{"Revenue", wholeKr(cur.RevenueCents)},
+		{"Projects paid", strconv.Itoa(cur.Projects)},
+		{"New projects", strconv.Itoa(r.Created)},
+		{"Outstanding invoices", strconv.Itoa(len(r.Outstanding))},
+	}
+	const gap = 10.0
+	box := (pdfRight - pdfMargin - gap*float64(len(figures)-1)) / float64(len(figures))
+	for i, f := range figures {
+		x := pdfMargin + float64(i)*(box+gap)
+		page.Rect(x, y, box, 48, 0.94)
+		page.Text(x+8, y+16, pdf.Regular, 8, strings.ToUpper(f[0]))
+		page.Text(x+8, y+36, pdf.Bold, 14, fitText(f[1], pdf.Bold, 14, box-16))
+	}
+	y += 48
+	if !prev.Start.IsZero() {
+		y += 14
+		note := "Nothing was received in " + prev.Start.Format("January")
+		if prev.RevenueCents != 0 {
+			note = fmt.Sprintf("%+.0f%% on %s (%s)", float64(cur.RevenueCents-prev.RevenueCents)/math.Abs(float64(prev.RevenueCents))*100,
+				prev.Start.Format("January"), wholeKr(prev.RevenueCents))
+		}
+		page.Text(pdfMargin, y, pdf.Regular, 9, note)
+	}
+
+	y += 36
+	page.Text(pdfMargin, y, pdf.Bold, 12, "Revenue, last 12 months")
+	y = reportChart(page, r.Revenue, r.Month, y+10)
+
+	// section starts a titled table with its column headings, on a new page
+	// when the heading and a few rows wouldn't fit
+	type column struct {
+		label string
+		x     float64 // left edge, or right edge when right-aligned
+		right bool
+	}
+	newPage := func() {
+		footer()
+		page = doc.AddPage()
+		pages++
+		y = pdfMargin
+	}
+	var columns []column
+	heading := func() {
+		for _, c := range columns {
+			if c.right {
+				page.TextRight(c.x, y, pdf.Bold, 9, c.label)
+			} else {
+				page.Text(c.x, y, pdf.Bold, 9, c.label)
+			}
+		}
+		page.Line(pdfMargin, y+5, pdfRight, y+5, 0.75, 0)
+		y += 4
+	}
+	section := func(title string, cols ...column) {
+		if y+40+4*pdfRow > pdfBottom {
+			newPage()
+		}
+		y += 40
+		page.Text(pdfMargin, y, pdf.Bold, 12, title)
+		y += 20
+		columns = cols
+		heading()
+	}
+	row := func(font pdf.Font, cells ...string) {
+		if y+pdfRow > pdfBottom {
+			newPage()
+			heading()
+		}
+		y += pdfRow
+		for i, c := range columns {
+			if c.right {
+				page.TextRight(c.x, y, font, 9, cells[i])
+				continue
+			}
+			width := pdfRight - c.x
+			if i+1 < len(columns) {
+				width = columns[i+1].x - c.x - 60
+				if !columns[i+1].right {
+					width = columns[i+1].x - c.x - 10
+				}
+			}
+			page.Text(c.x, y, font, 9, fitText(cells[i], font, 9, width))
+		}
+	}
+	empty := func(s string) {
+		y += pdfRow
+		page.Text(pdfMargin, y, pdf.Regular, 9, s)
+	}
+
+	// Shares and hours: everyone active, or with either this month
+	section("Shares and hours",
+		column{"Person", pdfMargin, false}, column{"Hours", 330, true},
+		column{"Share", 440, true}, column{"Of revenue", pdfRight, true})
+	hours := models.PerPerson[float64]{}
+	for _, p := range r.Hours {
+		hours[p.PersonID] = p.Hours
+	}
+	for _, p := range involved(people, hours, kronorEach(cur.ShareCents)) {
+		var pct string
+		if cur.RevenueCents != 0 {
+			pct = fmt.Sprintf("%.0f%%", float64(cur.ShareCents[p.ID])/float64(cur.RevenueCents)*100)
+		}
+		row(pdf.Regular, p.Name, strconv.FormatFloat(hours[p.ID], 'f', 1, 64), formatCents(cur.ShareCents[p.ID]), pct)
+	}
+	row(pdf.Bold, "Total", strconv.FormatFloat(hours.Total(), 'f', 1, 64), formatCents(cur.ShareCents.Total()), "")
+
+	section("Projects paid",
+		column{"Client", pdfMargin, false}, column{"Description", 190, false},
+		column{"Paid", 440, true}, column{"Revenue", pdfRight, true})
+	var paid int64
+	for _, p := range r.Paid {
+		row(pdf.Regular, p.Client, p.Description, p.PaidAt.Format("2006-01-02"), formatCents(p.RevenueCents))
+		paid += p.RevenueCents
+	}
+	if len(r.Paid) == 0 {
+		empty("No projects were paid in " + month + ".")
+	} else {
+		row(pdf.Bold, "Total", "", "", formatCents(paid))
+	}
+
+	section("Outstanding invoices",
+		column{"Invoice", pdfMargin, false}, column{"Client", 140, false},
+		column{"Due", 380, true}, column{"Overdue", 440, true}, column{"Amount", pdfRight, true})
+	totals := map[string]int64{}
+	for _, inv := range r.Outstanding {
+		var overdue string
+		if days := int(r.Generated.Sub(inv.DueDate).Hours() / 24); inv.Overdue(r.Generated) && days > 0 {
+			overdue = strconv.Itoa(days) + " days"
+		}
+		row(pdf.Regular, inv.Label(), inv.Client, invoiceDate(inv.DueDate), overdue, formatMoney(inv.TotalCents(), inv.Currency))
+		totals[inv.Currency] += inv.TotalCents()
+	}
+	if len(r.Outstanding) == 0 {
+		empty("No invoices are waiting for payment.")
+	} else {
+		var sums []string
+		for _, currency := range slices.Sorted(maps.Keys(totals)) {
+			sums = append(sums, formatMoney(totals[currency], currency))
+		}
+		row(pdf.Bold, "Total", "", "", "", strings.Join(sums, " + "))
+	}
+
+	footer()
+	return doc.Bytes()
+}
+
+// reportChart draws the revenue series as bars from top down, the month
+// reported on dark, and returns where the chart ends
+func reportChart(page *pdf.Page, periods []models.RevenuePeriod, month time.Time, top float64) float64 {
+	base := top + 14 + reportChartHeight // the x axis, below the labels of the tallest bar
+	var highest int64
+	for _, p := range periods {
+		highest = max(highest, p.RevenueCents)
+	}
+	slot := (pdfRight - pdfMargin) / float64(max(len(periods), 1))
+	bar := slot * 0.6
+	for i, p := range periods {
+		x := pdfMargin + float64(i)*slot + (slot-bar)/2
+		mid := x + bar/2
+		if p.RevenueCents > 0 {
+			h := reportChartHeight * float64(p.RevenueCents) / float64(highest)
+			gray := 0.7
+			if p.Start.Year() == month.Year() && p.Start.Month() == month.Month() {
+				gray = 0.25
+			}
+			page.Rect(x, base-h, bar, h, gray)
+			label := compactKr(p.RevenueCents)
+			page.Text(mid-pdf.Width(pdf.Regular, 7, label)/2, base-h-4, pdf.Regular, 7, label)
+		}
+		name := p.Start.Format("Jan")
+		if p.Start.Month() == time.January || i == 0 {
+			name = p.Start.Format("Jan 06")
+		}
+		page.Text(mid-pdf.Width(pdf.Regular, 8, name)/2, base+12, pdf.Regular, 8, name)
+	}
+	page.Line(pdfMargin, base, pdfRight, base, 0.75, 0)
+	return base + 12
+}
+
+// compactKr renders an amount in kronor for a chart label: 950, 12k, 1.2M
+func compactKr(cents int64) string {
+	kr := kronor(cents)
+	switch {
+	case math.Abs(kr) >= 1e6:
+		return fmt.Sprintf("%.1fM", kr/1e6)
+	case math.Abs(kr) >= 1e3:
+		return fmt.Sprintf("%.0fk", kr/1e3)
+	}
+	return fmt.Sprintf("%.0f", kr)
+}
+
+// MonthlyReportFilename is the download name of a month's report
+func MonthlyReportFilename(month time.Time) string {
+	return "report-" + month.Format("2006-01") + ".pdf"
+}
@@ -0,0 +1,241 @@
+// webhooks/webhooks.go - Signed outbound webhooks for domain events, retried with backoff
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/noor-latif/fulldash/internal/events"
+	"github.com/noor-latif/fulldash/internal/models"
+)
+
+// JobName is the scheduler name of the retry job
+const JobName = "webhooks"
+
+// Request headers sent with every delivery
+const (
+	SignatureHeader = "FullDash-Signature"
+	EventHeader     = "FullDash-Event"
+	DeliveryHeader  = "FullDash-Delivery" // the delivery's ID, the same on retries
+)
+
+// secretPrefix marks webhook signing secrets
+const secretPrefix = "whsec_"
+
+// attemptTimeout bounds a single POST
+const attemptTimeout = 10 * time.Second
+
+// claimFor is how long a delivery is held by the instance sending it; if
+// that instance dies mid-send, the delivery is retried afterwards
+const claimFor = time.Minute
+
+// KeepDays is how long delivered and failed deliveries stay in the log
+const KeepDays = 30
+
+// backoff is the wait before each retry. A delivery that still fails after
+// the last one is marked failed.
+var backoff = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour, 12 * time.Hour}
+
+// MaxAttempts is how many times a delivery is tried before it fails
+var MaxAttempts = len(backoff) + 1
+
+// Events are the event types webhooks can subscribe to: projects created,
+// moved, paid and deleted
+var Events = []events.Type{
+	events.ProjectCreated, events.ProjectStatusChanged, events.PaymentReceived,
+	events.MilestonePaid, events.RetainerPaid, events.ProjectDeleted,
+}
+
+// Store is the data access the dispatcher needs
+type Store interface {
+	ListWebhooks() ([]models.Webhook, error)
+	GetWebhook(id int64) (*models.Webhook, error)
+	CreateWebhookDelivery(d *models.WebhookDelivery) error
+	DueWebhookDeliveries(now time.Time) ([]models.WebhookDelivery, error)
+	ClaimWebhookDelivery(id int64, now, until time.Time) (bool, error)
+	SaveWebhookAttempt(d *models.WebhookDelivery) error
+	PruneWebhookDeliveries(days int) error
+}
+
+// Payload is the JSON body POSTed for an event
+type Payload struct {
+	Event       events.Type          `json:"event"`
+	ProjectID   int64                `json:"project_id"`
+	Client      string               `json:"client"`
+	AmountCents int64                `json:"amount_cents"` // project revenue, or the amount paid for payments
+	From        models.ProjectStatus `json:"from,omitempty"`
+	To          models.ProjectStatus `json:"to,omitempty"`
+	Source      string               `json:"source,omitempty"` // user, api, stripe, automation or schedule
+	At          time.Time            `json:"occurred_at"`
+}
+
+// Dispatcher queues a delivery per subscribed webhook for every event and
+// sends it, retrying failures from the scheduled job
+type Dispatcher struct {
+	db     Store
+	client *http.Client
+}
+
+// New creates a dispatcher
+func New(db Store) *Dispatcher {
+	return &Dispatcher{db: db, client: &http.Client{Timeout: attemptTimeout}}
+}
+
+// Subscribe attaches the dispatcher to the events webhooks are sent for
+func (d *Dispatcher) Subscribe(bus *events.Bus) {
+	for _, t := range Events {
+		bus.Subscribe(t, d.onEvent)
+	}
+}
+
+// onEvent queues e for every enabled webhook that wants it, then sends the
+// deliveries in the background so slow endpoints don't hold up the request
+// that caused e
+func (d *Dispatcher) onEvent(ctx context.Context, e events.Event) error {
+	hooks, err := d.db.ListWebhooks()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(Payload{
+		Event: e.Type, ProjectID: e.ProjectID, Client: e.Client, AmountCents: e.AmountCents,
+		From: e.From, To: e.To, Source: e.Source, At: e.At.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var queued []models.WebhookDelivery
+	for _, w := range hooks {
+		if !w.Enabled || !w.Wants(string(e.Type)) {
+			continue
+		}
+		del := models.WebhookDelivery{WebhookID: w.ID, Event: string(e.Type), Payload: string(body), Status: models.WebhookPending, NextAttemptAt: &now}
+		if err := d.db.CreateWebhookDelivery(&del); err != nil {
+			return err
+		}
+		queued = append(queued, del)
+	}
+
+	if len(queued) > 0 {
+		go func() {
+			for _, del := range queued {
+				if err := d.attempt(context.Background(), del); err != nil {
+					log.Printf("[WEBHOOKS] delivery %d: %v", del.ID, err)
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+// RunDue sends every delivery whose next attempt is due, then prunes old
+// deliveries from the log. Endpoints failing are logged on the delivery,
+// not reported as the job failing.
+func (d *Dispatcher) RunDue(ctx context.Context) error {
+	due, err := d.db.DueWebhookDeliveries(time.Now())
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, del := range due {
+		if err := d.attempt(ctx, del); err != nil {
+			errs = append(errs, fmt.Errorf("delivery %d: %w", del.ID, err))
+		}
+	}
+	if err := d.db.PruneWebhookDeliveries(KeepDays); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// attempt claims del and POSTs it once, recording the outcome and when to
+// retry. It returns only store errors.
+func (d *Dispatcher) attempt(ctx context.Context, del models.WebhookDelivery) error {
+	now := time.Now()
+	ok, err := d.db.ClaimWebhookDelivery(del.ID, now, now.Add(claimFor))
+	if err != nil || !ok {
+		return err
+	}
+	w, err := d.db.GetWebhook(del.WebhookID)
+	if err != nil || w == nil {
+		return err
+	}
+
+	del.Attempts++
+	del.ResponseCode, err = d.post(ctx, w, &del)
+	switch {
+	case err == nil:
+		del.Status, del.Error, del.NextAttemptAt, del.DeliveredAt = models.WebhookDelivered, "", nil, &now
+	case del.Attempts >= MaxAttempts:
+		del.Status, del.Error, del.NextAttemptAt = models.WebhookFailed, err.Error(), nil
+		log.Printf("[WEBHOOKS] %s to %s failed after %d attempts: %v", del.Event, w.URL, del.Attempts, err)
+	default:
+		next := now.Add(backoff[del.Attempts-1])
+		del.Error, del.NextAttemptAt = err.Error(), &next
+	}
+	return d.db.SaveWebhookAttempt(&del)
+}
+
+// post sends del's payload to w signed with its secret, returning the
+// response status (0 if there was none)
+func (d *Dispatcher) post(ctx context.Context, w *models.Webhook, del *models.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, attemptTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, strings.NewReader(del.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "FullDash-Webhooks")
+	req.Header.Set(EventHeader, del.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(del.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(w.Secret, time.Now(), []byte(del.Payload)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header for body sent at t:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">", keyed by the
+// webhook's secret. Receivers recompute it, and can reject old timestamps
+// to refuse replays.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret returns a fresh signing secret
+func NewSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,198 @@
+// xlsx/xlsx.go - Excel workbook output with excelize: sheets of text, numbers, dates and formulas
+package xlsx
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Style is how a cell is formatted; the styles are registered by newStyles
+type Style int
+
+const (
+	Plain   Style = iota
+	Bold          // headers and totals
+	Amount        // #,##0.00
+	Date          // yyyy-mm-dd
+	Month         // mmm yyyy
+	Percent       // a number of percent, shown with a % sign
+	BoldAmount
+)
+
+// Cell is one cell: text, a number or a formula, which Excel calculates
+// when the workbook is opened
+type Cell struct {
+	Text    string
+	Number  float64
+	Formula string // without the leading =
+	Style   Style
+	kind    byte // 's' text, 'n' number, 'f' formula, 0 empty
+}
+
+// Text is a text cell
+func Text(s string) Cell { return Cell{Text: s, kind: 's'} }
+
+// Number is a number cell
+func Number(n float64, style Style) Cell { return Cell{Number: n, Style: style, kind: 'n'} }
+
+// Cents is an amount cell in whole currency units
+func Cents(cents int64) Cell { return Number(float64(cents)/100, Amount) }
+
+// Day is a date cell; a zero time is left empty
+func Day(t time.Time) Cell {
+	if t.IsZero() {
+		return Cell{}
+	}
+	return Number(Serial(t), Date)
+}
+
+// Formula is a formula cell, e.g. "SUM(B2:B13)"
+func Formula(f string, style Style) Cell { return Cell{Formula: f, Style: style, kind: 'f'} }
+
+// Sheet is a worksheet. Widths sets column widths in characters, from A.
+// With Header set the first row is bold and stays in view when scrolling.
+type Sheet struct {
+	Name   string // at most 31 characters, none of []:*?/\
+	Widths []float64
+	Header bool
+	Rows   [][]Cell
+}
+
+// Add appends a row
+func (s *Sheet) Add(cells ...Cell) {
+	s.Rows = append(s.Rows, cells)
+}
+
+// Ref is the A1 reference of a column (0 is A) and row (1 is the first)
+func Ref(col, row int) string {
+	return Column(col) + strconv.Itoa(row)
+}
+
+// Column is a column's letters: 0 is A, 26 is AA
+func Column(col int) string {
+	var s string
+	for col++; col > 0; col = (col - 1) / 26 {
+		s = string(rune('A'+(col-1)%26)) + s
+	}
+	return s
+}
+
+// Serial is a date as Excel counts it, days since 30 December 1899, for a
+// Number cell styled as a Date or Month
+func Serial(t time.Time) float64 {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24
+}
+
+// Write renders the sheets as an .xlsx workbook, with excelize. Text goes
+// in the shared strings table, so a cell starting with = stays text.
+func Write(w io.Writer, sheets []*Sheet) error {
+	if len(sheets) == 0 {
+		return fmt.Errorf("a workbook needs a sheet")
+	}
+	for _, s := range sheets {
+		if s.Name == "" || len([]rune(s.Name)) > 31 || strings.ContainsAny(s.Name, `[]:*?/\`) {
+			return fmt.Errorf("invalid sheet name %q", s.Name)
+		}
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	styles, err := newStyles(f)
+	if err != nil {
+		return err
+	}
+	for i, s := range sheets {
+		if i == 0 {
+			err = f.SetSheetName(f.GetSheetName(0), s.Name)
+		} else {
+			_, err = f.NewSheet(s.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeSheet(f, s, styles); err != nil {
+			return fmt.Errorf("sheet %s: %w", s.Name, err)
+		}
+	}
+	// The formulas are written without cached values; have Excel
+	// calculate them on opening
+	fullCalc := true
+	if err := f.SetCalcProps(&excelize.CalcPropsOptions{FullCalcOnLoad: &fullCalc}); err != nil {
+		return err
+	}
+	return f.Write(w)
+}
+
+// newStyles registers a style per Style and returns their IDs, indexed by
+// Style. Percent shows the number itself with a % sign (25 → 25%), as
+// FullDash stores percentages.
+func newStyles(f *excelize.File) ([]int, error) {
+	bold := &excelize.Font{Bold: true}
+	dateFmt, monthFmt, percentFmt := "yyyy-mm-dd", "mmm yyyy", `0.##"%"`
+	defs := []*excelize.Style{
+		Plain:      {},
+		Bold:       {Font: bold},
+		Amount:     {NumFmt: 4}, // #,##0.00
+		Date:       {CustomNumFmt: &dateFmt},
+		Month:      {CustomNumFmt: &monthFmt},
+		Percent:    {CustomNumFmt: &percentFmt},
+		BoldAmount: {NumFmt: 4, Font: bold},
+	}
+	ids := make([]int, len(defs))
+	for i, d := range defs {
+		if i == int(Plain) {
+			continue
+		}
+		id, err := f.NewStyle(d)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func writeSheet(f *excelize.File, s *Sheet, styles []int) error {
+	for i, width := range s.Widths {
+		if err := f.SetColWidth(s.Name, Column(i), Column(i), width); err != nil {
+			return err
+		}
+	}
+	if s.Header {
+		if err := f.SetPanes(s.Name, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+			return err
+		}
+	}
+	for r, row := range s.Rows {
+		for c, cell := range row {
+			if s.Header && r == 0 && cell.Style == Plain {
+				cell.Style = Bold
+			}
+			ref := Ref(c, r+1)
+			var err error
+			switch cell.kind {
+			case 's':
+				err = f.SetCellStr(s.Name, ref, cell.Text)
+			case 'n':
+				err = f.SetCellFloat(s.Name, ref, cell.Number, -1, 64)
+			case 'f':
+				err = f.SetCellFormula(s.Name, ref, cell.Formula)
+			default:
+				continue
+			}
+			if err == nil && cell.Style != Plain {
+				err = f.SetCellStyle(s.Name, ref, ref, styles[cell.Style])
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWrite(t *testing.T) {
+	summary := &Sheet{Name: "Summary", Header: true, Widths: []float64{12, 16}}
+	summary.Add(Text("Client"), Text("Amount"), Text("Date"), Text("VAT"))
+	summary.Add(Text("=HYPERLINK(\"http://evil\")"), Cents(123456), Day(time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)), Number(25, Percent))
+	summary.Add(Text("Acme"), Cents(-500), Day(time.Time{}))
+	summary.Add(Text("Total"), Formula("SUM(B2:B3)", BoldAmount))
+	notes := &Sheet{Name: "Notes"}
+	notes.Add(Text("Acme"), Text("tab\there"))
+
+	var buf bytes.Buffer
+	if err := Write(&buf, []*Sheet{summary, notes}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// Text is in the shared strings table, once each: "Acme" is used by
+	// both sheets
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, err := z.Open("xl/sharedStrings.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sst, err := io.ReadAll(shared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(sst), "<si>"); n != 8 {
+		t.Errorf("%d shared strings, want 8: %s", n, sst)
+	}
+
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if got := f.GetSheetList(); len(got) != 2 || got[0] != "Summary" || got[1] != "Notes" {
+		t.Fatalf("sheets = %q, want [Summary Notes]", got)
+	}
+
+	for _, tt := range []struct {
+		sheet, ref string
+		typ        excelize.CellType
+		raw        string
+	}{
+		{"Summary", "A1", excelize.CellTypeSharedString, "Client"},
+		{"Summary", "A2", excelize.CellTypeSharedString, "=HYPERLINK(\"http://evil\")"},
+		{"Summary", "B2", excelize.CellTypeUnset, "1234.56"},
+		{"Summary", "C2", excelize.CellTypeUnset, "46095"},
+		{"Summary", "D2", excelize.CellTypeUnset, "25"},
+		{"Summary", "B3", excelize.CellTypeUnset, "-5"},
+		{"Summary", "C3", excelize.CellTypeUnset, ""},
+		{"Notes", "A1", excelize.CellTypeSharedString, "Acme"},
+		{"Notes", "B1", excelize.CellTypeSharedString, "tab\there"},
+	} {
+		typ, err := f.GetCellType(tt.sheet, tt.ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ != tt.typ {
+			t.Errorf("%s!%s type = %v, want %v", tt.sheet, tt.ref, typ, tt.typ)
+		}
+		raw, err := f.GetCellValue(tt.sheet, tt.ref, excelize.Options{RawCellValue: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if raw != tt.raw {
+			t.Errorf("%s!%s = %q, want %q", tt.sheet, tt.ref, raw, tt.raw)
+		}
+	}
+
+	if got, err := f.GetCellFormula("Summary", "A2"); err != nil || got != "" {
+		t.Errorf("Summary!A2 formula = %q, %v; text must not become a formula", got, err)
+	}
+	if got, err := f.GetCellFormula("Summary", "B4"); err != nil || got != "SUM(B2:B3)" {
+		t.Errorf("Summary!B4 formula = %q, %v, want SUM(B2:B3)", got, err)
+	}
+
+	for _, tt := range []struct {
+		ref    string
+		bold   bool
+		numFmt string
+	}{
+		{"A1", true, ""},
+		{"A2", false, ""},
+		{"B2", false, "#,##0.00"},
+		{"C2", false, "yyyy-mm-dd"},
+		{"D2", false, `0.##"%"`},
+		{"B4", true, "#,##0.00"},
+	} {
+		id, err := f.GetCellStyle("Summary", tt.ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		style, err := f.GetStyle(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bold := style.Font != nil && style.Font.Bold
+		numFmt := ""
+		if style.CustomNumFmt != nil {
+			numFmt = *style.CustomNumFmt
+		} else if style.NumFmt == 4 {
+			numFmt = "#,##0.00"
+		}
+		if bold != tt.bold || numFmt != tt.numFmt {
+			t.Errorf("Summary!%s style: bold %v, format %q; want bold %v, format %q", tt.ref, bold, numFmt, tt.bold, tt.numFmt)
+		}
+	}
+
+	panes, err := f.GetPanes("Summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !panes.Freeze || panes.YSplit != 1 {
+		t.Errorf("Summary panes = %+v, want the header row frozen", panes)
+	}
+	if width, err := f.GetColWidth("Summary", "B"); err != nil || width != 16 {
+		t.Errorf("Summary column B width = %v, %v, want 16", width, err)
+	}
+	calc, err := f.GetCalcProps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calc.FullCalcOnLoad == nil || !*calc.FullCalcOnLoad {
+		t.Error("fullCalcOnLoad not set; Excel would show the formulas without values")
+	}
+}
+
+func TestWriteSheetNames(t *testing.T) {
+	for _, name := range []string{"", "Q1/Q2", "[Summary]", "A name longer than thirty-one chars"} {
+		var buf bytes.Buffer
+		if err := Write(&buf, []*Sheet{{Name: name}}); err == nil {
+			t.Errorf("Write accepted sheet name %q", name)
+		}
+	}
+	if err := Write(&bytes.Buffer{}, nil); err == nil {
+		t.Error("Write accepted a workbook without sheets")
+	}
+}
+
+func TestColumn(t *testing.T) {
+	for col, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := Column(col); got != want {
+			t.Errorf("Column(%d) = %q, want %q", col, got, want)
+		}
+	}
+	if got := Ref(2, 14); got != "C14" {
+		t.Errorf("Ref(2, 14) = %q, want C14", got)
+	}
+}
+
+func TestSerial(t *testing.T) {
+	for _, tt := range []struct {
+		t    time.Time
+		want float64
+	}{
+		{time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC), 61},
+		{time.Date(2026, 3, 14, 23, 59, 0, 0, time.UTC), 46095},
+	} {
+		if got := Serial(tt.t); got != tt.want {
+			t.Errorf("Serial(%s) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// scripts/a11y/a11y.mjs - axe-core check of the board, signed in, failing on any violation
+//
+// Starts the server on a scratch database, creates the owner through
+// /setup, adds a project so the board has a card, then runs axe against /
+// (WCAG 2.1 A and AA). Run from the repository root:
+//
+//   npm --prefix scripts/a11y install && npm --prefix scripts/a11y test
+import { spawn, spawnSync } from 'node:child_process';
+import { mkdtempSync, rmSync } from 'node:fs';
+import { tmpdir } from 'node:os';
+import { dirname, join } from 'node:path';
+import { fileURLToPath } from 'node:url';
+import puppeteer from 'puppeteer';
+import { AxePuppeteer } from '@axe-core/puppeteer';
+
+const root = join(dirname(fileURLToPath(import.meta.url)), '..', '..');
+const port = process.env.A11Y_PORT || '18181';
+const base = `http://localhost:${port}`;
+const tags = ['wcag2a', 'wcag2aa', 'wcag21a', 'wcag21aa'];
+
+const dir = mkdtempSync(join(tmpdir(), 'fulldash-a11y-'));
+const bin = join(dir, 'fullstacked');
+if (spawnSync('go', ['build', '-o', bin, './cmd/fullstacked'], { cwd: root, stdio: 'inherit' }).status !== 0) {
+  rmSync(dir, { recursive: true, force: true });
+  process.exit(1);
+}
+const server = spawn(bin, [], {
+  cwd: root,
+  env: { ...process.env, PORT: port, DB_PATH: join(dir, 'fulldash.db'), BACKUP_DIR: join(dir, 'backups') },
+  stdio: ['ignore', 'inherit', 'inherit'],
+});
+
+let browser;
+let failed = true;
+try {
+  await waitForServer();
+  browser = await puppeteer.launch();
+  const page = await browser.newPage();
+
+  // Sign in: a fresh database sends everyone to /setup to create the owner
+  await page.goto(`${base}/setup`);
+  await page.type('input[name=name]', 'Noor');
+  await page.type('input[name=email]', 'owner@example.com');
+  await page.type('input[name=password]', 'a11y-password');
+  await Promise.all([page.waitForNavigation(), page.click('button[type=submit]')]);
+  if (new URL(page.url()).pathname !== '/') {
+    throw new Error(`setup ended on ${page.url()}, not the board`);
+  }
+
+  // A card, so the board's columns, lists and cards are all checked
+  const status = await page.evaluate(async () => {
+    const body = new URLSearchParams({ client: 'Acme AB', description: 'Website', revenue: '1000', status: 'new' });
+    for (const o of document.querySelectorAll('select[name=secured_by] option')) {
+      if (o.value) body.append('secured_by', o.value);
+    }
+    const token = document.querySelector('meta[name=csrf-token]').content;
+    const res = await fetch('/projects', { method: 'POST', headers: { 'X-CSRF-Token': token }, body });
+    return res.status;
+  });
+  if (status !== 200) {
+    throw new Error(`adding a project: ${status}`);
+  }
+  await page.goto(base + '/');
+
+  const results = await new AxePuppeteer(page).withTags(tags).analyze();
+  for (const v of results.violations) {
+    console.error(`${v.impact}: ${v.id} - ${v.help} (${v.helpUrl})`);
+    for (const node of v.nodes) {
+      console.error(`  ${node.target.join(' ')}`);
+    }
+  }
+  console.log(`axe: ${results.passes.length} rules passed, ${results.violations.length} violated on /`);
+  failed = results.violations.length > 0;
+} catch (err) {
+  console.error(err);
+} finally {
+  await browser?.close();
+  server.kill();
+  rmSync(dir, { recursive: true, force: true });
+}
+process.exit(failed ? 1 : 0);
+
+// waitForServer polls /health until the server answers, for up to 15s
+async function waitForServer() {
+  for (let i = 0; i < 30; i++) {
+    if (server.exitCode !== null) {
+      throw new Error(`server exited with ${server.exitCode}`);
+    }
+    try {
+      if ((await fetch(`${base}/health`)).ok) return;
+    } catch {
+      // not listening yet
+    }
+    await new Promise((r) => setTimeout(r, 500));
+  }
+  throw new Error(`no answer from ${base}/health`);
+}
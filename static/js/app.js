@@ -0,0 +1,199 @@
+// Toast notifications triggered by the HX-Trigger response header
+document.addEventListener("showToast", (e) => {
+  const toast = document.getElementById("toast");
+  if (!toast) return;
+
+  toast.textContent = e.detail.message;
+  toast.classList.add("toast--visible");
+
+  clearTimeout(toast._timer);
+  toast._timer = setTimeout(() => toast.classList.remove("toast--visible"), 4000);
+});
+
+// Failed requests: show the server's message unless it already sent a toast
+// (HX-Trigger), so errors never pass silently. Server errors add the request
+// ID, for the user to quote when reporting one
+document.addEventListener("htmx:responseError", (e) => {
+  const xhr = e.detail.xhr;
+  if (xhr.getResponseHeader("HX-Trigger")) return;
+  let message = (xhr.responseText || "").trim().split("\n")[0];
+  if (!message || message.length >= 200) message = `Request failed (${xhr.status})`;
+  const id = xhr.getResponseHeader("X-Request-ID");
+  announce(xhr.status >= 500 && id ? `${message} (request ${id})` : message);
+});
+
+document.addEventListener("htmx:sendError", () => announce("Couldn't reach the server; try again"));
+
+// Live dashboard: the SSE extension opens a fresh connection after an error,
+// which can't replay missed events, so refetch the board on every reopen
+document.addEventListener("htmx:sseOpen", (e) => {
+  const el = e.target;
+  if (el._sseOpened) htmx.trigger(el, "sse:reload");
+  el._sseOpened = true;
+});
+
+// Global undo/redo for partners (the History button marks the page):
+// Ctrl/Cmd+Z undoes, Ctrl/Cmd+Shift+Z or Ctrl+Y redoes, outside form fields
+document.addEventListener("keydown", (e) => {
+  if (!(e.ctrlKey || e.metaKey) || e.altKey) return;
+  if (e.target.closest("input, textarea, select, [contenteditable]")) return;
+  if (!document.querySelector("[data-history]") || !document.querySelector(".kanban")) return;
+
+  const key = e.key.toLowerCase();
+  const redo = (key === "z" && e.shiftKey) || key === "y";
+  if (key !== "z" && !redo) return;
+
+  e.preventDefault();
+  htmx.ajax("POST", redo ? "/history/redo" : "/history/undo", {
+    target: ".kanban",
+    swap: "outerHTML",
+    values: { panel: document.getElementById("history-panel") ? "1" : "" },
+  });
+});
+
+// Screen reader/keyboard feedback goes through the toast (role="status")
+function announce(message) {
+  document.dispatchEvent(new CustomEvent("showToast", { detail: { message } }));
+}
+
+// Keyboard moves on the board: Space picks up the focused card, Left/Right
+// move it one column (POST /projects/{id}/move), Escape puts it down and
+// Up/Down move focus between cards in a column
+let pickedCard = null;
+
+function cardName(card) {
+  return card.querySelector(".project-card__client")?.textContent.trim() ?? "Project";
+}
+
+function setPicked(card, picked) {
+  document.querySelectorAll(".project-card--picked").forEach((c) => c.classList.remove("project-card--picked"));
+  pickedCard = picked ? card.dataset.projectId : null;
+  if (picked) card.classList.add("project-card--picked");
+  announce(picked
+    ? `Picked up ${cardName(card)}. Left and Right arrows move it, Escape puts it down.`
+    : `Put down ${cardName(card)}`);
+}
+
+function moveCard(card, dir) {
+  const columns = [...document.querySelectorAll(".kanban__column")];
+  const next = columns[columns.indexOf(card.closest(".kanban__column")) + dir];
+  if (!next) {
+    announce(dir < 0 ? "Already in the first column" : "Already in the last column");
+    return;
+  }
+  const values = { status: next.dataset.status };
+  // Lost needs a reason; cancelling the prompt leaves the card where it is
+  if (values.status === "lost") {
+    const reason = prompt(`Why was ${cardName(card)} lost?`);
+    if (!reason?.trim()) return;
+    values.lost_reason = reason;
+  }
+  htmx.ajax("POST", `/projects/${card.dataset.projectId}/move`, {
+    source: card,
+    target: ".kanban",
+    swap: "outerHTML",
+    values,
+  });
+}
+
+function focusSibling(card, dir) {
+  const cards = [...card.closest(".kanban__list").querySelectorAll(".project-card[tabindex]")];
+  cards[cards.indexOf(card) + dir]?.focus();
+}
+
+document.addEventListener("keydown", (e) => {
+  const card = e.target.closest?.(".project-card[tabindex]");
+  if (!card || e.ctrlKey || e.metaKey || e.altKey) return;
+  const picked = pickedCard === card.dataset.projectId;
+
+  switch (e.key) {
+    case " ":
+      e.preventDefault();
+      setPicked(card, !picked);
+      break;
+    case "Escape":
+      if (picked) {
+        e.preventDefault();
+        setPicked(card, false);
+      }
+      break;
+    case "ArrowLeft":
+    case "ArrowRight":
+      if (!picked) return;
+      e.preventDefault();
+      moveCard(card, e.key === "ArrowLeft" ? -1 : 1);
+      break;
+    case "ArrowUp":
+    case "ArrowDown":
+      e.preventDefault();
+      focusSibling(card, e.key === "ArrowUp" ? -1 : 1);
+      break;
+  }
+});
+
+// Focus management: swaps replace the focused card (board refreshes) or drop
+// focus to <body> (closing a modal), so put it back on the last card used.
+// An open modal takes focus, traps Tab and closes on Escape.
+let lastCard = null;
+
+document.addEventListener("focusin", (e) => {
+  const card = e.target.closest?.(".project-card[data-project-id]");
+  if (card) lastCard = card.dataset.projectId;
+});
+
+const focusable = "input, select, textarea, button, [href], [tabindex]";
+
+function restoreFocus(swapped) {
+  const modal = document.querySelector("#modal .modal");
+  if (modal) {
+    if (modal.contains(document.activeElement)) return;
+    const scope = swapped && modal.contains(swapped) ? swapped : modal.querySelector(".modal__content");
+    scope.querySelector(focusable)?.focus();
+    return;
+  }
+  if (document.activeElement && document.activeElement !== document.body) return;
+
+  const card = lastCard && document.querySelector(`.project-card[data-project-id="${lastCard}"]`);
+  if (!card) return;
+  card.focus();
+  if (pickedCard === lastCard) card.classList.add("project-card--picked");
+}
+
+document.addEventListener("htmx:afterSettle", (e) => restoreFocus(e.detail.elt));
+new MutationObserver(() => restoreFocus()).observe(document.getElementById("modal"), { childList: true });
+
+document.addEventListener("keydown", (e) => {
+  const modal = document.querySelector("#modal .modal");
+  if (!modal) return;
+
+  if (e.key === "Escape") {
+    e.preventDefault();
+    modal.remove();
+    return;
+  }
+  if (e.key !== "Tab") return;
+
+  const items = [...modal.querySelectorAll(focusable)].filter((el) => !el.disabled && el.offsetParent !== null);
+  const first = items[0];
+  const last = items[items.length - 1];
+  if (e.shiftKey && document.activeElement === first) {
+    e.preventDefault();
+    last.focus();
+  } else if (!e.shiftKey && document.activeElement === last) {
+    e.preventDefault();
+    first.focus();
+  }
+});
+
+// Links to a card (/#project-<id>, e.g. from Slack or Discord messages)
+// scroll to it and, for partners, open it
+function openLinkedCard() {
+  const id = location.hash.match(/^#project-(\d+)$/)?.[1];
+  const card = id && document.querySelector(`.project-card[data-project-id="${id}"]`);
+  if (!card) return;
+  card.scrollIntoView({ block: "center" });
+  if (card.hasAttribute("tabindex")) card.click();
+}
+
+window.addEventListener("load", openLinkedCard);
+window.addEventListener("hashchange", openLinkedCard);